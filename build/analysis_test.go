@@ -0,0 +1,1113 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build lint
+
+package build_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// An analyzer is a lint check that inspects the syntax tree of each Go file in
+// the tree. Unlike the checks that grep the source, analyzers aren't fooled by
+// strings, comments or renamed imports.
+type analyzer struct {
+	// name is also the name of the check in lint_exceptions.yaml.
+	name string
+	run  func(*pass)
+}
+
+// A pass is the application of an analyzer to a single file.
+type pass struct {
+	fset *token.FileSet
+	// path is the path of the file relative to pkg/, with forward slashes.
+	path string
+	file *ast.File
+	// imports maps the names under which packages are imported in the file to
+	// their import paths.
+	imports map[string]string
+	report  func(string)
+}
+
+// reportf reports a problem at the given position.
+func (p *pass) reportf(pos token.Pos, format string, args ...interface{}) {
+	p.report(fmt.Sprintf("%s:%d: %s", p.path, p.fset.Position(pos).Line, fmt.Sprintf(format, args...)))
+}
+
+// importedName returns the import path of the package and the name of the
+// package-level identifier referenced by the given expression, e.g. "os" and
+// "Getenv" for os.Getenv. ok is false if the expression isn't a reference to
+// an imported identifier.
+func (p *pass) importedName(e ast.Expr) (path, name string, ok bool) {
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok {
+		return "", "", false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	// An identifier resolved by the parser is declared in the file, and thus
+	// shadows any import.
+	if !ok || id.Obj != nil {
+		return "", "", false
+	}
+	path, ok = p.imports[id.Name]
+	return path, sel.Sel.Name, ok
+}
+
+var versionSuffixRE = regexp.MustCompile(`\.v\d+$`)
+
+// defaultImportName returns the name under which a package is imported when
+// the import doesn't name it. This is assumed to be the last element of the
+// import path, without the decorations commonly found there.
+func defaultImportName(path string) string {
+	name := path[strings.LastIndex(path, "/")+1:]
+	name = versionSuffixRE.ReplaceAllString(name, "")
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(name, "-go")
+	return name
+}
+
+func fileImports(file *ast.File) map[string]string {
+	imports := make(map[string]string, len(file.Imports))
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := defaultImportName(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// forbiddenRefs returns an analyzer that reports the references to the given
+// package-level identifiers, keyed by import path, along with the hint.
+func forbiddenRefs(name string, refs map[string][]string, hint string) *analyzer {
+	return forbidden(name, refs, hint, false /* callsOnly */)
+}
+
+// forbiddenCalls is like forbiddenRefs, but only reports calls to the given
+// functions. Other references, such as the `var _ = proto.Marshal` of the
+// generated protobuf code, are allowed.
+func forbiddenCalls(name string, funcs map[string][]string, hint string) *analyzer {
+	return forbidden(name, funcs, hint, true /* callsOnly */)
+}
+
+func forbidden(name string, refs map[string][]string, hint string, callsOnly bool) *analyzer {
+	a := &analyzer{name: name}
+	a.run = func(p *pass) {
+		ast.Inspect(p.file, func(n ast.Node) bool {
+			var e ast.Expr
+			if callsOnly {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				e = call.Fun
+			} else {
+				var ok bool
+				if e, ok = n.(*ast.SelectorExpr); !ok {
+					return true
+				}
+			}
+			path, name, ok := p.importedName(e)
+			if !ok {
+				return true
+			}
+			for _, ref := range refs[path] {
+				if ref == name {
+					p.reportf(e.Pos(), "%s.%s <- forbidden; %s", path, name, hint)
+				}
+			}
+			return true
+		})
+	}
+	return a
+}
+
+// printAnalyzer reports the calls to the fmt.Print functions and to the print
+// and println builtins, which write to the stdout or stderr of the server.
+var printAnalyzer = &analyzer{
+	name: "print",
+	run: func(p *pass) {
+		fmtPrintAnalyzer.run(p)
+		ast.Inspect(p.file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			// An identifier resolved by the parser is declared in the file, and
+			// thus shadows the builtin.
+			if id, ok := call.Fun.(*ast.Ident); ok && id.Obj == nil &&
+				(id.Name == "print" || id.Name == "println") {
+				p.reportf(id.Pos(), "%s <- forbidden; %s", id.Name, printHint)
+			}
+			return true
+		})
+	},
+}
+
+const printHint = `use "util/log" instead`
+
+var fmtPrintAnalyzer = forbiddenCalls("print",
+	map[string][]string{"fmt": {"Print", "Printf", "Println"}},
+	printHint)
+
+// fatalAnalyzer reports the calls to os.Exit and to the log.Fatal functions
+// outside of main packages. They bypass the shutdown of the stopper and the
+// deferred cleanups.
+var fatalAnalyzer = &analyzer{
+	name: "fatal",
+	run: func(p *pass) {
+		if p.file.Name.Name == "main" {
+			return
+		}
+		fatalCallsAnalyzer.run(p)
+	},
+}
+
+var fatalCallsAnalyzer = forbiddenCalls("fatal",
+	map[string][]string{
+		"os":  {"Exit"},
+		"log": {"Fatal", "Fatalf", "Fatalln"},
+		"github.com/cockroachdb/cockroach/pkg/util/log": {"Fatal", "Fatalf", "FatalfDepth"},
+	},
+	`return an error or use the stopper instead`)
+
+// panicAnalyzer reports the calls to the panic builtin in the production code
+// of sql and storage, where a stray panic crashes the node instead of failing
+// a single query. Panicking with a pgerror is allowed for assertions, and the
+// main packages may panic.
+var panicAnalyzer = &analyzer{
+	name: "panic",
+	run: func(p *pass) {
+		if p.file.Name.Name == "main" || strings.HasSuffix(p.path, "_test.go") ||
+			!(strings.HasPrefix(p.path, "sql/") || strings.HasPrefix(p.path, "storage/")) {
+			return
+		}
+		ast.Inspect(p.file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			// An identifier resolved by the parser is declared in the file, and
+			// thus shadows the builtin.
+			if id, ok := call.Fun.(*ast.Ident); !ok || id.Obj != nil || id.Name != "panic" {
+				return true
+			}
+			if len(call.Args) == 1 {
+				if arg, ok := call.Args[0].(*ast.CallExpr); ok {
+					if path, _, ok := p.importedName(arg.Fun); ok && path == pgerrorPath {
+						return true
+					}
+				}
+			}
+			p.reportf(call.Pos(), "panic <- forbidden; %s", panicHint)
+			return true
+		})
+	},
+}
+
+const pgerrorPath = "github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+
+const panicHint = `return an error, or panic with a "pgerror" for assertions`
+
+// contextAnalyzer reports the calls to context.TODO and context.Background
+// outside of main packages. A context made from scratch loses the log tags and
+// the trace span of the caller.
+var contextAnalyzer = &analyzer{
+	name: "context",
+	run: func(p *pass) {
+		if p.file.Name.Name == "main" {
+			return
+		}
+		contextCallsAnalyzer.run(p)
+	},
+}
+
+var contextCallsAnalyzer = forbiddenCalls("context",
+	map[string][]string{
+		"context":                  {"TODO", "Background"},
+		"golang.org/x/net/context": {"TODO", "Background"},
+	},
+	`plumb a context through, or use "AmbientContext.AnnotateCtx" instead`)
+
+// sleepAnalyzer reports the calls to time.Sleep in tests. Sleeping for long
+// enough slows the tests down, and sleeping for less makes them flaky.
+var sleepAnalyzer = &analyzer{
+	name: "sleep",
+	run: func(p *pass) {
+		// testutils implements the helpers that tests should use instead.
+		if !strings.HasSuffix(p.path, "_test.go") || strings.HasPrefix(p.path, "testutils/") {
+			return
+		}
+		sleepCallsAnalyzer.run(p)
+	},
+}
+
+var sleepCallsAnalyzer = forbiddenCalls("sleep",
+	map[string][]string{"time": {"Sleep"}},
+	`use "testutils.SucceedsSoon" or "retry" instead`)
+
+// leaktestAnalyzer reports the tests which don't start with
+// `defer leaktest.AfterTest(t)()`, which fails them if they leak goroutines.
+// A test is exempted by a "lint:ignore leaktest <reason>" line in its doc
+// comment. Only the packages which run add-leaktest.sh are checked, see
+// leaktestFiles.
+var leaktestAnalyzer = &analyzer{
+	name: "leaktest",
+	run: func(p *pass) {
+		if !strings.HasSuffix(p.path, "_test.go") {
+			return
+		}
+		for _, decl := range p.file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			t, ok := p.testParam(fn)
+			if !ok {
+				continue
+			}
+			if reason, ok := leaktestExemption(fn.Doc); ok {
+				if reason == "" {
+					p.reportf(fn.Pos(), "%s: lint:ignore leaktest needs a reason", fn.Name.Name)
+				}
+				continue
+			}
+			if !p.isAfterTest(fn.Body.List, t) {
+				p.reportf(fn.Pos(), "%s: missing defer leaktest.AfterTest", fn.Name.Name)
+			}
+		}
+	},
+}
+
+// testParam returns the name of the *testing.T parameter of the function, if
+// it's a test as understood by go test.
+func (p *pass) testParam(fn *ast.FuncDecl) (string, bool) {
+	name := fn.Name.Name
+	if fn.Recv != nil || fn.Body == nil || !strings.HasPrefix(name, "Test") || name == "TestMain" {
+		return "", false
+	}
+	// TestFoo is a test, but Testing isn't.
+	if r, _ := utf8.DecodeRuneInString(name[len("Test"):]); unicode.IsLower(r) {
+		return "", false
+	}
+	params := fn.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 {
+		return "", false
+	}
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return "", false
+	}
+	if path, name, ok := p.importedName(star.X); !ok || path != "testing" || name != "T" {
+		return "", false
+	}
+	if len(params[0].Names) == 0 {
+		return "_", true
+	}
+	return params[0].Names[0].Name, true
+}
+
+// isAfterTest returns whether the first of the statements is
+// `defer leaktest.AfterTest(t)()`.
+func (p *pass) isAfterTest(stmts []ast.Stmt, t string) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	def, ok := stmts[0].(*ast.DeferStmt)
+	if !ok || len(def.Call.Args) != 0 {
+		return false
+	}
+	call, ok := def.Call.Fun.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return false
+	}
+	if path, name, ok := p.importedName(call.Fun); !ok || path != leaktestPath || name != "AfterTest" {
+		return false
+	}
+	arg, ok := call.Args[0].(*ast.Ident)
+	return ok && arg.Name == t
+}
+
+const leaktestPath = "github.com/cockroachdb/cockroach/pkg/util/leaktest"
+
+// leaktestExemption returns the reason given by the "lint:ignore leaktest"
+// line of a doc comment, if any.
+func leaktestExemption(doc *ast.CommentGroup) (reason string, ok bool) {
+	if doc == nil {
+		return "", false
+	}
+	for _, c := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+		if fields := strings.Fields(text); len(fields) >= 2 &&
+			fields[0] == "lint:ignore" && fields[1] == "leaktest" {
+			return strings.Join(fields[2:], " "), true
+		}
+	}
+	return "", false
+}
+
+var leaktestGenerateRE = regexp.MustCompile(`(?m)^//go:generate .*add-leaktest\.sh`)
+
+// leaktestFiles returns the test files of the tree rooted at dir which
+// leaktestAnalyzer checks: those of the packages which run add-leaktest.sh
+// with go:generate. The directive is looked for in all the files of the
+// packages, since the tree may only hold the changed ones.
+func leaktestFiles(dir string, tree *sourceTree) ([]string, error) {
+	pkgs := make(map[string]bool)
+	var files []string
+	for _, path := range tree.paths {
+		if !strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		pkg := filepath.Dir(filepath.Join(dir, filepath.FromSlash(path)))
+		checked, ok := pkgs[pkg]
+		if !ok {
+			infos, err := ioutil.ReadDir(pkg)
+			if err != nil {
+				return nil, err
+			}
+			for _, info := range infos {
+				if !strings.HasSuffix(info.Name(), ".go") {
+					continue
+				}
+				src, err := ioutil.ReadFile(filepath.Join(pkg, info.Name()))
+				if err != nil {
+					return nil, err
+				}
+				if leaktestGenerateRE.Match(src) {
+					checked = true
+					break
+				}
+			}
+			pkgs[pkg] = checked
+		}
+		if checked {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// helperAnalyzer reports the test helpers which fail the test without
+// calling t.Helper(), so that the failures are reported at the line of the
+// helper instead of that of its caller. A helper is a function, other than a
+// test or a benchmark, which takes a *testing.T, a *testing.B or a
+// testing.TB and calls its Error, Errorf, Fatal or Fatalf method.
+var helperAnalyzer = &analyzer{
+	name: "helper",
+	run: func(p *pass) {
+		for _, decl := range p.file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || p.isTestEntry(fn) {
+				continue
+			}
+			for _, param := range p.testingParams(fn.Type) {
+				failure, helper := testingCalls(fn.Body, param)
+				if failure != "" && !helper {
+					p.reportf(fn.Pos(), "%s: calls %s.%s without %s.Helper()",
+						fn.Name.Name, param.Name, failure, param.Name)
+				}
+			}
+		}
+	},
+}
+
+// isTestEntry returns whether the function is a test or a benchmark, which
+// go test calls directly.
+func (p *pass) isTestEntry(fn *ast.FuncDecl) bool {
+	if _, ok := p.testParam(fn); ok {
+		return true
+	}
+	name := fn.Name.Name
+	if fn.Recv != nil || !strings.HasPrefix(name, "Benchmark") {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(name[len("Benchmark"):])
+	return !unicode.IsLower(r)
+}
+
+// testingParams returns the named parameters of the function which are a
+// *testing.T, a *testing.B or a testing.TB.
+func (p *pass) testingParams(typ *ast.FuncType) []*ast.Ident {
+	var params []*ast.Ident
+	for _, field := range typ.Params.List {
+		t := field.Type
+		if star, ok := t.(*ast.StarExpr); ok {
+			t = star.X
+		}
+		path, name, ok := p.importedName(t)
+		if !ok || path != "testing" {
+			continue
+		}
+		_, isStar := field.Type.(*ast.StarExpr)
+		if (isStar && (name == "T" || name == "B")) || (!isStar && name == "TB") {
+			for _, id := range field.Names {
+				if id.Name != "_" {
+					params = append(params, id)
+				}
+			}
+		}
+	}
+	return params
+}
+
+// testingCalls returns the first failure method called on the parameter in
+// the body of a function, if any, and whether its Helper method is called.
+// The parameter is told from the variables shadowing it by the object the
+// parser resolved it to.
+func testingCalls(body *ast.BlockStmt, param *ast.Ident) (failure string, helper bool) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if id, ok := sel.X.(*ast.Ident); !ok || id.Obj == nil || id.Obj != param.Obj {
+			return true
+		}
+		switch sel.Sel.Name {
+		case "Helper":
+			helper = true
+		case "Error", "Errorf", "Fatal", "Fatalf":
+			if failure == "" {
+				failure = sel.Sel.Name
+			}
+		}
+		return true
+	})
+	return failure, helper
+}
+
+// errwrapAnalyzer reports the calls to fmt.Errorf which format an error with
+// %v or %s. The resulting error loses the cause, and the stack trace if any,
+// which errors.Wrap preserves. Lacking type information, the analyzer
+// recognizes errors by their names: err, anything ending in Err, and the
+// result of their Error method.
+var errwrapAnalyzer = &analyzer{
+	name: "errwrap",
+	run: func(p *pass) {
+		ast.Inspect(p.file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) < 2 {
+				return true
+			}
+			if path, name, ok := p.importedName(call.Fun); !ok || path != "fmt" || name != "Errorf" {
+				return true
+			}
+			format, ok := stringConstant(call.Args[0])
+			if !ok {
+				return true
+			}
+			verbs, ok := formatVerbs(format)
+			if !ok {
+				return true
+			}
+			for i, verb := range verbs {
+				if i+1 < len(call.Args) && (verb == 'v' || verb == 's') && isErrorExpr(call.Args[i+1]) {
+					p.reportf(call.Args[i+1].Pos(), "fmt.Errorf(\"%%%c\", error) <- forbidden; %s",
+						verb, `use "errors.Wrap(f)" instead`)
+				}
+			}
+			return true
+		})
+	},
+}
+
+// stringConstant returns the value of a string literal, or of a concatenation
+// of string literals.
+func stringConstant(e ast.Expr) (string, bool) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		x, ok := stringConstant(e.X)
+		if !ok {
+			return "", false
+		}
+		y, ok := stringConstant(e.Y)
+		return x + y, ok
+	case *ast.ParenExpr:
+		return stringConstant(e.X)
+	}
+	return "", false
+}
+
+// formatVerbs returns the verbs of a format string, in the order of the
+// arguments they consume. A '*' width or precision consumes an argument of its
+// own. ok is false if the format uses explicit argument indexes.
+func formatVerbs(format string) (verbs []rune, ok bool) {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		for i++; i < len(format); i++ {
+			c := format[i]
+			if c == '[' {
+				return nil, false
+			}
+			if c == '*' {
+				verbs = append(verbs, '*')
+				continue
+			}
+			if strings.IndexByte("+-# 0.123456789", c) < 0 {
+				if c != '%' {
+					verbs = append(verbs, rune(c))
+				}
+				break
+			}
+		}
+	}
+	return verbs, true
+}
+
+// isErrorExpr guesses whether an expression is an error, or the message of
+// one.
+func isErrorExpr(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return isErrorName(e.Name)
+	case *ast.SelectorExpr:
+		return isErrorName(e.Sel.Name)
+	case *ast.CallExpr:
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Error" && len(e.Args) == 0 {
+			return isErrorExpr(sel.X)
+		}
+	}
+	return false
+}
+
+func isErrorName(name string) bool {
+	return name == "err" || strings.HasSuffix(name, "Err")
+}
+
+var protoPackages = []string{"github.com/gogo/protobuf/proto", "github.com/golang/protobuf/proto"}
+
+func protoRefs(name string) map[string][]string {
+	refs := make(map[string][]string, len(protoPackages))
+	for _, path := range protoPackages {
+		refs[path] = []string{name}
+	}
+	return refs
+}
+
+var (
+	envutilAnalyzer = forbiddenRefs("envutil",
+		map[string][]string{"os": {"Getenv", "LookupEnv"}},
+		`use "envutil" instead`)
+	syncutilAnalyzer = forbiddenRefs("syncutil",
+		map[string][]string{"sync": {"Mutex", "RWMutex"}},
+		`use "syncutil.{,RW}Mutex" instead`)
+	timeutilAnalyzer = forbiddenRefs("timeutil",
+		map[string][]string{"time": {"Now", "Since"}},
+		`use "timeutil" instead`)
+	grpcAnalyzer = forbiddenRefs("grpc",
+		map[string][]string{"google.golang.org/grpc": {"NewServer"}},
+		`use "rpc.NewServer" instead`)
+	protoCloneAnalyzer = forbiddenRefs("protoclone",
+		protoRefs("Clone"),
+		`use "protoutil.Clone" instead`)
+	protoMarshalAnalyzer = forbiddenCalls("protomarshal",
+		protoRefs("Marshal"),
+		`use "protoutil.Marshal" instead`)
+	// The global functions of math/rand share a locked source, which
+	// concurrent users contend on, and make the tests which depend on the order
+	// of their calls hard to reproduce.
+	randAnalyzer = forbiddenRefs("rand",
+		map[string][]string{"math/rand": {
+			"ExpFloat64", "Float32", "Float64", "Int", "Int31", "Int31n", "Int63", "Int63n",
+			"Intn", "NormFloat64", "Perm", "Read", "Seed", "Uint32", "Uint64",
+		}},
+		`use "randutil.NewPseudoRand" or "randutil.NewLockedPseudoRand" instead`)
+)
+
+// checkFile runs the analyzer on a file, unless the file is exempt.
+func checkFile(
+	a *analyzer,
+	exceptions lintExceptionList,
+	fset *token.FileSet,
+	path string,
+	file *ast.File,
+	report func(string),
+) {
+	if exceptions.exempts(path, "") {
+		return
+	}
+	a.run(&pass{
+		fset:    fset,
+		path:    path,
+		file:    file,
+		imports: fileImports(file),
+		report:  report,
+	})
+}
+
+// sourceTree holds the parsed Go files of a directory tree.
+type sourceTree struct {
+	fset *token.FileSet
+	// paths are the paths of the files relative to the root of the tree, with
+	// forward slashes, in sorted order.
+	paths []string
+	files map[string]*ast.File
+}
+
+// skippedDirs are the directories that don't contain our sources.
+var skippedDirs = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+	"testdata":     true,
+}
+
+// parseTree parses the Go files in the tree rooted at dir. If changed is not
+// nil, only the files it contains are parsed.
+func parseTree(dir string, changed map[string]bool) (*sourceTree, error) {
+	tree := &sourceTree{
+		fset:  token.NewFileSet(),
+		files: make(map[string]*ast.File),
+	}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && (skippedDirs[info.Name()] || strings.HasPrefix(info.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if changed != nil && !changed[rel] {
+			return nil
+		}
+		// The comments hold the exemptions of leaktestAnalyzer.
+		file, err := parser.ParseFile(tree.fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		tree.paths = append(tree.paths, rel)
+		tree.files[rel] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(tree.paths)
+	return tree, nil
+}
+
+var sourceTrees struct {
+	sync.Mutex
+	m map[string]*sourceTreeResult
+}
+
+type sourceTreeResult struct {
+	once sync.Once
+	tree *sourceTree
+	err  error
+}
+
+// loadTree parses the tree rooted at dir once (see parseTree), and shares it
+// between the analyzers.
+func loadTree(dir string, changed map[string]bool) (*sourceTree, error) {
+	sourceTrees.Lock()
+	if sourceTrees.m == nil {
+		sourceTrees.m = make(map[string]*sourceTreeResult)
+	}
+	res, ok := sourceTrees.m[dir]
+	if !ok {
+		res = &sourceTreeResult{}
+		sourceTrees.m[dir] = res
+	}
+	sourceTrees.Unlock()
+	res.once.Do(func() {
+		res.tree, res.err = parseTree(dir, changed)
+	})
+	return res.tree, res.err
+}
+
+// runAnalyzer runs the analyzer on the tree rooted at dir, or only on the
+// changed files in it if changed is not nil, and reports the problems it finds
+// as lint failures. The files exempted by the exceptions to the check are
+// skipped. It returns false if the tree couldn't be parsed, in which case the
+// caller falls back to the grep version of the check.
+func runAnalyzer(
+	t *testing.T,
+	report *lintReporter,
+	dir string,
+	changed map[string]bool,
+	a *analyzer,
+	exceptions lintExceptions,
+) bool {
+	tree, err := loadTree(dir, changed)
+	if err != nil {
+		t.Logf("%s: falling back to git grep: %s", a.name, err)
+		return false
+	}
+	for _, path := range tree.paths {
+		checkFile(a, exceptions[a.name], tree.fset, path, tree.files[path], func(s string) {
+			report.failLine(t, s, "", "")
+		})
+	}
+	return true
+}
+
+func TestAnalyzers(t *testing.T) {
+	const src = `package foo
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	t "time"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/gogo/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// os.Getenv("FOO") in a comment is fine.
+const s = "os.Getenv(\"FOO\") in a string is fine"
+
+type foo struct {
+	mu sync.Mutex
+}
+
+func bar(m proto.Message) {
+	_ = os.Getenv("FOO")
+	_, _ = os.LookupEnv("FOO")
+	_ = t.Now()
+	_ = grpc.NewServer()
+	_ = proto.Clone(m)
+	_, _ = proto.Marshal(m)
+
+	// A local variable shadows the import.
+	time := struct{ Now func() }{}
+	_ = time.Now
+	{
+		os := struct{ Getenv func(string) string }{}
+		_ = os.Getenv("FOO")
+	}
+}
+
+func baz() {
+	fmt.Println("foo")
+	_ = fmt.Sprintf("foo")
+	println("foo")
+	print := func(string) {}
+	print("foo")
+}
+
+func qux() {
+	os.Exit(1)
+}
+
+func quux(ctx context.Context) {
+	_ = context.TODO()
+	_ = context.Background()
+	_ = context.WithValue(ctx, 1, 2)
+}
+
+func corge(err error) {
+	_ = fmt.Errorf("foo: %v", err)
+	_ = fmt.Errorf("%d%% %s", 1, err.Error())
+	_ = fmt.Errorf("%*d: %+v", 1, 2, fooErr)
+	_ = fmt.Errorf("%q %d", err, 1)
+	_ = fmt.Errorf("%[1]v", err)
+	_ = fmt.Errorf("foo: %v", e)
+}
+
+func grault() {
+	t.Sleep(t.Second)
+}
+
+func garply() {
+	_ = rand.Intn(10)
+	_ = rand.New(rand.NewSource(1)).Intn(10)
+	f := rand.Float64
+	_ = f()
+}
+
+func waldo() {
+	panic("waldo")
+	panic(pgerror.NewErrorf(pgerror.CodeInternalError, "waldo"))
+	{
+		panic := func(string) {}
+		panic("waldo")
+	}
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "foo/foo.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exceptions, err := loadLintExceptions(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		a        *analyzer
+		path     string
+		expected []string
+	}{
+		{envutilAnalyzer, "foo/foo.go", []string{
+			`foo/foo.go:24: os.Getenv <- forbidden; use "envutil" instead`,
+			`foo/foo.go:25: os.LookupEnv <- forbidden; use "envutil" instead`,
+		}},
+		{envutilAnalyzer, "util/envutil/foo.go", nil},
+		{syncutilAnalyzer, "foo/foo.go", []string{
+			`foo/foo.go:20: sync.Mutex <- forbidden; use "syncutil.{,RW}Mutex" instead`,
+		}},
+		{timeutilAnalyzer, "foo/foo.go", []string{
+			`foo/foo.go:26: time.Now <- forbidden; use "timeutil" instead`,
+		}},
+		{grpcAnalyzer, "foo/foo.go", []string{
+			`foo/foo.go:27: google.golang.org/grpc.NewServer <- forbidden; use "rpc.NewServer" instead`,
+		}},
+		{protoCloneAnalyzer, "foo/foo.go", []string{
+			`foo/foo.go:28: github.com/gogo/protobuf/proto.Clone <- forbidden; use "protoutil.Clone" instead`,
+		}},
+		{printAnalyzer, "foo/foo.go", []string{
+			`foo/foo.go:41: fmt.Println <- forbidden; use "util/log" instead`,
+			`foo/foo.go:43: println <- forbidden; use "util/log" instead`,
+		}},
+		{printAnalyzer, "cli/foo.go", nil},
+		{printAnalyzer, "foo/foo_test.go", nil},
+		{fatalAnalyzer, "foo/foo.go", []string{
+			`foo/foo.go:49: os.Exit <- forbidden; return an error or use the stopper instead`,
+		}},
+		{fatalAnalyzer, "cmd/foo/foo.go", nil},
+		{contextAnalyzer, "foo/foo.go", []string{
+			`foo/foo.go:53: golang.org/x/net/context.TODO <- forbidden; plumb a context through, or use "AmbientContext.AnnotateCtx" instead`,
+			`foo/foo.go:54: golang.org/x/net/context.Background <- forbidden; plumb a context through, or use "AmbientContext.AnnotateCtx" instead`,
+		}},
+		{contextAnalyzer, "foo/foo_test.go", nil},
+		{errwrapAnalyzer, "foo/foo.go", []string{
+			`foo/foo.go:59: fmt.Errorf("%v", error) <- forbidden; use "errors.Wrap(f)" instead`,
+			`foo/foo.go:60: fmt.Errorf("%s", error) <- forbidden; use "errors.Wrap(f)" instead`,
+			`foo/foo.go:61: fmt.Errorf("%v", error) <- forbidden; use "errors.Wrap(f)" instead`,
+		}},
+		{errwrapAnalyzer, "foo/foo_test.go", nil},
+		{sleepAnalyzer, "foo/foo_test.go", []string{
+			`foo/foo_test.go:68: time.Sleep <- forbidden; use "testutils.SucceedsSoon" or "retry" instead`,
+		}},
+		{sleepAnalyzer, "foo/foo.go", nil},
+		{sleepAnalyzer, "testutils/foo_test.go", nil},
+		{randAnalyzer, "foo/foo.go", []string{
+			`foo/foo.go:72: math/rand.Intn <- forbidden; use "randutil.NewPseudoRand" or "randutil.NewLockedPseudoRand" instead`,
+			`foo/foo.go:74: math/rand.Float64 <- forbidden; use "randutil.NewPseudoRand" or "randutil.NewLockedPseudoRand" instead`,
+		}},
+		{randAnalyzer, "util/randutil/foo.go", nil},
+		{panicAnalyzer, "sql/foo.go", []string{
+			`sql/foo.go:79: panic <- forbidden; return an error, or panic with a "pgerror" for assertions`,
+		}},
+		{panicAnalyzer, "sql/foo_test.go", nil},
+		{panicAnalyzer, "util/foo.go", nil},
+		{protoMarshalAnalyzer, "foo/foo.go", []string{
+			`foo/foo.go:29: github.com/gogo/protobuf/proto.Marshal <- forbidden; use "protoutil.Marshal" instead`,
+		}},
+	}
+	for _, tc := range testCases {
+		var reported []string
+		checkFile(tc.a, exceptions[tc.a.name], fset, tc.path, file, func(s string) {
+			reported = append(reported, s)
+		})
+		if !reflect.DeepEqual(reported, tc.expected) {
+			t.Errorf("%s on %s: expected %q, got %q", tc.a.name, tc.path, tc.expected, reported)
+		}
+	}
+
+	// Main packages may exit, and make contexts from scratch.
+	file.Name.Name = "main"
+	for _, a := range []*analyzer{fatalAnalyzer, contextAnalyzer} {
+		checkFile(a, exceptions[a.name], fset, "foo/foo.go", file, func(s string) {
+			t.Errorf("%s: unexpected report on a main package: %s", a.name, s)
+		})
+	}
+}
+
+func TestLeaktestAnalyzer(t *testing.T) {
+	const src = `package foo
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestFoo(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+}
+
+func TestBar(t *testing.T) {
+	t.Parallel()
+	defer leaktest.AfterTest(t)()
+}
+
+// TestBaz runs in a subprocess.
+//
+//lint:ignore leaktest the subprocess exits at the end of the test
+func TestBaz(t *testing.T) {
+}
+
+//lint:ignore leaktest
+func TestQux(t *testing.T) {
+}
+
+func TestQuux(u *testing.T) {
+	defer leaktest.AfterTest(t)()
+}
+
+func Testing(t *testing.T) {
+}
+
+func TestMain(m *testing.M) {
+}
+
+func BenchmarkFoo(b *testing.B) {
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "foo/foo_test.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`foo/foo_test.go:13: TestBar: missing defer leaktest.AfterTest`,
+		`foo/foo_test.go:25: TestQux: lint:ignore leaktest needs a reason`,
+		`foo/foo_test.go:28: TestQuux: missing defer leaktest.AfterTest`,
+	}
+	var reported []string
+	checkFile(leaktestAnalyzer, nil, fset, "foo/foo_test.go", file, func(s string) {
+		reported = append(reported, s)
+	})
+	if !reflect.DeepEqual(reported, expected) {
+		t.Errorf("expected %q, got %q", expected, reported)
+	}
+
+	reported = nil
+	checkFile(leaktestAnalyzer, nil, fset, "foo/foo.go", file, func(s string) {
+		reported = append(reported, s)
+	})
+	if len(reported) != 0 {
+		t.Errorf("unexpected reports on a non-test file: %q", reported)
+	}
+}
+
+func TestHelperAnalyzer(t *testing.T) {
+	const src = `package foo
+
+import (
+	"testing"
+)
+
+func checkFoo(t *testing.T, foo int) {
+	t.Helper()
+	if foo != 1 {
+		t.Errorf("expected 1, got %d", foo)
+	}
+}
+
+func checkBar(t *testing.T, bar int) {
+	if bar != 1 {
+		t.Fatalf("expected 1, got %d", bar)
+	}
+}
+
+func checkBaz(tb testing.TB, b *testing.B) {
+	b.Helper()
+	tb.Error("baz")
+	b.Fatal("baz")
+}
+
+func run(t *testing.T) {
+	t.Run("foo", func(t *testing.T) {
+		t.Fatal("foo")
+	})
+}
+
+func (f foo) check(t *testing.T) {
+	t.Log("foo")
+}
+
+func TestFoo(t *testing.T) {
+	t.Fatal("foo")
+}
+
+func BenchmarkFoo(b *testing.B) {
+	b.Fatal("foo")
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "foo/foo_test.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`foo/foo_test.go:14: checkBar: calls t.Fatalf without t.Helper()`,
+		`foo/foo_test.go:20: checkBaz: calls tb.Error without tb.Helper()`,
+	}
+	var reported []string
+	checkFile(helperAnalyzer, nil, fset, "foo/foo_test.go", file, func(s string) {
+		reported = append(reported, s)
+	})
+	if !reflect.DeepEqual(reported, expected) {
+		t.Errorf("expected %q, got %q", expected, reported)
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build lint
+
+package build_test
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// knownOS and knownArch are the values of GOOS and GOARCH known to go/build,
+// which it matches against the build tags and the suffixes of the file names.
+var knownOS = map[string]bool{
+	"android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"linux": true, "nacl": true, "netbsd": true, "openbsd": true,
+	"plan9": true, "solaris": true, "windows": true, "zos": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true,
+	"arm64": true, "arm64be": true, "ppc64": true, "ppc64le": true, "mips": true,
+	"mipsle": true, "mips64": true, "mips64le": true, "mips64p32": true,
+	"mips64p32le": true, "ppc": true, "s390": true, "s390x": true, "sparc": true,
+	"sparc64": true,
+}
+
+// A buildTagRule requires the files whose path, relative to the root of the
+// repository, matches re to be excluded from the builds with the given tags.
+type buildTagRule struct {
+	re      *regexp.Regexp
+	tags    map[string]bool
+	message string
+	fix     string
+}
+
+// buildTagRules are the build tags required by the files whose name or
+// directory says they're restricted to some builds. go/build only restricts
+// the files whose name ends with a GOOS or GOARCH, and not e.g. *_unix.go.
+var buildTagRules = []buildTagRule{
+	{
+		re:      regexp.MustCompile(`^build/[^/]+\.go$`),
+		tags:    map[string]bool{"lint": false},
+		message: "file is built without the lint tag",
+		fix:     `add "// +build lint"`,
+	},
+	{
+		re:      regexp.MustCompile(`_unix(_test)?\.go$`),
+		tags:    map[string]bool{"windows": true},
+		message: "unix file is built on windows",
+		fix:     `add "// +build !windows"`,
+	},
+}
+
+// buildConstraints are the build constraints of a file: the +build lines
+// that go/build honors, and those it ignores.
+type buildConstraints struct {
+	// lines are the arguments of the +build lines honored by go/build, i.e.
+	// those before the package clause and followed by a blank line.
+	lines []string
+	// ignored are the positions of the other +build lines.
+	ignored []token.Pos
+}
+
+// parseBuildConstraints returns the build constraints of the file, whose
+// source is src. Like go/build, it only honors the +build lines in the
+// leading run of line comments and blank lines which ends with a blank line.
+func parseBuildConstraints(fset *token.FileSet, file *ast.File, src []byte) buildConstraints {
+	end := 0
+	for p := src; len(p) > 0; {
+		line := p
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line, p = line[:i], p[i+1:]
+		} else {
+			p = p[len(p):]
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			end = len(src) - len(p)
+			continue
+		}
+		if !bytes.HasPrefix(line, []byte("//")) {
+			break
+		}
+	}
+
+	var c buildConstraints
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if !strings.HasPrefix(comment.Text, "//") {
+				continue
+			}
+			text := strings.TrimSpace(comment.Text[2:])
+			if !strings.HasPrefix(text, "+build") {
+				continue
+			}
+			args := strings.TrimPrefix(text, "+build")
+			if args != "" && args[0] != ' ' && args[0] != '\t' {
+				// E.g. "+buildfoo", which isn't a constraint.
+				continue
+			}
+			if fset.Position(comment.Pos()).Offset < end {
+				c.lines = append(c.lines, strings.TrimSpace(args))
+			} else {
+				c.ignored = append(c.ignored, comment.Pos())
+			}
+		}
+	}
+	return c
+}
+
+// fileOSArch returns the GOOS and GOARCH the name of the file restricts it
+// to, if any, like go/build.
+func fileOSArch(name string) (goos, goarch string) {
+	if dot := strings.Index(name, "."); dot >= 0 {
+		name = name[:dot]
+	}
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return "", ""
+	}
+	l := strings.Split(name[i:], "_")
+	if n := len(l); n > 0 && l[n-1] == "test" {
+		l = l[:n-1]
+	}
+	n := len(l)
+	if n >= 2 && knownOS[l[n-2]] && knownArch[l[n-1]] {
+		return l[n-2], l[n-1]
+	}
+	if n >= 1 {
+		if knownOS[l[n-1]] {
+			return l[n-1], ""
+		}
+		if knownArch[l[n-1]] {
+			return "", l[n-1]
+		}
+	}
+	return "", ""
+}
+
+// buildContext is an assignment of the build tags. goos and goarch are empty
+// if they're none of those the constraints mention.
+type buildContext struct {
+	goos, goarch string
+	tags         map[string]bool
+}
+
+func (ctx buildContext) match(tag string) bool {
+	switch {
+	case knownOS[tag]:
+		return tag == ctx.goos || (tag == "linux" && ctx.goos == "android")
+	case knownArch[tag]:
+		return tag == ctx.goarch
+	default:
+		return ctx.tags[tag]
+	}
+}
+
+// matchLine returns whether the arguments of a +build line match the
+// context: the line is an OR of space-separated options, each an AND of
+// comma-separated, possibly negated, tags.
+func (ctx buildContext) matchLine(line string) bool {
+	for _, option := range strings.Fields(line) {
+		ok := true
+		for _, tag := range strings.Split(option, ",") {
+			if strings.HasPrefix(tag, "!") {
+				ok = ok && !ctx.match(tag[1:])
+			} else {
+				ok = ok && ctx.match(tag)
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// buildable returns whether a file named name, with the given +build lines,
+// is part of any build in which the tags in fixed are set as given.
+func buildable(name string, lines []string, fixed map[string]bool) bool {
+	fileOS, fileArch := fileOSArch(name)
+	oses := []string{fileOS}
+	arches := []string{fileArch}
+	tagSet := make(map[string]bool)
+	for _, line := range lines {
+		for _, option := range strings.Fields(line) {
+			for _, tag := range strings.Split(option, ",") {
+				tag = strings.TrimPrefix(tag, "!")
+				switch {
+				case knownOS[tag]:
+					if fileOS == "" {
+						oses = append(oses, tag)
+					}
+				case knownArch[tag]:
+					if fileArch == "" {
+						arches = append(arches, tag)
+					}
+				default:
+					tagSet[tag] = true
+				}
+			}
+		}
+	}
+	for tag, v := range fixed {
+		switch {
+		case !knownOS[tag]:
+		case v:
+			if fileOS != "" && fileOS != tag {
+				return false
+			}
+			oses = []string{tag}
+		default:
+			var rest []string
+			for _, goos := range oses {
+				if goos != tag {
+					rest = append(rest, goos)
+				}
+			}
+			oses = rest
+		}
+	}
+	var tags []string
+	for tag := range tagSet {
+		if _, ok := fixed[tag]; !ok {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	// Try all the combinations of the free tags, which are few.
+	for _, goos := range oses {
+		for _, goarch := range arches {
+			for bits := 0; bits < 1<<uint(len(tags)); bits++ {
+				ctx := buildContext{goos: goos, goarch: goarch, tags: make(map[string]bool)}
+				for tag, v := range fixed {
+					ctx.tags[tag] = v
+				}
+				for i, tag := range tags {
+					ctx.tags[tag] = bits&(1<<uint(i)) != 0
+				}
+				ok := true
+				for _, line := range lines {
+					ok = ok && ctx.matchLine(line)
+				}
+				if ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// checkBuildTags checks the build constraints of the file at path, relative to
+// the root of the repository, whose source is src. It reports the problems
+// through report, located in the file called name.
+func checkBuildTags(
+	fset *token.FileSet,
+	path, name string,
+	file *ast.File,
+	src []byte,
+	report func(s, message, fix string),
+) {
+	c := parseBuildConstraints(fset, file, src)
+	for _, pos := range c.ignored {
+		p := fset.Position(pos)
+		report(fmt.Sprintf("%s:%d:%d", name, p.Line, p.Column), "build constraint is ignored",
+			"move it before the package clause and its doc, followed by a blank line")
+	}
+	base := path[strings.LastIndex(path, "/")+1:]
+	if !buildable(base, c.lines, nil) {
+		report(name, "build constraints can never be satisfied",
+			"remove the conflicting tags")
+		return
+	}
+	for _, rule := range buildTagRules {
+		if rule.re.MatchString(path) && buildable(base, c.lines, rule.tags) {
+			report(name, rule.message, rule.fix)
+		}
+	}
+}
+
+func TestBuildTags(t *testing.T) {
+	testCases := []struct {
+		path string
+		src  string
+		errs []string
+	}{
+		{"pkg/foo.go", "package foo\n", nil},
+		{"pkg/foo_unix.go", "// +build !windows\n\npackage foo\n", nil},
+		{"pkg/foo_unix.go", "package foo\n", []string{
+			"foo_unix.go <- unix file is built on windows",
+		}},
+		{"pkg/foo_unix_test.go", "// +build linux darwin\n\npackage foo\n", nil},
+		{"pkg/foo_unix.go", "// +build !linux\n\npackage foo\n", []string{
+			"foo_unix.go <- unix file is built on windows",
+		}},
+		{"build/foo_test.go", "// +build lint\n\npackage foo\n", nil},
+		{"build/foo_test.go", "// +build lint,!windows\n\npackage foo\n", nil},
+		{"build/foo_test.go", "// +build lint race\n\npackage foo\n", []string{
+			"foo_test.go <- file is built without the lint tag",
+		}},
+		{"pkg/foo.go", "// +build race,!race\n\npackage foo\n", []string{
+			"foo.go <- build constraints can never be satisfied",
+		}},
+		{"pkg/foo.go", "// +build linux\n// +build darwin\n\npackage foo\n", []string{
+			"foo.go <- build constraints can never be satisfied",
+		}},
+		{"pkg/foo_windows.go", "// +build linux\n\npackage foo\n", []string{
+			"foo_windows.go <- build constraints can never be satisfied",
+		}},
+		{"pkg/foo_linux.go", "// +build !windows\n\npackage foo\n", nil},
+		{"pkg/foo_amd64.go", "// +build linux\n\npackage foo\n", nil},
+		{"pkg/foo_amd64.go", "// +build 386\n\npackage foo\n", []string{
+			"foo_amd64.go <- build constraints can never be satisfied",
+		}},
+		{"pkg/windows.go", "// +build linux\n\npackage foo\n", nil},
+		// The +build lines must be followed by a blank line.
+		{"pkg/foo.go", "// +build race\npackage foo\n", []string{
+			"foo.go:1:1 <- build constraint is ignored",
+		}},
+		{"pkg/foo.go", "// +build race\n// Package foo does things.\npackage foo\n", []string{
+			"foo.go:1:1 <- build constraint is ignored",
+		}},
+		{"pkg/foo.go", "package foo\n\n// +build race\n", []string{
+			"foo.go:3:1 <- build constraint is ignored",
+		}},
+		{"pkg/foo.go", "// +buildfoo\n\npackage foo\n", nil},
+		{"pkg/foo.go", "package foo\n\nconst s = `\n// +build race\n`\n", nil},
+	}
+	for i, tc := range testCases {
+		fset := token.NewFileSet()
+		name := tc.path[strings.LastIndex(tc.path, "/")+1:]
+		file, err := parser.ParseFile(fset, name, tc.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var errs []string
+		checkBuildTags(fset, tc.path, name, file, []byte(tc.src), func(s, message, fix string) {
+			errs = append(errs, s+" <- "+message)
+		})
+		if len(errs) != len(tc.errs) {
+			t.Errorf("%d: %s: expected %q, got %q", i, tc.path, tc.errs, errs)
+			continue
+		}
+		for j := range errs {
+			if errs[j] != tc.errs[j] {
+				t.Errorf("%d: %s: expected %q, got %q", i, tc.path, tc.errs, errs)
+				break
+			}
+		}
+	}
+}
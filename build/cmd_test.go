@@ -0,0 +1,157 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build lint
+
+package build_test
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// checkTimeout is the time after which the commands run by the checks are
+// killed, so that a hanging tool (e.g. errcheck on a broken GOPATH) fails its
+// check instead of hanging the whole lint run. It is set by
+// LINT_CHECK_TIMEOUT, and 0 disables it.
+var checkTimeout = 5 * time.Minute
+
+// A lintCmd is an exec.Cmd run in its own process group, which is killed as a
+// whole once the command has run for checkTimeout: killing only the command
+// could leave its children holding its output open.
+type lintCmd struct {
+	*exec.Cmd
+	cancel func()
+	done   chan struct{}
+	// timedOut is set, atomically, when the process group is killed.
+	timedOut int32
+}
+
+func command(dir string, name string, args ...string) *lintCmd {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	setpgid(cmd)
+	return &lintCmd{Cmd: cmd}
+}
+
+// timeoutError is the error of a lintCmd which was killed for running for
+// longer than checkTimeout.
+type timeoutError struct {
+	args    []string
+	timeout time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", strings.Join(e.args, " "), e.timeout)
+}
+
+func isTimeout(err error) bool {
+	_, ok := err.(*timeoutError)
+	return ok
+}
+
+// Start starts the command and the timer killing its process group.
+func (c *lintCmd) Start() error {
+	if err := c.Cmd.Start(); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if checkTimeout > 0 {
+		ctx, c.cancel = context.WithTimeout(ctx, checkTimeout)
+	} else {
+		ctx, c.cancel = context.WithCancel(ctx)
+	}
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		<-ctx.Done()
+		if ctx.Err() == context.DeadlineExceeded {
+			atomic.StoreInt32(&c.timedOut, 1)
+			// The group may be gone already if the command just exited.
+			_ = killGroup(c.Process)
+		}
+	}()
+	return nil
+}
+
+// Wait waits for the command to exit. It returns a *timeoutError if the
+// command was killed for timing out.
+func (c *lintCmd) Wait() error {
+	err := c.Cmd.Wait()
+	c.cancel()
+	<-c.done
+	if atomic.LoadInt32(&c.timedOut) != 0 {
+		return &timeoutError{args: c.Args, timeout: checkTimeout}
+	}
+	return err
+}
+
+// Run starts the command and waits for it to exit.
+func (c *lintCmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Output runs the command and returns its output. Like exec.Cmd.Output, it
+// captures the standard error of the command in the returned *exec.ExitError
+// if it isn't redirected.
+func (c *lintCmd) Output() ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	captureErr := c.Stderr == nil
+	if captureErr {
+		c.Stderr = &stderr
+	}
+	err := c.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && captureErr {
+		exitErr.Stderr = stderr.Bytes()
+	}
+	return stdout.Bytes(), err
+}
+
+func TestLintCmdTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { checkTimeout = timeout }(checkTimeout)
+	checkTimeout = 100 * time.Millisecond
+
+	if err := command("", "true").Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sleep in the background keeps the output open, so reading it only
+	// ends if the whole process group is killed.
+	cmd := command("", "sh", "-c", "sleep 60 & echo started; wait")
+	start := time.Now()
+	out, err := cmd.Output()
+	if !isTimeout(err) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Errorf("expected the command to be killed after %s, took %s", checkTimeout, elapsed)
+	}
+	if e := "started\n"; string(out) != e {
+		t.Errorf("expected output %q, got %q", e, out)
+	}
+	if e := "sh -c sleep 60 & echo started; wait timed out after 100ms"; err.Error() != e {
+		t.Errorf("expected error %q, got %q", e, err)
+	}
+}
@@ -12,19 +12,19 @@
 // implied. See the License for the specific language governing
 // permissions and limitations under the License.
 
-package main
+// +build lint
+
+package build_test
 
 import (
 	"os"
-
-	"github.com/cockroachdb/returncheck"
-	"github.com/kisielk/gotool"
+	"os/exec"
 )
 
-func main() {
-	targetPkg := "github.com/cockroachdb/cockroach/pkg/roachpb"
-	targetTypeName := "Error"
-	if err := returncheck.Run(gotool.ImportPaths(os.Args[1:]), targetPkg, targetTypeName); err != nil {
-		os.Exit(1)
-	}
+// setpgid is a no-op: there are no process groups on Windows.
+func setpgid(cmd *exec.Cmd) {}
+
+// killGroup kills the process only: there are no process groups on Windows.
+func killGroup(p *os.Process) error {
+	return p.Kill()
 }
@@ -171,7 +171,7 @@ $(BOOTSTRAP_TARGET): $(GITHOOKS) $(REPO_ROOT)/Gopkg.lock
 ifneq ($(GIT_DIR),)
 	git submodule update --init
 endif
-	@$(GO_INSTALL) -v $(PKG_ROOT)/cmd/{metacheck,ncpus,returncheck} \
+	@$(GO_INSTALL) -v $(PKG_ROOT)/cmd/ncpus \
 		"./vendor/github.com/client9/misspell/cmd/misspell" \
 		"./vendor/github.com/cockroachdb/crlfmt" \
 		"./vendor/github.com/cockroachdb/stress" \
@@ -0,0 +1,251 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build lint
+
+package build_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lintFix is set if LINT_FIX is, in which case the checks that have a
+// mechanical fix rewrite the offending files in place instead of failing.
+var lintFix = os.Getenv("LINT_FIX") != ""
+
+// fixFile applies fix to the file at path, relative to dir, and logs that it
+// was modified.
+func fixFile(t *testing.T, dir, path string, fix func(string) error) {
+	if err := fix(filepath.Join(dir, path)); err != nil {
+		t.Errorf("%s: unable to fix: %s", path, err)
+		return
+	}
+	t.Logf("%s <- fixed", path)
+}
+
+// rewriteFile replaces the contents of the file at path, keeping its mode.
+func rewriteFile(path string, src []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, src, info.Mode())
+}
+
+const apacheLicenseHeader = `// Copyright %d The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+`
+
+const cclLicenseHeader = `// Copyright %d The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+`
+
+// addLicenseHeader returns a fix which prepends the license header of the
+// current year to a file: the CCL one if ccl is set, the Apache one otherwise.
+func addLicenseHeader(ccl bool) func(string) error {
+	header := apacheLicenseHeader
+	if ccl {
+		header = cclLicenseHeader
+	}
+	header = fmt.Sprintf(header, time.Now().Year())
+	return func(path string) error {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return rewriteFile(path, append([]byte(header), src...))
+	}
+}
+
+// aliasDatabaseSQL imports "database/sql" as "gosql" in the Go file at path,
+// and renames its references accordingly.
+func aliasDatabaseSQL(path string) error {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	var spec *ast.ImportSpec
+	for _, s := range f.Imports {
+		if s.Path.Value == `"database/sql"` {
+			spec = s
+		}
+	}
+	if spec == nil {
+		return nil
+	}
+	name := "sql"
+	if spec.Name != nil {
+		name = spec.Name.Name
+	}
+	switch name {
+	case "gosql":
+		return nil
+	case "_", ".":
+		return errors.Errorf("can't rename the %s import of database/sql", name)
+	}
+
+	type edit struct {
+		pos  token.Pos
+		end  token.Pos
+		text string
+	}
+	var edits []edit
+	if spec.Name != nil {
+		edits = append(edits, edit{spec.Name.Pos(), spec.Name.End(), "gosql"})
+	} else {
+		edits = append(edits, edit{spec.Path.Pos(), spec.Path.Pos(), "gosql "})
+	}
+	// The references to the package are the unresolved identifiers which are
+	// the operands of selectors.
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Obj == nil && id.Name == name {
+				edits = append(edits, edit{id.Pos(), id.End(), "gosql"})
+			}
+		}
+		return true
+	})
+	sort.Slice(edits, func(i, j int) bool { return edits[i].pos > edits[j].pos })
+	base := fset.File(f.Pos()).Base()
+	for _, e := range edits {
+		start, end := int(e.pos)-base, int(e.end)-base
+		src = append(src[:start], append([]byte(e.text), src[end:]...)...)
+	}
+	// Realign the imports.
+	if src, err = format.Source(src); err != nil {
+		return err
+	}
+	return rewriteFile(path, src)
+}
+
+func TestLintFixes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lintfix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	path := filepath.Join(dir, "foo.go")
+	write := func(src string) {
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(expected string) {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(src) != expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", expected, src)
+		}
+	}
+
+	const src = `package foo
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// open opens a sql.DB.
+func open(url string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return nil, fmt.Errorf("sql: %s", err)
+	}
+	return db, nil
+}
+
+func count(sql string) string { return sql + "." }
+`
+	write(src)
+	if err := aliasDatabaseSQL(path); err != nil {
+		t.Fatal(err)
+	}
+	check(`package foo
+
+import (
+	gosql "database/sql"
+	"fmt"
+)
+
+// open opens a sql.DB.
+func open(url string) (*gosql.DB, error) {
+	db, err := gosql.Open("postgres", url)
+	if err != nil {
+		return nil, fmt.Errorf("sql: %s", err)
+	}
+	return db, nil
+}
+
+func count(sql string) string { return sql + "." }
+`)
+
+	write("package foo\n\nimport db \"database/sql\"\n\nvar _ db.Result\n")
+	if err := aliasDatabaseSQL(path); err != nil {
+		t.Fatal(err)
+	}
+	check("package foo\n\nimport gosql \"database/sql\"\n\nvar _ gosql.Result\n")
+
+	write("package foo\n\nimport _ \"database/sql\"\n")
+	if err := aliasDatabaseSQL(path); err == nil || !strings.Contains(err.Error(), "can't rename the _ import") {
+		t.Errorf("expected an error, got %v", err)
+	}
+
+	write("package foo\n")
+	if err := addLicenseHeader(true)(path); err != nil {
+		t.Fatal(err)
+	}
+	check(fmt.Sprintf(cclLicenseHeader, time.Now().Year()) + "package foo\n")
+}
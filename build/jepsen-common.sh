@@ -1,3 +1,4 @@
+# shellcheck shell=bash
 # Source this file from one of the other jepsen scripts
 
 PS4="+($(basename $0)) "
@@ -0,0 +1,491 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build lint
+
+package build_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ghemawat/stream"
+	"github.com/pkg/errors"
+)
+
+// A lintCache caches the output of a check which runs an external command,
+// by unit (e.g. file or package) checked. The entries are keyed by the hash of
+// the command, i.e. its binary and arguments, and of the files the output on
+// the unit depends on, so they never need to be invalidated: the cache
+// directory can be deleted at any time.
+type lintCache struct {
+	// dir is the directory of the entries, or empty if the cache is disabled.
+	dir  string
+	salt []byte
+
+	mu struct {
+		sync.Mutex
+		// hashes memoizes the hashes of the files and directories.
+		hashes map[string][]byte
+	}
+}
+
+// newLintCache returns the cache, in dir, of the check named check, which
+// runs the command name with the given arguments. The cache is disabled if
+// dir is empty.
+func newLintCache(dir, check, name string, args ...string) (*lintCache, error) {
+	c := &lintCache{}
+	c.mu.hashes = make(map[string][]byte)
+	if dir == "" {
+		return c, nil
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	if err := hashFile(h, path); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(h, "%q\n", args)
+	c.dir = filepath.Join(dir, check)
+	c.salt = h.Sum(nil)
+	return c, nil
+}
+
+func hashFile(h hash.Hash, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
+
+// fileHash returns the hash of the contents of the file at path.
+func (c *lintCache) fileHash(path string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sum, ok := c.mu.hashes[path]; ok {
+		return sum, nil
+	}
+	h := sha256.New()
+	if err := hashFile(h, path); err != nil {
+		return nil, err
+	}
+	sum := h.Sum(nil)
+	c.mu.hashes[path] = sum
+	return sum, nil
+}
+
+// dirHash returns the hash of the names and contents of the Go files in dir.
+func (c *lintCache) dirHash(dir string) ([]byte, error) {
+	c.mu.Lock()
+	sum, ok := c.mu.hashes[dir]
+	c.mu.Unlock()
+	if ok {
+		return sum, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	for _, f := range files {
+		fileSum, err := c.fileHash(f)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(h, "%q %x\n", filepath.Base(f), fileSum)
+	}
+	sum = h.Sum(nil)
+	c.mu.Lock()
+	c.mu.hashes[dir] = sum
+	c.mu.Unlock()
+	return sum, nil
+}
+
+// key returns the key of the output of the check on the unit, given the
+// hashes of what it depends on.
+func (c *lintCache) key(unit string, sums ...[]byte) string {
+	h := sha256.New()
+	_, _ = h.Write(c.salt)
+	fmt.Fprintf(h, "%q\n", unit)
+	for _, sum := range sums {
+		_, _ = h.Write(sum)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fileKey returns the key of the output of the check on the file at path,
+// relative to dir.
+func (c *lintCache) fileKey(dir string) func(path string) (string, error) {
+	return func(path string) (string, error) {
+		sum, err := c.fileHash(filepath.Join(dir, path))
+		if err != nil {
+			return "", err
+		}
+		return c.key(path, sum), nil
+	}
+}
+
+func (c *lintCache) get(key string) ([]string, bool) {
+	b, err := ioutil.ReadFile(filepath.Join(c.dir, key))
+	if err != nil {
+		return nil, false
+	}
+	if len(b) == 0 {
+		return nil, true
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n"), true
+}
+
+// put writes the entry atomically, so that a concurrent or interrupted run
+// never reads a partial one.
+func (c *lintCache) put(key string, lines []string) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(c.dir, key)
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(f, l); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(c.dir, key))
+}
+
+// run returns the output lines of the check on the units, whose keys are
+// returned by key. Only the units whose output isn't cached are checked, by
+// check, whose output lines are attributed to the units they're about by
+// unitOf. The output is only cached if all its lines are attributed to the
+// units checked: a check whose output can't be attributed only caches the
+// units when its output on them is empty.
+func (c *lintCache) run(
+	units []string,
+	key func(unit string) (string, error),
+	check func(units []string) ([]string, error),
+	unitOf func(line string) string,
+) ([]string, error) {
+	if c.dir == "" {
+		return check(units)
+	}
+	var out, missed []string
+	keys := make(map[string]string, len(units))
+	for _, u := range units {
+		k, err := key(u)
+		if err != nil {
+			return nil, err
+		}
+		if lines, ok := c.get(k); ok {
+			out = append(out, lines...)
+			continue
+		}
+		keys[u] = k
+		missed = append(missed, u)
+	}
+	if len(missed) == 0 {
+		return out, nil
+	}
+	lines, err := check(missed)
+	if err != nil {
+		return nil, err
+	}
+	byUnit := make(map[string][]string, len(missed))
+	for _, l := range lines {
+		u := unitOf(l)
+		if _, ok := keys[u]; !ok {
+			// The output can't be attributed.
+			return append(out, lines...), nil
+		}
+		byUnit[u] = append(byUnit[u], l)
+	}
+	for _, u := range missed {
+		if err := c.put(keys[u], byUnit[u]); err != nil {
+			return nil, err
+		}
+	}
+	return append(out, lines...), nil
+}
+
+// outputLines runs the command in dir and returns the lines of its output. It
+// fails if the command writes to its standard error, or times out.
+func outputLines(dir, name string, args ...string) ([]string, error) {
+	cmd, stderr, filter, err := dirCmd(dir, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	var lines []string
+	if err := stream.ForEach(filter, func(s string) {
+		lines = append(lines, s)
+	}); err != nil {
+		return nil, err
+	}
+	if err := cmd.Wait(); err != nil {
+		if out := stderr.String(); len(out) > 0 || isTimeout(err) {
+			return nil, errors.Errorf("err=%s, stderr=%s", err, out)
+		}
+	}
+	return lines, nil
+}
+
+// linePath returns the path, relative to dir, of the file a line of output of
+// a check is about, i.e. the path it starts with, relative to dir or not.
+func linePath(dir, s string) string {
+	path := s
+	if i := strings.IndexByte(path, ':'); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.TrimPrefix(path, dir+string(filepath.Separator))
+	path = strings.TrimPrefix(path, "./")
+	return filepath.ToSlash(path)
+}
+
+// linePackage returns the relative import path ("./dir") of the package a
+// line of output of a check is about.
+func linePackage(dir, s string) string {
+	return "./" + filepath.ToSlash(filepath.Dir(linePath(dir, s)))
+}
+
+// goPackage is a package, as listed by go list.
+type goPackage struct {
+	Dir          string
+	ImportPath   string
+	Deps         []string
+	TestImports  []string
+	XTestImports []string
+}
+
+// listPackages returns the packages matching the patterns, relative to dir.
+func listPackages(dir string, patterns ...string) ([]goPackage, error) {
+	out, err := command(dir, "go", append([]string{"list", "-json"}, patterns...)...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, errors.Errorf("go list: %s", exitErr.Stderr)
+		}
+		return nil, err
+	}
+	var pkgs []goPackage
+	for dec := json.NewDecoder(bytes.NewReader(out)); ; {
+		var p goPackage
+		if err := dec.Decode(&p); err == io.EOF {
+			return pkgs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, p)
+	}
+}
+
+// packageUnits returns the relative import paths ("./dir") of the packages
+// matching the patterns, relative to dir, in sorted order, and the directories
+// of the packages by relative import path.
+func packageUnits(dir string, patterns []string) ([]string, map[string]string, error) {
+	pkgs, err := listPackages(dir, patterns...)
+	if err != nil {
+		return nil, nil, err
+	}
+	units := make([]string, 0, len(pkgs))
+	dirs := make(map[string]string, len(pkgs))
+	for _, p := range pkgs {
+		rel, err := filepath.Rel(dir, p.Dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		u := "./" + filepath.ToSlash(rel)
+		units = append(units, u)
+		dirs[u] = p.Dir
+	}
+	sort.Strings(units)
+	return units, dirs, nil
+}
+
+// packageKey returns the key of the output of the check on the package, by
+// relative import path, whose directory is given by dirs. The output only
+// depends on the files of the package.
+func (c *lintCache) packageKey(dirs map[string]string) func(unit string) (string, error) {
+	return func(unit string) (string, error) {
+		sum, err := c.dirHash(dirs[unit])
+		if err != nil {
+			return "", err
+		}
+		return c.key(unit, sum), nil
+	}
+}
+
+// typedPackageKey is like packageKey for the checks which type-check the
+// packages, whose output also depends on the files of the packages they
+// import. Those are the packages of the repository, as listed in pkgs, and
+// the vendored ones, as pinned by the file lock.
+func (c *lintCache) typedPackageKey(
+	dirs map[string]string, pkgs []goPackage, lock string,
+) func(unit string) (string, error) {
+	byPath := make(map[string]goPackage, len(pkgs))
+	byDir := make(map[string]goPackage, len(pkgs))
+	for _, p := range pkgs {
+		byPath[p.ImportPath] = p
+		byDir[p.Dir] = p
+	}
+	return func(unit string) (string, error) {
+		dir := dirs[unit]
+		p := byDir[dir]
+		// The test imports aren't in Deps, but their own deps are.
+		deps := make(map[string]bool)
+		for _, imports := range [][]string{p.Deps, p.TestImports, p.XTestImports} {
+			for _, path := range imports {
+				deps[path] = true
+				for _, d := range byPath[path].Deps {
+					deps[d] = true
+				}
+			}
+		}
+		depDirs := []string{dir}
+		for path := range deps {
+			if q, ok := byPath[path]; ok && q.Dir != dir {
+				depDirs = append(depDirs, q.Dir)
+			}
+		}
+		sort.Strings(depDirs[1:])
+
+		lockSum, err := c.fileHash(lock)
+		if err != nil {
+			return "", err
+		}
+		sums := [][]byte{lockSum}
+		for _, d := range depDirs {
+			sum, err := c.dirHash(d)
+			if err != nil {
+				return "", err
+			}
+			sums = append(sums, []byte(d), sum)
+		}
+		return c.key(unit, sums...), nil
+	}
+}
+
+func TestLintCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lint-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	srcDir := filepath.Join(dir, "src")
+	if err := os.Mkdir(srcDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path, src string) {
+		if err := ioutil.WriteFile(filepath.Join(srcDir, path), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.go", "package a // bad\n")
+	write("b.go", "package a\n")
+	write("c.go", "package a\n")
+	files := []string{"a.go", "b.go", "c.go"}
+
+	// The check reports the files containing "bad", and records the files
+	// it checked.
+	var checked []string
+	check := func(units []string) ([]string, error) {
+		checked = append(checked, units...)
+		var out []string
+		for _, u := range units {
+			src, err := ioutil.ReadFile(filepath.Join(srcDir, u))
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Contains(src, []byte("bad")) {
+				out = append(out, filepath.Join(srcDir, u)+":1:11: bad")
+			}
+		}
+		return out, nil
+	}
+	unitOf := func(s string) string { return linePath(srcDir, s) }
+	// run runs the check through a cache in cacheDir, for the command
+	// "sh -c cmd", as a lint run would. The hashes of the files are memoized
+	// for the duration of a run.
+	run := func(cacheDir, cmd string, expChecked []string, expOut ...string) {
+		cache, err := newLintCache(cacheDir, "test", "sh", "-c", cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checked = nil
+		out, err := cache.run(files, cache.fileKey(srcDir), check, unitOf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range out {
+			out[i] = linePath(srcDir, out[i])
+		}
+		if !reflect.DeepEqual(expChecked, checked) {
+			t.Errorf("expected %q to be checked, got %q", expChecked, checked)
+		}
+		if !reflect.DeepEqual(expOut, out) {
+			t.Errorf("expected output %q, got %q", expOut, out)
+		}
+	}
+
+	run(dir, "true", files, "a.go")
+	// The output on all the files is cached, including the failures.
+	run(dir, "true", nil, "a.go")
+	// Only the modified file is checked again.
+	write("b.go", "package a // bad\n")
+	run(dir, "true", []string{"b.go"}, "a.go", "b.go")
+	write("a.go", "package a\n")
+	run(dir, "true", []string{"a.go"}, "b.go")
+	// The entries are specific to the command.
+	run(dir, "false", files, "b.go")
+	// A disabled cache checks everything.
+	run("", "true", files, "b.go")
+	run("", "true", files, "b.go")
+
+	// Output which can't be attributed isn't cached.
+	unitOf = func(string) string { return "" }
+	write("c.go", "package a // bad\n")
+	run(dir, "true", []string{"c.go"}, "b.go", "c.go")
+	run(dir, "true", []string{"c.go"}, "b.go", "c.go")
+	// But that of the units on which it's empty is.
+	write("c.go", "package a // good\n")
+	run(dir, "true", []string{"c.go"}, "b.go")
+	run(dir, "true", nil, "b.go")
+}
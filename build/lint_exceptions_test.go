@@ -0,0 +1,348 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build lint
+
+package build_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ghemawat/stream"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// lintExceptionsFile is the file, relative to the build directory, holding the
+// exceptions to the lint checks.
+const lintExceptionsFile = "lint_exceptions.yaml"
+
+// lintChecks are the checks that exceptions can be configured for.
+var lintChecks = map[string]bool{
+	"envutil":          true,
+	"syncutil":         true,
+	"timeutil":         true,
+	"grpc":             true,
+	"protoclone":       true,
+	"protomarshal":     true,
+	"print":            true,
+	"fatal":            true,
+	"context":          true,
+	"errwrap":          true,
+	"sleep":            true,
+	"rand":             true,
+	"helper":           true,
+	"panic":            true,
+	"forbiddenimports": true,
+	"metacheck":        true,
+	"settings":         true,
+	"settingnames":     true,
+	"shellcheck":       true,
+}
+
+// A lintException exempts some files or packages from a lint check. See
+// lint_exceptions.yaml for the meaning of the fields.
+type lintException struct {
+	Path    string `yaml:"path"`
+	Import  string `yaml:"import"`
+	Check   string `yaml:"check"`
+	Setting string `yaml:"setting"`
+	Reason  string `yaml:"reason"`
+
+	pathRE *regexp.Regexp
+	mu     struct {
+		sync.Mutex
+		used bool
+	}
+}
+
+func (e *lintException) String() string {
+	s := e.Path
+	if e.Import != "" {
+		s += ": " + e.Import
+	}
+	if e.Check != "" {
+		s += ":" + e.Check
+	}
+	if e.Setting != "" {
+		s += ": " + e.Setting
+	}
+	return s
+}
+
+func (e *lintException) markUsed() {
+	e.mu.Lock()
+	e.mu.used = true
+	e.mu.Unlock()
+}
+
+func (e *lintException) isUsed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.mu.used
+}
+
+// lintExceptionList is the list of exceptions to a lint check.
+type lintExceptionList []*lintException
+
+// exempts returns true if the file or package at the given path, relative to
+// pkg/, is exempt from the check, marking the exceptions exempting it as used.
+// For forbiddenimports, importPath is the forbidden import.
+func (l lintExceptionList) exempts(path, importPath string) bool {
+	exempt := false
+	for _, e := range l {
+		if e.Import == importPath && e.pathRE.MatchString(path) {
+			e.markUsed()
+			exempt = true
+		}
+	}
+	return exempt
+}
+
+// filter returns a filter dropping the lines of output about the exempt files,
+// i.e. the lines starting with "<path>:".
+func (l lintExceptionList) filter() stream.Filter {
+	return stream.FilterFunc(func(arg stream.Arg) error {
+		for s := range arg.In {
+			path := s
+			if i := strings.IndexByte(path, ':'); i >= 0 {
+				path = path[:i]
+			}
+			if !l.exempts(path, "") {
+				arg.Out <- s
+			}
+		}
+		return nil
+	})
+}
+
+// checkUsed reports the exceptions that didn't exempt anything as lint
+// failures, so that stale exceptions get removed.
+func (l lintExceptionList) checkUsed(t *testing.T, report *lintReporter, check string) {
+	for _, e := range l {
+		if !e.isUsed() {
+			message := fmt.Sprintf("unused %s exception %q", check, e)
+			fix := "remove it from " + lintExceptionsFile
+			report.fail(t, lintFailure{
+				File:    path.Join("build", lintExceptionsFile),
+				Message: message,
+				Fix:     fix,
+			}, fmt.Sprintf("%s: %s; %s", lintExceptionsFile, message, fix))
+		}
+	}
+}
+
+// lintExceptions maps the lint checks to their exceptions.
+type lintExceptions map[string]lintExceptionList
+
+// parseLintExceptions parses and validates the exceptions to the lint checks.
+func parseLintExceptions(data []byte) (lintExceptions, error) {
+	var exceptions lintExceptions
+	if err := yaml.Unmarshal(data, &exceptions); err != nil {
+		return nil, err
+	}
+	for check, l := range exceptions {
+		if !lintChecks[check] {
+			return nil, errors.Errorf("unknown lint check %q", check)
+		}
+		for _, e := range l {
+			if e.Path == "" || e.Reason == "" {
+				return nil, errors.Errorf("%s exception %q needs a path and a reason", check, e)
+			}
+			if (e.Import != "") != (check == "forbiddenimports") {
+				return nil, errors.Errorf("%s exception %q: only forbiddenimports exceptions have an import",
+					check, e)
+			}
+			if (e.Setting != "") != (check == "settingnames") {
+				return nil, errors.Errorf("%s exception %q: only settingnames exceptions have a setting",
+					check, e)
+			}
+			hasCheck := check == "metacheck" || check == "shellcheck"
+			if (e.Check != "") != hasCheck {
+				return nil, errors.Errorf(
+					"%s exception %q: only metacheck and shellcheck exceptions have a check", check, e)
+			}
+			if hasCheck {
+				// The path is a glob, matched by exemptsCheck.
+				if _, err := path.Match(e.Path, ""); err != nil {
+					return nil, errors.Wrapf(err, "%s exception %q", check, e)
+				}
+				continue
+			}
+			var err error
+			if e.pathRE, err = regexp.Compile(`^(?:` + e.Path + `)$`); err != nil {
+				return nil, errors.Wrapf(err, "%s exception %q", check, e)
+			}
+		}
+	}
+	return exceptions, nil
+}
+
+// loadLintExceptions reads the exceptions to the lint checks from the given
+// build directory.
+func loadLintExceptions(buildDir string) (lintExceptions, error) {
+	path := filepath.Join(buildDir, lintExceptionsFile)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := parseLintExceptions(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", path)
+	}
+	return exceptions, nil
+}
+
+// exemptsCheck returns true if the file at the given path is exempt from the
+// given check of metacheck or shellcheck, marking the exceptions exempting it
+// as used. The path is relative to pkg/ for metacheck, and to the root of the
+// repository for shellcheck.
+func (l lintExceptionList) exemptsCheck(file, check string) bool {
+	exempt := false
+	for _, e := range l {
+		if ok, _ := path.Match(e.Path, file); ok && e.Check == check {
+			e.markUsed()
+			exempt = true
+		}
+	}
+	return exempt
+}
+
+// exemptsSetting returns true if the setting with the given key, registered in
+// the file at the given path relative to pkg/, is exempt from the check,
+// marking the exceptions exempting it as used.
+func (l lintExceptionList) exemptsSetting(path, key string) bool {
+	exempt := false
+	for _, e := range l {
+		if e.Setting == key && e.pathRE.MatchString(path) {
+			e.markUsed()
+			exempt = true
+		}
+	}
+	return exempt
+}
+
+func TestLintExceptions(t *testing.T) {
+	// The checked-in exceptions must be valid.
+	if _, err := loadLintExceptions("."); err != nil {
+		t.Fatal(err)
+	}
+
+	exceptions, err := parseLintExceptions([]byte(`
+envutil:
+  - path: cmd/.*\.go
+    reason: commands
+  - path: util/log/\w+\.go
+    reason: logging
+forbiddenimports:
+  - path: cli|security
+    import: syscall
+    reason: syscalls
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	envutil := exceptions["envutil"]
+	for _, tc := range []struct {
+		path   string
+		exempt bool
+	}{
+		{"cmd/foo/main.go", true},
+		{"sql/cmd/foo.go", false},
+		{"cmd/foo/main.go.orig", false},
+	} {
+		if exempt := envutil.exempts(tc.path, ""); exempt != tc.exempt {
+			t.Errorf("envutil %s: expected exempt=%t, got %t", tc.path, tc.exempt, exempt)
+		}
+	}
+	if !envutil[0].isUsed() || envutil[1].isUsed() {
+		t.Errorf("expected only the first envutil exception to be used")
+	}
+
+	imports := exceptions["forbiddenimports"]
+	if !imports.exempts("security", "syscall") || imports.exempts("security", "log") ||
+		imports.exempts("securitytest", "syscall") {
+		t.Errorf("unexpected forbiddenimports exemptions")
+	}
+
+	metacheck, err := parseLintExceptions([]byte(`
+metacheck:
+  - path: sql/*.go
+    check: SA1019
+    reason: deprecated
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := metacheck["metacheck"]
+	if !l.exemptsCheck("sql/foo.go", "SA1019") || l.exemptsCheck("sql/parser/foo.go", "SA1019") ||
+		l.exemptsCheck("sql/foo.go", "U1000") {
+		t.Errorf("unexpected metacheck exemptions")
+	}
+
+	shellcheck, err := parseLintExceptions([]byte(`
+shellcheck:
+  - path: build/*.sh
+    check: SC2148
+    reason: sourced
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l = shellcheck["shellcheck"]
+	if !l.exemptsCheck("build/common.sh", "SC2148") || l.exemptsCheck("scripts/common.sh", "SC2148") ||
+		l.exemptsCheck("build/common.sh", "SC2068") {
+		t.Errorf("unexpected shellcheck exemptions")
+	}
+
+	settingnames, err := parseLintExceptions([]byte(`
+settingnames:
+  - path: storage/\w+\.go
+    setting: kv.foo
+    reason: released
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l = settingnames["settingnames"]
+	if !l.exemptsSetting("storage/replica.go", "kv.foo") || l.exemptsSetting("sql/foo.go", "kv.foo") ||
+		l.exemptsSetting("storage/replica.go", "kv.bar") {
+		t.Errorf("unexpected settingnames exemptions")
+	}
+
+	for _, data := range []string{
+		"foo:\n  - path: bar\n    reason: baz\n",
+		"envutil:\n  - path: bar\n",
+		"envutil:\n  - path: bar\n    import: log\n    reason: baz\n",
+		"forbiddenimports:\n  - path: bar\n    reason: baz\n",
+		"metacheck:\n  - path: bar\n    reason: baz\n",
+		"shellcheck:\n  - path: bar\n    reason: baz\n",
+		"envutil:\n  - path: (\n    reason: baz\n",
+		"metacheck:\n  - path: '['\n    check: U1000\n    reason: baz\n",
+		"settingnames:\n  - path: bar\n    reason: baz\n",
+		"envutil:\n  - path: bar\n    setting: kv.foo\n    reason: baz\n",
+	} {
+		if _, err := parseLintExceptions([]byte(data)); err == nil {
+			t.Errorf("expected an error parsing %q", data)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build lint
+
+package build_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// printfuncsPkgs are the packages, relative to pkg/, whose exported
+// formatting functions are checked by go vet like those of fmt.
+var printfuncsPkgs = []string{"util/log", "util/tracing"}
+
+// extraPrintfuncs are the formatting functions checked by go vet which aren't
+// in printfuncsPkgs, in the format of -printfuncs.
+var extraPrintfuncs = []string{
+	"UnimplementedWithIssueErrorf:1",
+}
+
+// vetPrintfuncs returns the value of the -printfuncs flag of go vet: the
+// formatting functions of printfuncsPkgs, found in the packages under pkgDir,
+// followed by extraPrintfuncs.
+func vetPrintfuncs(pkgDir string) (string, error) {
+	fset := token.NewFileSet()
+	var funcs []string
+	for _, path := range printfuncsPkgs {
+		pkgs, err := parser.ParseDir(fset, filepath.Join(pkgDir, path), func(fi os.FileInfo) bool {
+			return !strings.HasSuffix(fi.Name(), "_test.go")
+		}, 0)
+		if err != nil {
+			return "", err
+		}
+		for _, pkg := range pkgs {
+			for _, file := range pkg.Files {
+				funcs = append(funcs, printfuncs(file)...)
+			}
+		}
+	}
+	sort.Strings(funcs)
+	return strings.Join(append(funcs, extraPrintfuncs...), ","), nil
+}
+
+// printfuncs returns the exported functions of the file which go vet should
+// check like those of fmt, as "<name>:<index>": the functions whose last
+// parameters are a format string and a ...interface{} are checked like
+// fmt.Printf, with index the position of the format. The other functions
+// whose last parameter is a ...interface{}, or a msg string, are checked like
+// fmt.Print, with index the position of that parameter.
+func printfuncs(file *ast.File) []string {
+	var funcs []string
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !fn.Name.IsExported() {
+			continue
+		}
+		// The names of the parameters, "" for those which are unnamed, and
+		// their types.
+		var names []string
+		var types []ast.Expr
+		for _, field := range fn.Type.Params.List {
+			if len(field.Names) == 0 {
+				names = append(names, "")
+				types = append(types, field.Type)
+			}
+			for _, name := range field.Names {
+				names = append(names, name.Name)
+				types = append(types, field.Type)
+			}
+		}
+		last := len(types) - 1
+		if last < 0 {
+			continue
+		}
+		isString := func(i int) bool {
+			id, ok := types[i].(*ast.Ident)
+			return ok && id.Name == "string"
+		}
+		if ellipsis, ok := types[last].(*ast.Ellipsis); ok {
+			if iface, ok := ellipsis.Elt.(*ast.InterfaceType); !ok || len(iface.Methods.List) > 0 {
+				continue
+			}
+			if last > 0 && names[last-1] == "format" && isString(last-1) {
+				funcs = append(funcs, fmt.Sprintf("%s:%d", fn.Name.Name, last-1))
+			} else {
+				funcs = append(funcs, fmt.Sprintf("%s:%d", fn.Name.Name, last))
+			}
+		} else if names[last] == "msg" && isString(last) {
+			funcs = append(funcs, fmt.Sprintf("%s:%d", fn.Name.Name, last))
+		}
+	}
+	return funcs
+}
+
+func TestPrintfuncs(t *testing.T) {
+	const src = `package log
+
+func Infof(ctx context.Context, format string, args ...interface{}) {}
+
+func Info(ctx context.Context, args ...interface{}) {}
+
+func VEventf(ctx context.Context, level int, format string, args ...interface{}) {}
+
+func Event(ctx context.Context, msg string) {}
+
+func Shout(ctx context.Context, sev, depth int, args ...interface{}) {}
+
+func MakeMessage(ctx context.Context, format string, args []interface{}) string { return "" }
+
+func Strings(strs ...string) {}
+
+func Errors(errs ...interface{ Error() string }) {}
+
+func Name(name string) {}
+
+func infof(format string, args ...interface{}) {}
+
+type T struct{}
+
+func (T) Infof(format string, args ...interface{}) {}
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "log.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Infof:1", "Info:1", "VEventf:2", "Event:1", "Shout:3"}
+	if funcs := printfuncs(file); !reflect.DeepEqual(funcs, expected) {
+		t.Errorf("expected %q, got %q", expected, funcs)
+	}
+}
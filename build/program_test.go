@@ -0,0 +1,1108 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build lint
+
+package build_test
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"unicode"
+
+	"github.com/ghemawat/stream"
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/loader"
+	"honnef.co/go/tools/gcsizes"
+	"honnef.co/go/tools/lint"
+	"honnef.co/go/tools/simple"
+	"honnef.co/go/tools/staticcheck"
+	"honnef.co/go/tools/unused"
+)
+
+var programs struct {
+	sync.Mutex
+	m map[string]*programResult
+}
+
+type programResult struct {
+	once sync.Once
+	prog *loader.Program
+	err  error
+}
+
+// loadProgram type-checks the packages matching the given patterns, relative
+// to dir, along with their tests, once, and shares the program between the
+// checks that need type information: errcheck, returncheck, protoequal,
+// settings and metacheck. Type-checking the whole tree takes a few GB of RAM,
+// which each of these checks used to spend on its own.
+func loadProgram(dir string, patterns []string) (*loader.Program, error) {
+	key := dir + ":" + strings.Join(patterns, " ")
+	programs.Lock()
+	if programs.m == nil {
+		programs.m = make(map[string]*programResult)
+	}
+	res, ok := programs.m[key]
+	if !ok {
+		res = &programResult{}
+		programs.m[key] = res
+	}
+	programs.Unlock()
+	res.once.Do(func() {
+		res.prog, res.err = typeCheck(dir, patterns)
+	})
+	return res.prog, res.err
+}
+
+func typeCheck(dir string, patterns []string) (*loader.Program, error) {
+	cmd, stderr, filter, err := dirCmd(dir, "go", append([]string{"list"}, patterns...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	var paths []string
+	if err := stream.ForEach(filter, func(s string) {
+		paths = append(paths, s)
+	}); err != nil {
+		return nil, err
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, errors.Wrapf(err, "go list: %s", stderr)
+	}
+
+	conf := loader.Config{
+		Build:      &build.Default,
+		Cwd:        dir,
+		ParserMode: parser.ParseComments,
+		TypeChecker: types.Config{
+			Sizes: gcsizes.ForArch(build.Default.GOARCH),
+		},
+	}
+	for _, path := range paths {
+		conf.ImportWithTests(path)
+	}
+	return conf.Load()
+}
+
+// A resultCheck reports the calls whose results of some types are discarded.
+type resultCheck struct {
+	// checked returns true for the types of the results that must be checked.
+	checked func(types.Type) bool
+	// blank is set if assigning such a result to the blank identifier doesn't
+	// count as checking it.
+	blank bool
+	// exempt, if not nil, returns true for the functions whose results needn't
+	// be checked.
+	exempt func(*types.Func) bool
+}
+
+// run reports the calls of the files discarding some results they must check.
+func (c *resultCheck) run(info *types.Info, files []*ast.File, report func(*ast.CallExpr)) {
+	// checkedResults returns, for each result of the call, whether it must be
+	// checked.
+	checkedResults := func(call *ast.CallExpr) []bool {
+		if fn := calledFunc(info, call); fn != nil && c.exempt != nil && c.exempt(fn) {
+			return nil
+		}
+		typ := info.TypeOf(call)
+		tuple, ok := typ.(*types.Tuple)
+		if !ok {
+			return []bool{typ != nil && c.checked(typ)}
+		}
+		results := make([]bool, tuple.Len())
+		for i := range results {
+			results[i] = c.checked(tuple.At(i).Type())
+		}
+		return results
+	}
+	discarded := func(call *ast.CallExpr) {
+		for _, checked := range checkedResults(call) {
+			if checked {
+				report(call)
+				return
+			}
+		}
+	}
+	isBlank := func(e ast.Expr) bool {
+		id, ok := e.(*ast.Ident)
+		return ok && id.Name == "_"
+	}
+
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch stmt := n.(type) {
+			case *ast.ExprStmt:
+				if call, ok := stmt.X.(*ast.CallExpr); ok {
+					discarded(call)
+				}
+			case *ast.GoStmt:
+				discarded(stmt.Call)
+			case *ast.DeferStmt:
+				discarded(stmt.Call)
+			case *ast.AssignStmt:
+				if !c.blank {
+					break
+				}
+				if len(stmt.Rhs) == 1 && len(stmt.Lhs) > 1 {
+					// A call returning multiple results, e.g. _, err := f().
+					call, ok := stmt.Rhs[0].(*ast.CallExpr)
+					if !ok {
+						break
+					}
+					for i, checked := range checkedResults(call) {
+						if checked && i < len(stmt.Lhs) && isBlank(stmt.Lhs[i]) {
+							report(call)
+							break
+						}
+					}
+					break
+				}
+				for i, rhs := range stmt.Rhs {
+					if call, ok := rhs.(*ast.CallExpr); ok && i < len(stmt.Lhs) && isBlank(stmt.Lhs[i]) {
+						discarded(call)
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// calledFunc returns the function or method called, or nil if the call is a
+// conversion or the call of a function value.
+func calledFunc(info *types.Info, call *ast.CallExpr) *types.Func {
+	var id *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil
+	}
+	fn, _ := info.Uses[id].(*types.Func)
+	return fn
+}
+
+var errorIface = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+// errcheckDefaultExcludes are the functions whose errors errcheck doesn't
+// report by default, in addition to those of package fmt.
+var errcheckDefaultExcludes = []string{
+	"(*bytes.Buffer).Write",
+	"(*bytes.Buffer).WriteByte",
+	"(*bytes.Buffer).WriteRune",
+	"(*bytes.Buffer).WriteString",
+	"math/rand.Read",
+	"(*math/rand.Rand).Read",
+	"(hash.Hash).Write",
+}
+
+// errCheck returns the check of errcheck: the errors returned by function
+// calls must be checked, except for those of the excluded functions, named
+// like "(*os.File).Close". As in errcheck, assigning an error to the blank
+// identifier counts as checking it.
+func errCheck(excludes map[string]bool) *resultCheck {
+	return &resultCheck{
+		checked: func(typ types.Type) bool {
+			switch typ.(type) {
+			case *types.Named, *types.Pointer:
+				return types.Implements(typ, errorIface)
+			}
+			return false
+		},
+		exempt: func(fn *types.Func) bool {
+			return (fn.Pkg() != nil && fn.Pkg().Path() == "fmt") || excludes[fn.FullName()]
+		},
+	}
+}
+
+// loadErrcheckExcludes reads the functions excluded from errcheck, one per
+// line, from the given file, and adds errcheck's default exclusions.
+func loadErrcheckExcludes(path string) (map[string]bool, error) {
+	excludes := make(map[string]bool)
+	for _, name := range errcheckDefaultExcludes {
+		excludes[name] = true
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" && !strings.HasPrefix(name, "//") {
+			excludes[name] = true
+		}
+	}
+	return excludes, scanner.Err()
+}
+
+// returnCheck returns the check of returncheck: the results of the given
+// pointer type, such as *roachpb.Error, must be checked, and can't be
+// assigned to the blank identifier.
+func returnCheck(pkgPath, typeName string) *resultCheck {
+	return &resultCheck{
+		checked: func(typ types.Type) bool {
+			ptr, ok := typ.(*types.Pointer)
+			if !ok {
+				return false
+			}
+			named, ok := ptr.Elem().(*types.Named)
+			if !ok {
+				return false
+			}
+			obj := named.Obj()
+			return obj.Pkg() != nil && obj.Pkg().Path() == pkgPath && obj.Name() == typeName
+		},
+		blank: true,
+	}
+}
+
+// runResultCheck runs the check on the initial packages of the program, and
+// reports the calls discarding results as "<path>:<line>:<col>: <call>", with
+// paths relative to dir.
+func runResultCheck(prog *loader.Program, dir string, c *resultCheck, report func(path, s string)) {
+	for _, pkgInfo := range prog.InitialPackages() {
+		c.run(&pkgInfo.Info, pkgInfo.Files, func(call *ast.CallExpr) {
+			pos := prog.Fset.Position(call.Lparen)
+			path := relPath(dir, pos.Filename)
+			report(path, fmt.Sprintf("%s:%d:%d: %s", path, pos.Line, pos.Column, types.ExprString(call)))
+		})
+	}
+}
+
+// unpredictableProto returns the first proto message reachable from typ,
+// through pointers, slices, arrays, maps and struct fields, which has XXX_
+// fields, or nil if there is none. The XXX_ fields hold the unrecognized
+// fields and the caches of the generated code, which make reflect.DeepEqual
+// report messages which are equal as different, depending on whether they
+// were marshaled or received from another version. The messages generated
+// without such fields compare correctly.
+func unpredictableProto(typ types.Type, seen map[types.Type]bool) *types.Named {
+	if seen[typ] {
+		return nil
+	}
+	seen[typ] = true
+	switch t := typ.(type) {
+	case *types.Pointer:
+		return unpredictableProto(t.Elem(), seen)
+	case *types.Slice:
+		return unpredictableProto(t.Elem(), seen)
+	case *types.Array:
+		return unpredictableProto(t.Elem(), seen)
+	case *types.Map:
+		if named := unpredictableProto(t.Key(), seen); named != nil {
+			return named
+		}
+		return unpredictableProto(t.Elem(), seen)
+	case *types.Named:
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok {
+			return nil
+		}
+		if obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, "ProtoMessage"); obj != nil {
+			for i := 0; i < st.NumFields(); i++ {
+				if strings.HasPrefix(st.Field(i).Name(), "XXX_") {
+					return t
+				}
+			}
+		}
+		return unpredictableProto(st, seen)
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if named := unpredictableProto(t.Field(i).Type(), seen); named != nil {
+				return named
+			}
+		}
+	}
+	return nil
+}
+
+// protoEqualCheck reports the calls to reflect.DeepEqual comparing proto
+// messages with XXX_ fields (see unpredictableProto), along with the message
+// and its fix.
+func protoEqualCheck(info *types.Info, files []*ast.File, report func(*ast.CallExpr, string, string)) {
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if fn := calledFunc(info, call); fn == nil || fn.FullName() != "reflect.DeepEqual" {
+				return true
+			}
+			for _, arg := range call.Args {
+				typ := info.TypeOf(arg)
+				if typ == nil {
+					continue
+				}
+				msg := unpredictableProto(typ, make(map[types.Type]bool))
+				if msg == nil {
+					continue
+				}
+				name := types.TypeString(msg, (*types.Package).Name)
+				fix := `use "proto.Equal" instead`
+				if obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(msg), true, nil, "Equal"); obj != nil {
+					fix = fmt.Sprintf(`use "proto.Equal" or "(*%s).Equal" instead`, name)
+				}
+				report(call, fmt.Sprintf("reflect.DeepEqual on %s, which has XXX_ fields", name), fix)
+				break
+			}
+			return true
+		})
+	}
+}
+
+// runProtoEqualCheck runs protoEqualCheck on the initial packages of the
+// program, and reports the calls as "<path>:<line>:<col>: <call>", with paths
+// relative to dir.
+func runProtoEqualCheck(prog *loader.Program, dir string, report func(path, s, message, fix string)) {
+	for _, pkgInfo := range prog.InitialPackages() {
+		protoEqualCheck(&pkgInfo.Info, pkgInfo.Files, func(call *ast.CallExpr, message, fix string) {
+			pos := prog.Fset.Position(call.Lparen)
+			path := relPath(dir, pos.Filename)
+			report(path, fmt.Sprintf("%s:%d:%d: %s", path, pos.Line, pos.Column, types.ExprString(call)), message, fix)
+		})
+	}
+}
+
+const settingsPath = cockroachDB + "/settings"
+
+// A registeredSetting is a call to one of the settings.Register functions.
+type registeredSetting struct {
+	call *ast.CallExpr
+	// fn is the name of the function called, e.g. RegisterBoolSetting.
+	fn  string
+	key string
+	// desc is the description of the setting, if it is a constant.
+	desc    string
+	hasDesc bool
+	// obj is the package-level variable holding the setting, if any.
+	obj types.Object
+}
+
+// registeredSettings returns the cluster settings registered outside of tests
+// and of the settings package, in order. The settings whose key isn't a
+// constant can't be checked, and are omitted.
+func registeredSettings(fset *token.FileSet, pkgs []*loader.PackageInfo) []registeredSetting {
+	var registered []registeredSetting
+	for _, pkg := range pkgs {
+		if pkg.Pkg.Path() == settingsPath {
+			continue
+		}
+		for _, file := range pkg.Files {
+			if isTestFile(fset, file.Pos()) {
+				continue
+			}
+			vars := make(map[*ast.CallExpr]types.Object)
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					vs := spec.(*ast.ValueSpec)
+					if len(vs.Names) != len(vs.Values) {
+						continue
+					}
+					for i, v := range vs.Values {
+						if call, ok := v.(*ast.CallExpr); ok {
+							vars[call] = pkg.Defs[vs.Names[i]]
+						}
+					}
+				}
+			}
+			ast.Inspect(file, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				fn := calledFunc(&pkg.Info, call)
+				if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != settingsPath ||
+					!strings.HasPrefix(fn.Name(), "Register") || len(call.Args) == 0 {
+					return true
+				}
+				key := pkg.Types[call.Args[0]].Value
+				if key == nil || key.Kind() != constant.String {
+					return true
+				}
+				s := registeredSetting{
+					call: call, fn: fn.Name(), key: constant.StringVal(key), obj: vars[call],
+				}
+				if len(call.Args) > 1 {
+					if desc := pkg.Types[call.Args[1]].Value; desc != nil && desc.Kind() == constant.String {
+						s.desc, s.hasDesc = constant.StringVal(desc), true
+					}
+				}
+				registered = append(registered, s)
+				return true
+			})
+		}
+	}
+	return registered
+}
+
+func isTestFile(fset *token.FileSet, pos token.Pos) bool {
+	return strings.HasSuffix(fset.Position(pos).Filename, "_test.go")
+}
+
+// settingsCheck reports the cluster settings registered, by a call to one of
+// the settings.Register functions, under the key of a setting registered
+// before them, which panics when the packages registering them are linked in
+// the same binary. If reportUnused is set, it also reports the settings held
+// by a package-level variable that isn't read outside of tests: those are dead
+// knobs. The settings registered by tests are ignored. The duplicates are
+// reported along with the position of the first registration, relative to
+// dir.
+func settingsCheck(
+	fset *token.FileSet,
+	pkgs []*loader.PackageInfo,
+	dir string,
+	reportUnused bool,
+	report func(pos token.Pos, message, fix string),
+) {
+	read := make(map[types.Object]bool)
+	for _, pkg := range pkgs {
+		for id, obj := range pkg.Uses {
+			if !isTestFile(fset, id.Pos()) {
+				read[obj] = true
+			}
+		}
+	}
+
+	first := make(map[string]*ast.CallExpr)
+	for _, s := range registeredSettings(fset, pkgs) {
+		if prev, ok := first[s.key]; ok {
+			pos := fset.Position(prev.Pos())
+			report(s.call.Pos(),
+				fmt.Sprintf("setting %q is already registered at %s:%d", s.key, relPath(dir, pos.Filename), pos.Line),
+				"use another key, or share the setting")
+			continue
+		}
+		first[s.key] = s.call
+		if reportUnused && s.obj != nil && !read[s.obj] {
+			report(s.call.Pos(), fmt.Sprintf("setting %q is never read", s.key),
+				"use it, or remove it")
+		}
+	}
+}
+
+// settingKeyRE matches the keys made of dot-separated lowercase words, e.g.
+// kv.raft_log.max_size.
+var settingKeyRE = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)+$`)
+
+// settingNamesCheck reports the cluster settings which don't follow the
+// conventions that keep the output of SHOW CLUSTER SETTINGS consistent: the
+// key is made of dot-separated lowercase words, and ends in ".enabled" for a
+// boolean; the description is non-empty, starts with a lowercase letter (or an
+// acronym) and doesn't end with a period. The settings registered by tests
+// are ignored, and so are the descriptions which aren't constants.
+func settingNamesCheck(
+	fset *token.FileSet,
+	pkgs []*loader.PackageInfo,
+	report func(pos token.Pos, key, message, fix string),
+) {
+	for _, s := range registeredSettings(fset, pkgs) {
+		pos := s.call.Pos()
+		if !settingKeyRE.MatchString(s.key) {
+			report(pos, s.key, fmt.Sprintf("setting %q isn't made of dot-separated lowercase words", s.key),
+				"use lowercase letters, digits and underscores, separated by dots")
+		}
+		if s.fn == "RegisterBoolSetting" && !strings.HasSuffix(s.key, ".enabled") {
+			report(pos, s.key, fmt.Sprintf("boolean setting %q doesn't end in \".enabled\"", s.key),
+				"end its key in \".enabled\"")
+		}
+		if !s.hasDesc {
+			continue
+		}
+		desc := strings.TrimSpace(s.desc)
+		if desc == "" {
+			report(pos, s.key, fmt.Sprintf("setting %q has no description", s.key),
+				"describe what it controls")
+			continue
+		}
+		if word := strings.Fields(desc)[0]; unicode.IsUpper([]rune(word)[0]) && strings.ToUpper(word) != word {
+			report(pos, s.key, fmt.Sprintf("the description of setting %q starts with an uppercase letter", s.key),
+				"start it with a lowercase letter")
+		}
+		if strings.HasSuffix(desc, ".") {
+			report(pos, s.key, fmt.Sprintf("the description of setting %q ends with a period", s.key),
+				"remove the period")
+		}
+	}
+}
+
+// runSettingNamesCheck runs settingNamesCheck on the cockroach packages of the
+// program, and reports the registrations as "<path>:<line>:<col>", with paths
+// relative to dir.
+func runSettingNamesCheck(
+	prog *loader.Program, dir string, report func(path, s, key, message, fix string),
+) {
+	settingNamesCheck(prog.Fset, cockroachPackages(prog), func(pos token.Pos, key, message, fix string) {
+		p := prog.Fset.Position(pos)
+		path := relPath(dir, p.Filename)
+		report(path, fmt.Sprintf("%s:%d:%d", path, p.Line, p.Column), key, message, fix)
+	})
+}
+
+// cockroachPackages returns the cockroach packages of the program, sorted by
+// path.
+func cockroachPackages(prog *loader.Program) []*loader.PackageInfo {
+	var pkgs []*loader.PackageInfo
+	for pkg, pkgInfo := range prog.AllPackages {
+		if strings.HasPrefix(pkg.Path(), cockroachDB) {
+			pkgs = append(pkgs, pkgInfo)
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Pkg.Path() < pkgs[j].Pkg.Path()
+	})
+	return pkgs
+}
+
+// runSettingsCheck runs settingsCheck on the cockroach packages of the
+// program, and reports the registrations as "<path>:<line>:<col>", with paths
+// relative to dir.
+func runSettingsCheck(
+	prog *loader.Program, dir string, reportUnused bool, report func(path, s, message, fix string),
+) {
+	// Which of two registrations of a key is reported as the duplicate
+	// mustn't depend on the order of the packages, hence the sorting done by
+	// cockroachPackages.
+	settingsCheck(prog.Fset, cockroachPackages(prog), dir, reportUnused,
+		func(pos token.Pos, message, fix string) {
+			p := prog.Fset.Position(pos)
+			path := relPath(dir, p.Filename)
+			report(path, fmt.Sprintf("%s:%d:%d", path, p.Line, p.Column), message, fix)
+		})
+}
+
+// relPath returns the path of the file relative to dir, with forward slashes.
+func relPath(dir, file string) string {
+	if rel, err := filepath.Rel(dir, file); err == nil {
+		file = rel
+	}
+	return filepath.ToSlash(file)
+}
+
+// metacheckProblem is a problem found by metacheck.
+type metacheckProblem struct {
+	Position token.Position
+	Text     string
+	// Check is the check which found the problem, e.g. "SA1019".
+	Check string
+}
+
+// problemCheckRE matches the text of a lint.Problem, which ends with the
+// check which found it.
+var problemCheckRE = regexp.MustCompile(`^(.*) \(([A-Z]+[0-9]+)\)$`)
+
+// makeMetacheckProblem resolves the position of the problem and splits the
+// check which found it from its text.
+func makeMetacheckProblem(fset *token.FileSet, p lint.Problem) metacheckProblem {
+	problem := metacheckProblem{Position: fset.Position(p.Position), Text: p.Text}
+	if m := problemCheckRE.FindStringSubmatch(p.Text); m != nil {
+		problem.Text, problem.Check = m[1], m[2]
+	}
+	return problem
+}
+
+// runMetacheck runs the checks of metacheck on the program, and reports the
+// problems found, along with the paths of their files relative to dir, except
+// those exempted by the exceptions.
+func runMetacheck(
+	prog *loader.Program,
+	dir string,
+	exceptions lintExceptionList,
+	report func(string, metacheckProblem),
+) {
+	unusedChecker := unused.NewChecker(unused.CheckAll)
+	unusedChecker.WholeProgram = true
+	linter := lint.Linter{
+		Checker: &metaChecker{
+			checkers: []lint.Checker{
+				simple.NewChecker(),
+				staticcheck.NewChecker(),
+				unused.NewLintChecker(unusedChecker),
+			},
+		},
+	}
+	for _, lp := range linter.Lint(prog) {
+		p := makeMetacheckProblem(prog.Fset, lp)
+		path := relPath(dir, p.Position.Filename)
+		if exceptions.exemptsCheck(path, p.Check) {
+			continue
+		}
+		report(path, p)
+	}
+}
+
+type metaChecker struct {
+	checkers []lint.Checker
+}
+
+func (m *metaChecker) Init(program *lint.Program) {
+	for _, checker := range m.checkers {
+		checker.Init(program)
+	}
+}
+
+func (m *metaChecker) Funcs() map[string]lint.Func {
+	funcs := map[string]lint.Func{
+		"FloatToUnsigned": checkConvertFloatToUnsigned,
+	}
+	for _, checker := range m.checkers {
+		for k, v := range checker.Funcs() {
+			if _, ok := funcs[k]; ok {
+				log.Fatalf("duplicate lint function %s", k)
+			} else {
+				funcs[k] = v
+			}
+		}
+	}
+	return funcs
+}
+
+// @ianlancetaylor via golang-nuts[0]:
+//
+// For the record, the spec says, in https://golang.org/ref/spec#Conversions:
+// "In all non-constant conversions involving floating-point or complex
+// values, if the result type cannot represent the value the conversion
+// succeeds but the result value is implementation-dependent."  That is the
+// case that applies here: you are converting a negative floating point number
+// to uint64, which can not represent a negative value, so the result is
+// implementation-dependent.  The conversion to int64 works, of course. And
+// the conversion to int64 and then to uint64 succeeds in converting to int64,
+// and when converting to uint64 follows a different rule: "When converting
+// between integer types, if the value is a signed integer, it is sign
+// extended to implicit infinite precision; otherwise it is zero extended. It
+// is then truncated to fit in the result type's size."
+//
+// So, basically, don't convert a negative floating point number to an
+// unsigned integer type.
+//
+// [0] https://groups.google.com/d/msg/golang-nuts/LH2AO1GAIZE/PyygYRwLAwAJ
+//
+// TODO(tamird): upstream this.
+func checkConvertFloatToUnsigned(j *lint.Job) {
+	fn := func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		castType, ok := j.Program.Info.TypeOf(call.Fun).(*types.Basic)
+		if !ok {
+			return true
+		}
+		if castType.Info()&types.IsUnsigned == 0 {
+			return true
+		}
+		for _, arg := range call.Args {
+			argType, ok := j.Program.Info.TypeOf(arg).(*types.Basic)
+			if !ok {
+				continue
+			}
+			if argType.Info()&types.IsFloat == 0 {
+				continue
+			}
+			j.Errorf(arg, "do not convert a floating point number to an unsigned integer type")
+		}
+		return true
+	}
+	for _, f := range j.Program.Files {
+		ast.Inspect(f, fn)
+	}
+}
+
+func TestResultChecks(t *testing.T) {
+	const src = `package foo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+type Error struct{}
+
+type closer struct{}
+
+func (closer) Close() error { return nil }
+
+func f() error { return errors.New("f") }
+
+func g() (int, error) { return 0, nil }
+
+func h() *Error { return nil }
+
+func i() (int, *Error) { return 0, nil }
+
+func foo() {
+	f()
+	_ = f()
+	go f()
+	defer f()
+	_, _ = g()
+	if err := f(); err != nil {
+		return
+	}
+	fmt.Println()
+	var b bytes.Buffer
+	b.WriteString("")
+	os.Remove("")
+	closer{}.Close()
+	h()
+	_ = h()
+	_, _ = i()
+	_, pErr := i()
+	_ = pErr
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "foo.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: importer.Default()}
+	if _, err := conf.Check("foo", fset, []*ast.File{file}, info); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(c *resultCheck) []string {
+		var calls []string
+		c.run(info, []*ast.File{file}, func(call *ast.CallExpr) {
+			calls = append(calls, fmt.Sprintf("%d: %s", fset.Position(call.Pos()).Line, types.ExprString(call)))
+		})
+		return calls
+	}
+	excludes := map[string]bool{"(foo.closer).Close": true}
+	for _, name := range errcheckDefaultExcludes {
+		excludes[name] = true
+	}
+	if calls, expected := check(errCheck(excludes)), []string{
+		"25: f()", "27: f()", "28: f()", "36: os.Remove(\"\")",
+	}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("errcheck: expected %q, got %q", expected, calls)
+	}
+	if calls, expected := check(returnCheck("foo", "Error")), []string{
+		"38: h()", "39: h()", "40: i()",
+	}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("returncheck: expected %q, got %q", expected, calls)
+	}
+}
+
+func TestProtoEqualCheck(t *testing.T) {
+	const src = `package foo
+
+import "reflect"
+
+type Span struct {
+	Key []byte
+}
+
+func (*Span) ProtoMessage() {}
+
+type Msg struct {
+	Span             Span
+	XXX_unrecognized []byte
+}
+
+func (*Msg) ProtoMessage() {}
+
+func (m *Msg) Equal(o interface{}) bool { return true }
+
+type Wrapper struct {
+	Msgs map[string]*Msg
+}
+
+type Other struct {
+	XXX_unrecognized []byte
+}
+
+func foo(a, b Span, c, d *Msg, e, f []Wrapper, g, h Other) {
+	_ = reflect.DeepEqual(a, b)
+	_ = reflect.DeepEqual(c, d)
+	_ = reflect.DeepEqual(e, f)
+	_ = reflect.DeepEqual(g, h)
+	_ = reflect.DeepEqual(a, c)
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "foo.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: importer.Default()}
+	if _, err := conf.Check("foo", fset, []*ast.File{file}, info); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	protoEqualCheck(info, []*ast.File{file}, func(call *ast.CallExpr, message, fix string) {
+		calls = append(calls, fmt.Sprintf("%d: %s <- %s; %s",
+			fset.Position(call.Pos()).Line, types.ExprString(call), message, fix))
+	})
+	expected := []string{
+		`30: reflect.DeepEqual(c, d) <- reflect.DeepEqual on foo.Msg, which has XXX_ fields; use "proto.Equal" or "(*foo.Msg).Equal" instead`,
+		`31: reflect.DeepEqual(e, f) <- reflect.DeepEqual on foo.Msg, which has XXX_ fields; use "proto.Equal" or "(*foo.Msg).Equal" instead`,
+		`33: reflect.DeepEqual(a, c) <- reflect.DeepEqual on foo.Msg, which has XXX_ fields; use "proto.Equal" or "(*foo.Msg).Equal" instead`,
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected %q, got %q", expected, calls)
+	}
+}
+
+// packageImporter imports the given packages, and the others from the
+// compiled packages.
+type packageImporter map[string]*types.Package
+
+func (m packageImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := m[path]; ok {
+		return pkg, nil
+	}
+	return importer.Default().Import(path)
+}
+
+// A testSource is a file of a package type-checked by checkSources.
+type testSource struct {
+	path, file, src string
+}
+
+// checkSources type-checks the packages made of the given files, which are
+// grouped by package in dependency order.
+func checkSources(t *testing.T, fset *token.FileSet, srcs []testSource) []*loader.PackageInfo {
+	imp := make(packageImporter)
+	var pkgs []*loader.PackageInfo
+	for i := 0; i < len(srcs); {
+		path := srcs[i].path
+		var files []*ast.File
+		for ; i < len(srcs) && srcs[i].path == path; i++ {
+			file, err := parser.ParseFile(fset, srcs[i].file, srcs[i].src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, file)
+		}
+		pkgInfo := &loader.PackageInfo{
+			Files: files,
+			Info: types.Info{
+				Types: make(map[ast.Expr]types.TypeAndValue),
+				Defs:  make(map[*ast.Ident]types.Object),
+				Uses:  make(map[*ast.Ident]types.Object),
+			},
+		}
+		conf := types.Config{Importer: imp}
+		pkg, err := conf.Check(path, fset, files, &pkgInfo.Info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkgInfo.Pkg = pkg
+		imp[path] = pkg
+		pkgs = append(pkgs, pkgInfo)
+	}
+	return pkgs
+}
+
+func TestSettingsCheck(t *testing.T) {
+	srcs := []testSource{
+		{settingsPath, "settings.go", `package settings
+
+type BoolSetting struct{}
+
+func (*BoolSetting) Get() bool { return false }
+
+func RegisterBoolSetting(key, desc string, v bool) *BoolSetting { return nil }
+`},
+		{"foo", "foo.go", `package foo
+
+import "github.com/cockroachdb/cockroach/pkg/settings"
+
+const key = "foo.key"
+
+var (
+	Exported = settings.RegisterBoolSetting("foo.exported", "", false)
+	read     = settings.RegisterBoolSetting(key, "", false)
+	unread   = settings.RegisterBoolSetting("foo.unread", "", false)
+	_        = settings.RegisterBoolSetting("foo.blank", "", false)
+	testOnly = settings.RegisterBoolSetting("foo.test_only", "", false)
+)
+
+func f() bool {
+	return read.Get()
+}
+`},
+		{"foo", "foo_test.go", `package foo
+
+import "github.com/cockroachdb/cockroach/pkg/settings"
+
+var inTest = settings.RegisterBoolSetting("foo.key", "", false)
+
+func g() bool {
+	return testOnly.Get() && unread == nil && inTest.Get()
+}
+`},
+		{"bar", "bar.go", `package bar
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"foo"
+)
+
+var dup = settings.RegisterBoolSetting("foo.key", "", false)
+
+func h() bool {
+	return dup.Get() && foo.Exported.Get()
+}
+`},
+	}
+
+	fset := token.NewFileSet()
+	pkgs := checkSources(t, fset, srcs)
+
+	for _, reportUnused := range []bool{false, true} {
+		var problems []string
+		settingsCheck(fset, pkgs, "", reportUnused, func(pos token.Pos, message, fix string) {
+			p := fset.Position(pos)
+			problems = append(problems, fmt.Sprintf("%s:%d: %s; %s", p.Filename, p.Line, message, fix))
+		})
+		var expected []string
+		if reportUnused {
+			expected = append(expected,
+				`foo.go:10: setting "foo.unread" is never read; use it, or remove it`,
+				`foo.go:11: setting "foo.blank" is never read; use it, or remove it`,
+				`foo.go:12: setting "foo.test_only" is never read; use it, or remove it`,
+			)
+		}
+		expected = append(expected,
+			`bar.go:8: setting "foo.key" is already registered at foo.go:9; use another key, or share the setting`)
+		sort.Strings(problems)
+		sort.Strings(expected)
+		if !reflect.DeepEqual(problems, expected) {
+			t.Errorf("reportUnused=%t: expected %q, got %q", reportUnused, expected, problems)
+		}
+	}
+}
+
+func TestSettingNamesCheck(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs := checkSources(t, fset, []testSource{
+		{settingsPath, "settings.go", `package settings
+
+type BoolSetting struct{}
+
+type IntSetting struct{}
+
+func RegisterBoolSetting(key, desc string, v bool) *BoolSetting { return nil }
+
+func RegisterIntSetting(key, desc string, v int64) *IntSetting { return nil }
+`},
+		{"foo", "foo.go", `package foo
+
+import "github.com/cockroachdb/cockroach/pkg/settings"
+
+var desc = "not a constant"
+
+var (
+	_ = settings.RegisterBoolSetting("foo.bar.enabled", "if set, bars are fooed", false)
+	_ = settings.RegisterIntSetting("foo.max_bars", "SQL bars "+"fooed at most", 1)
+	_ = settings.RegisterIntSetting("foo.non_constant", desc, 1)
+	_ = settings.RegisterBoolSetting("foo.bar", "if set, bars are fooed", false)
+	_ = settings.RegisterIntSetting("foo.maxBars", "maximum number of bars", 1)
+	_ = settings.RegisterIntSetting("foo", "maximum number of foos", 1)
+	_ = settings.RegisterIntSetting("foo..bars", "maximum number of bars", 1)
+	_ = settings.RegisterIntSetting("foo.empty", " ", 1)
+	_ = settings.RegisterIntSetting("foo.upper", "Maximum number of bars.", 1)
+)
+`},
+		{"foo", "foo_test.go", `package foo
+
+import "github.com/cockroachdb/cockroach/pkg/settings"
+
+var _ = settings.RegisterBoolSetting("FOO", "", false)
+`},
+	})
+
+	var problems []string
+	settingNamesCheck(fset, pkgs, func(pos token.Pos, key, message, fix string) {
+		p := fset.Position(pos)
+		problems = append(problems, fmt.Sprintf("%s:%d: %s: %s; %s", p.Filename, p.Line, key, message, fix))
+	})
+	expected := []string{
+		`foo.go:11: foo.bar: boolean setting "foo.bar" doesn't end in ".enabled"; end its key in ".enabled"`,
+		`foo.go:12: foo.maxBars: setting "foo.maxBars" isn't made of dot-separated lowercase words; ` +
+			`use lowercase letters, digits and underscores, separated by dots`,
+		`foo.go:13: foo: setting "foo" isn't made of dot-separated lowercase words; ` +
+			`use lowercase letters, digits and underscores, separated by dots`,
+		`foo.go:14: foo..bars: setting "foo..bars" isn't made of dot-separated lowercase words; ` +
+			`use lowercase letters, digits and underscores, separated by dots`,
+		`foo.go:15: foo.empty: setting "foo.empty" has no description; describe what it controls`,
+		`foo.go:16: foo.upper: the description of setting "foo.upper" starts with an uppercase letter; ` +
+			`start it with a lowercase letter`,
+		`foo.go:16: foo.upper: the description of setting "foo.upper" ends with a period; remove the period`,
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("expected %q, got %q", expected, problems)
+	}
+}
+
+func TestMakeMetacheckProblem(t *testing.T) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("foo.go", -1, 100)
+	file.SetLines([]int{0, 10, 20})
+	pos := file.Pos(23)
+
+	testCases := []struct {
+		text     string
+		expected metacheckProblem
+	}{
+		{"field noCopy is unused (U1000)", metacheckProblem{Text: "field noCopy is unused", Check: "U1000"}},
+		{"should use a simple channel send/receive (S1000)",
+			metacheckProblem{Text: "should use a simple channel send/receive", Check: "S1000"}},
+		{"no check (in parentheses)", metacheckProblem{Text: "no check (in parentheses)"}},
+	}
+	for _, tc := range testCases {
+		tc.expected.Position = token.Position{Filename: "foo.go", Offset: 23, Line: 3, Column: 4}
+		if p := makeMetacheckProblem(fset, lint.Problem{Position: pos, Text: tc.text}); p != tc.expected {
+			t.Errorf("%q: expected %+v, got %+v", tc.text, tc.expected, p)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build lint
+
+package build_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lintFailure is the JSON record of a lint failure. File is relative to the
+// root of the repository, and is empty along with Line and Column if the
+// failure isn't about a location in a file.
+type lintFailure struct {
+	Check   string `json:"check"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// lintReporter reports the lint failures as test errors and, if it has an
+// output file, appends them to it as JSON records, one per line, so that CI
+// doesn't have to parse the test output.
+type lintReporter struct {
+	root   string
+	pkgDir string
+	path   string
+	mu     sync.Mutex
+}
+
+// newLintReporter returns a reporter for the failures about the files in
+// pkgDir, which is in the repository rooted at root. If path isn't empty, the
+// file it names, relative to root, is truncated and the JSON records of the
+// failures are appended to it.
+func newLintReporter(root, pkgDir, path string) (*lintReporter, error) {
+	r := &lintReporter{root: root, pkgDir: pkgDir}
+	if path == "" {
+		return r, nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r.path = path
+	return r, f.Close()
+}
+
+// lintCheckName returns the name of the check run by the subtest t of
+// TestStyle, e.g. "envutil" for TestStyle/TestEnvutil. These are also the
+// names of the checks in lint_exceptions.yaml.
+func lintCheckName(t *testing.T) string {
+	return strings.ToLower(strings.TrimPrefix(path.Base(t.Name()), "Test"))
+}
+
+// fail reports the failure f of the check run by t, whose text is the test
+// error.
+func (r *lintReporter) fail(t *testing.T, f lintFailure, text string) {
+	t.Error(text)
+	f.Check = lintCheckName(t)
+	if err := r.write(f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// write appends the JSON record of f to the output file, if any.
+func (r *lintReporter) write(f lintFailure) error {
+	if r.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(append(b, '\n')); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// lintLocationRE matches the location at the start of the lines of output of
+// the checks: a path, relative to the package directory or absolute, a line
+// and optionally a column.
+var lintLocationRE = regexp.MustCompile(`^([^:\s]+):(\d+):(?:(\d+):)?\s*(.*)$`)
+
+// lintPathRE matches the lines of output that are only a path, e.g. those of
+// `git grep -L`.
+var lintPathRE = regexp.MustCompile(`^[\w./-]+\.\w+$`)
+
+// failLine reports the failure described by the line s of the output of the
+// check run by t.
+func (r *lintReporter) failLine(t *testing.T, s, message, fix string) {
+	f, text := r.parseLine(s, message, fix)
+	r.fail(t, f, text)
+}
+
+// parseLine returns the failure described by the line s of the output of a
+// check, and its text. If message isn't empty, the text is
+// "s <- message; fix", like that of the failures found by the analyzers.
+// Otherwise the text is s, and the message and fix are found past the
+// location it starts with.
+func (r *lintReporter) parseLine(s, message, fix string) (lintFailure, string) {
+	var f lintFailure
+	rest := s
+	if m := lintLocationRE.FindStringSubmatch(s); m != nil {
+		f.File = r.relPath(m[1])
+		f.Line, _ = strconv.Atoi(m[2])
+		f.Column, _ = strconv.Atoi(m[3])
+		rest = m[4]
+	} else if lintPathRE.MatchString(s) {
+		f.File = r.relPath(s)
+	}
+
+	text := s
+	if message != "" {
+		text += " <- " + message
+		if fix != "" {
+			text += "; " + fix
+		}
+	} else if i := strings.Index(rest, " <- "); i >= 0 {
+		message = rest[i+len(" <- "):]
+		if j := strings.Index(message, "; "); j >= 0 {
+			message, fix = message[:j], message[j+len("; "):]
+		}
+	} else {
+		message = rest
+	}
+	f.Message, f.Fix = message, fix
+	return f, text
+}
+
+// relPath returns the path relative to the root of the repository of a path
+// relative to the package directory, or absolute.
+func (r *lintReporter) relPath(file string) string {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(r.pkgDir, file)
+	}
+	return relPath(r.root, file)
+}
+
+func TestLintReporter(t *testing.T) {
+	root := filepath.FromSlash("/src/cockroach")
+	r, err := newLintReporter(root, filepath.Join(root, "pkg"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		s, message, fix string
+		expected        lintFailure
+		text            string
+	}{
+		{
+			s:        "util/foo.go",
+			message:  "missing license header",
+			expected: lintFailure{File: "pkg/util/foo.go", Message: "missing license header"},
+			text:     "util/foo.go <- missing license header",
+		},
+		{
+			s:        "util/foo.go:12:\tv := os.Getenv(\"FOO\")",
+			message:  "forbidden",
+			fix:      `use "envutil" instead`,
+			expected: lintFailure{File: "pkg/util/foo.go", Line: 12, Message: "forbidden", Fix: `use "envutil" instead`},
+			text:     "util/foo.go:12:\tv := os.Getenv(\"FOO\") <- forbidden; use \"envutil\" instead",
+		},
+		{
+			s:        "util/foo.go:3: time.Now <- forbidden; use \"timeutil\" instead",
+			expected: lintFailure{File: "pkg/util/foo.go", Line: 3, Message: "forbidden", Fix: `use "timeutil" instead`},
+			text:     "util/foo.go:3: time.Now <- forbidden; use \"timeutil\" instead",
+		},
+		{
+			s:        "./sql/bar.go:7:2: unreachable code",
+			expected: lintFailure{File: "pkg/sql/bar.go", Line: 7, Column: 2, Message: "unreachable code"},
+			text:     "./sql/bar.go:7:2: unreachable code",
+		},
+		{
+			s:        filepath.Join(root, "docs", "README.md") + ":4:10: \"teh\" is a misspelling of \"the\"",
+			expected: lintFailure{File: "docs/README.md", Line: 4, Column: 10, Message: `"teh" is a misspelling of "the"`},
+			text:     filepath.Join(root, "docs", "README.md") + ":4:10: \"teh\" is a misspelling of \"the\"",
+		},
+		{
+			s:        "github.com/cockroachdb/cockroach/pkg/util: path",
+			message:  "forbidden import",
+			fix:      `please use "path/filepath" instead of "path"`,
+			expected: lintFailure{Message: "forbidden import", Fix: `please use "path/filepath" instead of "path"`},
+			text:     `github.com/cockroachdb/cockroach/pkg/util: path <- forbidden import; please use "path/filepath" instead of "path"`,
+		},
+	}
+	for _, tc := range testCases {
+		f, text := r.parseLine(tc.s, tc.message, tc.fix)
+		if f != tc.expected {
+			t.Errorf("%q: expected %+v, got %+v", tc.s, tc.expected, f)
+		}
+		if text != tc.text {
+			t.Errorf("%q: expected text %q, got %q", tc.s, tc.text, text)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	r, err = newLintReporter(dir, filepath.Join(dir, "pkg"), "lint.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range testCases[:2] {
+		if err := r.write(tc.expected); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "lint.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = `{"check":"","file":"pkg/util/foo.go","message":"missing license header"}
+{"check":"","file":"pkg/util/foo.go","line":12,"message":"forbidden","fix":"use \"envutil\" instead"}
+`
+	if string(b) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, b)
+	}
+}
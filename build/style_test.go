@@ -19,26 +19,47 @@ package build_test
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ghemawat/stream"
 	"github.com/pkg/errors"
 	"golang.org/x/tools/go/buildutil"
+	"golang.org/x/tools/go/loader"
 )
 
 const cockroachDB = "github.com/cockroachdb/cockroach/pkg"
 
+// noCopyRE matches the unused noCopy fields and types reported by metacheck,
+// which are only there for go vet's copylocks check.
+var noCopyRE = regexp.MustCompile(`^(field no|type No)Copy is unused$`)
+
+// shellcheckSeverity is the minimum severity of the problems reported by
+// shellcheck. It is pinned, rather than left to the version of shellcheck
+// installed, so that the check is the same everywhere.
+const shellcheckSeverity = "error"
+
+// shellcheckRE matches the lines of output of shellcheck --format=gcc,
+// capturing the path and the code of the problem, e.g. SC2148.
+var shellcheckRE = regexp.MustCompile(`^([^:]+):\d+:\d+: \w+: .* \[(SC\d+)\]$`)
+
 func dirCmd(
 	dir string, name string, args ...string,
-) (*exec.Cmd, *bytes.Buffer, stream.Filter, error) {
-	cmd := exec.Command(name, args...)
-	cmd.Dir = dir
+) (*lintCmd, *bytes.Buffer, stream.Filter, error) {
+	cmd := command(dir, name, args...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return nil, nil, nil, err
@@ -48,13 +69,197 @@ func dirCmd(
 	return cmd, stderr, stream.ReadLines(stdout), nil
 }
 
+// changedFiles returns the files in dir, relative to it, that changed since the
+// merge base of HEAD and the given ref, including uncommitted and untracked
+// files. Deleted files are omitted.
+func changedFiles(dir string, base string) (map[string]bool, error) {
+	git := func(args ...string) ([]string, error) {
+		out, err := command(dir, "git", args...).Output()
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				return nil, errors.Errorf("git %s: %s", strings.Join(args, " "), exitErr.Stderr)
+			}
+			return nil, err
+		}
+		return strings.Fields(string(out)), nil
+	}
+	mergeBase, err := git("merge-base", base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if len(mergeBase) != 1 {
+		return nil, errors.Errorf("no merge base between %s and HEAD", base)
+	}
+	diff, err := git("diff", "--name-only", "--relative", "--diff-filter=d", mergeBase[0])
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git("ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]bool, len(diff)+len(untracked))
+	for _, f := range append(diff, untracked...) {
+		changed[f] = true
+	}
+	return changed, nil
+}
+
+// changedPackages returns the relative import paths ("./dir") of the packages
+// containing the given changed Go files, in sorted order.
+func changedPackages(changed map[string]bool) []string {
+	dirs := make(map[string]bool)
+	for f := range changed {
+		if strings.HasSuffix(f, ".go") {
+			dirs["./"+filepath.ToSlash(filepath.Dir(f))] = true
+		}
+	}
+	pkgs := make([]string, 0, len(dirs))
+	for d := range dirs {
+		pkgs = append(pkgs, d)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// changedGoFiles returns the changed Go files, in sorted order.
+func changedGoFiles(changed map[string]bool) []string {
+	var files []string
+	for f := range changed {
+		if strings.HasSuffix(f, ".go") {
+			files = append(files, f)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// changedFilesFilter returns a filter that only passes the lines of output
+// about the given changed files, i.e. the lines starting with their path,
+// relative to dir or not. All lines are passed if changed is nil.
+func changedFilesFilter(dir string, changed map[string]bool) stream.Filter {
+	return stream.FilterFunc(func(arg stream.Arg) error {
+		for s := range arg.In {
+			if changed == nil {
+				arg.Out <- s
+				continue
+			}
+			if changed[linePath(dir, s)] {
+				arg.Out <- s
+			}
+		}
+		return nil
+	})
+}
+
+// nonMainFilter returns a filter that drops the lines of output about the
+// files of main packages, i.e. the lines starting with their path, relative to
+// dir.
+func nonMainFilter(dir string) stream.Filter {
+	fset := token.NewFileSet()
+	return stream.FilterFunc(func(arg stream.Arg) error {
+		for s := range arg.In {
+			path := s
+			if i := strings.IndexByte(path, ':'); i >= 0 {
+				path = path[:i]
+			}
+			f, err := parser.ParseFile(fset, filepath.Join(dir, path), nil, parser.PackageClauseOnly)
+			if err != nil {
+				return err
+			}
+			if f.Name.Name != "main" {
+				arg.Out <- s
+			}
+		}
+		return nil
+	})
+}
+
 func TestStyle(t *testing.T) {
 	pkg, err := build.Import(cockroachDB, "", build.FindOnly)
 	if err != nil {
 		t.Skip(err)
 	}
 
-	t.Run("TestCopyrightHeaders", func(t *testing.T) {
+	// If LINT_DIFF_BASE is set to a git ref (e.g. origin/master), only the
+	// files that changed since its merge base with HEAD are checked, which
+	// makes for a much faster local loop. The package-scoped checks are run on
+	// the packages containing those files.
+	var changed map[string]bool
+	if base := os.Getenv("LINT_DIFF_BASE"); base != "" {
+		if changed, err = changedFiles(pkg.Dir, base); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("checking %d files changed since %s", len(changed), base)
+	}
+	// If LINT_CHECK_TIMEOUT is set to a duration (e.g. 10m), the commands run
+	// by the checks are killed after running for that long rather than the
+	// default of 5m, or never if it is 0.
+	if s := os.Getenv("LINT_CHECK_TIMEOUT"); s != "" {
+		if checkTimeout, err = time.ParseDuration(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// If LINT_CHECKS is set to a regexp, only the checks whose name matches it
+	// are run, e.g. `LINT_CHECKS='Vet|ErrCheck'`. Unlike -run, it matches the
+	// whole name of the check, and the checks it excludes are not started at
+	// all. If LINT_LIST_CHECKS is set, the names of the selected checks are
+	// printed, one per line, instead of running them.
+	var checksRE *regexp.Regexp
+	if s := os.Getenv("LINT_CHECKS"); s != "" {
+		if checksRE, err = regexp.Compile(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listChecks := os.Getenv("LINT_LIST_CHECKS") != ""
+	// The output of the slower external checks (misspell, crlfmt, golint and
+	// unconvert) is cached by the hash of the files it depends on, in
+	// LINT_CACHE_DIR or by default in a directory of the system's temp dir,
+	// so that the local runs only check again what was modified since the
+	// previous ones. If LINT_NO_CACHE is set, the cache is bypassed.
+	cacheDir := os.Getenv("LINT_CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = filepath.Join(os.TempDir(), "cockroach-lint-cache")
+	}
+	if os.Getenv("LINT_NO_CACHE") != "" {
+		cacheDir = ""
+	}
+	run := func(name string, f func(t *testing.T)) {
+		if checksRE != nil && !checksRE.MatchString(name) {
+			return
+		}
+		if listChecks {
+			fmt.Println(name)
+			return
+		}
+		t.Run(name, f)
+	}
+	diffFilter := func() stream.Filter {
+		return changedFilesFilter(pkg.Dir, changed)
+	}
+
+	buildDir := filepath.Join(filepath.Dir(pkg.Dir), "build")
+	exceptions, err := loadLintExceptions(buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// If LINT_JSON_OUTPUT is set to a file path, relative to the root of the
+	// repository, the failures are also written to that file as JSON records,
+	// one per line, e.g. for CI to annotate pull requests with them.
+	report, err := newLintReporter(filepath.Dir(pkg.Dir), pkg.Dir, os.Getenv("LINT_JSON_OUTPUT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// checkUsed fails the check if some of its exceptions didn't exempt
+	// anything. This can only be determined when the whole tree is checked.
+	checkUsed := func(t *testing.T, check string) {
+		if changed != nil || t.Failed() {
+			return
+		}
+		exceptions[check].checkUsed(t, report, check)
+	}
+
+	run("TestCopyrightHeaders", func(t *testing.T) {
 		t.Parallel()
 		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-LE", `^// (Copyright|Code generated by)`, "--", "*.go")
 		if err != nil {
@@ -65,22 +270,71 @@ func TestStyle(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if err := stream.ForEach(filter, func(s string) {
-			t.Errorf(`%s <- missing license header`, s)
+		if err := stream.ForEach(stream.Sequence(filter, diffFilter()), func(s string) {
+			if lintFix {
+				fixFile(t, pkg.Dir, s, addLicenseHeader(strings.HasPrefix(s, "ccl/")))
+				return
+			}
+			report.failLine(t, s, "missing license header", "")
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestMissingLeakTest", func(t *testing.T) {
+	run("TestMissingLeakTest", func(t *testing.T) {
 		t.Parallel()
-		cmd, stderr, filter, err := dirCmd(pkg.Dir, "util/leaktest/check-leaktest.sh")
+		tree, err := loadTree(pkg.Dir, changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := leaktestFiles(pkg.Dir, tree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range files {
+			checkFile(leaktestAnalyzer, nil, tree.fset, path, tree.files[path], func(s string) {
+				report.failLine(t, s, "", "")
+			})
+		}
+	})
+
+	run("TestBuildTags", func(t *testing.T) {
+		t.Parallel()
+		dirs := []string{pkg.Dir}
+		if changed == nil {
+			// The lint helpers aren't under pkg/, whose files are the only
+			// ones changedFiles knows about.
+			dirs = append(dirs, buildDir)
+		}
+		root := filepath.Dir(pkg.Dir)
+		for _, dir := range dirs {
+			tree, err := loadTree(dir, changed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, path := range tree.paths {
+				file := filepath.Join(dir, filepath.FromSlash(path))
+				src, err := ioutil.ReadFile(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkBuildTags(tree.fset, relPath(root, file), relPath(pkg.Dir, file), tree.files[path], src,
+					func(s, message, fix string) {
+						report.failLine(t, s, message, fix)
+					})
+			}
+		}
+	})
+
+	run("TestTabsInShellScripts", func(t *testing.T) {
+		t.Parallel()
+		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nF", "\t", "--", "*.sh")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -89,22 +343,50 @@ func TestStyle(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if err := stream.ForEach(filter, func(s string) {
-			t.Error(s)
+		if err := stream.ForEach(stream.Sequence(filter, diffFilter()), func(s string) {
+			report.failLine(t, s, "tab detected", "use spaces instead")
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestTabsInShellScripts", func(t *testing.T) {
+	run("TestShellcheck", func(t *testing.T) {
 		t.Parallel()
-		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nF", "\t", "--", "*.sh")
+		if _, err := exec.LookPath("shellcheck"); err != nil {
+			t.Skip(err)
+		}
+		defer checkUsed(t, "shellcheck")
+		// The shell scripts are all over the repository, so they are listed,
+		// and their changes found, from its root.
+		root := filepath.Dir(pkg.Dir)
+		changedScripts := changed
+		if changed != nil {
+			var err error
+			if changedScripts, err = changedFiles(root, os.Getenv("LINT_DIFF_BASE")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		out, err := command(root, "git", "ls-files", "--", "*.sh").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		args := []string{"--format=gcc", "--severity=" + shellcheckSeverity}
+		for _, f := range strings.Fields(string(out)) {
+			if changedScripts == nil || changedScripts[f] {
+				args = append(args, filepath.Join(root, f))
+			}
+		}
+		if len(args) == 2 {
+			t.Skip("no changed shell scripts")
+		}
+
+		cmd, stderr, filter, err := dirCmd(root, "shellcheck", args...)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -114,20 +396,31 @@ func TestStyle(t *testing.T) {
 		}
 
 		if err := stream.ForEach(filter, func(s string) {
-			t.Errorf(`%s <- tab detected, use spaces instead`, s)
+			if m := shellcheckRE.FindStringSubmatch(s); m != nil &&
+				exceptions["shellcheck"].exemptsCheck(relPath(root, m[1]), m[2]) {
+				return
+			}
+			report.failLine(t, s, "", "")
 		}); err != nil {
 			t.Error(err)
 		}
 
+		// shellcheck exits with a non-zero status when it finds problems.
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestEnvutil", func(t *testing.T) {
+	run("TestEnvutil", func(t *testing.T) {
 		t.Parallel()
+		defer checkUsed(t, "envutil")
+		// The analyzers inspect the syntax trees of the files. The git grep
+		// versions of their checks only run if the tree can't be parsed.
+		if runAnalyzer(t, report, pkg.Dir, changed, envutilAnalyzer, exceptions) {
+			return
+		}
 		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `os\.(Getenv|LookupEnv)`, "--", "*.go")
 		if err != nil {
 			t.Fatal(err)
@@ -139,26 +432,27 @@ func TestStyle(t *testing.T) {
 
 		if err := stream.ForEach(stream.Sequence(
 			filter,
-			stream.GrepNot(`^cmd(/.*)?/\w+\.go\b`),
-			stream.GrepNot(`^build/style_test\.go\b`),
-			stream.GrepNot(`^ccl/(sqlccl/backup_cloud|storageccl/export_storage|acceptanceccl/backup)_test\.go\b`),
-			stream.GrepNot(`^acceptance(/.*)?/\w+\.go\b`),
-			stream.GrepNot(`^util/(log|envutil|sdnotify)/\w+\.go\b`),
+			diffFilter(),
+			exceptions["envutil"].filter(),
 		), func(s string) {
-			t.Errorf(`%s <- forbidden; use "envutil" instead`, s)
+			report.failLine(t, s, "forbidden", `use "envutil" instead`)
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestSyncutil", func(t *testing.T) {
+	run("TestSyncutil", func(t *testing.T) {
 		t.Parallel()
+		defer checkUsed(t, "syncutil")
+		if runAnalyzer(t, report, pkg.Dir, changed, syncutilAnalyzer, exceptions) {
+			return
+		}
 		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `sync\.(RW)?Mutex`, "--", "*.go")
 		if err != nil {
 			t.Fatal(err)
@@ -170,21 +464,22 @@ func TestStyle(t *testing.T) {
 
 		if err := stream.ForEach(stream.Sequence(
 			filter,
-			stream.GrepNot(`^util/syncutil/mutex_sync\.go\b`),
+			diffFilter(),
+			exceptions["syncutil"].filter(),
 		), func(s string) {
-			t.Errorf(`%s <- forbidden; use "syncutil.{,RW}Mutex" instead`, s)
+			report.failLine(t, s, "forbidden", `use "syncutil.{,RW}Mutex" instead`)
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestTodoStyle", func(t *testing.T) {
+	run("TestTodoStyle", func(t *testing.T) {
 		t.Parallel()
 		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `\sTODO\([^)]*\)[^:]`, "--", "*.go")
 		if err != nil {
@@ -195,21 +490,25 @@ func TestStyle(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if err := stream.ForEach(filter, func(s string) {
-			t.Errorf(`%s <- use 'TODO(...): ' instead`, s)
+		if err := stream.ForEach(stream.Sequence(filter, diffFilter()), func(s string) {
+			report.failLine(t, s, "malformed TODO", `use 'TODO(...): ' instead`)
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestTimeutil", func(t *testing.T) {
+	run("TestTimeutil", func(t *testing.T) {
 		t.Parallel()
+		defer checkUsed(t, "timeutil")
+		if runAnalyzer(t, report, pkg.Dir, changed, timeutilAnalyzer, exceptions) {
+			return
+		}
 		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `time\.(Now|Since)`, "--", "*.go")
 		if err != nil {
 			t.Fatal(err)
@@ -221,22 +520,27 @@ func TestStyle(t *testing.T) {
 
 		if err := stream.ForEach(stream.Sequence(
 			filter,
-			stream.GrepNot(`^util/(log|syncutil|timeutil|tracing)/\w+\.go\b`),
+			diffFilter(),
+			exceptions["timeutil"].filter(),
 		), func(s string) {
-			t.Errorf(`%s <- forbidden; use "timeutil" instead`, s)
+			report.failLine(t, s, "forbidden", `use "timeutil" instead`)
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestGrpc", func(t *testing.T) {
+	run("TestGrpc", func(t *testing.T) {
 		t.Parallel()
+		defer checkUsed(t, "grpc")
+		if runAnalyzer(t, report, pkg.Dir, changed, grpcAnalyzer, exceptions) {
+			return
+		}
 		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `grpc.NewServer\([^)]*\)`, "--", "*.go")
 		if err != nil {
 			t.Fatal(err)
@@ -248,22 +552,27 @@ func TestStyle(t *testing.T) {
 
 		if err := stream.ForEach(stream.Sequence(
 			filter,
-			stream.GrepNot(`^rpc/context(_test)?\.go\b`),
+			diffFilter(),
+			exceptions["grpc"].filter(),
 		), func(s string) {
-			t.Errorf(`%s <- forbidden; use "rpc.NewServer" instead`, s)
+			report.failLine(t, s, "forbidden", `use "rpc.NewServer" instead`)
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestProtoClone", func(t *testing.T) {
+	run("TestProtoClone", func(t *testing.T) {
 		t.Parallel()
+		defer checkUsed(t, "protoclone")
+		if runAnalyzer(t, report, pkg.Dir, changed, protoCloneAnalyzer, exceptions) {
+			return
+		}
 		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `\.Clone\([^)]+\)`, "--", "*.go")
 		if err != nil {
 			t.Fatal(err)
@@ -275,23 +584,28 @@ func TestStyle(t *testing.T) {
 
 		if err := stream.ForEach(stream.Sequence(
 			filter,
+			diffFilter(),
 			stream.GrepNot(`protoutil\.Clone\([^)]+\)`),
-			stream.GrepNot(`^util/protoutil/clone(_test)?\.go\b`),
+			exceptions["protoclone"].filter(),
 		), func(s string) {
-			t.Errorf(`%s <- forbidden; use "protoutil.Clone" instead`, s)
+			report.failLine(t, s, "forbidden", `use "protoutil.Clone" instead`)
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestProtoMarshal", func(t *testing.T) {
+	run("TestProtoMarshal", func(t *testing.T) {
 		t.Parallel()
+		defer checkUsed(t, "protomarshal")
+		if runAnalyzer(t, report, pkg.Dir, changed, protoMarshalAnalyzer, exceptions) {
+			return
+		}
 		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `\.Marshal\([^)]+\)`, "--", "*.go")
 		if err != nil {
 			t.Fatal(err)
@@ -303,24 +617,29 @@ func TestStyle(t *testing.T) {
 
 		if err := stream.ForEach(stream.Sequence(
 			filter,
+			diffFilter(),
 			stream.GrepNot(`(json|yaml|protoutil|Field)\.Marshal`),
-			stream.GrepNot(`^util/protoutil/marshal(_test)?\.go\b`),
+			exceptions["protomarshal"].filter(),
 		), func(s string) {
-			t.Errorf(`%s <- forbidden; use "protoutil.Marshal" instead`, s)
+			report.failLine(t, s, "forbidden", `use "protoutil.Marshal" instead`)
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestImportNames", func(t *testing.T) {
+	run("TestPrint", func(t *testing.T) {
 		t.Parallel()
-		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `^(import|\s+)(\w+ )?"database/sql"$`, "--", "*.go")
+		defer checkUsed(t, "print")
+		if runAnalyzer(t, report, pkg.Dir, changed, printAnalyzer, exceptions) {
+			return
+		}
+		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `(\bfmt\.Print(f|ln)?|(^|[^.\w])print(ln)?)\(`, "--", "*.go")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -331,23 +650,28 @@ func TestStyle(t *testing.T) {
 
 		if err := stream.ForEach(stream.Sequence(
 			filter,
-			stream.GrepNot(`gosql "database/sql"`),
+			diffFilter(),
+			exceptions["print"].filter(),
 		), func(s string) {
-			t.Errorf(`%s <- forbidden; import "database/sql" as "gosql" to avoid confusion with "cockroach/sql"`, s)
+			report.failLine(t, s, "forbidden", `use "util/log" instead`)
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestMisspell", func(t *testing.T) {
+	run("TestFatal", func(t *testing.T) {
 		t.Parallel()
-		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "ls-files")
+		defer checkUsed(t, "fatal")
+		if runAnalyzer(t, report, pkg.Dir, changed, fatalAnalyzer, exceptions) {
+			return
+		}
+		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `\b(os\.Exit|log\.Fatal(f|ln|fDepth)?)\(`, "--", "*.go")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -356,28 +680,32 @@ func TestStyle(t *testing.T) {
 			t.Fatal(err)
 		}
 
+		// Main packages may exit.
 		if err := stream.ForEach(stream.Sequence(
 			filter,
-			stream.Map(func(s string) string {
-				return filepath.Join(pkg.Dir, s)
-			}),
-			stream.Xargs("misspell"),
+			diffFilter(),
+			exceptions["fatal"].filter(),
+			nonMainFilter(pkg.Dir),
 		), func(s string) {
-			t.Errorf(s)
+			report.failLine(t, s, "forbidden", `return an error or use the stopper instead`)
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestGofmtSimplify", func(t *testing.T) {
+	run("TestContext", func(t *testing.T) {
 		t.Parallel()
-		cmd, stderr, filter, err := dirCmd(pkg.Dir, "gofmt", "-s", "-d", "-l", ".")
+		defer checkUsed(t, "context")
+		if runAnalyzer(t, report, pkg.Dir, changed, contextAnalyzer, exceptions) {
+			return
+		}
+		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `\bcontext\.(TODO|Background)\(`, "--", "*.go")
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -386,22 +714,282 @@ func TestStyle(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if err := stream.ForEach(filter, func(s string) {
-			t.Error(s)
+		// Main packages may make contexts from scratch.
+		if err := stream.ForEach(stream.Sequence(
+			filter,
+			diffFilter(),
+			exceptions["context"].filter(),
+			nonMainFilter(pkg.Dir),
+		), func(s string) {
+			report.failLine(t, s, "forbidden", `plumb a context through, or use "AmbientContext.AnnotateCtx" instead`)
+		}); err != nil {
+			t.Error(err)
+		}
+
+		if err := cmd.Wait(); err != nil {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
+				t.Fatalf("err=%s, stderr=%s", err, out)
+			}
+		}
+	})
+
+	run("TestErrwrap", func(t *testing.T) {
+		t.Parallel()
+		defer checkUsed(t, "errwrap")
+		if runAnalyzer(t, report, pkg.Dir, changed, errwrapAnalyzer, exceptions) {
+			return
+		}
+		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `\bfmt\.Errorf\(.*%[+#]?[sv].*\b(err|\w+Err)(\.Error\(\))?\)`, "--", "*.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := stream.ForEach(stream.Sequence(
+			filter,
+			diffFilter(),
+			exceptions["errwrap"].filter(),
+		), func(s string) {
+			report.failLine(t, s, "forbidden", `use "errors.Wrap(f)" instead`)
+		}); err != nil {
+			t.Error(err)
+		}
+
+		if err := cmd.Wait(); err != nil {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
+				t.Fatalf("err=%s, stderr=%s", err, out)
+			}
+		}
+	})
+
+	run("TestSleep", func(t *testing.T) {
+		t.Parallel()
+		defer checkUsed(t, "sleep")
+		if runAnalyzer(t, report, pkg.Dir, changed, sleepAnalyzer, exceptions) {
+			return
+		}
+		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `\btime\.Sleep\(`, "--", "*_test.go", ":!testutils")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := stream.ForEach(stream.Sequence(
+			filter,
+			diffFilter(),
+			exceptions["sleep"].filter(),
+		), func(s string) {
+			report.failLine(t, s, "forbidden", `use "testutils.SucceedsSoon" or "retry" instead`)
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
 	})
 
-	t.Run("TestCrlfmt", func(t *testing.T) {
+	run("TestRand", func(t *testing.T) {
 		t.Parallel()
-		cmd, stderr, filter, err := dirCmd(pkg.Dir, "crlfmt", "-ignore", `\.pb(\.gw)?\.go`, "-tab", "2", ".")
+		defer checkUsed(t, "rand")
+		if runAnalyzer(t, report, pkg.Dir, changed, randAnalyzer, exceptions) {
+			return
+		}
+		// Unlike the analyzer, this doesn't tell the functions of math/rand from
+		// those of crypto/rand, so it skips rand.Int and rand.Read.
+		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE",
+			`\brand\.(ExpFloat64|Float(32|64)|Int(31|63)?n|Int(31|63)|NormFloat64|Perm|Seed|Uint(32|64))\(`,
+			"--", "*.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := stream.ForEach(stream.Sequence(
+			filter,
+			diffFilter(),
+			exceptions["rand"].filter(),
+		), func(s string) {
+			report.failLine(t, s, "forbidden", `use "randutil.NewPseudoRand" or "randutil.NewLockedPseudoRand" instead`)
+		}); err != nil {
+			t.Error(err)
+		}
+
+		if err := cmd.Wait(); err != nil {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
+				t.Fatalf("err=%s, stderr=%s", err, out)
+			}
+		}
+	})
+
+	run("TestPanic", func(t *testing.T) {
+		t.Parallel()
+		defer checkUsed(t, "panic")
+		if runAnalyzer(t, report, pkg.Dir, changed, panicAnalyzer, exceptions) {
+			return
+		}
+		// Unlike the analyzer, this can't tell the main packages, so it skips
+		// the commands.
+		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `\bpanic\(`,
+			"--", "sql/*.go", "storage/*.go", ":!*_test.go", ":!*/cmd/*")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := stream.ForEach(stream.Sequence(
+			filter,
+			diffFilter(),
+			stream.GrepNot(`\bpanic\(pgerror\.`),
+			exceptions["panic"].filter(),
+		), func(s string) {
+			report.failLine(t, s, "forbidden", panicHint)
+		}); err != nil {
+			t.Error(err)
+		}
+
+		if err := cmd.Wait(); err != nil {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
+				t.Fatalf("err=%s, stderr=%s", err, out)
+			}
+		}
+	})
+
+	run("TestHelper", func(t *testing.T) {
+		t.Parallel()
+		// Calling t.Helper() doesn't compile before go1.9, whose toolchain
+		// can't hold the helpers to it.
+		if _, ok := reflect.TypeOf(t).MethodByName("Helper"); !ok {
+			t.Skip("t.Helper() requires go1.9")
+		}
+		defer checkUsed(t, "helper")
+		tree, err := loadTree(pkg.Dir, changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range tree.paths {
+			checkFile(helperAnalyzer, exceptions["helper"], tree.fset, path, tree.files[path], func(s string) {
+				report.failLine(t, s, "", "")
+			})
+		}
+	})
+
+	run("TestImportNames", func(t *testing.T) {
+		t.Parallel()
+		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "grep", "-nE", `^(import|\s+)(\w+ )?"database/sql"$`, "--", "*.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := stream.ForEach(stream.Sequence(
+			filter,
+			diffFilter(),
+			stream.GrepNot(`gosql "database/sql"`),
+		), func(s string) {
+			if lintFix {
+				fixFile(t, pkg.Dir, s[:strings.IndexByte(s, ':')], aliasDatabaseSQL)
+				return
+			}
+			report.failLine(t, s, "forbidden", `import "database/sql" as "gosql" to avoid confusion with "cockroach/sql"`)
+		}); err != nil {
+			t.Error(err)
+		}
+
+		if err := cmd.Wait(); err != nil {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
+				t.Fatalf("err=%s, stderr=%s", err, out)
+			}
+		}
+	})
+
+	run("TestMisspell", func(t *testing.T) {
+		t.Parallel()
+		cache, err := newLintCache(cacheDir, "misspell", "misspell")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd, stderr, filter, err := dirCmd(pkg.Dir, "git", "ls-files")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		var files []string
+		if err := stream.ForEach(stream.Sequence(
+			filter,
+			diffFilter(),
+		), func(s string) {
+			files = append(files, s)
+		}); err != nil {
+			t.Error(err)
+		}
+
+		if err := cmd.Wait(); err != nil {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
+				t.Fatalf("err=%s, stderr=%s", err, out)
+			}
+		}
+
+		out, err := cache.run(files, cache.fileKey(pkg.Dir), func(files []string) ([]string, error) {
+			var out []string
+			err := stream.ForEach(stream.Sequence(
+				stream.Items(files...),
+				stream.Map(func(s string) string {
+					return filepath.Join(pkg.Dir, s)
+				}),
+				stream.Xargs("misspell"),
+			), func(s string) {
+				out = append(out, s)
+			})
+			return out, err
+		}, func(s string) string {
+			return linePath(pkg.Dir, s)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range out {
+			report.failLine(t, s, "", "")
+		}
+	})
+
+	run("TestGofmtSimplify", func(t *testing.T) {
+		t.Parallel()
+		args := []string{"-s", "-d", "-l"}
+		if lintFix {
+			// With -w, -l lists the files which were rewritten.
+			args = []string{"-s", "-w", "-l"}
+		}
+		if changed != nil {
+			args = append(args, changedGoFiles(changed)...)
+			if len(args) == 3 {
+				t.Skip("no changed Go files")
+			}
+		} else {
+			args = append(args, ".")
+		}
+		cmd, stderr, filter, err := dirCmd(pkg.Dir, "gofmt", args...)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -411,60 +999,93 @@ func TestStyle(t *testing.T) {
 		}
 
 		if err := stream.ForEach(filter, func(s string) {
-			t.Error(s)
+			if lintFix {
+				t.Logf("%s <- fixed", s)
+				return
+			}
+			report.failLine(t, s, "", "")
 		}); err != nil {
 			t.Error(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
+			if out := stderr.String(); len(out) > 0 || isTimeout(err) {
 				t.Fatalf("err=%s, stderr=%s", err, out)
 			}
 		}
+	})
+
+	run("TestCrlfmt", func(t *testing.T) {
+		t.Parallel()
+		args := []string{"-ignore", `\.pb(\.gw)?\.go`, "-tab", "2"}
+		if lintFix {
+			// The diffs show the rewritten files.
+			args = append(args, "-w")
+		}
+		cache, err := newLintCache(cacheDir, "crlfmt", "crlfmt", args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ignoreRE := regexp.MustCompile(args[1])
+		var files []string
+		out, err := command(pkg.Dir, "git", "ls-files", "--cached", "--others", "--exclude-standard", "--", "*.go").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range strings.Fields(string(out)) {
+			if !ignoreRE.MatchString(f) {
+				files = append(files, f)
+			}
+		}
+
+		// crlfmt prints diffs, which can't be attributed to the files, so only
+		// the files on which its output is empty are cached, and it checks the
+		// whole tree rather than the files which aren't, unless they're few.
+		// It is fast enough anyway.
+		lines, err := cache.run(files, cache.fileKey(pkg.Dir), func(files []string) ([]string, error) {
+			paths := []string{"."}
+			if len(files) <= 100 {
+				paths = files
+			}
+			return outputLines(pkg.Dir, "crlfmt", append(append([]string(nil), args...), paths...)...)
+		}, func(string) string {
+			return ""
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range lines {
+			if lintFix {
+				t.Log(s)
+				continue
+			}
+			report.failLine(t, s, "", "")
+		}
 
 		if t.Failed() {
-			args := append([]string(nil), cmd.Args[1:len(cmd.Args)-1]...)
-			args = append(args, "-w", pkg.Dir)
+			args := append(append([]string(nil), args...), "-w", pkg.Dir)
 			for i := range args {
 				args[i] = strconv.Quote(args[i])
 			}
 			t.Logf("run the following to fix your formatting:\n"+
 				"\n%s %s\n\n"+
 				"Don't forget to add amend the result to the correct commits.",
-				cmd.Args[0], strings.Join(args, " "),
+				"crlfmt", strings.Join(args, " "),
 			)
 		}
 	})
 
-	t.Run("TestVet", func(t *testing.T) {
+	run("TestVet", func(t *testing.T) {
 		t.Parallel()
 		// `go tool vet` is a special snowflake that emits all its output on
 		// `stderr.
-		cmd := exec.Command("go", "tool", "vet", "-all", "-shadow", "-printfuncs",
-			strings.Join([]string{
-				"Info:1",
-				"Infof:1",
-				"InfofDepth:2",
-				"Warning:1",
-				"Warningf:1",
-				"WarningfDepth:2",
-				"Error:1",
-				"Errorf:1",
-				"ErrorfDepth:2",
-				"Fatal:1",
-				"Fatalf:1",
-				"FatalfDepth:2",
-				"Event:1",
-				"Eventf:1",
-				"ErrEvent:1",
-				"ErrEventf:1",
-				"VEvent:2",
-				"VEventf:2",
-				"UnimplementedWithIssueErrorf:1",
-			}, ","),
+		printfuncs, err := vetPrintfuncs(pkg.Dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd := command(pkg.Dir, "go", "tool", "vet", "-all", "-shadow", "-printfuncs", printfuncs,
 			".",
 		)
-		cmd.Dir = pkg.Dir
 		var b bytes.Buffer
 		cmd.Stdout = &b
 		cmd.Stderr = &b
@@ -484,17 +1105,19 @@ func TestStyle(t *testing.T) {
 				}
 				return scanner.Err()
 			}),
+			diffFilter(),
 			stream.GrepNot(`declaration of "?(pE|e)rr"? shadows`),
 			stream.GrepNot(`\.pb\.gw\.go:[0-9]+: declaration of "?ctx"? shadows`),
 		), func(s string) {
-			t.Error(s)
+			report.failLine(t, s, "", "")
 		}); err != nil {
 			t.Error(err)
 		}
 	})
 
-	t.Run("TestForbiddenImports", func(t *testing.T) {
+	run("TestForbiddenImports", func(t *testing.T) {
 		t.Parallel()
+		defer checkUsed(t, "forbiddenimports")
 		filter := stream.FilterFunc(func(arg stream.Arg) error {
 			for _, useAllFiles := range []bool{false, true} {
 				buildContext := build.Default
@@ -533,29 +1156,35 @@ func TestStyle(t *testing.T) {
 			stream.Uniq(),
 			stream.GrepNot(`cockroach/pkg/cmd/`),
 			stream.Grep(`^`+settingsPkgPrefix+`: | (github\.com/golang/protobuf/proto|github\.com/satori/go\.uuid|log|path|context|syscall)$`),
-			stream.GrepNot(`cockroach/pkg/(cli|security): syscall$`),
-			stream.GrepNot(`cockroach/pkg/(base|security|util/(log|randutil|stop)): log$`),
-			stream.GrepNot(`cockroach/pkg/(server/serverpb|ts/tspb): github\.com/golang/protobuf/proto$`),
-			stream.GrepNot(`cockroach/pkg/util/caller: path$`),
-			stream.GrepNot(`cockroach/pkg/util/uuid: github\.com/satori/go\.uuid$`),
+			stream.FilterFunc(func(arg stream.Arg) error {
+				for s := range arg.In {
+					// The lines are "<package>: <import>".
+					i := strings.Index(s, ": ")
+					pkgPath := strings.TrimPrefix(s[:i], cockroachDB+"/")
+					if !exceptions["forbiddenimports"].exempts(pkgPath, s[i+2:]) {
+						arg.Out <- s
+					}
+				}
+				return nil
+			}),
 		), func(s string) {
 			switch {
 			case strings.HasSuffix(s, " path"):
-				t.Errorf(`%s <- please use "path/filepath" instead of "path"`, s)
+				report.failLine(t, s, "forbidden import", `please use "path/filepath" instead of "path"`)
 			case strings.HasSuffix(s, " log"):
-				t.Errorf(`%s <- please use "util/log" instead of "log"`, s)
+				report.failLine(t, s, "forbidden import", `please use "util/log" instead of "log"`)
 			case strings.HasSuffix(s, " github.com/golang/protobuf/proto"):
-				t.Errorf(`%s <- please use "github.com/gogo/protobuf/proto" instead of "github.com/golang/protobuf/proto"`, s)
+				report.failLine(t, s, "forbidden import", `please use "github.com/gogo/protobuf/proto" instead of "github.com/golang/protobuf/proto"`)
 			case strings.HasSuffix(s, " github.com/satori/go.uuid"):
-				t.Errorf(`%s <- please use "util/uuid" instead of "github.com/satori/go.uuid"`, s)
+				report.failLine(t, s, "forbidden import", `please use "util/uuid" instead of "github.com/satori/go.uuid"`)
 			case strings.HasSuffix(s, " context"):
-				t.Errorf(`%s <- please use "golang.org/x/net/context" instead of "context"`, s)
+				report.failLine(t, s, "forbidden import", `please use "golang.org/x/net/context" instead of "context"`)
 			case strings.HasSuffix(s, " syscall"):
-				t.Errorf(`%s <- please use "golang.org/x/sys" instead of "syscall"`, s)
+				report.failLine(t, s, "forbidden import", `please use "golang.org/x/sys" instead of "syscall"`)
 			case strings.HasPrefix(s, settingsPkgPrefix+": github.com/cockroachdb/cockroach"):
 				if !strings.HasSuffix(s, "testutils") && !strings.HasSuffix(s, "humanizeutil") &&
 					!strings.HasSuffix(s, settingsPkgPrefix) {
-					t.Errorf("%s <- please don't add CRDB dependencies to settings pkg", s)
+					report.failLine(t, s, "forbidden import", "please don't add CRDB dependencies to settings pkg")
 				}
 			}
 		}); err != nil {
@@ -564,243 +1193,175 @@ func TestStyle(t *testing.T) {
 	})
 
 	// Things that are packaged scoped are below here.
-	pkgScope, ok := os.LookupEnv("PKG")
-	if !ok {
-		pkgScope = "./..."
+	pkgScope := []string{"./..."}
+	if pkgs, ok := os.LookupEnv("PKG"); ok {
+		pkgScope = []string{pkgs}
+	} else if changed != nil {
+		pkgScope = changedPackages(changed)
+		if len(pkgScope) == 0 && !listChecks {
+			// No Go files changed.
+			return
+		}
 	}
 
-	// TODO(tamird): replace this with errcheck.NewChecker() when
-	// https://github.com/dominikh/go-tools/issues/57 is fixed.
-	t.Run("TestErrCheck", func(t *testing.T) {
-		// errcheck uses 1GB of ram (as of 2017-02-18), so don't parallelize it.
-		cmd, stderr, filter, err := dirCmd(
-			pkg.Dir,
-			"errcheck",
-			"-exclude",
-			filepath.Join(filepath.Dir(pkg.Dir), "build", "errcheck_excludes.txt"),
-			pkgScope,
-		)
+	// errcheck, returncheck, protoequal, settings and metacheck share the
+	// type-checked program of the packages in scope, which is loaded by the
+	// first of them to run. This takes a few GB of RAM for the whole tree.
+	loadProg := func(t *testing.T) *loader.Program {
+		prog, err := loadProgram(pkg.Dir, pkgScope)
 		if err != nil {
 			t.Fatal(err)
 		}
+		return prog
+	}
+	inScope := func(path string) bool {
+		return changed == nil || changed[path]
+	}
 
-		if err := cmd.Start(); err != nil {
+	run("TestErrCheck", func(t *testing.T) {
+		t.Parallel()
+		excludes, err := loadErrcheckExcludes(filepath.Join(buildDir, "errcheck_excludes.txt"))
+		if err != nil {
 			t.Fatal(err)
 		}
-
-		if err := stream.ForEach(filter, func(s string) {
-			t.Errorf(`%s <- unchecked error`, s)
-		}); err != nil {
-			t.Error(err)
-		}
-
-		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
-				t.Fatalf("err=%s, stderr=%s", err, out)
+		runResultCheck(loadProg(t), pkg.Dir, errCheck(excludes), func(path, s string) {
+			if inScope(path) {
+				report.failLine(t, s, "unchecked error", "")
 			}
-		}
+		})
 	})
 
-	t.Run("TestReturnCheck", func(t *testing.T) {
-		// returncheck uses 1GB of ram (as of 2017-02-18), so don't parallelize it.
-		cmd, stderr, filter, err := dirCmd(pkg.Dir, "returncheck", pkgScope)
-		if err != nil {
-			t.Fatal(err)
-		}
+	run("TestReturnCheck", func(t *testing.T) {
+		t.Parallel()
+		c := returnCheck(cockroachDB+"/roachpb", "Error")
+		runResultCheck(loadProg(t), pkg.Dir, c, func(path, s string) {
+			if inScope(path) {
+				report.failLine(t, s, "unchecked error", "")
+			}
+		})
+	})
 
-		if err := cmd.Start(); err != nil {
-			t.Fatal(err)
-		}
+	run("TestProtoEqual", func(t *testing.T) {
+		t.Parallel()
+		runProtoEqualCheck(loadProg(t), pkg.Dir, func(path, s, message, fix string) {
+			if inScope(path) {
+				report.failLine(t, s, message, fix)
+			}
+		})
+	})
 
-		if err := stream.ForEach(filter, func(s string) {
-			t.Errorf(`%s <- unchecked error`, s)
-		}); err != nil {
-			t.Error(err)
-		}
+	run("TestSettings", func(t *testing.T) {
+		t.Parallel()
+		// Whether a setting is read can only be determined when the whole
+		// tree is checked.
+		wholeTree := len(pkgScope) == 1 && pkgScope[0] == "./..."
+		if wholeTree {
+			defer checkUsed(t, "settings")
+		}
+		runSettingsCheck(loadProg(t), pkg.Dir, wholeTree, func(path, s, message, fix string) {
+			if inScope(path) && !exceptions["settings"].exempts(path, "") {
+				report.failLine(t, s, message, fix)
+			}
+		})
+	})
 
-		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
-				t.Fatalf("err=%s, stderr=%s", err, out)
+	run("TestSettingNames", func(t *testing.T) {
+		t.Parallel()
+		defer checkUsed(t, "settingnames")
+		runSettingNamesCheck(loadProg(t), pkg.Dir, func(path, s, key, message, fix string) {
+			if inScope(path) && !exceptions["settingnames"].exemptsSetting(path, key) {
+				report.failLine(t, s, message, fix)
 			}
-		}
+		})
 	})
 
-	t.Run("TestGolint", func(t *testing.T) {
+	run("TestGolint", func(t *testing.T) {
 		t.Parallel()
-		cmd, stderr, filter, err := dirCmd(pkg.Dir, "golint", pkgScope)
+		cache, err := newLintCache(cacheDir, "golint", "golint")
 		if err != nil {
 			t.Fatal(err)
 		}
-
-		if err := cmd.Start(); err != nil {
+		units, dirs, err := packageUnits(pkg.Dir, pkgScope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := cache.run(units, cache.packageKey(dirs), func(units []string) ([]string, error) {
+			return outputLines(pkg.Dir, "golint", units...)
+		}, func(s string) string {
+			return linePackage(pkg.Dir, s)
+		})
+		if err != nil {
 			t.Fatal(err)
 		}
 
 		if err := stream.ForEach(stream.Sequence(
-			filter,
+			stream.Items(out...),
+			diffFilter(),
 			stream.GrepNot(`((\.pb|\.pb\.gw|embedded|_string)\.go|sql/parser/(yaccpar|sql\.y):)`),
 		), func(s string) {
-			t.Error(s)
+			report.failLine(t, s, "", "")
 		}); err != nil {
 			t.Error(err)
 		}
-
-		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
-				t.Fatalf("err=%s, stderr=%s", err, out)
-			}
-		}
 	})
 
-	t.Run("TestUnconvert", func(t *testing.T) {
+	run("TestUnconvert", func(t *testing.T) {
 		if testing.Short() {
 			t.Skip("short flag")
 		}
 		t.Parallel()
-		cmd, stderr, filter, err := dirCmd(pkg.Dir, "unconvert", pkgScope)
+		cache, err := newLintCache(cacheDir, "unconvert", "unconvert")
 		if err != nil {
 			t.Fatal(err)
 		}
-
-		if err := cmd.Start(); err != nil {
+		units, dirs, err := packageUnits(pkg.Dir, pkgScope)
+		if err != nil {
 			t.Fatal(err)
 		}
-
-		if err := stream.ForEach(stream.Sequence(
-			filter,
-			stream.GrepNot(`\.pb\.go:`),
-		), func(s string) {
-			t.Error(s)
-		}); err != nil {
-			t.Error(err)
-		}
-
-		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
-				t.Fatalf("err=%s, stderr=%s", err, out)
-			}
-		}
-	})
-
-	t.Run("TestMetacheck", func(t *testing.T) {
-		if testing.Short() {
-			t.Skip("short flag")
-		}
-		// metacheck uses 2.5GB of ram (as of 2017-02-18), so don't parallelize it.
-		cmd, stderr, filter, err := dirCmd(
-			pkg.Dir,
-			"metacheck",
-			"-ignore",
-
-			strings.Join([]string{
-				"github.com/cockroachdb/cockroach/pkg/security/securitytest/embedded.go:S1013",
-				"github.com/cockroachdb/cockroach/pkg/ui/embedded.go:S1013",
-
-				// Intentionally compare an unsigned integer <= 0 to avoid knowledge
-				// of the type at the caller and for consistency with convention.
-				"github.com/cockroachdb/cockroach/pkg/storage/replica.go:SA4003",
-				// Allow a comment to refer to an "unused" argument.
-				//
-				// TODO(bdarnell): remove when/if #8360 is fixed.
-				"github.com/cockroachdb/cockroach/pkg/storage/intent_resolver.go:SA4009",
-				// The generated parser is full of `case` arms such as:
-				//
-				// case 1:
-				// 	sqlDollar = sqlS[sqlpt-1 : sqlpt+1]
-				// 	//line sql.y:781
-				// 	{
-				// 		sqllex.(*Scanner).stmts = sqlDollar[1].union.stmts()
-				// 	}
-				//
-				// where the code in braces is generated from the grammar action; if
-				// the action does not make use of the matched expression, sqlDollar
-				// will be assigned but not used. This is expected and intentional.
-				//
-				// Concretely, the grammar:
-				//
-				// stmt:
-				//   alter_table_stmt
-				// | backup_stmt
-				// | copy_from_stmt
-				// | create_stmt
-				// | delete_stmt
-				// | drop_stmt
-				// | explain_stmt
-				// | help_stmt
-				// | prepare_stmt
-				// | execute_stmt
-				// | deallocate_stmt
-				// | grant_stmt
-				// | insert_stmt
-				// | rename_stmt
-				// | revoke_stmt
-				// | savepoint_stmt
-				// | select_stmt
-				//   {
-				//     $$.val = $1.slct()
-				//   }
-				// | set_stmt
-				// | show_stmt
-				// | split_stmt
-				// | transaction_stmt
-				// | release_stmt
-				// | truncate_stmt
-				// | update_stmt
-				// | /* EMPTY */
-				//   {
-				//     $$.val = Statement(nil)
-				//   }
-				//
-				// is compiled into the `case` arm:
-				//
-				// case 28:
-				// 	sqlDollar = sqlS[sqlpt-0 : sqlpt+1]
-				// 	//line sql.y:830
-				// 	{
-				// 		sqlVAL.union.val = Statement(nil)
-				// 	}
-				//
-				// which results in the unused warning:
-				//
-				// sql/parser/yaccpar:362:3: this value of sqlDollar is never used (SA4006)
-				"github.com/cockroachdb/cockroach/pkg/sql/parser/sql.go:SA4006",
-				// sql/parser/yaccpar:14:6: type sqlParser is unused (U1000)
-				// sql/parser/yaccpar:15:2: func sqlParser.Parse is unused (U1000)
-				// sql/parser/yaccpar:16:2: func sqlParser.Lookahead is unused (U1000)
-				// sql/parser/yaccpar:29:6: func sqlNewParser is unused (U1000)
-				// sql/parser/yaccpar:152:6: func sqlParse is unused (U1000)
-				"github.com/cockroachdb/cockroach/pkg/sql/parser/sql.go:U1000",
-				// Generated file containing many unused postgres error codes.
-				"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror/codes.go:U1000",
-				// Deprecated database/sql/driver interfaces not compatible with 1.7.
-				"github.com/cockroachdb/cockroach/pkg/sql/*.go:SA1019",
-				"github.com/cockroachdb/cockroach/pkg/cli/sql_util.go:SA1019",
-			}, " "),
-			// NB: this doesn't use `pkgScope` because `honnef.co/go/unused`
-			// produces many false positives unless it inspects all our packages.
-			"./...",
-		)
+		// unconvert type-checks the packages, so its output on a package also
+		// depends on those it imports.
+		pkgs, err := listPackages(pkg.Dir, "./...")
 		if err != nil {
 			t.Fatal(err)
 		}
-
-		if err := cmd.Start(); err != nil {
+		lock := filepath.Join(filepath.Dir(pkg.Dir), "Gopkg.lock")
+		out, err := cache.run(units, cache.typedPackageKey(dirs, pkgs, lock), func(units []string) ([]string, error) {
+			return outputLines(pkg.Dir, "unconvert", units...)
+		}, func(s string) string {
+			return linePackage(pkg.Dir, s)
+		})
+		if err != nil {
 			t.Fatal(err)
 		}
 
 		if err := stream.ForEach(stream.Sequence(
-			filter,
-			stream.GrepNot(`: (field no|type No)Copy is unused \(U1000\)$`),
+			stream.Items(out...),
+			diffFilter(),
+			stream.GrepNot(`\.pb\.go:`),
 		), func(s string) {
-			t.Error(s)
+			report.failLine(t, s, "", "")
 		}); err != nil {
 			t.Error(err)
 		}
+	})
 
-		if err := cmd.Wait(); err != nil {
-			if out := stderr.String(); len(out) > 0 {
-				t.Fatalf("err=%s, stderr=%s", err, out)
-			}
+	run("TestMetacheck", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("short flag")
 		}
+		t.Parallel()
+		// honnef.co/go/unused produces many false positives unless it inspects
+		// all our packages, so the unused identifiers are only reported, and
+		// the exceptions checked, when the whole tree is.
+		wholeTree := len(pkgScope) == 1 && pkgScope[0] == "./..."
+		if wholeTree {
+			defer checkUsed(t, "metacheck")
+		}
+		runMetacheck(loadProg(t), pkg.Dir, exceptions["metacheck"], func(path string, p metacheckProblem) {
+			if !inScope(path) || (p.Check == "U1000" && (!wholeTree || noCopyRE.MatchString(p.Text))) {
+				return
+			}
+			report.failLine(t, fmt.Sprintf("%s:%d:%d: %s (%s)", path, p.Position.Line, p.Position.Column, p.Text, p.Check), "", "")
+		})
 	})
 }
@@ -0,0 +1,361 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package acceptance
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+
+	"golang.org/x/net/context"
+)
+
+// Images used by the drivers which can't be installed in the postgres-test
+// image.
+const (
+	golangTestImage = "docker.io/library/golang:1.8"
+	mavenTestImage  = "docker.io/library/maven:3.5-jdk-8"
+)
+
+// driverMatrix lists the client drivers and ORMs whose typical usage is
+// exercised against a test cluster. Each script creates a schema, migrates it
+// and runs basic CRUD statements, exiting with an error if the results are not
+// the expected ones. The versions of the drivers are pinned so that failures
+// point at regressions in our pgwire compatibility rather than at upstream
+// changes.
+var driverMatrix = []struct {
+	name string
+	// image defaults to postgresTestImage.
+	image  string
+	script string
+}{
+	{name: "psycopg2", script: psycopg2CRUD},
+	{name: "sqlalchemy", script: sqlalchemyCRUD},
+	{name: "activerecord", script: activerecordCRUD},
+	{name: "pgx", image: golangTestImage, script: pgxCRUD},
+	{name: "hibernate", image: mavenTestImage, script: hibernateCRUD},
+}
+
+func TestDockerDriverMatrix(t *testing.T) {
+	s := log.Scope(t)
+	defer s.Close(t)
+
+	ctx := context.Background()
+	for _, d := range driverMatrix {
+		t.Run(d.name, func(t *testing.T) {
+			containerConfig := defaultContainerConfig()
+			if d.image != "" {
+				containerConfig.Image = d.image
+			}
+			containerConfig.Cmd = []string{"/bin/sh", "-c", d.script}
+			if err := testDockerSingleNode(ctx, t, d.name, containerConfig); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+const psycopg2CRUD = `
+set -e
+python - << 'EOF'
+import psycopg2
+
+conn = psycopg2.connect('')
+conn.autocommit = True
+cur = conn.cursor()
+cur.execute("CREATE DATABASE matrix")
+cur.execute("CREATE TABLE matrix.users (id INT PRIMARY KEY, name STRING)")
+
+# Migration.
+cur.execute("ALTER TABLE matrix.users ADD COLUMN email STRING")
+cur.execute("CREATE INDEX users_email_idx ON matrix.users (email)")
+
+cur.execute("INSERT INTO matrix.users VALUES (%s, %s, %s), (%s, %s, %s)",
+            (1, 'alice', 'alice@example.com', 2, 'bob', None))
+cur.execute("UPDATE matrix.users SET email = %s WHERE id = %s", ('bob@example.com', 2))
+cur.execute("DELETE FROM matrix.users WHERE id = %s", (1,))
+cur.execute("SELECT id, name, email FROM matrix.users ORDER BY id")
+v = cur.fetchall()
+assert v == [(2, 'bob', 'bob@example.com')], v
+EOF
+`
+
+const sqlalchemyCRUD = `
+set -e
+pip install -q SQLAlchemy==1.1.10
+python - << 'EOF'
+import sqlalchemy as sa
+from sqlalchemy.dialects.postgresql.base import PGDialect
+from sqlalchemy.ext.declarative import declarative_base
+from sqlalchemy.orm import sessionmaker
+
+# SQLAlchemy parses the server version out of version(), which doesn't use the
+# PostgreSQL format.
+PGDialect._get_server_version_info = lambda self, conn: (9, 5, 0)
+
+engine = sa.create_engine('postgresql://')
+engine.execute("CREATE DATABASE matrix")
+engine = sa.create_engine('postgresql:///matrix')
+
+Base = declarative_base()
+
+class User(Base):
+    __tablename__ = 'users'
+    id = sa.Column(sa.Integer, primary_key=True, autoincrement=False)
+    name = sa.Column(sa.String)
+
+Base.metadata.create_all(engine)
+
+# Migration.
+engine.execute("ALTER TABLE users ADD COLUMN email STRING")
+User.email = sa.Column(sa.String)
+User.__table__.append_column(User.email)
+
+session = sessionmaker(bind=engine)()
+session.add_all([User(id=1, name='alice', email='alice@example.com'), User(id=2, name='bob')])
+session.commit()
+session.query(User).filter_by(id=2).update({'email': 'bob@example.com'})
+session.query(User).filter_by(id=1).delete()
+session.commit()
+
+v = [(u.id, u.name, u.email) for u in session.query(User).order_by(User.id)]
+assert v == [(2, 'bob', 'bob@example.com')], v
+EOF
+`
+
+const activerecordCRUD = `
+set -e
+gem install --no-document activerecord -v 4.2.8
+ruby - << 'EOF'
+require 'pg'
+require 'active_record'
+
+PG.connect().exec('CREATE DATABASE matrix')
+ActiveRecord::Base.establish_connection(adapter: 'postgresql', database: 'matrix')
+
+class CreateUsers < ActiveRecord::Migration
+  def change
+    create_table :users do |t|
+      t.string :name
+    end
+  end
+end
+
+class AddEmailToUsers < ActiveRecord::Migration
+  def change
+    add_column :users, :email, :string
+    add_index :users, :email
+  end
+end
+
+CreateUsers.migrate(:up)
+AddEmailToUsers.migrate(:up)
+
+class User < ActiveRecord::Base
+end
+
+alice = User.create!(name: 'alice', email: 'alice@example.com')
+bob = User.create!(name: 'bob')
+bob.update!(email: 'bob@example.com')
+alice.destroy!
+
+v = User.order(:id).map { |u| [u.name, u.email] }
+raise 'Unexpected: ' + v.to_s unless v == [['bob', 'bob@example.com']]
+EOF
+`
+
+const pgxCRUD = `
+set -e
+git clone -q -b v3.0.0 https://github.com/jackc/pgx $GOPATH/src/github.com/jackc/pgx
+go get github.com/pkg/errors
+mkdir -p $GOPATH/src/matrix
+cat > $GOPATH/src/matrix/main.go << 'EOF'
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/jackc/pgx"
+)
+
+func main() {
+	cert, err := tls.LoadX509KeyPair(os.Getenv("PGSSLCERT"), os.Getenv("PGSSLKEY"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	port, err := strconv.Atoi(os.Getenv("PGPORT"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn, err := pgx.Connect(pgx.ConnConfig{
+		Host: os.Getenv("PGHOST"),
+		Port: uint16(port),
+		User: "root",
+		TLSConfig: &tls.Config{
+			Certificates:       []tls.Certificate{cert},
+			InsecureSkipVerify: true,
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, stmt := range []string{
+		"CREATE DATABASE matrix",
+		"CREATE TABLE matrix.users (id INT PRIMARY KEY, name STRING)",
+		// Migration.
+		"ALTER TABLE matrix.users ADD COLUMN email STRING",
+		"CREATE INDEX users_email_idx ON matrix.users (email)",
+	} {
+		if _, err := conn.Exec(stmt); err != nil {
+			log.Fatalf("%s: %s", stmt, err)
+		}
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO matrix.users VALUES ($1, $2, $3), ($4, $5, NULL)",
+		1, "alice", "alice@example.com", 2, "bob",
+	); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := tx.Exec("UPDATE matrix.users SET email = $1 WHERE id = $2", "bob@example.com", 2); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := tx.Exec("DELETE FROM matrix.users WHERE id = $1", 1); err != nil {
+		log.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	var id int
+	var name, email string
+	if err := conn.QueryRow("SELECT id, name, email FROM matrix.users").Scan(&id, &name, &email); err != nil {
+		log.Fatal(err)
+	}
+	if v := fmt.Sprintf("%d %s %s", id, name, email); v != "2 bob bob@example.com" {
+		log.Fatalf("unexpected: %s", v)
+	}
+}
+EOF
+go run $GOPATH/src/matrix/main.go
+`
+
+const hibernateCRUD = `
+set -e
+mkdir -p /matrix/src/main/java
+cd /matrix
+cat > pom.xml << 'EOF'
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <modelVersion>4.0.0</modelVersion>
+  <groupId>matrix</groupId>
+  <artifactId>matrix</artifactId>
+  <version>1.0</version>
+  <properties>
+    <maven.compiler.source>1.8</maven.compiler.source>
+    <maven.compiler.target>1.8</maven.compiler.target>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>org.hibernate</groupId>
+      <artifactId>hibernate-core</artifactId>
+      <version>5.2.10.Final</version>
+    </dependency>
+    <dependency>
+      <groupId>org.postgresql</groupId>
+      <artifactId>postgresql</artifactId>
+      <version>42.1.1</version>
+    </dependency>
+  </dependencies>
+</project>
+EOF
+cat > src/main/java/Main.java << 'EOF'
+import java.util.List;
+import javax.persistence.*;
+import org.hibernate.Session;
+import org.hibernate.SessionFactory;
+import org.hibernate.cfg.Configuration;
+
+public class Main {
+	@Entity(name = "User")
+	@Table(name = "users")
+	public static class User {
+		@Id
+		public int id;
+		public String name;
+		public String email;
+	}
+
+	private static User user(int id, String name, String email) {
+		User u = new User();
+		u.id = id;
+		u.name = name;
+		u.email = email;
+		return u;
+	}
+
+	public static void main(String[] args) throws Exception {
+		String url = "jdbc:postgresql://" + System.getenv("PGHOST") + ":" + System.getenv("PGPORT");
+		String params = "?ssl=true"
+			+ "&sslcert=" + System.getenv("PGSSLCERT")
+			+ "&sslkey=/matrix/key.pk8"
+			+ "&sslrootcert=/certs/ca.crt"
+			+ "&sslfactory=org.postgresql.ssl.jdbc4.LibPQFactory";
+		java.sql.DriverManager.getConnection(url + "/" + params)
+			.createStatement().execute("CREATE DATABASE matrix");
+
+		// Migration: hbm2ddl creates the table from the entity.
+		SessionFactory factory = new Configuration()
+			.addAnnotatedClass(User.class)
+			.setProperty("hibernate.connection.url", url + "/matrix" + params)
+			.setProperty("hibernate.connection.username", "root")
+			.setProperty("hibernate.dialect", "org.hibernate.dialect.PostgreSQL95Dialect")
+			.setProperty("hibernate.hbm2ddl.auto", "create")
+			.buildSessionFactory();
+
+		Session session = factory.openSession();
+		session.beginTransaction();
+		session.save(user(1, "alice", "alice@example.com"));
+		session.save(user(2, "bob", null));
+		session.getTransaction().commit();
+
+		session.beginTransaction();
+		session.get(User.class, 2).email = "bob@example.com";
+		session.delete(session.get(User.class, 1));
+		session.getTransaction().commit();
+		session.close();
+
+		session = factory.openSession();
+		List<User> users = session.createQuery("FROM User ORDER BY id", User.class).list();
+		if (users.size() != 1 || users.get(0).id != 2 || !"bob@example.com".equals(users.get(0).email)) {
+			throw new Exception("unexpected users: " + users.size());
+		}
+		session.close();
+		factory.close();
+	}
+}
+EOF
+# See: https://basildoncoder.com/blog/postgresql-jdbc-client-certificates.html
+openssl pkcs8 -topk8 -inform PEM -outform DER -in /certs/node.key -out key.pk8 -nocrypt
+mvn -q compile org.codehaus.mojo:exec-maven-plugin:1.6.0:java -Dexec.mainClass=Main
+`
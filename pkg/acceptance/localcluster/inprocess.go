@@ -0,0 +1,331 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localcluster
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/rpc"
+	"github.com/cockroachdb/cockroach/pkg/server"
+	"github.com/cockroachdb/cockroach/pkg/util"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// replicationTimeout is the time after which WaitForFullReplication gives up.
+const replicationTimeout = time.Minute
+
+// InProcessCluster is a cluster of nodes running in the current process, e.g.
+// that of a test, which doesn't need a cockroach binary or docker. Its nodes
+// can be killed and restarted, since their stores are on disk, and the
+// network between them can be partitioned: the connections they dial to each
+// other go through the cluster, which refuses them, and cuts those already
+// open, between the partitioned nodes.
+type InProcessCluster struct {
+	args  base.TestServerArgs
+	Nodes []*InProcessNode
+
+	mu struct {
+		syncutil.Mutex
+		// addrs maps the RPC addresses of the nodes, which they keep across
+		// restarts, to their indexes.
+		addrs map[string]int
+		// partitioned holds the pairs of nodes between which the network is
+		// partitioned.
+		partitioned map[nodePair]bool
+		// conns holds the open connections between the pairs of nodes.
+		conns map[nodePair]map[*clusterConn]struct{}
+	}
+}
+
+// nodePair is a pair of node indexes, the lowest first.
+type nodePair [2]int
+
+func makeNodePair(i, j int) nodePair {
+	if i > j {
+		i, j = j, i
+	}
+	return nodePair{i, j}
+}
+
+// InProcessNode holds the state of a node of an InProcessCluster, and provides
+// methods to kill and restart it.
+type InProcessNode struct {
+	c     *InProcessCluster
+	index int
+	dir   string
+
+	mu struct {
+		syncutil.Mutex
+		// server is nil while the node is killed.
+		server   *server.TestServer
+		addr     string
+		httpAddr string
+	}
+}
+
+// StartInProcess starts a cluster of size nodes, whose stores are in
+// subdirectories of dir, with the given arguments, and waits for its ranges to
+// be fully replicated. The cluster must be closed by Close, after which dir can
+// be removed.
+func StartInProcess(size int, dir string, args base.TestServerArgs) (*InProcessCluster, error) {
+	if args.JoinAddr != "" || args.Stopper != nil || len(args.StoreSpecs) != 0 {
+		return nil, errors.New("the cluster sets the join address, stopper and stores of its nodes")
+	}
+	c := &InProcessCluster{
+		args:  args,
+		Nodes: make([]*InProcessNode, size),
+	}
+	c.mu.addrs = make(map[string]int)
+	c.mu.partitioned = make(map[nodePair]bool)
+	c.mu.conns = make(map[nodePair]map[*clusterConn]struct{})
+	for i := range c.Nodes {
+		c.Nodes[i] = &InProcessNode{
+			c:     c,
+			index: i,
+			dir:   filepath.Join(dir, fmt.Sprintf("%d", i+1)),
+		}
+		if err := c.Nodes[i].Restart(); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if err := c.WaitForFullReplication(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close stops all the nodes.
+func (c *InProcessCluster) Close() {
+	// Quiesce the nodes in parallel: a node stopped once the others are could
+	// wait forever for its Raft commands to commit.
+	var stoppers []*stop.Stopper
+	for _, n := range c.Nodes {
+		if n == nil {
+			continue
+		}
+		n.mu.Lock()
+		if n.mu.server != nil {
+			stoppers = append(stoppers, n.mu.server.Stopper())
+			n.mu.server = nil
+		}
+		n.mu.Unlock()
+	}
+	var wg sync.WaitGroup
+	wg.Add(len(stoppers))
+	for _, s := range stoppers {
+		go func(s *stop.Stopper) {
+			defer wg.Done()
+			s.Quiesce(context.TODO())
+		}(s)
+	}
+	wg.Wait()
+	for _, s := range stoppers {
+		s.Stop(context.TODO())
+	}
+}
+
+// WaitForFullReplication waits for all the ranges to have as many replicas as
+// the default zone config requires, or as there are nodes if there are fewer.
+func (c *InProcessCluster) WaitForFullReplication() error {
+	numReplicas := int(config.DefaultZoneConfig().NumReplicas)
+	if len(c.Nodes) < numReplicas {
+		numReplicas = len(c.Nodes)
+	}
+	return util.RetryForDuration(replicationTimeout, func() error {
+		s := c.runningServer()
+		if s == nil {
+			return errors.New("no node is running")
+		}
+		rows, err := s.DB().Scan(context.TODO(), keys.Meta2Prefix, keys.MetaMax, 0)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			var desc roachpb.RangeDescriptor
+			if err := row.ValueProto(&desc); err != nil {
+				return err
+			}
+			if len(desc.Replicas) < numReplicas {
+				return errors.Errorf("r%d has %d replicas, expected %d",
+					desc.RangeID, len(desc.Replicas), numReplicas)
+			}
+		}
+		return nil
+	})
+}
+
+// runningServer returns the server of a running node, or nil if none is.
+func (c *InProcessCluster) runningServer() *server.TestServer {
+	for _, n := range c.Nodes {
+		if s := n.Server(); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// Partition partitions the network between the nodes with the given indexes:
+// the connections between them are cut, and they can't connect to each other
+// until Heal is called.
+func (c *InProcessCluster) Partition(i, j int) {
+	pair := makeNodePair(i, j)
+	c.mu.Lock()
+	c.mu.partitioned[pair] = true
+	conns := c.mu.conns[pair]
+	delete(c.mu.conns, pair)
+	c.mu.Unlock()
+
+	for conn := range conns {
+		// The node which dialed the connection is told it broke.
+		_ = conn.Conn.Close()
+	}
+}
+
+// Heal lets the nodes with the given indexes connect to each other again.
+func (c *InProcessCluster) Heal(i, j int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.mu.partitioned, makeNodePair(i, j))
+}
+
+// dial dials the connection from the node with the given index to addr,
+// through the cluster, which refuses it if the node at addr is partitioned
+// from it.
+func (c *InProcessCluster) dial(from int, addr string, timeout time.Duration) (net.Conn, error) {
+	c.mu.Lock()
+	to, ok := c.mu.addrs[addr]
+	pair := makeNodePair(from, to)
+	partitioned := ok && c.mu.partitioned[pair]
+	c.mu.Unlock()
+	if partitioned {
+		return nil, errors.Errorf("n%d is partitioned from n%d", from+1, to+1)
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil || !ok {
+		return conn, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The nodes may have been partitioned while the connection was dialed.
+	if c.mu.partitioned[pair] {
+		_ = conn.Close()
+		return nil, errors.Errorf("n%d is partitioned from n%d", from+1, to+1)
+	}
+	cc := &clusterConn{Conn: conn, c: c, pair: pair}
+	if c.mu.conns[pair] == nil {
+		c.mu.conns[pair] = make(map[*clusterConn]struct{})
+	}
+	c.mu.conns[pair][cc] = struct{}{}
+	return cc, nil
+}
+
+// clusterConn is a connection between two nodes of an InProcessCluster, which
+// the cluster tracks until it's closed.
+type clusterConn struct {
+	net.Conn
+	c    *InProcessCluster
+	pair nodePair
+}
+
+// Close implements the net.Conn interface.
+func (cc *clusterConn) Close() error {
+	cc.c.mu.Lock()
+	delete(cc.c.mu.conns[cc.pair], cc)
+	cc.c.mu.Unlock()
+	return cc.Conn.Close()
+}
+
+// Server returns the server of the node, or nil if the node is killed.
+func (n *InProcessNode) Server() *server.TestServer {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.mu.server
+}
+
+// Alive returns true if the node is running.
+func (n *InProcessNode) Alive() bool {
+	return n.Server() != nil
+}
+
+// Kill stops the node, without draining it first.
+func (n *InProcessNode) Kill() {
+	n.mu.Lock()
+	s := n.mu.server
+	n.mu.server = nil
+	n.mu.Unlock()
+	if s != nil {
+		s.Stopper().Stop(context.TODO())
+	}
+}
+
+// Restart starts the node again on its stores, with the same addresses, after
+// killing it if it is running. The node joins the cluster through the first
+// other node running, if any.
+func (n *InProcessNode) Restart() error {
+	n.Kill()
+
+	args := n.c.args
+	args.PartOfCluster = true
+	args.Stopper = stop.NewStopper()
+	args.StoreSpecs = []base.StoreSpec{{Path: n.dir}}
+	args.Knobs.RPC = &rpc.ContextTestingKnobs{
+		Dialer: func(addr string, timeout time.Duration) (net.Conn, error) {
+			return n.c.dial(n.index, addr, timeout)
+		},
+	}
+	for _, other := range n.c.Nodes {
+		if other == nil || other == n {
+			continue
+		}
+		if s := other.Server(); s != nil {
+			args.JoinAddr = s.ServingAddr()
+			break
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	args.Addr = n.mu.addr
+	args.HTTPAddr = n.mu.httpAddr
+	s := server.TestServerFactory.New(args).(*server.TestServer)
+	if err := s.Start(args); err != nil {
+		args.Stopper.Stop(context.TODO())
+		return errors.Wrapf(err, "starting n%d", n.index+1)
+	}
+	n.mu.server = s
+	n.mu.addr = s.ServingAddr()
+	n.mu.httpAddr = s.Cfg.HTTPAddr
+
+	n.c.mu.Lock()
+	n.c.mu.addrs[n.mu.addr] = n.index
+	n.c.mu.Unlock()
+	return nil
+}
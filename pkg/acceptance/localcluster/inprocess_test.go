@@ -0,0 +1,83 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package localcluster
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestInProcessCluster(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	if testing.Short() {
+		t.Skip("short flag")
+	}
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	c, err := StartInProcess(3, dir, base.TestServerArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx := context.TODO()
+
+	key := roachpb.Key("a")
+	put := func(i int, value string) {
+		testutils.SucceedsSoon(t, func() error {
+			return c.Nodes[i].Server().DB().Put(ctx, key, value)
+		})
+	}
+	checkValue := func(i int, value string) {
+		testutils.SucceedsSoon(t, func() error {
+			kv, err := c.Nodes[i].Server().DB().Get(ctx, key)
+			if err != nil {
+				return err
+			}
+			if v := string(kv.ValueBytes()); v != value {
+				return errors.Errorf("n%d: expected %q, got %q", i+1, value, v)
+			}
+			return nil
+		})
+	}
+
+	// A killed node catches up on the writes it missed once restarted.
+	put(0, "1")
+	c.Nodes[2].Kill()
+	if c.Nodes[2].Alive() {
+		t.Fatal("expected n3 to be killed")
+	}
+	put(0, "2")
+	if err := c.Nodes[2].Restart(); err != nil {
+		t.Fatal(err)
+	}
+	checkValue(2, "2")
+
+	// The nodes which aren't partitioned from the majority keep making
+	// progress, and the others catch up once the partition heals.
+	c.Partition(0, 1)
+	c.Partition(0, 2)
+	put(1, "3")
+	checkValue(2, "3")
+	c.Heal(0, 1)
+	c.Heal(0, 2)
+	checkValue(0, "3")
+}
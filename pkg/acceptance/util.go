@@ -559,7 +559,7 @@ func testDocker(
 	}
 	hostConfig := container.HostConfig{NetworkMode: "host"}
 	if err := l.OneShot(
-		ctx, postgresTestImage, types.ImagePullOptions{}, containerConfig, hostConfig, "docker-"+name,
+		ctx, containerConfig.Image, types.ImagePullOptions{}, containerConfig, hostConfig, "docker-"+name,
 	); err != nil {
 		return err
 	}
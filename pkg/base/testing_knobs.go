@@ -30,4 +30,6 @@ type TestingKnobs struct {
 	SQLLeaseManager  ModuleTestingKnobs
 	SQLSchemaChanger ModuleTestingKnobs
 	DistSQL          ModuleTestingKnobs
+	SQLJobs          ModuleTestingKnobs
+	RPC              ModuleTestingKnobs
 }
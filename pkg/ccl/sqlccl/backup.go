@@ -44,6 +44,10 @@ const (
 	BackupDescriptorName = "BACKUP"
 	// BackupFormatInitialVersion is the first version of backup and its files.
 	BackupFormatInitialVersion uint32 = 0
+
+	// showBackupOptCheckFiles makes SHOW BACKUP check the descriptor and files
+	// of the backup (see verifyBackup).
+	showBackupOptCheckFiles = "check_files"
 )
 
 // exportStorageFromURI returns an ExportStorage for the given URI.
@@ -531,6 +535,7 @@ func backupPlanHook(
 			Username:    p.User(),
 			Details:     jobs.BackupJobDetails{},
 		})
+		jobLogger.WithTestingKnobs(p.ExecCfg().JobsTestingKnobs)
 		desc, err := Backup(ctx,
 			p,
 			to,
@@ -601,6 +606,11 @@ func showBackupPlanHook(
 		if err != nil {
 			return nil, err
 		}
+		if _, ok := backup.Options.Get(showBackupOptCheckFiles); ok {
+			if err := verifyBackup(ctx, desc); err != nil {
+				return nil, err
+			}
+		}
 		var ret []parser.Datums
 		descs := make(map[sqlbase.ID]string)
 		for _, descriptor := range desc.Descriptors {
@@ -20,14 +20,12 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
-	"sort"
 	"strconv"
 	"strings"
 	"sync/atomic"
 	"testing"
 
 	"github.com/kr/pretty"
-	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
@@ -42,6 +40,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/jobutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/testcluster"
 	"github.com/cockroachdb/cockroach/pkg/util"
@@ -379,46 +378,6 @@ func backupAndRestore(
 	}
 }
 
-func verifySystemJob(
-	db *sqlutils.SQLRunner, offset int, expectedType string, expected jobs.JobRecord,
-) error {
-	var actual jobs.JobRecord
-	var rawDescriptorIDs pq.Int64Array
-	var actualType string
-	var statusString string
-	// We have to query for the nth job created rather than filtering by ID,
-	// because job-generating SQL queries (e.g. BACKUP) do not currently return
-	// the job ID.
-	db.QueryRow(`
-		SELECT type, description, username, descriptor_ids, status
-		FROM crdb_internal.jobs ORDER BY created LIMIT 1 OFFSET $1`,
-		offset,
-	).Scan(
-		&actualType, &actual.Description, &actual.Username, &rawDescriptorIDs,
-		&statusString,
-	)
-
-	for _, id := range rawDescriptorIDs {
-		actual.DescriptorIDs = append(actual.DescriptorIDs, sqlbase.ID(id))
-	}
-	sort.Sort(actual.DescriptorIDs)
-	sort.Sort(expected.DescriptorIDs)
-	expected.Details = nil
-	if e, a := expected, actual; !reflect.DeepEqual(e, a) {
-		return errors.Errorf("job %d did not match:\n%s",
-			offset, strings.Join(pretty.Diff(e, a), "\n"))
-	}
-
-	if e, a := jobs.JobStatusSucceeded, jobs.JobStatus(statusString); e != a {
-		return errors.Errorf("job %d: expected status %v, got %v", offset, e, a)
-	}
-	if e, a := expectedType, actualType; e != a {
-		return errors.Errorf("job %d: expected type %v, got type %v", offset, e, a)
-	}
-
-	return nil
-}
-
 // verifySystemJobProgress asserts that the fractionCompleted of the latest job
 // in the system.jobs table is approximately 0.5 when half of the expected
 // responses have completed.
@@ -525,7 +484,7 @@ func TestBackupRestoreSystemJobs(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if err := verifySystemJob(sqlDB, 1, jobs.JobTypeBackup, jobs.JobRecord{
+		if err := jobutils.VerifySystemJob(sqlDB, 1, jobs.JobTypeBackup, jobs.JobStatusSucceeded, jobs.JobRecord{
 			Username: security.RootUser,
 			Description: fmt.Sprintf(
 				`BACKUP DATABASE bench TO '%s' INCREMENTAL FROM '%s'`,
@@ -564,7 +523,7 @@ func TestBackupRestoreSystemJobs(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if err := verifySystemJob(sqlDB, 2, jobs.JobTypeRestore, jobs.JobRecord{
+		if err := jobutils.VerifySystemJob(sqlDB, 2, jobs.JobTypeRestore, jobs.JobStatusSucceeded, jobs.JobRecord{
 			Username: security.RootUser,
 			Description: fmt.Sprintf(
 				`RESTORE bench.* FROM '%s', '%s' WITH OPTIONS ('into_db'='bench2')`,
@@ -1186,6 +1145,7 @@ func TestBackupRestoreChecksum(t *testing.T) {
 	rawDir := strings.TrimPrefix(dir, "nodelocal://")
 
 	sqlDB.Exec(`BACKUP DATABASE bench TO $1`, dir)
+	sqlDB.Exec(`SHOW BACKUP $1 WITH OPTIONS ('check_files')`, dir)
 
 	var backupDesc BackupDescriptor
 	{
@@ -1216,7 +1176,17 @@ func TestBackupRestoreChecksum(t *testing.T) {
 		t.Fatalf("%+v", err)
 	}
 
+	// The corruption is detected up front when verification is requested.
+	_, err = sqlDB.DB.Exec(`SHOW BACKUP $1 WITH OPTIONS ('check_files')`, dir)
+	if !testutils.IsError(err, "checksum mismatch") {
+		t.Fatalf("expected 'checksum mismatch' error got: %+v", err)
+	}
+
 	sqlDB.Exec(`DROP TABLE bench.bank`)
+	_, err = sqlDB.DB.Exec(`RESTORE bench.* FROM $1 WITH OPTIONS ('experimental_verify')`, dir)
+	if !testutils.IsError(err, "verifying backup .*checksum mismatch") {
+		t.Fatalf("expected 'checksum mismatch' error got: %+v", err)
+	}
 	_, err = sqlDB.DB.Exec(`RESTORE bench.* FROM $1`, dir)
 	if !testutils.IsError(err, "checksum mismatch") {
 		t.Fatalf("expected 'checksum mismatch' error got: %+v", err)
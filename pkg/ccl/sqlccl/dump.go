@@ -0,0 +1,1649 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+package sqlccl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+)
+
+// DumpFormat is the format of a SQL dump read by LoadDump.
+type DumpFormat int
+
+const (
+	// DumpFormatCockroach is the format of `cockroach dump`, which Load reads
+	// as is.
+	DumpFormatCockroach DumpFormat = iota
+	// DumpFormatMySQL is the format of mysqldump.
+	DumpFormatMySQL
+	// DumpFormatPostgres is the plain SQL format of pg_dump.
+	DumpFormatPostgres
+)
+
+// A DumpNote records an object of a dump which was skipped, or translated
+// with some loss, and why.
+type DumpNote struct {
+	// Line is the line of the dump at which the statement defining the object
+	// starts.
+	Line   int
+	Object string
+	Reason string
+}
+
+func (n DumpNote) String() string {
+	return fmt.Sprintf("line %d: %s: %s", n.Line, n.Object, n.Reason)
+}
+
+// dumpInsertBatchRows is the number of rows of the COPY data of pg_dump
+// translated into each INSERT statement.
+const dumpInsertBatchRows = 100
+
+// TranslateDump translates a dump produced by mysqldump or pg_dump into the
+// statements read by Load, which it writes to w: a CREATE TABLE statement per
+// table, each followed by the INSERT statements of the table's rows. The
+// column types are converted to their closest equivalent. The objects which
+// Load doesn't support, such as secondary indexes, foreign keys, views or
+// sequences, are skipped. They're reported by the returned notes, along with
+// the conversions which lose information.
+//
+// pg_dump defines the primary keys after the data. The primary key of a table
+// is only kept if the table has no data; the other tables get an implicit
+// rowid primary key instead.
+func TranslateDump(r io.Reader, format DumpFormat, w io.Writer) ([]DumpNote, error) {
+	br := bufio.NewReader(r)
+	switch format {
+	case DumpFormatMySQL:
+	case DumpFormatPostgres:
+		if isPostgresArchive(br) {
+			return nil, errors.New("pg_dump archives aren't supported, " +
+				"use pg_restore -f to convert the archive to a plain SQL dump")
+		}
+	default:
+		return nil, errors.Errorf("unsupported dump format %d", format)
+	}
+
+	t := dumpTranslator{
+		mysql:  format == DumpFormatMySQL,
+		r:      dumpReader{r: br, mysql: format == DumpFormatMySQL, delim: ";", line: 1},
+		w:      w,
+		tables: make(map[string]*dumpTable),
+	}
+	for {
+		stmt, line, err := t.r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return t.notes, err
+		}
+		toks, err := lexDump(stmt, t.mysql)
+		if err != nil {
+			return t.notes, errors.Wrapf(err, "line %d", line)
+		}
+		if len(toks) == 0 {
+			continue
+		}
+		t.line = line + strings.Count(stmt[:toks[0].pos], "\n")
+		if err := t.translate(&dumpTokens{toks: toks}); err != nil {
+			return t.notes, errors.Wrapf(err, "line %d", t.line)
+		}
+	}
+	// The tables of pg_dump without data are only defined at the end.
+	for _, tab := range t.pending {
+		if err := t.define(tab); err != nil {
+			return t.notes, err
+		}
+	}
+	return t.notes, nil
+}
+
+// isPostgresArchive returns whether the dump is a pg_dump archive, in the
+// custom or the tar format, rather than a plain SQL dump.
+func isPostgresArchive(r *bufio.Reader) bool {
+	const tarMagicOffset = 257
+	header, _ := r.Peek(tarMagicOffset + len("ustar"))
+	return bytes.HasPrefix(header, []byte("PGDMP")) ||
+		(len(header) > tarMagicOffset && bytes.HasPrefix(header[tarMagicOffset:], []byte("ustar")))
+}
+
+// A dumpReader splits a dump into statements. It knows enough of the lexical
+// structure of the dialects to find the delimiters of the statements.
+type dumpReader struct {
+	r     *bufio.Reader
+	mysql bool
+	// delim is the delimiter of the statements, which the DELIMITER command of
+	// the mysql client changes.
+	delim string
+	// buf holds the input read and not returned yet, which starts at line.
+	buf  []byte
+	line int
+	eof  bool
+}
+
+// fill reads the next line of input into buf.
+func (d *dumpReader) fill() error {
+	line, err := d.r.ReadBytes('\n')
+	d.buf = append(d.buf, line...)
+	if err == io.EOF {
+		d.eof = true
+		return nil
+	}
+	return err
+}
+
+// consume drops the first n bytes of buf, and returns them.
+func (d *dumpReader) consume(n int) string {
+	s := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	d.line += strings.Count(s, "\n")
+	return s
+}
+
+func (d *dumpReader) at(i int) byte {
+	if i < len(d.buf) {
+		return d.buf[i]
+	}
+	return 0
+}
+
+var dollarTagRE = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// next returns the next statement, without its delimiter, and the line at
+// which it starts. It returns io.EOF at the end of the dump.
+func (d *dumpReader) next() (string, int, error) {
+	var quote byte
+	var escapes bool
+	var dollarTag string
+	var lineComment, blockComment bool
+	// blank is set until the statement has any content but comments.
+	blank := true
+	for i := 0; ; {
+		if i == len(d.buf) {
+			if d.eof {
+				if blank {
+					d.consume(len(d.buf))
+					return "", 0, io.EOF
+				}
+				line := d.line
+				return d.consume(len(d.buf)), line, nil
+			}
+			if err := d.fill(); err != nil {
+				return "", 0, err
+			}
+			continue
+		}
+		c := d.buf[i]
+		switch {
+		case lineComment:
+			lineComment = c != '\n'
+			i++
+		case blockComment:
+			if c == '*' && d.at(i+1) == '/' {
+				blockComment = false
+				i += 2
+			} else {
+				i++
+			}
+		case dollarTag != "":
+			if bytes.HasPrefix(d.buf[i:], []byte(dollarTag)) {
+				i += len(dollarTag)
+				dollarTag = ""
+			} else {
+				i++
+			}
+		case quote != 0:
+			if c == '\\' && escapes {
+				i += 2
+				continue
+			}
+			if c == quote {
+				quote = 0
+			}
+			i++
+		case blank && d.isClientCommand(i):
+			// Client commands end at the end of their line. Their line is
+			// complete, since the buffer is filled a line at a time.
+			j := bytes.IndexByte(d.buf[i:], '\n')
+			if j == -1 {
+				if !d.eof {
+					if err := d.fill(); err != nil {
+						return "", 0, err
+					}
+					continue
+				}
+				j = len(d.buf) - i
+			}
+			if fields := strings.Fields(string(d.buf[i : i+j])); d.mysql && len(fields) == 2 {
+				d.delim = fields[1]
+			}
+			d.consume(i + j)
+			i = 0
+		case bytes.HasPrefix(d.buf[i:], []byte(d.delim)):
+			line := d.line
+			stmt := d.consume(i)
+			d.consume(len(d.delim))
+			return stmt, line, nil
+		case c == '\'' || c == '"' || (c == '`' && d.mysql):
+			quote = c
+			// Backslashes escape in the strings of MySQL, and in the escape
+			// strings of Postgres, e.g. E'\n'.
+			escapes = (d.mysql && c != '`') ||
+				(c == '\'' && i > 0 && (d.buf[i-1] == 'e' || d.buf[i-1] == 'E') &&
+					(i < 2 || !isDumpIdentChar(d.buf[i-2])))
+			blank = false
+			i++
+		case c == '-' && d.at(i+1) == '-', c == '#' && d.mysql:
+			lineComment = true
+			i++
+		case c == '/' && d.at(i+1) == '*':
+			blockComment = true
+			i += 2
+		case c == '$' && !d.mysql && (i == 0 || !isDumpIdentChar(d.buf[i-1])):
+			blank = false
+			if tag := dollarTagRE.Find(d.buf[i:]); tag != nil {
+				dollarTag = string(tag)
+				i += len(tag)
+			} else {
+				i++
+			}
+		default:
+			if !isDumpSpace(c) {
+				blank = false
+			}
+			i++
+		}
+	}
+}
+
+// isClientCommand returns whether a command of the client starts at buf[i]:
+// DELIMITER for mysql, or a backslash command such as \connect for psql.
+func (d *dumpReader) isClientCommand(i int) bool {
+	if !d.mysql {
+		return d.buf[i] == '\\'
+	}
+	const delimiter = "DELIMITER"
+	return len(d.buf)-i > len(delimiter) &&
+		strings.EqualFold(string(d.buf[i:i+len(delimiter)]), delimiter) &&
+		isDumpSpace(d.buf[i+len(delimiter)])
+}
+
+// readLine returns the next line of input, without its line terminator. It's
+// used to read the data of the COPY statements of pg_dump.
+func (d *dumpReader) readLine() (string, error) {
+	for {
+		if j := bytes.IndexByte(d.buf, '\n'); j != -1 {
+			line := d.consume(j + 1)
+			return strings.TrimRight(line, "\r\n"), nil
+		}
+		if d.eof {
+			return "", io.ErrUnexpectedEOF
+		}
+		if err := d.fill(); err != nil {
+			return "", err
+		}
+	}
+}
+
+func isDumpSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isDumpIdentChar(c byte) bool {
+	return c == '_' || c == '$' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isDumpDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+type dumpTokenKind int
+
+const (
+	tokEOF dumpTokenKind = iota
+	// tokWord is a keyword or an unquoted identifier.
+	tokWord
+	// tokIdent is a quoted identifier.
+	tokIdent
+	tokString
+	tokNumber
+	tokPunct
+)
+
+// A dumpToken is a lexical token of a statement of a dump.
+type dumpToken struct {
+	kind dumpTokenKind
+	// s is the text of the token, decoded for the quoted identifiers and the
+	// strings.
+	s   string
+	pos int
+}
+
+func (t dumpToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of statement"
+	case tokString:
+		return parser.AsString(parser.NewDString(t.s))
+	case tokIdent:
+		return parser.AsString(parser.Name(t.s))
+	}
+	return t.s
+}
+
+// lexDump splits a statement of a dump into tokens. The conditional comments
+// of MySQL, e.g. /*!40101 SET NAMES utf8 */, are lexed as their content.
+func lexDump(s string, mysql bool) ([]dumpToken, error) {
+	var toks []dumpToken
+	at := func(i int) byte {
+		if i < len(s) {
+			return s[i]
+		}
+		return 0
+	}
+	conditional := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		start := i
+		switch {
+		case isDumpSpace(c):
+			i++
+		case c == '-' && at(i+1) == '-', c == '#' && mysql:
+			if j := strings.IndexByte(s[i:], '\n'); j != -1 {
+				i += j + 1
+			} else {
+				i = len(s)
+			}
+		case c == '/' && at(i+1) == '*' && at(i+2) == '!' && mysql:
+			i += 3
+			for i < len(s) && isDumpDigit(s[i]) {
+				i++
+			}
+			conditional++
+		case c == '*' && at(i+1) == '/' && conditional > 0:
+			i += 2
+			conditional--
+		case c == '/' && at(i+1) == '*':
+			j := strings.Index(s[i+2:], "*/")
+			if j == -1 {
+				return nil, errors.New("unterminated comment")
+			}
+			i += j + 4
+		case c == '\'' || (c == '"' && mysql):
+			str, n, err := unquoteDump(s[i:], mysql, mysql)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, dumpToken{kind: tokString, s: str, pos: start})
+			i += n
+		case c == '"' || (c == '`' && mysql):
+			str, n, err := unquoteDump(s[i:], false, mysql)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, dumpToken{kind: tokIdent, s: str, pos: start})
+			i += n
+		case c == '0' && (at(i+1) == 'x' || at(i+1) == 'X') && mysql:
+			j := i + 2
+			for j < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[j]) != -1 {
+				j++
+			}
+			b, err := hex.DecodeString(s[i+2 : j])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid hexadecimal literal %s", s[i:j])
+			}
+			toks = append(toks, dumpToken{kind: tokString, s: string(b), pos: start})
+			i = j
+		case isDumpDigit(c) || (c == '.' && isDumpDigit(at(i+1))):
+			for i < len(s) && (isDumpDigit(s[i]) || s[i] == '.') {
+				i++
+			}
+			if at(i) == 'e' || at(i) == 'E' {
+				j := i + 1
+				if at(j) == '+' || at(j) == '-' {
+					j++
+				}
+				if isDumpDigit(at(j)) {
+					for i = j; i < len(s) && isDumpDigit(s[i]); i++ {
+					}
+				}
+			}
+			toks = append(toks, dumpToken{kind: tokNumber, s: s[start:i], pos: start})
+		case isDumpIdentChar(c):
+			for i < len(s) && isDumpIdentChar(s[i]) {
+				i++
+			}
+			word := s[start:i]
+			if mysql && word[0] == '_' {
+				// The character set introducers of MySQL, e.g. _binary 'foo'.
+				j := i
+				for j < len(s) && isDumpSpace(s[j]) {
+					j++
+				}
+				if at(j) == '\'' {
+					i = j
+				}
+			}
+			if at(i) != '\'' {
+				toks = append(toks, dumpToken{kind: tokWord, s: word, pos: start})
+				continue
+			}
+			// A prefixed string, e.g. E'\n', x'00' or MySQL's _binary'\0'.
+			str, n, err := unquoteDump(s[i:], mysql || strings.EqualFold(word, "e"), mysql)
+			if err != nil {
+				return nil, err
+			}
+			i += n
+			switch {
+			case strings.EqualFold(word, "x"):
+				b, err := hex.DecodeString(str)
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid hexadecimal literal %s", s[start:i])
+				}
+				str = string(b)
+			case strings.EqualFold(word, "e"), strings.EqualFold(word, "b"), strings.EqualFold(word, "n"):
+			case mysql && word[0] == '_':
+			default:
+				return nil, errors.Errorf("unsupported literal %s", s[start:i])
+			}
+			toks = append(toks, dumpToken{kind: tokString, s: str, pos: start})
+		case c == '$' && !mysql:
+			tag := dollarTagRE.FindString(s[i:])
+			if tag == "" {
+				toks = append(toks, dumpToken{kind: tokPunct, s: "$", pos: start})
+				i++
+				continue
+			}
+			j := strings.Index(s[i+len(tag):], tag)
+			if j == -1 {
+				return nil, errors.New("unterminated dollar-quoted string")
+			}
+			toks = append(toks, dumpToken{kind: tokString, s: s[i+len(tag) : i+len(tag)+j], pos: start})
+			i += len(tag) + j + len(tag)
+		case c == ':' && at(i+1) == ':':
+			toks = append(toks, dumpToken{kind: tokPunct, s: "::", pos: start})
+			i += 2
+		default:
+			toks = append(toks, dumpToken{kind: tokPunct, s: s[i : i+1], pos: start})
+			i++
+		}
+	}
+	return toks, nil
+}
+
+// unquoteDump decodes the quoted string or identifier at the start of s, and
+// returns it along with its length in s. A quote is escaped by doubling it,
+// or by a backslash if escapes is set, in which case the other backslash
+// escapes of the dialect are decoded too.
+func unquoteDump(s string, escapes, mysql bool) (string, int, error) {
+	quote := s[0]
+	var buf bytes.Buffer
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				buf.WriteByte(quote)
+				i++
+				continue
+			}
+			return buf.String(), i + 1, nil
+		case c == '\\' && escapes && i+1 < len(s):
+			i++
+			if mysql {
+				buf.WriteString(unescapeMySQL(s[i]))
+			} else {
+				i += unescapePostgres(&buf, s[i:]) - 1
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", 0, errors.Errorf("unterminated quoted string %.20q", s)
+}
+
+// unescapeMySQL returns the character escaped by a backslash in MySQL.
+func unescapeMySQL(c byte) string {
+	switch c {
+	case '0':
+		return "\x00"
+	case 'b':
+		return "\b"
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 't':
+		return "\t"
+	case 'Z':
+		return "\x1a"
+	case '%', '_':
+		// These are only escaped in patterns, and keep their backslash.
+		return "\\" + string(c)
+	}
+	return string(c)
+}
+
+// unescapePostgres decodes the backslash escape of Postgres which s starts
+// with, following the backslash, and returns its length. It's used for the
+// escape strings, and the text format of COPY.
+func unescapePostgres(buf *bytes.Buffer, s string) int {
+	switch c := s[0]; c {
+	case 'b':
+		buf.WriteByte('\b')
+	case 'f':
+		buf.WriteByte('\f')
+	case 'n':
+		buf.WriteByte('\n')
+	case 'r':
+		buf.WriteByte('\r')
+	case 't':
+		buf.WriteByte('\t')
+	case 'v':
+		buf.WriteByte('\v')
+	case 'x':
+		n := 1
+		for n < 3 && n < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[n]) != -1 {
+			n++
+		}
+		if n == 1 {
+			buf.WriteByte(c)
+			return 1
+		}
+		v, _ := strconv.ParseUint(s[1:n], 16, 8)
+		buf.WriteByte(byte(v))
+		return n
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		n := 1
+		for n < 3 && n < len(s) && s[n] >= '0' && s[n] <= '7' {
+			n++
+		}
+		v, _ := strconv.ParseUint(s[:n], 8, 8)
+		buf.WriteByte(byte(v))
+		return n
+	default:
+		buf.WriteByte(c)
+	}
+	return 1
+}
+
+// dumpTokens is the cursor of the translation of a statement.
+type dumpTokens struct {
+	toks []dumpToken
+	i    int
+}
+
+func (t *dumpTokens) done() bool { return t.i == len(t.toks) }
+
+func (t *dumpTokens) peek() dumpToken {
+	if t.done() {
+		return dumpToken{}
+	}
+	return t.toks[t.i]
+}
+
+func (t *dumpTokens) next() dumpToken {
+	tok := t.peek()
+	if !t.done() {
+		t.i++
+	}
+	return tok
+}
+
+// isWord returns whether the next tokens are the given keywords.
+func (t *dumpTokens) isWord(words ...string) bool {
+	if len(t.toks)-t.i < len(words) {
+		return false
+	}
+	for i, w := range words {
+		if tok := t.toks[t.i+i]; tok.kind != tokWord || !strings.EqualFold(tok.s, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptWord consumes the given keywords if they're next.
+func (t *dumpTokens) acceptWord(words ...string) bool {
+	if !t.isWord(words...) {
+		return false
+	}
+	t.i += len(words)
+	return true
+}
+
+func (t *dumpTokens) isPunct(p string) bool {
+	tok := t.peek()
+	return tok.kind == tokPunct && tok.s == p
+}
+
+func (t *dumpTokens) acceptPunct(p string) bool {
+	if !t.isPunct(p) {
+		return false
+	}
+	t.i++
+	return true
+}
+
+// ident consumes an identifier, quoted or not.
+func (t *dumpTokens) ident() (string, error) {
+	tok := t.next()
+	if tok.kind != tokWord && tok.kind != tokIdent {
+		return "", errors.Errorf("expected a name, found %s", tok)
+	}
+	return tok.s, nil
+}
+
+// name consumes a name, and returns its last part: the schema of Postgres,
+// or the database of MySQL, is dropped.
+func (t *dumpTokens) name() (string, error) {
+	name, err := t.ident()
+	for err == nil && t.acceptPunct(".") {
+		name, err = t.ident()
+	}
+	return name, err
+}
+
+// list consumes a parenthesized list, and returns the tokens of each of its
+// elements.
+func (t *dumpTokens) list() ([][]dumpToken, error) {
+	if !t.acceptPunct("(") {
+		return nil, errors.Errorf("expected (, found %s", t.peek())
+	}
+	var items [][]dumpToken
+	start, depth := t.i, 0
+	for !t.done() {
+		tok := t.next()
+		if tok.kind != tokPunct {
+			continue
+		}
+		switch tok.s {
+		case "(":
+			depth++
+		case ")":
+			if depth > 0 {
+				depth--
+				continue
+			}
+			if t.i-1 > start || len(items) > 0 {
+				items = append(items, t.toks[start:t.i-1])
+			}
+			return items, nil
+		case ",":
+			if depth == 0 {
+				items = append(items, t.toks[start:t.i-1])
+				start = t.i
+			}
+		}
+	}
+	return nil, errors.New("unterminated parenthesized list")
+}
+
+// skipGroup consumes a parenthesized group, if it's next.
+func (t *dumpTokens) skipGroup() error {
+	if !t.isPunct("(") {
+		return nil
+	}
+	_, err := t.list()
+	return err
+}
+
+// dumpValueKind determines how the values of a column are written.
+type dumpValueKind int
+
+const (
+	// valueString values are written as strings, which Load parses into the
+	// type of the column.
+	valueString dumpValueKind = iota
+	valueNumber
+	valueBytes
+	// valueDate values are written as strings, except for the zero dates of
+	// MySQL, which are written as NULL.
+	valueDate
+)
+
+// A dumpType is the translation of a column type.
+type dumpType struct {
+	// typ is the translated type, in which %s stands for the arguments of the
+	// original type, e.g. the (10, 2) of DECIMAL(10, 2).
+	typ  string
+	kind dumpValueKind
+	// loss, if set, describes the information lost by the translation.
+	loss string
+}
+
+var (
+	smallintType  = dumpType{typ: "SMALLINT", kind: valueNumber}
+	intType       = dumpType{typ: "INT", kind: valueNumber}
+	bigintType    = dumpType{typ: "BIGINT", kind: valueNumber}
+	realType      = dumpType{typ: "REAL", kind: valueNumber}
+	doubleType    = dumpType{typ: "DOUBLE PRECISION", kind: valueNumber}
+	decimalType   = dumpType{typ: "DECIMAL%s", kind: valueNumber}
+	charType      = dumpType{typ: "CHAR%s"}
+	varcharType   = dumpType{typ: "VARCHAR%s"}
+	stringType    = dumpType{typ: "STRING"}
+	bytesType     = dumpType{typ: "BYTES", kind: valueBytes}
+	dateType      = dumpType{typ: "DATE", kind: valueDate}
+	timestampType = dumpType{typ: "TIMESTAMP", kind: valueDate}
+	timeType      = dumpType{typ: "INTERVAL", loss: "TIME is stored as INTERVAL"}
+)
+
+// mysqlTypes are the translations of the column types of MySQL. The others
+// are stored as STRING.
+var mysqlTypes = map[string]dumpType{
+	"tinyint":          smallintType,
+	"smallint":         smallintType,
+	"year":             smallintType,
+	"mediumint":        intType,
+	"int":              intType,
+	"integer":          intType,
+	"bigint":           bigintType,
+	"float":            realType,
+	"double":           doubleType,
+	"double precision": doubleType,
+	"real":             doubleType,
+	"decimal":          decimalType,
+	"dec":              decimalType,
+	"numeric":          decimalType,
+	"fixed":            decimalType,
+	"char":             charType,
+	"varchar":          varcharType,
+	"tinytext":         stringType,
+	"text":             stringType,
+	"mediumtext":       stringType,
+	"longtext":         stringType,
+	"binary":           bytesType,
+	"varbinary":        bytesType,
+	"tinyblob":         bytesType,
+	"blob":             bytesType,
+	"mediumblob":       bytesType,
+	"longblob":         bytesType,
+	"date":             dateType,
+	"datetime":         timestampType,
+	"timestamp":        timestampType,
+	"time":             timeType,
+	"enum":             {typ: "STRING", loss: "the allowed values aren't enforced"},
+	"set":              {typ: "STRING", loss: "the allowed values aren't enforced"},
+}
+
+// postgresTypes are the translations of the column types of Postgres. The
+// others are stored as STRING.
+var postgresTypes = map[string]dumpType{
+	"smallint":                    smallintType,
+	"int2":                        smallintType,
+	"integer":                     intType,
+	"int":                         intType,
+	"int4":                        intType,
+	"bigint":                      bigintType,
+	"int8":                        bigintType,
+	"real":                        realType,
+	"float4":                      realType,
+	"double precision":            doubleType,
+	"float8":                      doubleType,
+	"numeric":                     decimalType,
+	"decimal":                     decimalType,
+	"boolean":                     {typ: "BOOL"},
+	"bool":                        {typ: "BOOL"},
+	"character":                   charType,
+	"char":                        charType,
+	"bpchar":                      charType,
+	"character varying":           varcharType,
+	"varchar":                     varcharType,
+	"text":                        stringType,
+	"bytea":                       bytesType,
+	"date":                        {typ: "DATE"},
+	"timestamp":                   {typ: "TIMESTAMP"},
+	"timestamp without time zone": {typ: "TIMESTAMP"},
+	"timestamp with time zone":    {typ: "TIMESTAMPTZ"},
+	"timestamptz":                 {typ: "TIMESTAMPTZ"},
+	"interval":                    {typ: "INTERVAL"},
+	"uuid":                        {typ: "UUID"},
+	"time":                        timeType,
+	"time without time zone":      timeType,
+}
+
+// columnOptions are the keywords which end the type of a column definition.
+var columnOptions = map[string]bool{
+	"NOT": true, "NULL": true, "DEFAULT": true, "AUTO_INCREMENT": true, "COMMENT": true,
+	"COLLATE": true, "CHARSET": true, "PRIMARY": true, "UNIQUE": true, "KEY": true,
+	"ON": true, "CONSTRAINT": true, "CHECK": true, "REFERENCES": true, "GENERATED": true,
+	"AS": true, "VIRTUAL": true, "STORED": true,
+}
+
+// A dumpColumn is a column of a table of a dump, translated.
+type dumpColumn struct {
+	name    string
+	typ     string
+	kind    dumpValueKind
+	notNull bool
+	// def is the translated default expression, if any.
+	def     string
+	primary bool
+}
+
+// A dumpTable is a table of a dump, translated.
+type dumpTable struct {
+	name string
+	cols []dumpColumn
+	// pk are the columns of the primary key, if defined by a constraint.
+	pk []string
+	// defined is set once the CREATE TABLE statement was written.
+	defined bool
+	// zeroDates is set once zero dates were replaced by NULL in the data of
+	// the table, which is only noted once.
+	zeroDates bool
+}
+
+func (tab *dumpTable) column(name string) (int, error) {
+	for i := range tab.cols {
+		if tab.cols[i].name == name {
+			return i, nil
+		}
+	}
+	return 0, errors.Errorf("unknown column %q of table %q", name, tab.name)
+}
+
+// columnOrder returns the indexes of the columns of the table listed by the
+// column list which comes next, if any, or else of all the columns. The
+// listed columns must be all the columns of the table.
+func (tab *dumpTable) columnOrder(ts *dumpTokens) ([]int, error) {
+	order := make([]int, len(tab.cols))
+	if !ts.isPunct("(") {
+		for i := range order {
+			order[i] = i
+		}
+		return order, nil
+	}
+	items, err := ts.list()
+	if err != nil {
+		return nil, err
+	}
+	if len(items) != len(tab.cols) {
+		return nil, errors.Errorf("expected the %d columns of table %q, found %d",
+			len(tab.cols), tab.name, len(items))
+	}
+	for i, item := range items {
+		name, err := (&dumpTokens{toks: item}).ident()
+		if err != nil {
+			return nil, err
+		}
+		if order[i], err = tab.column(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// A dumpTranslator translates the statements of a dump.
+type dumpTranslator struct {
+	mysql bool
+	r     dumpReader
+	w     io.Writer
+	notes []DumpNote
+	// line is the line at which the statement being translated starts.
+	line   int
+	tables map[string]*dumpTable
+	// pending are the tables of pg_dump, which are only defined once their
+	// data comes, in order.
+	pending []*dumpTable
+}
+
+func (t *dumpTranslator) note(object, reason string) {
+	t.notes = append(t.notes, DumpNote{Line: t.line, Object: object, Reason: reason})
+}
+
+// skip notes a statement defining an object which isn't translated.
+func (t *dumpTranslator) skip(ts *dumpTokens, reason string) {
+	t.note(describeDumpObject(ts), reason)
+}
+
+// describeDumpObject describes the object defined by a statement, e.g. `view
+// "foo"`.
+func describeDumpObject(ts *dumpTokens) string {
+	kinds := []string{"TABLE", "VIEW", "INDEX", "SEQUENCE", "FUNCTION", "PROCEDURE", "TRIGGER",
+		"EVENT", "TYPE", "DOMAIN", "EXTENSION", "SCHEMA", "AGGREGATE", "OPERATOR", "RULE",
+		"POLICY", "COLLATION", "LANGUAGE", "SERVER", "PUBLICATION", "SUBSCRIPTION"}
+	for i := ts.i; i < len(ts.toks); i++ {
+		for _, kind := range kinds {
+			if ts.toks[i].kind != tokWord || !strings.EqualFold(ts.toks[i].s, kind) {
+				continue
+			}
+			rest := &dumpTokens{toks: ts.toks, i: i + 1}
+			rest.acceptWord("IF", "NOT", "EXISTS")
+			rest.acceptWord("CONCURRENTLY")
+			if name, err := rest.name(); err == nil {
+				return fmt.Sprintf("%s %q", strings.ToLower(kind), name)
+			}
+			return strings.ToLower(kind)
+		}
+	}
+	var words []string
+	for i := ts.i; i < len(ts.toks) && i < ts.i+3; i++ {
+		words = append(words, ts.toks[i].String())
+	}
+	return fmt.Sprintf("statement %s", strings.Join(words, " "))
+}
+
+func (t *dumpTranslator) translate(ts *dumpTokens) error {
+	if t.mysql {
+		return t.translateMySQL(ts)
+	}
+	return t.translatePostgres(ts)
+}
+
+func (t *dumpTranslator) translateMySQL(ts *dumpTokens) error {
+	switch {
+	case ts.acceptWord("CREATE", "TABLE"), ts.acceptWord("CREATE", "TEMPORARY", "TABLE"):
+		tab, err := t.createTable(ts)
+		if err != nil {
+			return err
+		}
+		return t.define(tab)
+
+	case ts.acceptWord("INSERT"), ts.acceptWord("REPLACE"):
+		ts.acceptWord("IGNORE")
+		ts.acceptWord("INTO")
+		return t.insert(ts)
+
+	case ts.isWord("SET"), ts.isWord("USE"), ts.isWord("LOCK"), ts.isWord("UNLOCK"),
+		ts.isWord("DROP"), ts.isWord("START"), ts.isWord("BEGIN"), ts.isWord("COMMIT"),
+		ts.isWord("CREATE", "DATABASE"), ts.isWord("CREATE", "SCHEMA"):
+		// Session settings, locking and the like.
+		return nil
+
+	case ts.isWord("ALTER", "TABLE"):
+		if last := ts.toks[len(ts.toks)-1]; len(ts.toks) > 4 && strings.EqualFold(last.s, "KEYS") {
+			// ALTER TABLE ... {DISABLE,ENABLE} KEYS.
+			return nil
+		}
+		t.skip(ts, "ALTER TABLE isn't supported by LOAD")
+		return nil
+	}
+	t.skip(ts, "not supported by LOAD")
+	return nil
+}
+
+func (t *dumpTranslator) translatePostgres(ts *dumpTokens) error {
+	switch {
+	case ts.acceptWord("CREATE", "TABLE"), ts.acceptWord("CREATE", "UNLOGGED", "TABLE"):
+		tab, err := t.createTable(ts)
+		if err != nil {
+			return err
+		}
+		t.pending = append(t.pending, tab)
+		return nil
+
+	case ts.acceptWord("COPY"):
+		return t.copyFrom(ts)
+
+	case ts.acceptWord("INSERT", "INTO"):
+		return t.insert(ts)
+
+	case ts.isWord("ALTER") && len(ts.toks) > 3 && ts.toks[len(ts.toks)-3].kind == tokWord &&
+		strings.EqualFold(ts.toks[len(ts.toks)-3].s, "OWNER"):
+		// ALTER ... OWNER TO ...
+		return nil
+
+	case ts.isWord("ALTER", "TABLE"):
+		return t.alterTable(ts)
+
+	case ts.isWord("SET"), ts.isWord("SELECT"), ts.isWord("COMMENT"), ts.isWord("GRANT"),
+		ts.isWord("REVOKE"), ts.isWord("DROP"), ts.isWord("BEGIN"), ts.isWord("COMMIT"),
+		ts.isWord("SECURITY", "LABEL"), ts.isWord("ALTER", "SEQUENCE"),
+		ts.isWord("ALTER", "DEFAULT", "PRIVILEGES"), ts.isWord("CREATE", "DATABASE"):
+		// Session settings, sequence values, permissions and the like.
+		return nil
+
+	case ts.isWord("CREATE", "INDEX"), ts.isWord("CREATE", "UNIQUE", "INDEX"):
+		t.skip(ts, "secondary indexes aren't supported by LOAD; create it after the restore")
+		return nil
+	}
+	t.skip(ts, "not supported by LOAD")
+	return nil
+}
+
+// createTable translates the CREATE TABLE statement following CREATE TABLE.
+// The options of the table which follow its definition are ignored.
+func (t *dumpTranslator) createTable(ts *dumpTokens) (*dumpTable, error) {
+	ts.acceptWord("IF", "NOT", "EXISTS")
+	name, err := ts.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := t.tables[name]; ok {
+		return nil, errors.Errorf("duplicate table %q", name)
+	}
+	items, err := ts.list()
+	if err != nil {
+		return nil, err
+	}
+	tab := &dumpTable{name: name}
+	for _, item := range items {
+		if err := t.tableElem(tab, &dumpTokens{toks: item}); err != nil {
+			return nil, errors.Wrapf(err, "table %q", name)
+		}
+	}
+	t.tables[name] = tab
+	return tab, nil
+}
+
+func (t *dumpTranslator) tableElem(tab *dumpTable, ts *dumpTokens) error {
+	if ts.acceptWord("CONSTRAINT") {
+		name := ""
+		if !ts.isWord("PRIMARY") && !ts.isWord("FOREIGN") && !ts.isWord("UNIQUE") && !ts.isWord("CHECK") {
+			var err error
+			if name, err = ts.ident(); err != nil {
+				return err
+			}
+		}
+		return t.constraint(tab, name, ts)
+	}
+	for _, w := range []string{"PRIMARY", "FOREIGN", "UNIQUE", "CHECK", "KEY", "INDEX", "FULLTEXT", "SPATIAL", "EXCLUDE"} {
+		if ts.isWord(w) {
+			return t.constraint(tab, "", ts)
+		}
+	}
+	return t.column(tab, ts)
+}
+
+// constraint translates a constraint of a table, or of the ALTER TABLE
+// statements of pg_dump.
+func (t *dumpTranslator) constraint(tab *dumpTable, name string, ts *dumpTokens) error {
+	object := func(kind string) string {
+		if name == "" {
+			return fmt.Sprintf("%s of table %q", kind, tab.name)
+		}
+		return fmt.Sprintf("%s %q of table %q", kind, name, tab.name)
+	}
+	switch {
+	case ts.acceptWord("PRIMARY", "KEY"):
+		cols, err := indexColumns(ts)
+		if err != nil {
+			return err
+		}
+		tab.pk = cols
+	case ts.isWord("FOREIGN"):
+		t.note(object("foreign key"), "foreign keys aren't supported by LOAD")
+	case ts.isWord("CHECK"):
+		t.note(object("check constraint"), "check constraints aren't translated")
+	default:
+		ts.acceptWord("UNIQUE")
+		ts.acceptWord("FULLTEXT")
+		ts.acceptWord("SPATIAL")
+		if !ts.acceptWord("KEY") {
+			ts.acceptWord("INDEX")
+		}
+		if name == "" && !ts.isPunct("(") {
+			name, _ = ts.ident()
+		}
+		t.note(object("index"), "secondary indexes aren't supported by LOAD; create it after the restore")
+	}
+	return nil
+}
+
+// indexColumns returns the names of the columns of an index. The lengths of
+// the prefix indexes of MySQL are dropped.
+func indexColumns(ts *dumpTokens) ([]string, error) {
+	items, err := ts.list()
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]string, len(items))
+	for i, item := range items {
+		if cols[i], err = (&dumpTokens{toks: item}).ident(); err != nil {
+			return nil, err
+		}
+	}
+	return cols, nil
+}
+
+// column translates a column definition.
+func (t *dumpTranslator) column(tab *dumpTable, ts *dumpTokens) error {
+	name, err := ts.ident()
+	if err != nil {
+		return err
+	}
+	col := dumpColumn{name: name}
+	object := fmt.Sprintf("column %q of table %q", name, tab.name)
+	if err := t.columnType(&col, object, ts); err != nil {
+		return err
+	}
+	for !ts.done() {
+		switch {
+		case ts.acceptWord("NOT", "NULL"):
+			col.notNull = true
+		case ts.acceptWord("NULL"):
+		case ts.acceptWord("DEFAULT"):
+			def, err := t.defaultExpr(&col, object, ts)
+			if err != nil {
+				return err
+			}
+			col.def = def
+		case ts.acceptWord("AUTO_INCREMENT"):
+			col.def = "unique_rowid()"
+			t.note(object, "AUTO_INCREMENT is translated to DEFAULT unique_rowid(), which isn't sequential")
+		case ts.acceptWord("PRIMARY", "KEY"):
+			col.primary = true
+		case ts.acceptWord("UNIQUE"):
+			ts.acceptWord("KEY")
+			t.note(object, "secondary indexes aren't supported by LOAD; create the unique index after the restore")
+		case ts.acceptWord("COMMENT"), ts.acceptWord("COLLATE"), ts.acceptWord("CHARACTER", "SET"),
+			ts.acceptWord("CHARSET"):
+			ts.next()
+		case ts.acceptWord("ON", "UPDATE"):
+			ts.next()
+			if err := ts.skipGroup(); err != nil {
+				return err
+			}
+			t.note(object, "ON UPDATE isn't supported")
+		default:
+			var rest []string
+			for !ts.done() {
+				rest = append(rest, ts.next().String())
+			}
+			t.note(object, fmt.Sprintf("%s isn't translated", strings.Join(rest, " ")))
+		}
+	}
+	tab.cols = append(tab.cols, col)
+	return nil
+}
+
+// columnType translates the type of a column.
+func (t *dumpTranslator) columnType(col *dumpColumn, object string, ts *dumpTokens) error {
+	var words []string
+	var args string
+	unsigned, array := false, false
+	for {
+		tok := ts.peek()
+		switch {
+		case tok.kind == tokWord && ts.isWord("CHARACTER", "SET"):
+		case tok.kind == tokWord && columnOptions[strings.ToUpper(tok.s)]:
+		case tok.kind == tokWord:
+			ts.next()
+			switch w := strings.ToLower(tok.s); w {
+			case "unsigned":
+				unsigned = true
+			case "signed", "zerofill":
+			default:
+				words = append(words, w)
+			}
+			continue
+		case ts.isPunct("("):
+			items, err := ts.list()
+			if err != nil {
+				return err
+			}
+			var parts []string
+			for _, item := range items {
+				if len(item) == 1 && item[0].kind == tokNumber {
+					parts = append(parts, item[0].s)
+				}
+			}
+			if len(parts) > 0 && len(parts) == len(items) {
+				args = "(" + strings.Join(parts, ", ") + ")"
+			}
+			continue
+		case ts.acceptPunct("["):
+			ts.acceptPunct("]")
+			array = true
+			continue
+		case ts.acceptPunct("."):
+			// A qualified type, e.g. public.mood.
+			words = words[:0]
+			continue
+		}
+		break
+	}
+	name := strings.Join(words, " ")
+	if name == "" {
+		return errors.Errorf("expected the type of %s, found %s", object, ts.peek())
+	}
+
+	types := postgresTypes
+	if t.mysql {
+		types = mysqlTypes
+	}
+	typ, ok := types[name]
+	switch {
+	case array:
+		typ = dumpType{typ: "STRING", loss: fmt.Sprintf("the array type %s[] is stored as STRING", name)}
+	case !ok:
+		typ = dumpType{typ: "STRING", loss: fmt.Sprintf("the type %s is stored as STRING", name)}
+	}
+	if strings.Contains(typ.typ, "%s") {
+		col.typ = fmt.Sprintf(typ.typ, args)
+	} else {
+		col.typ = typ.typ
+	}
+	col.kind = typ.kind
+	if typ.loss != "" {
+		t.note(object, typ.loss)
+	}
+	if unsigned && name == "bigint" {
+		t.note(object, "UNSIGNED is dropped, values above 2^63-1 can't be loaded")
+	}
+	return nil
+}
+
+var nowFuncs = map[string]string{
+	"CURRENT_TIMESTAMP": "now()",
+	"LOCALTIMESTAMP":    "now()",
+	"NOW":               "now()",
+	"CURRENT_DATE":      "current_date()",
+}
+
+// defaultExpr translates the default expression of a column. The literals
+// and the current time are translated; the sequences become unique_rowid().
+func (t *dumpTranslator) defaultExpr(col *dumpColumn, object string, ts *dumpTokens) (string, error) {
+	start := ts.i
+	def, err := t.defaultValue(col, object, ts)
+	if err != nil {
+		return "", err
+	}
+	// Skip the casts of Postgres, e.g. 'foo'::character varying.
+	for ts.acceptPunct("::") {
+		for ts.peek().kind == tokWord && !columnOptions[strings.ToUpper(ts.peek().s)] {
+			ts.next()
+			if err := ts.skipGroup(); err != nil {
+				return "", err
+			}
+		}
+		if ts.acceptPunct("[") {
+			ts.acceptPunct("]")
+		}
+	}
+	if ts.done() || (ts.peek().kind == tokWord && columnOptions[strings.ToUpper(ts.peek().s)]) {
+		return def, nil
+	}
+	// An expression, which is skipped.
+	for !ts.done() && !(ts.peek().kind == tokWord && columnOptions[strings.ToUpper(ts.peek().s)]) {
+		if err := ts.skipGroup(); err != nil {
+			return "", err
+		}
+		ts.next()
+	}
+	var expr []string
+	for _, tok := range ts.toks[start:ts.i] {
+		expr = append(expr, tok.String())
+	}
+	t.note(object, fmt.Sprintf("DEFAULT %s isn't translated", strings.Join(expr, " ")))
+	return "", nil
+}
+
+func (t *dumpTranslator) defaultValue(
+	col *dumpColumn, object string, ts *dumpTokens,
+) (string, error) {
+	tok := ts.peek()
+	switch {
+	case tok.kind == tokString:
+		ts.next()
+		if col.kind == valueDate && isZeroDate(tok.s) {
+			t.note(object, "the zero date default is dropped")
+			return "", nil
+		}
+		return dumpValue(col, tok.s)
+	case tok.kind == tokNumber:
+		ts.next()
+		return dumpValue(col, tok.s)
+	case ts.isPunct("-") || ts.isPunct("+"):
+		ts.next()
+		if num := ts.next(); num.kind == tokNumber {
+			return dumpValue(col, tok.s+num.s)
+		}
+		return "", errors.Errorf("invalid default of %s", object)
+	case ts.acceptWord("NULL"):
+		return "", nil
+	case ts.isWord("TRUE"), ts.isWord("FALSE"):
+		ts.next()
+		return dumpValue(col, strings.ToLower(tok.s))
+	case tok.kind == tokWord && nowFuncs[strings.ToUpper(tok.s)] != "":
+		ts.next()
+		if err := ts.skipGroup(); err != nil {
+			return "", err
+		}
+		return nowFuncs[strings.ToUpper(tok.s)], nil
+	case ts.isWord("nextval"):
+		ts.next()
+		if err := ts.skipGroup(); err != nil {
+			return "", err
+		}
+		t.note(object, "the sequence is replaced by DEFAULT unique_rowid(), which isn't sequential")
+		return "unique_rowid()", nil
+	}
+	return "", nil
+}
+
+// alterTable translates the ALTER TABLE statements of pg_dump which define the
+// primary keys and the defaults of the columns. They only apply to the tables
+// which weren't defined yet.
+func (t *dumpTranslator) alterTable(ts *dumpTokens) error {
+	all := &dumpTokens{toks: ts.toks}
+	ts.acceptWord("ALTER", "TABLE")
+	ts.acceptWord("ONLY")
+	name, err := ts.name()
+	if err != nil {
+		return err
+	}
+	tab, ok := t.tables[name]
+	if !ok {
+		t.skip(all, "unknown table")
+		return nil
+	}
+	switch {
+	case ts.acceptWord("ADD", "CONSTRAINT"):
+		cname, err := ts.ident()
+		if err != nil {
+			return err
+		}
+		if ts.isWord("PRIMARY") && tab.defined {
+			t.note(fmt.Sprintf("primary key %q of table %q", cname, name),
+				"pg_dump defines it after the data of the table, which has a rowid primary key instead")
+			return nil
+		}
+		return t.constraint(tab, cname, ts)
+
+	case ts.acceptWord("ALTER", "COLUMN"), ts.acceptWord("ALTER"):
+		colName, err := ts.ident()
+		if err != nil {
+			return err
+		}
+		i, err := tab.column(colName)
+		if err != nil {
+			return err
+		}
+		col := &tab.cols[i]
+		object := fmt.Sprintf("column %q of table %q", colName, name)
+		if !ts.acceptWord("SET", "DEFAULT") {
+			t.skip(all, "ALTER COLUMN isn't supported by LOAD")
+			return nil
+		}
+		def, err := t.defaultExpr(col, object, ts)
+		if err != nil {
+			return err
+		}
+		if tab.defined {
+			t.note(object, "pg_dump defines the default after the data of the table")
+			return nil
+		}
+		col.def = def
+		return nil
+	}
+	t.skip(all, "ALTER TABLE isn't supported by LOAD")
+	return nil
+}
+
+// define writes the CREATE TABLE statement of a table, unless it was already.
+func (t *dumpTranslator) define(tab *dumpTable) error {
+	if tab.defined {
+		return nil
+	}
+	tab.defined = true
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "CREATE TABLE %s (", parser.AsString(parser.Name(tab.name)))
+	for i, col := range tab.cols {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "%s %s", parser.AsString(parser.Name(col.name)), col.typ)
+		if col.notNull {
+			buf.WriteString(" NOT NULL")
+		}
+		if col.def != "" {
+			fmt.Fprintf(&buf, " DEFAULT %s", col.def)
+		}
+		if col.primary {
+			buf.WriteString(" PRIMARY KEY")
+		}
+	}
+	if len(tab.pk) > 0 {
+		buf.WriteString(", PRIMARY KEY (")
+		for i, col := range tab.pk {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(parser.AsString(parser.Name(col)))
+		}
+		buf.WriteString(")")
+	}
+	buf.WriteString(");\n")
+	_, err := t.w.Write(buf.Bytes())
+	return err
+}
+
+// insert translates an INSERT statement, following INSERT INTO.
+func (t *dumpTranslator) insert(ts *dumpTokens) error {
+	name, err := ts.name()
+	if err != nil {
+		return err
+	}
+	tab, ok := t.tables[name]
+	if !ok {
+		return errors.Errorf("INSERT into unknown table %q", name)
+	}
+	order, err := tab.columnOrder(ts)
+	if err != nil {
+		return err
+	}
+	if !ts.acceptWord("VALUES") && !ts.acceptWord("VALUE") {
+		return errors.Errorf("expected VALUES, found %s", ts.peek())
+	}
+	var rows [][]string
+	for {
+		items, err := ts.list()
+		if err != nil {
+			return err
+		}
+		if len(items) != len(order) {
+			return errors.Errorf("expected %d values for table %q, found %d", len(order), name, len(items))
+		}
+		row := make([]string, len(tab.cols))
+		for i, item := range items {
+			col := &tab.cols[order[i]]
+			if row[order[i]], err = t.value(tab, col, &dumpTokens{toks: item}); err != nil {
+				return errors.Wrapf(err, "column %q of table %q", col.name, name)
+			}
+		}
+		rows = append(rows, row)
+		if !ts.acceptPunct(",") {
+			break
+		}
+	}
+	if !ts.done() {
+		return errors.Errorf("unsupported INSERT clause %s", ts.peek())
+	}
+	return t.writeRows(tab, rows)
+}
+
+// value translates a value of an INSERT statement.
+func (t *dumpTranslator) value(tab *dumpTable, col *dumpColumn, ts *dumpTokens) (string, error) {
+	tok := ts.next()
+	var v string
+	switch {
+	case tok.kind == tokWord && strings.EqualFold(tok.s, "NULL"):
+		v = "NULL"
+	case tok.kind == tokWord && (strings.EqualFold(tok.s, "TRUE") || strings.EqualFold(tok.s, "FALSE")):
+		v = strings.ToLower(tok.s)
+	case tok.kind == tokString, tok.kind == tokNumber:
+		v = tok.s
+	case tok.kind == tokPunct && (tok.s == "-" || tok.s == "+"):
+		num := ts.next()
+		if num.kind != tokNumber {
+			return "", errors.Errorf("unsupported value %s%s", tok.s, num)
+		}
+		v = tok.s + num.s
+	default:
+		return "", errors.Errorf("unsupported value %s", tok)
+	}
+	// Skip the casts of Postgres, e.g. '2017-01-01'::date.
+	for ts.acceptPunct("::") {
+		for ts.peek().kind == tokWord {
+			ts.next()
+		}
+		if err := ts.skipGroup(); err != nil {
+			return "", err
+		}
+	}
+	if !ts.done() {
+		return "", errors.Errorf("unsupported value %s", tok)
+	}
+	if v == "NULL" {
+		return v, nil
+	}
+	if col.kind == valueDate && isZeroDate(v) {
+		if !tab.zeroDates {
+			tab.zeroDates = true
+			t.note(fmt.Sprintf("table %q", tab.name), "the zero dates are loaded as NULL")
+		}
+		return "NULL", nil
+	}
+	return dumpValue(col, v)
+}
+
+var numberRE = regexp.MustCompile(`^-?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+
+// dumpValue returns the literal of a value of a column.
+func dumpValue(col *dumpColumn, v string) (string, error) {
+	switch col.kind {
+	case valueNumber:
+		v = strings.TrimPrefix(v, "+")
+		if !numberRE.MatchString(v) {
+			return "", errors.Errorf("invalid number %q", v)
+		}
+		return v, nil
+	case valueBytes:
+		return parser.AsString(parser.NewDBytes(parser.DBytes(v))), nil
+	}
+	return parser.AsString(parser.NewDString(v)), nil
+}
+
+// isZeroDate returns whether a value is one of the zero dates of MySQL, e.g.
+// 0000-00-00 or 0000-00-00 00:00:00.
+func isZeroDate(v string) bool {
+	return strings.HasPrefix(v, "0000-00-00")
+}
+
+// copyFrom translates a COPY FROM stdin statement of pg_dump, following COPY,
+// along with its data in the text format.
+func (t *dumpTranslator) copyFrom(ts *dumpTokens) error {
+	name, err := ts.name()
+	if err != nil {
+		return err
+	}
+	tab, ok := t.tables[name]
+	if !ok {
+		return errors.Errorf("COPY into unknown table %q", name)
+	}
+	order, err := tab.columnOrder(ts)
+	if err != nil {
+		return err
+	}
+	if !ts.acceptWord("FROM", "stdin") || !ts.done() {
+		return errors.Errorf("only COPY FROM stdin is supported")
+	}
+	// The data starts on the line following the statement.
+	if rest, err := t.r.readLine(); err != nil {
+		return err
+	} else if strings.TrimSpace(rest) != "" {
+		return errors.Errorf("unexpected %q after COPY", rest)
+	}
+	if err := t.define(tab); err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for {
+		line, err := t.r.readLine()
+		if err != nil {
+			return errors.Wrapf(err, "reading the data of table %q", name)
+		}
+		if line == `\.` {
+			break
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != len(order) {
+			return errors.Errorf("line %d: expected %d values for table %q, found %d",
+				t.r.line-1, len(order), name, len(fields))
+		}
+		row := make([]string, len(tab.cols))
+		for i, f := range fields {
+			col := &tab.cols[order[i]]
+			if f == `\N` {
+				row[order[i]] = "NULL"
+				continue
+			}
+			v := unescapeCopy(f)
+			if col.kind == valueBytes && strings.HasPrefix(v, `\x`) {
+				b, err := hex.DecodeString(v[2:])
+				if err != nil {
+					return errors.Wrapf(err, "line %d: column %q of table %q", t.r.line-1, col.name, name)
+				}
+				v = string(b)
+			}
+			if row[order[i]], err = dumpValue(col, v); err != nil {
+				return errors.Wrapf(err, "line %d: column %q of table %q", t.r.line-1, col.name, name)
+			}
+		}
+		rows = append(rows, row)
+		if len(rows) == dumpInsertBatchRows {
+			if err := t.writeRows(tab, rows); err != nil {
+				return err
+			}
+			rows = rows[:0]
+		}
+	}
+	return t.writeRows(tab, rows)
+}
+
+// unescapeCopy decodes a field of the text format of COPY.
+func unescapeCopy(f string) string {
+	if strings.IndexByte(f, '\\') == -1 {
+		return f
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(f); i++ {
+		if f[i] != '\\' || i+1 == len(f) {
+			buf.WriteByte(f[i])
+			continue
+		}
+		i += unescapePostgres(&buf, f[i+1:])
+	}
+	return buf.String()
+}
+
+// writeRows writes an INSERT statement for the rows of a table, and the
+// CREATE TABLE statement of the table if it wasn't yet.
+func (t *dumpTranslator) writeRows(tab *dumpTable, rows [][]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := t.define(tab); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "INSERT INTO %s VALUES ", parser.AsString(parser.Name(tab.name)))
+	for i, row := range rows {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "(%s)", strings.Join(row, ", "))
+	}
+	buf.WriteString(";\n")
+	_, err := t.w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,225 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+package sqlccl
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+const mysqlTestDump = "-- MySQL dump 10.13  Distrib 5.7.18, for Linux (x86_64)\n" +
+	"/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;\n" +
+	"/*!40101 SET NAMES utf8 */;\n" +
+	"DROP TABLE IF EXISTS `customers`;\n" +
+	"CREATE TABLE `customers` (\n" +
+	"  `id` int(11) NOT NULL AUTO_INCREMENT,\n" +
+	"  `name` varchar(64) NOT NULL DEFAULT '',\n" +
+	"  `balance` decimal(10,2) DEFAULT NULL,\n" +
+	"  `created` datetime NOT NULL DEFAULT '0000-00-00 00:00:00',\n" +
+	"  `photo` blob,\n" +
+	"  PRIMARY KEY (`id`),\n" +
+	"  KEY `customers_name` (`name`)\n" +
+	") ENGINE=InnoDB AUTO_INCREMENT=3 DEFAULT CHARSET=utf8;\n" +
+	"LOCK TABLES `customers` WRITE;\n" +
+	"/*!40000 ALTER TABLE `customers` DISABLE KEYS */;\n" +
+	"INSERT INTO `customers` VALUES (1,'it\\'s',-1.50,'2017-06-01 10:00:00',0x00FF)," +
+	"(2,'a;b\\nc',NULL,'0000-00-00 00:00:00',_binary 'x');\n" +
+	"/*!40000 ALTER TABLE `customers` ENABLE KEYS */;\n" +
+	"UNLOCK TABLES;\n" +
+	"DELIMITER ;;\n" +
+	"CREATE TRIGGER `touch` BEFORE INSERT ON `customers` FOR EACH ROW BEGIN SET NEW.name = 'x'; END ;;\n" +
+	"DELIMITER ;\n" +
+	"CREATE TABLE `orders` (\n" +
+	"  `id` bigint(20) unsigned NOT NULL,\n" +
+	"  `customer` int(11) DEFAULT NULL,\n" +
+	"  `status` enum('new','done') DEFAULT 'new',\n" +
+	"  PRIMARY KEY (`id`),\n" +
+	"  CONSTRAINT `orders_fk` FOREIGN KEY (`customer`) REFERENCES `customers` (`id`)\n" +
+	") ENGINE=InnoDB;\n" +
+	"INSERT INTO `orders` (`id`, `customer`, `status`) VALUES (10,1,'done');\n"
+
+const postgresTestDump = `--
+-- PostgreSQL database dump
+--
+
+SET statement_timeout = 0;
+SET client_encoding = 'UTF8';
+SELECT pg_catalog.set_config('search_path', '', false);
+
+CREATE FUNCTION public.touch() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+  NEW.updated := now();
+  RETURN NEW;
+END;
+$$;
+
+ALTER FUNCTION public.touch() OWNER TO app;
+
+CREATE TABLE public.accounts (
+    id integer NOT NULL,
+    holder character varying(32) DEFAULT 'nobody'::character varying NOT NULL,
+    balance numeric(12,2) DEFAULT '-1'::integer,
+    tags text[],
+    opened timestamp with time zone DEFAULT now(),
+    photo bytea
+);
+
+ALTER TABLE public.accounts OWNER TO app;
+
+CREATE SEQUENCE public.accounts_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1;
+
+ALTER SEQUENCE public.accounts_id_seq OWNED BY public.accounts.id;
+
+ALTER TABLE ONLY public.accounts ALTER COLUMN id SET DEFAULT nextval('public.accounts_id_seq'::regclass);
+
+CREATE TABLE public.notes (
+    k text NOT NULL
+);
+
+COPY public.accounts (id, holder, balance, tags, opened, photo) FROM stdin;
+1	alice	10.50	{a,b}	2017-06-01 10:00:00+00	\\x00ff
+2	bob\tby	\N	\N	2017-06-02 10:00:00+00	\N
+\.
+
+SELECT pg_catalog.setval('public.accounts_id_seq', 2, true);
+
+ALTER TABLE ONLY public.accounts
+    ADD CONSTRAINT accounts_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.notes
+    ADD CONSTRAINT notes_pkey PRIMARY KEY (k);
+
+CREATE INDEX accounts_holder_idx ON public.accounts USING btree (holder);
+`
+
+func TestTranslateDump(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		format   DumpFormat
+		dump     string
+		expected string
+		notes    []string
+	}{
+		{
+			format: DumpFormatMySQL,
+			dump:   mysqlTestDump,
+			expected: `CREATE TABLE customers (id INT NOT NULL DEFAULT unique_rowid(), name VARCHAR(64) NOT NULL DEFAULT '', balance DECIMAL(10, 2), created TIMESTAMP NOT NULL, photo BYTES, PRIMARY KEY (id));
+INSERT INTO customers VALUES (1, e'it\'s', -1.50, '2017-06-01 10:00:00', b'\x00\xff'), (2, e'a;b\nc', NULL, NULL, b'x');
+CREATE TABLE orders (id BIGINT NOT NULL, customer INT, status STRING DEFAULT 'new', PRIMARY KEY (id));
+INSERT INTO orders VALUES (10, 1, 'done');
+`,
+			notes: []string{
+				`line 5: column "id" of table "customers": AUTO_INCREMENT is translated to DEFAULT unique_rowid(), which isn't sequential`,
+				`line 5: column "created" of table "customers": the zero date default is dropped`,
+				`line 5: index "customers_name" of table "customers": secondary indexes aren't supported by LOAD; create it after the restore`,
+				`line 16: table "customers": the zero dates are loaded as NULL`,
+				`line 20: trigger "touch": not supported by LOAD`,
+				`line 22: column "id" of table "orders": UNSIGNED is dropped, values above 2^63-1 can't be loaded`,
+				`line 22: column "status" of table "orders": the allowed values aren't enforced`,
+				`line 22: foreign key "orders_fk" of table "orders": foreign keys aren't supported by LOAD`,
+			},
+		},
+		{
+			format: DumpFormatPostgres,
+			dump:   postgresTestDump,
+			expected: `CREATE TABLE accounts (id INT NOT NULL DEFAULT unique_rowid(), holder VARCHAR(32) NOT NULL DEFAULT 'nobody', balance DECIMAL(12, 2) DEFAULT -1, tags STRING, opened TIMESTAMPTZ DEFAULT now(), photo BYTES);
+INSERT INTO accounts VALUES (1, 'alice', 10.50, '{a,b}', '2017-06-01 10:00:00+00', b'\x00\xff'), (2, e'bob\tby', NULL, NULL, '2017-06-02 10:00:00+00', NULL);
+CREATE TABLE notes (k STRING NOT NULL, PRIMARY KEY (k));
+`,
+			notes: []string{
+				`line 9: function "touch": not supported by LOAD`,
+				`line 20: column "tags" of table "accounts": the array type text[] is stored as STRING`,
+				`line 31: sequence "accounts_id_seq": not supported by LOAD`,
+				`line 38: column "id" of table "accounts": the sequence is replaced by DEFAULT unique_rowid(), which isn't sequential`,
+				`line 51: primary key "accounts_pkey" of table "accounts": pg_dump defines it after the data of the table, which has a rowid primary key instead`,
+				`line 57: index "accounts_holder_idx": secondary indexes aren't supported by LOAD; create it after the restore`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		notes, err := TranslateDump(strings.NewReader(tc.dump), tc.format, &buf)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if buf.String() != tc.expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", tc.expected, buf.String())
+		}
+		var actual []string
+		for _, n := range notes {
+			actual = append(actual, n.String())
+		}
+		if !reflect.DeepEqual(actual, tc.notes) {
+			t.Errorf("expected notes:\n%s\ngot:\n%s", strings.Join(tc.notes, "\n"), strings.Join(actual, "\n"))
+		}
+	}
+
+	errCases := []struct {
+		format DumpFormat
+		dump   string
+		err    string
+	}{
+		{DumpFormatPostgres, "PGDMP\x01\x0c\x04", "pg_dump archives aren't supported"},
+		{DumpFormatPostgres, "CREATE TABLE t (a int);\nCOPY t (a) FROM stdin;\n1\n", "unexpected EOF"},
+		{DumpFormatPostgres, "CREATE TABLE t (a int);\nCOPY t (a) FROM stdin;\nx\n\\.\n", `line 3: column "a" of table "t": invalid number "x"`},
+		{DumpFormatMySQL, "INSERT INTO `t` VALUES (1);\n", `line 1: INSERT into unknown table "t"`},
+		{DumpFormatMySQL, "CREATE TABLE `t` (`a` int);\nINSERT INTO `t` VALUES (1, 2);\n", `line 2: expected 1 values for table "t", found 2`},
+		{DumpFormatMySQL, "CREATE TABLE `t` (`a` text);\nINSERT INTO `t` VALUES ('foo);\n", "unterminated quoted string"},
+	}
+	for _, tc := range errCases {
+		var buf bytes.Buffer
+		if _, err := TranslateDump(strings.NewReader(tc.dump), tc.format, &buf); !testutils.IsError(err, tc.err) {
+			t.Errorf("%q: expected error %q, got %v", tc.dump, tc.err, err)
+		}
+	}
+}
+
+func TestLoadDump(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx, dir, _, sqlDB, cleanupFn := backupRestoreTestSetup(t, singleNode, 0)
+	defer cleanupFn()
+	sqlDB.Exec(`DROP TABLE bench.bank`)
+
+	ts := hlc.Timestamp{WallTime: hlc.UnixNano()}
+	_, notes, err := LoadDump(
+		ctx, sqlDB.DB, strings.NewReader(mysqlTestDump), DumpFormatMySQL, "bench", dir, ts, 0, dir,
+	)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(notes) == 0 {
+		t.Errorf("expected notes")
+	}
+	sqlDB.Exec(fmt.Sprintf(`RESTORE bench.* FROM '%s'`, dir))
+
+	var balance string
+	sqlDB.QueryRow(`SELECT balance FROM bench.customers WHERE id = 1`).Scan(&balance)
+	if balance != "-1.50" {
+		t.Errorf("expected balance -1.50, got %s", balance)
+	}
+	var status string
+	sqlDB.QueryRow(`SELECT status FROM bench.orders WHERE id = 10`).Scan(&status)
+	if status != "done" {
+		t.Errorf("expected status done, got %s", status)
+	}
+}
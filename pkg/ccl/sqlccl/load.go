@@ -13,6 +13,8 @@ import (
 	"bytes"
 	gosql "database/sql"
 	"fmt"
+	"go/constant"
+	"go/token"
 	"io"
 	"math/rand"
 	"time"
@@ -148,7 +150,10 @@ func Load(
 			// only uses txn for resolving FKs and interleaved tables, neither of which
 			// are present here.
 			var txn *client.Txn
-			desc, err := sql.MakeTableDesc(ctx, txn, sql.NilVirtualTabler, nil, s, dbDesc.ID, 0 /* table ID */, privs, affected, dbDesc.Name, &evalCtx)
+			// The tables get distinct IDs, which RESTORE rewrites, in increasing
+			// order so that their keys are too.
+			tableID := sqlbase.ID(keys.MaxReservedDescID + 1 + len(tableDescs))
+			desc, err := sql.MakeTableDesc(ctx, txn, sql.NilVirtualTabler, nil, s, dbDesc.ID, tableID, privs, affected, dbDesc.Name, &evalCtx)
 			if err != nil {
 				return BackupDescriptor{}, errors.Wrap(err, "make table desc")
 			}
@@ -227,6 +232,40 @@ func Load(
 	return backup, nil
 }
 
+// LoadDump is like Load, for a dump in the given format, which is translated
+// by TranslateDump. It returns the notes of the translation along with the
+// backup descriptor.
+func LoadDump(
+	ctx context.Context,
+	db *gosql.DB,
+	r io.Reader,
+	format DumpFormat,
+	database, uri string,
+	ts hlc.Timestamp,
+	loadChunkBytes int64,
+	tempPrefix string,
+) (BackupDescriptor, []DumpNote, error) {
+	if format == DumpFormatCockroach {
+		backup, err := Load(ctx, db, r, database, uri, ts, loadChunkBytes, tempPrefix)
+		return backup, nil, err
+	}
+
+	pr, pw := io.Pipe()
+	var notes []DumpNote
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var err error
+		notes, err = TranslateDump(r, format, pw)
+		pw.CloseWithError(errors.Wrap(err, "translate dump"))
+	}()
+	backup, err := Load(ctx, db, pr, database, uri, ts, loadChunkBytes, tempPrefix)
+	// Stop the translation if Load failed before the end of the dump.
+	pr.CloseWithError(errors.New("load failed"))
+	<-done
+	return backup, notes, err
+}
+
 func insertStmtToKVs(
 	ctx context.Context,
 	tableDesc *sqlbase.TableDescriptor,
@@ -272,6 +311,15 @@ func insertStmtToKVs(
 				row[i] = parser.DNull
 				continue
 			}
+			// Negative numbers parse as the negation of a number.
+			if neg, ok := expr.(*parser.UnaryExpr); ok && neg.Operator == parser.UnaryMinus {
+				if n, ok := neg.Expr.(*parser.NumVal); ok {
+					expr = &parser.NumVal{
+						Value:      constant.UnaryOp(token.SUB, n.Value, 0),
+						OrigString: "-" + n.OrigString,
+					}
+				}
+			}
 			c, ok := expr.(parser.Constant)
 			if !ok {
 				return errors.Errorf("unsupported expr: %q", expr)
@@ -35,6 +35,9 @@ import (
 const (
 	restoreOptIntoDB         = "into_db"
 	restoreOptSkipMissingFKs = "skip_missing_foreign_keys"
+	// restoreOptVerify makes RESTORE check the descriptors and files of the
+	// backups (see verifyBackup) before restoring anything.
+	restoreOptVerify = "experimental_verify"
 )
 
 // Import loads some data in sstables into an empty range. Only the keys between
@@ -324,6 +327,11 @@ func reassignReferencedTables(
 		// since this is a "new" table in eyes of new cluster, any leftover change
 		// lease is obviously bogus (plus the nodeID is relative to backup cluster).
 		table.Lease = nil
+		// The data of the dropped indexes is deleted by the schema changer of
+		// the restored table, but their GC jobs belong to the backup cluster.
+		for i := range table.GCMutations {
+			table.GCMutations[i].JobID = 0
+		}
 	}
 	return nil
 }
@@ -632,6 +640,13 @@ func Restore(
 	if err != nil {
 		return 0, err
 	}
+	if _, ok := opt.Get(restoreOptVerify); ok {
+		for i, desc := range backupDescs {
+			if err := verifyBackup(ctx, desc); err != nil {
+				return 0, errors.Wrapf(err, "verifying backup %s", uris[i])
+			}
+		}
+	}
 	lastBackupDesc := backupDescs[len(backupDescs)-1]
 
 	databasesByID := make(map[sqlbase.ID]*sqlbase.DatabaseDescriptor)
@@ -861,6 +876,7 @@ func restorePlanHook(
 			Username:    p.User(),
 			Details:     jobs.RestoreJobDetails{},
 		})
+		jobLogger.WithTestingKnobs(p.ExecCfg().JobsTestingKnobs)
 		dataSize, err := Restore(
 			ctx,
 			p,
@@ -0,0 +1,119 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+package sqlccl
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// verifyBackupDescriptor checks that a BackupDescriptor is consistent: its end
+// time is set and after its start time, its spans don't overlap, and each of
+// its files has a path and covers a keyrange that doesn't overlap with the
+// other files and is contained in one of the spans.
+//
+// The files may not cover all of the spans, since no file is written for a
+// keyrange without any changes.
+func verifyBackupDescriptor(desc BackupDescriptor) error {
+	if desc.EndTime == (hlc.Timestamp{}) {
+		return errors.New("backup has no end time")
+	}
+	if !desc.StartTime.Less(desc.EndTime) {
+		return errors.Errorf("backup start time %s is not before its end time %s",
+			desc.StartTime, desc.EndTime)
+	}
+
+	spans := append([]roachpb.Span(nil), desc.Spans...)
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Key.Compare(spans[j].Key) < 0 })
+	for i := range spans {
+		if spans[i].Key.Compare(spans[i].EndKey) >= 0 {
+			return errors.Errorf("backup span %s is empty", spans[i])
+		}
+		if i > 0 && spans[i-1].Overlaps(spans[i]) {
+			return errors.Errorf("backup spans %s and %s overlap", spans[i-1], spans[i])
+		}
+	}
+
+	files := append(backupFileDescriptors(nil), desc.Files...)
+	sort.Sort(files)
+	for i, f := range files {
+		if len(f.Path) == 0 {
+			return errors.Errorf("backup file for %s has no path", f.Span)
+		}
+		if i > 0 && files[i-1].Span.Overlaps(f.Span) {
+			return errors.Errorf("backup files %s (%s) and %s (%s) overlap",
+				files[i-1].Path, files[i-1].Span, f.Path, f.Span)
+		}
+		// The span containing the file, if any, is the first one that ends after
+		// the start of the file.
+		j := sort.Search(len(spans), func(j int) bool {
+			return spans[j].EndKey.Compare(f.Span.Key) > 0
+		})
+		if j == len(spans) || !spans[j].Contains(f.Span) {
+			return errors.Errorf("backup file %s (%s) is not covered by the backup spans",
+				f.Path, f.Span)
+		}
+	}
+	return nil
+}
+
+// verifyBackupFiles checks that the files of a backup can be read and match the
+// checksums recorded in its BackupDescriptor. Files backed up before checksums
+// were recorded are only checked for presence.
+func verifyBackupFiles(ctx context.Context, desc BackupDescriptor) error {
+	dir, err := storageccl.MakeExportStorage(ctx, desc.Dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	for _, f := range desc.Files {
+		if err := verifyBackupFile(ctx, dir, f); err != nil {
+			return err
+		}
+	}
+	log.Eventf(ctx, "verified %d backup files", len(desc.Files))
+	return nil
+}
+
+func verifyBackupFile(
+	ctx context.Context, dir storageccl.ExportStorage, f BackupDescriptor_File,
+) error {
+	r, err := dir.ReadFile(ctx, f.Path)
+	if err != nil {
+		return errors.Wrapf(err, "reading backup file %s", f.Path)
+	}
+	defer r.Close()
+	h := sha512.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return errors.Wrapf(err, "reading backup file %s", f.Path)
+	}
+	if len(f.Sha512) > 0 && !bytes.Equal(h.Sum(nil), f.Sha512) {
+		return errors.Errorf("checksum mismatch for backup file %s (%s)", f.Path, f.Span)
+	}
+	return nil
+}
+
+// verifyBackup runs verifyBackupDescriptor and verifyBackupFiles on a backup.
+func verifyBackup(ctx context.Context, desc BackupDescriptor) error {
+	if err := verifyBackupDescriptor(desc); err != nil {
+		return err
+	}
+	return verifyBackupFiles(ctx, desc)
+}
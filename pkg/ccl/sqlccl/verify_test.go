@@ -0,0 +1,86 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed as a CockroachDB Enterprise file under the Cockroach Community
+// License (the "License"); you may not use this file except in compliance with
+// the License. You may obtain a copy of the License at
+//
+//     https://github.com/cockroachdb/cockroach/blob/master/LICENSE
+
+package sqlccl
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestVerifyBackupDescriptor(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	span := func(start, end string) roachpb.Span {
+		return roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)}
+	}
+	file := func(start, end, path string) BackupDescriptor_File {
+		return BackupDescriptor_File{Span: span(start, end), Path: path}
+	}
+	ts1, ts2 := hlc.Timestamp{WallTime: 1}, hlc.Timestamp{WallTime: 2}
+
+	testCases := []struct {
+		desc BackupDescriptor
+		err  string
+	}{
+		{BackupDescriptor{
+			EndTime: ts1,
+			Spans:   []roachpb.Span{span("c", "e"), span("a", "c")},
+			Files:   []BackupDescriptor_File{file("c", "d", "2.sst"), file("a", "b", "1.sst")},
+		}, ""},
+		{BackupDescriptor{
+			StartTime: ts1,
+			EndTime:   ts2,
+			Spans:     []roachpb.Span{span("a", "c")},
+		}, ""},
+		{BackupDescriptor{}, "backup has no end time"},
+		{BackupDescriptor{StartTime: ts2, EndTime: ts1}, "is not before its end time"},
+		{BackupDescriptor{
+			EndTime: ts1,
+			Spans:   []roachpb.Span{span("b", "a")},
+		}, "is empty"},
+		{BackupDescriptor{
+			EndTime: ts1,
+			Spans:   []roachpb.Span{span("a", "c"), span("b", "d")},
+		}, "backup spans .* overlap"},
+		{BackupDescriptor{
+			EndTime: ts1,
+			Spans:   []roachpb.Span{span("a", "c")},
+			Files:   []BackupDescriptor_File{file("a", "b", "")},
+		}, "has no path"},
+		{BackupDescriptor{
+			EndTime: ts1,
+			Spans:   []roachpb.Span{span("a", "c")},
+			Files:   []BackupDescriptor_File{file("a", "b", "1.sst"), file("a", "c", "2.sst")},
+		}, "backup files .* overlap"},
+		{BackupDescriptor{
+			EndTime: ts1,
+			Spans:   []roachpb.Span{span("a", "c"), span("d", "e")},
+			Files:   []BackupDescriptor_File{file("b", "d", "1.sst")},
+		}, "not covered by the backup spans"},
+		{BackupDescriptor{
+			EndTime: ts1,
+			Spans:   []roachpb.Span{span("a", "c")},
+			Files:   []BackupDescriptor_File{file("x", "y", "1.sst")},
+		}, "not covered by the backup spans"},
+	}
+	for i, tc := range testCases {
+		err := verifyBackupDescriptor(tc.desc)
+		if tc.err == "" {
+			if err != nil {
+				t.Errorf("%d: unexpected error: %+v", i, err)
+			}
+		} else if !testutils.IsError(err, tc.err) {
+			t.Errorf("%d: expected error %q, got %v", i, tc.err, err)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/ccl/storageccl/engineccl"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/kv/kverrors"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/storage"
@@ -45,7 +46,11 @@ func init() {
 }
 
 var importBatchSize = func() *settings.ByteSizeSetting {
-	s := settings.RegisterByteSizeSetting("kv.import.batch_size", "", 2<<20)
+	s := settings.RegisterByteSizeSetting(
+		"kv.import.batch_size",
+		"the maximum size of the batches of keys an import writes at once",
+		2<<20,
+	)
 	s.Hide()
 	return s
 }()
@@ -111,7 +116,7 @@ func (b *writeBatcher) Finish(ctx context.Context, db *client.DB) error {
 		if err == nil {
 			return nil
 		}
-		if _, ok := err.(*roachpb.AmbiguousResultError); i == maxWriteBatchRetries || !ok {
+		if i == maxWriteBatchRetries || !kverrors.IsAmbiguous(err) {
 			return errors.Wrapf(err, "writebatch [%s,%s)", start, end)
 		}
 		log.Warningf(ctx, "writebatch [%s,%s) attempt %d failed: %+v",
@@ -165,7 +170,7 @@ func (b *sstBatcher) Finish(ctx context.Context, db *client.DB) error {
 		if err == nil {
 			return nil
 		}
-		if _, ok := err.(*roachpb.AmbiguousResultError); i == maxAddSSTableRetries || !ok {
+		if i == maxAddSSTableRetries || !kverrors.IsAmbiguous(err) {
 			return errors.Wrapf(err, "addsstable [%s,%s)", start, end)
 		}
 		log.Warningf(ctx, "addsstable [%s,%s) attempt %d failed: %+v",
@@ -49,8 +49,9 @@ func Main() {
 	defer log.RecoverAndReportPanic(context.Background())
 
 	if err := Run(os.Args[1:]); err != nil {
-		fmt.Fprintf(stderr, "Failed running %q\n", os.Args[1])
-		os.Exit(ErrorCode)
+		code := exitCode(err)
+		reportError(stderr, cliCtx.errorFormat, os.Args[1], err, code)
+		os.Exit(code)
 	}
 }
 
@@ -104,11 +105,16 @@ func init() {
 
 	// Set an error function for flag parsing which prints the usage message.
 	cockroachCmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
-		if err := c.Usage(); err != nil {
-			return err
+		// The error format is known if the flag was parsed before the invalid
+		// one.
+		setErrorOutput()
+		if cliCtx.errorFormat != errorFormatJSON {
+			if err := c.Usage(); err != nil {
+				return err
+			}
+			fmt.Fprintln(c.OutOrStderr()) // provide a line break between usage and error
 		}
-		fmt.Fprintln(c.OutOrStderr()) // provide a line break between usage and error
-		return err
+		return &cliError{exitCode: exitUsage, cause: err}
 	})
 
 	cockroachCmd.AddCommand(
@@ -513,7 +513,7 @@ func Example_zone() {
 	// num_replicas: 1
 	// constraints: []
 	// zone get system.nonexistent
-	// system.nonexistent not found
+	// pq: table "system.nonexistent" does not exist
 	// zone get system.lease
 	// system
 	// range_min_bytes: 1048576
@@ -523,11 +523,11 @@ func Example_zone() {
 	// num_replicas: 1
 	// constraints: [us-east-1a, ssd]
 	// zone set system.lease --file=./testdata/zone_attrs.yaml
-	// setting zone configs for individual system tables is not supported; try setting your config on the entire "system" database instead
+	// pq: setting zone configs for individual system tables is not supported; try setting your config on the entire "system" database instead
 	// zone set system.namespace --file=./testdata/zone_attrs.yaml
-	// setting zone configs for individual system tables is not supported; try setting your config on the entire "system" database instead
+	// pq: setting zone configs for individual system tables is not supported; try setting your config on the entire "system" database instead
 	// zone set system.nonexistent --file=./testdata/zone_attrs.yaml
-	// system.nonexistent not found
+	// pq: table "system.nonexistent" does not exist
 	// zone set system --file=./testdata/zone_range_max_bytes.yaml
 	// range_min_bytes: 1048576
 	// range_max_bytes: 134217728
@@ -544,11 +544,11 @@ func Example_zone() {
 	// num_replicas: 3
 	// constraints: [us-east-1a, ssd]
 	// zone rm system
-	// DELETE 1
+	// CONFIGURE ZONE
 	// zone ls
 	// .default
 	// zone rm .default
-	// unable to remove special zone .default
+	// pq: unable to remove the default zone
 	// zone set .meta --file=./testdata/zone_range_max_bytes.yaml
 	// range_min_bytes: 1048576
 	// range_max_bytes: 134217728
@@ -614,22 +614,22 @@ func Example_zone() {
 	// num_replicas: 1
 	// constraints: []
 	// zone rm .meta
-	// DELETE 1
+	// CONFIGURE ZONE
 	// zone rm .system
-	// DELETE 1
+	// CONFIGURE ZONE
 	// zone ls
 	// .default
 	// .timeseries
 	// zone rm .timeseries
-	// DELETE 1
+	// CONFIGURE ZONE
 	// zone ls
 	// .default
 	// zone rm .meta
-	// DELETE 0
+	// CONFIGURE ZONE
 	// zone rm .system
-	// DELETE 0
+	// CONFIGURE ZONE
 	// zone rm .timeseries
-	// DELETE 0
+	// CONFIGURE ZONE
 }
 
 func Example_sql() {
@@ -1215,6 +1215,7 @@ Available Commands:
 
 Flags:
       --log-backtrace-at traceLocation   when logging hits line file:N, emit a stack trace (default :0)
+      --log-config-file string           if non-empty, a file of name=value logging flags applied at startup and reloaded on SIGHUP
       --log-dir string                   if non-empty, write log files in this directory
       --log-dir-max-size bytes           maximum combined size of all log files (default 100 MiB)
       --log-file-max-size bytes          maximum size of each log file (default 10 MiB)
@@ -1589,13 +1590,20 @@ writing ` + os.DevNull + `
   debug/nodes/1/ranges/8
   debug/nodes/1/ranges/9
   debug/nodes/1/ranges/10
+  debug/nodes/1/ranges/11
+  debug/nodes/1/ranges/12
+  debug/nodes/1/ranges/13
+  debug/nodes/1/ranges/14
+  debug/nodes/1/ranges/15
   debug/schema/system@details
   debug/schema/system/descriptor
   debug/schema/system/eventlog
   debug/schema/system/jobs
   debug/schema/system/lease
+  debug/schema/system/livenesslog
   debug/schema/system/namespace
   debug/schema/system/rangelog
+  debug/schema/system/scheduled_jobs
   debug/schema/system/settings
   debug/schema/system/ui
   debug/schema/system/users
@@ -163,6 +163,27 @@ csv, pretty, records, sql, html. If left unspecified, defaults to tsv
 for non-interactive sessions and pretty for interactive sessions.`,
 	}
 
+	ErrorFormat = FlagInfo{
+		Name: "error-format",
+		Description: `
+Selects how to report the error ending the command on the standard error.
+Possible values: text, json. In JSON, the error is a single object with the
+command, exit code, class and message of the error, along with the SQLSTATE
+code, detail and hint of the errors returned by the server. Whatever the
+format, the exit code of the command tells the kinds of failures apart:
+<PRE>
+
+  0  success
+  1  other error (class: error)
+  2  invalid invocation, e.g. an unknown flag (class: usage)
+  3  failure to connect or authenticate, or connection lost (class: connection)
+  4  SQL error (class: sql)
+  5  error after some statements were executed (class: partial_success)
+</PRE>
+A node terminated by a signal during its graceful shutdown exits with 128 plus
+the signal number (class: signal).`,
+	}
+
 	Join = FlagInfo{
 		Name:      "join",
 		Shorthand: "j",
@@ -243,6 +264,14 @@ After the CockroachDB node has started up successfully, it will
 write its process ID to the specified file.`,
 	}
 
+	StrictPreflight = FlagInfo{
+		Name: "strict-preflight",
+		Description: `
+Fail to start if one of the preflight checks of the environment of the node
+(open file limits, clock, stores, memory and certificates) fails, instead of
+only logging the problem.`,
+	}
+
 	Socket = FlagInfo{
 		Name:   "socket",
 		EnvVar: "COCKROACH_SOCKET",
@@ -297,6 +326,13 @@ a public network without combining it with --host.`,
 		Description: `Define the maximum number of results that will be retrieved.`,
 	}
 
+	DowntimeSince = FlagInfo{
+		Name: "since",
+		Description: `
+Length of the reporting period, which ends now, e.g. "720h". By default, the
+whole recorded history is reported on.`,
+	}
+
 	Password = FlagInfo{
 		Name:        "password",
 		Description: `Prompt for the new user's password.`,
@@ -473,4 +509,44 @@ If specified, takes priority over host/port flags.`,
 If specified, print the system config contents. Beware that the output will be
 long and not particularly human-readable.`,
 	}
+
+	DecodeEncoding = FlagInfo{
+		Name: "encoding",
+		Description: `
+Encoding of the arguments, either hex or base64. If not specified, hex is tried
+first, then base64.`,
+	}
+
+	MergeLogsFrom = FlagInfo{
+		Name: "from",
+		Description: `
+Only print the log entries at or after the given time, e.g. "2017-06-01 15:04:05".
+Times without a time zone are in the local time zone.`,
+	}
+
+	MergeLogsTo = FlagInfo{
+		Name: "to",
+		Description: `
+Only print the log entries at or before the given time, e.g. "2017-06-01 15:04:05".
+Times without a time zone are in the local time zone.`,
+	}
+
+	MergeLogsFilter = FlagInfo{
+		Name: "filter",
+		Description: `
+Only print the log entries whose message or file name match the given regular
+expression.`,
+	}
+
+	TraceDuration = FlagInfo{
+		Name: "duration",
+		Description: `
+How long to record the matching spans for. The node caps the duration at 10
+minutes.`,
+	}
+
+	TraceStop = FlagInfo{
+		Name:        "stop",
+		Description: `Stop the recording of matching spans.`,
+	}
 )
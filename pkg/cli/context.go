@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/keys"
@@ -51,6 +52,9 @@ type cliContext struct {
 
 	// tableDisplayFormat indicates how to format result tables.
 	tableDisplayFormat tableDisplayFormat
+
+	// errorFormat indicates how to report the error ending a command.
+	errorFormat errorFormat
 }
 
 type tableDisplayFormat int
@@ -113,6 +117,40 @@ func (f *tableDisplayFormat) Set(s string) error {
 	return nil
 }
 
+type errorFormat int
+
+const (
+	errorFormatText errorFormat = iota
+	errorFormatJSON
+)
+
+// Type implements the pflag.Value interface.
+func (f *errorFormat) Type() string { return "string" }
+
+// String implements the pflag.Value interface.
+func (f *errorFormat) String() string {
+	switch *f {
+	case errorFormatText:
+		return "text"
+	case errorFormatJSON:
+		return "json"
+	}
+	return ""
+}
+
+// Set implements the pflag.Value interface.
+func (f *errorFormat) Set(s string) error {
+	switch s {
+	case "text":
+		*f = errorFormatText
+	case "json":
+		*f = errorFormatJSON
+	default:
+		return fmt.Errorf("invalid error format: %s (possible values: text, json)", s)
+	}
+	return nil
+}
+
 type sqlContext struct {
 	// Embed the cli context.
 	*cliContext
@@ -267,4 +305,12 @@ type debugContext struct {
 	replicated        bool
 	inputFile         string
 	printSystemConfig bool
+	encoding          string
+	// The time range and filter of `debug merge-logs`.
+	logsFrom, logsTo string
+	logsFilter       string
+	// The duration of the recording started by `debug trace-ondemand`, and
+	// whether to stop it instead.
+	traceDuration time.Duration
+	traceStop     bool
 }
@@ -110,6 +110,18 @@ func printKeyValue(kv engine.MVCCKeyValue) (bool, error) {
 	if debugCtx.sizes {
 		fmt.Printf("%d %d: ", len(kv.Key.Key), len(kv.Value))
 	}
+	if out, ok := tryDecodeValue(kv); ok {
+		fmt.Println(out)
+		return false, nil
+	}
+	// No better idea, just print raw bytes and hope that folks use `less -S`.
+	fmt.Printf("%q\n\n", kv.Value)
+	return false, nil
+}
+
+// tryDecodeValue pretty-prints the value of an internal key, trying each of the
+// known formats in turn. It returns false if none of them applies.
+func tryDecodeValue(kv engine.MVCCKeyValue) (string, bool) {
 	decoders := []func(kv engine.MVCCKeyValue) (string, error){
 		tryRaftLogEntry,
 		tryRangeDescriptor,
@@ -122,12 +134,9 @@ func printKeyValue(kv engine.MVCCKeyValue) (bool, error) {
 		if err != nil {
 			continue
 		}
-		fmt.Println(out)
-		return false, nil
+		return out, true
 	}
-	// No better idea, just print raw bytes and hope that folks use `less -S`.
-	fmt.Printf("%q\n\n", kv.Value)
-	return false, nil
+	return "", false
 }
 
 func runDebugKeys(cmd *cobra.Command, args []string) error {
@@ -570,7 +579,7 @@ func runDebugGCCmd(cmd *cobra.Command, args []string) error {
 		snap := db.NewSnapshot()
 		defer snap.Close()
 		_, info, err := storage.RunGC(context.Background(), &desc, snap, hlc.Timestamp{WallTime: timeutil.Now().UnixNano()},
-			config.GCPolicy{TTLSeconds: 24 * 60 * 60 /* 1 day */}, func(_ hlc.Timestamp, _ *roachpb.Transaction, _ roachpb.PushTxnType) {
+			config.ZoneConfig{GC: config.GCPolicy{TTLSeconds: 24 * 60 * 60 /* 1 day */}}, func(_ hlc.Timestamp, _ *roachpb.Transaction, _ roachpb.PushTxnType) {
 			}, func(_ []roachpb.Intent, _, _ bool) error { return nil })
 		if err != nil {
 			return err
@@ -905,6 +914,10 @@ var debugCmds = []*cobra.Command{
 	debugCompactCmd,
 	debugSSTablesCmd,
 	debugGossipValuesCmd,
+	debugDecodeProtoCmd,
+	debugDecodeKVCmd,
+	debugMergeLogsCmd,
+	debugTraceOnDemandCmd,
 	rangeCmd,
 	debugEnvCmd,
 	debugZipCmd,
@@ -0,0 +1,300 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/coreos/etcd/raft/raftpb"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// protoTypeAliases maps the short names accepted by `debug decode-proto` to
+// the messages they decode. Raft entries are handled separately.
+var protoTypeAliases = map[string]func() proto.Message{
+	"descriptor":       func() proto.Message { return &sqlbase.Descriptor{} },
+	"table-descriptor": func() proto.Message { return &sqlbase.TableDescriptor{} },
+	"range-descriptor": func() proto.Message { return &roachpb.RangeDescriptor{} },
+	"raft-command":     func() proto.Message { return &storagebase.RaftCommand{} },
+	"lease":            func() proto.Message { return &roachpb.Lease{} },
+	"txn":              func() proto.Message { return &roachpb.Transaction{} },
+	"mvcc-metadata":    func() proto.Message { return &enginepb.MVCCMetadata{} },
+}
+
+const raftEntryProtoType = "raft-entry"
+
+func protoTypeNames() string {
+	names := []string{raftEntryProtoType}
+	for name := range protoTypeAliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+var debugDecodeProtoCmd = &cobra.Command{
+	Use:   "decode-proto [type] [encoded]",
+	Short: "decode a protocol buffer into JSON",
+	Long: `
+Decodes a hex or base64 encoded protocol buffer message and prints it as JSON.
+If the encoded message is omitted, it is read from standard input.
+
+The type is either one of ` + protoTypeNames() + `,
+or the fully qualified name of a message, e.g. cockroach.roachpb.RangeDescriptor.
+The data of a raft entry is decoded as a raft command or configuration change.
+`,
+	RunE: MaybeDecorateGRPCError(runDebugDecodeProto),
+}
+
+var debugDecodeKVCmd = &cobra.Command{
+	Use:   "decode-kv [key] [value]",
+	Short: "decode an MVCC key and value into JSON",
+	Long: `
+Decodes a hex or base64 encoded MVCC key, and optionally its value, as found in
+the data files of a store, and prints them as JSON. A key without an encoded
+timestamp is accepted as well.
+`,
+	RunE: MaybeDecorateGRPCError(runDebugDecodeKV),
+}
+
+// decodeBytesArg decodes a command line argument in the given encoding. If the
+// encoding is empty, hex is tried first, then base64.
+func decodeBytesArg(arg, encoding string) ([]byte, error) {
+	arg = strings.TrimSpace(arg)
+	switch encoding {
+	case "hex":
+		return hex.DecodeString(arg)
+	case "base64":
+		return base64.StdEncoding.DecodeString(arg)
+	case "":
+		if b, err := hex.DecodeString(arg); err == nil {
+			return b, nil
+		}
+		if b, err := base64.StdEncoding.DecodeString(arg); err == nil {
+			return b, nil
+		}
+		if b, err := base64.URLEncoding.DecodeString(arg); err == nil {
+			return b, nil
+		}
+		return nil, errors.Errorf("%q is neither hex nor base64 encoded", arg)
+	default:
+		return nil, errors.Errorf("unknown encoding %q; expected hex or base64", encoding)
+	}
+}
+
+// newProtoMessage returns an empty message of the given type, which is either
+// a short name from protoTypeAliases or a fully qualified message name.
+func newProtoMessage(typ string) (proto.Message, error) {
+	if newMsg, ok := protoTypeAliases[typ]; ok {
+		return newMsg(), nil
+	}
+	if t := proto.MessageType(typ); t != nil && t.Kind() == reflect.Ptr {
+		if msg, ok := reflect.New(t.Elem()).Interface().(proto.Message); ok {
+			return msg, nil
+		}
+	}
+	return nil, errors.Errorf("unknown message type %q; expected one of %s or a fully qualified name",
+		typ, protoTypeNames())
+}
+
+func marshalJSON(msg proto.Message) (json.RawMessage, error) {
+	m := jsonpb.Marshaler{OrigName: true}
+	s, err := m.MarshalToString(msg)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(s), nil
+}
+
+func indentJSON(v interface{}) (string, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// decodeProtoJSON decodes data as a message of the given type and returns its
+// JSON representation.
+func decodeProtoJSON(typ string, data []byte) (string, error) {
+	if typ == raftEntryProtoType {
+		return decodeRaftEntryJSON(data)
+	}
+	msg, err := newProtoMessage(typ)
+	if err != nil {
+		return "", err
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return "", errors.Wrapf(err, "failed to decode %s", typ)
+	}
+	out, err := marshalJSON(msg)
+	if err != nil {
+		return "", err
+	}
+	return indentJSON(out)
+}
+
+// decodeRaftEntryJSON decodes a raft entry, along with the command or
+// configuration change it carries.
+func decodeRaftEntryJSON(data []byte) (string, error) {
+	var ent raftpb.Entry
+	if err := ent.Unmarshal(data); err != nil {
+		return "", errors.Wrap(err, "failed to decode raft entry")
+	}
+	result := make(map[string]json.RawMessage)
+	payload := ent.Data
+	ent.Data = nil
+	var err error
+	if result["entry"], err = marshalJSON(&ent); err != nil {
+		return "", err
+	}
+
+	switch {
+	case len(payload) == 0:
+	case ent.Type == raftpb.EntryNormal:
+		_, cmdData := storage.DecodeRaftCommand(payload)
+		var cmd storagebase.RaftCommand
+		if err := cmd.Unmarshal(cmdData); err != nil {
+			return "", errors.Wrap(err, "failed to decode raft command")
+		}
+		if result["command"], err = marshalJSON(&cmd); err != nil {
+			return "", err
+		}
+	case ent.Type == raftpb.EntryConfChange:
+		var cc raftpb.ConfChange
+		if err := cc.Unmarshal(payload); err != nil {
+			return "", errors.Wrap(err, "failed to decode configuration change")
+		}
+		var ctx storage.ConfChangeContext
+		if err := ctx.Unmarshal(cc.Context); err != nil {
+			return "", errors.Wrap(err, "failed to decode configuration change context")
+		}
+		var res storagebase.ReplicatedEvalResult
+		if err := res.Unmarshal(ctx.Payload); err != nil {
+			return "", errors.Wrap(err, "failed to decode configuration change payload")
+		}
+		cc.Context = nil
+		if result["conf_change"], err = marshalJSON(&cc); err != nil {
+			return "", err
+		}
+		if result["replicated_eval_result"], err = marshalJSON(&res); err != nil {
+			return "", err
+		}
+	}
+	return indentJSON(result)
+}
+
+// decodeKVJSON decodes an MVCC key and, if not nil, its value.
+func decodeKVJSON(key, value []byte) (string, error) {
+	if len(key) == 0 {
+		return "", errors.New("empty key")
+	}
+	mvccKey, err := engine.DecodeKey(key)
+	if err != nil {
+		// Not an encoded MVCC key; treat it as a plain key.
+		mvccKey = engine.MakeMVCCMetadataKey(roachpb.Key(key))
+	}
+
+	result := struct {
+		Key       string `json:"key"`
+		RawKey    string `json:"raw_key"`
+		Timestamp string `json:"timestamp,omitempty"`
+		Value     string `json:"value,omitempty"`
+	}{
+		Key:    mvccKey.Key.String(),
+		RawKey: fmt.Sprintf("%q", []byte(mvccKey.Key)),
+	}
+	if mvccKey.Timestamp != (hlc.Timestamp{}) {
+		result.Timestamp = mvccKey.Timestamp.String()
+	}
+	if value != nil {
+		kv := engine.MVCCKeyValue{Key: mvccKey, Value: value}
+		if out, ok := tryDecodeValue(kv); ok {
+			result.Value = strings.TrimSpace(out)
+		} else if mvccKey.Timestamp != (hlc.Timestamp{}) {
+			result.Value = roachpb.Value{RawBytes: value}.PrettyPrint()
+		} else {
+			result.Value = fmt.Sprintf("%q", value)
+		}
+	}
+	return indentJSON(result)
+}
+
+func runDebugDecodeProto(cmd *cobra.Command, args []string) error {
+	var encoded string
+	switch len(args) {
+	case 1:
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		encoded = string(b)
+	case 2:
+		encoded = args[1]
+	default:
+		return errors.New("one or two arguments required: type [encoded]")
+	}
+	data, err := decodeBytesArg(encoded, debugCtx.encoding)
+	if err != nil {
+		return err
+	}
+	out, err := decodeProtoJSON(args[0], data)
+	if err != nil {
+		return err
+	}
+	fmt.Println(out)
+	return nil
+}
+
+func runDebugDecodeKV(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 && len(args) != 2 {
+		return errors.New("one or two arguments required: key [value]")
+	}
+	key, err := decodeBytesArg(args[0], debugCtx.encoding)
+	if err != nil {
+		return err
+	}
+	var value []byte
+	if len(args) == 2 {
+		if value, err = decodeBytesArg(args[1], debugCtx.encoding); err != nil {
+			return err
+		}
+	}
+	out, err := decodeKVJSON(key, value)
+	if err != nil {
+		return err
+	}
+	fmt.Println(out)
+	return nil
+}
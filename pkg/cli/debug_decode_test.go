@@ -0,0 +1,126 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestDecodeBytesArg(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	data := []byte("\x00\x01hello\xff")
+	testCases := []struct {
+		arg, encoding string
+		err           string
+	}{
+		{hex.EncodeToString(data), "", ""},
+		{hex.EncodeToString(data), "hex", ""},
+		{base64.StdEncoding.EncodeToString(data), "", ""},
+		{base64.StdEncoding.EncodeToString(data), "base64", ""},
+		{base64.URLEncoding.EncodeToString(data), "", ""},
+		{"  " + hex.EncodeToString(data) + "\n", "", ""},
+		{"not encoded!", "", "neither hex nor base64"},
+		{hex.EncodeToString(data), "base32", "unknown encoding"},
+	}
+	for i, c := range testCases {
+		b, err := decodeBytesArg(c.arg, c.encoding)
+		if !testutils.IsError(err, c.err) {
+			t.Errorf("%d: expected error %q, got %v", i, c.err, err)
+			continue
+		}
+		if c.err == "" && string(b) != string(data) {
+			t.Errorf("%d: expected %q, got %q", i, data, b)
+		}
+	}
+}
+
+func TestDecodeProtoJSON(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	desc := roachpb.RangeDescriptor{
+		RangeID:  5,
+		StartKey: roachpb.RKey("a"),
+		EndKey:   roachpb.RKey("b"),
+		Replicas: []roachpb.ReplicaDescriptor{{NodeID: 2, StoreID: 3, ReplicaID: 4}},
+	}
+	data, err := desc.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []string{"range-descriptor", "cockroach.roachpb.RangeDescriptor"} {
+		out, err := decodeProtoJSON(typ, data)
+		if err != nil {
+			t.Fatalf("%s: %v", typ, err)
+		}
+		for _, exp := range []string{`"node_id": 2`, `"store_id": 3`, `"replica_id": 4`} {
+			if !strings.Contains(out, exp) {
+				t.Errorf("%s: expected %s in output:\n%s", typ, exp, out)
+			}
+		}
+	}
+
+	if _, err := decodeProtoJSON("no-such-type", data); !testutils.IsError(err, "unknown message type") {
+		t.Errorf("expected unknown message type error, got %v", err)
+	}
+	if _, err := decodeProtoJSON("range-descriptor", []byte("\xff\xff")); err == nil {
+		t.Error("expected an error decoding garbage")
+	}
+}
+
+func TestDecodeKVJSON(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// An MVCC key is encoded as the key, a zero byte, the timestamp and the
+	// length of the timestamp.
+	key := roachpb.Key("foo")
+	encoded := append([]byte(nil), key...)
+	encoded = append(encoded, 0)
+	var wallTime [8]byte
+	binary.BigEndian.PutUint64(wallTime[:], 123)
+	encoded = append(encoded, wallTime[:]...)
+	encoded = append(encoded, byte(len(wallTime)+1))
+
+	value := roachpb.MakeValueFromString("bar")
+	value.InitChecksum(key)
+
+	out, err := decodeKVJSON(encoded, value.RawBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{`"key": "\"foo\""`, `"timestamp": "0.000000123,0"`, `"value"`} {
+		if !strings.Contains(out, exp) {
+			t.Errorf("expected %s in output:\n%s", exp, out)
+		}
+	}
+
+	// A key without a timestamp is decoded as a plain key.
+	out, err = decodeKVJSON([]byte("foo"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, `"timestamp"`) || strings.Contains(out, `"value"`) {
+		t.Errorf("unexpected timestamp or value in output:\n%s", out)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var debugMergeLogsCmd = &cobra.Command{
+	Use:   "merge-logs [path]...",
+	Short: "merge the logs of multiple nodes into a single stream",
+	Long: `
+Reads the log files of one or more nodes and prints their entries as a single
+stream ordered by time. Each path is either a log file or a directory
+containing log files, such as the logs directory of a node or the directory
+extracted from 'cockroach debug zip'. Every entry is prefixed with the node it
+came from: n<id> for the paths of a debug zip (nodes/<id>/...), or the name of
+the path otherwise.
+
+The --from and --to flags restrict the output to a time range, and --filter
+to the entries whose message or file name match a regular expression.
+`,
+	RunE: MaybeDecorateGRPCError(runDebugMergeLogs),
+}
+
+// mergeLogsTimeFormats are the formats accepted by the --from and --to flags of
+// `debug merge-logs`. Times without a time zone are in the local time zone, like
+// the timestamps of the log entries.
+var mergeLogsTimeFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"060102 15:04:05.999999",
+}
+
+func parseMergeLogsTime(s string) (time.Time, error) {
+	for _, format := range mergeLogsTimeFormats {
+		if t, err := time.ParseInLocation(format, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("unable to parse time %q; expected e.g. %q",
+		s, mergeLogsTimeFormats[1])
+}
+
+func runDebugMergeLogs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("at least one path is required")
+	}
+
+	var from, to int64 = 0, math.MaxInt64
+	if debugCtx.logsFrom != "" {
+		t, err := parseMergeLogsTime(debugCtx.logsFrom)
+		if err != nil {
+			return err
+		}
+		from = t.UnixNano()
+	}
+	if debugCtx.logsTo != "" {
+		t, err := parseMergeLogsTime(debugCtx.logsTo)
+		if err != nil {
+			return err
+		}
+		to = t.UnixNano()
+	}
+	var filter *regexp.Regexp
+	if debugCtx.logsFilter != "" {
+		var err error
+		if filter, err = regexp.Compile(debugCtx.logsFilter); err != nil {
+			return err
+		}
+	}
+
+	var files []logSource
+	for _, path := range args {
+		pathFiles, err := findLogFiles(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, pathFiles...)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if err := mergeLogs(out, files, from, to, filter); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// logSource is a log file and the label of the node it belongs to.
+type logSource struct {
+	label string
+	path  string
+}
+
+var debugZipNodeRE = regexp.MustCompile(`(?:^|/)nodes/(\d+)(?:/|$)`)
+
+// logSourceLabel returns the label for the log files found under the given
+// path: n<id> if the path is part of a debug zip, and the base name of the path
+// otherwise.
+func logSourceLabel(path string) string {
+	if m := debugZipNodeRE.FindStringSubmatch(filepath.ToSlash(path)); m != nil {
+		return "n" + m[1]
+	}
+	return filepath.Base(filepath.Clean(path))
+}
+
+// findLogFiles returns the log files found under the given path. Symlinks are
+// skipped, since the symlinks in a log directory point to files that are
+// listed already.
+func findLogFiles(path string) ([]logSource, error) {
+	label := logSourceLabel(path)
+	var files []logSource
+	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		files = append(files, logSource{label: label, path: p})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("no log files found in %s", path)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
+
+// logStream reads the entries of a log file in order.
+type logStream struct {
+	logSource
+	// idx is the position of the file in the arguments, used to order entries
+	// with the same timestamp.
+	idx     int
+	file    *os.File
+	decoder *log.EntryDecoder
+	entry   log.Entry
+}
+
+// next reads the next entry from the file. It returns false when there are no
+// more entries.
+func (s *logStream) next() (bool, error) {
+	s.entry = log.Entry{}
+	if err := s.decoder.Decode(&s.entry); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "reading %s", s.path)
+	}
+	return true, nil
+}
+
+// logStreamHeap orders log streams by the timestamp of their current entry.
+type logStreamHeap []*logStream
+
+func (h logStreamHeap) Len() int { return len(h) }
+func (h logStreamHeap) Less(i, j int) bool {
+	if h[i].entry.Time != h[j].entry.Time {
+		return h[i].entry.Time < h[j].entry.Time
+	}
+	return h[i].idx < h[j].idx
+}
+func (h logStreamHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *logStreamHeap) Push(x interface{}) { *h = append(*h, x.(*logStream)) }
+func (h *logStreamHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// mergeLogs writes the entries of the given log files to w, ordered by
+// timestamp and prefixed with the label of their file. Only the entries in
+// [from, to] whose message or file name match filter (if not nil) are written.
+// The entries of each file are assumed to be in order, which is the case for
+// the files written by a single process.
+func mergeLogs(w io.Writer, files []logSource, from, to int64, filter *regexp.Regexp) error {
+	h := make(logStreamHeap, 0, len(files))
+	defer func() {
+		for _, s := range h {
+			_ = s.file.Close()
+		}
+	}()
+	for i, src := range files {
+		f, err := os.Open(src.path)
+		if err != nil {
+			return err
+		}
+		s := &logStream{logSource: src, idx: i, file: f, decoder: log.NewEntryDecoder(f)}
+		ok, err := s.next()
+		if !ok || err != nil {
+			_ = f.Close()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		h = append(h, s)
+	}
+	heap.Init(&h)
+
+	width := mergeLogsLabelWidth(files)
+	for len(h) > 0 {
+		s := h[0]
+		e := s.entry
+		if e.Time >= from && e.Time <= to &&
+			(filter == nil || filter.MatchString(e.Message) || filter.MatchString(e.File)) {
+			if _, err := fmt.Fprintf(w, "%-*s ", width, s.label); err != nil {
+				return err
+			}
+			if err := e.Format(w); err != nil {
+				return err
+			}
+		}
+		ok, err := s.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+			_ = s.file.Close()
+		}
+	}
+	return nil
+}
+
+// mergeLogsLabelWidth returns the width of the longest label, for aligning the
+// output.
+func mergeLogsLabelWidth(files []logSource) int {
+	var width int
+	for _, f := range files {
+		if l := len(f.label); l > width {
+			width = l
+		}
+	}
+	return width
+}
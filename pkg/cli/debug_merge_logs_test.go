@@ -0,0 +1,147 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestMergeLogs(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, err := ioutil.TempDir("", "TestMergeLogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// Lay out the logs like a debug zip. Node 1 has two log files, and node 2's
+	// directory contains a symlink which must be skipped.
+	logs := map[string]string{
+		"nodes/1/logs/cockroach.a.log": `I170601 10:00:00.000000 1 server.go:10  n1 first
+I170601 10:00:02.000000 1 server.go:11  n1 second
+`,
+		"nodes/1/logs/cockroach.b.log": `W170601 10:00:04.000000 5 store.go:20  n1 third
+`,
+		"nodes/2/logs/cockroach.a.log": `I170601 10:00:01.000000 7 server.go:10  n2 first
+E170601 10:00:03.000000 7 replica.go:30  n2 second
+spanning two lines
+`,
+	}
+	for name, content := range logs {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(
+		filepath.Join(dir, "nodes/2/logs/cockroach.a.log"),
+		filepath.Join(dir, "nodes/2/logs/cockroach.log"),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []logSource
+	for _, node := range []string{"nodes/1", "nodes/2"} {
+		nodeFiles, err := findLogFiles(filepath.Join(dir, node))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, nodeFiles...)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected 3 log files, got %+v", files)
+	}
+
+	from, err := parseMergeLogsTime("2017-06-01 10:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := parseMergeLogsTime("170601 10:00:03.000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		from, to int64
+		filter   string
+		expected string
+	}{
+		{0, math.MaxInt64, "", `n1 I170601 10:00:00.000000 1 server.go:10  n1 first
+n2 I170601 10:00:01.000000 7 server.go:10  n2 first
+n1 I170601 10:00:02.000000 1 server.go:11  n1 second
+n2 E170601 10:00:03.000000 7 replica.go:30  n2 second
+spanning two lines
+n1 W170601 10:00:04.000000 5 store.go:20  n1 third
+`},
+		{from.UnixNano(), to.UnixNano(), "", `n2 I170601 10:00:01.000000 7 server.go:10  n2 first
+n1 I170601 10:00:02.000000 1 server.go:11  n1 second
+n2 E170601 10:00:03.000000 7 replica.go:30  n2 second
+spanning two lines
+`},
+		{0, math.MaxInt64, "second|store", `n1 I170601 10:00:02.000000 1 server.go:11  n1 second
+n2 E170601 10:00:03.000000 7 replica.go:30  n2 second
+spanning two lines
+n1 W170601 10:00:04.000000 5 store.go:20  n1 third
+`},
+	}
+	for i, tc := range testCases {
+		var filter *regexp.Regexp
+		if tc.filter != "" {
+			filter = regexp.MustCompile(tc.filter)
+		}
+		var buf bytes.Buffer
+		if err := mergeLogs(&buf, files, tc.from, tc.to, filter); err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if out := buf.String(); out != tc.expected {
+			t.Errorf("%d: expected:\n%s\ngot:\n%s", i, tc.expected, out)
+		}
+	}
+}
+
+func TestLogSourceLabel(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		path, expected string
+	}{
+		{"debug/nodes/3", "n3"},
+		{"debug/nodes/12/logs/cockroach.log", "n12"},
+		{"/mnt/data1/logs/", "logs"},
+		{"node4.log", "node4.log"},
+	}
+	for _, tc := range testCases {
+		if label := logSourceLabel(tc.path); label != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.path, tc.expected, label)
+		}
+	}
+}
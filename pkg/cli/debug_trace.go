@@ -0,0 +1,86 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License. See the AUTHORS file
+// for names of contributors.
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// onDemandTracingPath is the path of the on-demand tracing endpoint of the
+// node's HTTP server.
+const onDemandTracingPath = "/debug/tracez/ondemand"
+
+var debugTraceOnDemandCmd = &cobra.Command{
+	Use:   "trace-ondemand [pattern]",
+	Short: "record the spans of a running node matching a pattern",
+	Long: `
+Starts recording, on the node at --host, the new spans whose operation name
+matches the given regular expression, for --duration. Without a pattern, prints
+the recordings collected so far as a JSON array. With --stop, stops recording.
+
+The recordings are also available at the /debug/tracez/ondemand page of the
+node's admin UI.
+`,
+	RunE: MaybeDecorateGRPCError(runDebugTraceOnDemand),
+}
+
+func runDebugTraceOnDemand(cmd *cobra.Command, args []string) error {
+	if len(args) > 1 || (len(args) == 1 && debugCtx.traceStop) {
+		return usageAndError(cmd)
+	}
+
+	client, err := serverCfg.GetHTTPClient()
+	if err != nil {
+		return err
+	}
+	endpoint := serverCfg.AdminURL() + onDemandTracingPath + "?format=json"
+
+	form := url.Values{}
+	if debugCtx.traceStop {
+		form.Set("stop", "true")
+	} else if len(args) == 1 {
+		form.Set("pattern", args[0])
+		form.Set("duration", debugCtx.traceDuration.String())
+	}
+	var resp *http.Response
+	if len(form) > 0 {
+		resp, err = client.PostForm(endpoint, form)
+	} else {
+		resp, err = client.Get(endpoint)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if len(form) == 0 {
+		fmt.Println(string(body))
+	}
+	return nil
+}
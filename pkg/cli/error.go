@@ -15,8 +15,14 @@
 package cli
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net"
+	"strings"
 
+	"github.com/lib/pq"
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
@@ -26,6 +32,135 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// The exit codes of the commands, which scripts can rely on to tell the
+// kinds of failures apart. They are documented in the description of the
+// --error-format flag: keep it in sync.
+const (
+	// exitError is the exit code of the errors which don't fall in any of
+	// the categories below.
+	exitError = 1
+	// exitUsage is the exit code of the invalid invocations, e.g. an unknown
+	// flag or a missing argument.
+	exitUsage = 2
+	// exitConnection is the exit code of the failures to connect or
+	// authenticate to a node, and of the connections lost.
+	exitConnection = 3
+	// exitSQL is the exit code of the errors returned by the execution of a
+	// SQL statement.
+	exitSQL = 4
+	// exitPartialSuccess is the exit code of the failures of a statement
+	// after some others of the same command were executed successfully.
+	exitPartialSuccess = 5
+)
+
+// exitClasses are the names of the exit codes in the errors reported with
+// --error-format=json.
+var exitClasses = map[int]string{
+	exitError:          "error",
+	exitUsage:          "usage",
+	exitConnection:     "connection",
+	exitSQL:            "sql",
+	exitPartialSuccess: "partial_success",
+}
+
+// A cliError is an error which determines the exit code of the process.
+type cliError struct {
+	exitCode int
+	cause    error
+}
+
+func (e *cliError) Error() string {
+	return e.cause.Error()
+}
+
+// Cause implements the causer interface of github.com/pkg/errors.
+func (e *cliError) Cause() error {
+	return e.cause
+}
+
+// exitCode returns the exit code of the process for an error returned by a
+// command. The exit code set by a cliError takes precedence over the one of
+// the errors it wraps.
+func exitCode(err error) int {
+	for e := err; e != nil; {
+		if cliErr, ok := e.(*cliError); ok {
+			return cliErr.exitCode
+		}
+		causer, ok := e.(interface {
+			Cause() error
+		})
+		if !ok {
+			break
+		}
+		e = causer.Cause()
+	}
+
+	switch cause := errors.Cause(err).(type) {
+	case *pq.Error:
+		// The classes 08 (connection exception) and 28 (invalid authorization
+		// specification) are reported by the server when it refuses the
+		// connection.
+		if class := cause.Code.Class(); class == "08" || class == "28" {
+			return exitConnection
+		}
+		return exitSQL
+	case *net.OpError, *roachpb.SendError:
+		return exitConnection
+	default:
+		if cause == driver.ErrBadConn || grpcutil.IsClosedConnection(cause) {
+			return exitConnection
+		}
+		// Cobra doesn't give the unknown commands an error type.
+		if strings.HasPrefix(cause.Error(), "unknown command ") {
+			return exitUsage
+		}
+		return exitError
+	}
+}
+
+// jsonError is the error reported with --error-format=json.
+type jsonError struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+	Class    string `json:"class"`
+	Message  string `json:"message"`
+	// The SQLSTATE code, detail and hint of the errors returned by the
+	// server.
+	SQLState string `json:"sqlstate,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+// reportError prints the error which ended the command, along with its exit
+// code and class if the error format is JSON.
+func reportError(w io.Writer, format errorFormat, command string, err error, code int) {
+	if format != errorFormatJSON {
+		fmt.Fprintf(w, "Failed running %q\n", command)
+		return
+	}
+	jsonErr := jsonError{
+		Command:  command,
+		ExitCode: code,
+		Class:    exitClasses[code],
+		Message:  err.Error(),
+	}
+	if jsonErr.Class == "" {
+		// The process was terminated by a signal during a graceful shutdown.
+		jsonErr.Class = "signal"
+	}
+	if pqErr, ok := errors.Cause(err).(*pq.Error); ok {
+		jsonErr.SQLState = string(pqErr.Code)
+		jsonErr.Detail = pqErr.Detail
+		jsonErr.Hint = pqErr.Hint
+	}
+	out, marshalErr := json.Marshal(jsonErr)
+	if marshalErr != nil {
+		fmt.Fprintf(w, "Failed running %q: %s\n", command, err)
+		return
+	}
+	fmt.Fprintf(w, "%s\n", out)
+}
+
 // MaybeDecorateGRPCError catches grpc errors and provides a more helpful error
 // message to the user.
 func MaybeDecorateGRPCError(
@@ -54,7 +189,7 @@ communicate with a secure cluster).
 
 %s`
 
-		return errors.Errorf(format, err)
+		return &cliError{exitCode: exitConnection, cause: errors.Errorf(format, err)}
 	}
 }
 
@@ -71,9 +206,17 @@ func MaybeShoutError(
 	}
 }
 
+// setErrorOutput keeps cobra from printing the error ending a command when
+// Main reports it as JSON.
+func setErrorOutput() {
+	cockroachCmd.SilenceErrors = cliCtx.errorFormat == errorFormatJSON
+}
+
 func usageAndError(cmd *cobra.Command) error {
-	if err := cmd.Usage(); err != nil {
-		return err
+	if cliCtx.errorFormat != errorFormatJSON {
+		if err := cmd.Usage(); err != nil {
+			return err
+		}
 	}
-	return errors.New("invalid arguments")
+	return &cliError{exitCode: exitUsage, cause: errors.New("invalid arguments")}
 }
@@ -0,0 +1,81 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestExitCode(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sqlErr := &pq.Error{Code: "42P01", Message: `table "foo" does not exist`}
+	for i, tc := range []struct {
+		err      error
+		expected int
+	}{
+		{errors.New("boom"), exitError},
+		{&cliError{exitCode: exitUsage, cause: errors.New("invalid arguments")}, exitUsage},
+		{fmt.Errorf(`unknown command "foo" for "cockroach"`), exitUsage},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, exitConnection},
+		{errors.Wrap(driver.ErrBadConn, "executing"), exitConnection},
+		{&pq.Error{Code: "28000", Message: "invalid authorization"}, exitConnection},
+		{sqlErr, exitSQL},
+		{errors.Wrap(sqlErr, "executing"), exitSQL},
+		{partialSuccessError(sqlErr, 0), exitSQL},
+		{partialSuccessError(sqlErr, 2), exitPartialSuccess},
+		{errors.Wrap(&cliError{exitCode: 130, cause: errors.New("signal")}, "shutdown"), 130},
+	} {
+		if code := exitCode(tc.err); code != tc.expected {
+			t.Errorf("%d: expected exit code %d for %q, got %d", i, tc.expected, tc.err, code)
+		}
+	}
+}
+
+func TestReportError(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	sqlErr := &pq.Error{Code: "42P01", Message: `table "foo" does not exist`}
+	for i, tc := range []struct {
+		format   errorFormat
+		err      error
+		code     int
+		expected string
+	}{
+		{errorFormatText, sqlErr, exitSQL, `Failed running "sql"` + "\n"},
+		{errorFormatJSON, errors.New("boom"), exitError,
+			`{"command":"sql","exit_code":1,"class":"error","message":"boom"}` + "\n"},
+		{errorFormatJSON, partialSuccessError(sqlErr, 1), exitPartialSuccess,
+			`{"command":"sql","exit_code":5,"class":"partial_success",` +
+				`"message":"pq: table \"foo\" does not exist","sqlstate":"42P01"}` + "\n"},
+		{errorFormatJSON, errors.New("received signal"), 130,
+			`{"command":"sql","exit_code":130,"class":"signal","message":"received signal"}` + "\n"},
+	} {
+		var buf bytes.Buffer
+		reportError(&buf, tc.format, "sql", tc.err, tc.code)
+		if buf.String() != tc.expected {
+			t.Errorf("%d: expected %q, got %q", i, tc.expected, buf.String())
+		}
+	}
+}
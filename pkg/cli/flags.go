@@ -66,6 +66,7 @@ var serverSSLCertsDir string
 // InitCLIDefaults is used for testing.
 func InitCLIDefaults() {
 	cliCtx.tableDisplayFormat = tableDisplayTSV
+	cliCtx.errorFormat = errorFormatText
 	dumpCtx.dumpMode = dumpBoth
 }
 
@@ -181,12 +182,14 @@ func init() {
 
 	// Every command but start will inherit the following setting.
 	cockroachCmd.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error {
+		setErrorOutput()
 		extraClientFlagInit()
 		return setDefaultStderrVerbosity(cmd, log.Severity_WARNING)
 	}
 
 	// The following only runs for `start`.
 	startCmd.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error {
+		setErrorOutput()
 		extraServerFlagInit()
 		return setDefaultStderrVerbosity(cmd, log.Severity_INFO)
 	}
@@ -223,6 +226,12 @@ func init() {
 	// special severity value DEFAULT instead.
 	pf.Lookup(logflags.LogToStderrName).NoOptDefVal = log.Severity_DEFAULT.String()
 
+	// Every command reports the error ending it in the format selected by
+	// --error-format.
+	for _, cmd := range cockroachCmd.Commands() {
+		varFlag(cmd.PersistentFlags(), &cliCtx.errorFormat, cliflags.ErrorFormat)
+	}
+
 	// Security flags.
 
 	{
@@ -254,6 +263,8 @@ func init() {
 
 		stringFlag(f, &serverCfg.PIDFile, cliflags.PIDFile, "")
 
+		boolFlag(f, &serverCfg.StrictPreflight, cliflags.StrictPreflight, false)
+
 		// Use a separate variable to store the value of ServerInsecure.
 		// We share the default with the ClientInsecure flag.
 		boolFlag(f, &serverInsecure, cliflags.ServerInsecure, baseCfg.Insecure)
@@ -305,6 +316,7 @@ func init() {
 
 	clientCmds := []*cobra.Command{
 		debugGossipValuesCmd,
+		debugTraceOnDemandCmd,
 		debugZipCmd,
 		dumpCmd,
 		genHAProxyCmd,
@@ -369,6 +381,8 @@ func init() {
 	// Max results flag for range list.
 	int64Flag(lsRangesCmd.Flags(), &maxResults, cliflags.MaxResults, 1000)
 
+	durationFlag(downtimeNodeCmd.Flags(), &downtimeSince, cliflags.DowntimeSince, 0)
+
 	// Debug commands.
 	{
 		f := debugKeysCmd.Flags()
@@ -386,6 +400,22 @@ func init() {
 		stringFlag(f, &debugCtx.inputFile, cliflags.GossipInputFile, "")
 		boolFlag(f, &debugCtx.printSystemConfig, cliflags.PrintSystemConfig, false)
 	}
+
+	for _, cmd := range []*cobra.Command{debugDecodeProtoCmd, debugDecodeKVCmd} {
+		stringFlag(cmd.Flags(), &debugCtx.encoding, cliflags.DecodeEncoding, "")
+	}
+	{
+		f := debugMergeLogsCmd.Flags()
+		stringFlag(f, &debugCtx.logsFrom, cliflags.MergeLogsFrom, "")
+		stringFlag(f, &debugCtx.logsTo, cliflags.MergeLogsTo, "")
+		stringFlag(f, &debugCtx.logsFilter, cliflags.MergeLogsFilter, "")
+	}
+	{
+		f := debugTraceOnDemandCmd.Flags()
+		stringFlag(f, &serverHTTPPort, cliflags.ServerHTTPPort, base.DefaultHTTPPort)
+		durationFlag(f, &debugCtx.traceDuration, cliflags.TraceDuration, time.Minute)
+		boolFlag(f, &debugCtx.traceStop, cliflags.TraceStop, false)
+	}
 }
 
 func extraServerFlagInit() {
@@ -407,7 +437,7 @@ func extraClientFlagInit() {
 	serverCfg.Addr = net.JoinHostPort(clientConnHost, clientConnPort)
 	serverCfg.AdvertiseAddr = serverCfg.Addr
 	if serverHTTPHost == "" {
-		serverHTTPHost = serverConnHost
+		serverHTTPHost = clientConnHost
 	}
 	serverCfg.HTTPAddr = net.JoinHostPort(serverHTTPHost, serverHTTPPort)
 }
@@ -58,7 +58,7 @@ eexpect "syntax error"
 eexpect "current transaction is aborted"
 eexpect ":/# "
 send "echo \$?\r"
-eexpect "1\r\n:/# "
+eexpect "5\r\n:/# "
 
 send "(echo '\\unset errexit'; echo '\\set check_syntax'; echo 'begin;'; echo 'select 1+;'; echo 'select 1;'; echo 'commit;') | $argv sql\r"
 eexpect "syntax error"
@@ -13,7 +13,23 @@ send "(echo 'select foo;'; echo 'select 1;') | $argv sql\r"
 eexpect "pq: column name \"foo\" not found\r\nError: pq: column name"
 eexpect ":/# "
 send "echo \$?\r"
-eexpect "1\r\n:/# "
+eexpect "4\r\n:/# "
+end_test
+
+start_test "Check that an error after some statements were executed is reported as a partial success."
+send "(echo 'select 1;'; echo 'select foo;') | $argv sql\r"
+eexpect "1 row"
+eexpect "pq: column name \"foo\" not found"
+eexpect ":/# "
+send "echo \$?\r"
+eexpect "5\r\n:/# "
+end_test
+
+start_test "Check that the error can be reported as JSON."
+send "$argv sql -e 'select * from system.foo' --error-format=json\r"
+eexpect "{\"command\":\"sql\",\"exit_code\":4,\"class\":\"sql\""
+eexpect "\"sqlstate\":\"42P01\""
+eexpect ":/# "
 end_test
 
 start_test "Check that a user can request to continue upon failures."
@@ -41,7 +57,7 @@ send "select foo;\r"
 eexpect "Error: pq: column name"
 eexpect ":/# "
 send "echo \$?\r"
-eexpect "1\r\n:/# "
+eexpect "4\r\n:/# "
 end_test
 
 send "exit 0\r"
@@ -25,9 +25,11 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
 	"github.com/cockroachdb/cockroach/pkg/server/status"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 )
 
 const (
@@ -185,10 +187,86 @@ func nodeStatusesToRows(statuses []status.NodeStatus) [][]string {
 	return rows
 }
 
+var downtimeColumnHeaders = []string{
+	"id",
+	"live",
+	"suspect",
+	"dead",
+	"outages",
+	"availability",
+}
+
+var downtimeSince time.Duration
+
+var downtimeNodeCmd = &cobra.Command{
+	Use:   "downtime <optional node ID>",
+	Short: "shows the uptime and downtime of a node or all nodes",
+	Long: `
+	Shows how long a node, or every node if no node ID is specified, was live, suspect
+	and dead over the reporting period, as recorded by the cluster. The availability
+	is the fraction of the recorded time during which the node was live.
+	`,
+	RunE: MaybeDecorateGRPCError(runDowntimeNode),
+}
+
+func runDowntimeNode(cmd *cobra.Command, args []string) error {
+	var req serverpb.DowntimeRequest
+	switch len(args) {
+	case 0:
+	case 1:
+		nodeID, err := strconv.ParseInt(args[0], 10, 32)
+		if err != nil {
+			return errors.Wrapf(err, "invalid node ID %q", args[0])
+		}
+		req.NodeID = roachpb.NodeID(nodeID)
+	default:
+		return usageAndError(cmd)
+	}
+
+	c, stopper, err := getStatusClient()
+	if err != nil {
+		return err
+	}
+	ctx := stopperContext(stopper)
+	defer stopper.Stop(ctx)
+
+	if downtimeSince > 0 {
+		req.StartNanos = timeutil.Now().Add(-downtimeSince).UnixNano()
+	}
+	resp, err := c.Downtime(ctx, &req)
+	if err != nil {
+		return err
+	}
+	return printQueryOutput(os.Stdout, downtimeColumnHeaders, newRowSliceIter(downtimesToRows(resp.Nodes)), "",
+		cliCtx.tableDisplayFormat)
+}
+
+// downtimesToRows converts NodeDowntimes to SQL-like result rows, so that we
+// can pretty-print them.
+func downtimesToRows(downtimes []serverpb.NodeDowntime) [][]string {
+	var rows [][]string
+	for _, d := range downtimes {
+		availability := "NULL"
+		if total := d.LiveNanos + d.SuspectNanos + d.DeadNanos; total > 0 {
+			availability = fmt.Sprintf("%.3f%%", 100*float64(d.LiveNanos)/float64(total))
+		}
+		rows = append(rows, []string{
+			strconv.FormatInt(int64(d.NodeID), 10),
+			time.Duration(d.LiveNanos).String(),
+			time.Duration(d.SuspectNanos).String(),
+			time.Duration(d.DeadNanos).String(),
+			strconv.FormatInt(int64(d.Outages), 10),
+			availability,
+		})
+	}
+	return rows
+}
+
 // Sub-commands for node command.
 var nodeCmds = []*cobra.Command{
 	lsNodesCmd,
 	statusNodeCmd,
+	downtimeNodeCmd,
 }
 
 var nodeCmd = &cobra.Command{
@@ -122,6 +122,9 @@ type cliState struct {
 	// by Ctrl+D, causes the shell to terminate with an error --
 	// reporting the status of the last valid SQL statement executed.
 	exitErr error
+
+	// numExecuted is the number of statements executed successfully.
+	numExecuted int
 }
 
 // cliStateEnum drives the CLI state machine in runInteractive().
@@ -880,7 +883,9 @@ func (c *cliState) doRunStatement(nextState cliStateEnum) cliStateEnum {
 		if c.errExit {
 			return cliStop
 		}
+		return nextState
 	}
+	c.numExecuted++
 	return nextState
 }
 
@@ -965,21 +970,33 @@ func runInteractive(conn *sqlConn, config *readline.Config) (exitErr error) {
 		}
 	}
 
+	if c.exitErr != nil && !isInteractive {
+		return partialSuccessError(c.exitErr, c.numExecuted)
+	}
 	return c.exitErr
 }
 
-// runOneStatement executes one statement and terminates
-// on error.
+// runStatements executes the statements and terminates on error.
 func runStatements(conn *sqlConn, stmts []string, displayFormat tableDisplayFormat) error {
-	for _, stmt := range stmts {
+	for i, stmt := range stmts {
 		if err := runQueryAndFormatResults(conn, os.Stdout, makeQuery(stmt),
 			displayFormat); err != nil {
-			return err
+			return partialSuccessError(err, i)
 		}
 	}
 	return nil
 }
 
+// partialSuccessError returns the error of a statement executed after
+// numExecuted others, which tells scripts whether some of the statements were
+// executed through the exit code of the process.
+func partialSuccessError(err error, numExecuted int) error {
+	if numExecuted == 0 {
+		return err
+	}
+	return &cliError{exitCode: exitPartialSuccess, cause: err}
+}
+
 func runTerm(cmd *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		return usageAndError(cmd)
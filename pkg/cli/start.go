@@ -275,11 +275,6 @@ func initBlockProfile() {
 	runtime.SetBlockProfileRate(int(d))
 }
 
-// ErrorCode is the value to be used by main() as exit code in case of
-// error. For most errors 1 is appropriate, but a signal termination
-// can change this.
-var ErrorCode = 1
-
 // runStart starts the cockroach node using --store as the list of
 // storage devices ("stores") on this machine and --join as the list
 // of other active nodes used to join this node to the cockroach
@@ -338,6 +333,10 @@ func runStart(cmd *cobra.Command, args []string) error {
 				return errors.Wrap(err, "failed to initialize node")
 			}
 
+			if err := serverCfg.RunPreflightChecks(startCtx); err != nil {
+				return err
+			}
+
 			log.Info(startCtx, "starting cockroach node")
 			if envVarsUsed := envutil.GetEnvVarsUsed(); len(envVarsUsed) > 0 {
 				log.Infof(startCtx, "using local environment variables: %s", strings.Join(envVarsUsed, ", "))
@@ -498,14 +497,16 @@ func runStart(cmd *cobra.Command, args []string) error {
 
 	select {
 	case sig := <-signalCh:
-		returnErr = fmt.Errorf("received signal '%s' during shutdown, initiating hard shutdown", sig)
 		// This new signal is not welcome, as it interferes with the graceful
 		// shutdown process. On Unix, a signal that was not handled gracefully by
 		// the application should be visible to other processes as an exit code
 		// encoded as 128+signal number.
 		//
 		// Also, on Unix, os.Signal is syscall.Signal and it's convertible to int.
-		ErrorCode = 128 + int(sig.(syscall.Signal))
+		returnErr = &cliError{
+			exitCode: 128 + int(sig.(syscall.Signal)),
+			cause:    fmt.Errorf("received signal '%s' during shutdown, initiating hard shutdown", sig),
+		}
 		// NB: we do not return here to go through log.Flush below.
 	case <-time.After(time.Minute):
 		returnErr = errors.New("time limit reached, initiating hard shutdown")
@@ -583,6 +584,14 @@ func setupAndInitializeLoggingAndProfiling(startCtx context.Context) (*stop.Stop
 			" and --log-dir not specified, you may want to specify --log-dir to disambiguate.")
 	}
 
+	// The logging config file, if any, overrides the logging flags. It is
+	// reloaded on SIGHUP, see below.
+	if log.ConfigFile() != "" {
+		if err := log.ReloadConfig(startCtx); err != nil {
+			return nil, err
+		}
+	}
+
 	if serverInsecure {
 		// Use a non-annotated context here since the annotation just looks funny,
 		// particularly to new users (made worse by it always printing as [n?]).
@@ -604,6 +613,7 @@ func setupAndInitializeLoggingAndProfiling(startCtx context.Context) (*stop.Stop
 	info := build.GetInfo()
 	log.Infof(startCtx, info.Short())
 
+	serverCfg.HeapProfileDirName = filepath.Join(outputDirectory, "heap_profiler")
 	initMemProfile(startCtx, outputDirectory)
 	initCPUProfile(startCtx, outputDirectory)
 	initBlockProfile()
@@ -614,9 +624,35 @@ func setupAndInitializeLoggingAndProfiling(startCtx context.Context) (*stop.Stop
 	stopper := initBacktrace(outputDirectory)
 	log.Event(startCtx, "initialized profiles")
 
+	if log.ConfigFile() != "" {
+		reloadLogConfigOnSignal(stopper)
+	}
+
 	return stopper, nil
 }
 
+// reloadLogConfigOnSignal reloads the logging config file whenever the process
+// receives SIGHUP. An invalid config is reported and otherwise ignored, so the
+// previous settings remain in effect.
+func reloadLogConfigOnSignal(stopper *stop.Stopper) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	stopper.RunWorker(context.Background(), func(ctx context.Context) {
+		for {
+			select {
+			case <-stopper.ShouldStop():
+				signal.Stop(sigs)
+				return
+			case sig := <-sigs:
+				log.Infof(ctx, "received signal %q, reloading the logging config", sig)
+				if err := log.ReloadConfig(ctx); err != nil {
+					log.Warningf(ctx, "could not reload the logging config: %v", err)
+				}
+			}
+		}
+	})
+}
+
 func addrWithDefaultHost(addr string) (string, error) {
 	host, port, err := net.SplitHostPort(baseCfg.Addr)
 	if err != nil {
@@ -170,9 +170,44 @@ func (z ZoneConfig) Validate() error {
 		return fmt.Errorf("RangeMinBytes %d is greater than or equal to RangeMaxBytes %d",
 			z.RangeMinBytes, z.RangeMaxBytes)
 	}
+	seenIndexes := make(map[uint32]struct{}, len(z.IndexGC))
+	for _, p := range z.IndexGC {
+		if p.IndexID == 0 {
+			return fmt.Errorf("index GC policy is missing an index ID")
+		}
+		if _, ok := seenIndexes[p.IndexID]; ok {
+			return fmt.Errorf("multiple GC policies for index %d", p.IndexID)
+		}
+		seenIndexes[p.IndexID] = struct{}{}
+	}
 	return nil
 }
 
+// GCPolicyForIndex returns the GC policy that applies to the index with the
+// given ID: the index's override, if there is one, or the zone's policy.
+func (z ZoneConfig) GCPolicyForIndex(indexID uint32) GCPolicy {
+	for _, p := range z.IndexGC {
+		if p.IndexID == indexID {
+			return p.GC
+		}
+	}
+	return z.GC
+}
+
+// MinGCTTLSeconds returns the shortest positive TTL among the GC policies of
+// the zone and of its index overrides, so that indexes with a shorter TTL than
+// their table are collected in time. Returns the zone's TTL if none of the TTLs
+// are positive.
+func (z ZoneConfig) MinGCTTLSeconds() int32 {
+	ttl := z.GC.TTLSeconds
+	for _, p := range z.IndexGC {
+		if p.GC.TTLSeconds > 0 && (ttl <= 0 || p.GC.TTLSeconds < ttl) {
+			ttl = p.GC.TTLSeconds
+		}
+	}
+	return ttl
+}
+
 // ObjectIDForKey returns the object ID (table or database) for 'key',
 // or (_, false) if not within the structured key space.
 func ObjectIDForKey(key roachpb.RKey) (uint32, bool) {
@@ -243,6 +278,52 @@ func (s SystemConfig) GetIndex(key roachpb.Key) (int, bool) {
 	return index, true
 }
 
+// GetSpanValues returns the key/values of the system config in the given
+// span.
+func (s SystemConfig) GetSpanValues(span roachpb.Span) []roachpb.KeyValue {
+	start := sort.Search(len(s.Values), func(i int) bool {
+		return bytes.Compare(s.Values[i].Key, span.Key) >= 0
+	})
+	end := sort.Search(len(s.Values), func(i int) bool {
+		return bytes.Compare(s.Values[i].Key, span.EndKey) >= 0
+	})
+	return s.Values[start:end]
+}
+
+// DiffValues returns the key/values which changed between prev and next,
+// which must be sorted in key order: those of next whose key isn't in prev or
+// whose value differs from the one in prev, and those of prev whose key isn't
+// in next, with an empty value to signify their deletion. The result is sorted
+// in key order.
+func DiffValues(prev, next []roachpb.KeyValue) []roachpb.KeyValue {
+	var diff []roachpb.KeyValue
+	for len(prev) > 0 || len(next) > 0 {
+		var c int
+		switch {
+		case len(prev) == 0:
+			c = 1
+		case len(next) == 0:
+			c = -1
+		default:
+			c = prev[0].Key.Compare(next[0].Key)
+		}
+		switch {
+		case c < 0:
+			diff = append(diff, roachpb.KeyValue{Key: prev[0].Key})
+			prev = prev[1:]
+		case c > 0:
+			diff = append(diff, next[0])
+			next = next[1:]
+		default:
+			if !bytes.Equal(prev[0].Value.RawBytes, next[0].Value.RawBytes) {
+				diff = append(diff, next[0])
+			}
+			prev, next = prev[1:], next[1:]
+		}
+	}
+	return diff
+}
+
 func decodeDescMetadataID(key roachpb.Key) (uint64, error) {
 	// Extract object ID from key.
 	// TODO(marc): move sql/keys.go to keys (or similar) and use a DecodeDescMetadataKey.
@@ -346,6 +427,12 @@ func (s SystemConfig) GetLargestObjectID(maxID uint32) (uint32, error) {
 // GetZoneConfigForKey looks up the zone config for the range containing 'key'.
 // It is the caller's responsibility to ensure that the range does not need to be split.
 func (s SystemConfig) GetZoneConfigForKey(key roachpb.RKey) (ZoneConfig, error) {
+	return s.getZoneConfigForID(zoneConfigObjectIDForKey(key))
+}
+
+// zoneConfigObjectIDForKey returns the ID of the object whose zone config
+// applies to key.
+func zoneConfigObjectIDForKey(key roachpb.RKey) uint32 {
 	objectID, ok := ObjectIDForKey(key)
 	if !ok {
 		// Not in the structured data namespace.
@@ -366,8 +453,7 @@ func (s SystemConfig) GetZoneConfigForKey(key roachpb.RKey) (ZoneConfig, error)
 	} else if bytes.HasPrefix(key, keys.SystemPrefix) {
 		objectID = keys.SystemRangesID
 	}
-
-	return s.getZoneConfigForID(objectID)
+	return objectID
 }
 
 // getZoneConfigForID looks up the zone config for the object (table or database)
@@ -464,6 +550,10 @@ func (s SystemConfig) ComputeSplitKey(startKey, endKey roachpb.RKey) roachpb.RKe
 	findSplitKey := func(startID, endID uint32) roachpb.RKey {
 		// endID could be smaller than startID if we don't have user tables.
 		for id := startID; id <= endID; id++ {
+			if isPseudoTableID(id) {
+				// There is no table with this ID, and thus no need for a range.
+				continue
+			}
 			key := roachpb.RKey(keys.MakeRowSentinelKey(keys.MakeTablePrefix(id)))
 			// Skip if this ID matches the provided startKey.
 			if !startKey.Less(key) {
@@ -501,6 +591,16 @@ func (s SystemConfig) ComputeSplitKey(startKey, endKey roachpb.RKey) roachpb.RKe
 	return findSplitKey(startID, endID)
 }
 
+// isPseudoTableID returns whether the reserved ID is used to refer to a part
+// of the system ranges, e.g. in zone configs, rather than to a table.
+func isPseudoTableID(id uint32) bool {
+	switch id {
+	case keys.MetaRangesID, keys.SystemRangesID, keys.TimeseriesRangesID:
+		return true
+	}
+	return false
+}
+
 // NeedsSplit returns whether the range [startKey, endKey) needs a split due
 // to zone configs.
 func (s SystemConfig) NeedsSplit(startKey, endKey roachpb.RKey) bool {
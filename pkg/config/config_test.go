@@ -132,6 +132,74 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestGetSpanValues(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	cfg := config.SystemConfig{Values: []roachpb.KeyValue{
+		plainKV("a", "vala"),
+		plainKV("c", "valc"),
+		plainKV("d", "vald"),
+	}}
+
+	testCases := []struct {
+		start, end string
+		expected   []string
+	}{
+		{"", "a", nil},
+		{"a", "b", []string{"a"}},
+		{"a", "d", []string{"a", "c"}},
+		{"b", "e", []string{"c", "d"}},
+		{"d\x00", "e", nil},
+	}
+	for tcNum, tc := range testCases {
+		var keys []string
+		span := roachpb.Span{Key: roachpb.Key(tc.start), EndKey: roachpb.Key(tc.end)}
+		for _, kv := range cfg.GetSpanValues(span) {
+			keys = append(keys, string(kv.Key))
+		}
+		if !reflect.DeepEqual(keys, tc.expected) {
+			t.Errorf("#%d: expected %q, got %q", tcNum, tc.expected, keys)
+		}
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	prev := []roachpb.KeyValue{
+		plainKV("a", "vala"),
+		plainKV("b", "valb"),
+		plainKV("d", "vald"),
+	}
+	next := []roachpb.KeyValue{
+		plainKV("b", "valb2"),
+		plainKV("c", "valc"),
+		plainKV("d", "vald"),
+		plainKV("e", "vale"),
+	}
+
+	testCases := []struct {
+		prev, next []roachpb.KeyValue
+		expected   []roachpb.KeyValue
+	}{
+		{nil, nil, nil},
+		{prev, prev, nil},
+		{nil, next, next},
+		{prev, nil, []roachpb.KeyValue{{Key: roachpb.Key("a")}, {Key: roachpb.Key("b")}, {Key: roachpb.Key("d")}}},
+		{prev, next, []roachpb.KeyValue{
+			{Key: roachpb.Key("a")},
+			plainKV("b", "valb2"),
+			plainKV("c", "valc"),
+			plainKV("e", "vale"),
+		}},
+	}
+	for tcNum, tc := range testCases {
+		if diff := config.DiffValues(tc.prev, tc.next); !reflect.DeepEqual(diff, tc.expected) {
+			t.Errorf("#%d: expected %+v, got %+v", tcNum, tc.expected, diff)
+		}
+	}
+}
+
 func TestGetLargestID(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	testCases := []struct {
@@ -300,6 +368,9 @@ func TestComputeSplitKeyTableIDs(t *testing.T) {
 	allSql := append(schema.GetInitialValues(),
 		descriptor(start), descriptor(start+1), descriptor(start+5))
 	sort.Sort(roachpb.KeyValueByKey(allSql))
+	// Real system tables plus a system table past the IDs of the system ranges.
+	laterSql := append(schema.GetInitialValues(), descriptor(keys.ScheduledJobsTableID))
+	sort.Sort(roachpb.KeyValueByKey(laterSql))
 
 	testCases := []struct {
 		values     []roachpb.KeyValue
@@ -325,6 +396,10 @@ func TestComputeSplitKeyTableIDs(t *testing.T) {
 		{baseSql, testutils.MakeKey(keys.MakeTablePrefix(reservedStart), roachpb.RKey("foo")),
 			testutils.MakeKey(keys.MakeTablePrefix(start+10), roachpb.RKey("foo")), reservedStart + 1},
 
+		// The IDs of the system ranges don't get ranges of their own.
+		{laterSql, keys.MakeTablePrefix(keys.JobsTableID), roachpb.RKeyMax, keys.ScheduledJobsTableID},
+		{laterSql, keys.MakeTablePrefix(keys.ScheduledJobsTableID), roachpb.RKeyMax, -1},
+
 		// Reserved + User descriptors.
 		{allSql, keys.MakeTablePrefix(start - 1), roachpb.RKeyMax, start},
 		{allSql, keys.MakeTablePrefix(start), roachpb.RKeyMax, start + 1},
@@ -463,6 +538,25 @@ func TestZoneConfigValidate(t *testing.T) {
 			},
 			"is greater than or equal to RangeMaxBytes",
 		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   1,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				IndexGC:       []config.IndexGCPolicy{{GC: config.GCPolicy{TTLSeconds: 1}}},
+			},
+			"index GC policy is missing an index ID",
+		},
+		{
+			config.ZoneConfig{
+				NumReplicas:   1,
+				RangeMaxBytes: config.DefaultZoneConfig().RangeMaxBytes,
+				IndexGC: []config.IndexGCPolicy{
+					{IndexID: 2, GC: config.GCPolicy{TTLSeconds: 1}},
+					{IndexID: 2, GC: config.GCPolicy{TTLSeconds: 2}},
+				},
+			},
+			"multiple GC policies for index 2",
+		},
 	}
 	for i, c := range testCases {
 		err := c.cfg.Validate()
@@ -528,3 +622,32 @@ constraints: [foo, +duck=foo, -duck=foo]
 		t.Errorf("yaml.Unmarshal(%q) = %+v; not %+v", body, unmarshaled, original)
 	}
 }
+
+func TestZoneConfigGCPolicyForIndex(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const body = `gc:
+  ttlseconds: 100
+index_gc:
+- index_id: 2
+  gc:
+    ttlseconds: 10
+`
+	var zone config.ZoneConfig
+	if err := yaml.Unmarshal([]byte(body), &zone); err != nil {
+		t.Fatal(err)
+	}
+	for indexID, expected := range map[uint32]int32{1: 100, 2: 10, 3: 100} {
+		if ttl := zone.GCPolicyForIndex(indexID).TTLSeconds; ttl != expected {
+			t.Errorf("index %d: expected TTL %d, got %d", indexID, expected, ttl)
+		}
+	}
+
+	if ttl := zone.MinGCTTLSeconds(); ttl != 10 {
+		t.Errorf("expected minimum TTL of 10s, got %ds", ttl)
+	}
+	zone.IndexGC = append(zone.IndexGC, config.IndexGCPolicy{IndexID: 3})
+	if ttl := zone.MinGCTTLSeconds(); ttl != 10 {
+		t.Errorf("expected a TTL of 0 to be ignored, got %ds", ttl)
+	}
+}
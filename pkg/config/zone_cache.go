@@ -0,0 +1,89 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+)
+
+// ZoneConfigCacheSpan is the span of the system config on which the zone
+// configs depend: that of the descriptors and of the zones.
+var ZoneConfigCacheSpan = roachpb.Span{
+	Key:    roachpb.Key(keys.MakeTablePrefix(keys.DescriptorTableID)),
+	EndKey: roachpb.Key(keys.MakeTablePrefix(keys.ZonesTableID + 1)),
+}
+
+// ZoneConfigCache caches the zone configs of the objects of a system config.
+// It is updated with the changes to ZoneConfigCacheSpan since the previous
+// system config, so that only the zone configs affected by an update are
+// looked up again. It isn't safe for concurrent use.
+type ZoneConfigCache struct {
+	cfg   SystemConfig
+	zones map[uint32]ZoneConfig
+}
+
+// NewZoneConfigCache returns an empty ZoneConfigCache.
+func NewZoneConfigCache() *ZoneConfigCache {
+	return &ZoneConfigCache{zones: make(map[uint32]ZoneConfig)}
+}
+
+// Update replaces the system config of the cache with cfg. changes are the
+// key/values of ZoneConfigCacheSpan which changed since the previous system
+// config, as returned by DiffValues.
+func (c *ZoneConfigCache) Update(cfg SystemConfig, changes []roachpb.KeyValue) {
+	c.cfg = cfg
+	for _, kv := range changes {
+		_, tableID, err := keys.DecodeTablePrefix(kv.Key)
+		if err != nil {
+			continue
+		}
+		switch tableID {
+		case keys.ZonesTableID:
+			// A zone config applies to the objects of its database or table
+			// which don't have their own, and the default one to all of them.
+			// Zone configs change rarely enough to start over.
+			c.zones = make(map[uint32]ZoneConfig)
+			return
+		case keys.DescriptorTableID:
+			// The zone config of an object without its own depends on its
+			// descriptor, e.g. on the database of a table.
+			if id, err := decodeDescMetadataID(kv.Key); err == nil {
+				delete(c.zones, uint32(id))
+			}
+		}
+	}
+}
+
+// GetZoneConfigForKey looks up the zone config for the range containing
+// 'key', like SystemConfig.GetZoneConfigForKey.
+func (c *ZoneConfigCache) GetZoneConfigForKey(key roachpb.RKey) (ZoneConfig, error) {
+	id := zoneConfigObjectIDForKey(key)
+	if zone, ok := c.zones[id]; ok {
+		return zone, nil
+	}
+	zone, err := c.cfg.getZoneConfigForID(id)
+	if err != nil {
+		return ZoneConfig{}, err
+	}
+	testingLock.Lock()
+	// The zone configs of the testing hook change without the system config.
+	cacheable := !testingHasHook
+	testingLock.Unlock()
+	if cacheable {
+		c.zones[id] = zone
+	}
+	return zone, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestZoneConfigCache(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var lookups int
+	testingLock.Lock()
+	prevHook := ZoneConfigHook
+	ZoneConfigHook = func(_ SystemConfig, id uint32) (ZoneConfig, bool, error) {
+		lookups++
+		return ZoneConfig{NumReplicas: int32(id)}, true, nil
+	}
+	testingLock.Unlock()
+	defer func() {
+		testingLock.Lock()
+		ZoneConfigHook = prevHook
+		testingLock.Unlock()
+	}()
+
+	descKey := func(id uint64) roachpb.Key {
+		k := keys.MakeTablePrefix(keys.DescriptorTableID)
+		k = encoding.EncodeUvarintAscending(k, 1 /* primary index */)
+		return encoding.EncodeUvarintAscending(k, id)
+	}
+	zoneKey := roachpb.Key(keys.MakeTablePrefix(keys.ZonesTableID))
+
+	c := NewZoneConfigCache()
+	c.Update(SystemConfig{}, nil)
+	check := func(id uint32, expectedLookups int) {
+		t.Helper()
+		zone, err := c.GetZoneConfigForKey(keys.MakeTablePrefix(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if zone.NumReplicas != int32(id) {
+			t.Fatalf("expected the zone config of %d, got %+v", id, zone)
+		}
+		if lookups != expectedLookups {
+			t.Fatalf("expected %d lookups, got %d", expectedLookups, lookups)
+		}
+	}
+
+	check(100, 1)
+	check(100, 1)
+	check(101, 2)
+
+	// A change to a descriptor only invalidates the zone config of its object.
+	c.Update(SystemConfig{}, []roachpb.KeyValue{{Key: descKey(101)}})
+	check(100, 2)
+	check(101, 3)
+
+	// A change to a zone config invalidates all of them.
+	c.Update(SystemConfig{}, []roachpb.KeyValue{{Key: zoneKey}})
+	check(100, 4)
+	check(101, 5)
+}
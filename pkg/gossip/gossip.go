@@ -84,6 +84,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 )
 
 const (
@@ -234,7 +235,8 @@ func New(
 	stopper *stop.Stopper,
 	registry *metric.Registry,
 ) *Gossip {
-	ambient.SetEventLog("gossip", "gossip")
+	ambient.SetSubsystem(tracing.SubsystemGossip)
+	finishEventLog := ambient.StartEventLog("gossip")
 	g := &Gossip{
 		server:            newServer(ambient, nodeID, stopper, registry),
 		Connected:         make(chan struct{}),
@@ -251,7 +253,7 @@ func New(
 		resolverAddrs:     map[util.UnresolvedAddr]resolver.Resolver{},
 		bootstrapAddrs:    map[util.UnresolvedAddr]roachpb.NodeID{},
 	}
-	stopper.AddCloser(stop.CloserFn(g.server.AmbientContext.FinishEventLog))
+	stopper.AddCloser(stop.CloserFn(finishEventLog))
 
 	registry.AddMetric(g.outgoing.gauge)
 	g.clientsMu.breakers = map[string]*circuit.Breaker{}
@@ -880,6 +882,43 @@ func (g *Gossip) RegisterSystemConfigChannel() <-chan struct{} {
 	return c
 }
 
+// A SystemConfigWatcher delivers the changes to a span of the system config,
+// so that its users don't have to process the whole config on every update.
+type SystemConfigWatcher struct {
+	g    *Gossip
+	span roachpb.Span
+	// C is notified after registration and whenever a new system config is
+	// unmarshalled, like the channels returned by RegisterSystemConfigChannel.
+	C <-chan struct{}
+	// prev are the key/values of the span as of the previous call to Changes.
+	prev []roachpb.KeyValue
+}
+
+// WatchSystemConfig returns a watcher for the given span of the system config.
+func (g *Gossip) WatchSystemConfig(span roachpb.Span) *SystemConfigWatcher {
+	return &SystemConfigWatcher{g: g, span: span, C: g.RegisterSystemConfigChannel()}
+}
+
+// Changes returns the current system config, along with the key/values of the
+// watched span which changed since the previous call (see config.DiffValues).
+// The first call returns all the key/values of the span. Changes is meant to
+// be called after receiving from C, and isn't safe for concurrent use.
+func (w *SystemConfigWatcher) Changes() (config.SystemConfig, []roachpb.KeyValue) {
+	cfg, _ := w.g.GetSystemConfig()
+	// The system config is replaced, not modified, by updates, so its values
+	// can be retained.
+	next := cfg.GetSpanValues(w.span)
+	changes := config.DiffValues(w.prev, next)
+	w.prev = next
+	return cfg, changes
+}
+
+// Reset makes the next call to Changes return all the key/values of the
+// watched span, as the first call does.
+func (w *SystemConfigWatcher) Reset() {
+	w.prev = nil
+}
+
 // updateSystemConfig is the raw gossip info callback.
 // Unmarshal the system config, and if successfully, update out
 // copy and run the callbacks.
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 
+	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/gossip/resolver"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/rpc"
@@ -61,6 +63,56 @@ func TestGossipInfoStore(t *testing.T) {
 	}
 }
 
+// TestGossipSystemConfigWatcher verifies that a SystemConfigWatcher delivers
+// the changes to its span of the system config.
+func TestGossipSystemConfigWatcher(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	rpcContext := newInsecureRPCContext(stopper)
+	g := NewTest(1, rpcContext, rpc.NewServer(rpcContext), stopper, metric.NewRegistry())
+	w := g.WatchSystemConfig(roachpb.Span{Key: roachpb.Key("b"), EndKey: roachpb.Key("d")})
+
+	kv := func(k, v string) roachpb.KeyValue {
+		return roachpb.KeyValue{Key: roachpb.Key(k), Value: roachpb.MakeValueFromString(v)}
+	}
+	testCases := []struct {
+		values, changes []roachpb.KeyValue
+	}{
+		{[]roachpb.KeyValue{kv("a", "1"), kv("b", "1"), kv("c", "1")},
+			[]roachpb.KeyValue{kv("b", "1"), kv("c", "1")}},
+		{[]roachpb.KeyValue{kv("a", "2"), kv("b", "1"), kv("c", "2")},
+			[]roachpb.KeyValue{kv("c", "2")}},
+		{[]roachpb.KeyValue{kv("b", "1"), kv("d", "1")},
+			[]roachpb.KeyValue{{Key: roachpb.Key("c")}}},
+		{[]roachpb.KeyValue{kv("b", "1"), kv("d", "2")}, nil},
+	}
+	for i, tc := range testCases {
+		if err := g.AddInfoProto(KeySystemConfig, &config.SystemConfig{Values: tc.values}, 0); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-w.C:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%d: timed out waiting for the system config", i)
+		}
+		cfg, changes := w.Changes()
+		if len(cfg.Values) != len(tc.values) {
+			t.Errorf("%d: expected %d values, got %+v", i, len(tc.values), cfg.Values)
+		}
+		if !reflect.DeepEqual(changes, tc.changes) {
+			t.Errorf("%d: expected changes %+v, got %+v", i, tc.changes, changes)
+		}
+	}
+
+	// After a reset, all the key/values of the span are delivered again.
+	w.Reset()
+	expected := []roachpb.KeyValue{kv("b", "1")}
+	if _, changes := w.Changes(); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected changes %+v after reset, got %+v", expected, changes)
+	}
+}
+
 // TestGossipOverwriteNode verifies that if a new node is added with the same
 // address as an old node, that old node is removed from the cluster.
 func TestGossipOverwriteNode(t *testing.T) {
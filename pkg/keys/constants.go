@@ -301,4 +301,10 @@ const (
 	MetaRangesID       = 16
 	SystemRangesID     = 17
 	TimeseriesRangesID = 18
+
+	// ScheduledJobsTableID is placed after the reserved range IDs above
+	// because it was added after they were allocated.
+	// NOTE: IDs must be <= MaxReservedDescID.
+	ScheduledJobsTableID = 19
+	LivenessLogTableID   = 20
 )
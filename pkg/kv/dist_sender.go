@@ -149,6 +149,9 @@ type DistSender struct {
 	pendingRPCTimeout time.Duration
 	asyncSenderSem    chan struct{}
 	asyncSenderCount  int32
+	// loadStats attributes the load of a sample of the batches to the table
+	// indexes they address.
+	loadStats loadStats
 }
 
 var _ client.Sender = &DistSender{}
@@ -188,12 +191,14 @@ type DistSenderConfig struct {
 // defaults will be used.
 func NewDistSender(cfg DistSenderConfig, g *gossip.Gossip) *DistSender {
 	ds := &DistSender{
-		clock:   cfg.Clock,
-		gossip:  g,
-		metrics: makeDistSenderMetrics(),
+		clock:     cfg.Clock,
+		gossip:    g,
+		metrics:   makeDistSenderMetrics(),
+		loadStats: makeLoadStats(),
 	}
 
 	ds.AmbientContext = cfg.AmbientCtx
+	ds.AmbientContext.SetSubsystem(tracing.SubsystemKV)
 	if ds.AmbientContext.Tracer == nil {
 		ds.AmbientContext.Tracer = tracing.NewTracer()
 	}
@@ -589,7 +594,7 @@ var errNo1PCTxn = roachpb.NewErrorf("cannot send 1PC txn to multiple ranges")
 // record is created will cause the transaction to abort early.
 func (ds *DistSender) Send(
 	ctx context.Context, ba roachpb.BatchRequest,
-) (*roachpb.BatchResponse, *roachpb.Error) {
+) (br *roachpb.BatchResponse, pErr *roachpb.Error) {
 	ds.metrics.BatchCount.Inc(1)
 
 	tracing.AnnotateTrace()
@@ -598,6 +603,10 @@ func (ds *DistSender) Send(
 		return nil, pErr
 	}
 
+	if sample := ds.loadStats.sample(ba); sample != nil {
+		defer func() { sample.finish(br) }()
+	}
+
 	ctx = ds.AnnotateCtx(ctx)
 	ctx, cleanup := tracing.EnsureContext(ctx, ds.AmbientContext.Tracer, "dist sender")
 	defer cleanup()
@@ -1131,6 +1140,10 @@ func (ds *DistSender) sendToReplicas(
 		etArg.(*roachpb.EndTransactionRequest).Commit {
 		haveCommit = true
 	}
+	// Idempotent batches can be sent again after an RPC ended ambiguously,
+	// since a replay is either indistinguishable from the original or, for
+	// transactional writes, rejected with a retryable error.
+	idempotent := args.IsIdempotent()
 	done := make(chan BatchCall, len(replicas))
 
 	transportFactory := opts.transportFactory
@@ -1174,7 +1187,7 @@ func (ds *DistSender) sendToReplicas(
 		case <-sendNextTimer.C:
 			sendNextTimer.Read = true
 			// On successive RPC timeouts, send to additional replicas if available.
-			if !transport.IsExhausted() {
+			if !transport.IsExhausted() && (!ambiguousResult || haveCommit) {
 				ds.metrics.SendNextTimeoutCount.Inc(1)
 				log.VEventf(ctx, 2, "timeout, trying next peer: %s", transport.NextReplica())
 				pending++
@@ -1200,6 +1213,14 @@ func (ds *DistSender) sendToReplicas(
 				case *roachpb.StoreNotFoundError, *roachpb.NodeUnavailableError:
 					// These errors are likely to be unique to the replica that reported
 					// them, so no action is required before the next retry.
+				case *roachpb.AmbiguousResultError:
+					// The replica could not determine whether the batch was applied
+					// (for example because it was removed or shut down while the
+					// command was in flight). Idempotent batches can safely be
+					// retried on the next replica.
+					if !idempotent {
+						propagateError = true
+					}
 				case *roachpb.NotLeaseHolderError:
 					ds.metrics.NotLeaseHolderErrCount.Inc(1)
 					if lh := tErr.LeaseHolder; lh != nil {
@@ -1255,12 +1276,12 @@ func (ds *DistSender) sendToReplicas(
 
 					// The error received is likely not specific to this
 					// replica, so we should return it instead of trying other
-					// replicas. However, if we're trying to commit a
-					// transaction and there are still other RPCs outstanding
-					// or an ambiguous RPC error was already received, we must
-					// return an ambiguous commit error instead of the
-					// returned error.
-					if haveCommit {
+					// replicas. However, if we're sending a non-idempotent
+					// batch (such as a transaction commit) and there are
+					// still other RPCs outstanding or an ambiguous RPC error
+					// was already received, we must return an ambiguous
+					// result error instead of the returned error.
+					if !idempotent {
 						if pending > 0 || ambiguousResult {
 							log.ErrEventf(ctx, "returning ambiguous result (pending=%d)", pending)
 							return nil, roachpb.NewAmbiguousResultError(
@@ -1300,26 +1321,41 @@ func (ds *DistSender) sendToReplicas(
 				// if a node is down.
 				// See https://github.com/grpc/grpc-go/blob/52f6504dc290bd928a8139ba94e3ab32ed9a6273/call.go#L182
 				// See https://github.com/grpc/grpc-go/blob/52f6504dc290bd928a8139ba94e3ab32ed9a6273/stream.go#L158
-				if haveCommit && grpc.Code(err) != codes.Unavailable {
-					log.ErrEventf(ctx, "txn may have committed despite RPC error: %s", err)
+				//
+				// Other non-idempotent batches, such as non-transactional
+				// writes, are not retried after an ambiguous RPC error: a
+				// replay could fail (e.g. the condition of a ConditionalPut no
+				// longer holds) or apply twice (e.g. an Increment), so the
+				// ambiguity is returned to the caller instead. Idempotent
+				// batches are retried as usual.
+				if !idempotent && grpc.Code(err) != codes.Unavailable {
+					if haveCommit {
+						log.ErrEventf(ctx, "txn may have committed despite RPC error: %s", err)
+					} else {
+						log.ErrEventf(ctx, "batch may have been applied despite RPC error: %s", err)
+					}
 					ambiguousResult = true
 				} else {
 					log.ErrEventf(ctx, "RPC error: %s", err)
 				}
 			}
 
-			// Send to additional replicas if available.
-			if !transport.IsExhausted() {
+			// Send to additional replicas if available and if retrying
+			// cannot turn an ambiguous result into an incorrect one.
+			if !transport.IsExhausted() && (!ambiguousResult || haveCommit) {
 				ds.metrics.NextReplicaErrCount.Inc(1)
 				log.VEventf(ctx, 2, "error, trying next peer: %s", transport.NextReplica())
 				pending++
 				transport.SendNext(ctx, done)
 			}
 			if pending == 0 {
-				if ambiguousResult {
+				if ambiguousResult && haveCommit {
 					err = roachpb.NewAmbiguousResultError(
 						fmt.Sprintf("sending to all %d replicas failed; last error: %v, "+
 							"but RPC failure may have masked txn commit", len(replicas), err))
+				} else if ambiguousResult {
+					err = roachpb.NewAmbiguousResultError(
+						fmt.Sprintf("RPC error: %v, but the batch may have been applied", err))
 				} else {
 					err = roachpb.NewSendError(
 						fmt.Sprintf("sending to all %d replicas failed; last error: %v", len(replicas), err),
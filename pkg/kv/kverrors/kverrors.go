@@ -0,0 +1,122 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package kverrors classifies the errors returned by the KV layer according to
+// what the caller can do about them. It lets code outside of kv (the SQL
+// executor, jobs, internal clients) decide whether to retry an operation
+// without each caller maintaining its own list of roachpb error types.
+package kverrors
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+)
+
+// Class is the category of a KV error.
+type Class int
+
+const (
+	// Permanent errors won't go away by retrying the operation.
+	Permanent Class = iota
+	// RetryWithBackoff errors are transient conditions in the cluster (e.g. a
+	// range being split or a lease moving); retrying the same operation after
+	// a backoff may succeed.
+	RetryWithBackoff
+	// RefreshAndRetry errors mean that the transaction that ran into them must
+	// be restarted (with a new timestamp or epoch) before being retried.
+	RefreshAndRetry
+	// Ambiguous errors mean that the operation may or may not have been
+	// applied. Retrying is only safe if the operation is idempotent.
+	Ambiguous
+)
+
+func (c Class) String() string {
+	switch c {
+	case Permanent:
+		return "permanent"
+	case RetryWithBackoff:
+		return "retry-with-backoff"
+	case RefreshAndRetry:
+		return "refresh-and-retry"
+	case Ambiguous:
+		return "ambiguous"
+	default:
+		return "unknown"
+	}
+}
+
+// Classify returns the class of err, looking through errors wrapped with
+// github.com/pkg/errors. Errors that don't come from the KV layer, as well as
+// nil, are Permanent.
+func Classify(err error) Class {
+	switch errors.Cause(err).(type) {
+	case *roachpb.HandledRetryableTxnError,
+		*roachpb.UnhandledRetryableError,
+		*roachpb.TransactionAbortedError,
+		*roachpb.TransactionPushError,
+		*roachpb.TransactionRetryError,
+		*roachpb.WriteTooOldError,
+		*roachpb.ReadWithinUncertaintyIntervalError:
+		return RefreshAndRetry
+	case *roachpb.AmbiguousResultError:
+		return Ambiguous
+	case *roachpb.SendError,
+		*roachpb.NodeUnavailableError,
+		*roachpb.RangeNotFoundError,
+		*roachpb.RangeKeyMismatchError,
+		*roachpb.NotLeaseHolderError,
+		*roachpb.LeaseRejectedError,
+		*roachpb.WriteIntentError,
+		*roachpb.RaftGroupDeletedError:
+		return RetryWithBackoff
+	default:
+		return Permanent
+	}
+}
+
+// ClassifyPErr is like Classify, but for a *roachpb.Error. A nil pErr is
+// Permanent.
+func ClassifyPErr(pErr *roachpb.Error) Class {
+	if pErr == nil {
+		return Permanent
+	}
+	if pErr.TransactionRestart != roachpb.TransactionRestart_NONE {
+		return RefreshAndRetry
+	}
+	return Classify(pErr.GetDetail())
+}
+
+// IsRetryable returns true if the operation that returned err may succeed if
+// retried, possibly after restarting its transaction. Ambiguous errors are not
+// considered retryable; see IsAmbiguous.
+func IsRetryable(err error) bool {
+	switch Classify(err) {
+	case RetryWithBackoff, RefreshAndRetry:
+		return true
+	}
+	return false
+}
+
+// IsTxnRestart returns true if err requires the transaction that ran into it to
+// be restarted.
+func IsTxnRestart(err error) bool {
+	return Classify(err) == RefreshAndRetry
+}
+
+// IsAmbiguous returns true if the operation that returned err may or may not
+// have been applied.
+func IsAmbiguous(err error) bool {
+	return Classify(err) == Ambiguous
+}
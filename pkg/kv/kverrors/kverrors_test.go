@@ -0,0 +1,86 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package kverrors
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+)
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected Class
+	}{
+		{nil, Permanent},
+		{errors.New("boom"), Permanent},
+		{&roachpb.ConditionFailedError{}, Permanent},
+		{&roachpb.TransactionStatusError{}, Permanent},
+		{roachpb.NewSendError("no replicas"), RetryWithBackoff},
+		{roachpb.NewRangeNotFoundError(1), RetryWithBackoff},
+		{&roachpb.NotLeaseHolderError{}, RetryWithBackoff},
+		{roachpb.NewTransactionRetryError(roachpb.RETRY_SERIALIZABLE), RefreshAndRetry},
+		{roachpb.NewTransactionAbortedError(), RefreshAndRetry},
+		{&roachpb.UnhandledRetryableError{}, RefreshAndRetry},
+		{&roachpb.HandledRetryableTxnError{}, RefreshAndRetry},
+		{roachpb.NewAmbiguousResultError("unknown"), Ambiguous},
+		{errors.Wrap(roachpb.NewAmbiguousResultError("unknown"), "wrapped"), Ambiguous},
+		{errors.Wrap(roachpb.NewSendError("no replicas"), "wrapped"), RetryWithBackoff},
+	}
+	for _, tc := range testCases {
+		if class := Classify(tc.err); class != tc.expected {
+			t.Errorf("%v: expected %s, got %s", tc.err, tc.expected, class)
+		}
+	}
+}
+
+func TestClassifyPErr(t *testing.T) {
+	testCases := []struct {
+		pErr     *roachpb.Error
+		expected Class
+	}{
+		{nil, Permanent},
+		{roachpb.NewErrorf("boom"), Permanent},
+		{roachpb.NewError(roachpb.NewTransactionRetryError(roachpb.RETRY_SERIALIZABLE)), RefreshAndRetry},
+		{roachpb.NewError(roachpb.NewAmbiguousResultError("unknown")), Ambiguous},
+		{roachpb.NewError(roachpb.NewRangeNotFoundError(1)), RetryWithBackoff},
+	}
+	for _, tc := range testCases {
+		if class := ClassifyPErr(tc.pErr); class != tc.expected {
+			t.Errorf("%v: expected %s, got %s", tc.pErr, tc.expected, class)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	if !IsRetryable(roachpb.NewSendError("no replicas")) {
+		t.Error("expected SendError to be retryable")
+	}
+	if !IsRetryable(roachpb.NewTransactionAbortedError()) {
+		t.Error("expected TransactionAbortedError to be retryable")
+	}
+	if IsRetryable(roachpb.NewAmbiguousResultError("unknown")) {
+		t.Error("expected AmbiguousResultError not to be retryable")
+	}
+	if !IsAmbiguous(roachpb.NewAmbiguousResultError("unknown")) {
+		t.Error("expected AmbiguousResultError to be ambiguous")
+	}
+	if IsTxnRestart(roachpb.NewSendError("no replicas")) {
+		t.Error("expected SendError not to require a txn restart")
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package kv
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/randutil"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// loadSampleRate is the fraction of the batches sent by a DistSender whose
+// latency and size are attributed to the table index they address.
+var loadSampleRate = settings.RegisterValidatedFloatSetting(
+	"kv.load_attribution.sample_rate",
+	"fraction of the KV batches whose latency and size are attributed to the table index they address",
+	0.01,
+	func(v float64) error {
+		if v < 0 || v > 1 {
+			return errors.Errorf("sample rate must be between 0 and 1, got %f", v)
+		}
+		return nil
+	},
+)
+
+// TableLoad is the sampled load of the KV batches addressed to a table
+// index.
+type TableLoad struct {
+	TableID, IndexID uint32
+	// ReadBatches and WriteBatches are the numbers of read-only and of other
+	// sampled batches.
+	ReadBatches, WriteBatches int64
+	// RequestBytes and ResponseBytes are the total sizes of the sampled
+	// batches and of their responses.
+	RequestBytes, ResponseBytes int64
+	// Latency is the total latency of the sampled batches.
+	Latency time.Duration
+}
+
+type tableIndex struct {
+	tableID, indexID uint32
+}
+
+// loadStats aggregates the load of the sampled batches by table index.
+type loadStats struct {
+	rand *rand.Rand
+
+	mu struct {
+		syncutil.Mutex
+		tables map[tableIndex]*TableLoad
+	}
+}
+
+func makeLoadStats() loadStats {
+	var s loadStats
+	s.rand, _ = randutil.NewLockedPseudoRand()
+	s.mu.tables = make(map[tableIndex]*TableLoad)
+	return s
+}
+
+// loadSample is a sampled batch, whose load is recorded by finish.
+type loadSample struct {
+	stats        *loadStats
+	table        tableIndex
+	readOnly     bool
+	requestBytes int64
+	start        time.Time
+}
+
+// sample returns a sample for the batch, or nil if the batch isn't sampled.
+// Only the batches addressed to a table index are sampled: they are
+// attributed to the index of the start of their span.
+func (s *loadStats) sample(ba roachpb.BatchRequest) *loadSample {
+	rate := loadSampleRate.Get()
+	if rate <= 0 || (rate < 1 && s.rand.Float64() >= rate) {
+		return nil
+	}
+	rs, err := keys.Range(ba)
+	if err != nil {
+		return nil
+	}
+	table, ok := decodeTableIndex(rs.Key)
+	if !ok {
+		return nil
+	}
+	return &loadSample{
+		stats:        s,
+		table:        table,
+		readOnly:     ba.IsReadOnly(),
+		requestBytes: int64(ba.Size()),
+		start:        timeutil.Now(),
+	}
+}
+
+// decodeTableIndex returns the table index of a key in the table data
+// span.
+func decodeTableIndex(key roachpb.RKey) (tableIndex, bool) {
+	rest, tableID, err := keys.DecodeTablePrefix(roachpb.Key(key))
+	if err != nil {
+		return tableIndex{}, false
+	}
+	// The start of the span of a whole table has no index ID.
+	var indexID uint64
+	if len(rest) > 0 {
+		if _, indexID, err = encoding.DecodeUvarintAscending(rest); err != nil {
+			return tableIndex{}, false
+		}
+	}
+	return tableIndex{tableID: uint32(tableID), indexID: uint32(indexID)}, true
+}
+
+// finish records the load of the sampled batch, given its response, which
+// is nil if the batch failed.
+func (ls *loadSample) finish(br *roachpb.BatchResponse) {
+	latency := timeutil.Since(ls.start)
+	var responseBytes int64
+	if br != nil {
+		responseBytes = int64(br.Size())
+	}
+
+	s := ls.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	load, ok := s.mu.tables[ls.table]
+	if !ok {
+		load = &TableLoad{TableID: ls.table.tableID, IndexID: ls.table.indexID}
+		s.mu.tables[ls.table] = load
+	}
+	if ls.readOnly {
+		load.ReadBatches++
+	} else {
+		load.WriteBatches++
+	}
+	load.RequestBytes += ls.requestBytes
+	load.ResponseBytes += responseBytes
+	load.Latency += latency
+}
+
+// TableLoads returns the load of the sampled batches sent by the DistSender,
+// by table index, sorted by table and index ID. See
+// kv.load_attribution.sample_rate.
+func (ds *DistSender) TableLoads() []TableLoad {
+	s := &ds.loadStats
+	s.mu.Lock()
+	loads := make([]TableLoad, 0, len(s.mu.tables))
+	for _, load := range s.mu.tables {
+		loads = append(loads, *load)
+	}
+	s.mu.Unlock()
+	sort.Slice(loads, func(i, j int) bool {
+		if loads[i].TableID != loads[j].TableID {
+			return loads[i].TableID < loads[j].TableID
+		}
+		return loads[i].IndexID < loads[j].IndexID
+	})
+	return loads
+}
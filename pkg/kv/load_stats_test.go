@@ -0,0 +1,90 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package kv
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestLoadStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	indexKey := func(tableID, indexID uint32) roachpb.Key {
+		return roachpb.Key(encoding.EncodeUvarintAscending(keys.MakeTablePrefix(tableID), uint64(indexID)))
+	}
+	batch := func(reqs ...roachpb.Request) roachpb.BatchRequest {
+		var ba roachpb.BatchRequest
+		ba.Add(reqs...)
+		return ba
+	}
+	ds := &DistSender{loadStats: makeLoadStats()}
+
+	// Nothing is sampled by default.
+	defer settings.TestingSetFloat(&loadSampleRate, 0)()
+	if s := ds.loadStats.sample(batch(roachpb.NewGet(indexKey(51, 1)))); s != nil {
+		t.Fatalf("expected no sample with a sample rate of 0, got %+v", s)
+	}
+
+	defer settings.TestingSetFloat(&loadSampleRate, 1)()
+	for _, ba := range []roachpb.BatchRequest{
+		batch(roachpb.NewGet(indexKey(51, 1))),
+		batch(roachpb.NewGet(indexKey(51, 1)), roachpb.NewGet(indexKey(51, 2))),
+		batch(roachpb.NewPut(indexKey(51, 2), roachpb.MakeValueFromString("foo"))),
+		batch(roachpb.NewGet(keys.MakeTablePrefix(52))),
+		batch(roachpb.NewGet(roachpb.Key(keys.MakeTablePrefix(keys.NamespaceTableID)))),
+	} {
+		s := ds.loadStats.sample(ba)
+		if s == nil {
+			t.Fatalf("expected %s to be sampled", ba)
+		}
+		s.finish(&roachpb.BatchResponse{})
+	}
+	// The batches which don't address a table aren't sampled.
+	if s := ds.loadStats.sample(batch(roachpb.NewGet(keys.NodeLivenessKey(1)))); s != nil {
+		t.Fatalf("expected a batch outside of the tables not to be sampled, got %+v", s)
+	}
+
+	type counts struct {
+		tableID, indexID          uint32
+		readBatches, writeBatches int64
+	}
+	var actual []counts
+	for _, load := range ds.TableLoads() {
+		actual = append(actual, counts{load.TableID, load.IndexID, load.ReadBatches, load.WriteBatches})
+		if load.RequestBytes == 0 {
+			t.Errorf("expected request bytes for %d/%d", load.TableID, load.IndexID)
+		}
+	}
+	expected := []counts{
+		{keys.NamespaceTableID, 0, 1, 0},
+		{51, 1, 2, 0},
+		{51, 2, 0, 1},
+		{52, 0, 1, 0},
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("%d: expected %+v, got %+v", i, expected[i], actual[i])
+		}
+	}
+}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/rpc"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
@@ -478,6 +479,85 @@ func TestComplexScenarios(t *testing.T) {
 	}
 }
 
+// TestSendToReplicasAmbiguousRPCError verifies that a batch whose RPC ended
+// ambiguously is retried on the next replica only if it is idempotent or is
+// a transaction commit (whose replays are detected). Writes are only
+// idempotent in a transaction.
+func TestSendToReplicasAmbiguousRPCError(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+
+	nodeContext := rpc.NewContext(
+		log.AmbientContext{},
+		testutils.NewNodeTestBaseContext(),
+		hlc.NewClock(hlc.UnixNano, time.Nanosecond),
+		stopper,
+	)
+	replicas := makeReplicas(
+		util.NewUnresolvedAddr("dummy", "1"),
+		util.NewUnresolvedAddr("dummy", "2"),
+		util.NewUnresolvedAddr("dummy", "3"),
+	)
+
+	key := roachpb.Key("a")
+	span := roachpb.Span{Key: key}
+	txn := roachpb.NewTransaction("test", key, roachpb.NormalUserPriority,
+		enginepb.SERIALIZABLE, hlc.Timestamp{WallTime: 1}, 0)
+	testCases := []struct {
+		args      roachpb.Request
+		txn       *roachpb.Transaction
+		ambiguous bool
+	}{
+		{&roachpb.GetRequest{Span: span}, nil, false},
+		{&roachpb.GetRequest{Span: span}, txn, false},
+		{&roachpb.PutRequest{Span: span}, nil, true},
+		{&roachpb.PutRequest{Span: span}, txn, false},
+		{&roachpb.PutRequest{Span: span, Inline: true}, nil, true},
+		{&roachpb.DeleteRequest{Span: span}, nil, true},
+		{&roachpb.DeleteRequest{Span: span}, txn, false},
+		{&roachpb.IncrementRequest{Span: span}, nil, true},
+		{&roachpb.IncrementRequest{Span: span}, txn, false},
+		{&roachpb.ConditionalPutRequest{Span: span}, nil, true},
+		{&roachpb.ConditionalPutRequest{Span: span}, txn, false},
+		{&roachpb.DeleteRangeRequest{Span: span, Inline: true}, nil, true},
+		{&roachpb.AdminSplitRequest{Span: span, SplitKey: key}, nil, false},
+		{&roachpb.AdminMergeRequest{Span: span}, nil, true},
+		{&roachpb.MergeRequest{Span: span}, nil, true},
+		{&roachpb.ResolveIntentRequest{Span: span}, nil, false},
+		{&roachpb.EndTransactionRequest{Span: span, Commit: true}, txn, false},
+	}
+	for i, test := range testCases {
+		var ba roachpb.BatchRequest
+		ba.Txn = test.txn
+		ba.Add(test.args)
+		transport := &firstNErrorTransport{replicas: replicas, args: ba, numErrors: 1}
+		opts := SendOptions{
+			SendNextTimeout: time.Hour,
+			transportFactory: func(
+				_ SendOptions, _ *rpc.Context, _ ReplicaSlice, _ roachpb.BatchRequest,
+			) (Transport, error) {
+				return transport, nil
+			},
+		}
+
+		ds := NewDistSender(DistSenderConfig{}, nil)
+		opts.metrics = &ds.metrics
+		_, err := ds.sendToReplicas(context.Background(), opts, 0, replicas, ba, nodeContext)
+		if test.ambiguous {
+			if _, ok := err.(*roachpb.AmbiguousResultError); !ok {
+				t.Errorf("%d: expected AmbiguousResultError for %s; got %v", i, ba.Summary(), err)
+			}
+			if transport.numSent != 1 {
+				t.Errorf("%d: expected no retries for %s; sent %d RPCs", i, ba.Summary(), transport.numSent)
+			}
+		} else if err != nil {
+			t.Errorf("%d: unexpected error for %s: %s", i, ba.Summary(), err)
+		}
+	}
+}
+
 // TestSplitHealthy tests that the splitHealthy helper function sorts healthy
 // nodes before unhealthy nodes.
 func TestSplitHealthy(t *testing.T) {
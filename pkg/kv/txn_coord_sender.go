@@ -37,6 +37,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 )
 
@@ -219,6 +220,7 @@ func NewTxnCoordSender(
 	stopper *stop.Stopper,
 	txnMetrics TxnMetrics,
 ) *TxnCoordSender {
+	ambient.SetSubsystem(tracing.SubsystemKV)
 	tc := &TxnCoordSender{
 		AmbientContext:    ambient,
 		wrapped:           wrapped,
@@ -827,6 +829,18 @@ func (tc *TxnCoordSender) updateState(
 	}
 
 	txnID := *ba.Txn.ID
+	// An ambiguous result for a batch which doesn't end the transaction can
+	// be resolved by restarting the transaction: whether or not the batch was
+	// applied, its writes are superseded by those of the next epoch. Only
+	// ambiguity about the transaction's commit is surfaced to the client.
+	if _, ok := pErr.GetDetail().(*roachpb.AmbiguousResultError); ok {
+		if _, isEnding := ba.GetArg(roachpb.EndTransaction); !isEnding {
+			log.VEventf(ctx, 2, "restarting txn after ambiguous result: %s", pErr)
+			errTxn := ba.Txn.Clone()
+			pErr = roachpb.NewErrorWithTxn(
+				roachpb.NewTransactionRetryError(roachpb.RETRY_POSSIBLE_REPLAY), &errTxn)
+		}
+	}
 	var newTxn roachpb.Transaction
 	if pErr == nil {
 		newTxn.Update(ba.Txn)
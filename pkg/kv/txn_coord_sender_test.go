@@ -873,6 +873,18 @@ func TestTxnCoordSenderTxnUpdatedOnError(t *testing.T) {
 			expTS:     plus10,
 			expOrigTS: plus10,
 		},
+		{
+			// On an ambiguous result for a batch which doesn't end the
+			// transaction, restart with a new epoch.
+			name: "AmbiguousResultError",
+			pErrGen: func(_ *roachpb.Transaction) *roachpb.Error {
+				return roachpb.NewError(roachpb.NewAmbiguousResultError("boom"))
+			},
+			expEpoch:  1,
+			expPri:    1,
+			expTS:     origTS,
+			expOrigTS: origTS,
+		},
 	}
 
 	for _, test := range testCases {
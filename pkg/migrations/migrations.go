@@ -65,6 +65,18 @@ var backwardCompatibleMigrations = []migrationDescriptor{
 		name:   "enable diagnostics reporting",
 		workFn: optInToDiagnosticsStatReporting,
 	},
+	{
+		name:           "create system.scheduled_jobs table",
+		workFn:         createScheduledJobsTable,
+		newDescriptors: 1,
+		newRanges:      1,
+	},
+	{
+		name:           "create system.livenesslog table",
+		workFn:         createLivenessLogTable,
+		newDescriptors: 1,
+		newRanges:      1,
+	},
 }
 
 // migrationDescriptor describes a single migration hook that's used to modify
@@ -349,6 +361,14 @@ func createSettingsTable(ctx context.Context, r runner) error {
 	return createSystemTable(ctx, r, sqlbase.SettingsTable)
 }
 
+func createScheduledJobsTable(ctx context.Context, r runner) error {
+	return createSystemTable(ctx, r, sqlbase.ScheduledJobsTable)
+}
+
+func createLivenessLogTable(ctx context.Context, r runner) error {
+	return createSystemTable(ctx, r, sqlbase.LivenessLogTable)
+}
+
 func createSystemTable(ctx context.Context, r runner, desc sqlbase.TableDescriptor) error {
 	// We install the table at the KV layer so that we can choose a known ID in
 	// the reserved ID space. (The SQL layer doesn't allow this.)
@@ -83,6 +83,7 @@ const (
 	skipLeaseCheck
 	consultsTSCache // mutating commands which write data at a timestamp
 	updatesTSCache  // commands which read data at a timestamp
+	nonIdempotent   // commands whose replays aren't detected, even in a transaction
 )
 
 // GetTxnID returns the transaction ID if the header has a transaction
@@ -876,7 +877,7 @@ func (drr *DeleteRangeRequest) flags() int {
 	// This workaround does not preclude us from creating a separate
 	// "DeleteInlineRange" command at a later date.
 	if drr.Inline {
-		return isWrite | isRange | isAlone
+		return isWrite | isRange | isAlone | nonIdempotent
 	}
 	// DeleteRange updates the timestamp cache as it doesn't leave
 	// intents or tombstones for keys which don't yet exist. By updating
@@ -890,12 +891,16 @@ func (*BeginTransactionRequest) flags() int { return isWrite | isTxn | consultsT
 
 // EndTransaction updates the write timestamp cache to prevent
 // replays. Replays for the same transaction key and timestamp will
-// have Txn.WriteTooOld=true and must retry on EndTransaction.
-func (*EndTransactionRequest) flags() int      { return isWrite | isTxn | isAlone | updatesTSCache }
-func (*AdminSplitRequest) flags() int          { return isAdmin | isAlone }
-func (*AdminMergeRequest) flags() int          { return isAdmin | isAlone }
+// have Txn.WriteTooOld=true and must retry on EndTransaction. Such
+// a replay can't be told apart from a failed commit, so it isn't
+// idempotent.
+func (*EndTransactionRequest) flags() int {
+	return isWrite | isTxn | isAlone | updatesTSCache | nonIdempotent
+}
+func (*AdminSplitRequest) flags() int          { return isAdmin | isAlone } // replays find the range already split
+func (*AdminMergeRequest) flags() int          { return isAdmin | isAlone | nonIdempotent }
 func (*AdminTransferLeaseRequest) flags() int  { return isAdmin | isAlone }
-func (*AdminChangeReplicasRequest) flags() int { return isAdmin | isAlone }
+func (*AdminChangeReplicasRequest) flags() int { return isAdmin | isAlone | nonIdempotent }
 func (*HeartbeatTxnRequest) flags() int        { return isWrite | isTxn }
 func (*GCRequest) flags() int                  { return isWrite | isRange }
 func (*PushTxnRequest) flags() int             { return isWrite | isAlone }
@@ -905,7 +910,7 @@ func (*ResolveIntentRequest) flags() int       { return isWrite }
 func (*ResolveIntentRangeRequest) flags() int  { return isWrite | isRange }
 func (*NoopRequest) flags() int                { return isRead } // slightly special
 func (*TruncateLogRequest) flags() int         { return isWrite }
-func (*MergeRequest) flags() int               { return isWrite }
+func (*MergeRequest) flags() int               { return isWrite | nonIdempotent }
 
 func (*RequestLeaseRequest) flags() int {
 	return isWrite | isAlone | skipLeaseCheck
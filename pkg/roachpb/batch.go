@@ -110,6 +110,19 @@ func (ba *BatchRequest) IsTransactionWrite() bool {
 	return ba.hasFlag(isTxnWrite)
 }
 
+// IsIdempotent returns true iff the BatchRequest can be sent again after an
+// RPC for it ended ambiguously, because a replay either has the same effect
+// and result as the original or is rejected. Transactional writes carry the
+// transaction's epoch and sequence number, which the store uses to reject
+// replays, so they are idempotent while the same writes outside of a
+// transaction are not.
+func (ba *BatchRequest) IsIdempotent() bool {
+	if ba.hasFlag(nonIdempotent) {
+		return false
+	}
+	return ba.Txn != nil || !ba.hasFlag(isTxnWrite)
+}
+
 // IsSingleRequest returns true iff the BatchRequest contains a single request.
 func (ba *BatchRequest) IsSingleRequest() bool {
 	return len(ba.Requests) == 1
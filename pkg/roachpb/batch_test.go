@@ -102,6 +102,43 @@ func TestBatchRequestGetArg(t *testing.T) {
 	}
 }
 
+func TestBatchRequestIsIdempotent(t *testing.T) {
+	txn := &Transaction{}
+	testCases := []struct {
+		bu  []RequestUnion
+		txn *Transaction
+		exp bool
+	}{
+		{[]RequestUnion{}, nil, true},
+		{[]RequestUnion{{Get: &GetRequest{}}, {Scan: &ScanRequest{}}}, nil, true},
+		{[]RequestUnion{{Get: &GetRequest{}}, {Scan: &ScanRequest{}}}, txn, true},
+		{[]RequestUnion{{Put: &PutRequest{}}, {Delete: &DeleteRequest{}}}, nil, false},
+		{[]RequestUnion{{Put: &PutRequest{}}, {Delete: &DeleteRequest{}}}, txn, true},
+		{[]RequestUnion{{Put: &PutRequest{Inline: true}}}, nil, false},
+		{[]RequestUnion{{ConditionalPut: &ConditionalPutRequest{}}}, nil, false},
+		{[]RequestUnion{{ConditionalPut: &ConditionalPutRequest{}}}, txn, true},
+		{[]RequestUnion{{Increment: &IncrementRequest{}}}, nil, false},
+		{[]RequestUnion{{Increment: &IncrementRequest{}}}, txn, true},
+		{[]RequestUnion{{DeleteRange: &DeleteRangeRequest{}}}, txn, true},
+		{[]RequestUnion{{DeleteRange: &DeleteRangeRequest{Inline: true}}}, nil, false},
+		{[]RequestUnion{{BeginTransaction: &BeginTransactionRequest{}}, {Put: &PutRequest{}}}, txn, true},
+		{[]RequestUnion{{EndTransaction: &EndTransactionRequest{}}}, txn, false},
+		{[]RequestUnion{{AdminSplit: &AdminSplitRequest{}}}, nil, true},
+		{[]RequestUnion{{AdminMerge: &AdminMergeRequest{}}}, nil, false},
+		{[]RequestUnion{{Merge: &MergeRequest{}}}, nil, false},
+		{[]RequestUnion{{ResolveIntent: &ResolveIntentRequest{}}}, nil, true},
+	}
+
+	for i, c := range testCases {
+		ba := BatchRequest{Requests: c.bu}
+		ba.Txn = c.txn
+		if r := ba.IsIdempotent(); r != c.exp {
+			t.Errorf("%d: expected idempotent=%t for %s (txn=%t), got %t",
+				i, c.exp, ba.Summary(), c.txn != nil, r)
+		}
+	}
+}
+
 func TestBatchRequestSummary(t *testing.T) {
 	// The Summary function is generated automatically, so the tests don't need to
 	// be exhaustive.
@@ -137,6 +137,19 @@ type connMeta struct {
 	heartbeatErr error
 }
 
+// ContextTestingKnobs provides hooks to aid in testing the rpc framework.
+type ContextTestingKnobs struct {
+	// Dialer, if set, is used to dial the connections of the Context instead
+	// of the default dialer, e.g. to inject network partitions between the
+	// nodes of a test cluster.
+	Dialer func(addr string, timeout time.Duration) (net.Conn, error)
+}
+
+var _ base.ModuleTestingKnobs = &ContextTestingKnobs{}
+
+// ModuleTestingKnobs is part of the base.ModuleTestingKnobs interface.
+func (*ContextTestingKnobs) ModuleTestingKnobs() {}
+
 // Context contains the fields required by the rpc framework.
 type Context struct {
 	*base.Config
@@ -161,8 +174,15 @@ type Context struct {
 		cache map[string]*connMeta
 	}
 
+	connEventCallbacks struct {
+		syncutil.Mutex
+		nextID int
+		cbs    map[int]func(ConnEvent)
+	}
+
 	// For unittesting.
 	BreakerFactory func() *circuit.Breaker
+	Knobs          ContextTestingKnobs
 }
 
 // NewContext creates an rpc Context with the supplied values.
@@ -189,6 +209,7 @@ func NewContext(
 	ctx.heartbeatInterval = defaultHeartbeatInterval
 	ctx.heartbeatTimeout = 2 * defaultHeartbeatInterval
 	ctx.conns.cache = make(map[string]*connMeta)
+	ctx.connEventCallbacks.cbs = make(map[int]func(ConnEvent))
 
 	stopper.RunWorker(ctx.masterCtx, func(context.Context) {
 		<-stopper.ShouldQuiesce()
@@ -293,7 +314,9 @@ func (ctx *Context) GRPCDial(target string, opts ...grpc.DialOption) (*grpc.Clie
 		}))
 		dialOpts = append(dialOpts, opts...)
 
-		if SourceAddr != nil {
+		if ctx.Knobs.Dialer != nil {
+			dialOpts = append(dialOpts, grpc.WithDialer(ctx.Knobs.Dialer))
+		} else if SourceAddr != nil {
 			dialOpts = append(dialOpts, grpc.WithDialer(
 				func(addr string, timeout time.Duration) (net.Conn, error) {
 					dialer := net.Dialer{
@@ -324,6 +347,7 @@ func (ctx *Context) GRPCDial(target string, opts ...grpc.DialOption) (*grpc.Clie
 							log.Errorf(masterCtx, "removing connection to %s due to error: %s", target, err)
 						}
 						ctx.removeConn(target, meta)
+						ctx.notifyConnEvent(ConnEvent{Type: ConnDisconnected, Addr: target, Err: err})
 					})
 				}); err != nil {
 				meta.dialErr = err
@@ -368,6 +392,79 @@ func (ctx *Context) ConnHealth(remoteAddr string) error {
 	return ErrNotConnected
 }
 
+// ConnEventType is the type of a ConnEvent.
+type ConnEventType int
+
+const (
+	// ConnConnected is emitted when the first heartbeat on a connection
+	// succeeds, and when a heartbeat succeeds after previously failing.
+	ConnConnected ConnEventType = iota
+	// ConnUnhealthy is emitted when the first heartbeat on a connection fails,
+	// and when a heartbeat fails after previously succeeding.
+	ConnUnhealthy
+	// ConnDisconnected is emitted when a connection is closed. A subsequent
+	// call to GRPCDial for the same address creates a new connection.
+	ConnDisconnected
+)
+
+func (t ConnEventType) String() string {
+	switch t {
+	case ConnConnected:
+		return "connected"
+	case ConnUnhealthy:
+		return "unhealthy"
+	case ConnDisconnected:
+		return "disconnected"
+	default:
+		return fmt.Sprintf("ConnEventType(%d)", int(t))
+	}
+}
+
+// ConnEvent describes a change in the state of a connection to a peer.
+type ConnEvent struct {
+	Type ConnEventType
+	// Addr is the address of the peer, as passed to GRPCDial.
+	Addr string
+	// Err is the heartbeat error for ConnUnhealthy events and the error which
+	// caused the connection to be closed, if any, for ConnDisconnected events.
+	Err error
+}
+
+// RegisterConnEventCallback registers a callback which is invoked whenever
+// the state of a connection created by GRPCDial changes. This allows
+// subsystems to react to peers becoming unavailable (or available again)
+// immediately, instead of waiting for their next RPC to time out. The callback
+// is invoked synchronously from the connection's heartbeat loop and must not
+// block. The returned function unregisters the callback.
+func (ctx *Context) RegisterConnEventCallback(cb func(ConnEvent)) func() {
+	ctx.connEventCallbacks.Lock()
+	defer ctx.connEventCallbacks.Unlock()
+	id := ctx.connEventCallbacks.nextID
+	ctx.connEventCallbacks.nextID++
+	ctx.connEventCallbacks.cbs[id] = cb
+	return func() {
+		ctx.connEventCallbacks.Lock()
+		defer ctx.connEventCallbacks.Unlock()
+		delete(ctx.connEventCallbacks.cbs, id)
+	}
+}
+
+func (ctx *Context) notifyConnEvent(ev ConnEvent) {
+	ctx.connEventCallbacks.Lock()
+	cbs := make([]func(ConnEvent), 0, len(ctx.connEventCallbacks.cbs))
+	for _, cb := range ctx.connEventCallbacks.cbs {
+		cbs = append(cbs, cb)
+	}
+	ctx.connEventCallbacks.Unlock()
+
+	if log.V(1) {
+		log.Infof(ctx.masterCtx, "connection to %s %s", ev.Addr, ev.Type)
+	}
+	for _, cb := range cbs {
+		cb(ev)
+	}
+}
+
 func (ctx *Context) runHeartbeat(meta *connMeta, remoteAddr string) error {
 	maxOffset := ctx.LocalClock.MaxOffset()
 
@@ -403,9 +500,17 @@ func (ctx *Context) runHeartbeat(meta *connMeta, remoteAddr string) error {
 			cancel()
 		}
 		ctx.conns.Lock()
+		prevErr := meta.heartbeatErr
 		meta.heartbeatErr = err
 		ctx.conns.Unlock()
 
+		// Notify the subscribers when the health of the connection changes.
+		if err == nil && prevErr != nil {
+			ctx.notifyConnEvent(ConnEvent{Type: ConnConnected, Addr: remoteAddr})
+		} else if err != nil && (prevErr == nil || prevErr == ErrNotHeartbeated) {
+			ctx.notifyConnEvent(ConnEvent{Type: ConnUnhealthy, Addr: remoteAddr, Err: err})
+		}
+
 		// HACK: work around https://github.com/grpc/grpc-go/issues/1026
 		// Getting a "connection refused" error from the "write" system call
 		// has confused grpc's error handling and this connection is permanently
@@ -19,6 +19,7 @@ package rpc
 import (
 	"math"
 	"net"
+	"reflect"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -201,6 +202,93 @@ func TestHeartbeatHealth(t *testing.T) {
 	}
 }
 
+func TestConnEventCallback(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+
+	// Can't be zero because that'd be an empty offset.
+	clock := hlc.NewClock(time.Unix(0, 1).UnixNano, time.Nanosecond)
+
+	serverCtx := NewContext(log.AmbientContext{}, testutils.NewNodeTestBaseContext(), clock, stopper)
+	s, ln := newTestServer(t, serverCtx, true)
+	remoteAddr := ln.Addr().String()
+
+	heartbeat := &ManualHeartbeatService{
+		ready:              make(chan error),
+		stopper:            stopper,
+		clock:              clock,
+		remoteClockMonitor: serverCtx.RemoteClocks,
+	}
+	RegisterHeartbeatServer(s, heartbeat)
+
+	clientCtx := NewContext(log.AmbientContext{}, testutils.NewNodeTestBaseContext(), clock, stopper)
+	// Make the interval shorter to speed up the test.
+	clientCtx.heartbeatInterval = 1 * time.Millisecond
+
+	var mu syncutil.Mutex
+	var events []ConnEventType
+	unregister := clientCtx.RegisterConnEventCallback(func(ev ConnEvent) {
+		if ev.Addr != remoteAddr {
+			t.Errorf("unexpected address %s", ev.Addr)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, ev.Type)
+	})
+	waitForEvents := func(expected ...ConnEventType) {
+		testutils.SucceedsSoon(t, func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(events, expected) {
+				return errors.Errorf("expected events %s, got %s", expected, events)
+			}
+			return nil
+		})
+	}
+
+	if _, err := clientCtx.GRPCDial(remoteAddr); err != nil {
+		t.Fatal(err)
+	}
+
+	var hbSuccess atomic.Value
+	hbSuccess.Store(true)
+
+	go func() {
+		for {
+			var err error
+			if !hbSuccess.Load().(bool) {
+				err = errors.New("failed heartbeat")
+			}
+
+			select {
+			case <-stopper.ShouldStop():
+				return
+			case heartbeat.ready <- err:
+			}
+		}
+	}()
+
+	// Events are only emitted when the health of the connection changes.
+	waitForEvents(ConnConnected)
+	hbSuccess.Store(false)
+	waitForEvents(ConnConnected, ConnUnhealthy)
+	hbSuccess.Store(true)
+	waitForEvents(ConnConnected, ConnUnhealthy, ConnConnected)
+
+	// No events are emitted after unregistering the callback.
+	unregister()
+	hbSuccess.Store(false)
+	testutils.SucceedsSoon(t, func() error {
+		if err := clientCtx.ConnHealth(remoteAddr); err == nil {
+			return errors.New("expected unhealthy connection")
+		}
+		return nil
+	})
+	waitForEvents(ConnConnected, ConnUnhealthy, ConnConnected)
+}
+
 type interceptingListener struct {
 	net.Listener
 	connCB func(net.Conn)
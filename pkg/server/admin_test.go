@@ -179,6 +179,62 @@ func TestAdminDebugTrace(t *testing.T) {
 	}
 }
 
+// TestAdminDebugTracezOnDemand verifies that on-demand tracing can only be
+// started with a POST to /debug/tracez/ondemand, for a limited duration.
+func TestAdminDebugTracezOnDemand(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, _, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+	tr := s.(*TestServer).cfg.AmbientCtx.Tracer.(*tracing.Tracer)
+	defer tr.StopOnDemandTracing()
+
+	client, err := s.GetHTTPClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := s.AdminURL() + tracezOnDemandDebugEndpoint
+
+	resp, err := client.Get(endpoint + "?pattern=foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status %d, got %s", http.StatusMethodNotAllowed, resp.Status)
+	}
+	if status := tr.GetOnDemandTracingStatus(); status.Active() {
+		t.Fatalf("expected on-demand tracing not to be started by a GET, got %+v", status)
+	}
+
+	start := timeutil.Now()
+	resp, err = client.PostForm(endpoint, url.Values{"pattern": {"foo"}, "duration": {"24h"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %s", http.StatusOK, resp.Status)
+	}
+	status := tr.GetOnDemandTracingStatus()
+	if !status.Active() || status.Pattern != "foo" {
+		t.Fatalf("expected on-demand tracing of foo to be active, got %+v", status)
+	}
+	if max := timeutil.Now().Add(maxOnDemandTracingDuration); status.Deadline.After(max) ||
+		status.Deadline.Before(start.Add(maxOnDemandTracingDuration)) {
+		t.Fatalf("expected the duration to be capped at %s, got deadline %s",
+			maxOnDemandTracingDuration, status.Deadline)
+	}
+
+	resp, err = client.PostForm(endpoint, url.Values{"stop": {"true"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if status := tr.GetOnDemandTracingStatus(); status.Active() {
+		t.Fatalf("expected on-demand tracing to be stopped, got %+v", status)
+	}
+}
+
 // TestAdminDebugRedirect verifies that the /debug/ endpoint is redirected to on
 // incorrect /debug/ paths.
 func TestAdminDebugRedirect(t *testing.T) {
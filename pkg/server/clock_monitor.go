@@ -0,0 +1,239 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// The actions taken when the clock of a node is found to be unreliable.
+const (
+	// clockActionFatal terminates the process.
+	clockActionFatal = iota
+	// clockActionQuarantine drains the node, which then refuses to serve
+	// clients and to hold leases until it is restarted.
+	clockActionQuarantine
+	// clockActionResync drains the node like clockActionQuarantine, waits for
+	// the maximum offset and undrains the node once its clock is in sync with
+	// the rest of the cluster again.
+	clockActionResync
+)
+
+var clockOffsetAction = settings.RegisterEnumSetting(
+	"server.clock.offset_action",
+	"what a node does when its clock is offset from the cluster or jumps forward by more than the maximum offset",
+	"fatal",
+	map[int64]string{
+		clockActionFatal:      "fatal",
+		clockActionQuarantine: "quarantine",
+		clockActionResync:     "resync",
+	},
+)
+
+var forwardClockJumpGuard = settings.RegisterNonNegativeDurationSetting(
+	"server.clock.forward_jump_guard",
+	"if nonzero, forward clock jumps larger than this are detected; those within the maximum offset are smoothed",
+	0,
+)
+
+// clockMonitorInterval is the interval at which the clock monitor reads the
+// clock when the forward jump guard doesn't require it more often.
+const clockMonitorInterval = time.Second
+
+var (
+	metaClockJumpsForward = metric.Metadata{
+		Name: "clock.jumps.forward",
+		Help: "Number of forward jumps of the wall clock"}
+	metaClockJumpsBackward = metric.Metadata{
+		Name: "clock.jumps.backward",
+		Help: "Number of backward jumps of the wall clock"}
+	metaClockJumpsSmoothed = metric.Metadata{
+		Name: "clock.jumps.smoothed",
+		Help: "Number of forward jumps of the wall clock which were smoothed"}
+	metaClockQuarantined = metric.Metadata{
+		Name: "clock.quarantined",
+		Help: "Whether the node is drained because its clock is unreliable"}
+)
+
+// clockMonitorMetrics holds the metrics of a clockMonitor.
+type clockMonitorMetrics struct {
+	JumpsForward  *metric.Counter
+	JumpsBackward *metric.Counter
+	JumpsSmoothed *metric.Counter
+	Quarantined   *metric.Gauge
+}
+
+// clockMonitor watches the jumps of the node's clock and the offsets to the
+// other nodes, and takes the action configured by server.clock.offset_action
+// when the clock is found to be unreliable.
+type clockMonitor struct {
+	clock *hlc.Clock
+	// drain and undrain stop and resume serving clients and holding leases.
+	drain   func() error
+	undrain func()
+	// verifyOffset checks the offset of the clock to the rest of the cluster.
+	verifyOffset func(context.Context) error
+
+	metrics clockMonitorMetrics
+	// problemC receives the clock problems to act upon.
+	problemC chan error
+}
+
+func newClockMonitor(
+	clock *hlc.Clock,
+	drain func() error,
+	undrain func(),
+	verifyOffset func(context.Context) error,
+) *clockMonitor {
+	m := &clockMonitor{
+		clock:        clock,
+		drain:        drain,
+		undrain:      undrain,
+		verifyOffset: verifyOffset,
+		metrics: clockMonitorMetrics{
+			JumpsForward:  metric.NewCounter(metaClockJumpsForward),
+			JumpsBackward: metric.NewCounter(metaClockJumpsBackward),
+			JumpsSmoothed: metric.NewCounter(metaClockJumpsSmoothed),
+			Quarantined:   metric.NewGauge(metaClockQuarantined),
+		},
+		problemC: make(chan error, 1),
+	}
+	return m
+}
+
+// newServerClockMonitor returns a clockMonitor draining the given server.
+func newServerClockMonitor(s *Server) *clockMonitor {
+	modes := GracefulDrainModes
+	return newClockMonitor(
+		s.clock,
+		func() error {
+			_, err := s.Drain(modes)
+			return err
+		},
+		func() { s.Undrain(modes) },
+		s.rpcContext.RemoteClocks.VerifyClockOffset,
+	)
+}
+
+// handleJump is the jump handler of the clock, registered by start with the
+// monitor's context. It is called with the clock's lock held.
+func (m *clockMonitor) handleJump(ctx context.Context, jump hlc.ClockJump) {
+	if jump.Jump < 0 {
+		// The HLC doesn't go backward, so backward jumps don't endanger
+		// consistency; they are only counted.
+		m.metrics.JumpsBackward.Inc(1)
+		return
+	}
+	m.metrics.JumpsForward.Inc(1)
+	if jump.Smoothed {
+		m.metrics.JumpsSmoothed.Inc(1)
+		return
+	}
+	if maxOffset := m.clock.MaxOffset(); maxOffset > 0 && jump.Jump > maxOffset {
+		m.report(ctx, errors.Errorf(
+			"clock jumped forward by %s, more than the maximum offset %s", jump.Jump, maxOffset))
+	}
+}
+
+// report acts upon a clock problem: the process is terminated right away if
+// the configured action is fatal, otherwise the problem is passed to the
+// monitor's worker. It doesn't block.
+func (m *clockMonitor) report(ctx context.Context, err error) {
+	if clockOffsetAction.Get() == clockActionFatal {
+		log.Fatal(ctx, err)
+	}
+	select {
+	case m.problemC <- err:
+	default:
+		// A problem is already pending.
+	}
+}
+
+// start starts the worker which reads the clock often enough for the forward
+// jump guard to work, and quarantines the node upon clock problems.
+func (m *clockMonitor) start(ctx context.Context, stopper *stop.Stopper) {
+	m.clock.SetJumpHandler(func(jump hlc.ClockJump) {
+		m.handleJump(ctx, jump)
+	})
+	stopper.RunWorker(ctx, func(ctx context.Context) {
+		var quarantined bool
+		// resyncAt is the time after which the clock offsets are checked to
+		// end the quarantine, if the action is resync.
+		var resyncAt time.Time
+		var timer timeutil.Timer
+		defer timer.Stop()
+		for {
+			guard := forwardClockJumpGuard.Get()
+			m.clock.SetForwardJumpGuard(guard)
+			interval := clockMonitorInterval
+			if guard > 0 && guard/4 < interval {
+				interval = guard / 4
+			}
+			// Reading the clock is what detects its jumps.
+			now := m.clock.PhysicalTime()
+
+			if quarantined && !resyncAt.IsZero() && now.After(resyncAt) {
+				if err := m.verifyOffset(ctx); err != nil {
+					log.Warningf(ctx, "clock still unreliable, remaining quarantined: %v", err)
+				} else {
+					log.Infof(ctx, "clock in sync with the cluster again; ending quarantine")
+					m.undrain()
+					m.metrics.Quarantined.Update(0)
+					quarantined = false
+					resyncAt = time.Time{}
+				}
+			}
+
+			timer.Reset(interval)
+			select {
+			case <-timer.C:
+				timer.Read = true
+			case err := <-m.problemC:
+				action := clockOffsetAction.Get()
+				if action == clockActionFatal {
+					log.Fatal(ctx, err)
+				}
+				if !quarantined {
+					log.Errorf(ctx, "%v; quarantining the node", err)
+					if err := m.drain(); err != nil {
+						log.Errorf(ctx, "failed to drain the node: %v", err)
+					}
+					m.metrics.Quarantined.Update(1)
+					quarantined = true
+				}
+				if action == clockActionResync {
+					// Timestamps up to the maximum offset in the future may have
+					// been handed out; wait for the other clocks to pass them.
+					resyncAt = m.clock.PhysicalTime().Add(m.clock.MaxOffset())
+				} else {
+					log.Errorf(ctx, "the node remains quarantined until it is restarted")
+					resyncAt = time.Time{}
+				}
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+)
+
+func TestClockMonitorResync(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer settings.TestingSetEnum(&clockOffsetAction, clockActionResync)()
+	defer settings.TestingSetDuration(&forwardClockJumpGuard, 100*time.Millisecond)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+
+	manual := hlc.NewManualClock(int64(time.Second))
+	clock := hlc.NewClock(manual.UnixNano, 500*time.Millisecond)
+
+	var drained, undrained, inSync int32
+	m := newClockMonitor(
+		clock,
+		func() error {
+			atomic.AddInt32(&drained, 1)
+			return nil
+		},
+		func() { atomic.AddInt32(&undrained, 1) },
+		func(context.Context) error {
+			if atomic.LoadInt32(&inSync) == 0 {
+				return errors.New("clock offset too large")
+			}
+			return nil
+		},
+	)
+	clock.SetForwardJumpGuard(100 * time.Millisecond)
+	clock.PhysicalNow()
+	m.start(context.Background(), stopper)
+
+	// A jump within the maximum offset is smoothed.
+	manual.Increment(int64(300 * time.Millisecond))
+	clock.PhysicalNow()
+	if c := m.metrics.JumpsSmoothed.Count(); c != 1 {
+		t.Fatalf("expected 1 smoothed jump, got %d", c)
+	}
+	if d := atomic.LoadInt32(&drained); d != 0 {
+		t.Fatalf("expected the node not to be drained, got %d drains", d)
+	}
+
+	// A jump beyond the maximum offset quarantines the node.
+	manual.Increment(int64(time.Second))
+	clock.PhysicalNow()
+	if c := m.metrics.JumpsForward.Count(); c != 2 {
+		t.Fatalf("expected 2 forward jumps, got %d", c)
+	}
+	testutils.SucceedsSoon(t, func() error {
+		if d := atomic.LoadInt32(&drained); d != 1 {
+			return errors.Errorf("expected the node to be drained once, got %d drains", d)
+		}
+		if q := m.metrics.Quarantined.Value(); q != 1 {
+			return errors.Errorf("expected the node to be quarantined, got %d", q)
+		}
+		return nil
+	})
+
+	// Once the maximum offset has passed and the clock is in sync again, the
+	// quarantine ends.
+	for i := 0; i < 20; i++ {
+		manual.Increment(int64(50 * time.Millisecond))
+		clock.PhysicalNow()
+	}
+	atomic.StoreInt32(&inSync, 1)
+	testutils.SucceedsSoon(t, func() error {
+		if u := atomic.LoadInt32(&undrained); u != 1 {
+			return errors.Errorf("expected the node to be undrained once, got %d", u)
+		}
+		if q := m.metrics.Quarantined.Value(); q != 0 {
+			return errors.Errorf("expected the quarantine to end, got %d", q)
+		}
+		return nil
+	})
+}
@@ -106,6 +106,11 @@ type Config struct {
 	// used by SQL clients to store row data in server RAM.
 	SQLMemoryPoolSize int64
 
+	// HeapProfileDirName is the directory in which heap profiles and goroutine
+	// dumps are written when the memory usage of the process gets high. If
+	// empty, no profiles are taken.
+	HeapProfileDirName string
+
 	// Parsed values.
 
 	// NodeAttributes is the parsed representation of Attrs.
@@ -200,6 +205,11 @@ type Config struct {
 	// it is ready.
 	PIDFile string
 
+	// StrictPreflight makes the node fail to start if one of the preflight
+	// checks fails, instead of only logging the problem. See
+	// RunPreflightChecks.
+	StrictPreflight bool
+
 	enginesCreated bool
 }
 
@@ -121,3 +121,25 @@ func setOpenFileLimitInner(physicalStoreCount int) (int, error) {
 	// used by the stores.
 	return int(rLimit.Cur-minimumNetworkFileDescriptors) / physicalStoreCount, nil
 }
+
+// checkOpenFileLimitInner returns warnings if the hard limit for open file
+// descriptors can't be raised to the recommended limit, and an error if it is
+// under the minimum. Unlike setOpenFileLimitInner, it doesn't change the
+// limits.
+func checkOpenFileLimitInner(physicalStoreCount int) ([]string, error) {
+	minimumOpenFileLimit := uint64(physicalStoreCount*engine.MinimumMaxOpenFiles + minimumNetworkFileDescriptors)
+	recommendedOpenFileLimit := uint64(physicalStoreCount*engine.RecommendedMaxOpenFiles + recommendedNetworkFileDescriptors)
+	var rLimit rlimit
+	if err := getRlimitNoFile(&rLimit); err != nil {
+		return []string{fmt.Sprintf("could not get the open file descriptor limits: %s", err)}, nil
+	}
+	if rLimit.Max < minimumOpenFileLimit {
+		return nil, fmt.Errorf("hard open file descriptor limit of %d is under the minimum required %d; %s",
+			rLimit.Max, minimumOpenFileLimit, productionSettingsWebpage)
+	}
+	if rLimit.Max < recommendedOpenFileLimit {
+		return []string{fmt.Sprintf("hard open file descriptor limit of %d is under the recommended limit %d; %s",
+			rLimit.Max, recommendedOpenFileLimit, productionSettingsWebpage)}, nil
+	}
+	return nil, nil
+}
@@ -21,3 +21,7 @@ import (
 func setOpenFileLimitInner(physicalStoreCount int) (int, error) {
 	return engine.DefaultMaxOpenFiles, nil
 }
+
+func checkOpenFileLimitInner(physicalStoreCount int) ([]string, error) {
+	return nil, nil
+}
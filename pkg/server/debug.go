@@ -137,6 +137,10 @@ func init() {
         <td>trace (local node only)</td>
         <td><a href="./requests">requests</a>, <a href="./events">events</a></td>
       </tr>
+      <tr>
+        <td>spans (local node only)</td>
+        <td><a href="./tracez">open and slow spans</a>, <a href="./tracez/ondemand">on-demand tracing</a>, <a href="./tracez/noop">noop spans</a></td>
+      </tr>
       <tr>
         <td>stopper</td>
         <td><a href="./stopper">active tasks</a></td>
@@ -197,6 +201,12 @@ func init() {
           get /debug/vmodule/<your_vmodule_here><br />For example, <code>*=1</code> or <code>raft=3,storage=2</code>. Empty string disables vmodule logging.
         </td>
       </tr>
+      <tr>
+        <td>reload logging config</td>
+        <td>
+          get <a href="./logconfig/reload">/debug/logconfig/reload</a><br />Reloads the file given by <code>--log-config-file</code>, as on SIGHUP.
+        </td>
+      </tr>
     </table>
   </body>
 </html>
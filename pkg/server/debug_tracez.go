@@ -0,0 +1,416 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+)
+
+// Returns an HTML page listing the spans currently open on this node, as well
+// as recently finished slow spans bucketed by latency.
+func (s *statusServer) handleDebugTracez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-type", "text/html")
+
+	tr, ok := s.Tracer.(*tracing.Tracer)
+	if !ok {
+		http.Error(w, "tracer does not support span tracking", http.StatusInternalServerError)
+		return
+	}
+
+	webData := tracezWebData{Active: makeTracezSpans(tr.ActiveSpans())}
+	for _, b := range tr.SlowSpans() {
+		webData.Slow = append(webData.Slow, tracezBucket{
+			MinDuration: b.MinDuration,
+			Spans:       makeTracezSpans(b.Spans),
+		})
+	}
+
+	t, err := template.New("webpage").Parse(debugTracezTemplate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := t.Execute(w, webData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Controls on-demand tracing and returns an HTML page with its status and the
+// recordings collected so far. POSTing a pattern (and optionally a duration, at
+// most maxOnDemandTracingDuration) starts recording the new spans whose
+// operation name matches the pattern; POSTing stop=true stops it. Passing
+// format=json returns the recordings as a JSON array instead, each encoded by
+// tracing.RecordingToJSON.
+func (s *statusServer) handleDebugTracezOnDemand(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-type", "text/html")
+
+	tr, ok := s.Tracer.(*tracing.Tracer)
+	if !ok {
+		http.Error(w, "tracer does not support on-demand tracing", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	if r.Method == http.MethodPost {
+		if pattern := r.PostFormValue("pattern"); pattern != "" {
+			duration := defaultOnDemandTracingDuration
+			if durationString := r.PostFormValue("duration"); durationString != "" {
+				var err error
+				if duration, err = time.ParseDuration(durationString); err != nil {
+					http.Error(w, fmt.Sprintf("invalid duration: %s", err), http.StatusBadRequest)
+					return
+				}
+			}
+			if duration > maxOnDemandTracingDuration {
+				duration = maxOnDemandTracingDuration
+			}
+			if err := tr.StartOnDemandTracing(pattern, duration); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		} else if r.PostFormValue("stop") == "true" {
+			tr.StopOnDemandTracing()
+		}
+	} else if query.Get("pattern") != "" || query.Get("stop") != "" {
+		http.Error(w, "on-demand tracing can only be started or stopped with a POST",
+			http.StatusMethodNotAllowed)
+		return
+	}
+
+	if query.Get("format") == "json" {
+		recs := tr.OnDemandRecordings()
+		encoded := make([]json.RawMessage, len(recs))
+		for i, rec := range recs {
+			var err error
+			if encoded[i], err = tracing.RecordingToJSON(rec); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		data, err := json.Marshal(encoded)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-type", "application/json")
+		if _, err := w.Write(data); err != nil {
+			log.Warningf(r.Context(), "failed to write recordings: %v", err)
+		}
+		return
+	}
+
+	webData := tracezOnDemandWebData{
+		Status:          tr.GetOnDemandTracingStatus(),
+		DefaultDuration: defaultOnDemandTracingDuration,
+		MaxDuration:     maxOnDemandTracingDuration,
+	}
+	for _, rec := range tr.OnDemandRecordings() {
+		webData.Recordings = append(webData.Recordings, tracing.FormatRecordedSpans(rec))
+	}
+
+	t, err := template.New("webpage").Parse(debugTracezOnDemandTemplate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := t.Execute(w, webData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Returns an HTML page with the number of noop spans started on this node for
+// each operation and the reason they are noop, which helps understand why the
+// traces of an operation are empty. Passing reset=true discards the counts
+// collected so far.
+func (s *statusServer) handleDebugTracezNoop(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-type", "text/html")
+
+	tr, ok := s.Tracer.(*tracing.Tracer)
+	if !ok {
+		http.Error(w, "tracer does not support noop span stats", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("reset") == "true" {
+		tr.ResetNoopSpanStats()
+	}
+
+	t, err := template.New("webpage").Parse(debugTracezNoopTemplate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := t.Execute(w, tr.NoopSpanStats()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// defaultOnDemandTracingDuration is used when on-demand tracing is started
+// without an explicit duration.
+const defaultOnDemandTracingDuration = time.Minute
+
+// maxOnDemandTracingDuration caps the duration of on-demand tracing, which
+// slows down the operations it records.
+const maxOnDemandTracingDuration = 10 * time.Minute
+
+type tracezOnDemandWebData struct {
+	Status          tracing.OnDemandTracingStatus
+	DefaultDuration time.Duration
+	MaxDuration     time.Duration
+	Recordings      []string
+}
+
+type tracezWebData struct {
+	Active []tracezSpan
+	Slow   []tracezBucket
+}
+
+type tracezBucket struct {
+	MinDuration time.Duration
+	Spans       []tracezSpan
+}
+
+type tracezSpan struct {
+	tracing.RecordedSpan
+	// Tags are sorted by key.
+	Tags []stringPair
+}
+
+func makeTracezSpans(spans []tracing.RecordedSpan) []tracezSpan {
+	result := make([]tracezSpan, len(spans))
+	for i, sp := range spans {
+		result[i].RecordedSpan = sp
+		for k, v := range sp.Tags {
+			result[i].Tags = append(result[i].Tags, stringPair{Key: k, Value: v})
+		}
+		sort.Slice(result[i].Tags, func(a, b int) bool {
+			return result[i].Tags[a].Key < result[i].Tags[b].Key
+		})
+	}
+	return result
+}
+
+const debugTracezTemplate = `
+<!DOCTYPE html>
+<HTML>
+  <HEAD>
+    <META CHARSET="UTF-8"/>
+    <TITLE>Spans</TITLE>
+    <STYLE>
+      body {
+        font-family: "Helvetica Neue", Helvetica, Arial;
+        font-size: 14px;
+        line-height: 20px;
+        font-weight: 400;
+        color: #3b3b3b;
+        -webkit-font-smoothing: antialiased;
+        font-smoothing: antialiased;
+        background-color: #e4e4e4;
+      }
+      .wrapper {
+        margin: 0 auto;
+        padding: 0 40px;
+      }
+      .table {
+        margin: 0 0 40px 0;
+        display: table;
+      }
+      .row {
+        display: table-row;
+        background-color: white;
+      }
+      .cell {
+        padding: 6px 12px;
+        display: table-cell;
+        height: 20px;
+        white-space: nowrap;
+        border-width: 1px 1px 0 0;
+        border-color: rgba(0, 0, 0, 0.1);
+        border-style: solid;
+      }
+      .cell.header {
+        font-weight: 900;
+        color: #ffffff;
+        background-color: #2980b9;
+      }
+    </STYLE>
+  </HEAD>
+  <BODY>
+    <DIV CLASS="wrapper">
+      <H1>Spans</H1>
+      <P>Spans are only tracked while the <CODE>trace.registry.enabled</CODE> cluster setting is set.</P>
+      {{- define "spans"}}
+      <DIV CLASS="table">
+        <DIV CLASS="row">
+          <DIV CLASS="cell header">Operation</DIV>
+          <DIV CLASS="cell header">Start</DIV>
+          <DIV CLASS="cell header">Duration</DIV>
+          <DIV CLASS="cell header">Trace ID</DIV>
+          <DIV CLASS="cell header">Span ID</DIV>
+          <DIV CLASS="cell header">Tags</DIV>
+        </DIV>
+        {{- range $_, $span := .}}
+        <DIV CLASS="row">
+          <DIV CLASS="cell">{{$span.Operation}}</DIV>
+          <DIV CLASS="cell">{{$span.StartTime.Format "2006-01-02 15:04:05.000000"}}</DIV>
+          <DIV CLASS="cell">{{$span.Duration}}</DIV>
+          <DIV CLASS="cell">{{printf "%x" $span.TraceID}}</DIV>
+          <DIV CLASS="cell">{{printf "%x" $span.SpanID}}</DIV>
+          <DIV CLASS="cell">
+            {{- range $_, $tag := $span.Tags}}{{$tag.Key}}={{$tag.Value}} {{end -}}
+          </DIV>
+        </DIV>
+        {{- end}}
+      </DIV>
+      {{- end}}
+      <H2>Active spans ({{len $.Active}})</H2>
+      {{template "spans" $.Active}}
+      {{- range $_, $bucket := $.Slow}}
+      <H2>Recently finished spans &ge; {{$bucket.MinDuration}} ({{len $bucket.Spans}})</H2>
+      {{template "spans" $bucket.Spans}}
+      {{- end}}
+    </DIV>
+  </BODY>
+</HTML>
+`
+
+const debugTracezOnDemandTemplate = `
+<!DOCTYPE html>
+<HTML>
+  <HEAD>
+    <META CHARSET="UTF-8"/>
+    <TITLE>On-demand tracing</TITLE>
+    <STYLE>
+      body {
+        font-family: "Helvetica Neue", Helvetica, Arial;
+        font-size: 14px;
+        line-height: 20px;
+        font-weight: 400;
+        color: #3b3b3b;
+        -webkit-font-smoothing: antialiased;
+        font-smoothing: antialiased;
+        background-color: #e4e4e4;
+      }
+      .wrapper {
+        margin: 0 auto;
+        padding: 0 40px;
+      }
+      pre {
+        padding: 6px 12px;
+        background-color: white;
+      }
+    </STYLE>
+  </HEAD>
+  <BODY>
+    <DIV CLASS="wrapper">
+      <H1>On-demand tracing</H1>
+      {{- if $.Status.Active}}
+      <FORM METHOD="POST">
+        Recording spans matching <CODE>{{$.Status.Pattern}}</CODE> until {{$.Status.Deadline.Format "2006-01-02 15:04:05"}}.
+        <INPUT TYPE="hidden" NAME="stop" VALUE="true"/>
+        <INPUT TYPE="submit" VALUE="Stop"/>
+      </FORM>
+      {{- else}}
+      <P>Not active.</P>
+      {{- end}}
+      <FORM METHOD="POST">
+        Operation pattern: <INPUT TYPE="text" NAME="pattern" VALUE="{{$.Status.Pattern}}"/>
+        Duration (at most {{$.MaxDuration}}): <INPUT TYPE="text" NAME="duration" VALUE="{{$.DefaultDuration}}"/>
+        <INPUT TYPE="submit" VALUE="Start"/>
+      </FORM>
+      <H2>Recordings ({{len $.Recordings}})</H2>
+      {{- range $_, $rec := $.Recordings}}
+      <PRE>{{$rec}}</PRE>
+      {{- end}}
+    </DIV>
+  </BODY>
+</HTML>
+`
+
+const debugTracezNoopTemplate = `
+<!DOCTYPE html>
+<HTML>
+  <HEAD>
+    <META CHARSET="UTF-8"/>
+    <TITLE>Noop spans</TITLE>
+    <STYLE>
+      body {
+        font-family: "Helvetica Neue", Helvetica, Arial;
+        font-size: 14px;
+        line-height: 20px;
+        font-weight: 400;
+        color: #3b3b3b;
+        -webkit-font-smoothing: antialiased;
+        font-smoothing: antialiased;
+        background-color: #e4e4e4;
+      }
+      .wrapper {
+        margin: 0 auto;
+        padding: 0 40px;
+      }
+      .table {
+        margin: 0 0 40px 0;
+        display: table;
+      }
+      .row {
+        display: table-row;
+        background-color: white;
+      }
+      .cell {
+        padding: 6px 12px;
+        display: table-cell;
+        height: 20px;
+        white-space: nowrap;
+        border-width: 1px 1px 0 0;
+        border-color: rgba(0, 0, 0, 0.1);
+        border-style: solid;
+      }
+      .cell.header {
+        font-weight: 900;
+        color: #ffffff;
+        background-color: #2980b9;
+      }
+    </STYLE>
+  </HEAD>
+  <BODY>
+    <DIV CLASS="wrapper">
+      <H1>Noop spans</H1>
+      <P>Noop spans are only counted while the <CODE>trace.debug.noop_stats.enabled</CODE> cluster setting is set. <A HREF="?reset=true">Reset</A></P>
+      <DIV CLASS="table">
+        <DIV CLASS="row">
+          <DIV CLASS="cell header">Operation</DIV>
+          <DIV CLASS="cell header">Reason</DIV>
+          <DIV CLASS="cell header">Count</DIV>
+        </DIV>
+        {{- range $_, $stat := .}}
+        <DIV CLASS="row">
+          <DIV CLASS="cell">{{$stat.Operation}}</DIV>
+          <DIV CLASS="cell">{{$stat.Reason}}</DIV>
+          <DIV CLASS="cell">{{$stat.Count}}</DIV>
+        </DIV>
+        {{- end}}
+      </DIV>
+    </DIV>
+  </BODY>
+</HTML>
+`
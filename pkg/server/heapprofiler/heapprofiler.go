@@ -0,0 +1,218 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package heapprofiler takes heap and goroutine profiles when the memory usage
+// of the process gets high, so that there are artifacts to look at even if the
+// process is eventually killed for running out of memory.
+package heapprofiler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+var rssFractions = settings.RegisterValidatedStringSetting(
+	"server.heap_profile.rss_fractions",
+	"comma-separated fractions of the system memory; a heap profile is taken "+
+		"when the RSS of the process grows past one of them (empty to disable)",
+	"0.5,0.75,0.9",
+	func(s string) error {
+		_, err := parseFractions(s)
+		return err
+	},
+)
+
+var minProfileInterval = settings.RegisterNonNegativeDurationSetting(
+	"server.heap_profile.min_interval",
+	"minimum time between two heap profiles taken because of high memory usage",
+	time.Minute,
+)
+
+var maxProfiles = settings.RegisterValidatedIntSetting(
+	"server.heap_profile.max_profiles",
+	"maximum number of heap profiles (and as many goroutine dumps) retained "+
+		"in the heap profile directory",
+	20,
+	func(v int64) error {
+		if v < 1 {
+			return errors.Errorf("cannot set to a value less than 1: %d", v)
+		}
+		return nil
+	},
+)
+
+const (
+	heapFilePrefix      = "memprof."
+	goroutineFilePrefix = "goroutines."
+	fileTimeFormat      = "2006-01-02T15_04_05.000"
+)
+
+// parseFractions parses a comma-separated list of fractions in (0, 1] and
+// returns them in increasing order.
+func parseFractions(s string) ([]float64, error) {
+	var fractions []float64
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid fraction %q", f)
+		}
+		if v <= 0 || v > 1 {
+			return nil, errors.Errorf("fraction %s is not in (0, 1]", f)
+		}
+		fractions = append(fractions, v)
+	}
+	sort.Float64s(fractions)
+	return fractions, nil
+}
+
+// HeapProfiler takes a heap profile and a goroutine dump whenever the RSS of
+// the process grows past one of the fractions of the system memory set by the
+// server.heap_profile.rss_fractions setting. Profiles are taken again when the
+// RSS drops below a fraction and grows past it again, but no more often than
+// server.heap_profile.min_interval. Only the most recent
+// server.heap_profile.max_profiles profiles are retained.
+//
+// HeapProfiler is not thread-safe; it is meant to be driven by a single
+// goroutine which periodically samples the RSS.
+type HeapProfiler struct {
+	dir         string
+	totalMemory int64
+
+	// level is the number of fractions that the RSS exceeded when it was last
+	// sampled.
+	level           int
+	lastProfileTime time.Time
+
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+// NewHeapProfiler creates a HeapProfiler which writes profiles to dir. The
+// directory is created if it doesn't exist. totalMemory is the amount of
+// memory available to the process.
+func NewHeapProfiler(dir string, totalMemory int64) (*HeapProfiler, error) {
+	if dir == "" {
+		return nil, errors.New("directory to store profiles could not be determined")
+	}
+	if totalMemory <= 0 {
+		return nil, errors.Errorf("invalid total memory %d", totalMemory)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &HeapProfiler{dir: dir, totalMemory: totalMemory, now: timeutil.Now}, nil
+}
+
+// MaybeTakeProfile is called with the current RSS of the process and takes
+// profiles if the RSS grew past one of the configured fractions of the system
+// memory since the last call.
+func (hp *HeapProfiler) MaybeTakeProfile(ctx context.Context, rss int64) {
+	fractions, err := parseFractions(rssFractions.Get())
+	if err != nil {
+		// The setting is validated, so this shouldn't happen.
+		log.Warningf(ctx, "invalid heap profile fractions: %s", err)
+		return
+	}
+	level := 0
+	for _, f := range fractions {
+		if float64(rss) <= f*float64(hp.totalMemory) {
+			break
+		}
+		level++
+	}
+	prevLevel := hp.level
+	hp.level = level
+	if level <= prevLevel {
+		return
+	}
+	now := hp.now()
+	if !hp.lastProfileTime.IsZero() && now.Sub(hp.lastProfileTime) < minProfileInterval.Get() {
+		// Leave the level unchanged so that the profile is taken by a later call
+		// if the RSS is still high.
+		hp.level = prevLevel
+		return
+	}
+	hp.lastProfileTime = now
+
+	log.Infof(ctx, "RSS %s exceeds %.0f%% of the system memory (%s); writing profiles to %s",
+		humanizeutil.IBytes(rss), fractions[level-1]*100, humanizeutil.IBytes(hp.totalMemory), hp.dir)
+	suffix := fmt.Sprintf("%s.%d", now.Format(fileTimeFormat), rss)
+	if err := writeProfile(filepath.Join(hp.dir, heapFilePrefix+suffix), "heap", 0); err != nil {
+		log.Warningf(ctx, "error writing heap profile: %s", err)
+	}
+	if err := writeProfile(filepath.Join(hp.dir, goroutineFilePrefix+suffix), "goroutine", 2); err != nil {
+		log.Warningf(ctx, "error writing goroutine dump: %s", err)
+	}
+	max := int(maxProfiles.Get())
+	gcProfiles(ctx, hp.dir, heapFilePrefix, max)
+	gcProfiles(ctx, hp.dir, goroutineFilePrefix, max)
+}
+
+// writeProfile writes the named pprof profile to path.
+func writeProfile(path string, profile string, debug int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pprof.Lookup(profile).WriteTo(f, debug); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// gcProfiles removes the oldest files with the given prefix in dir, so that at
+// most max of them are retained. The file names embed the time at which the
+// profile was taken, so they sort chronologically.
+func gcProfiles(ctx context.Context, dir, prefix string, max int) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Warningf(ctx, "%v", err)
+		return
+	}
+	var names []string
+	for _, f := range files {
+		if f.Mode().IsRegular() && strings.HasPrefix(f.Name(), prefix) {
+			names = append(names, f.Name())
+		}
+	}
+	if len(names) <= max {
+		return
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-max] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			log.Warningf(ctx, "%v", err)
+		}
+	}
+}
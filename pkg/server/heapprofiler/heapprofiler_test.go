@@ -0,0 +1,124 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package heapprofiler
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestParseFractions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		s        string
+		expected []float64
+		err      string
+	}{
+		{"", nil, ""},
+		{"0.9, 0.5,", []float64{0.5, 0.9}, ""},
+		{"1", []float64{1}, ""},
+		{"0", nil, "not in"},
+		{"1.5", nil, "not in"},
+		{"x", nil, "invalid fraction"},
+	}
+	for _, tc := range testCases {
+		fractions, err := parseFractions(tc.s)
+		if !testutils.IsError(err, tc.err) {
+			t.Errorf("%q: expected error %q, got %v", tc.s, tc.err, err)
+		} else if err == nil && !reflect.DeepEqual(fractions, tc.expected) {
+			t.Errorf("%q: expected %v, got %v", tc.s, tc.expected, fractions)
+		}
+	}
+}
+
+func TestHeapProfiler(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, cleanup := testutils.TempDir(t)
+	defer cleanup()
+	defer settings.TestingSetString(&rssFractions, "0.5,0.9")()
+	defer settings.TestingSetDuration(&minProfileInterval, time.Minute)()
+	defer settings.TestingSetInt(&maxProfiles, 2)()
+
+	const totalMemory = 1000
+	hp, err := NewHeapProfiler(dir, totalMemory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	hp.now = func() time.Time { return now }
+
+	countProfiles := func() int {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var heap, goroutine int
+		for _, f := range files {
+			switch {
+			case strings.HasPrefix(f.Name(), heapFilePrefix):
+				heap++
+			case strings.HasPrefix(f.Name(), goroutineFilePrefix):
+				goroutine++
+			}
+		}
+		if heap != goroutine {
+			t.Fatalf("%d heap profiles but %d goroutine dumps", heap, goroutine)
+		}
+		return heap
+	}
+
+	ctx := context.Background()
+	testCases := []struct {
+		advance  time.Duration
+		rss      int64
+		expected int
+	}{
+		// Below the lowest fraction.
+		{0, 400, 0},
+		// Crossing the first fraction.
+		{time.Second, 600, 1},
+		// Staying above it.
+		{time.Hour, 700, 1},
+		// Crossing the second fraction, too soon after the previous profile.
+		{time.Second, 950, 1},
+		// Still above it once the interval has elapsed.
+		{time.Minute, 950, 2},
+		// Dropping below the first fraction and crossing it again; only the two
+		// most recent profiles are retained.
+		{time.Hour, 100, 2},
+		{time.Hour, 600, 2},
+	}
+	for i, tc := range testCases {
+		now = now.Add(tc.advance)
+		hp.MaybeTakeProfile(ctx, tc.rss)
+		if n := countProfiles(); n != tc.expected {
+			t.Errorf("%d: expected %d profiles, got %d", i, tc.expected, n)
+		}
+	}
+	if hp.lastProfileTime != now {
+		t.Errorf("expected the last profile to be taken at %s, got %s", now, hp.lastProfileTime)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+)
+
+// IndexStats reports the MVCC stats of the keys of each table index. Each
+// range is accounted for by the node holding its lease, so that the stats
+// of a node can be summed with those of the others: without a node ID, the
+// request is sent to each live node. The ranges without a valid lease aren't
+// accounted for.
+func (s *statusServer) IndexStats(
+	ctx context.Context, req *serverpb.IndexStatsRequest,
+) (*serverpb.IndexStatsResponse, error) {
+	ctx = s.AnnotateCtx(ctx)
+
+	if len(req.NodeID) > 0 {
+		nodeID, local, err := s.parseNodeID(req.NodeID)
+		if err != nil {
+			return nil, grpc.Errorf(codes.InvalidArgument, err.Error())
+		}
+		if !local {
+			status, err := s.dialNode(nodeID)
+			if err != nil {
+				return nil, err
+			}
+			return status.IndexStats(ctx, req)
+		}
+
+		totals := make(indexStatsTotals)
+		if err := s.stores.VisitStores(func(store *storage.Store) error {
+			stats, err := store.ComputeIndexStats()
+			if err != nil {
+				return err
+			}
+			for _, is := range stats {
+				totals.add(serverpb.IndexStats{TableID: is.TableID, IndexID: is.IndexID, Stats: is.Stats})
+			}
+			return nil
+		}); err != nil {
+			return nil, grpc.Errorf(codes.Internal, err.Error())
+		}
+		return totals.response(), nil
+	}
+
+	type nodeResponse struct {
+		nodeID roachpb.NodeID
+		resp   *serverpb.IndexStatsResponse
+		err    error
+	}
+	var numNodes int
+	responses := make(chan nodeResponse)
+	nodeCtx, cancel := context.WithTimeout(ctx, base.NetworkTimeout)
+	defer cancel()
+	for nodeID, alive := range s.nodeLiveness.GetIsLiveMap() {
+		if !alive {
+			continue
+		}
+		numNodes++
+		nodeID := nodeID
+		if err := s.stopper.RunAsyncTask(
+			nodeCtx, "server.statusServer: requesting remote index stats",
+			func(ctx context.Context) {
+				status, err := s.dialNode(nodeID)
+				var resp *serverpb.IndexStatsResponse
+				if err == nil {
+					req := &serverpb.IndexStatsRequest{NodeID: fmt.Sprint(nodeID)}
+					resp, err = status.IndexStats(ctx, req)
+				}
+				select {
+				case responses <- nodeResponse{nodeID: nodeID, resp: resp, err: err}:
+					// Response processed.
+				case <-ctx.Done():
+					// Context completed, response no longer needed.
+				}
+			}); err != nil {
+			return nil, grpc.Errorf(codes.Internal, err.Error())
+		}
+	}
+
+	// The stats are summed over all the nodes: they'd be meaningless if a
+	// node was missing.
+	totals := make(indexStatsTotals)
+	for remainingResponses := numNodes; remainingResponses > 0; remainingResponses-- {
+		select {
+		case resp := <-responses:
+			if resp.err != nil {
+				return nil, grpc.Errorf(codes.Unavailable, "n%d: %s", resp.nodeID, resp.err)
+			}
+			for _, is := range resp.resp.Indexes {
+				totals.add(is)
+			}
+		case <-ctx.Done():
+			return nil, grpc.Errorf(codes.DeadlineExceeded, ctx.Err().Error())
+		}
+	}
+	return totals.response(), nil
+}
+
+type tableIndex struct {
+	tableID, indexID uint32
+}
+
+// indexStatsTotals sums up the stats of the table indexes.
+type indexStatsTotals map[tableIndex]*serverpb.IndexStats
+
+func (t indexStatsTotals) add(is serverpb.IndexStats) {
+	key := tableIndex{tableID: is.TableID, indexID: is.IndexID}
+	if total, ok := t[key]; ok {
+		total.Stats.Add(is.Stats)
+		return
+	}
+	t[key] = &is
+}
+
+// response returns the totals sorted by table and index ID.
+func (t indexStatsTotals) response() *serverpb.IndexStatsResponse {
+	resp := &serverpb.IndexStatsResponse{Indexes: make([]serverpb.IndexStats, 0, len(t))}
+	for _, is := range t {
+		resp.Indexes = append(resp.Indexes, *is)
+	}
+	sort.Slice(resp.Indexes, func(i, j int) bool {
+		if resp.Indexes[i].TableID != resp.Indexes[j].TableID {
+			return resp.Indexes[i].TableID < resp.Indexes[j].TableID
+		}
+		return resp.Indexes[i].IndexID < resp.Indexes[j].IndexID
+	})
+	return resp
+}
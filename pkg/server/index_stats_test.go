@@ -0,0 +1,58 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestIndexStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, rawDB, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+
+	db := sqlutils.MakeSQLRunner(t, rawDB)
+	db.Exec(`CREATE DATABASE t`)
+	db.Exec(`CREATE TABLE t.kv (k INT PRIMARY KEY, v INT, INDEX (v))`)
+	db.Exec(`INSERT INTO t.kv VALUES (1, 1), (2, 2), (3, 3)`)
+	var tableID uint32
+	db.QueryRow(`SELECT id FROM system.namespace WHERE name = 'kv'`).Scan(&tableID)
+
+	var resp serverpb.IndexStatsResponse
+	if err := getStatusJSONProto(s, "indexstats", &resp); err != nil {
+		t.Fatal(err)
+	}
+	liveCounts := make(map[uint32]int64)
+	for _, is := range resp.Indexes {
+		if is.TableID == tableID {
+			liveCounts[is.IndexID] = is.Stats.LiveCount
+		}
+	}
+	// The primary index and the secondary index each have a key per row.
+	for _, indexID := range []uint32{1, 2} {
+		if a, e := liveCounts[indexID], int64(3); a != e {
+			t.Errorf("index %d: expected %d live keys, got %d", indexID, e, a)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// livenessLogInterval is the interval at which the nodes record the changes
+// of the liveness statuses they observe.
+const livenessLogInterval = 10 * time.Second
+
+// A livenessLogger records the transitions between the liveness statuses of
+// the nodes in the system.livenesslog table, for uptime reporting. Every node
+// runs one, so that the transitions of a node get recorded while it's down.
+// The loggers don't coordinate: a transition is recorded by the first logger
+// to observe it, in a transaction which checks that the node's last recorded
+// status differs, and the other loggers find it recorded.
+type livenessLogger struct {
+	clock         *hlc.Clock
+	db            *client.DB
+	ex            sqlutil.InternalExecutor
+	nodeLiveness  *storage.NodeLiveness
+	timeUntilDead *settings.DurationSetting
+
+	// statuses are the last statuses recorded, or found recorded, for each
+	// node. They're only accessed by the logger's worker.
+	statuses map[roachpb.NodeID]string
+}
+
+func newLivenessLogger(
+	clock *hlc.Clock,
+	db *client.DB,
+	ex sqlutil.InternalExecutor,
+	nodeLiveness *storage.NodeLiveness,
+	timeUntilDead *settings.DurationSetting,
+) *livenessLogger {
+	return &livenessLogger{
+		clock:         clock,
+		db:            db,
+		ex:            ex,
+		nodeLiveness:  nodeLiveness,
+		timeUntilDead: timeUntilDead,
+		statuses:      make(map[roachpb.NodeID]string),
+	}
+}
+
+// start records the liveness statuses until the stopper is stopped.
+func (ll *livenessLogger) start(ctx context.Context, stopper *stop.Stopper) {
+	stopper.RunWorker(ctx, func(ctx context.Context) {
+		var timer timeutil.Timer
+		defer timer.Stop()
+		for {
+			ll.logStatuses(ctx)
+			timer.Reset(livenessLogInterval)
+			select {
+			case <-timer.C:
+				timer.Read = true
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// logStatuses records the liveness statuses which changed since the last
+// call.
+func (ll *livenessLogger) logStatuses(ctx context.Context) {
+	now := ll.clock.Now()
+	maxOffset := ll.clock.MaxOffset()
+	timeUntilDead := ll.timeUntilDead.Get()
+	for _, l := range ll.nodeLiveness.GetLivenesses() {
+		status := l.Status(now, maxOffset, timeUntilDead)
+		if ll.statuses[l.NodeID] == status {
+			continue
+		}
+		if err := ll.logStatus(ctx, l, status, now, maxOffset, timeUntilDead); err != nil {
+			log.Warningf(ctx, "unable to record the liveness status of node %d: %s", l.NodeID, err)
+			continue
+		}
+		ll.statuses[l.NodeID] = status
+	}
+}
+
+// logStatus records the given status of a node, unless it's already its last
+// recorded status. The transitions to suspect and dead are recorded at the
+// time they happened according to the liveness record, rather than at the
+// time they were observed. A node observed dead right after being recorded
+// live is recorded suspect in between.
+func (ll *livenessLogger) logStatus(
+	ctx context.Context,
+	l storage.Liveness,
+	status string,
+	now hlc.Timestamp,
+	maxOffset, timeUntilDead time.Duration,
+) error {
+	suspectAt := timeutil.Unix(0, l.Expiration.WallTime).Add(-maxOffset)
+	var ts time.Time
+	switch status {
+	case storage.LivenessStatusLive:
+		ts = timeutil.Unix(0, now.WallTime)
+	case storage.LivenessStatusSuspect:
+		ts = suspectAt
+	case storage.LivenessStatusDead:
+		ts = timeutil.Unix(0, l.Expiration.WallTime).Add(timeUntilDead)
+	}
+
+	return ll.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		const selectStmt = `SELECT status FROM system.livenesslog WHERE "nodeID" = $1
+ORDER BY timestamp DESC LIMIT 1`
+		row, err := ll.ex.QueryRowInTransaction(ctx, "liveness-log-select", txn, selectStmt, l.NodeID)
+		if err != nil {
+			return err
+		}
+		var last string
+		if row != nil {
+			last = string(*row[0].(*parser.DString))
+		}
+		if last == status {
+			return nil
+		}
+
+		const insertStmt = `INSERT INTO system.livenesslog ("nodeID", timestamp, status, epoch)
+VALUES ($1, $2, $3, $4)`
+		if status == storage.LivenessStatusDead && last == storage.LivenessStatusLive {
+			if _, err := ll.ex.ExecuteStatementInTransaction(ctx, "liveness-log-insert", txn,
+				insertStmt, l.NodeID, suspectAt, storage.LivenessStatusSuspect, l.Epoch); err != nil {
+				return err
+			}
+		}
+		_, err = ll.ex.ExecuteStatementInTransaction(ctx, "liveness-log-insert", txn,
+			insertStmt, l.NodeID, ts, status, l.Epoch)
+		return err
+	})
+}
+
+// A livenessLogEntry is a row of the system.livenesslog table.
+type livenessLogEntry struct {
+	nodeID    roachpb.NodeID
+	timestamp time.Time
+	status    string
+}
+
+// downtimeReport sums up, for each node, the time spent in each liveness
+// status between start and end. The entries must be sorted by node and
+// timestamp, and include the last entry of each node before start. The time
+// before the first entry of a node isn't accounted for.
+func downtimeReport(entries []livenessLogEntry, start, end time.Time) []serverpb.NodeDowntime {
+	var report []serverpb.NodeDowntime
+	for i, e := range entries {
+		first := i == 0 || entries[i-1].nodeID != e.nodeID
+		if first {
+			report = append(report, serverpb.NodeDowntime{NodeID: e.nodeID})
+		}
+		d := &report[len(report)-1]
+		if !first && entries[i-1].status == storage.LivenessStatusLive &&
+			e.status != storage.LivenessStatusLive &&
+			!e.timestamp.Before(start) && e.timestamp.Before(end) {
+			d.Outages++
+		}
+
+		// The status lasts until the next entry of the node, if any.
+		from, until := e.timestamp, end
+		if i+1 < len(entries) && entries[i+1].nodeID == e.nodeID && entries[i+1].timestamp.Before(end) {
+			until = entries[i+1].timestamp
+		}
+		if from.Before(start) {
+			from = start
+		}
+		if !from.Before(until) {
+			continue
+		}
+		nanos := until.Sub(from).Nanoseconds()
+		switch e.status {
+		case storage.LivenessStatusLive:
+			d.LiveNanos += nanos
+		case storage.LivenessStatusSuspect:
+			d.SuspectNanos += nanos
+		case storage.LivenessStatusDead:
+			d.DeadNanos += nanos
+		}
+	}
+	return report
+}
+
+// Downtime reports the time each node spent in each liveness status over the
+// requested period, as recorded in system.livenesslog.
+func (s *statusServer) Downtime(
+	ctx context.Context, req *serverpb.DowntimeRequest,
+) (*serverpb.DowntimeResponse, error) {
+	end := timeutil.Now()
+	if req.EndNanos != 0 {
+		end = timeutil.Unix(0, req.EndNanos)
+	}
+	start := timeutil.Unix(0, req.StartNanos)
+	if !start.Before(end) {
+		return nil, grpc.Errorf(codes.InvalidArgument, "start %s is not before end %s", start, end)
+	}
+
+	args := sql.SessionArgs{User: s.admin.getUser(req)}
+	ctx, session := s.admin.NewContextAndSessionForRPC(ctx, args)
+	defer session.Finish(s.admin.server.sqlExecutor)
+
+	// The entries before start are needed to know the status of the nodes at
+	// start.
+	q := makeSQLQuery()
+	q.Append(`SELECT "nodeID", timestamp, status FROM system.livenesslog `)
+	q.Append("WHERE timestamp < $ ", parser.MakeDTimestamp(end, time.Microsecond))
+	if req.NodeID > 0 {
+		q.Append(`AND "nodeID" = $ `, parser.NewDInt(parser.DInt(req.NodeID)))
+	}
+	q.Append(`ORDER BY "nodeID", timestamp`)
+	if len(q.Errors()) > 0 {
+		return nil, s.admin.serverErrors(q.Errors())
+	}
+	r := s.admin.server.sqlExecutor.ExecuteStatements(session, q.String(), q.QueryArguments())
+	defer r.Close(ctx)
+	if err := s.admin.checkQueryResults(r.ResultList, 1); err != nil {
+		return nil, s.admin.serverError(err)
+	}
+
+	var entries []livenessLogEntry
+	scanner := makeResultScanner(r.ResultList[0].Columns)
+	for i, nRows := 0, r.ResultList[0].Rows.Len(); i < nRows; i++ {
+		row := r.ResultList[0].Rows.At(i)
+		var e livenessLogEntry
+		var nodeID int64
+		if err := scanner.ScanIndex(row, 0, &nodeID); err != nil {
+			return nil, errors.Wrapf(err, "nodeID didn't parse correctly: %s", row[0])
+		}
+		e.nodeID = roachpb.NodeID(nodeID)
+		if err := scanner.ScanIndex(row, 1, &e.timestamp); err != nil {
+			return nil, errors.Wrapf(err, "timestamp didn't parse correctly: %s", row[1])
+		}
+		if err := scanner.ScanIndex(row, 2, &e.status); err != nil {
+			return nil, errors.Wrapf(err, "status didn't parse correctly: %s", row[2])
+		}
+		entries = append(entries, e)
+	}
+	return &serverpb.DowntimeResponse{Nodes: downtimeReport(entries, start, end)}, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestDowntimeReport(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const (
+		live    = storage.LivenessStatusLive
+		suspect = storage.LivenessStatusSuspect
+		dead    = storage.LivenessStatusDead
+	)
+	t0 := time.Unix(1000, 0)
+	at := func(secs int) time.Time { return t0.Add(time.Duration(secs) * time.Second) }
+	entries := []livenessLogEntry{
+		// Node 1 is live before the start, and has an outage within the period.
+		{1, at(-10), live},
+		{1, at(20), suspect},
+		{1, at(30), dead},
+		{1, at(60), live},
+		// Node 2 is dead until after the start, and live until the end.
+		{2, at(-5), dead},
+		{2, at(10), live},
+		// Node 3 appears in the middle of the period.
+		{3, at(50), live},
+	}
+	expected := []serverpb.NodeDowntime{
+		{NodeID: 1, LiveNanos: int64(60 * time.Second), SuspectNanos: int64(10 * time.Second),
+			DeadNanos: int64(30 * time.Second), Outages: 1},
+		{NodeID: 2, LiveNanos: int64(90 * time.Second), DeadNanos: int64(10 * time.Second)},
+		{NodeID: 3, LiveNanos: int64(50 * time.Second)},
+	}
+	if report := downtimeReport(entries, at(0), at(100)); !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %+v, got %+v", expected, report)
+	}
+
+	// An outage before the start isn't counted.
+	expected = []serverpb.NodeDowntime{
+		{NodeID: 1, LiveNanos: int64(40 * time.Second), DeadNanos: int64(25 * time.Second)},
+		{NodeID: 2, LiveNanos: int64(65 * time.Second)},
+		{NodeID: 3, LiveNanos: int64(50 * time.Second)},
+	}
+	if report := downtimeReport(entries, at(35), at(100)); !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %+v, got %+v", expected, report)
+	}
+}
+
+// TestDowntime verifies that the liveness of a node is recorded, and reported
+// by the /_status/downtime endpoint.
+func TestDowntime(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, _, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+
+	testutils.SucceedsSoon(t, func() error {
+		var resp serverpb.DowntimeResponse
+		if err := getStatusJSONProto(s, "downtime", &resp); err != nil {
+			return err
+		}
+		if len(resp.Nodes) != 1 {
+			return errors.Errorf("expected 1 node, got %+v", resp.Nodes)
+		}
+		if d := resp.Nodes[0]; d.NodeID != s.NodeID() || d.LiveNanos == 0 || d.DeadNanos != 0 {
+			return errors.Errorf("expected node %d to have been live, got %+v", s.NodeID(), d)
+		}
+		return nil
+	})
+}
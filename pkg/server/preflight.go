@@ -0,0 +1,229 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/build"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+const (
+	// slowStoreSyncThreshold is the latency of the write and sync of a small
+	// file in a store directory above which the store is reported as slow.
+	slowStoreSyncThreshold = 100 * time.Millisecond
+
+	// certificateExpirationWarning is how long before their expiration the
+	// certificates are reported as expiring.
+	certificateExpirationWarning = 30 * 24 * time.Hour
+)
+
+// A preflightCheck validates a part of the environment of a node before it
+// starts. It returns the problems which may degrade the node as warnings,
+// and the problems which are likely to prevent it from working as an error.
+type preflightCheck struct {
+	name string
+	run  func(ctx context.Context, cfg *Config) (warnings []string, err error)
+}
+
+var preflightChecks = []preflightCheck{
+	{"open-files", checkOpenFiles},
+	{"clock", checkClock},
+	{"stores", checkStores},
+	{"memory", checkMemory},
+	{"certificates", checkCertificates},
+}
+
+// RunPreflightChecks validates the environment of the node before it starts:
+// the open file descriptor limits, the sanity of the clock, the writability
+// and latency of the stores, the memory budgets and the certificates. The
+// problems found are logged, tagged with the name of the check which found
+// them. An error is returned only if cfg.StrictPreflight is set, as soon as
+// a check fails.
+func (cfg *Config) RunPreflightChecks(ctx context.Context) error {
+	for _, c := range preflightChecks {
+		ctx := log.WithLogTag(ctx, "preflight", c.name)
+		warnings, err := c.run(ctx, cfg)
+		for _, w := range warnings {
+			log.Warning(ctx, w)
+		}
+		if err != nil {
+			if cfg.StrictPreflight {
+				return errors.Wrapf(err, "preflight check %s failed", c.name)
+			}
+			log.Error(ctx, err)
+		}
+	}
+	return nil
+}
+
+func checkOpenFiles(_ context.Context, cfg *Config) ([]string, error) {
+	var physicalStores int
+	for _, spec := range cfg.Stores.Specs {
+		if !spec.InMemory {
+			physicalStores++
+		}
+	}
+	return checkOpenFileLimitInner(physicalStores)
+}
+
+// checkClock verifies that the wall clock isn't earlier than the time the
+// binary was built, which is a sure sign that it is wrong. The clock offsets
+// between the nodes are monitored once the node is running.
+func checkClock(_ context.Context, _ *Config) ([]string, error) {
+	buildTime, err := build.GetInfo().Timestamp()
+	if err != nil {
+		// Development builds have no build time.
+		return nil, nil
+	}
+	if now := timeutil.Now(); now.Unix() < buildTime {
+		return nil, errors.Errorf("the clock (%s) is earlier than the build time of the binary (%s)",
+			now.UTC().Format(build.TimeFormat), build.GetInfo().Time)
+	}
+	return nil, nil
+}
+
+// checkStores writes and syncs a small file in the directory of each
+// on-disk store.
+func checkStores(_ context.Context, cfg *Config) ([]string, error) {
+	var warnings []string
+	for _, spec := range cfg.Stores.Specs {
+		if spec.InMemory {
+			continue
+		}
+		latency, err := syncTestFile(spec.Path)
+		if err != nil {
+			return warnings, errors.Wrapf(err, "store %s is not writable", spec.Path)
+		}
+		if latency > slowStoreSyncThreshold {
+			warnings = append(warnings, fmt.Sprintf(
+				"writing and syncing a file in store %s took %s; the store may be too slow", spec.Path, latency))
+		}
+	}
+	return warnings, nil
+}
+
+// syncTestFile writes and syncs a temporary file in dir, which is created if
+// needed, and returns how long it took.
+func syncTestFile(dir string) (time.Duration, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	start := timeutil.Now()
+	f, err := ioutil.TempFile(dir, "preflight")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+	if _, err := f.Write(make([]byte, 4096)); err != nil {
+		_ = f.Close()
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	return timeutil.Since(start), nil
+}
+
+func checkMemory(ctx context.Context, cfg *Config) ([]string, error) {
+	totalMem, err := GetTotalMemory(ctx)
+	if err != nil {
+		return []string{fmt.Sprintf("unable to determine the available memory: %s", err)}, nil
+	}
+	return checkMemoryBudgets(cfg.CacheSize, cfg.SQLMemoryPoolSize, totalMem)
+}
+
+// checkMemoryBudgets verifies that the cache and the SQL memory pool fit in
+// the memory available to the process, as returned by GetTotalMemory, which
+// takes the cgroup limits into account.
+func checkMemoryBudgets(cacheSize, sqlMemoryPoolSize, totalMem int64) ([]string, error) {
+	budgets := cacheSize + sqlMemoryPoolSize
+	if budgets > totalMem {
+		return nil, errors.Errorf("--cache (%s) and --max-sql-memory (%s) exceed the available memory (%s)",
+			humanizeutil.IBytes(cacheSize), humanizeutil.IBytes(sqlMemoryPoolSize), humanizeutil.IBytes(totalMem))
+	}
+	// Leave room for the rest of the process, e.g. the Go heap.
+	if budgets > totalMem/4*3 {
+		return []string{fmt.Sprintf(
+			"--cache (%s) and --max-sql-memory (%s) use more than 3/4 of the available memory (%s); %s",
+			humanizeutil.IBytes(cacheSize), humanizeutil.IBytes(sqlMemoryPoolSize), humanizeutil.IBytes(totalMem),
+			productionSettingsWebpage)}, nil
+	}
+	return nil, nil
+}
+
+// checkCertificates verifies that the CA and node certificates can be
+// loaded and haven't expired, unless the node is insecure.
+func checkCertificates(_ context.Context, cfg *Config) ([]string, error) {
+	if cfg.Insecure {
+		return nil, nil
+	}
+	cm, err := cfg.GetCertificateManager()
+	if err != nil {
+		return nil, err
+	}
+	now := timeutil.Now()
+	var warnings []string
+	for _, c := range []struct {
+		name string
+		cert *security.CertInfo
+	}{
+		{"CA", cm.CACert()},
+		{"node", cm.NodeCert()},
+	} {
+		warning, err := checkCertificate(c.cert, now)
+		if err != nil {
+			return warnings, errors.Wrapf(err, "%s certificate", c.name)
+		}
+		if warning != "" {
+			warnings = append(warnings, fmt.Sprintf("%s certificate %s", c.name, warning))
+		}
+	}
+	return warnings, nil
+}
+
+// checkCertificate returns an error if the certificate is missing, invalid
+// or expired, and a warning if it expires soon.
+func checkCertificate(cert *security.CertInfo, now time.Time) (string, error) {
+	if cert == nil {
+		return "", errors.New("not found")
+	}
+	if cert.Error != nil {
+		return "", cert.Error
+	}
+	if !now.Before(cert.ExpirationTime) {
+		return "", errors.Errorf("%s expired on %s", cert.Filename, cert.ExpirationTime)
+	}
+	if remaining := cert.ExpirationTime.Sub(now); remaining < certificateExpirationWarning {
+		return fmt.Sprintf("%s expires on %s", cert.Filename, cert.ExpirationTime), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestPreflightMemoryBudgets(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const gib = 1 << 30
+	testCases := []struct {
+		cache, sqlMem, total int64
+		warning              bool
+		err                  string
+	}{
+		{1 * gib, 1 * gib, 4 * gib, false, ""},
+		{2 * gib, 2 * gib, 5 * gib, true, ""},
+		{2 * gib, 3 * gib, 4 * gib, false, "exceed the available memory"},
+	}
+	for _, tc := range testCases {
+		warnings, err := checkMemoryBudgets(tc.cache, tc.sqlMem, tc.total)
+		if !testutils.IsError(err, tc.err) {
+			t.Errorf("%+v: expected error %q, got %v", tc, tc.err, err)
+		}
+		if (len(warnings) > 0) != tc.warning {
+			t.Errorf("%+v: expected a warning: %t, got %q", tc, tc.warning, warnings)
+		}
+	}
+}
+
+func TestPreflightCertificate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	now := time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		cert    *security.CertInfo
+		warning bool
+		err     string
+	}{
+		{nil, false, "not found"},
+		{&security.CertInfo{Error: errors.New("bad permissions")}, false, "bad permissions"},
+		{&security.CertInfo{Filename: "node.crt", ExpirationTime: now}, false, "node.crt expired"},
+		{&security.CertInfo{Filename: "node.crt", ExpirationTime: now.Add(24 * time.Hour)}, true, ""},
+		{&security.CertInfo{Filename: "node.crt", ExpirationTime: now.AddDate(1, 0, 0)}, false, ""},
+	}
+	for i, tc := range testCases {
+		warning, err := checkCertificate(tc.cert, now)
+		if !testutils.IsError(err, tc.err) {
+			t.Errorf("%d: expected error %q, got %v", i, tc.err, err)
+		}
+		if (warning != "") != tc.warning {
+			t.Errorf("%d: expected a warning: %t, got %q", i, tc.warning, warning)
+		}
+	}
+}
+
+func TestPreflightStores(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, err := ioutil.TempDir("", "preflight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// The store directories are created if needed, and left empty.
+	storeDir := filepath.Join(dir, "store")
+	cfg := Config{Stores: base.StoreSpecList{Specs: []base.StoreSpec{
+		{InMemory: true},
+		{Path: storeDir},
+	}}}
+	if _, err := checkStores(context.Background(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if files, err := ioutil.ReadDir(storeDir); err != nil {
+		t.Fatal(err)
+	} else if len(files) != 0 {
+		t.Errorf("expected the store directory to be left empty, got %d files", len(files))
+	}
+
+	// A store whose path is a file isn't writable.
+	filePath := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(filePath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Stores.Specs = []base.StoreSpec{{Path: filePath}}
+	if _, err := checkStores(context.Background(), &cfg); !testutils.IsError(err, "is not writable") {
+		t.Errorf("expected a store which isn't writable, got %v", err)
+	}
+}
@@ -51,10 +51,12 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/rpc"
 	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/server/heapprofiler"
 	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
 	"github.com/cockroachdb/cockroach/pkg/server/status"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
+	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
 	"github.com/cockroachdb/cockroach/pkg/sql/mon"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire"
 	"github.com/cockroachdb/cockroach/pkg/storage"
@@ -119,6 +121,7 @@ type Server struct {
 	engines            Engines
 	internalMemMetrics sql.MemoryMetrics
 	adminMemMetrics    sql.MemoryMetrics
+	clockMonitor       *clockMonitor
 }
 
 // NewServer creates a Server from a server.Context.
@@ -139,6 +142,14 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 		registry: metric.NewRegistry(),
 	}
 
+	// Feed the per-operation latency histograms (if enabled through the
+	// trace.histograms.enabled setting) into the server's registry.
+	if tr, ok := s.cfg.AmbientCtx.Tracer.(*tracing.Tracer); ok {
+		tr.SetSpanDurationRecorder(metric.NewOperationLatencies(
+			s.registry, "trace.op", cfg.HistogramWindowInterval(),
+		))
+	}
+
 	// Attempt to load TLS configs right away, failures are permanent.
 	if certMgr, err := cfg.InitializeNodeTLSConfigs(stopper); err != nil {
 		return nil, err
@@ -164,9 +175,13 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 	ctx := s.AnnotateCtx(context.Background())
 
 	s.rpcContext = rpc.NewContext(s.cfg.AmbientCtx, s.cfg.Config, s.clock, s.stopper)
+	if s.cfg.TestingKnobs.RPC != nil {
+		s.rpcContext.Knobs = *s.cfg.TestingKnobs.RPC.(*rpc.ContextTestingKnobs)
+	}
+	s.clockMonitor = newServerClockMonitor(s)
 	s.rpcContext.HeartbeatCB = func() {
 		if err := s.rpcContext.RemoteClocks.VerifyClockOffset(ctx); err != nil {
-			log.Fatal(ctx, err)
+			s.clockMonitor.report(ctx, err)
 		}
 	}
 	s.grpc = rpc.NewServer(s.rpcContext)
@@ -271,6 +286,8 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 	distSQLMetrics := sql.MakeMemMetrics("distsql", cfg.HistogramWindowInterval())
 	s.registry.AddMetric(distSQLMetrics.CurBytesCount)
 	s.registry.AddMetric(distSQLMetrics.MaxBytesHist)
+	distSQLFlowControlMetrics := distsqlrun.MakeFlowControlMetrics()
+	s.registry.AddMetricStruct(distSQLFlowControlMetrics)
 
 	// Set up the DistSQL server.
 	distSQLCfg := distsqlrun.ServerConfig{
@@ -285,6 +302,7 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 		ParentMemoryMonitor: &rootSQLMemoryMonitor,
 		Counter:             distSQLMetrics.CurBytesCount,
 		Hist:                distSQLMetrics.MaxBytesHist,
+		FlowControlMetrics:  &distSQLFlowControlMetrics,
 	}
 	if s.cfg.TestingKnobs.DistSQL != nil {
 		distSQLCfg.TestingKnobs = *s.cfg.TestingKnobs.DistSQL.(*distsqlrun.TestingKnobs)
@@ -332,6 +350,7 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 
 	s.recorder = status.NewMetricsRecorder(s.clock, s.nodeLiveness, s.rpcContext.RemoteClocks, s.gossip)
 	s.registry.AddMetricStruct(s.rpcContext.RemoteClocks.Metrics())
+	s.registry.AddMetricStruct(s.clockMonitor.metrics)
 
 	s.runtime = status.MakeRuntimeStatSampler(s.clock)
 	s.registry.AddMetricStruct(s.runtime)
@@ -389,6 +408,11 @@ func NewServer(cfg Config, stopper *stop.Stopper) (*Server, error) {
 	} else {
 		execCfg.SchemaChangerTestingKnobs = &sql.SchemaChangerTestingKnobs{}
 	}
+	if s.cfg.TestingKnobs.SQLJobs != nil {
+		execCfg.JobsTestingKnobs = s.cfg.TestingKnobs.SQLJobs.(*jobs.TestingKnobs)
+	} else {
+		execCfg.JobsTestingKnobs = &jobs.TestingKnobs{}
+	}
 	s.sqlExecutor = sql.NewExecutor(execCfg, s.stopper)
 	s.registry.AddMetricStruct(s.sqlExecutor)
 
@@ -742,8 +766,10 @@ func (s *Server) Start(ctx context.Context) error {
 	if s.cfg.TestingKnobs.SQLSchemaChanger != nil {
 		testingKnobs = s.cfg.TestingKnobs.SQLSchemaChanger.(*sql.SchemaChangerTestingKnobs)
 	}
+	jobsKnobs, _ := s.cfg.TestingKnobs.SQLJobs.(*jobs.TestingKnobs)
 	sql.NewSchemaChangeManager(
 		testingKnobs,
+		jobsKnobs,
 		*s.db,
 		s.node.Descriptor,
 		s.rpcContext,
@@ -814,6 +840,9 @@ func (s *Server) Start(ctx context.Context) error {
 		})
 	})
 
+	// Begin watching the clock for jumps.
+	s.clockMonitor.start(ctx, s.stopper)
+
 	// Initialize grpc-gateway mux and context.
 	jsonpb := &protoutil.JSONPb{
 		EnumsAsInts:  true,
@@ -849,17 +878,26 @@ func (s *Server) Start(ctx context.Context) error {
 		AssetInfo: ui.AssetInfo,
 	}))
 
+	// The API and debug endpoints are served in server spans.
+	traced := tracing.HTTPMiddleware(s.cfg.AmbientCtx.Tracer)
+	handle := func(route string, h http.Handler) {
+		s.mux.Handle(route, traced(route, h))
+	}
+
 	// TODO(marc): when cookie-based authentication exists,
 	// apply it for all web endpoints.
-	s.mux.Handle(adminPrefix, gwMux)
-	s.mux.Handle(ts.URLPrefix, gwMux)
-	s.mux.Handle(statusPrefix, gwMux)
-	s.mux.Handle("/health", gwMux)
-	s.mux.Handle(statusVars, http.HandlerFunc(s.status.handleVars))
-	s.mux.Handle(rangeDebugEndpoint, authorizedHandler(http.HandlerFunc(s.status.handleDebugRange)))
-	s.mux.Handle(certificatesDebugEndpoint, authorizedHandler(http.HandlerFunc(s.status.handleDebugCertificates)))
-	s.mux.Handle(networkDebugEndpoint, authorizedHandler(http.HandlerFunc(s.status.handleDebugNetwork)))
-	s.mux.Handle(nodesDebugEndpoint, authorizedHandler(http.HandlerFunc(s.status.handleDebugNodes)))
+	handle(adminPrefix, gwMux)
+	handle(ts.URLPrefix, gwMux)
+	handle(statusPrefix, gwMux)
+	handle("/health", gwMux)
+	handle(statusVars, http.HandlerFunc(s.status.handleVars))
+	handle(rangeDebugEndpoint, authorizedHandler(http.HandlerFunc(s.status.handleDebugRange)))
+	handle(certificatesDebugEndpoint, authorizedHandler(http.HandlerFunc(s.status.handleDebugCertificates)))
+	handle(networkDebugEndpoint, authorizedHandler(http.HandlerFunc(s.status.handleDebugNetwork)))
+	handle(nodesDebugEndpoint, authorizedHandler(http.HandlerFunc(s.status.handleDebugNodes)))
+	handle(tracezDebugEndpoint, authorizedHandler(http.HandlerFunc(s.status.handleDebugTracez)))
+	handle(tracezOnDemandDebugEndpoint, authorizedHandler(http.HandlerFunc(s.status.handleDebugTracezOnDemand)))
+	handle(tracezNoopDebugEndpoint, authorizedHandler(http.HandlerFunc(s.status.handleDebugTracezNoop)))
 	log.Event(ctx, "added http endpoints")
 
 	// Before serving SQL requests, we have to make sure the database is
@@ -879,6 +917,19 @@ func (s *Server) Start(ctx context.Context) error {
 	// executes a SQL query, this must be done after the SQL layer is ready.
 	s.node.recordJoinEvent()
 
+	// Start firing scheduled jobs. Like the join event above, scheduled
+	// statements go through the SQL layer.
+	jobs.NewScheduler(
+		s.db, sql.InternalExecutor{LeaseManager: s.leaseMgr}, s.runScheduledStatement,
+		jobsKnobs,
+	).Start(ctx, s.stopper)
+
+	// Record the liveness transitions of the nodes for uptime reporting.
+	newLivenessLogger(
+		s.clock, s.db, sql.InternalExecutor{LeaseManager: s.leaseMgr}, s.nodeLiveness,
+		s.cfg.TimeUntilStoreDead,
+	).start(ctx, s.stopper)
+
 	if s.cfg.PIDFile != "" {
 		if err := ioutil.WriteFile(s.cfg.PIDFile, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644); err != nil {
 			log.Error(ctx, err)
@@ -904,6 +955,22 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
+// runScheduledStatement executes a statement fired by the jobs scheduler as
+// the schedule's owner.
+func (s *Server) runScheduledStatement(ctx context.Context, user string, stmt string) error {
+	session := sql.NewSession(ctx, sql.SessionArgs{User: user}, s.sqlExecutor, nil, &s.internalMemMetrics)
+	session.StartUnlimitedMonitor()
+	defer session.Finish(s.sqlExecutor)
+	r := s.sqlExecutor.ExecuteStatements(session, stmt, nil)
+	defer r.Close(ctx)
+	for _, res := range r.ResultList {
+		if res.Err != nil {
+			return res.Err
+		}
+	}
+	return nil
+}
+
 func (s *Server) doDrain(modes []serverpb.DrainMode, setTo bool) ([]serverpb.DrainMode, error) {
 	for _, mode := range modes {
 		switch mode {
@@ -960,10 +1027,21 @@ func (s *Server) Undrain(off []serverpb.DrainMode) []serverpb.DrainMode {
 }
 
 // startSampleEnvironment begins a worker that periodically instructs the
-// runtime stat sampler to sample the environment.
+// runtime stat sampler to sample the environment. If a heap profile directory
+// is configured, heap profiles are also taken when the RSS gets high.
 func (s *Server) startSampleEnvironment(frequency time.Duration) {
 	// Immediately record summaries once on server startup.
 	ctx := s.AnnotateCtx(context.Background())
+	var heapProfiler *heapprofiler.HeapProfiler
+	if s.cfg.HeapProfileDirName != "" {
+		if totalMemory, err := GetTotalMemory(ctx); err != nil {
+			log.Warningf(ctx, "not taking heap profiles: %s", err)
+		} else if heapProfiler, err = heapprofiler.NewHeapProfiler(
+			s.cfg.HeapProfileDirName, totalMemory,
+		); err != nil {
+			log.Warningf(ctx, "not taking heap profiles: %s", err)
+		}
+	}
 	s.stopper.RunWorker(ctx, func(ctx context.Context) {
 		ticker := time.NewTicker(frequency)
 		defer ticker.Stop()
@@ -971,6 +1049,9 @@ func (s *Server) startSampleEnvironment(frequency time.Duration) {
 			select {
 			case <-ticker.C:
 				s.runtime.SampleEnvironment(ctx)
+				if heapProfiler != nil {
+					heapProfiler.MaybeTakeProfile(ctx, s.runtime.Rss.Value())
+				}
 			case <-s.stopper.ShouldStop():
 				return
 			}
@@ -33,6 +33,8 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 
 	"github.com/cockroachdb/cockroach/pkg/base"
 	"github.com/cockroachdb/cockroach/pkg/build"
@@ -47,6 +49,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/server/status"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/grpcutil"
 	"github.com/cockroachdb/cockroach/pkg/util/httputil"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
@@ -81,6 +84,18 @@ const (
 	// and their statuses.
 	nodesDebugEndpoint = "/debug/nodes"
 
+	// tracezDebugEndpoint exposes an html page with the spans currently open
+	// on the node and recently finished slow spans.
+	tracezDebugEndpoint = "/debug/tracez"
+
+	// tracezOnDemandDebugEndpoint exposes an html page which controls
+	// on-demand tracing and shows the collected recordings.
+	tracezOnDemandDebugEndpoint = "/debug/tracez/ondemand"
+
+	// tracezNoopDebugEndpoint exposes an html page with the number of noop
+	// spans started for each operation and the reason they are noop.
+	tracezNoopDebugEndpoint = "/debug/tracez/noop"
+
 	// raftStateDormant is used when there is no known raft state.
 	raftStateDormant = "StateDormant"
 
@@ -898,6 +913,59 @@ func (s *statusServer) ListSessions(
 	return &resp, nil
 }
 
+// CancelQuery cancels a query running on the given node; the request is
+// forwarded to that node if it isn't this one.
+func (s *statusServer) CancelQuery(
+	ctx context.Context, req *serverpb.CancelQueryRequest,
+) (*serverpb.CancelQueryResponse, error) {
+	ctx = s.AnnotateCtx(ctx)
+	// The username of the request is filled in by the node which runs the
+	// CANCEL QUERY statement for the user of its session, so it can only be
+	// trusted when the request comes from a node.
+	if err := checkNodeUser(ctx); err != nil {
+		return nil, grpc.Errorf(codes.PermissionDenied, err.Error())
+	}
+	nodeID, local, err := s.parseNodeID(req.NodeID)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	if !local {
+		status, err := s.dialNode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return status.CancelQuery(ctx, req)
+	}
+
+	output := &serverpb.CancelQueryResponse{}
+	output.Canceled, err = s.sessionRegistry.CancelQuery(req.QueryID, req.Username)
+	if err != nil {
+		output.Error = err.Error()
+	}
+	return output, nil
+}
+
+// checkNodeUser returns an error unless the request of ctx is an in-process
+// request or was made with the certificate of the node user.
+func checkNodeUser(ctx context.Context) error {
+	if grpcutil.IsLocalRequestContext(ctx) {
+		return nil
+	}
+	if peer, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := peer.AuthInfo.(credentials.TLSInfo); ok {
+			certUser, err := security.GetCertificateUser(&tlsInfo.State)
+			if err != nil {
+				return err
+			}
+			if certUser != security.NodeUser {
+				return errors.Errorf("user %s is not allowed", certUser)
+			}
+		}
+	}
+	return nil
+}
+
 // SpanStats requests the total statistics stored on a node for a given key
 // span, which may include multiple ranges.
 func (s *statusServer) SpanStats(
@@ -310,6 +310,11 @@ func (ts *TestServer) Start(params base.TestServerArgs) error {
 		stk.DisableSplitQueue {
 		return nil
 	}
+	// A server restarted on the stores of a previous one is part of a cluster
+	// which completed its initial splits, and may have split further since.
+	if !ts.InitialBoot() {
+		return nil
+	}
 	if err := ts.WaitForInitialSplits(); err != nil {
 		ts.Stop()
 		return err
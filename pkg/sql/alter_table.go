@@ -40,7 +40,7 @@ type alterTableNode struct {
 //   notes: postgres requires CREATE on the table.
 //          mysql requires ALTER, CREATE, INSERT on the table.
 func (p *planner) AlterTable(ctx context.Context, n *parser.AlterTable) (planNode, error) {
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.normalizeTableName(ctx, &n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -149,7 +149,7 @@ func (n *alterTableNode) Start(ctx context.Context) error {
 				descriptorChanged = true
 
 			case *parser.ForeignKeyConstraintTableDef:
-				if _, err := d.Table.NormalizeWithDatabaseName(n.p.session.Database); err != nil {
+				if _, err := n.p.normalizeTableName(ctx, &d.Table); err != nil {
 					return err
 				}
 				affected := make(map[sqlbase.ID]*sqlbase.TableDescriptor)
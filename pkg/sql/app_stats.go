@@ -103,15 +103,14 @@ func (a *appStats) recordStatement(
 		return
 	}
 
-	// Extend the statement key with a character that indicated whether
-	// there was an error and/or whether the query was distributed, so
-	// that we use separate buckets for the different situations.
-	var buf bytes.Buffer
-
-	parser.FormatNode(&buf, parser.FmtHideConstants, stmt.AST)
+	// The statements are identified by their fingerprint. Extend the
+	// statement key with a character that indicated whether there was an
+	// error and/or whether the query was distributed, so that we use
+	// separate buckets for the different situations.
+	key := stmtKey{stmt: parser.Fingerprint(stmt.AST), failed: err != nil, distSQLUsed: distSQLUsed}
 
 	// Get the statistics object.
-	s := a.getStatsForStmt(stmtKey{stmt: buf.String(), failed: err != nil, distSQLUsed: distSQLUsed})
+	s := a.getStatsForStmt(key)
 
 	// Collect the per-statement statistics.
 	s.Lock()
@@ -285,3 +285,78 @@ func TestAsOfRetry(t *testing.T) {
 		t.Fatalf("unexpected val: %v", i)
 	}
 }
+
+func TestAsOfTransaction(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	s, db, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop(context.TODO())
+	// The transactions are driven through explicit statements, which must all
+	// use the same connection.
+	db.SetMaxOpenConns(1)
+
+	const val1 = 1
+	const val2 = 2
+
+	if _, err := db.Exec(`
+		CREATE DATABASE d;
+		CREATE TABLE d.t (a INT);
+	`); err != nil {
+		t.Fatal(err)
+	}
+	var tsVal1 string
+	if err := db.QueryRow(
+		"INSERT INTO d.t (a) VALUES ($1) RETURNING cluster_logical_timestamp()", val1,
+	).Scan(&tsVal1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("UPDATE d.t SET a = $1", val2); err != nil {
+		t.Fatal(err)
+	}
+
+	var i int
+	for _, begin := range []string{
+		fmt.Sprintf("BEGIN AS OF SYSTEM TIME %s", tsVal1),
+		fmt.Sprintf("BEGIN; SET TRANSACTION AS OF SYSTEM TIME %s", tsVal1),
+	} {
+		if _, err := db.Exec(begin); err != nil {
+			t.Fatal(err)
+		}
+		// Every read in the transaction sees the data as of the timestamp.
+		for j := 0; j < 2; j++ {
+			if err := db.QueryRow("SELECT a FROM d.t").Scan(&i); err != nil {
+				t.Fatal(err)
+			} else if i != val1 {
+				t.Fatalf("%s: expected %v, got %v", begin, val1, i)
+			}
+		}
+		// Writes are rejected.
+		if _, err := db.Exec("INSERT INTO d.t (a) VALUES (3)"); !testutils.IsError(
+			err, "cannot execute INSERT in a transaction using AS OF SYSTEM TIME",
+		) {
+			t.Fatalf("%s: unexpected error: %v", begin, err)
+		}
+		if _, err := db.Exec("ROLLBACK"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The timestamp cannot be set once the transaction has read data.
+	if _, err := db.Exec("BEGIN"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT a FROM d.t").Scan(&i); err != nil {
+		t.Fatal(err)
+	} else if i != val2 {
+		t.Fatalf("expected %v, got %v", val2, i)
+	}
+	if _, err := db.Exec(fmt.Sprintf("SET TRANSACTION AS OF SYSTEM TIME %s", tsVal1)); !testutils.IsError(
+		err, "AS OF SYSTEM TIME must be specified before any statement in the transaction",
+	) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := db.Exec("ROLLBACK"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -24,12 +24,10 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
-	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
-	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 )
 
 const (
@@ -108,11 +106,7 @@ func (sc *SchemaChanger) runBackfill(
 
 	// Mutations are applied in a FIFO order. Only apply the first set of
 	// mutations. Collect the elements that are part of the mutation.
-	var droppedIndexDescs []sqlbase.IndexDescriptor
 	var addedIndexDescs []sqlbase.IndexDescriptor
-	// Indexes within the Mutations slice for checkpointing.
-	mutationSentinel := -1
-	var droppedIndexMutationIdx int
 
 	var tableDesc *sqlbase.TableDescriptor
 	if err := sc.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
@@ -132,7 +126,7 @@ func (sc *SchemaChanger) runBackfill(
 		tableDesc.Name, tableDesc.Version, sc.mutationID)
 
 	needColumnBackfill := false
-	for i, m := range tableDesc.Mutations {
+	for _, m := range tableDesc.Mutations {
 		if m.MutationID != sc.mutationID {
 			break
 		}
@@ -151,28 +145,19 @@ func (sc *SchemaChanger) runBackfill(
 			}
 
 		case sqlbase.DescriptorMutation_DROP:
-			switch t := m.Descriptor_.(type) {
+			switch m.Descriptor_.(type) {
 			case *sqlbase.DescriptorMutation_Column:
 				needColumnBackfill = true
 			case *sqlbase.DescriptorMutation_Index:
-				droppedIndexDescs = append(droppedIndexDescs, *t.Index)
-				if droppedIndexMutationIdx == mutationSentinel {
-					droppedIndexMutationIdx = i
-				}
+				// The data of the index is deleted by its GC job, once the
+				// mutation is done.
 			default:
 				return errors.Errorf("unsupported mutation: %+v", m)
 			}
 		}
 	}
 
-	// First drop indexes, then add/drop columns, and only then add indexes.
-
-	// Drop indexes.
-	if err := sc.truncateIndexes(
-		ctx, lease, version, droppedIndexDescs, droppedIndexMutationIdx,
-	); err != nil {
-		return err
-	}
+	// First add/drop columns, and only then add indexes.
 
 	// Add and drop columns.
 	if needColumnBackfill {
@@ -191,47 +176,6 @@ func (sc *SchemaChanger) runBackfill(
 	return nil
 }
 
-func (sc *SchemaChanger) maybeWriteResumeSpan(
-	ctx context.Context,
-	txn *client.Txn,
-	version sqlbase.DescriptorVersion,
-	resume roachpb.Span,
-	mutationIdx int,
-	lastCheckpoint *time.Time,
-) error {
-	checkpointInterval := checkpointInterval
-	if sc.testingKnobs.WriteCheckpointInterval > 0 {
-		checkpointInterval = sc.testingKnobs.WriteCheckpointInterval
-	}
-	if timeutil.Since(*lastCheckpoint) < checkpointInterval {
-		return nil
-	}
-	tableDesc, err := sqlbase.GetTableDescFromID(ctx, txn, sc.tableID)
-	if err != nil {
-		return err
-	}
-	if tableDesc.Version != version {
-		return errors.Errorf("table version mismatch: %d, expected: %d", tableDesc.Version, version)
-	}
-	if len(tableDesc.Mutations[mutationIdx].ResumeSpans) > 0 {
-		tableDesc.Mutations[mutationIdx].ResumeSpans[0] = resume
-	} else {
-		tableDesc.Mutations[mutationIdx].ResumeSpans = append(tableDesc.Mutations[mutationIdx].ResumeSpans, resume)
-	}
-	if err := txn.SetSystemConfigTrigger(); err != nil {
-		return err
-	}
-	if err := txn.Put(
-		ctx,
-		sqlbase.MakeDescMetadataKey(tableDesc.GetID()),
-		sqlbase.WrapDescriptor(tableDesc),
-	); err != nil {
-		return err
-	}
-	*lastCheckpoint = timeutil.Now()
-	return nil
-}
-
 func (sc *SchemaChanger) getTableLease(
 	ctx context.Context, txn *client.Txn, lc *LeaseCollection, version sqlbase.DescriptorVersion,
 ) (*sqlbase.TableDescriptor, error) {
@@ -245,80 +189,6 @@ func (sc *SchemaChanger) getTableLease(
 	return tableDesc, nil
 }
 
-func (sc *SchemaChanger) truncateIndexes(
-	ctx context.Context,
-	lease *sqlbase.TableDescriptor_SchemaChangeLease,
-	version sqlbase.DescriptorVersion,
-	dropped []sqlbase.IndexDescriptor,
-	mutationIdx int,
-) error {
-	chunkSize := sc.getChunkSize(indexTruncateChunkSize)
-	if sc.testingKnobs.BackfillChunkSize > 0 {
-		chunkSize = sc.testingKnobs.BackfillChunkSize
-	}
-	for _, desc := range dropped {
-		var resume roachpb.Span
-		lastCheckpoint := timeutil.Now()
-		for row, done := int64(0), false; !done; row += chunkSize {
-			// First extend the schema change lease.
-			if err := sc.ExtendLease(ctx, lease); err != nil {
-				return err
-			}
-
-			resumeAt := resume
-			if log.V(2) {
-				log.Infof(ctx, "drop index (%d, %d) at row: %d, span: %s",
-					sc.tableID, sc.mutationID, row, resume)
-			}
-			if err := sc.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
-				if sc.testingKnobs.RunBeforeBackfillChunk != nil {
-					if err := sc.testingKnobs.RunBeforeBackfillChunk(resume); err != nil {
-						return err
-					}
-				}
-				if sc.testingKnobs.RunAfterBackfillChunk != nil {
-					defer sc.testingKnobs.RunAfterBackfillChunk()
-				}
-
-				// TODO(vivek): See comment in backfillIndexesChunk.
-				if err := txn.SetSystemConfigTrigger(); err != nil {
-					return err
-				}
-
-				lc := &LeaseCollection{leaseMgr: sc.leaseMgr}
-				defer lc.releaseLeases(ctx)
-				tableDesc, err := sc.getTableLease(ctx, txn, lc, version)
-				if err != nil {
-					return err
-				}
-
-				rd, err := sqlbase.MakeRowDeleter(txn, tableDesc, nil, nil, false)
-				if err != nil {
-					return err
-				}
-				td := tableDeleter{rd: rd}
-				if err := td.init(txn); err != nil {
-					return err
-				}
-				resume, err = td.deleteIndex(
-					ctx, &desc, resumeAt, chunkSize,
-				)
-				if err != nil {
-					return err
-				}
-				if err := sc.maybeWriteResumeSpan(ctx, txn, version, resume, mutationIdx, &lastCheckpoint); err != nil {
-					return err
-				}
-				done = resume.Key == nil
-				return nil
-			}); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
 type backfillType int
 
 const (
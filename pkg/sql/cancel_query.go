@@ -0,0 +1,59 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+)
+
+// CancelQuery cancels a query running on any node of the cluster: the ID of
+// the query identifies its gateway node, to which the request is forwarded.
+// Privileges: None; users other than root can only cancel their own queries.
+func (p *planner) CancelQuery(ctx context.Context, n *parser.CancelQuery) (planNode, error) {
+	typedID, err := p.TypeAsString(n.ID, "CANCEL QUERY")
+	if err != nil {
+		return nil, err
+	}
+	queryID, err := typedID()
+	if err != nil {
+		return nil, err
+	}
+	nodeID, err := nodeIDFromQueryID(queryID)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := p.session.execCfg.StatusServer.CancelQuery(ctx, &serverpb.CancelQueryRequest{
+		NodeID:   fmt.Sprintf("%d", nodeID),
+		QueryID:  queryID,
+		Username: p.session.User,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !response.Canceled {
+		if response.Error != "" {
+			return nil, errors.Errorf("could not cancel query %s: %s", queryID, response.Error)
+		}
+		return nil, errors.Errorf("could not cancel query %s: query not found", queryID)
+	}
+	return &emptyNode{}, nil
+}
@@ -26,9 +26,12 @@ import (
 
 	"github.com/cockroachdb/cockroach/pkg/build"
 	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/storage"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 )
 
 var crdbInternal = virtualSchema{
@@ -41,6 +44,9 @@ var crdbInternal = virtualSchema{
 		crdbInternalStmtStatsTable,
 		crdbInternalJobsTable,
 		crdbInternalSessionTraceTable,
+		crdbInternalGCProgressTable,
+		crdbInternalKVTableLoadTable,
+		crdbInternalIndexMVCCStatsTable,
 	},
 }
 
@@ -487,3 +493,164 @@ CREATE TABLE crdb_internal.session_trace(
 		return nil
 	},
 }
+
+// crdbInternalGCProgressTable exposes the progress of garbage collection on
+// the replicas stored on this node.
+var crdbInternalGCProgressTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.gc_progress (
+  range_id                    INT NOT NULL,
+  store_id                    INT NOT NULL,
+  start_key                   STRING NOT NULL,
+  end_key                     STRING NOT NULL,
+  ttl_seconds                 INT NOT NULL,     -- The shortest GC TTL of the range's
+                                                -- zone and its index overrides.
+  gc_threshold                TIMESTAMP,        -- Versions older than this have been
+                                                -- collected. NULL if GC never ran.
+  oldest_live_data            TIMESTAMP,        -- The oldest timestamp at which the data
+                                                -- must remain readable, per the TTL.
+                                                -- NULL if the zone is never collected.
+  gc_bytes                    INT NOT NULL,     -- The size of the non-live data.
+  estimated_reclaimable_bytes INT NOT NULL      -- The estimated size of the non-live
+                                                -- data older than the TTL.
+);
+`,
+	populate: func(ctx context.Context, p *planner, addRow func(...parser.Datum) error) error {
+		if err := p.RequireSuperUser("access GC progress"); err != nil {
+			return err
+		}
+		sysCfg, ok := p.session.execCfg.Gossip.GetSystemConfig()
+		if !ok {
+			return errors.New("system config not yet available")
+		}
+		resp, err := p.session.execCfg.StatusServer.Ranges(ctx, &serverpb.RangesRequest{NodeId: "local"})
+		if err != nil {
+			return err
+		}
+		now := p.session.execCfg.Clock.Now()
+		for _, r := range resp.Ranges {
+			state := &r.State.ReplicaState
+			if state.Desc == nil {
+				continue
+			}
+			zone, err := sysCfg.GetZoneConfigForKey(state.Desc.StartKey)
+			if err != nil {
+				return err
+			}
+			// The versions of the indexes with the shortest TTL bound how far
+			// back the range remains readable and how much of it can be collected.
+			ttlSeconds := zone.MinGCTTLSeconds()
+
+			gcThreshold := parser.DNull
+			if state.GCThreshold != (hlc.Timestamp{}) {
+				gcThreshold = parser.MakeDTimestamp(
+					time.Unix(0, state.GCThreshold.WallTime), time.Microsecond)
+			}
+			oldestLiveData := parser.DNull
+			if ttlSeconds > 0 {
+				oldestLiveData = parser.MakeDTimestamp(
+					time.Unix(0, now.WallTime).Add(-time.Duration(ttlSeconds)*time.Second), time.Microsecond)
+			}
+			if err := addRow(
+				parser.NewDInt(parser.DInt(state.Desc.RangeID)),
+				parser.NewDInt(parser.DInt(r.SourceStoreID)),
+				parser.NewDString(r.Span.StartKey),
+				parser.NewDString(r.Span.EndKey),
+				parser.NewDInt(parser.DInt(ttlSeconds)),
+				gcThreshold,
+				oldestLiveData,
+				parser.NewDInt(parser.DInt(state.Stats.GCBytes())),
+				parser.NewDInt(parser.DInt(
+					storage.EstimateReclaimableBytes(state.Stats, now.WallTime, ttlSeconds))),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var crdbInternalKVTableLoadTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.node_kv_table_load (
+  node_id        INT NOT NULL,
+  table_id       INT NOT NULL,
+  index_id       INT NOT NULL,    -- 0 for the batches addressed to the start of
+                                  -- the table rather than to an index.
+  read_batches   INT NOT NULL,    -- The sampled read-only batches.
+  write_batches  INT NOT NULL,    -- The other sampled batches.
+  request_bytes  INT NOT NULL,
+  response_bytes INT NOT NULL,
+  latency_avg    FLOAT NOT NULL   -- In seconds.
+);
+`,
+	populate: func(_ context.Context, p *planner, addRow func(...parser.Datum) error) error {
+		if err := p.RequireSuperUser("access KV load statistics"); err != nil {
+			return err
+		}
+		ds := p.session.execCfg.DistSender
+		if ds == nil {
+			return errors.New("cannot access KV load statistics from this context")
+		}
+		nodeID := parser.NewDInt(parser.DInt(int64(p.session.execCfg.NodeID.Get())))
+		for _, load := range ds.TableLoads() {
+			batches := load.ReadBatches + load.WriteBatches
+			if err := addRow(
+				nodeID,
+				parser.NewDInt(parser.DInt(load.TableID)),
+				parser.NewDInt(parser.DInt(load.IndexID)),
+				parser.NewDInt(parser.DInt(load.ReadBatches)),
+				parser.NewDInt(parser.DInt(load.WriteBatches)),
+				parser.NewDInt(parser.DInt(load.RequestBytes)),
+				parser.NewDInt(parser.DInt(load.ResponseBytes)),
+				parser.NewDFloat(parser.DFloat(load.Latency.Seconds()/float64(batches))),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// crdbInternalIndexMVCCStatsTable exposes the MVCC stats of the keys of each
+// table index, across the cluster.
+var crdbInternalIndexMVCCStatsTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.index_mvcc_stats (
+  table_id      INT NOT NULL,
+  index_id      INT NOT NULL,
+  live_bytes    INT NOT NULL,   -- The size of the live keys and values.
+  live_count    INT NOT NULL,
+  key_bytes     INT NOT NULL,   -- The size of all the keys, live or not.
+  val_bytes     INT NOT NULL,   -- The size of all the values, live or not.
+  garbage_bytes INT NOT NULL,   -- The size of the non-live data.
+  intent_count  INT NOT NULL,
+  intent_bytes  INT NOT NULL
+);
+`,
+	populate: func(ctx context.Context, p *planner, addRow func(...parser.Datum) error) error {
+		if err := p.RequireSuperUser("access index MVCC stats"); err != nil {
+			return err
+		}
+		resp, err := p.session.execCfg.StatusServer.IndexStats(ctx, &serverpb.IndexStatsRequest{})
+		if err != nil {
+			return err
+		}
+		for _, is := range resp.Indexes {
+			if err := addRow(
+				parser.NewDInt(parser.DInt(is.TableID)),
+				parser.NewDInt(parser.DInt(is.IndexID)),
+				parser.NewDInt(parser.DInt(is.Stats.LiveBytes)),
+				parser.NewDInt(parser.DInt(is.Stats.LiveCount)),
+				parser.NewDInt(parser.DInt(is.Stats.KeyBytes)),
+				parser.NewDInt(parser.DInt(is.Stats.ValBytes)),
+				parser.NewDInt(parser.DInt(is.Stats.GCBytes())),
+				parser.NewDInt(parser.DInt(is.Stats.IntentCount)),
+				parser.NewDInt(parser.DInt(is.Stats.IntentBytes)),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
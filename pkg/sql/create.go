@@ -134,7 +134,7 @@ type createIndexNode struct {
 //   notes: postgres requires CREATE on the table.
 //          mysql requires INDEX on the table.
 func (p *planner) CreateIndex(ctx context.Context, n *parser.CreateIndex) (planNode, error) {
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.normalizeTableName(ctx, &n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -342,7 +342,7 @@ type createViewNode struct {
 //						selected columns.
 //          mysql requires CREATE VIEW plus SELECT on all the selected columns.
 func (p *planner) CreateView(ctx context.Context, n *parser.CreateView) (planNode, error) {
-	name, err := n.Name.NormalizeWithDatabaseName(p.session.Database)
+	name, err := p.normalizeNewTableName(&n.Name)
 	if err != nil {
 		return nil, err
 	}
@@ -516,7 +516,7 @@ type createTableNode struct {
 // Privileges: CREATE on database.
 //   Notes: postgres/mysql require CREATE on database.
 func (p *planner) CreateTable(ctx context.Context, n *parser.CreateTable) (planNode, error) {
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.normalizeNewTableName(&n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -534,11 +534,16 @@ func (p *planner) CreateTable(ctx context.Context, n *parser.CreateTable) (planN
 	for _, def := range n.Defs {
 		switch t := def.(type) {
 		case *parser.ForeignKeyConstraintTableDef:
-			if _, err := t.Table.NormalizeWithDatabaseName(p.session.Database); err != nil {
+			if _, err := p.normalizeTableName(ctx, &t.Table); err != nil {
 				return nil, err
 			}
 		}
 	}
+	if n.Interleave != nil {
+		if _, err := p.normalizeTableName(ctx, &n.Interleave.Parent); err != nil {
+			return nil, err
+		}
+	}
 
 	var sourcePlan planNode
 	if n.As() {
@@ -1005,6 +1010,9 @@ func (p *planner) addInterleave(
 	index *sqlbase.IndexDescriptor,
 	interleave *parser.InterleaveDef,
 ) error {
+	if _, err := p.normalizeTableName(ctx, &interleave.Parent); err != nil {
+		return err
+	}
 	return addInterleave(ctx, p.txn, &p.session.virtualSchemas, desc, index, interleave, p.session.Database)
 }
 
@@ -426,21 +426,6 @@ func (p *planner) getDataSource(
 	}
 }
 
-func (p *planner) QualifyWithDatabase(
-	ctx context.Context, t *parser.NormalizableTableName,
-) (*parser.TableName, error) {
-	tn, err := t.Normalize()
-	if err != nil {
-		return nil, err
-	}
-	if tn.DatabaseName == "" {
-		if err := p.searchAndQualifyDatabase(ctx, tn); err != nil {
-			return nil, err
-		}
-	}
-	return tn, nil
-}
-
 func (p *planner) getTableScanByRef(
 	ctx context.Context,
 	tref *parser.TableRef,
@@ -48,7 +48,7 @@ type deleteNode struct {
 func (p *planner) Delete(
 	ctx context.Context, n *parser.Delete, desiredTypes []parser.Type,
 ) (planNode, error) {
-	tn, err := p.getAliasedTableName(n.Table)
+	tn, err := p.getAliasedTableName(ctx, n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -146,6 +146,7 @@ func (d *deleteNode) Next(ctx context.Context) (bool, error) {
 	if !next {
 		if err == nil {
 			// We're done. Finish the batch.
+			d.p.maybeRefreshLeaseDeadline(ctx)
 			err = d.tw.finalize(ctx)
 		}
 		return false, err
@@ -206,6 +207,7 @@ func (d *deleteNode) fastDelete(ctx context.Context, scan *scanNode) error {
 	if err := d.tw.init(d.p.txn); err != nil {
 		return err
 	}
+	d.p.maybeRefreshLeaseDeadline(ctx)
 	rowCount, err := d.tw.fastDelete(ctx, scan)
 	if err != nil {
 		return err
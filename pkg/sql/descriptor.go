@@ -236,9 +236,10 @@ func getAllDescriptors(ctx context.Context, txn *client.Txn) ([]sqlbase.Descript
 }
 
 // getDescriptorsFromTargetList fetches the descriptors for the targets.
-func getDescriptorsFromTargetList(
-	ctx context.Context, txn *client.Txn, vt VirtualTabler, db string, targets parser.TargetList,
+func (p *planner) getDescriptorsFromTargetList(
+	ctx context.Context, targets parser.TargetList,
 ) ([]sqlbase.DescriptorProto, error) {
+	txn, vt := p.txn, p.getVirtualTabler()
 	if targets.Databases != nil {
 		if len(targets.Databases) == 0 {
 			return nil, errNoDatabase
@@ -263,7 +264,7 @@ func getDescriptorsFromTargetList(
 		if err != nil {
 			return nil, err
 		}
-		tables, err := expandTableGlob(ctx, txn, vt, db, tableGlob)
+		tables, err := p.expandTableGlob(ctx, tableGlob)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,85 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestDiscardRows(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	s, db, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop(context.TODO())
+
+	// The session variables are per connection.
+	db.SetMaxOpenConns(1)
+	sqlDB := sqlutils.MakeSQLRunner(t, db)
+	sqlDB.Exec(`CREATE DATABASE d; CREATE TABLE d.t (a INT PRIMARY KEY)`)
+	sqlDB.Exec(`INSERT INTO d.t SELECT * FROM generate_series(1, 100)`)
+
+	sqlDB.Exec(`SET discard_rows = on`)
+	for _, mode := range []string{"off", "on"} {
+		t.Run("distsql="+mode, func(t *testing.T) {
+			sqlDB := sqlutils.MakeSQLRunner(t, db)
+			sqlDB.Exec(`SET distsql = ` + mode)
+
+			rows := sqlDB.Query(`SELECT * FROM d.t WHERE a > 10`)
+			defer rows.Close()
+			cols, err := rows.Columns()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cols) != 2 || cols[0] != "rows" || cols[1] != "execution_time" {
+				t.Fatalf("unexpected columns %v", cols)
+			}
+			var n int
+			for rows.Next() {
+				var count int
+				var execTime string
+				if err := rows.Scan(&count, &execTime); err != nil {
+					t.Fatal(err)
+				}
+				if count != 90 {
+					t.Errorf("expected 90 rows, got %d", count)
+				}
+				n++
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if n != 1 {
+				t.Errorf("expected a single row, got %d", n)
+			}
+		})
+	}
+
+	// Statements which don't return rows are unaffected.
+	sqlDB.ExecRowsAffected(10, `UPDATE d.t SET a = a + 1000 WHERE a <= 10`)
+
+	sqlDB.Exec(`SET discard_rows = off`)
+	var count int
+	sqlDB.QueryRow(`SELECT COUNT(*) FROM d.t`).Scan(&count)
+	if count != 100 {
+		t.Errorf("expected 100 rows, got %d", count)
+	}
+}
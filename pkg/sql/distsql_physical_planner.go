@@ -86,6 +86,12 @@ var logPlanDiagram = envutil.EnvOrDefaultBool("COCKROACH_DISTSQL_LOG_PLAN", fals
 // reader.
 var distributeIndexJoin = envutil.EnvOrDefaultBool("COCKROACH_DISTSQL_DISTRIBUTE_INDEX_JOIN", true)
 
+// If true, plans which are not recommended for distribution (see
+// checkSupportForNode) are planned entirely on the gateway node, which reads
+// the ranges owned by other nodes through the KV layer instead of setting up
+// remote flows (see planningCtx.isLocal).
+var planLocalWhenNotRecommended = envutil.EnvOrDefaultBool("COCKROACH_DISTSQL_PLAN_LOCAL", false)
+
 func newDistSQLPlanner(
 	nodeDesc roachpb.NodeDescriptor,
 	rpcCtx *rpc.Context,
@@ -323,6 +329,11 @@ type planningCtx struct {
 	// physicalPlan we generate with this context.
 	// Nodes that fail a health check have empty addresses.
 	nodeAddresses map[roachpb.NodeID]string
+
+	// isLocal is a planner hint: if set, all the spans are assigned to the
+	// gateway node, so that all the processors are planned on it and the plan
+	// doesn't involve any remote flows.
+	isLocal bool
 }
 
 // physicalPlan is a partial physical plan which corresponds to a planNode
@@ -389,6 +400,9 @@ func (dsp *distSQLPlanner) partitionSpans(
 	if len(spans) == 0 {
 		panic("no spans")
 	}
+	if planCtx.isLocal {
+		return []spanPartition{{node: dsp.nodeDesc.NodeID, spans: spans}}, nil
+	}
 	ctx := planCtx.ctx
 	partitions := make([]spanPartition, 0, 1)
 	// nodeMap maps a nodeID to an index inside the partitions array.
@@ -1600,6 +1614,33 @@ func (dsp *distSQLPlanner) NewPlanningCtx(ctx context.Context, txn *client.Txn)
 	return planCtx
 }
 
+// setLocalHint sets the isLocal hint of the planning context if the plan tree is
+// not recommended for distribution and planLocalWhenNotRecommended is set.
+func (dsp *distSQLPlanner) setLocalHint(planCtx *planningCtx, node planNode) error {
+	if !planLocalWhenNotRecommended {
+		return nil
+	}
+	rec, err := dsp.checkSupportForNode(node)
+	if err != nil {
+		return err
+	}
+	planCtx.isLocal = rec != shouldDistribute
+	return nil
+}
+
+// isLocalPlan returns true if all the processors of the plan are on the
+// gateway node, either because of the isLocal hint or because all the data
+// the plan reads is owned by the gateway. Such a plan is run as a single flow
+// on the gateway, without any remote flows or streams.
+func (dsp *distSQLPlanner) isLocalPlan(plan *physicalPlan) bool {
+	for i := range plan.Processors {
+		if plan.Processors[i].Node != dsp.nodeDesc.NodeID {
+			return false
+		}
+	}
+	return true
+}
+
 // FinalizePlan adds a final "result" stage if necessary and populates the
 // endpoints of the plan.
 func (dsp *distSQLPlanner) FinalizePlan(planCtx *planningCtx, plan *physicalPlan) {
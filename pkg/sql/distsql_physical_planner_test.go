@@ -637,6 +637,8 @@ func TestPartitionSpans(t *testing.T) {
 		deadNodes []int
 
 		gatewayNode int
+		// isLocal is the planner hint set in the planning context.
+		isLocal bool
 
 		// spans to be passed to partitionSpans
 		spans [][2]string
@@ -722,6 +724,18 @@ func TestPartitionSpans(t *testing.T) {
 				3: {{"A1", "B"}, {"C", "C1"}, {"D1", "X"}},
 			},
 		},
+
+		{
+			ranges:      []testSpanResolverRange{{"A", 1}, {"B", 2}, {"C", 1}, {"D", 3}},
+			gatewayNode: 2,
+			isLocal:     true,
+
+			spans: [][2]string{{"A1", "C1"}, {"D1", "X"}},
+
+			partitions: map[int][][2]string{
+				2: {{"A1", "C1"}, {"D1", "X"}},
+			},
+		},
 	}
 
 	for testIdx, tc := range testCases {
@@ -757,6 +771,7 @@ func TestPartitionSpans(t *testing.T) {
 			}
 
 			planCtx := dsp.NewPlanningCtx(context.Background(), nil /* txn */)
+			planCtx.isLocal = tc.isLocal
 			var spans []roachpb.Span
 			for _, s := range tc.spans {
 				spans = append(spans, roachpb.Span{Key: roachpb.Key(s[0]), EndKey: roachpb.Key(s[1])})
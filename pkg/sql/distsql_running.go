@@ -119,7 +119,11 @@ func (dsp *distSQLPlanner) Run(
 		}
 	}
 
-	log.VEvent(ctx, 1, "running DistSQL plan")
+	if dsp.isLocalPlan(plan) {
+		log.VEvent(ctx, 1, "running DistSQL plan locally")
+	} else {
+		log.VEvent(ctx, 1, "running DistSQL plan")
+	}
 
 	recv.resultToStreamColMap = plan.planToStreamColMap
 	thisNodeID := dsp.nodeDesc.NodeID
@@ -390,6 +394,9 @@ func (dsp *distSQLPlanner) PlanAndRun(
 	evalCtx parser.EvalContext,
 ) error {
 	planCtx := dsp.NewPlanningCtx(ctx, txn)
+	if err := dsp.setLocalHint(&planCtx, tree); err != nil {
+		return err
+	}
 
 	log.VEvent(ctx, 1, "creating DistSQL plan")
 
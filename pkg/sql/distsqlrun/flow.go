@@ -68,6 +68,9 @@ type FlowCtx struct {
 	// run.
 	nodeID       roachpb.NodeID
 	testingKnobs TestingKnobs
+	// flowControlMetrics, if set, records the time the outboxes of the flow
+	// spent waiting for their consumers.
+	flowControlMetrics *FlowControlMetrics
 }
 
 func (flowCtx *FlowCtx) setupTxn() *client.Txn {
@@ -0,0 +1,204 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// The streams between flows are flow controlled by their consumer: the
+// producer announces in its header that it obeys the flow control, and the
+// consumer grants it a window of flowControlWindowSize bytes. The producer
+// charges each message it sends against the bytes it was granted and, once
+// they are exhausted, waits before sending more. The consumer grants the
+// bytes of the messages it has pushed to their destination back to the
+// producer, so that a slow consumer stops the producer instead of letting
+// the messages pile up in the gRPC buffers of both nodes.
+//
+// A producer doesn't wait until it has received its first window: a consumer
+// which doesn't grant any (because flow control is disabled on its node, or
+// because it predates flow control) doesn't block it. The messages sent before
+// the first window are charged against it.
+
+// flowControlWindowSize is the number of bytes that the producer of a stream
+// between two flows can send beyond those that the consumer has pushed to
+// their destination.
+var flowControlWindowSize = settings.RegisterByteSizeSetting(
+	"sql.distsql.flow_control.window_size",
+	"maximum number of bytes buffered on a DistSQL stream between its producer and "+
+		"its consumer (set to 0 to disable flow control)",
+	1<<20)
+
+// FlowControlMetrics records the time the producers of the streams between
+// flows spent waiting for their consumer to grant them more bytes.
+type FlowControlMetrics struct {
+	BlockedCount *metric.Counter
+	BlockedNanos *metric.Counter
+}
+
+// MetricStruct implements the metric.Struct interface.
+func (FlowControlMetrics) MetricStruct() {}
+
+var _ metric.Struct = FlowControlMetrics{}
+
+var (
+	metaFlowControlBlockedCount = metric.Metadata{
+		Name: "sql.distsql.flow_control.blocked",
+		Help: "Number of times a DistSQL stream waited for its consumer to grant it more bytes"}
+	metaFlowControlBlockedNanos = metric.Metadata{
+		Name: "sql.distsql.flow_control.blocked_nanos",
+		Help: "Time DistSQL streams spent waiting for their consumer to grant them more bytes"}
+)
+
+// MakeFlowControlMetrics instantiates the flow control metrics.
+func MakeFlowControlMetrics() FlowControlMetrics {
+	return FlowControlMetrics{
+		BlockedCount: metric.NewCounter(metaFlowControlBlockedCount),
+		BlockedNanos: metric.NewCounter(metaFlowControlBlockedNanos),
+	}
+}
+
+// outboxFlowControl keeps track of the bytes that the consumer of an outbox
+// granted it.
+type outboxFlowControl struct {
+	// metrics is nil if the outbox doesn't record its blocked time.
+	metrics *FlowControlMetrics
+	// granted is signaled when the consumer grants more bytes.
+	granted chan struct{}
+	// closed is closed once the consumer can't grant any more bytes, i.e. when
+	// the stream is done.
+	closed chan struct{}
+
+	mu struct {
+		syncutil.Mutex
+		// enabled is set once the consumer has granted the first window.
+		enabled bool
+		// credit is the number of bytes that the outbox can still send. It goes
+		// negative when a message is larger than the remaining credit, or when
+		// messages are sent before the first window.
+		credit int64
+	}
+}
+
+func newOutboxFlowControl(metrics *FlowControlMetrics) *outboxFlowControl {
+	return &outboxFlowControl{
+		metrics: metrics,
+		granted: make(chan struct{}, 1),
+		closed:  make(chan struct{}),
+	}
+}
+
+// grant adds bytes to the credit of the outbox, and enables the flow control
+// if it wasn't yet.
+func (fc *outboxFlowControl) grant(bytes int64) {
+	fc.mu.Lock()
+	fc.mu.enabled = true
+	fc.mu.credit += bytes
+	fc.mu.Unlock()
+	select {
+	case fc.granted <- struct{}{}:
+	default:
+		// A signal is already pending.
+	}
+}
+
+// consume charges a message of the given size against the credit.
+func (fc *outboxFlowControl) consume(bytes int64) {
+	fc.mu.Lock()
+	fc.mu.credit -= bytes
+	fc.mu.Unlock()
+}
+
+// close is called when the stream is done.
+func (fc *outboxFlowControl) close() {
+	close(fc.closed)
+}
+
+func (fc *outboxFlowControl) blocked() bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.mu.enabled && fc.mu.credit <= 0
+}
+
+// waitForCredit blocks until the outbox has credit left, or the stream is
+// done (in which case the next send fails), or ctx is canceled.
+func (fc *outboxFlowControl) waitForCredit(ctx context.Context) error {
+	if !fc.blocked() {
+		return nil
+	}
+	start := timeutil.Now()
+	defer func() {
+		if fc.metrics != nil {
+			fc.metrics.BlockedCount.Inc(1)
+			fc.metrics.BlockedNanos.Inc(timeutil.Since(start).Nanoseconds())
+		}
+	}()
+	for fc.blocked() {
+		select {
+		case <-fc.granted:
+		case <-fc.closed:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// inboundFlowControl keeps track of the bytes that the consumer of a stream
+// has pushed to their destination and not yet granted back to the producer.
+type inboundFlowControl struct {
+	// window is the size of the window granted to the producer, or 0 if flow
+	// control is disabled on the stream.
+	window int64
+	// consumed is the number of bytes pushed since the last grant.
+	consumed int64
+}
+
+// start returns the first window to grant to the producer, or nil if the
+// stream isn't flow controlled.
+func (fc *inboundFlowControl) start(hdr *ProducerHeader) *WindowUpdate {
+	if !hdr.FlowControl {
+		return nil
+	}
+	fc.window = flowControlWindowSize.Get()
+	if fc.window <= 0 {
+		fc.window = 0
+		return nil
+	}
+	return &WindowUpdate{Bytes: fc.window}
+}
+
+// consume records that a message of the given size was pushed to its
+// destination and returns the grant to send to the producer, or nil if none
+// needs to be sent yet. The bytes are granted back once they add up to a
+// quarter of the window, to limit the number of signals.
+func (fc *inboundFlowControl) consume(bytes int64) *WindowUpdate {
+	if fc.window == 0 {
+		return nil
+	}
+	fc.consumed += bytes
+	if fc.consumed < fc.window/4 {
+		return nil
+	}
+	update := &WindowUpdate{Bytes: fc.consumed}
+	fc.consumed = 0
+	return update
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsqlrun
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestOutboxFlowControl(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	metrics := MakeFlowControlMetrics()
+	fc := newOutboxFlowControl(&metrics)
+	ctx := context.Background()
+
+	// The outbox doesn't wait until it has received the first window, but the
+	// bytes it sends are charged against it.
+	fc.consume(100)
+	if fc.blocked() {
+		t.Fatal("expected the outbox not to wait before the first window")
+	}
+	fc.grant(50)
+	if !fc.blocked() {
+		t.Fatal("expected the outbox to wait once it sent more than its window")
+	}
+
+	waitC := make(chan error)
+	go func() {
+		waitC <- fc.waitForCredit(ctx)
+	}()
+	fc.grant(30)
+	select {
+	case err := <-waitC:
+		t.Fatalf("expected the outbox to wait for more credit, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	fc.grant(30)
+	if err := <-waitC; err != nil {
+		t.Fatal(err)
+	}
+	if a, e := metrics.BlockedCount.Count(), int64(1); a != e {
+		t.Errorf("expected %d blocked outboxes, got %d", e, a)
+	}
+
+	fc.consume(20)
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := fc.waitForCredit(cancelCtx); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// Once the stream is done, the outbox stops waiting: its next send fails.
+	go func() {
+		waitC <- fc.waitForCredit(ctx)
+	}()
+	fc.close()
+	if err := <-waitC; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInboundFlowControl(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	defer settings.TestingSetByteSize(&flowControlWindowSize, 100)()
+
+	var fc inboundFlowControl
+	if update := fc.start(&ProducerHeader{}); update != nil {
+		t.Fatalf("expected no window for a producer without flow control, got %v", update)
+	}
+	if update := fc.consume(1000); update != nil {
+		t.Fatalf("expected no grant for a producer without flow control, got %v", update)
+	}
+
+	fc = inboundFlowControl{}
+	if update := fc.start(&ProducerHeader{FlowControl: true}); update == nil || update.Bytes != 100 {
+		t.Fatalf("expected a window of 100 bytes, got %v", update)
+	}
+	// The bytes are granted back once they add up to a quarter of the window.
+	for i, tc := range []struct {
+		consumed int64
+		granted  int64
+	}{
+		{10, 0},
+		{10, 0},
+		{10, 30},
+		{60, 60},
+		{24, 0},
+		{1, 25},
+	} {
+		var granted int64
+		if update := fc.consume(tc.consumed); update != nil {
+			granted = update.Bytes
+		}
+		if granted != tc.granted {
+			t.Errorf("%d: expected %d bytes to be granted, got %d", i, tc.granted, granted)
+		}
+	}
+
+	defer settings.TestingSetByteSize(&flowControlWindowSize, 0)()
+	fc = inboundFlowControl{}
+	if update := fc.start(&ProducerHeader{FlowControl: true}); update != nil {
+		t.Fatalf("expected no window with flow control disabled, got %v", update)
+	}
+}
@@ -56,6 +56,7 @@ func processInboundStreamHelper(
 	var finalErr error
 	draining := false
 	var sd StreamDecoder
+	var flowControl inboundFlowControl
 	for {
 		var msg *ProducerMessage
 		if firstMsg != nil {
@@ -75,6 +76,14 @@ func processInboundStreamHelper(
 			}
 		}
 
+		msgSize := int64(msg.Size())
+		if msg.Header != nil {
+			if update := flowControl.start(msg.Header); update != nil {
+				if err := sendWindowUpdateToStreamProducer(ctx, stream, update); err != nil {
+					return err
+				}
+			}
+		}
 		err := sd.AddMessage(msg)
 		if err != nil {
 			return errors.Wrap(err, log.MakeMessage(ctx, "decoding error", nil /* args */))
@@ -125,9 +134,31 @@ func processInboundStreamHelper(
 				return finalErr
 			}
 		}
+		// The rows of the message have been pushed (or discarded, if we're
+		// draining): the producer can send more.
+		if update := flowControl.consume(msgSize); update != nil {
+			if err := sendWindowUpdateToStreamProducer(ctx, stream, update); err != nil {
+				return err
+			}
+		}
 	}
 }
 
+// sendWindowUpdateToStreamProducer grants the producer more bytes to send on
+// stream.
+func sendWindowUpdateToStreamProducer(
+	ctx context.Context, stream DistSQL_FlowStreamServer, update *WindowUpdate,
+) error {
+	if log.V(3) {
+		log.Infof(ctx, "granting %d bytes to producer", update.Bytes)
+	}
+	sig := ConsumerSignal{WindowUpdate: update}
+	if err := stream.Send(&sig); err != nil {
+		return errors.Wrap(err, log.MakeMessage(ctx, "communication error", nil /* args */))
+	}
+	return nil
+}
+
 // sendDrainSignalToProducer is called when the consumer wants to signal the
 // producer that it doesn't need any more rows and the producer should drain. A
 // signal is sent on stream to the producer to ask it to send metadata.
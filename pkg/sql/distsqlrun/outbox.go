@@ -57,6 +57,10 @@ type outbox struct {
 	// numRows is the number of rows that have been accumulated in the encoder.
 	numRows int
 
+	// flowControl is set if the outbox obeys the windows granted by its
+	// consumer, i.e. if it outputs to another flow (see flow_control.go).
+	flowControl *outboxFlowControl
+
 	err error
 	wg  *sync.WaitGroup
 }
@@ -64,8 +68,13 @@ type outbox struct {
 var _ RowReceiver = &outbox{}
 
 func newOutbox(flowCtx *FlowCtx, addr string, flowID FlowID, streamID StreamID) *outbox {
-	m := &outbox{flowCtx: flowCtx, addr: addr}
+	m := &outbox{
+		flowCtx:     flowCtx,
+		addr:        addr,
+		flowControl: newOutboxFlowControl(flowCtx.flowControlMetrics),
+	}
 	m.encoder.setHeaderFields(flowID, streamID)
+	m.encoder.msgHdr.FlowControl = true
 	return m
 }
 
@@ -122,6 +131,11 @@ func (m *outbox) flush(ctx context.Context) error {
 	if m.numRows == 0 && m.encoder.headerSent {
 		return nil
 	}
+	if m.flowControl != nil {
+		if err := m.flowControl.waitForCredit(ctx); err != nil {
+			return err
+		}
+	}
 	msg := m.encoder.FormMessage(ctx)
 
 	if log.V(3) {
@@ -129,6 +143,9 @@ func (m *outbox) flush(ctx context.Context) error {
 	}
 	var sendErr error
 	if m.stream != nil {
+		if m.flowControl != nil {
+			m.flowControl.consume(int64(msg.Size()))
+		}
 		sendErr = m.stream.Send(msg)
 	} else {
 		sendErr = m.syncFlowStream.Send(msg)
@@ -272,30 +289,50 @@ type drainSignal struct {
 
 // waitForDrainSignalFromConsumer returns a channel that will be pinged once the
 // consumer has closed its send-side of the stream, or has sent a drain signal.
+// The windows granted by the consumer are passed to m.flowControl; if it is
+// set, the stream keeps being received from after a drain signal, so that the
+// outbox can be granted the bytes it needs to send its trailing metadata. The
+// closing of the stream is then also sent on the channel, which is buffered
+// so that it doesn't need to be read.
 func (m *outbox) waitForDrainSignalFromConsumer() <-chan drainSignal {
-	ch := make(chan drainSignal, 1)
+	ch := make(chan drainSignal, 2)
 
 	go func(
 		stream DistSQL_FlowStreamClient,
 		syncFlowStream DistSQL_RunSyncFlowServer,
+		flowControl *outboxFlowControl,
 	) {
-		var signal *ConsumerSignal
-		var err error
-		if stream != nil {
-			signal, err = stream.Recv()
-		} else {
-			signal, err = syncFlowStream.Recv()
+		if flowControl != nil {
+			defer flowControl.close()
 		}
-		if err == io.EOF {
-			ch <- drainSignal{drainRequested: false, err: nil}
-			return
-		}
-		if err != nil {
-			ch <- drainSignal{drainRequested: false, err: err}
-			return
+		for {
+			var signal *ConsumerSignal
+			var err error
+			if stream != nil {
+				signal, err = stream.Recv()
+			} else {
+				signal, err = syncFlowStream.Recv()
+			}
+			if err == io.EOF {
+				ch <- drainSignal{drainRequested: false, err: nil}
+				return
+			}
+			if err != nil {
+				ch <- drainSignal{drainRequested: false, err: err}
+				return
+			}
+			if signal.WindowUpdate != nil {
+				if flowControl != nil {
+					flowControl.grant(signal.WindowUpdate.Bytes)
+				}
+				continue
+			}
+			ch <- drainSignal{drainRequested: signal.DrainRequest != nil, err: nil}
+			if signal.DrainRequest == nil || flowControl == nil {
+				return
+			}
 		}
-		ch <- drainSignal{drainRequested: signal.DrainRequest != nil, err: nil}
-	}(m.stream, m.syncFlowStream)
+	}(m.stream, m.syncFlowStream, m.flowControl)
 	return ch
 }
 
@@ -88,6 +88,9 @@ type ServerConfig struct {
 	ParentMemoryMonitor *mon.MemoryMonitor
 	Counter             *metric.Counter
 	Hist                *metric.Histogram
+	// FlowControlMetrics, if set, records the time the outboxes spent waiting
+	// for their consumers.
+	FlowControlMetrics *FlowControlMetrics
 	// NodeID is the id of the node on which this Server is running.
 	NodeID *base.NodeIDContainer
 }
@@ -105,6 +108,7 @@ var _ DistSQLServer = &ServerImpl{}
 
 // NewServer instantiates a DistSQLServer.
 func NewServer(ctx context.Context, cfg ServerConfig) *ServerImpl {
+	cfg.AmbientContext.SetSubsystem(tracing.SubsystemSQL)
 	ds := &ServerImpl{
 		ServerConfig:  cfg,
 		regexpCache:   parser.NewRegexpCache(512),
@@ -195,6 +199,8 @@ func (ds *ServerImpl) setupFlow(
 		remoteTxnDB:    ds.FlowDB,
 		testingKnobs:   ds.TestingKnobs,
 		nodeID:         nodeID,
+
+		flowControlMetrics: ds.FlowControlMetrics,
 	}
 
 	ctx = flowCtx.AnnotateCtx(ctx)
@@ -30,6 +30,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 )
 
 type dropDatabaseNode struct {
@@ -423,7 +424,7 @@ func (p *planner) DropView(ctx context.Context, n *parser.DropView) (planNode, e
 		if err != nil {
 			return nil, err
 		}
-		if err := tn.QualifyWithDatabase(p.session.Database); err != nil {
+		if err := p.qualifyTableName(ctx, tn); err != nil {
 			return nil, err
 		}
 
@@ -531,7 +532,7 @@ func (p *planner) DropTable(ctx context.Context, n *parser.DropTable) (planNode,
 		if err != nil {
 			return nil, err
 		}
-		if err := tn.QualifyWithDatabase(p.session.Database); err != nil {
+		if err := p.qualifyTableName(ctx, tn); err != nil {
 			return nil, err
 		}
 
@@ -849,6 +850,12 @@ func (p *planner) initiateDropTable(ctx context.Context, tableDesc *sqlbase.Tabl
 		return err
 	}
 	tableDesc.State = sqlbase.TableDescriptor_DROP
+	tableDesc.DropTime = timeutil.Now().UnixNano()
+	if tableDesc.IsTable() {
+		if err := p.createTableGCJob(ctx, tableDesc); err != nil {
+			return err
+		}
+	}
 	if err := p.writeTableDesc(ctx, tableDesc); err != nil {
 		return err
 	}
@@ -983,25 +990,21 @@ func (p *planner) dropViewImpl(
 	return cascadeDroppedViews, nil
 }
 
-// truncateAndDropTable batches all the commands required for truncating and
-// deleting the table descriptor. It is called from a mutation, async wrt the
-// DROP statement. Before this method is called, the table has already been
-// marked for deletion and has been purged from the descriptor cache on all
-// nodes. No node is reading/writing data on the table at this stage,
-// therefore the entire table can be deleted with no concern for conflicts (we
-// can even eliminate the need to use a transaction for each chunk at a later
-// stage if it proves inefficient).
-func truncateAndDropTable(
+// releaseTableName deletes the name of a dropped table, so that it can be
+// used by another table. It is called from a mutation, async wrt the DROP
+// statement, once the table has been purged from the descriptor cache on all
+// nodes.
+func releaseTableName(
 	ctx context.Context,
 	tableDesc *sqlbase.TableDescriptor,
 	db *client.DB,
 	testingKnobs SchemaChangerTestingKnobs,
 ) error {
-	zoneKey, nameKey, descKey := GetKeysForTableDescriptor(tableDesc)
+	_, nameKey, _ := GetKeysForTableDescriptor(tableDesc)
 	// The table name is no longer in use across the entire cluster.
 	// Delete the namekey so that it can be used by another table.
 	// We do this before truncating the table because the table truncation
-	// takes too much time.
+	// takes too much time, and may even have to wait for the GC TTL.
 	if err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
 		b := &client.Batch{}
 		// Use CPut because we want to remove a specific name -> id map.
@@ -1019,14 +1022,42 @@ func truncateAndDropTable(
 	}
 
 	if cb := testingKnobs.RunAfterTableNameDropped; cb != nil {
-		if err := cb(); err != nil {
-			return err
-		}
+		return cb()
 	}
+	return nil
+}
 
-	if err := truncateTableInChunks(ctx, tableDesc, db); err != nil {
+// truncateAndDropTable batches all the commands required for truncating and
+// deleting the table descriptor. It is called from the GC job of the table,
+// after its name has been released. No node is reading/writing data on the
+// table at this stage, therefore the entire table can be deleted with no
+// concern for conflicts (we can even eliminate the need to use a transaction
+// for each chunk at a later stage if it proves inefficient). beforeChunk is
+// called before the deletion of each chunk of data. The data of the indexes
+// of the table whose GC jobs are indexJobs is deleted with the table, so these
+// jobs succeed along with the job of the table.
+func truncateAndDropTable(
+	ctx context.Context,
+	tableDesc *sqlbase.TableDescriptor,
+	db *client.DB,
+	job gcJob,
+	indexJobs []gcJob,
+	beforeChunk func(row int64) error,
+) error {
+	zoneKey, _, descKey := GetKeysForTableDescriptor(tableDesc)
+	job.started(ctx)
+	span := tableDesc.TableSpan()
+	if err := truncateTableInChunks(ctx, tableDesc, db, gcChunkSize.Get(), func(
+		row int64, resume roachpb.Span,
+	) error {
+		if resume.Key != nil {
+			job.progressed(ctx, .9*gcFraction(span, resume.Key))
+		}
+		return beforeChunk(row)
+	}); err != nil {
 		return err
 	}
+	job.progressed(ctx, .9)
 
 	// Finished deleting all the table data, now delete the table meta data.
 	return db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
@@ -1038,7 +1069,14 @@ func truncateAndDropTable(
 		if err := txn.SetSystemConfigTrigger(); err != nil {
 			return err
 		}
-		return txn.Run(ctx, b)
+		if err := txn.Run(ctx, b); err != nil {
+			return err
+		}
+		job.succeeded(ctx, txn)
+		for _, indexJob := range indexJobs {
+			indexJob.succeeded(ctx, txn)
+		}
+		return nil
 	})
 }
 
@@ -35,12 +35,14 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/gossip"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/kv/kverrors"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/rpc"
 	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/distsqlplan"
 	"github.com/cockroachdb/cockroach/pkg/sql/distsqlrun"
+	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
@@ -51,6 +53,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 )
 
@@ -254,6 +257,7 @@ type ExecutorConfig struct {
 
 	TestingKnobs              *ExecutorTestingKnobs
 	SchemaChangerTestingKnobs *SchemaChangerTestingKnobs
+	JobsTestingKnobs          *jobs.TestingKnobs
 	// HistogramWindowInterval is (server.Context).HistogramWindowInterval.
 	HistogramWindowInterval time.Duration
 
@@ -519,6 +523,14 @@ func (e *Executor) ExecuteStatements(
 
 	defer session.maybeRecover("executing", stmts)
 
+	// The transactions started by the batch continue the trace of the client
+	// application if the statements start with a trace comment.
+	if sc, ok, err := tracing.SpanContextFromSQLComment(e.cfg.AmbientCtx.Tracer, stmts); err != nil {
+		log.Warningf(session.Ctx(), "ignoring trace comment: %s", err)
+	} else if ok {
+		session.batchTraceParent = sc
+	}
+
 	// If the Executor wants config updates to be blocked, then block them so
 	// that session.testingVerifyMetadataFn can later be run on a known version
 	// of the system config. The point is to lock the system config so that no
@@ -752,6 +764,10 @@ func (e *Executor) execParsed(
 
 			if protoTS != nil {
 				txnState.mu.txn.SetFixedTimestamp(*protoTS)
+			} else if ts := txnState.historicalTimestamp; ts != nil {
+				// The transaction was pinned to a historical timestamp by a
+				// previous batch of statements; keep it there across retries.
+				txnState.mu.txn.SetFixedTimestamp(*ts)
 			}
 
 			var err error
@@ -763,6 +779,10 @@ func (e *Executor) execParsed(
 			results, remainingStmts, err = runTxnAttempt(
 				e, session, stmtsToExec, pinfo, origState, opt,
 				avoidCachedDescriptors, automaticRetryCount)
+			if err == nil && opt.AutoCommit && txn.Proto().Status == roachpb.PENDING {
+				// The transaction is about to be committed by txn.Exec().
+				session.leases.refreshDeadline(ctx, txn)
+			}
 
 			// TODO(andrei): Until #7881 fixed.
 			if err == nil && txnState.State == Aborted {
@@ -826,7 +846,7 @@ func (e *Executor) execParsed(
 
 		// Sanity check about not leaving KV txns open on errors.
 		if err != nil && txnState.mu.txn != nil && !txnState.mu.txn.IsFinalized() {
-			if _, retryable := err.(*roachpb.HandledRetryableTxnError); !retryable {
+			if !kverrors.IsTxnRestart(err) {
 				log.Fatalf(session.Ctx(), "got a non-retryable error but the KV "+
 					"transaction is not finalized. TxnState: %s, err: %s\n"+
 					"err:%+v\n\ntxn: %s", txnState.State, err, err, txnState.mu.txn.Proto())
@@ -1056,6 +1076,12 @@ func (e *Executor) execStmtsInCurrentTxn(
 			default:
 				panic(fmt.Sprintf("unexpected txn state: %s", txnState.State))
 			}
+			if err != nil && session.queryCanceled(stmt.queryHandle) {
+				// The query failed because CANCEL QUERY canceled the context of
+				// its txn; report it as canceled rather than with the error of
+				// whatever operation observed the cancelation.
+				err = errQueryCanceled
+			}
 			if (e.cfg.TestingKnobs.CheckStmtStringChange && false) ||
 				(e.cfg.TestingKnobs.StatementFilter != nil) {
 				if after := stmt.String(); after != stmtStrBefore {
@@ -1289,14 +1315,14 @@ func (e *Executor) execStmtInOpenTxn(
 	case *parser.CommitTransaction:
 		// CommitTransaction is executed fully here; there's no planNode for it
 		// and a planner is not involved at all.
-		return commitSQLTransaction(txnState, commit)
+		return commitSQLTransaction(txnState, &session.leases, commit)
 	case *parser.ReleaseSavepoint:
 		if err := parser.ValidateRestartCheckpoint(s.Savepoint); err != nil {
 			return Result{}, err
 		}
 		// ReleaseSavepoint is executed fully here; there's no planNode for it
 		// and a planner is not involved at all.
-		return commitSQLTransaction(txnState, release)
+		return commitSQLTransaction(txnState, &session.leases, release)
 	case *parser.RollbackTransaction:
 		// RollbackTransaction is executed fully here; there's no planNode for it
 		// and a planner is not involved at all.
@@ -1407,7 +1433,7 @@ func (e *Executor) execStmtInOpenTxn(
 	p.evalCtx.SetTxnTimestamp(txnState.sqlTimestamp)
 	p.evalCtx.SetStmtTimestamp(e.cfg.Clock.PhysicalTime())
 	p.semaCtx.Placeholders.Assign(pinfo)
-	p.avoidCachedDescriptors = avoidCachedDescriptors
+	p.avoidCachedDescriptors = avoidCachedDescriptors || txnState.historicalTimestamp != nil
 	p.phaseTimes[plannerStartExecStmt] = timeutil.Now()
 
 	// constantMemAcc accounts for all constant folded values that are computed
@@ -1494,14 +1520,19 @@ const (
 	release
 )
 
-// commitSqlTransaction commits a transaction.
-func commitSQLTransaction(txnState *txnState, commitType commitType) (Result, error) {
+// commitSqlTransaction commits a transaction. The leases held by the
+// transaction are refreshed first, so that the commit isn't rejected because
+// leases acquired early in a long-running transaction have since expired.
+func commitSQLTransaction(
+	txnState *txnState, leases *LeaseCollection, commitType commitType,
+) (Result, error) {
 	if txnState.State != Open {
 		panic(fmt.Sprintf("commitSqlTransaction called on non-open txn: %+v", txnState.mu.txn))
 	}
 	if commitType == commit {
 		txnState.commitSeen = true
 	}
+	leases.refreshDeadline(txnState.Ctx, txnState.mu.txn)
 	if err := txnState.mu.txn.Commit(txnState.Ctx); err != nil {
 		// Errors on COMMIT need special handling: if the errors is not handled by
 		// auto-retry, COMMIT needs to finalize the transaction (it can't leave it
@@ -1544,7 +1575,7 @@ func (e *Executor) execDistSQL(planner *planner, tree planNode, result *Result)
 	if recv.err != nil {
 		return recv.err
 	}
-	if result.Type == parser.RowsAffected {
+	if result.Type == parser.RowsAffected || result.Rows == nil {
 		result.RowsAffected = int(recv.numRows)
 	}
 	return nil
@@ -1582,6 +1613,12 @@ func (e *Executor) execClassic(planner *planner, plan planNode, result *Result)
 			rowAcc = planner.evalCtx.Mon.MakeBoundAccount()
 			planner.evalCtx.ActiveMemAcc = &rowAcc
 
+			if result.Rows == nil {
+				// The rows are discarded; only count them.
+				result.RowsAffected++
+				continue
+			}
+
 			// The plan.Values Datums needs to be copied on each iteration.
 			values := plan.Values()
 
@@ -1701,6 +1738,14 @@ func (e *Executor) execStmt(
 		return Result{}, err
 	}
 
+	// With discard_rows, the rows are counted but not accumulated; see
+	// makeDiscardedRowsResult.
+	discardRows := session.DiscardRows && result.Type == parser.Rows && !mockResults
+	if discardRows {
+		result.Close(session.Ctx())
+		result.Rows = nil
+	}
+
 	planner.phaseTimes[plannerStartExecStmt] = timeutil.Now()
 	session.setQueryExecutionMode(stmt.queryHandle, useDistSQL)
 	if useDistSQL {
@@ -1712,6 +1757,7 @@ func (e *Executor) execStmt(
 	e.recordStatementSummary(
 		planner, stmt, useDistSQL, automaticRetryCount, result, err,
 	)
+	maybeLogSlowQuery(planner, stmt, plan, err)
 	if err != nil {
 		result.Close(session.Ctx())
 		return Result{}, err
@@ -1721,6 +1767,43 @@ func (e *Executor) execStmt(
 		result.Close(session.Ctx())
 		return makeRes(stmt, planner, plan)
 	}
+	if discardRows {
+		execTime := planner.phaseTimes[plannerEndExecStmt].Sub(planner.phaseTimes[plannerStartExecStmt])
+		return makeDiscardedRowsResult(session, result, execTime)
+	}
+	return result, nil
+}
+
+// discardedRowsColumns are the columns of the result returned instead of the
+// rows of a statement when the discard_rows session variable is set.
+var discardedRowsColumns = sqlbase.ResultColumns{
+	{Name: "rows", Typ: parser.TypeInt},
+	{Name: "execution_time", Typ: parser.TypeInterval},
+}
+
+// makeDiscardedRowsResult replaces the result of a statement whose rows were
+// discarded with a single row holding the number of rows and the execution
+// time. This allows measuring the cost of a query without the cost of
+// sending its results to the client.
+func makeDiscardedRowsResult(
+	session *Session, discarded Result, execTime time.Duration,
+) (Result, error) {
+	result := Result{
+		PGTag:   discarded.PGTag,
+		Type:    parser.Rows,
+		Columns: discardedRowsColumns,
+		Rows: sqlbase.NewRowContainer(
+			session.makeBoundAccount(), sqlbase.ColTypeInfoFromResCols(discardedRowsColumns), 1,
+		),
+	}
+	row := parser.Datums{
+		parser.NewDInt(parser.DInt(discarded.RowsAffected)),
+		&parser.DInterval{Duration: duration.Duration{Nanos: execTime.Nanoseconds()}},
+	}
+	if _, err := result.Rows.AddRow(session.Ctx(), row); err != nil {
+		result.Close(session.Ctx())
+		return Result{}, err
+	}
 	return result, nil
 }
 
@@ -2009,14 +2092,15 @@ func convertToErrWithPGCode(err error) error {
 	if err == nil {
 		return nil
 	}
-	switch tErr := err.(type) {
-	case *roachpb.HandledRetryableTxnError:
+	switch kverrors.Classify(err) {
+	case kverrors.RefreshAndRetry:
 		return sqlbase.NewRetryError(err)
-	case *roachpb.AmbiguousResultError:
+	case kverrors.Ambiguous:
 		// TODO(andrei): Once DistSQL starts executing writes, we'll need a
 		// different mechanism to marshal AmbiguousResultErrors from the executing
 		// nodes.
-		return sqlbase.NewStatementCompletionUnknownError(tErr)
+		return sqlbase.NewStatementCompletionUnknownError(
+			errors.Cause(err).(*roachpb.AmbiguousResultError))
 	default:
 		return err
 	}
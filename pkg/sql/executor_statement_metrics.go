@@ -85,7 +85,7 @@ func (e *Executor) recordStatementSummary(
 
 	// Collect the statistics.
 	numRows := result.RowsAffected
-	if result.Type == parser.Rows {
+	if result.Type == parser.Rows && result.Rows != nil {
 		numRows = result.Rows.Len()
 	}
 
@@ -34,9 +34,9 @@ const (
 	explainNone explainMode = iota
 	explainDebug
 	explainPlan
-	// explainDistSQL shows the physical distsql plan for a query and whether a
-	// query would be run in "auto" DISTSQL mode. See explainDistSQLNode for
-	// details.
+	// explainDistSQL shows the physical distsql plan for a query, whether a
+	// query would be run in "auto" DISTSQL mode and whether the plan runs
+	// entirely on the gateway node. See explainDistSQLNode for details.
 	explainDistSQL
 )
 
@@ -260,6 +260,7 @@ func (*explainDistSQLNode) Close(context.Context)    {}
 
 var explainDistSQLColumns = sqlbase.ResultColumns{
 	{Name: "Automatic", Typ: parser.TypeBool},
+	{Name: "Local", Typ: parser.TypeBool},
 	{Name: "URL", Typ: parser.TypeString},
 	{Name: "JSON", Typ: parser.TypeString},
 }
@@ -274,6 +275,9 @@ func (n *explainDistSQLNode) Start(ctx context.Context) error {
 	}
 
 	planCtx := n.distSQLPlanner.NewPlanningCtx(ctx, n.txn)
+	if err := n.distSQLPlanner.setLocalHint(&planCtx, n.plan); err != nil {
+		return err
+	}
 	plan, err := n.distSQLPlanner.createPlanForNode(&planCtx, n.plan)
 	if err != nil {
 		return err
@@ -287,6 +291,7 @@ func (n *explainDistSQLNode) Start(ctx context.Context) error {
 
 	n.values = parser.Datums{
 		parser.MakeDBool(parser.DBool(auto)),
+		parser.MakeDBool(parser.DBool(n.distSQLPlanner.isLocalPlan(&plan))),
 		parser.NewDString(planURL.String()),
 		parser.NewDString(planJSON),
 	}
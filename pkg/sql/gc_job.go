@@ -0,0 +1,375 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// The data of dropped tables and indexes is deleted by GC jobs, run by the
+// schema changer of the table once the schema change dropping them is done.
+// Each dropped table or index has its own job, which reports the progress of
+// the deletion in system.jobs. The deletion is chunked, and paced by the
+// settings below so that it doesn't starve the foreground traffic.
+
+// gcWaitForTTL makes the GC jobs wait for the GC TTL of the zone of the
+// dropped table or index to pass, so that the dropped data stays readable
+// with AS OF SYSTEM TIME queries for as long as the data it replaced.
+var gcWaitForTTL = settings.RegisterBoolSetting(
+	"sql.gc_job.wait_for_gc_ttl.enabled",
+	"delay the deletion of the data of dropped tables and indexes until the GC TTL of their zone has passed",
+	false,
+)
+
+// gcChunkSize is the number of keys deleted per transaction by the GC jobs.
+var gcChunkSize = settings.RegisterValidatedIntSetting(
+	"sql.gc_job.chunk_size",
+	"maximum number of keys deleted per transaction when deleting the data of a dropped table or index",
+	TableTruncateChunkSize,
+	func(v int64) error {
+		if v < 1 {
+			return errors.Errorf("cannot set to a value less than 1: %d", v)
+		}
+		return nil
+	},
+)
+
+// gcChunkDelay is the pause between two transactions of a GC job.
+var gcChunkDelay = settings.RegisterNonNegativeDurationSetting(
+	"sql.gc_job.chunk_delay",
+	"pause between two transactions deleting the data of a dropped table or index",
+	0,
+)
+
+// gcPause waits for sql.gc_job.chunk_delay before the deletion of a chunk of
+// dropped data, except the first one.
+func gcPause(ctx context.Context, row int64) error {
+	delay := gcChunkDelay.Get()
+	if row == 0 || delay == 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// gcDeadline returns the time after which the data of the table, or of its
+// index if indexID is non-zero, dropped at dropTime can be deleted. That's
+// once the GC TTL of the zone of the table has passed if
+// sql.gc_job.wait_for_gc_ttl.enabled is set, and right away otherwise.
+func gcDeadline(
+	ctx context.Context,
+	db *client.DB,
+	tableDesc *sqlbase.TableDescriptor,
+	indexID sqlbase.IndexID,
+	dropTime int64,
+) (time.Time, error) {
+	if !gcWaitForTTL.Get() || dropTime == 0 || tableDesc.IsView() {
+		return time.Time{}, nil
+	}
+	var ttlSeconds int32
+	if err := db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		// The zone config of the table applies to its indexes, and the zone
+		// configs of the database and of the cluster apply if it has none.
+		for _, id := range []sqlbase.ID{tableDesc.ID, tableDesc.ParentID, keys.RootNamespaceID} {
+			zone, found, err := getZoneConfig(ctx, txn, id)
+			if err != nil {
+				return err
+			}
+			if found {
+				ttlSeconds = zone.GC.TTLSeconds
+				if indexID != 0 {
+					ttlSeconds = zone.GCPolicyForIndex(uint32(indexID)).TTLSeconds
+				}
+				return nil
+			}
+		}
+		return errors.Errorf("default zone config is missing")
+	}); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, dropTime).Add(time.Duration(ttlSeconds) * time.Second), nil
+}
+
+// gcJob reports the progress of a GC job. Its methods only log the errors
+// encountered while updating system.jobs: the deletion of the dropped data
+// doesn't depend on them.
+type gcJob struct {
+	jobLogger *jobs.JobLogger
+}
+
+// gcJob loads the GC job with the given ID. The job may be missing, e.g. for
+// tables dropped before the GC jobs existed, in which case the progress of the
+// deletion isn't reported.
+func (sc *SchemaChanger) gcJob(ctx context.Context, jobID int64) gcJob {
+	if jobID == 0 {
+		return gcJob{}
+	}
+	jl, err := jobs.GetJobLogger(ctx, &sc.db, InternalExecutor{LeaseManager: sc.leaseMgr}, jobID)
+	if err != nil {
+		log.Warningf(ctx, "failed to load GC job %d: %v", jobID, err)
+		return gcJob{}
+	}
+	return gcJob{jobLogger: jl.WithTestingKnobs(sc.jobsTestingKnobs)}
+}
+
+func (j gcJob) started(ctx context.Context) {
+	if j.jobLogger == nil {
+		return
+	}
+	if err := j.jobLogger.Started(ctx); err != nil {
+		log.Warningf(ctx, "failed to mark GC job %d as started: %v", *j.jobLogger.JobID(), err)
+	}
+}
+
+// gcFraction estimates the fraction of the data of span which is deleted
+// when the deletion resumes at key, assuming that the keys are spread evenly
+// over the span.
+func gcFraction(span roachpb.Span, key roachpb.Key) float32 {
+	if key.Compare(span.Key) <= 0 {
+		return 0
+	}
+	if key.Compare(span.EndKey) >= 0 {
+		return 1
+	}
+	// Interpolate the position of the key using the 8 bytes following the
+	// prefix shared by the bounds of the span.
+	prefix := 0
+	for prefix < len(span.Key) && prefix < len(span.EndKey) && span.Key[prefix] == span.EndKey[prefix] {
+		prefix++
+	}
+	toUint64 := func(k roachpb.Key) uint64 {
+		var buf [8]byte
+		if len(k) > prefix {
+			copy(buf[:], k[prefix:])
+		}
+		return binary.BigEndian.Uint64(buf[:])
+	}
+	start, end, pos := toUint64(span.Key), toUint64(span.EndKey), toUint64(key)
+	if pos <= start || end <= start {
+		return 0
+	}
+	if pos >= end {
+		return 1
+	}
+	return float32(float64(pos-start) / float64(end-start))
+}
+
+func (j gcJob) progressed(ctx context.Context, fractionCompleted float32) {
+	if j.jobLogger == nil {
+		return
+	}
+	if err := j.jobLogger.Progressed(ctx, fractionCompleted); err != nil {
+		log.Warningf(ctx, "failed to log progress on GC job %d: %v", *j.jobLogger.JobID(), err)
+	}
+}
+
+// succeeded marks the job as succeeded in txn, the transaction deleting the
+// last trace of the dropped table or index, or in a transaction of its own if
+// txn is nil.
+func (j gcJob) succeeded(ctx context.Context, txn *client.Txn) {
+	if j.jobLogger == nil {
+		return
+	}
+	if err := j.jobLogger.WithTxn(txn).Succeeded(ctx); err != nil {
+		log.Warningf(ctx, "failed to mark GC job %d as successful: %v", *j.jobLogger.JobID(), err)
+	}
+}
+
+func (j gcJob) failed(ctx context.Context, err error) {
+	if j.jobLogger == nil {
+		return
+	}
+	j.jobLogger.Failed(ctx, err)
+}
+
+// createTableGCJob creates the GC job of a table being dropped, in the
+// transaction dropping it.
+func (p *planner) createTableGCJob(ctx context.Context, tableDesc *sqlbase.TableDescriptor) error {
+	jobRecord := jobs.JobRecord{
+		Description:   fmt.Sprintf("GC for DROP TABLE %s", tableDesc.Name),
+		Username:      p.User(),
+		DescriptorIDs: sqlbase.IDs{tableDesc.GetID()},
+		Details:       jobs.SchemaChangeGCJobDetails{},
+	}
+	jobLogger := jobs.NewJobLogger(p.ExecCfg().DB, InternalExecutor{LeaseManager: p.session.leases.leaseMgr}, jobRecord)
+	jobLogger.WithTestingKnobs(p.ExecCfg().JobsTestingKnobs)
+	if err := jobLogger.WithTxn(p.txn).Created(ctx); err != nil {
+		return err
+	}
+	tableDesc.DropJobID = *jobLogger.JobID()
+	return nil
+}
+
+// createIndexGCJob creates the GC job of an index dropped by the mutations
+// of the schema changer, in txn, the transaction publishing the version of
+// the table in which the index is dropped. It returns the ID of the job.
+func (sc *SchemaChanger) createIndexGCJob(ctx context.Context, txn *client.Txn) (int64, error) {
+	jobRecord := jobs.JobRecord{
+		Description:   "GC for " + sc.jobLogger.Job.Description,
+		Username:      sc.jobLogger.Job.Username,
+		DescriptorIDs: sqlbase.IDs{sc.tableID},
+		Details:       jobs.SchemaChangeGCJobDetails{},
+	}
+	jobLogger := jobs.NewJobLogger(&sc.db, InternalExecutor{LeaseManager: sc.leaseMgr}, jobRecord)
+	jobLogger.WithTestingKnobs(sc.jobsTestingKnobs)
+	if err := jobLogger.WithTxn(txn).Created(ctx); err != nil {
+		return 0, err
+	}
+	return *jobLogger.JobID(), nil
+}
+
+// gcIndexes deletes the data of the dropped indexes of the table whose GC
+// deadline has passed. If the deadline of an index hasn't passed yet,
+// sc.execAfter is set to the earliest such deadline so that the
+// SchemaChangeManager comes back to it.
+func (sc *SchemaChanger) gcIndexes(
+	ctx context.Context, lease *sqlbase.TableDescriptor_SchemaChangeLease,
+) error {
+	var tableDesc *sqlbase.TableDescriptor
+	if err := sc.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		var err error
+		tableDesc, err = sqlbase.GetTableDescFromID(ctx, txn, sc.tableID)
+		return err
+	}); err != nil {
+		return err
+	}
+	now := timeutil.Now()
+	for _, m := range tableDesc.GCMutations {
+		deadline, err := gcDeadline(ctx, &sc.db, tableDesc, m.Index.ID, m.DropTime)
+		if err != nil {
+			return err
+		}
+		if now.Before(deadline) {
+			if !sc.execAfter.After(now) || deadline.Before(sc.execAfter) {
+				sc.execAfter = deadline
+			}
+			continue
+		}
+		if err := sc.gcIndex(ctx, lease, tableDesc, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gcIndex deletes the data of a dropped index in chunks, and then removes it
+// from the GC mutations of the table.
+func (sc *SchemaChanger) gcIndex(
+	ctx context.Context,
+	lease *sqlbase.TableDescriptor_SchemaChangeLease,
+	tableDesc *sqlbase.TableDescriptor,
+	m sqlbase.TableDescriptor_GCDescriptorMutation,
+) error {
+	job := sc.gcJob(ctx, m.JobID)
+	job.started(ctx)
+
+	// The index is no longer part of the table, so the columns needed to
+	// delete its entries by scanning the table (when it's interleaved) have to
+	// be requested explicitly.
+	var cols []sqlbase.ColumnDescriptor
+	for _, ids := range [][]sqlbase.ColumnID{m.Index.ColumnIDs, m.Index.ExtraColumnIDs} {
+		for _, id := range ids {
+			col, err := tableDesc.FindColumnByID(id)
+			if err != nil {
+				return err
+			}
+			cols = append(cols, *col)
+		}
+	}
+
+	span := tableDesc.IndexSpan(m.Index.ID)
+	if len(m.Index.Interleave.Ancestors) > 0 || len(m.Index.InterleavedBy) > 0 {
+		// The entries of interleaved indexes are found by scanning the table.
+		span = tableDesc.PrimaryIndexSpan()
+	}
+	chunkSize := sc.getChunkSize(gcChunkSize.Get())
+	var resume roachpb.Span
+	for row, done := int64(0), false; !done; row += chunkSize {
+		// First extend the schema change lease.
+		if err := sc.ExtendLease(ctx, lease); err != nil {
+			return err
+		}
+		if err := gcPause(ctx, row); err != nil {
+			return err
+		}
+
+		resumeAt := resume
+		if log.V(2) {
+			log.Infof(ctx, "GC of index (%d, %d) at row: %d, span: %s",
+				sc.tableID, m.Index.ID, row, resume)
+		}
+		if err := sc.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+			if sc.testingKnobs.RunBeforeBackfillChunk != nil {
+				if err := sc.testingKnobs.RunBeforeBackfillChunk(resume); err != nil {
+					return err
+				}
+			}
+			if sc.testingKnobs.RunAfterBackfillChunk != nil {
+				defer sc.testingKnobs.RunAfterBackfillChunk()
+			}
+
+			rd, err := sqlbase.MakeRowDeleter(txn, tableDesc, nil, cols, false)
+			if err != nil {
+				return err
+			}
+			td := tableDeleter{rd: rd}
+			if err := td.init(txn); err != nil {
+				return err
+			}
+			resume, err = td.deleteIndex(ctx, &m.Index, resumeAt, chunkSize)
+			return err
+		}); err != nil {
+			return err
+		}
+		if done = resume.Key == nil; !done {
+			job.progressed(ctx, .9*gcFraction(span, resume.Key))
+		}
+	}
+	job.progressed(ctx, .9)
+
+	// Finished deleting the index data, now forget about the index.
+	_, err := sc.leaseMgr.Publish(ctx, sc.tableID, func(desc *sqlbase.TableDescriptor) error {
+		for i, g := range desc.GCMutations {
+			if g.Index.ID == m.Index.ID {
+				desc.GCMutations = append(desc.GCMutations[:i], desc.GCMutations[i+1:]...)
+				return nil
+			}
+		}
+		// Another schema changer already got rid of the index.
+		return errDidntUpdateDescriptor
+	}, func(txn *client.Txn) error {
+		job.succeeded(ctx, txn)
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,203 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+)
+
+// TestGCJobWaitsForTTL tests that the data of dropped indexes and tables is
+// kept until the GC TTL of their zone has passed when
+// sql.gc_job.wait_for_gc_ttl.enabled is set, and that their GC jobs report
+// the deletion.
+func TestGCJobWaitsForTTL(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer settings.TestingSetBool(&gcWaitForTTL, true)()
+
+	knobs := &SchemaChangerTestingKnobs{
+		// The schema changers which aren't run by the statements are run by
+		// the test.
+		AsyncExecNotification: func() error {
+			return errors.New("async schema changer disabled")
+		},
+	}
+	s, sqlDB, kvDB := serverutils.StartServer(t, base.TestServerArgs{
+		Knobs: base.TestingKnobs{SQLSchemaChanger: knobs},
+	})
+	defer s.Stopper().Stop(context.TODO())
+	ctx := context.TODO()
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE t;
+CREATE TABLE t.kv (k INT PRIMARY KEY, v INT, FAMILY (k, v), INDEX foo (v), INDEX bar (v));
+INSERT INTO t.kv VALUES (1, 2), (3, 4), (5, 6);
+`); err != nil {
+		t.Fatal(err)
+	}
+	tableDesc := sqlbase.GetTableDescriptor(kvDB, "t", "kv")
+	idx, _, err := tableDesc.FindIndexByName("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The dropped data of the table must be kept for an hour.
+	const ttl = time.Hour
+	zone := config.DefaultZoneConfig()
+	zone.GC.TTLSeconds = int32(ttl.Seconds())
+	buf, err := protoutil.Marshal(&zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec(`INSERT INTO system.zones VALUES ($1, $2)`, tableDesc.ID, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	checkKeyCount := func(span roachpb.Span, numKeys int) {
+		if kvs, err := kvDB.Scan(ctx, span.Key, span.EndKey, 0); err != nil {
+			t.Fatal(err)
+		} else if len(kvs) != numKeys {
+			t.Fatalf("expected %d key value pairs, but got %d", numKeys, len(kvs))
+		}
+	}
+	checkGCJob := func(description string, status jobs.JobStatus) {
+		var actual string
+		if err := sqlDB.QueryRow(
+			`SELECT status FROM crdb_internal.jobs WHERE type = $1 AND description = $2`,
+			jobs.JobTypeSchemaChangeGC, description,
+		).Scan(&actual); err != nil {
+			t.Fatal(err)
+		}
+		if actual != string(status) {
+			t.Fatalf("expected the GC job %q to be %s, got %s", description, status, actual)
+		}
+	}
+	getTableDesc := func() (*sqlbase.TableDescriptor, error) {
+		var desc *sqlbase.TableDescriptor
+		err := kvDB.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+			var err error
+			desc, err = sqlbase.GetTableDescFromID(ctx, txn, tableDesc.ID)
+			return err
+		})
+		return desc, err
+	}
+	// runSchemaChanger runs the schema changer of the table, as the
+	// SchemaChangeManager would.
+	runSchemaChanger := func() SchemaChanger {
+		sc := SchemaChanger{
+			tableID:      tableDesc.ID,
+			nodeID:       s.NodeID(),
+			db:           *kvDB,
+			leaseMgr:     s.LeaseManager().(*LeaseManager),
+			testingKnobs: knobs,
+		}
+		if err := sc.exec(ctx, createSchemaChangeEvalCtx(s.Clock().Now())); err != nil {
+			t.Fatal(err)
+		}
+		return sc
+	}
+
+	// The index is dropped right away, but its data is kept.
+	if _, err := sqlDB.Exec(`DROP INDEX t.kv@foo`); err != nil {
+		t.Fatal(err)
+	}
+	indexSpan := tableDesc.IndexSpan(idx.ID)
+	checkKeyCount(indexSpan, 3)
+	checkGCJob("GC for DROP INDEX t.kv@foo", jobs.JobStatusPending)
+	if sc := runSchemaChanger(); sc.execAfter.Before(timeutil.Now().Add(ttl - time.Minute)) {
+		t.Fatalf("expected the schema changer to wait for the GC TTL, got %s", sc.execAfter)
+	}
+	checkKeyCount(indexSpan, 3)
+
+	// Once the TTL doesn't apply anymore, the data is deleted.
+	resetWait := settings.TestingSetBool(&gcWaitForTTL, false)
+	runSchemaChanger()
+	checkKeyCount(indexSpan, 0)
+	checkGCJob("GC for DROP INDEX t.kv@foo", jobs.JobStatusSucceeded)
+	if desc, err := getTableDesc(); err != nil {
+		t.Fatal(err)
+	} else if len(desc.GCMutations) != 0 {
+		t.Fatalf("expected no GC mutations, got %+v", desc.GCMutations)
+	}
+	resetWait()
+
+	// The data of an index still waiting for its GC deadline when the table is
+	// dropped goes away with the table.
+	if _, err := sqlDB.Exec(`DROP INDEX t.kv@bar`); err != nil {
+		t.Fatal(err)
+	}
+	runSchemaChanger()
+	checkGCJob("GC for DROP INDEX t.kv@bar", jobs.JobStatusPending)
+
+	// Same for the table: its name is released right away, but its data and
+	// descriptor are kept.
+	if _, err := sqlDB.Exec(`DROP TABLE t.kv`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec(`CREATE TABLE t.kv (k INT PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	tableSpan := tableDesc.TableSpan()
+	checkKeyCount(tableSpan, 6)
+	checkGCJob("GC for DROP TABLE kv", jobs.JobStatusPending)
+	runSchemaChanger()
+	checkKeyCount(tableSpan, 6)
+
+	defer settings.TestingSetBool(&gcWaitForTTL, false)()
+	runSchemaChanger()
+	checkKeyCount(tableSpan, 0)
+	checkGCJob("GC for DROP TABLE kv", jobs.JobStatusSucceeded)
+	checkGCJob("GC for DROP INDEX t.kv@bar", jobs.JobStatusSucceeded)
+	if _, err := getTableDesc(); err != sqlbase.ErrDescriptorNotFound {
+		t.Fatalf("expected the descriptor to be deleted, got %v", err)
+	}
+}
+
+func TestGCFraction(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	span := roachpb.Span{Key: roachpb.Key("a\x00"), EndKey: roachpb.Key("a\xff")}
+	testCases := []struct {
+		key      roachpb.Key
+		expected float32
+	}{
+		{roachpb.Key("a"), 0},
+		{roachpb.Key("a\x00"), 0},
+		{roachpb.Key("a\x33"), 0.2},
+		{roachpb.Key("a\x7f\xff"), 0.5},
+		{roachpb.Key("a\xff"), 1},
+		{roachpb.Key("b"), 1},
+	}
+	for _, tc := range testCases {
+		if f := gcFraction(span, tc.key); f < tc.expected-0.01 || f > tc.expected+0.01 {
+			t.Errorf("%q: expected %f, got %f", tc.key, tc.expected, f)
+		}
+	}
+}
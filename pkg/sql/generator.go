@@ -69,8 +69,18 @@ func (p *planner) makeGenerator(ctx context.Context, t *parser.FuncExpr) (planNo
 		return nil, errors.Errorf("FROM expression is not a generator: %s", t)
 	}
 
+	var labels []string
+	if f, ok := normalized.(*parser.FuncExpr); ok {
+		labels = f.GetGeneratorColumnLabels()
+	}
+
 	var columns sqlbase.ResultColumns
-	if len(tType.Cols) == 1 {
+	if len(labels) == len(tType.Cols) {
+		columns = make(sqlbase.ResultColumns, len(tType.Cols))
+		for i, t := range tType.Cols {
+			columns[i] = sqlbase.ResultColumn{Name: labels[i], Typ: t}
+		}
+	} else if len(tType.Cols) == 1 {
 		columns = sqlbase.ResultColumns{sqlbase.ResultColumn{Name: origName, Typ: tType.Cols[0]}}
 	} else {
 		columns = make(sqlbase.ResultColumns, len(tType.Cols))
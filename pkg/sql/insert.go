@@ -54,7 +54,7 @@ type insertNode struct {
 func (p *planner) Insert(
 	ctx context.Context, n *parser.Insert, desiredTypes []parser.Type,
 ) (planNode, error) {
-	tn, err := p.getAliasedTableName(n.Table)
+	tn, err := p.getAliasedTableName(ctx, n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -258,6 +258,7 @@ func (n *insertNode) Next(ctx context.Context) (bool, error) {
 	if next, err := n.run.rows.Next(ctx); !next {
 		if err == nil {
 			// We're done. Finish the batch.
+			n.p.maybeRefreshLeaseDeadline(ctx)
 			err = n.tw.finalize(ctx)
 		}
 		return false, err
@@ -0,0 +1,205 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package jobs
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CronExpr is a parsed cron expression. It uses the classic five-field
+// format ("minute hour day-of-month month day-of-week"), where each field is
+// either "*", a number, a range "a-b", or a comma-separated list of those,
+// optionally followed by a step "/n". The macros @yearly, @annually,
+// @monthly, @weekly, @daily, @midnight and @hourly are also accepted.
+//
+// All times are interpreted in UTC.
+type CronExpr struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record whether the day-of-month and day-of-week
+	// fields were unrestricted. As in Vixie cron, when both fields are
+	// restricted a day matches if it matches either of them.
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var (
+	cronMinute = cronField{"minute", 0, 59}
+	cronHour   = cronField{"hour", 0, 23}
+	cronDom    = cronField{"day of month", 1, 31}
+	cronMonth  = cronField{"month", 1, 12}
+	cronDow    = cronField{"day of week", 0, 6}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCronExpr parses the cron expression s.
+func ParseCronExpr(s string) (CronExpr, error) {
+	spec := strings.TrimSpace(s)
+	if expanded, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return CronExpr{}, errors.Errorf(
+			"invalid cron expression %q: expected 5 fields, found %d", s, len(fields))
+	}
+
+	var c CronExpr
+	var err error
+	if c.minute, _, err = parseCronField(fields[0], cronMinute); err != nil {
+		return CronExpr{}, errors.Wrapf(err, "invalid cron expression %q", s)
+	}
+	if c.hour, _, err = parseCronField(fields[1], cronHour); err != nil {
+		return CronExpr{}, errors.Wrapf(err, "invalid cron expression %q", s)
+	}
+	if c.dom, c.domStar, err = parseCronField(fields[2], cronDom); err != nil {
+		return CronExpr{}, errors.Wrapf(err, "invalid cron expression %q", s)
+	}
+	if c.month, _, err = parseCronField(fields[3], cronMonth); err != nil {
+		return CronExpr{}, errors.Wrapf(err, "invalid cron expression %q", s)
+	}
+	// Both 0 and 7 mean Sunday in the day-of-week field.
+	dowField := cronDow
+	dowField.max = 7
+	if c.dow, c.dowStar, err = parseCronField(fields[4], dowField); err != nil {
+		return CronExpr{}, errors.Wrapf(err, "invalid cron expression %q", s)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow = (c.dow &^ (1 << 7)) | 1
+	}
+	return c, nil
+}
+
+// parseCronField parses a single field of a cron expression into a bitmask
+// of the values it matches. The returned bool is true if the field was an
+// unstepped "*".
+func parseCronField(s string, f cronField) (uint64, bool, error) {
+	var bits uint64
+	star := false
+	for _, part := range strings.Split(s, ",") {
+		rangeSpec, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			rangeSpec = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, false, errors.Errorf("invalid step %q in %s field", part[i+1:], f.name)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangeSpec == "*":
+			if step == 1 && len(s) == 1 {
+				star = true
+			}
+		case strings.IndexByte(rangeSpec, '-') >= 0:
+			bounds := strings.SplitN(rangeSpec, "-", 2)
+			var err error
+			if lo, err = parseCronValue(bounds[0], f); err != nil {
+				return 0, false, err
+			}
+			if hi, err = parseCronValue(bounds[1], f); err != nil {
+				return 0, false, err
+			}
+			if lo > hi {
+				return 0, false, errors.Errorf("invalid range %q in %s field", rangeSpec, f.name)
+			}
+		default:
+			v, err := parseCronValue(rangeSpec, f)
+			if err != nil {
+				return 0, false, err
+			}
+			lo = v
+			// "a/n" means "a-max/n".
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, star, nil
+}
+
+func parseCronValue(s string, f cronField) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q in %s field", s, f.name)
+	}
+	if v < f.min || v > f.max {
+		return 0, errors.Errorf("value %d out of range [%d, %d] in %s field", v, f.min, f.max, f.name)
+	}
+	return v, nil
+}
+
+// maxCronSearch bounds the search performed by Next. Every valid expression
+// matches at least once every four years (e.g. "0 0 29 2 *"), so anything
+// that hasn't matched by then never will (e.g. "0 0 31 2 *").
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+// Next returns the first time strictly after t that matches the expression,
+// or the zero time if there is none.
+func (c CronExpr) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxCronSearch)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c CronExpr) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+)
+
+func TestCronExprNext(t *testing.T) {
+	// 2017-06-14 is a Wednesday.
+	from := time.Date(2017, 6, 14, 10, 30, 15, 0, time.UTC)
+
+	testCases := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2017, 6, 14, 10, 31, 0, 0, time.UTC)},
+		{"30 * * * *", time.Date(2017, 6, 14, 11, 30, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2017, 6, 14, 10, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2017, 6, 15, 2, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2017, 6, 14, 13, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2017, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2017, 6, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2017, 6, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", time.Date(2017, 6, 15, 0, 0, 0, 0, time.UTC)},
+		// Day-of-month and day-of-week are ORed when both are restricted.
+		{"0 0 1 * 5", time.Date(2017, 6, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2017, 6, 14, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2017, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2017, 6, 18, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2017, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// February 31st never happens.
+		{"0 0 31 2 *", time.Time{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			c, err := ParseCronExpr(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next := c.Next(from); !next.Equal(tc.expected) {
+				t.Errorf("expected %s, got %s", tc.expected, next)
+			}
+		})
+	}
+}
+
+func TestParseCronExprErrors(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{"", "expected 5 fields, found 0"},
+		{"* * * *", "expected 5 fields, found 4"},
+		{"60 * * * *", `value 60 out of range \[0, 59\] in minute field`},
+		{"* 24 * * *", `value 24 out of range \[0, 23\] in hour field`},
+		{"* * 0 * *", `value 0 out of range \[1, 31\] in day of month field`},
+		{"* * * 13 *", `value 13 out of range \[1, 12\] in month field`},
+		{"* * * * 8", `value 8 out of range \[0, 7\] in day of week field`},
+		{"5-1 * * * *", `invalid range "5-1" in minute field`},
+		{"*/0 * * * *", `invalid step "0" in minute field`},
+		{"a * * * *", `invalid value "a" in minute field`},
+		{"@fortnightly", "expected 5 fields, found 1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := ParseCronExpr(tc.expr)
+			if !testutils.IsError(err, tc.expected) {
+				t.Errorf("expected error %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
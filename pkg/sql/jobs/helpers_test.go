@@ -0,0 +1,38 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package jobs
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ScheduledStatement exposes scheduledStatement for testing.
+func ScheduledStatement(sql string, runAt time.Time) (string, error) {
+	return scheduledStatement(sql, runAt)
+}
+
+// Claim attempts to claim the current run of the given schedule as of now. If
+// a run is claimed, the returned function executes it.
+func (s *Scheduler) Claim(
+	ctx context.Context, id int64, now time.Time,
+) (func(context.Context), error) {
+	run, err := s.claim(ctx, id, now)
+	if err != nil || run == nil {
+		return nil, err
+	}
+	return func(ctx context.Context) { s.execute(ctx, *run) }, nil
+}
@@ -44,6 +44,7 @@ type JobLogger struct {
 	jobID *int64
 	Job   JobRecord
 	txn   *client.Txn
+	knobs *TestingKnobs
 }
 
 // JobDetails is a marker interface for job details proto structs.
@@ -107,6 +108,8 @@ func GetJobLogger(
 			jl.Job.Details = *d.Restore
 		case *JobPayload_SchemaChange:
 			jl.Job.Details = *d.SchemaChange
+		case *JobPayload_SchemaChangeGC:
+			jl.Job.Details = *d.SchemaChangeGC
 		default:
 			return errors.Errorf("JobLogger: unsupported job details type %T", d)
 		}
@@ -139,6 +142,21 @@ func (jl *JobLogger) WithTxn(txn *client.Txn) *JobLogger {
 	return jl
 }
 
+// WithTestingKnobs sets the testing knobs consulted by this JobLogger. A nil
+// knobs is allowed.
+func (jl *JobLogger) WithTestingKnobs(knobs *TestingKnobs) *JobLogger {
+	jl.knobs = knobs
+	return jl
+}
+
+// beforeEvent calls the BeforeJobEvent testing knob, if any.
+func (jl *JobLogger) beforeEvent(event JobEvent) error {
+	if jl.knobs == nil || jl.knobs.BeforeJobEvent == nil || jl.jobID == nil {
+		return nil
+	}
+	return jl.knobs.BeforeJobEvent(*jl.jobID, event)
+}
+
 // JobID returns the ID of the job that this JobLogger is currently tracking.
 // This will be nil if Created has not yet been called.
 func (jl *JobLogger) JobID() *int64 {
@@ -161,6 +179,8 @@ func (jl *JobLogger) Created(ctx context.Context) error {
 		payload.Details = &JobPayload_Restore{Restore: &d}
 	case SchemaChangeJobDetails:
 		payload.Details = &JobPayload_SchemaChange{SchemaChange: &d}
+	case SchemaChangeGCJobDetails:
+		payload.Details = &JobPayload_SchemaChangeGC{SchemaChangeGC: &d}
 	default:
 		return errors.Errorf("JobLogger: unsupported job details type %T", d)
 	}
@@ -169,6 +189,9 @@ func (jl *JobLogger) Created(ctx context.Context) error {
 
 // Started marks the tracked job as started.
 func (jl *JobLogger) Started(ctx context.Context) error {
+	if err := jl.beforeEvent(JobEventStarted); err != nil {
+		return err
+	}
 	return jl.updateJobRecord(ctx, JobStatusRunning, func(payload *JobPayload) (bool, error) {
 		if payload.StartedMicros != 0 {
 			// Already started - do nothing.
@@ -189,6 +212,9 @@ func (jl *JobLogger) Progressed(ctx context.Context, fractionCompleted float32)
 			fractionCompleted, jl.jobID,
 		)
 	}
+	if err := jl.beforeEvent(JobEventProgressed); err != nil {
+		return err
+	}
 	return jl.updateJobRecord(ctx, JobStatusRunning, func(payload *JobPayload) (bool, error) {
 		if payload.StartedMicros == 0 {
 			return false, errors.Errorf("JobLogger: job %d not started", jl.jobID)
@@ -214,6 +240,9 @@ func (jl *JobLogger) Failed(ctx context.Context, err error) {
 	if jl.jobID == nil {
 		return
 	}
+	if knobErr := jl.beforeEvent(JobEventFailed); knobErr != nil {
+		log.Errorf(ctx, "JobLogger: ignoring testing knob error for job %d: %v", *jl.jobID, knobErr)
+	}
 	internalErr := jl.updateJobRecord(ctx, JobStatusFailed, func(payload *JobPayload) (bool, error) {
 		if payload.FinishedMicros != 0 {
 			// Already finished - do nothing.
@@ -232,6 +261,9 @@ func (jl *JobLogger) Failed(ctx context.Context, err error) {
 // Succeeded marks the tracked job as having succeeded and sets its fraction
 // completed to 1.0.
 func (jl *JobLogger) Succeeded(ctx context.Context) error {
+	if err := jl.beforeEvent(JobEventSucceeded); err != nil {
+		return err
+	}
 	return jl.updateJobRecord(ctx, JobStatusSucceeded, func(payload *JobPayload) (bool, error) {
 		if payload.FinishedMicros != 0 {
 			// Already finished - do nothing.
@@ -319,9 +351,10 @@ func (jl *JobLogger) updateJobRecord(
 
 // Job types are named for the SQL query that creates them.
 const (
-	JobTypeBackup       string = "BACKUP"
-	JobTypeRestore      string = "RESTORE"
-	JobTypeSchemaChange string = "SCHEMA CHANGE"
+	JobTypeBackup         string = "BACKUP"
+	JobTypeRestore        string = "RESTORE"
+	JobTypeSchemaChange   string = "SCHEMA CHANGE"
+	JobTypeSchemaChangeGC string = "SCHEMA CHANGE GC"
 )
 
 // Typ returns the payload's job type.
@@ -333,6 +366,8 @@ func (jp *JobPayload) Typ() string {
 		return JobTypeRestore
 	case *JobPayload_SchemaChange:
 		return JobTypeSchemaChange
+	case *JobPayload_SchemaChangeGC:
+		return JobTypeSchemaChangeGC
 	default:
 		panic("JobPayload.Typ called on a payload with an unknown details type")
 	}
@@ -363,3 +398,4 @@ func jobTimestamp(ts time.Time) int64 {
 var _ JobDetails = BackupJobDetails{}
 var _ JobDetails = RestoreJobDetails{}
 var _ JobDetails = SchemaChangeJobDetails{}
+var _ JobDetails = SchemaChangeGCJobDetails{}
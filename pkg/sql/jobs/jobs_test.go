@@ -30,6 +30,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/jobutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
@@ -400,4 +401,73 @@ func TestJobLogger(t *testing.T) {
 			t.Fatal(err)
 		}
 	})
+
+	t.Run("testing knobs inject errors", func(t *testing.T) {
+		db := sqlutils.MakeSQLRunner(t, rawSQLDB)
+		job := jobs.JobRecord{Details: jobs.BackupJobDetails{}}
+		expectation := jobExpectation{
+			Job:    job,
+			Type:   jobs.JobTypeBackup,
+			Before: timeutil.Now(),
+		}
+		var events []jobs.JobEvent
+		knobs := &jobs.TestingKnobs{
+			BeforeJobEvent: func(_ int64, event jobs.JobEvent) error {
+				events = append(events, event)
+				if event == jobs.JobEventProgressed {
+					return errors.New("injected")
+				}
+				return nil
+			},
+		}
+		logger := jobs.NewJobLogger(kvDB, sql.InternalExecutor{LeaseManager: s.LeaseManager().(*sql.LeaseManager)}, job)
+		logger.WithTestingKnobs(knobs)
+		if err := logger.Created(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := logger.Started(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := logger.Progressed(ctx, 0.2); !testutils.IsError(err, "injected") {
+			t.Fatalf("expected 'injected' error, but got %v", err)
+		}
+		if err := verifyJobRecord(db, jobs.JobStatusRunning, expectation); err != nil {
+			t.Fatal(err)
+		}
+		if err := logger.Succeeded(ctx); err != nil {
+			t.Fatal(err)
+		}
+		expected := []jobs.JobEvent{jobs.JobEventStarted, jobs.JobEventProgressed, jobs.JobEventSucceeded}
+		if !reflect.DeepEqual(expected, events) {
+			t.Fatalf("expected events %v, got %v", expected, events)
+		}
+	})
+
+	t.Run("checkpoint pauses job", func(t *testing.T) {
+		job := jobs.JobRecord{Details: jobs.BackupJobDetails{}}
+		cp := jobutils.NewCheckpoint(jobs.JobEventSucceeded)
+		logger := jobs.NewJobLogger(kvDB, sql.InternalExecutor{LeaseManager: s.LeaseManager().(*sql.LeaseManager)}, job)
+		logger.WithTestingKnobs(&jobs.TestingKnobs{BeforeJobEvent: cp.Knob})
+		if err := logger.Created(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := logger.Started(ctx); err != nil {
+			t.Fatal(err)
+		}
+		errCh := make(chan error)
+		go func() { errCh <- logger.Succeeded(ctx) }()
+		if e, a := *logger.JobID(), cp.Wait(); e != a {
+			t.Fatalf("expected job %d at the checkpoint, got %d", e, a)
+		}
+		var status string
+		db := sqlutils.MakeSQLRunner(t, rawSQLDB)
+		db.QueryRow(`SELECT status FROM system.jobs WHERE id = $1`, *logger.JobID()).Scan(&status)
+		if e, a := jobs.JobStatusRunning, jobs.JobStatus(status); e != a {
+			t.Fatalf("expected status %s while paused, got %s", e, a)
+		}
+		cp.Release(errors.New("injected"))
+		if err := <-errCh; !testutils.IsError(err, "injected") {
+			t.Fatalf("expected 'injected' error, but got %v", err)
+		}
+	})
 }
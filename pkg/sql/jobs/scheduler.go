@@ -0,0 +1,410 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package jobs
+
+import (
+	"net/url"
+	"path"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/stop"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/pkg/errors"
+)
+
+var schedulerEnabled = settings.RegisterBoolSetting(
+	"jobs.scheduler.enabled",
+	"if set, scheduled jobs in system.scheduled_jobs are fired when due",
+	true,
+)
+
+var schedulerPollInterval = settings.RegisterValidatedDurationSetting(
+	"jobs.scheduler.poll_interval",
+	"how often each node checks system.scheduled_jobs for due schedules",
+	time.Minute,
+	func(v time.Duration) error {
+		if v <= 0 {
+			return errors.Errorf("jobs.scheduler.poll_interval must be positive: %s", v)
+		}
+		return nil
+	},
+)
+
+var schedulerRunTimeout = settings.RegisterValidatedDurationSetting(
+	"jobs.scheduler.run_timeout",
+	"how long a run of a schedule can go without completing before it is presumed abandoned, e.g. because its node died",
+	24*time.Hour,
+	func(v time.Duration) error {
+		if v <= 0 {
+			return errors.Errorf("jobs.scheduler.run_timeout must be positive: %s", v)
+		}
+		return nil
+	},
+)
+
+// maxSchedulesPerPoll bounds the number of due schedules a node considers
+// each time it polls. Any remainder is picked up by the next poll.
+const maxSchedulesPerPoll = 100
+
+// OverlapPolicy determines what happens when a schedule comes due while a
+// previous run of the same schedule is still in progress.
+type OverlapPolicy string
+
+const (
+	// OverlapWait delays the new run until the previous one finishes, at
+	// which point it is fired immediately.
+	OverlapWait OverlapPolicy = "wait"
+	// OverlapSkip drops the new run and advances the schedule to its next
+	// occurrence.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapConcurrent fires the new run regardless of the previous one.
+	OverlapConcurrent OverlapPolicy = "concurrent"
+)
+
+// ScheduleRecord stores the schedule fields that are not automatically
+// managed by the Scheduler.
+type ScheduleRecord struct {
+	Name          string
+	Owner         string
+	Cron          string
+	Statement     string
+	OverlapPolicy OverlapPolicy
+}
+
+// backupTimestampFormat is used to give each run of a scheduled BACKUP its
+// own destination.
+const backupTimestampFormat = "20060102-150405"
+
+// CreateSchedule validates the supplied schedule and records it in the
+// system.scheduled_jobs table, returning its ID. The first run is scheduled
+// for the next time after now that matches the cron expression.
+//
+// Only BACKUP statements can currently be scheduled. Since a BACKUP cannot
+// overwrite an existing backup, each run writes to a subdirectory of the
+// destination named after the time the run was due.
+func CreateSchedule(
+	ctx context.Context, ex sqlutil.InternalExecutor, txn *client.Txn, sched ScheduleRecord,
+) (int64, error) {
+	cron, err := ParseCronExpr(sched.Cron)
+	if err != nil {
+		return 0, err
+	}
+	next := cron.Next(timeutil.Now())
+	if next.IsZero() {
+		return 0, errors.Errorf("cron expression %q never matches", sched.Cron)
+	}
+	switch sched.OverlapPolicy {
+	case OverlapWait, OverlapSkip, OverlapConcurrent:
+	default:
+		return 0, errors.Errorf("unknown overlap policy %q", sched.OverlapPolicy)
+	}
+	// Make sure the statement can be turned into a runnable statement before
+	// accepting it.
+	if _, err := scheduledStatement(sched.Statement, timeutil.Now()); err != nil {
+		return 0, err
+	}
+
+	const stmt = `INSERT INTO system.scheduled_jobs
+(name, owner, cron, statement, overlap_policy, next_run)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	row, err := ex.QueryRowInTransaction(ctx, "schedule-insert", txn, stmt,
+		sched.Name, sched.Owner, sched.Cron, sched.Statement, sched.OverlapPolicy, next)
+	if err != nil {
+		return 0, err
+	}
+	return int64(*row[0].(*parser.DInt)), nil
+}
+
+// PauseSchedule stops the schedule with the given ID from firing until it is
+// resumed. Runs already in progress are not affected.
+func PauseSchedule(
+	ctx context.Context, ex sqlutil.InternalExecutor, txn *client.Txn, id int64,
+) error {
+	const stmt = `UPDATE system.scheduled_jobs SET paused = true WHERE id = $1`
+	return updateSchedule(ctx, ex, txn, "schedule-pause", id, stmt)
+}
+
+// ResumeSchedule resumes a paused schedule. Occurrences missed while the
+// schedule was paused are not fired; the next run is the next time after
+// now that matches the cron expression.
+func ResumeSchedule(
+	ctx context.Context, ex sqlutil.InternalExecutor, txn *client.Txn, id int64,
+) error {
+	row, err := ex.QueryRowInTransaction(ctx, "schedule-resume", txn,
+		`SELECT cron FROM system.scheduled_jobs WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if row == nil {
+		return errors.Errorf("schedule %d does not exist", id)
+	}
+	cron, err := ParseCronExpr(string(*row[0].(*parser.DString)))
+	if err != nil {
+		return err
+	}
+	const stmt = `UPDATE system.scheduled_jobs SET paused = false, next_run = $2 WHERE id = $1`
+	return updateSchedule(ctx, ex, txn, "schedule-resume", id, stmt, nextRun(cron, timeutil.Now()))
+}
+
+// nextRun returns the value to store in the next_run column of a schedule
+// with the given cron expression: the next matching time after now, or NULL
+// if there is none.
+func nextRun(cron CronExpr, now time.Time) interface{} {
+	if next := cron.Next(now); !next.IsZero() {
+		return next
+	}
+	return nil
+}
+
+func updateSchedule(
+	ctx context.Context,
+	ex sqlutil.InternalExecutor,
+	txn *client.Txn,
+	opName string,
+	id int64,
+	stmt string,
+	qargs ...interface{},
+) error {
+	n, err := ex.ExecuteStatementInTransaction(ctx, opName, txn, stmt, append([]interface{}{id}, qargs...)...)
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return errors.Errorf("schedule %d does not exist", id)
+	}
+	return nil
+}
+
+// scheduledStatement returns the SQL to execute for a run of a schedule
+// with the given statement that was due at runAt.
+func scheduledStatement(sql string, runAt time.Time) (string, error) {
+	stmt, err := parser.ParseOne(sql)
+	if err != nil {
+		return "", err
+	}
+	switch s := stmt.(type) {
+	case *parser.Backup:
+		to, ok := s.To.(*parser.StrVal)
+		if !ok {
+			return "", errors.Errorf("scheduled BACKUP destination must be a string literal, found %s", s.To)
+		}
+		d, err := to.ResolveAsType(&parser.SemaContext{}, parser.TypeString)
+		if err != nil {
+			return "", err
+		}
+		uri, err := url.Parse(string(*d.(*parser.DString)))
+		if err != nil {
+			return "", err
+		}
+		uri.Path = path.Join(uri.Path, runAt.UTC().Format(backupTimestampFormat))
+		s.To = parser.NewDString(uri.String())
+		return s.String(), nil
+	default:
+		return "", errors.Errorf("%s statements cannot be scheduled", stmt.StatementTag())
+	}
+}
+
+// StatementRunner executes a scheduled statement on behalf of user.
+type StatementRunner func(ctx context.Context, user string, stmt string) error
+
+// Scheduler fires the schedules stored in system.scheduled_jobs. Every node
+// runs a Scheduler; claiming a due schedule happens in a transaction that
+// advances its next run time, so each occurrence is fired by a single node.
+type Scheduler struct {
+	db    *client.DB
+	ex    sqlutil.InternalExecutor
+	run   StatementRunner
+	knobs *TestingKnobs
+}
+
+// NewScheduler creates a new Scheduler. Scheduled statements are executed by
+// run. knobs may be nil.
+func NewScheduler(
+	db *client.DB, ex sqlutil.InternalExecutor, run StatementRunner, knobs *TestingKnobs,
+) *Scheduler {
+	return &Scheduler{db: db, ex: ex, run: run, knobs: knobs}
+}
+
+// pollInterval returns the interval at which the scheduler polls for due
+// schedules.
+func (s *Scheduler) pollInterval() time.Duration {
+	if s.knobs != nil && s.knobs.SchedulerPollInterval != 0 {
+		return s.knobs.SchedulerPollInterval
+	}
+	return schedulerPollInterval.Get()
+}
+
+// Start runs the scheduler's poll loop until the stopper is stopped.
+func (s *Scheduler) Start(ctx context.Context, stopper *stop.Stopper) {
+	stopper.RunWorker(ctx, func(ctx context.Context) {
+		var timer timeutil.Timer
+		defer timer.Stop()
+		for {
+			timer.Reset(s.pollInterval())
+			select {
+			case <-timer.C:
+				timer.Read = true
+				if schedulerEnabled.Get() {
+					if err := s.fireDueSchedules(ctx, stopper, timeutil.Now()); err != nil {
+						log.Warningf(ctx, "failed to fire scheduled jobs: %v", err)
+					}
+				}
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// scheduledRun describes a single claimed run of a schedule.
+type scheduledRun struct {
+	id        int64
+	owner     string
+	statement string
+	runAt     time.Time
+	// claimedAt is the value the claim stored in running_since.
+	claimedAt time.Time
+}
+
+func (s *Scheduler) fireDueSchedules(
+	ctx context.Context, stopper *stop.Stopper, now time.Time,
+) error {
+	var rows []parser.Datums
+	if err := s.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		const stmt = `SELECT id FROM system.scheduled_jobs
+WHERE next_run <= $1 AND NOT paused ORDER BY next_run LIMIT $2`
+		var err error
+		rows, err = s.ex.QueryRowsInTransaction(ctx, "schedule-find-due", txn, stmt, now, maxSchedulesPerPoll)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		id := int64(*row[0].(*parser.DInt))
+		run, err := s.claim(ctx, id, now)
+		if err != nil {
+			log.Warningf(ctx, "failed to claim schedule %d: %v", id, err)
+			continue
+		}
+		if run == nil {
+			continue
+		}
+		if err := stopper.RunAsyncTask(ctx, "jobs.Scheduler: run schedule", func(ctx context.Context) {
+			s.execute(ctx, *run)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claim attempts to claim the current run of the schedule with the given ID,
+// returning nil if the schedule is no longer due or its overlap policy
+// prevents it from firing. A previous run that has not completed within
+// jobs.scheduler.run_timeout is presumed abandoned and does not prevent the
+// schedule from firing.
+func (s *Scheduler) claim(ctx context.Context, id int64, now time.Time) (*scheduledRun, error) {
+	var run *scheduledRun
+	err := s.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		run = nil
+		const selectStmt = `SELECT owner, cron, statement, overlap_policy, next_run, running_since
+FROM system.scheduled_jobs WHERE id = $1 AND next_run <= $2 AND NOT paused`
+		row, err := s.ex.QueryRowInTransaction(ctx, "schedule-claim", txn, selectStmt, id, now)
+		if err != nil || row == nil {
+			// Either something went wrong or another node got there first.
+			return err
+		}
+		owner := string(*row[0].(*parser.DString))
+		cronExpr := string(*row[1].(*parser.DString))
+		statement := string(*row[2].(*parser.DString))
+		policy := OverlapPolicy(*row[3].(*parser.DString))
+		runAt := row[4].(*parser.DTimestamp).Time
+		running := false
+		if row[5] != parser.DNull {
+			runningSince := row[5].(*parser.DTimestamp).Time
+			if running = now.Sub(runningSince) < schedulerRunTimeout.Get(); !running {
+				log.Warningf(ctx, "schedule %d has been running since %s, presuming the run abandoned",
+					id, runningSince)
+			}
+		}
+
+		cron, err := ParseCronExpr(cronExpr)
+		if err != nil {
+			// The schedule can never fire again; record why and stop
+			// considering it.
+			const disableStmt = `UPDATE system.scheduled_jobs SET next_run = NULL, last_error = $2 WHERE id = $1`
+			return updateSchedule(ctx, s.ex, txn, "schedule-disable", id, disableStmt, err.Error())
+		}
+		next := nextRun(cron, now)
+
+		if running {
+			switch policy {
+			case OverlapWait:
+				// Leave next_run alone so the run fires as soon as the
+				// previous one completes.
+				return nil
+			case OverlapSkip:
+				const skipStmt = `UPDATE system.scheduled_jobs SET next_run = $2 WHERE id = $1`
+				return updateSchedule(ctx, s.ex, txn, "schedule-skip", id, skipStmt, next)
+			}
+		}
+
+		const claimStmt = `UPDATE system.scheduled_jobs SET next_run = $2, running_since = $3 WHERE id = $1`
+		if err := updateSchedule(ctx, s.ex, txn, "schedule-claim", id, claimStmt, next, now); err != nil {
+			return err
+		}
+		run = &scheduledRun{id: id, owner: owner, statement: statement, runAt: runAt, claimedAt: now}
+		return nil
+	})
+	return run, err
+}
+
+// execute runs a claimed schedule and records the outcome. Errors are
+// recorded in the schedule's last_error column rather than returned.
+func (s *Scheduler) execute(ctx context.Context, run scheduledRun) {
+	log.Infof(ctx, "running schedule %d due at %s", run.id, run.runAt)
+	sql, err := scheduledStatement(run.statement, run.runAt)
+	if err == nil {
+		err = s.run(ctx, run.owner, sql)
+	}
+	var lastError interface{}
+	if err != nil {
+		log.Warningf(ctx, "schedule %d failed: %v", run.id, err)
+		lastError = err.Error()
+	}
+
+	// Only clear running_since if it still records this run's claim. It
+	// differs if the schedule was claimed again in the meantime, either
+	// because of OverlapConcurrent or because this run took long enough to
+	// be presumed abandoned.
+	if err := s.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		const stmt = `UPDATE system.scheduled_jobs
+SET running_since = CASE WHEN running_since = $4 THEN NULL ELSE running_since END,
+    last_run = $2, last_error = $3
+WHERE id = $1`
+		return updateSchedule(ctx, s.ex, txn, "schedule-finish", run.id, stmt,
+			timeutil.Now(), lastError, run.claimedAt)
+	}); err != nil {
+		log.Warningf(ctx, "failed to record completion of schedule %d: %v", run.id, err)
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package jobs_test
+
+import (
+	gosql "database/sql"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/sql"
+	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestScheduledStatement(t *testing.T) {
+	runAt := time.Date(2017, 6, 14, 2, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		stmt     string
+		expected string
+		err      string
+	}{
+		{
+			stmt:     `BACKUP DATABASE bank TO 'nodelocal:///backups/bank'`,
+			expected: `BACKUP DATABASE bank TO 'nodelocal:///backups/bank/20170614-020000'`,
+		},
+		{
+			stmt:     `BACKUP TABLE bank.accounts TO 's3://bucket/path?AWS_REGION=us-east-1'`,
+			expected: `BACKUP TABLE bank.accounts TO 's3://bucket/path/20170614-020000?AWS_REGION=us-east-1'`,
+		},
+		{
+			stmt: `BACKUP DATABASE bank TO $1`,
+			err:  `scheduled BACKUP destination must be a string literal`,
+		},
+		{
+			stmt: `SELECT 1`,
+			err:  `SELECT statements cannot be scheduled`,
+		},
+		{
+			stmt: `BACKUP`,
+			err:  `syntax error`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.stmt, func(t *testing.T) {
+			actual, err := jobs.ScheduledStatement(tc.stmt, runAt)
+			if tc.err != "" {
+				if !testutils.IsError(err, tc.err) {
+					t.Fatalf("expected error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+// schedulerTest drives a Scheduler by hand, with a statement runner that
+// records the statements it is asked to run instead of running them.
+type schedulerTest struct {
+	t         *testing.T
+	kvDB      *client.DB
+	sqlDB     *sqlutils.SQLRunner
+	ex        sql.InternalExecutor
+	scheduler *jobs.Scheduler
+
+	// ran records the statements passed to the runner, which fails with
+	// runErr if it is set.
+	ran    []string
+	runErr error
+}
+
+func newSchedulerTest(
+	t *testing.T, s serverutils.TestServerInterface, sqlDB *gosql.DB, kvDB *client.DB,
+) *schedulerTest {
+	st := &schedulerTest{
+		t:     t,
+		kvDB:  kvDB,
+		sqlDB: sqlutils.MakeSQLRunner(t, sqlDB),
+		ex:    sql.InternalExecutor{LeaseManager: s.LeaseManager().(*sql.LeaseManager)},
+	}
+	st.scheduler = jobs.NewScheduler(st.kvDB, st.ex, func(_ context.Context, user, stmt string) error {
+		if user != "testuser" {
+			t.Errorf("expected statement to run as testuser, got %s", user)
+		}
+		st.ran = append(st.ran, stmt)
+		return st.runErr
+	}, nil)
+	return st
+}
+
+// create creates an hourly schedule with the given overlap policy and returns
+// its ID along with its first run time.
+func (st *schedulerTest) create(policy jobs.OverlapPolicy) (int64, time.Time) {
+	var id int64
+	if err := st.kvDB.Txn(context.TODO(), func(ctx context.Context, txn *client.Txn) error {
+		var err error
+		id, err = jobs.CreateSchedule(ctx, st.ex, txn, jobs.ScheduleRecord{
+			Name:          string(policy),
+			Owner:         "testuser",
+			Cron:          "@hourly",
+			Statement:     `BACKUP DATABASE bank TO 'nodelocal:///backups'`,
+			OverlapPolicy: policy,
+		})
+		return err
+	}); err != nil {
+		st.t.Fatal(err)
+	}
+	return id, st.nextRun(id)
+}
+
+func (st *schedulerTest) claim(id int64, now time.Time) func(context.Context) {
+	run, err := st.scheduler.Claim(context.TODO(), id, now)
+	if err != nil {
+		st.t.Fatal(err)
+	}
+	return run
+}
+
+func (st *schedulerTest) nextRun(id int64) time.Time {
+	var nextRun time.Time
+	st.sqlDB.QueryRow(`SELECT next_run FROM system.scheduled_jobs WHERE id = $1`, id).Scan(&nextRun)
+	return nextRun
+}
+
+func (st *schedulerTest) running(id int64) bool {
+	var runningSince pq.NullTime
+	st.sqlDB.QueryRow(
+		`SELECT running_since FROM system.scheduled_jobs WHERE id = $1`, id,
+	).Scan(&runningSince)
+	return runningSince.Valid
+}
+
+func (st *schedulerTest) lastError(id int64) gosql.NullString {
+	var lastError gosql.NullString
+	st.sqlDB.QueryRow(
+		`SELECT last_error FROM system.scheduled_jobs WHERE id = $1`, id,
+	).Scan(&lastError)
+	return lastError
+}
+
+func (st *schedulerTest) expectNextRun(id int64, expected time.Time) {
+	if actual := st.nextRun(id); !actual.Equal(expected) {
+		st.t.Fatalf("expected next run at %s, got %s", expected, actual)
+	}
+}
+
+func (st *schedulerTest) expectRunning(id int64, expected bool) {
+	if actual := st.running(id); actual != expected {
+		st.t.Fatalf("expected running=%t, got %t", expected, actual)
+	}
+}
+
+func TestSchedulerOverlapPolicies(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Keep the server's own scheduler from firing the schedules under test.
+	s, sqlDB, kvDB := serverutils.StartServer(t, base.TestServerArgs{
+		Knobs: base.TestingKnobs{SQLJobs: &jobs.TestingKnobs{SchedulerPollInterval: time.Hour}},
+	})
+	defer s.Stopper().Stop(context.TODO())
+	st := newSchedulerTest(t, s, sqlDB, kvDB)
+
+	testCases := []struct {
+		policy jobs.OverlapPolicy
+		// claimOverlap is whether a run is claimed while the previous one is
+		// still in progress.
+		claimOverlap bool
+		// skipOverlap is whether the overlapping run is dropped rather than
+		// fired once the previous run completes.
+		skipOverlap bool
+	}{
+		{policy: jobs.OverlapWait},
+		{policy: jobs.OverlapSkip, skipOverlap: true},
+		{policy: jobs.OverlapConcurrent, claimOverlap: true},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			st.t = t
+			st.ran = nil
+			id, first := st.create(tc.policy)
+			second := first.Add(time.Hour)
+
+			if run := st.claim(id, first.Add(-time.Second)); run != nil {
+				t.Fatal("claimed a schedule before it was due")
+			}
+			firstRun := st.claim(id, first)
+			if firstRun == nil {
+				t.Fatal("failed to claim a due schedule")
+			}
+			st.expectNextRun(id, second)
+			st.expectRunning(id, true)
+			if run := st.claim(id, first); run != nil {
+				t.Fatal("claimed the same run twice")
+			}
+
+			// The second occurrence comes due while the first is running.
+			secondRun := st.claim(id, second)
+			if (secondRun != nil) != tc.claimOverlap {
+				t.Fatalf("expected claimed=%t for the overlapping run, got %t",
+					tc.claimOverlap, secondRun != nil)
+			}
+			if tc.claimOverlap || tc.skipOverlap {
+				st.expectNextRun(id, second.Add(time.Hour))
+			} else {
+				st.expectNextRun(id, second)
+			}
+
+			firstRun(context.TODO())
+			expected := `BACKUP DATABASE bank TO 'nodelocal:///backups/` +
+				first.UTC().Format("20060102-150405") + `'`
+			if len(st.ran) != 1 || st.ran[0] != expected {
+				t.Fatalf("expected %s to run, got %v", expected, st.ran)
+			}
+			// The second run's claim is still in progress for
+			// OverlapConcurrent and must not be cleared by the first run.
+			st.expectRunning(id, tc.claimOverlap)
+
+			if !tc.claimOverlap && !tc.skipOverlap {
+				// With OverlapWait, the delayed run fires as soon as the
+				// previous one has completed.
+				if secondRun = st.claim(id, second.Add(time.Minute)); secondRun == nil {
+					t.Fatal("failed to claim the delayed run")
+				}
+				st.expectNextRun(id, second.Add(time.Hour))
+			}
+			if secondRun != nil {
+				secondRun(context.TODO())
+				if len(st.ran) != 2 {
+					t.Fatalf("expected 2 runs, got %v", st.ran)
+				}
+			}
+			st.expectRunning(id, false)
+		})
+	}
+}
+
+func TestSchedulerAbandonedRun(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	s, sqlDB, kvDB := serverutils.StartServer(t, base.TestServerArgs{
+		Knobs: base.TestingKnobs{SQLJobs: &jobs.TestingKnobs{SchedulerPollInterval: time.Hour}},
+	})
+	defer s.Stopper().Stop(context.TODO())
+	st := newSchedulerTest(t, s, sqlDB, kvDB)
+
+	id, first := st.create(jobs.OverlapWait)
+	abandonedRun := st.claim(id, first)
+	if abandonedRun == nil {
+		t.Fatal("failed to claim a due schedule")
+	}
+
+	// A run that has not completed within jobs.scheduler.run_timeout no
+	// longer holds up the schedule.
+	now := first.Add(25 * time.Hour)
+	run := st.claim(id, now)
+	if run == nil {
+		t.Fatal("an abandoned run prevented the schedule from firing")
+	}
+	st.expectNextRun(id, first.Add(26*time.Hour))
+
+	// If the abandoned run does complete after all, it must not clear the
+	// claim of the run that replaced it.
+	st.runErr = errors.New("boom")
+	abandonedRun(context.TODO())
+	st.expectRunning(id, true)
+	if lastError := st.lastError(id); !lastError.Valid || lastError.String != "boom" {
+		t.Fatalf("expected last_error to be boom, got %v", lastError)
+	}
+
+	st.runErr = nil
+	run(context.TODO())
+	st.expectRunning(id, false)
+	if lastError := st.lastError(id); lastError.Valid {
+		t.Fatalf("expected last_error to be cleared, got %q", lastError.String)
+	}
+}
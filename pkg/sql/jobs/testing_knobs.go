@@ -0,0 +1,55 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package jobs
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+)
+
+// JobEvent identifies a point in the lifetime of a job at which the
+// BeforeJobEvent testing knob is called.
+type JobEvent string
+
+const (
+	// JobEventStarted is reported by JobLogger.Started.
+	JobEventStarted JobEvent = "started"
+	// JobEventProgressed is reported by JobLogger.Progressed.
+	JobEventProgressed JobEvent = "progressed"
+	// JobEventSucceeded is reported by JobLogger.Succeeded.
+	JobEventSucceeded JobEvent = "succeeded"
+	// JobEventFailed is reported by JobLogger.Failed.
+	JobEventFailed JobEvent = "failed"
+)
+
+// TestingKnobs are the testing knobs for jobs.
+type TestingKnobs struct {
+	// BeforeJobEvent, if set, is called before the job record is updated for
+	// the given event. Blocking in the callback pauses the job at that
+	// checkpoint. A returned error is returned by the corresponding JobLogger
+	// method without updating the record, except for JobEventFailed, for which
+	// the error is only logged.
+	BeforeJobEvent func(jobID int64, event JobEvent) error
+
+	// SchedulerPollInterval, if nonzero, overrides the
+	// jobs.scheduler.poll_interval cluster setting.
+	SchedulerPollInterval time.Duration
+}
+
+var _ base.ModuleTestingKnobs = &TestingKnobs{}
+
+// ModuleTestingKnobs is part of the base.ModuleTestingKnobs interface.
+func (*TestingKnobs) ModuleTestingKnobs() {}
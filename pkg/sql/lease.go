@@ -33,6 +33,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/gossip"
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/security"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
@@ -41,6 +42,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/retry"
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 )
 
 // TODO(pmattis): Periodically renew leases for tables that were used recently and
@@ -303,6 +305,22 @@ func (s LeaseStore) Publish(
 	tableID sqlbase.ID,
 	update func(*sqlbase.TableDescriptor) error,
 	logEvent func(*client.Txn) error,
+) (*sqlbase.Descriptor, error) {
+	return s.publishWithTxn(ctx, tableID, func(_ *client.Txn, desc *sqlbase.TableDescriptor) error {
+		return update(desc)
+	}, logEvent)
+}
+
+// publishWithTxn is like Publish, but the update closure is also given the
+// transaction writing the new version of the descriptor, so that it can
+// write the records the new version refers to atomically with it. Since
+// the closure is called again when the transaction is retried, whatever it
+// writes must be written anew on each call.
+func (s LeaseStore) publishWithTxn(
+	ctx context.Context,
+	tableID sqlbase.ID,
+	update func(*client.Txn, *sqlbase.TableDescriptor) error,
+	logEvent func(*client.Txn) error,
 ) (*sqlbase.Descriptor, error) {
 	errLeaseVersionChanged := errors.New("lease version changed")
 	// Retry while getting errLeaseVersionChanged.
@@ -340,7 +358,7 @@ func (s LeaseStore) Publish(
 
 			// Run the update closure.
 			version := tableDesc.Version
-			if err := update(tableDesc); err != nil {
+			if err := update(txn, tableDesc); err != nil {
 				return err
 			}
 			if version != tableDesc.Version {
@@ -1143,6 +1161,27 @@ func (m *LeaseManager) Acquire(
 	return lease, err
 }
 
+// acquireRenewed returns the newest lease the manager holds on the descriptor
+// version of the given lease, if it expires after it: the lease was renewed,
+// e.g. by a transaction which found it about to expire. No lease is acquired
+// from the store; nil is returned if the lease wasn't renewed. The returned
+// lease had its refcount incremented, so the caller is responsible for
+// release()ing it.
+func (m *LeaseManager) acquireRenewed(lease *LeaseState) *LeaseState {
+	t := m.findTableState(lease.ID, false /* create */)
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	newest := t.active.findNewest(lease.Version)
+	if newest == nil || !newest.expiration.After(lease.expiration.Time) {
+		return nil
+	}
+	newest.incRefcount()
+	return newest
+}
+
 // acquireFreshestFromStore acquires a new lease from the store. The returned
 // lease is guaranteed to have a version of the descriptor at least as recent as
 // the time of the call (i.e. if we were in the process of acquiring a lease
@@ -1217,62 +1256,49 @@ func (m *LeaseManager) findTableState(tableID sqlbase.ID, create bool) *tableSta
 
 // RefreshLeases starts a goroutine that refreshes the lease manager
 // leases for tables received in the latest system configuration via gossip.
+// Only the descriptors which changed since the previous system configuration
+// are processed, along with those which failed to be processed before. All
+// the descriptors are processed again every LeaseDuration, in case some
+// failures aren't fixed by retrying.
 func (m *LeaseManager) RefreshLeases(s *stop.Stopper, db *client.DB, gossip *gossip.Gossip) {
 	ctx := context.TODO()
 	s.RunWorker(ctx, func(ctx context.Context) {
-		descKeyPrefix := keys.MakeTablePrefix(uint32(sqlbase.DescriptorTable.ID))
-		gossipUpdateC := gossip.RegisterSystemConfigChannel()
+		descKeyPrefix := roachpb.Key(keys.MakeTablePrefix(uint32(sqlbase.DescriptorTable.ID)))
+		watcher := gossip.WatchSystemConfig(roachpb.Span{
+			Key: descKeyPrefix, EndKey: descKeyPrefix.PrefixEnd(),
+		})
+		// retry holds the descriptors whose leases couldn't be refreshed, which
+		// are retried on the next update.
+		var retry []roachpb.KeyValue
+		var fullPass timeutil.Timer
+		defer fullPass.Stop()
+		fullPass.Reset(LeaseDuration)
 		for {
 			select {
-			case <-gossipUpdateC:
-				cfg, _ := gossip.GetSystemConfig()
+			case <-watcher.C:
+				cfg, changes := watcher.Changes()
 				if m.testingKnobs.GossipUpdateEvent != nil {
 					m.testingKnobs.GossipUpdateEvent(cfg)
 				}
-				// Read all tables and their versions
 				if log.V(2) {
-					log.Info(ctx, "received a new config; will refresh leases")
-				}
-
-				// Loop through the configuration to find all the tables.
-				for _, kv := range cfg.Values {
-					if !bytes.HasPrefix(kv.Key, descKeyPrefix) {
-						continue
-					}
-					// Attempt to unmarshal config into a table/database descriptor.
-					var descriptor sqlbase.Descriptor
-					if err := kv.Value.GetProto(&descriptor); err != nil {
-						log.Warningf(ctx, "%s: unable to unmarshal descriptor %v", kv.Key, kv.Value)
-						continue
-					}
-					switch union := descriptor.Union.(type) {
-					case *sqlbase.Descriptor_Table:
-						table := union.Table
-						table.MaybeUpgradeFormatVersion()
-						if err := table.ValidateTable(); err != nil {
-							log.Errorf(ctx, "%s: received invalid table descriptor: %v", kv.Key, table)
-							continue
-						}
-						if log.V(2) {
-							log.Infof(ctx, "%s: refreshing lease table: %d (%s), version: %d, dropped: %t",
-								kv.Key, table.ID, table.Name, table.Version, table.Dropped())
-						}
-						// Try to refresh the table lease to one >= this version.
-						if t := m.findTableState(table.ID, false /* create */); t != nil {
-							if err := t.purgeOldLeases(
-								ctx, db, table.Dropped(), table.Version, m); err != nil {
-								log.Warningf(ctx, "error purging leases for table %d(%s): %s",
-									table.ID, table.Name, err)
-							}
-						}
-					case *sqlbase.Descriptor_Database:
-						// Ignore.
-					}
+					log.Infof(ctx, "received a new config with %d changed descriptors; will refresh leases",
+						len(changes))
 				}
+				retry = m.refreshLeases(ctx, db, append(retry, changes...))
 				if m.testingKnobs.TestingLeasesRefreshedEvent != nil {
 					m.testingKnobs.TestingLeasesRefreshedEvent(cfg)
 				}
 
+			case <-fullPass.C:
+				fullPass.Read = true
+				fullPass.Reset(LeaseDuration)
+				watcher.Reset()
+				_, descs := watcher.Changes()
+				if log.V(2) {
+					log.Infof(ctx, "refreshing the leases of all %d descriptors", len(descs))
+				}
+				retry = m.refreshLeases(ctx, db, descs)
+
 			case <-s.ShouldStop():
 				return
 			}
@@ -1280,6 +1306,52 @@ func (m *LeaseManager) RefreshLeases(s *stop.Stopper, db *client.DB, gossip *gos
 	})
 }
 
+// refreshLeases refreshes the leases of the tables whose descriptors are
+// given, if they changed, and returns the descriptors whose leases couldn't be
+// refreshed. Deleted descriptors have an empty value.
+func (m *LeaseManager) refreshLeases(
+	ctx context.Context, db *client.DB, descs []roachpb.KeyValue,
+) []roachpb.KeyValue {
+	var failed []roachpb.KeyValue
+	for _, kv := range descs {
+		if kv.Value.RawBytes == nil {
+			// The descriptor was deleted.
+			continue
+		}
+		// Attempt to unmarshal config into a table/database descriptor.
+		var descriptor sqlbase.Descriptor
+		if err := kv.Value.GetProto(&descriptor); err != nil {
+			log.Warningf(ctx, "%s: unable to unmarshal descriptor %v", kv.Key, kv.Value)
+			continue
+		}
+		switch union := descriptor.Union.(type) {
+		case *sqlbase.Descriptor_Table:
+			table := union.Table
+			table.MaybeUpgradeFormatVersion()
+			if err := table.ValidateTable(); err != nil {
+				log.Errorf(ctx, "%s: received invalid table descriptor: %v", kv.Key, table)
+				continue
+			}
+			if log.V(2) {
+				log.Infof(ctx, "%s: refreshing lease table: %d (%s), version: %d, dropped: %t",
+					kv.Key, table.ID, table.Name, table.Version, table.Dropped())
+			}
+			// Try to refresh the table lease to one >= this version.
+			if t := m.findTableState(table.ID, false /* create */); t != nil {
+				if err := t.purgeOldLeases(
+					ctx, db, table.Dropped(), table.Version, m); err != nil {
+					log.Warningf(ctx, "error purging leases for table %d(%s): %s",
+						table.ID, table.Name, err)
+					failed = append(failed, kv)
+				}
+			}
+		case *sqlbase.Descriptor_Database:
+			// Ignore.
+		}
+	}
+	return failed
+}
+
 // LeaseCollection is a collection of leases held by a single session that
 // serves SQL requests, or a background job using a table descriptor.
 type LeaseCollection struct {
@@ -31,6 +31,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 )
 
@@ -499,3 +500,57 @@ CREATE TABLE t.test (k CHAR PRIMARY KEY, v CHAR);
 	}
 	wg.Wait()
 }
+
+// TestRefreshDeadlineAdoptsRenewedLease tests that refreshing the deadline of
+// a transaction adopts the lease renewed by the lease manager since the
+// transaction acquired its own.
+func TestRefreshDeadlineAdoptsRenewedLease(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, db, kvDB := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+	leaseManager := s.LeaseManager().(*LeaseManager)
+
+	if _, err := db.Exec(`
+CREATE DATABASE t;
+CREATE TABLE t.test (k CHAR PRIMARY KEY, v CHAR);
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	tableDesc := sqlbase.GetTableDescriptor(kvDB, "t", "test")
+
+	lc := LeaseCollection{leaseMgr: leaseManager}
+	defer lc.releaseLeases(context.TODO())
+	err := kvDB.Txn(context.TODO(), func(ctx context.Context, txn *client.Txn) error {
+		lc.releaseLeases(ctx)
+		if _, err := lc.getTableLeaseByID(ctx, txn, tableDesc.ID); err != nil {
+			return err
+		}
+		lease := lc.leases[0]
+
+		// Renew the lease, as a transaction finding it about to expire would.
+		renewed, err := leaseManager.acquireFreshestFromStore(ctx, txn, tableDesc.ID)
+		if err != nil {
+			return err
+		}
+		if err := leaseManager.Release(renewed); err != nil {
+			return err
+		}
+
+		lc.refreshDeadline(ctx, txn)
+		if lc.leases[0] != renewed {
+			t.Fatalf("expected lease %s to be replaced with %s, got %s", lease, renewed, lc.leases[0])
+		}
+		expected := hlc.Timestamp{WallTime: renewed.Expiration().UnixNano()}
+		if deadline := txn.GetDeadline(); deadline == nil || *deadline != expected {
+			t.Fatalf("expected deadline %s, got %v", expected, deadline)
+		}
+		if refcount := lease.Refcount(); refcount != 0 {
+			t.Fatalf("expected lease %s to be released, refcount %d", lease, refcount)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
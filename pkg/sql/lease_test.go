@@ -37,6 +37,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/server"
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
@@ -713,6 +714,211 @@ INSERT INTO t.kv VALUES ('a', 'b');
 	}
 }
 
+// TestTxnRefreshesLeaseDeadline tests that a transaction outliving the lease
+// it acquired can commit when the lease can be extended, and that it's still
+// aborted when a schema change has published a new version of the table in
+// the meantime.
+func TestTxnRefreshesLeaseDeadline(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Use short leases so that they expire while the transactions below are
+	// open.
+	savedLeaseDuration, savedMinLeaseDuration := sql.LeaseDuration, sql.MinLeaseDuration
+	defer func() {
+		sql.LeaseDuration, sql.MinLeaseDuration = savedLeaseDuration, savedMinLeaseDuration
+	}()
+	sql.LeaseDuration = 2 * time.Second
+	sql.MinLeaseDuration = time.Second
+
+	var mu syncutil.Mutex
+	expirations := make(map[string]time.Time)
+	params, _ := createTestServerParams()
+	params.Knobs = base.TestingKnobs{
+		SQLLeaseManager: &sql.LeaseManagerTestingKnobs{
+			LeaseStoreTestingKnobs: sql.LeaseStoreTestingKnobs{
+				LeaseAcquiredEvent: func(lease *sql.LeaseState, err error) {
+					if err != nil {
+						return
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					if lease.Expiration().After(expirations[lease.Name]) {
+						expirations[lease.Name] = lease.Expiration()
+					}
+				},
+			},
+		},
+	}
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop(context.TODO())
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE t;
+CREATE TABLE t.extended (k CHAR PRIMARY KEY, v CHAR);
+CREATE TABLE t.altered (k CHAR PRIMARY KEY, v CHAR);
+INSERT INTO t.extended VALUES ('a', 'b');
+INSERT INTO t.altered VALUES ('a', 'b');
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		table        string
+		schemaChange bool
+		expectedErr  string
+	}{
+		{"extended", false, ""},
+		{"altered", true, "transaction deadline exceeded"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.table, func(t *testing.T) {
+			txn, err := sqlDB.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := txn.Exec(`SET TRANSACTION ISOLATION LEVEL SNAPSHOT`); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := txn.Exec(`SET TRANSACTION PRIORITY LOW`); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := txn.Exec(fmt.Sprintf(`UPDATE t.%s SET v = 'c' WHERE k = 'a'`, tc.table)); err != nil {
+				t.Fatal(err)
+			}
+			mu.Lock()
+			expiration := expirations[tc.table]
+			mu.Unlock()
+
+			if tc.schemaChange {
+				// The schema change proceeds once the lease held by the transaction
+				// has expired.
+				if _, err := sqlDB.Exec(fmt.Sprintf(`ALTER TABLE t.%s ADD COLUMN x INT`, tc.table)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			testutils.SucceedsSoon(t, func() error {
+				if now := s.Clock().Now().GoTime(); !now.After(expiration) {
+					return fmt.Errorf("lease expires at %s, now %s", expiration, now)
+				}
+				return nil
+			})
+
+			// A high priority reader pushes the timestamp of the transaction past
+			// the expiration of its lease, and thus past its deadline.
+			reader, err := sqlDB.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := reader.Exec(`SET TRANSACTION PRIORITY HIGH`); err != nil {
+				t.Fatal(err)
+			}
+			var v string
+			if err := reader.QueryRow(
+				fmt.Sprintf(`SELECT v FROM t.%s WHERE k = 'a'`, tc.table),
+			).Scan(&v); err != nil {
+				t.Fatal(err)
+			}
+			if err := reader.Commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := txn.Commit(); !testutils.IsError(err, tc.expectedErr) {
+				t.Fatalf("expected error %q, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+// TestAutoCommitRefreshesLeaseDeadline tests that a statement committing its
+// transaction can commit after the lease it acquired has expired.
+func TestAutoCommitRefreshesLeaseDeadline(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Use short leases so that they expire while the statement below runs.
+	savedLeaseDuration, savedMinLeaseDuration := sql.LeaseDuration, sql.MinLeaseDuration
+	defer func() {
+		sql.LeaseDuration, sql.MinLeaseDuration = savedLeaseDuration, savedMinLeaseDuration
+	}()
+	sql.LeaseDuration = 2 * time.Second
+	sql.MinLeaseDuration = time.Second
+
+	var mu syncutil.Mutex
+	var expiration time.Time
+	params, cmdFilters := createTestServerParams()
+	params.Knobs = base.TestingKnobs{
+		Store: params.Knobs.Store,
+		SQLLeaseManager: &sql.LeaseManagerTestingKnobs{
+			LeaseStoreTestingKnobs: sql.LeaseStoreTestingKnobs{
+				LeaseAcquiredEvent: func(lease *sql.LeaseState, err error) {
+					if err != nil || lease.Name != "kv" {
+						return
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					if lease.Expiration().After(expiration) {
+						expiration = lease.Expiration()
+					}
+				},
+			},
+		},
+	}
+	s, sqlDB, kvDB := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop(context.TODO())
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE t;
+CREATE TABLE t.kv (k CHAR PRIMARY KEY, v CHAR);
+INSERT INTO t.kv VALUES ('a', 'b');
+`); err != nil {
+		t.Fatal(err)
+	}
+	tableDesc := sqlbase.GetTableDescriptor(kvDB, "t", "kv")
+	tablePrefix := roachpb.Key(keys.MakeTablePrefix(uint32(tableDesc.ID)))
+
+	// Block the scan of the statement below until its lease has expired.
+	var blockScan int32 = 1
+	blocked := make(chan struct{})
+	unblock := make(chan struct{})
+	defer cmdFilters.AppendFilter(func(args storagebase.FilterArgs) *roachpb.Error {
+		if _, ok := args.Req.(*roachpb.ScanRequest); !ok || !bytes.HasPrefix(args.Req.Header().Key, tablePrefix) {
+			return nil
+		}
+		if atomic.CompareAndSwapInt32(&blockScan, 1, 0) {
+			blocked <- struct{}{}
+			<-unblock
+		}
+		return nil
+	}, true)()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := sqlDB.Exec(`UPDATE t.kv SET v = 'c' WHERE k = 'a'`)
+		errCh <- err
+	}()
+	<-blocked
+	mu.Lock()
+	leaseExpiration := expiration
+	mu.Unlock()
+	testutils.SucceedsSoon(t, func() error {
+		if now := s.Clock().Now().GoTime(); !now.After(leaseExpiration) {
+			return fmt.Errorf("lease expires at %s, now %s", leaseExpiration, now)
+		}
+		return nil
+	})
+
+	// A read pushes the timestamp of the statement's write past the
+	// expiration of its lease, and thus past its deadline.
+	var v string
+	if err := sqlDB.QueryRow(`SELECT v FROM t.kv WHERE k = 'a'`).Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	close(unblock)
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
 // TestSubqueryLeases tests that all leases acquired by a subquery are
 // properly tracked and released.
 func TestSubqueryLeases(t *testing.T) {
@@ -0,0 +1,178 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+)
+
+// tableResolver resolves unqualified table names using the name resolution
+// session variables: the current database and the search_path. It is shared
+// by all statements that look up existing tables and views, so that the same
+// name refers to the same object regardless of the statement it appears in.
+//
+// The rules follow PostgreSQL's, with the session's current database playing
+// the role of the "public" schema:
+//
+//  - search_path entries are searched in order; a "public" entry refers to
+//    the current database.
+//  - if search_path does not mention "public", the current database is
+//    searched right after pg_catalog when pg_catalog leads the path (which is
+//    the case unless it was explicitly placed elsewhere), and first otherwise.
+//
+// Each database is searched at most once.
+type tableResolver struct {
+	database   string
+	searchPath parser.SearchPath
+}
+
+// makeTableResolver creates a tableResolver from the session's current name
+// resolution variables.
+func makeTableResolver(session *Session) tableResolver {
+	return tableResolver{database: session.Database, searchPath: session.SearchPath}
+}
+
+// searchOrder returns the list of databases, in order, in which an unqualified
+// table name is looked up.
+func (r tableResolver) searchOrder() []string {
+	order := make([]string, 0, len(r.searchPath)+1)
+	seen := make(map[string]struct{}, len(r.searchPath)+1)
+	add := func(db string) {
+		if db == "" {
+			return
+		}
+		if _, ok := seen[db]; ok {
+			return
+		}
+		seen[db] = struct{}{}
+		order = append(order, db)
+	}
+	path := r.searchPath
+	explicitPublic := false
+	for _, db := range path {
+		if db == parser.PublicSchema {
+			explicitPublic = true
+			break
+		}
+	}
+	if !explicitPublic {
+		if len(path) > 0 && path[0] == pgCatalogName {
+			// pg_catalog leads the path, either implicitly or explicitly: it
+			// takes precedence over the current database.
+			add(pgCatalogName)
+			path = path[1:]
+		}
+		add(r.database)
+	}
+	for _, db := range path {
+		if db == parser.PublicSchema {
+			db = r.database
+		}
+		add(db)
+	}
+	return order
+}
+
+// resolveTableName augments the table name with the database where it was
+// found, using the planner's session to determine the search order. Names
+// that are already qualified are left untouched. The provided TableName is
+// modified in-place in case of success, and left unchanged otherwise.
+func (p *planner) resolveTableName(ctx context.Context, tn *parser.TableName) error {
+	if tn.DatabaseName != "" {
+		return nil
+	}
+
+	descFunc := p.session.leases.getTableLease
+	if p.avoidCachedDescriptors {
+		// AS OF SYSTEM TIME queries need to fetch the table descriptor at the
+		// specified time, and never lease anything. The proto transaction already
+		// has its timestamps set correctly so getTableOrViewDesc will fetch with
+		// the correct timestamp.
+		descFunc = getTableOrViewDesc
+	}
+
+	t := *tn
+	for _, database := range makeTableResolver(p.session).searchOrder() {
+		t.DatabaseName = parser.Name(database)
+		desc, err := descFunc(ctx, p.txn, p.getVirtualTabler(), &t)
+		if err != nil && !sqlbase.IsUndefinedTableError(err) && !sqlbase.IsUndefinedDatabaseError(err) {
+			return err
+		}
+		if desc != nil {
+			// The table or view exists in this database, so use this name.
+			*tn = t
+			return nil
+		}
+	}
+
+	return sqlbase.NewUndefinedTableError(string(t.TableName))
+}
+
+// QualifyWithDatabase normalizes the table name and resolves it, if
+// unqualified, using the session's current database and search_path.
+func (p *planner) QualifyWithDatabase(
+	ctx context.Context, t *parser.NormalizableTableName,
+) (*parser.TableName, error) {
+	tn, err := t.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.resolveTableName(ctx, tn); err != nil {
+		return nil, err
+	}
+	return tn, nil
+}
+
+// normalizeTableName normalizes the table name and, if it is unqualified,
+// resolves it using the session's current database and search_path. Unlike
+// QualifyWithDatabase, a name which doesn't resolve to an existing table or
+// view is qualified with the current database rather than rejected, so that
+// the caller can report the missing table, or ignore it (e.g. because of IF
+// EXISTS), as it would for a qualified name.
+func (p *planner) normalizeTableName(
+	ctx context.Context, t *parser.NormalizableTableName,
+) (*parser.TableName, error) {
+	tn, err := t.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.qualifyTableName(ctx, tn); err != nil {
+		return nil, err
+	}
+	return tn, nil
+}
+
+// qualifyTableName is normalizeTableName for a table name which is already
+// normalized.
+func (p *planner) qualifyTableName(ctx context.Context, tn *parser.TableName) error {
+	if err := p.resolveTableName(ctx, tn); err != nil {
+		if !sqlbase.IsUndefinedTableError(err) {
+			return err
+		}
+		return tn.QualifyWithDatabase(p.session.Database)
+	}
+	return nil
+}
+
+// normalizeNewTableName normalizes the name of a table or view about to be
+// created. Unqualified names are qualified with the current database, which
+// plays the role of PostgreSQL's "public" schema where new objects are
+// created by default.
+func (p *planner) normalizeNewTableName(t *parser.NormalizableTableName) (*parser.TableName, error) {
+	return t.NormalizeWithDatabaseName(p.session.Database)
+}
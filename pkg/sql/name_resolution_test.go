@@ -0,0 +1,49 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestTableResolverSearchOrder(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		database   string
+		searchPath parser.SearchPath
+		expected   []string
+	}{
+		{"", parser.SearchPath{"pg_catalog"}, []string{"pg_catalog"}},
+		{"test", parser.SearchPath{"pg_catalog"}, []string{"pg_catalog", "test"}},
+		{"test", parser.SearchPath{"pg_catalog", "foo"}, []string{"pg_catalog", "test", "foo"}},
+		{"test", parser.SearchPath{"foo", "pg_catalog"}, []string{"test", "foo", "pg_catalog"}},
+		{"test", parser.SearchPath{"pg_catalog", "foo", "test"}, []string{"pg_catalog", "test", "foo"}},
+		{"test", parser.SearchPath{"foo", "public", "pg_catalog"}, []string{"foo", "test", "pg_catalog"}},
+		{"test", parser.SearchPath{"pg_catalog", "foo", "public"}, []string{"pg_catalog", "foo", "test"}},
+		{"", parser.SearchPath{"pg_catalog", "public"}, []string{"pg_catalog"}},
+	}
+	for _, tc := range testCases {
+		r := tableResolver{database: tc.database, searchPath: tc.searchPath}
+		if order := r.searchOrder(); !reflect.DeepEqual(order, tc.expected) {
+			t.Errorf("database %q, search_path %v: expected %v, got %v",
+				tc.database, tc.searchPath, tc.expected, order)
+		}
+	}
+}
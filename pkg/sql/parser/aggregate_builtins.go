@@ -661,9 +661,7 @@ func (a *intSumAggregate) Add(_ context.Context, datum Datum) error {
 		// result of the addition does not overflow.  However since Go
 		// does not provide checked addition, we have to check for the
 		// overflow explicitly.
-		if !a.large &&
-			((t < 0 && a.intSum < math.MinInt64-t) ||
-				(t > 0 && a.intSum > math.MaxInt64-t)) {
+		if !a.large && addInt64Overflows(a.intSum, t) {
 			// And overflow was detected; go to large integers, but keep the
 			// sum computed so far.
 			a.large = true
@@ -702,6 +700,13 @@ func (a *intSumAggregate) Result() (Datum, error) {
 func (a *intSumAggregate) Close(context.Context) {}
 
 type decimalSumAggregate struct {
+	// While all the values have the same exponent and small coefficients,
+	// which is the common case for a DECIMAL(p,s) column, the sum is
+	// accumulated in intSum with exponent exp. Once this isn't possible
+	// anymore, large is set and the sum is accumulated in sum.
+	intSum     int64
+	exp        int32
+	large      bool
 	sum        apd.Decimal
 	sawNonNull bool
 }
@@ -715,8 +720,21 @@ func (a *decimalSumAggregate) Add(_ context.Context, datum Datum) error {
 	if datum == DNull {
 		return nil
 	}
-	t := datum.(*DDecimal)
-	_, err := ExactCtx.Add(&a.sum, &a.sum, &t.Decimal)
+	t := &datum.(*DDecimal).Decimal
+	if !a.large {
+		if c, ok := smallDecimalCoefficient(t); ok &&
+			(!a.sawNonNull || t.Exponent == a.exp) && !addInt64Overflows(a.intSum, c) {
+			a.intSum += c
+			a.exp = t.Exponent
+			a.sawNonNull = true
+			return nil
+		}
+		if a.sawNonNull {
+			a.sum.SetCoefficient(a.intSum).SetExponent(a.exp)
+		}
+		a.large = true
+	}
+	_, err := ExactCtx.Add(&a.sum, &a.sum, t)
 	if err != nil {
 		return err
 	}
@@ -730,7 +748,11 @@ func (a *decimalSumAggregate) Result() (Datum, error) {
 		return DNull, nil
 	}
 	dd := &DDecimal{}
-	dd.Set(&a.sum)
+	if a.large {
+		dd.Set(&a.sum)
+	} else {
+		dd.SetCoefficient(a.intSum).SetExponent(a.exp)
+	}
 	return dd, nil
 }
 
@@ -875,13 +897,15 @@ type decimalVarianceAggregate struct {
 	tmp   apd.Decimal
 }
 
+// decimalVarianceCtx is the context used to compute the variance and standard
+// deviation of decimals. It uses extra internal precision to protect against
+// order changes that can happen in dist SQL. The additional 3 here should
+// allow for correctness up to 1000 more worst case inputs than non-worst case
+// inputs. See #13689 for more analysis and other algorithms.
+var decimalVarianceCtx = DecimalCtx.WithPrecision(DecimalCtx.Precision + 3)
+
 func newDecimalVariance() *decimalVarianceAggregate {
-	// Use extra internal precision during variance and stddev to protect against
-	// order changes that can happen in dist SQL. The additional 3 here should
-	// allow for correctness up to 1000 more worst case inputs than non-worst
-	// case inputs. See #13689 for more analysis and other algorithms.
-	c := DecimalCtx.WithPrecision(DecimalCtx.Precision + 3)
-	ed := apd.MakeErrDecimal(c)
+	ed := apd.MakeErrDecimal(decimalVarianceCtx)
 	return &decimalVarianceAggregate{
 		ed: &ed,
 	}
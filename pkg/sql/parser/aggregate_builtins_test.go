@@ -131,6 +131,10 @@ func TestSumDecimalResultDeepCopy(t *testing.T) {
 	testAggregateResultDeepCopy(t, newDecimalSumAggregate, makeDecimalTestDatum(10))
 }
 
+func TestSumFixedDecimalResultDeepCopy(t *testing.T) {
+	testAggregateResultDeepCopy(t, newDecimalSumAggregate, makeFixedDecimalTestDatum(10))
+}
+
 func TestSumIntervalResultDeepCopy(t *testing.T) {
 	testAggregateResultDeepCopy(t, newIntervalSumAggregate, makeIntervalTestDatum(10))
 }
@@ -212,6 +216,20 @@ func makeDecimalTestDatum(count int) []Datum {
 	return vals
 }
 
+// makeFixedDecimalTestDatum creates decimals with the same scale, like the
+// values of a DECIMAL(p,s) column.
+func makeFixedDecimalTestDatum(count int) []Datum {
+	rng, _ := randutil.NewPseudoRand()
+
+	vals := make([]Datum, count)
+	for i := range vals {
+		dd := &DDecimal{}
+		dd.SetCoefficient(rng.Int63n(2000000) - 1000000).SetExponent(-2)
+		vals[i] = dd
+	}
+	return vals
+}
+
 func makeBoolTestDatum(count int) []Datum {
 	rng, _ := randutil.NewPseudoRand()
 
@@ -272,6 +290,10 @@ func BenchmarkAvgAggregateDecimal1K(b *testing.B) {
 	runBenchmarkAggregate(b, newDecimalAvgAggregate, makeDecimalTestDatum(1000))
 }
 
+func BenchmarkAvgAggregateFixedDecimal1K(b *testing.B) {
+	runBenchmarkAggregate(b, newDecimalAvgAggregate, makeFixedDecimalTestDatum(1000))
+}
+
 func BenchmarkCountAggregate1K(b *testing.B) {
 	runBenchmarkAggregate(b, newCountAggregate, makeIntTestDatum(1000))
 }
@@ -296,6 +318,10 @@ func BenchmarkSumAggregateDecimal1K(b *testing.B) {
 	runBenchmarkAggregate(b, newDecimalSumAggregate, makeDecimalTestDatum(1000))
 }
 
+func BenchmarkSumAggregateFixedDecimal1K(b *testing.B) {
+	runBenchmarkAggregate(b, newDecimalSumAggregate, makeFixedDecimalTestDatum(1000))
+}
+
 func BenchmarkMaxAggregateInt1K(b *testing.B) {
 	runBenchmarkAggregate(b, newMaxAggregate, makeIntTestDatum(1000))
 }
@@ -343,3 +369,44 @@ func BenchmarkStdDevAggregateFloat1K(b *testing.B) {
 func BenchmarkStdDevAggregateDecimal1K(b *testing.B) {
 	runBenchmarkAggregate(b, newDecimalStdDevAggregate, makeDecimalTestDatum(1000))
 }
+
+func TestDecimalSumAggregate(t *testing.T) {
+	testCases := []struct {
+		vals     []string
+		expected string
+	}{
+		// Values with the same scale are summed in an int64.
+		{[]string{"1.25", "-3.50", "10.00"}, "7.75"},
+		// Mixed scales fall back to apd.
+		{[]string{"1.25", "2", "0.005"}, "3.255"},
+		{[]string{"1", "2", "0.5", "3"}, "6.5"},
+		// Overflowing the int64 falls back to apd.
+		{[]string{"4611686018427387903", "4611686018427387903", "4611686018427387903"}, "13835058055282163709"},
+		{[]string{"-4611686018427387903", "-4611686018427387903", "-4611686018427387903"}, "-13835058055282163709"},
+		// Large coefficients use apd from the start.
+		{[]string{"123456789012345678901234567890", "1"}, "123456789012345678901234567891"},
+		{[]string{"0.00", "0.00"}, "0.00"},
+		{[]string{"-0.00"}, "0.00"},
+	}
+	evalCtx := NewTestingEvalContext()
+	defer evalCtx.Stop(context.Background())
+	for _, tc := range testCases {
+		agg := newDecimalSumAggregate([]Type{TypeDecimal}, evalCtx)
+		for _, v := range tc.vals {
+			d, err := ParseDDecimal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := agg.Add(context.Background(), d); err != nil {
+				t.Fatal(err)
+			}
+		}
+		res, err := agg.Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := res.String(); s != tc.expected {
+			t.Errorf("%v: expected %s, got %s", tc.vals, tc.expected, s)
+		}
+	}
+}
@@ -136,6 +136,10 @@ type Builtin struct {
 	// might be more appropriate.
 	Info string
 
+	// columnLabels are the names of the columns of the rows produced by a
+	// generator, if it names them.
+	columnLabels []string
+
 	AggregateFunc func([]Type, *EvalContext) AggregateFunc
 	WindowFunc    func([]Type, *EvalContext) WindowFunc
 	fn            func(*EvalContext, Datums) (Datum, error)
@@ -1637,6 +1641,23 @@ var Builtins = map[string][]Builtin{
 		},
 	},
 
+	"crdb_internal.statement_fingerprint": {
+		Builtin{
+			Types:      ArgTypes{{"sql", TypeString}},
+			ReturnType: fixedReturnType(TypeString),
+			category:   categorySystemInfo,
+			fn: func(_ *EvalContext, args Datums) (Datum, error) {
+				stmt, err := ParseOne(string(MustBeDString(args[0])))
+				if err != nil {
+					return nil, err
+				}
+				return NewDString(Fingerprint(stmt)), nil
+			},
+			Info: "Returns the fingerprint of the statement `sql`, which identifies it " +
+				"in the statement statistics and the slow query log.",
+		},
+	},
+
 	"crdb_internal.force_internal_error": {
 		Builtin{
 			Types:      ArgTypes{{"msg", TypeString}},
@@ -216,9 +216,16 @@ func LimitDecimalWidth(d *apd.Decimal, precision, scale int) error {
 	// exceeds the declared precision minus the declared scale, an
 	// error is raised."
 
-	c := DecimalCtx.WithPrecision(uint32(precision))
-	c.Traps = apd.InvalidOperation
+	// Fast path: a value which already has the declared scale and no more
+	// digits than the declared precision is left unchanged. This is the common
+	// case when the value comes from a column of the same type.
+	if d.Exponent == -int32(scale) && precision < len(int64Pow10) {
+		if c, ok := smallDecimalCoefficient(d); ok && c > -int64Pow10[precision] && c < int64Pow10[precision] {
+			return nil
+		}
+	}
 
+	c := limitDecimalCtx(precision)
 	if _, err := c.Quantize(d, d, -int32(scale)); err != nil {
 		var lt string
 		switch v := precision - scale; v {
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+
+	"github.com/cockroachdb/apd"
 )
 
 func TestParseColumnType(t *testing.T) {
@@ -89,3 +91,60 @@ func TestParseColumnType(t *testing.T) {
 		}
 	}
 }
+
+func TestLimitDecimalWidth(t *testing.T) {
+	testCases := []struct {
+		s                string
+		precision, scale int
+		expected         string
+		err              string
+	}{
+		// Values which already fit are left unchanged.
+		{"123.45", 5, 2, "123.45", ""},
+		{"-999.99", 5, 2, "-999.99", ""},
+		{"0.00", 5, 2, "0.00", ""},
+		// Values are rounded to the scale.
+		{"123.456", 5, 2, "123.46", ""},
+		{"1", 5, 2, "1.00", ""},
+		{"123.4567", 70, 2, "123.46", ""},
+		{"1234.5", 5, 2, "", "value with precision 5, scale 2 must round to an absolute value less than 10^3"},
+		{"1000.00", 5, 2, "", "value with precision 5, scale 2 must round to an absolute value less than 10^3"},
+		{"-1000.00", 5, 2, "", "value with precision 5, scale 2 must round to an absolute value less than 10^3"},
+		{"0.5", 1, 1, "0.5", ""},
+		{"1.0", 1, 1, "", "value with precision 1, scale 1 must round to an absolute value less than 1"},
+		{"1", 1, 2, "", "scale (2) must be between 0 and precision (1)"},
+	}
+	for _, tc := range testCases {
+		var d apd.Decimal
+		if _, _, err := d.SetString(tc.s); err != nil {
+			t.Fatal(err)
+		}
+		err := LimitDecimalWidth(&d, tc.precision, tc.scale)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s(%d,%d): expected error %q, got %v", tc.s, tc.precision, tc.scale, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s(%d,%d): unexpected error %v", tc.s, tc.precision, tc.scale, err)
+		} else if s := d.String(); s != tc.expected {
+			t.Errorf("%s(%d,%d): expected %s, got %s", tc.s, tc.precision, tc.scale, tc.expected, s)
+		}
+	}
+}
+
+func BenchmarkLimitDecimalWidth(b *testing.B) {
+	var d, v apd.Decimal
+	v.SetCoefficient(12345678).SetExponent(-2)
+	for _, scale := range []int{2, 3} {
+		b.Run(fmt.Sprintf("scale=%d", scale), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				d.Set(&v)
+				if err := LimitDecimalWidth(&d, 10, scale); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -14,7 +14,11 @@
 
 package parser
 
-import "github.com/cockroachdb/apd"
+import (
+	"math"
+
+	"github.com/cockroachdb/apd"
+)
 
 var (
 	// DecimalCtx is the default context for decimal operations. Any change
@@ -40,3 +44,61 @@ var (
 		return &ctx
 	}()
 )
+
+// maxCachedDecimalPrecision is the largest precision for which a context is
+// preallocated in limitDecimalCtxs.
+const maxCachedDecimalPrecision = 64
+
+// limitDecimalCtxs holds the contexts used by LimitDecimalWidth, indexed by
+// precision, so that a context isn't allocated for every value written to a
+// DECIMAL(p,s) column. They must not be modified.
+var limitDecimalCtxs = func() (ctxs [maxCachedDecimalPrecision + 1]*apd.Context) {
+	for i := range ctxs {
+		ctxs[i] = makeLimitDecimalCtx(i)
+	}
+	return ctxs
+}()
+
+func makeLimitDecimalCtx(precision int) *apd.Context {
+	c := DecimalCtx.WithPrecision(uint32(precision))
+	c.Traps = apd.InvalidOperation
+	return c
+}
+
+// limitDecimalCtx returns the context used to limit a decimal to the given
+// precision.
+func limitDecimalCtx(precision int) *apd.Context {
+	if precision <= maxCachedDecimalPrecision {
+		return limitDecimalCtxs[precision]
+	}
+	return makeLimitDecimalCtx(precision)
+}
+
+// int64Pow10 holds the powers of 10 that fit in an int64.
+var int64Pow10 = func() (pow [19]int64) {
+	pow[0] = 1
+	for i := 1; i < len(pow); i++ {
+		pow[i] = pow[i-1] * 10
+	}
+	return pow
+}()
+
+// smallDecimalCoefficient returns the signed coefficient of a finite decimal,
+// if it fits in 62 bits. Sums of two such coefficients can't overflow an
+// int64, which makes them usable for fast paths that avoid the big.Int
+// arithmetic of apd.
+func smallDecimalCoefficient(d *apd.Decimal) (int64, bool) {
+	if d.Form != apd.Finite || d.Coeff.BitLen() > 62 {
+		return 0, false
+	}
+	c := d.Coeff.Int64()
+	if d.Negative {
+		c = -c
+	}
+	return c, true
+}
+
+// addInt64Overflows returns whether the sum of a and b overflows an int64.
+func addInt64Overflows(a, b int64) bool {
+	return (b < 0 && a < math.MinInt64-b) || (b > 0 && a > math.MaxInt64-b)
+}
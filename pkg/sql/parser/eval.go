@@ -33,6 +33,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/mon"
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/util/duration"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
@@ -1702,6 +1703,22 @@ type EvalPlanner interface {
 	// QualifyWithDatabase resolves a possibly unqualified table name into a
 	// table name that is qualified by database.
 	QualifyWithDatabase(ctx context.Context, t *NormalizableTableName) (*TableName, error)
+
+	// RangeDescriptors returns the descriptors of the ranges overlapping the
+	// span, in key order.
+	RangeDescriptors(ctx context.Context, span roachpb.Span) ([]roachpb.RangeDescriptor, error)
+
+	// RangeLeaseHolder returns the replica holding the lease of the range.
+	RangeLeaseHolder(ctx context.Context, desc roachpb.RangeDescriptor) (roachpb.ReplicaDescriptor, error)
+
+	// RangeStats returns the MVCC stats of the range, as reported by its
+	// lease holder.
+	RangeStats(ctx context.Context, desc roachpb.RangeDescriptor) (enginepb.MVCCStats, error)
+
+	// CheckRangeConsistency runs a consistency check on the range.
+	// Inconsistencies are reported by the lease holder in its logs, like
+	// those found by the consistency queue.
+	CheckRangeConsistency(ctx context.Context, desc roachpb.RangeDescriptor) error
 }
 
 // contextHolder is a wrapper that returns a Context.
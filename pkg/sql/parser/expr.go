@@ -915,6 +915,13 @@ func (node *FuncExpr) GetWindowConstructor() func(*EvalContext) WindowFunc {
 	}
 }
 
+// GetGeneratorColumnLabels returns the names of the columns of the rows
+// produced by the FuncExpr if it is a built-in generator which names them, or
+// nil.
+func (node *FuncExpr) GetGeneratorColumnLabels() []string {
+	return node.fn.columnLabels
+}
+
 func typesOfExprs(exprs Exprs) []Type {
 	types := make([]Type, len(exprs))
 	for i, expr := range exprs {
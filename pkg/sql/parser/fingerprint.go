@@ -0,0 +1,32 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+// Fingerprint returns the fingerprint of a statement: its representation
+// with the literals and placeholders replaced by underscores, and the
+// lists of literals shortened (see FmtHideConstants). For example,
+//    SELECT a FROM t WHERE b IN (1, 2, 3) AND c = $1
+// has the fingerprint
+//    SELECT a FROM t WHERE (b IN (_, _)) AND (c = _)
+// Statements which only differ by their literals, or by whether they use
+// placeholders, have the same fingerprint.
+//
+// The fingerprints identify the statements in the statement statistics,
+// the slow query log and the diagnostics reports, and are compared
+// across nodes and versions: TestFingerprint pins their representation,
+// which must not change lightly.
+func Fingerprint(stmt Statement) string {
+	return AsStringWithFlags(stmt, FmtFingerprint)
+}
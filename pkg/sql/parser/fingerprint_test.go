@@ -0,0 +1,59 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	// The statements of each test case have the same fingerprint.
+	testData := []struct {
+		stmts    []string
+		expected string
+	}{
+		{[]string{
+			`SELECT a FROM t WHERE b IN (1, 2, 3) AND c = $1`,
+			`SELECT a FROM t WHERE b IN ($1, $2) AND c = 'foo'`,
+			`SELECT a FROM t WHERE b IN (4, $1, 5, 6, 7) AND c = NULL`,
+		}, `SELECT a FROM t WHERE (b IN (_, _)) AND (c = _)`},
+		{[]string{
+			`INSERT INTO t VALUES (1, 'a'), (2, 'b')`,
+			`INSERT INTO t VALUES ($1, $2)`,
+		}, `INSERT INTO t VALUES (_, _)`},
+		{[]string{
+			`UPDATE t SET a = a + 1 WHERE b = $1`,
+			`UPDATE t SET a = a + $2 WHERE b = 'foo'`,
+		}, `UPDATE t SET a = a + _ WHERE b = _`},
+		{[]string{
+			`SELECT length($1::STRING)`,
+			`SELECT length('foo'::STRING)`,
+		}, `SELECT length(_::STRING)`},
+		{[]string{
+			`SELECT a FROM t WHERE b IN (1, 2, c + 3)`,
+			`SELECT a FROM t WHERE b IN ($1, 1, c + $2)`,
+		}, `SELECT a FROM t WHERE b IN (_, _, c + _)`},
+	}
+
+	for _, test := range testData {
+		for _, sql := range test.stmts {
+			stmt, err := ParseOne(sql)
+			if err != nil {
+				t.Fatalf("%s: %v", sql, err)
+			}
+			if f := Fingerprint(stmt); f != test.expected {
+				t.Errorf("%s: expected %q, got %q", sql, test.expected, f)
+			}
+		}
+	}
+}
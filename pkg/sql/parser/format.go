@@ -26,6 +26,8 @@ type fmtFlags struct {
 	ShowTableAliases bool
 	symbolicVars     bool
 	hideConstants    bool
+	// If true, placeholders are hidden along with the constants.
+	hidePlaceholders bool
 	// tableNameFormatter will be called on all NormalizableTableNames if it is
 	// non-nil.
 	tableNameFormatter func(*NormalizableTableName, *bytes.Buffer, FmtFlags)
@@ -93,6 +95,12 @@ var FmtCheckEquivalence FmtFlags = &fmtFlags{symbolicVars: true, disambiguateDat
 // representation that does not disclose query-specific data.
 var FmtHideConstants FmtFlags = &fmtFlags{hideConstants: true}
 
+// FmtFingerprint instructs the pretty-printer to produce the
+// fingerprint of a statement: like FmtHideConstants, but placeholders
+// are hidden too, so that a prepared statement and its non-prepared
+// variants have the same representation. See Fingerprint.
+var FmtFingerprint FmtFlags = &fmtFlags{hideConstants: true, hidePlaceholders: true}
+
 // FmtAnonymize instructs the pretty-printer to remove
 // any name but function names.
 // TODO(knz): temporary until a better solution is found for #13968
@@ -19,6 +19,8 @@ package parser
 import (
 	"errors"
 	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
 )
 
 // Table generators, also called "set-generating functions", are
@@ -80,6 +82,7 @@ type ValueGenerator interface {
 
 var _ ValueGenerator = &seriesValueGenerator{}
 var _ ValueGenerator = &arrayValueGenerator{}
+var _ ValueGenerator = &rangeValueGenerator{}
 
 func initGeneratorBuiltins() {
 	// Add all windows to the Builtins map after a few sanity checks.
@@ -127,6 +130,83 @@ var Generators = map[string][]Builtin{
 			"Returns the input array as a set of rows",
 		),
 	},
+	"crdb_internal.check_consistency": {
+		makeRangeGeneratorBuiltin(
+			ArgTypes{{"start_key", TypeBytes}, {"end_key", TypeBytes}},
+			[]string{"range_id", "start_key", "end_key", "error_message"},
+			TTuple{TypeInt, TypeBytes, TypeBytes, TypeString},
+			func(ctx *EvalContext, desc roachpb.RangeDescriptor) (Datums, error) {
+				errMsg := Datum(DNull)
+				if err := ctx.Planner.CheckRangeConsistency(ctx.Ctx(), desc); err != nil {
+					errMsg = NewDString(err.Error())
+				}
+				return Datums{
+					NewDInt(DInt(desc.RangeID)),
+					NewDBytes(DBytes(desc.StartKey)),
+					NewDBytes(DBytes(desc.EndKey)),
+					errMsg,
+				}, nil
+			},
+			"Runs a consistency check on each range overlapping the span from `start_key` "+
+				"to `end_key`. `error_message` is NULL if the check could be run on the range; "+
+				"the inconsistencies it finds are reported in the logs of the lease holder "+
+				"of the range.",
+		),
+	},
+	"crdb_internal.lease_holder": {
+		makeRangeGeneratorBuiltin(
+			ArgTypes{{"key", TypeBytes}},
+			[]string{"range_id", "node_id", "store_id", "replica_id"},
+			TTuple{TypeInt, TypeInt, TypeInt, TypeInt},
+			func(ctx *EvalContext, desc roachpb.RangeDescriptor) (Datums, error) {
+				replica, err := ctx.Planner.RangeLeaseHolder(ctx.Ctx(), desc)
+				if err != nil {
+					return nil, err
+				}
+				return Datums{
+					NewDInt(DInt(desc.RangeID)),
+					NewDInt(DInt(replica.NodeID)),
+					NewDInt(DInt(replica.StoreID)),
+					NewDInt(DInt(replica.ReplicaID)),
+				}, nil
+			},
+			"Returns the replica holding the lease of the range containing `key`.",
+		),
+	},
+	"crdb_internal.range_stats": {
+		makeRangeGeneratorBuiltin(
+			ArgTypes{{"key", TypeBytes}},
+			[]string{
+				"range_id", "live_bytes", "live_count", "key_bytes", "key_count",
+				"val_bytes", "val_count", "intent_bytes", "intent_count", "sys_bytes", "sys_count",
+			},
+			TTuple{
+				TypeInt, TypeInt, TypeInt, TypeInt, TypeInt,
+				TypeInt, TypeInt, TypeInt, TypeInt, TypeInt, TypeInt,
+			},
+			func(ctx *EvalContext, desc roachpb.RangeDescriptor) (Datums, error) {
+				stats, err := ctx.Planner.RangeStats(ctx.Ctx(), desc)
+				if err != nil {
+					return nil, err
+				}
+				return Datums{
+					NewDInt(DInt(desc.RangeID)),
+					NewDInt(DInt(stats.LiveBytes)),
+					NewDInt(DInt(stats.LiveCount)),
+					NewDInt(DInt(stats.KeyBytes)),
+					NewDInt(DInt(stats.KeyCount)),
+					NewDInt(DInt(stats.ValBytes)),
+					NewDInt(DInt(stats.ValCount)),
+					NewDInt(DInt(stats.IntentBytes)),
+					NewDInt(DInt(stats.IntentCount)),
+					NewDInt(DInt(stats.SysBytes)),
+					NewDInt(DInt(stats.SysCount)),
+				}, nil
+			},
+			"Returns the MVCC stats of the range containing `key`, as reported by its "+
+				"lease holder.",
+		),
+	},
 }
 
 func makeGeneratorBuiltin(in ArgTypes, ret TTuple, g generatorFactory, info string) Builtin {
@@ -153,6 +233,81 @@ func makeGeneratorBuiltinWithReturnType(
 	}
 }
 
+// makeRangeGeneratorBuiltin makes a generator introspecting the ranges of the
+// cluster: it produces a row per range overlapping the span given by its
+// arguments, a single key or a start and end key, computed by rowFn. The
+// columns are named by labels. It can only be used by the root user.
+func makeRangeGeneratorBuiltin(
+	in ArgTypes,
+	labels []string,
+	ret TTuple,
+	rowFn func(*EvalContext, roachpb.RangeDescriptor) (Datums, error),
+	info string,
+) Builtin {
+	b := makeGeneratorBuiltin(in, ret, func(ctx *EvalContext, args Datums) (ValueGenerator, error) {
+		span := roachpb.Span{Key: roachpb.Key(*args[0].(*DBytes))}
+		if len(args) > 1 {
+			span.EndKey = roachpb.Key(*args[1].(*DBytes))
+		} else {
+			span.EndKey = span.Key.Next()
+		}
+		if span.Key.Compare(span.EndKey) >= 0 {
+			return nil, fmt.Errorf("invalid span: %s", span)
+		}
+		return &rangeValueGenerator{ctx: ctx, span: span, columnTypes: ret, rowFn: rowFn}, nil
+	}, info)
+	b.columnLabels = labels
+	b.privileged = true
+	b.distsqlBlacklist = true
+	b.category = categorySystemInfo
+	return b
+}
+
+// rangeValueGenerator supports the execution of the generators made by
+// makeRangeGeneratorBuiltin.
+type rangeValueGenerator struct {
+	ctx         *EvalContext
+	span        roachpb.Span
+	columnTypes TTuple
+	rowFn       func(*EvalContext, roachpb.RangeDescriptor) (Datums, error)
+
+	descs  []roachpb.RangeDescriptor
+	values Datums
+}
+
+// ColumnTypes implements the ValueGenerator interface.
+func (s *rangeValueGenerator) ColumnTypes() TTuple { return s.columnTypes }
+
+// Start implements the ValueGenerator interface.
+func (s *rangeValueGenerator) Start() error {
+	if s.ctx.Planner == nil {
+		return errors.New("cannot introspect the ranges from this context")
+	}
+	var err error
+	s.descs, err = s.ctx.Planner.RangeDescriptors(s.ctx.Ctx(), s.span)
+	return err
+}
+
+// Close implements the ValueGenerator interface.
+func (s *rangeValueGenerator) Close() {}
+
+// Next implements the ValueGenerator interface.
+func (s *rangeValueGenerator) Next() (bool, error) {
+	if len(s.descs) == 0 {
+		return false, nil
+	}
+	var err error
+	s.values, err = s.rowFn(s.ctx, s.descs[0])
+	if err != nil {
+		return false, err
+	}
+	s.descs = s.descs[1:]
+	return true, nil
+}
+
+// Values implements the ValueGenerator interface.
+func (s *rangeValueGenerator) Values() Datums { return s.values }
+
 // seriesValueGenerator supports the execution of generate_series()
 // with integer bounds.
 type seriesValueGenerator struct {
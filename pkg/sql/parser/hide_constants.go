@@ -20,7 +20,7 @@ import "bytes"
 
 // formatNodeOrHideConstants recurses into a node for pretty-printing,
 // unless hideConstants is set in the flags and the node is a datum or
-// a literal.
+// a literal, or hidePlaceholders is set and the node is a placeholder.
 func formatNodeOrHideConstants(buf *bytes.Buffer, f FmtFlags, n NodeFormatter) {
 	if f.hideConstants {
 		switch v := n.(type) {
@@ -37,6 +37,11 @@ func formatNodeOrHideConstants(buf *bytes.Buffer, f FmtFlags, n NodeFormatter) {
 		case Datum, Constant:
 			buf.WriteByte('_')
 			return
+		case *Placeholder:
+			if f.hidePlaceholders {
+				buf.WriteByte('_')
+				return
+			}
 		}
 	}
 	n.Format(buf, f)
@@ -30,6 +30,7 @@ var keywords = map[string]int{
 	"BY":                        BY,
 	"BYTEA":                     BYTEA,
 	"BYTES":                     BYTES,
+	"CANCEL":                    CANCEL,
 	"CASCADE":                   CASCADE,
 	"CASE":                      CASE,
 	"CAST":                      CAST,
@@ -45,6 +46,9 @@ var keywords = map[string]int{
 	"COLUMNS":                   COLUMNS,
 	"COMMIT":                    COMMIT,
 	"COMMITTED":                 COMMITTED,
+	"CONFIGURATION":             CONFIGURATION,
+	"CONFIGURATIONS":            CONFIGURATIONS,
+	"CONFIGURE":                 CONFIGURE,
 	"CONFLICT":                  CONFLICT,
 	"CONSTRAINT":                CONSTRAINT,
 	"CONSTRAINTS":               CONSTRAINTS,
@@ -181,6 +185,7 @@ var keywords = map[string]int{
 	"PARTIAL":                   PARTIAL,
 	"PARTITION":                 PARTITION,
 	"PASSWORD":                  PASSWORD,
+	"PAUSE":                     PAUSE,
 	"PLACING":                   PLACING,
 	"POSITION":                  POSITION,
 	"PRECEDING":                 PRECEDING,
@@ -189,9 +194,11 @@ var keywords = map[string]int{
 	"PRIMARY":                   PRIMARY,
 	"PRIORITY":                  PRIORITY,
 	"QUERIES":                   QUERIES,
+	"QUERY":                     QUERY,
 	"RANGE":                     RANGE,
 	"READ":                      READ,
 	"REAL":                      REAL,
+	"RECURRING":                 RECURRING,
 	"RECURSIVE":                 RECURSIVE,
 	"REF":                       REF,
 	"REFERENCES":                REFERENCES,
@@ -206,6 +213,7 @@ var keywords = map[string]int{
 	"RESET":                     RESET,
 	"RESTORE":                   RESTORE,
 	"RESTRICT":                  RESTRICT,
+	"RESUME":                    RESUME,
 	"RETURNING":                 RETURNING,
 	"REVOKE":                    REVOKE,
 	"RIGHT":                     RIGHT,
@@ -215,6 +223,7 @@ var keywords = map[string]int{
 	"ROWS":                      ROWS,
 	"SAVEPOINT":                 SAVEPOINT,
 	"SCATTER":                   SCATTER,
+	"SCHEDULE":                  SCHEDULE,
 	"SEARCH":                    SEARCH,
 	"SECOND":                    SECOND,
 	"SELECT":                    SELECT,
@@ -243,6 +252,7 @@ var keywords = map[string]int{
 	"STRING":                    STRING,
 	"SUBSTRING":                 SUBSTRING,
 	"SYMMETRIC":                 SYMMETRIC,
+	"SYNTAX":                    SYNTAX,
 	"SYSTEM":                    SYSTEM,
 	"TABLE":                     TABLE,
 	"TABLES":                    TABLES,
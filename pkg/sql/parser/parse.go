@@ -25,7 +25,9 @@ package parser
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/pkg/errors"
@@ -127,6 +129,39 @@ func Parse(sql string) (StatementList, error) {
 	return p.Parse(sql)
 }
 
+// RunShowSyntax parses the given SQL and reports, through the report
+// callback, either the tag and the normalized SQL of each statement or the
+// parse error. A parse error is reported as an "error" field holding the
+// message, a "detail" field holding the statement with a caret under the
+// offending token, a "code" field holding the pgwire error code and a
+// "position" field holding the 1-based character offset of the offending
+// token, as in the postgres ErrorResponse. It is used by SHOW SYNTAX, so
+// that clients can validate a statement before executing it.
+func RunShowSyntax(sql string, report func(field, msg string)) {
+	var p Parser
+	stmts, err := p.Parse(sql)
+	if err != nil {
+		msg, detail := p.scanner.lastError.msg, ""
+		if i := strings.IndexByte(msg, '\n'); i != -1 {
+			msg, detail = msg[:i], msg[i+1:]
+		}
+		report("error", msg)
+		if detail != "" {
+			report("detail", detail)
+		}
+		if pgErr, ok := pgerror.GetPGCause(err); ok {
+			report("code", pgErr.Code)
+		}
+		pos := p.scanner.lastError.pos
+		report("position", strconv.Itoa(utf8.RuneCountInString(sql[:pos])+1))
+		return
+	}
+	for _, stmt := range stmts {
+		report("tag", stmt.StatementTag())
+		report("sql", AsString(stmt))
+	}
+}
+
 // ParseOne parses a sql statement string, ensuring that it contains only a
 // single statement, and returns that Statement.
 func ParseOne(sql string) (Statement, error) {
@@ -47,6 +47,8 @@ func TestParse(t *testing.T) {
 		{`BEGIN TRANSACTION PRIORITY HIGH`},
 		{`BEGIN TRANSACTION ISOLATION LEVEL SERIALIZABLE, PRIORITY HIGH`},
 		{`BEGIN TRANSACTION ISOLATION LEVEL SERIALIZABLE, PRIORITY HIGH, READ WRITE`},
+		{`BEGIN TRANSACTION AS OF SYSTEM TIME '2016-01-01'`},
+		{`BEGIN TRANSACTION PRIORITY HIGH, AS OF SYSTEM TIME '2016-01-01'`},
 		{`COMMIT TRANSACTION`},
 		{`ROLLBACK TRANSACTION`},
 		{"SAVEPOINT foo"},
@@ -234,8 +236,17 @@ func TestParse(t *testing.T) {
 		{`SHOW CONSTRAINTS FROM a.b.c`},
 		{`SHOW TABLES FROM a; SHOW COLUMNS FROM b`},
 		{`SHOW USERS`},
+		{`SHOW SYNTAX 'select 1'`},
 		{`SHOW CLUSTER QUERIES`},
 		{`SHOW LOCAL QUERIES`},
+		{`CANCEL QUERY 'f54103d1ffb2c0e90000000000000001'`},
+		{`CANCEL QUERY $1`},
+		{`CREATE SCHEDULE nightly RECURRING '@daily' FOR BACKUP DATABASE bank TO 'nodelocal:///backups'`},
+		{`CREATE SCHEDULE nightly RECURRING '0 2 * * *' WITH OPTIONS ('overlap_policy'='skip') FOR BACKUP TABLE bank.accounts TO 'nodelocal:///backups'`},
+		{`PAUSE SCHEDULE 1`},
+		{`PAUSE SCHEDULE $1`},
+		{`RESUME SCHEDULE 1`},
+		{`RESUME SCHEDULE $1`},
 		{`SHOW CLUSTER SESSIONS`},
 		{`SHOW LOCAL SESSIONS`},
 		{`SHOW SESSION TRACE`},
@@ -568,6 +579,7 @@ func TestParse(t *testing.T) {
 		{`SET TRANSACTION PRIORITY NORMAL`},
 		{`SET TRANSACTION PRIORITY HIGH`},
 		{`SET TRANSACTION ISOLATION LEVEL SNAPSHOT, PRIORITY HIGH`},
+		{`SET TRANSACTION AS OF SYSTEM TIME '2016-01-01'`},
 		{`SET SESSION CHARACTERISTICS AS TRANSACTION ISOLATION LEVEL SERIALIZABLE`},
 		{`SET SESSION CHARACTERISTICS AS TRANSACTION ISOLATION LEVEL SNAPSHOT`},
 		{`SET CLUSTER SETTING a = 3`},
@@ -674,9 +686,22 @@ func TestParse(t *testing.T) {
 		{`ALTER TABLE d.a SCATTER`},
 		{`ALTER INDEX d.i SCATTER FROM (1) TO (2)`},
 
+		{`ALTER RANGE "default" CONFIGURE ZONE 'foo'`},
+		{`ALTER RANGE meta CONFIGURE ZONE $1`},
+		{`ALTER DATABASE db CONFIGURE ZONE 'foo'`},
+		{`ALTER TABLE db.t CONFIGURE ZONE 'foo'`},
+		{`ALTER INDEX db.t@i CONFIGURE ZONE 'foo'`},
+		{`ALTER TABLE t CONFIGURE ZONE NULL`},
+		{`SHOW ZONE CONFIGURATION FOR RANGE "default"`},
+		{`SHOW ZONE CONFIGURATION FOR DATABASE db`},
+		{`SHOW ZONE CONFIGURATION FOR TABLE db.t`},
+		{`SHOW ZONE CONFIGURATION FOR INDEX db.t@i`},
+		{`SHOW ALL ZONE CONFIGURATIONS`},
+
 		{`BACKUP foo TO 'bar'`},
 		{`BACKUP foo.foo, baz.baz TO 'bar'`},
 		{`SHOW BACKUP 'bar'`},
+		{`SHOW BACKUP 'bar' WITH OPTIONS ('check_files')`},
 		{`BACKUP foo TO 'bar' AS OF SYSTEM TIME '1' INCREMENTAL FROM 'baz'`},
 		{`BACKUP foo TO $1 INCREMENTAL FROM 'bar', $2, 'baz'`},
 		{`BACKUP DATABASE foo TO 'bar'`},
@@ -945,6 +970,8 @@ func TestParse2(t *testing.T) {
 			`BEGIN TRANSACTION ISOLATION LEVEL SNAPSHOT, PRIORITY LOW`},
 		{`SET TRANSACTION PRIORITY NORMAL, ISOLATION LEVEL SERIALIZABLE`,
 			`SET TRANSACTION ISOLATION LEVEL SERIALIZABLE, PRIORITY NORMAL`},
+		{`BEGIN AS OF SYSTEM TIME '2016-01-01', READ ONLY`,
+			`BEGIN TRANSACTION READ ONLY, AS OF SYSTEM TIME '2016-01-01'`},
 		{"SET CLUSTER SETTING a TO 1", "SET CLUSTER SETTING a = 1"},
 		{"RELEASE foo", "RELEASE SAVEPOINT foo"},
 		{"RELEASE SAVEPOINT foo", "RELEASE SAVEPOINT foo"},
@@ -1341,6 +1368,39 @@ SELECT 1 + ANY ARRAY[1, 2, 3]
 	}
 }
 
+func TestRunShowSyntax(t *testing.T) {
+	testData := []struct {
+		sql      string
+		expected []string
+	}{
+		{`select 1; show tables`,
+			[]string{"tag: SELECT", "sql: SELECT 1", "tag: SHOW TABLES", "sql: SHOW TABLES"}},
+		{`select 'é' from from`,
+			[]string{
+				`error: syntax error at or near "from"`,
+				"detail: select 'é' from from\n                 ^\n",
+				"code: 42601",
+				"position: 17",
+			}},
+		{`select 1 +`,
+			[]string{
+				`error: syntax error at or near "EOF"`,
+				"detail: select 1 +\n          ^\n",
+				"code: 42601",
+				"position: 11",
+			}},
+	}
+	for _, d := range testData {
+		var fields []string
+		RunShowSyntax(d.sql, func(field, msg string) {
+			fields = append(fields, field+": "+msg)
+		})
+		if !reflect.DeepEqual(d.expected, fields) {
+			t.Errorf("%s: expected %q, but found %q", d.sql, d.expected, fields)
+		}
+	}
+}
+
 func TestParsePanic(t *testing.T) {
 	// Replicates #1801.
 	defer func() {
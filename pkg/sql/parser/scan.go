@@ -43,6 +43,8 @@ type Scanner struct {
 	lastError struct {
 		msg                  string
 		unimplementedFeature string
+		// pos is the byte offset of the token at which the error occurred.
+		pos int
 	}
 	stmts       []Statement
 	identQuote  int
@@ -165,6 +167,7 @@ func (s *Scanner) Error(e string) {
 	fmt.Fprintf(&buf, "%s^\n", strings.Repeat(" ", s.lastTok.pos-j))
 	s.lastError.unimplementedFeature = ""
 	s.lastError.msg = buf.String()
+	s.lastError.pos = s.lastTok.pos
 }
 
 func (s *Scanner) scan(lval *sqlSymType) {
@@ -0,0 +1,67 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "bytes"
+
+// CreateSchedule represents a CREATE SCHEDULE statement.
+type CreateSchedule struct {
+	Name Name
+	// Recurrence is the cron expression that determines when the schedule
+	// fires.
+	Recurrence string
+	Options    KVOptions
+	// Statement is the statement run each time the schedule fires.
+	Statement Statement
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateSchedule) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("CREATE SCHEDULE ")
+	FormatNode(buf, f, node.Name)
+	buf.WriteString(" RECURRING ")
+	encodeSQLStringWithFlags(buf, node.Recurrence, f)
+	if node.Options != nil {
+		buf.WriteString(" WITH OPTIONS (")
+		FormatNode(buf, f, node.Options)
+		buf.WriteString(")")
+	}
+	buf.WriteString(" FOR ")
+	FormatNode(buf, f, node.Statement)
+}
+
+// PauseSchedule represents a PAUSE SCHEDULE statement.
+type PauseSchedule struct {
+	// ID evaluates to the ID of the schedule to pause.
+	ID Expr
+}
+
+// Format implements the NodeFormatter interface.
+func (node *PauseSchedule) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("PAUSE SCHEDULE ")
+	FormatNode(buf, f, node.ID)
+}
+
+// ResumeSchedule represents a RESUME SCHEDULE statement.
+type ResumeSchedule struct {
+	// ID evaluates to the ID of the schedule to resume.
+	ID Expr
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ResumeSchedule) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("RESUME SCHEDULE ")
+	FormatNode(buf, f, node.ID)
+}
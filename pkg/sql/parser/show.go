@@ -41,13 +41,19 @@ func (node *Show) Format(buf *bytes.Buffer, f FmtFlags) {
 
 // ShowBackup represents a SHOW BACKUP statement.
 type ShowBackup struct {
-	Path Expr
+	Path    Expr
+	Options KVOptions
 }
 
 // Format implements the NodeFormatter interface.
 func (node *ShowBackup) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString("SHOW BACKUP ")
 	FormatNode(buf, f, node.Path)
+	if node.Options != nil {
+		buf.WriteString(" WITH OPTIONS (")
+		FormatNode(buf, f, node.Options)
+		buf.WriteString(")")
+	}
 }
 
 // ShowColumns represents a SHOW COLUMNS statement.
@@ -196,6 +202,17 @@ func (node *ShowCreateView) Format(buf *bytes.Buffer, f FmtFlags) {
 	FormatNode(buf, f, node.View)
 }
 
+// ShowSyntax represents a SHOW SYNTAX statement.
+type ShowSyntax struct {
+	Statement string
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowSyntax) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("SHOW SYNTAX ")
+	encodeSQLStringWithFlags(buf, node.Statement, f)
+}
+
 // ShowTransactionStatus represents a SHOW TRANSACTION STATUS statement.
 type ShowTransactionStatus struct {
 }
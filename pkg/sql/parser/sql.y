@@ -348,6 +348,9 @@ func (u *sqlSymUnion) kvOptions() []KVOption {
 func (u *sqlSymUnion) transactionModes() TransactionModes {
     return u.val.(TransactionModes)
 }
+func (u *sqlSymUnion) zoneSpecifier() ZoneSpecifier {
+    return u.val.(ZoneSpecifier)
+}
 
 %}
 
@@ -376,10 +379,11 @@ func (u *sqlSymUnion) transactionModes() TransactionModes {
 %token <str>   BACKUP BEGIN BETWEEN BIGINT BIGSERIAL BIT
 %token <str>   BLOB BOOL BOOLEAN BOTH BY BYTEA BYTES
 
-%token <str>   CASCADE CASE CAST CHAR
+%token <str>   CANCEL CASCADE CASE CAST CHAR
 %token <str>   CHARACTER CHARACTERISTICS CHECK
 %token <str>   CLUSTER COALESCE COLLATE COLLATION COLUMN COLUMNS COMMIT
-%token <str>   COMMITTED CONCAT CONFLICT CONSTRAINT CONSTRAINTS
+%token <str>   COMMITTED CONCAT CONFIGURATION CONFIGURATIONS CONFIGURE
+%token <str>   CONFLICT CONSTRAINT CONSTRAINTS
 %token <str>   COPY COVERING CREATE
 %token <str>   CROSS CUBE CURRENT CURRENT_CATALOG CURRENT_DATE
 %token <str>   CURRENT_ROLE CURRENT_TIME CURRENT_TIMESTAMP
@@ -421,22 +425,22 @@ func (u *sqlSymUnion) transactionModes() TransactionModes {
 %token <str>   OF OFF OFFSET OID ON ONLY OPTIONS OR
 %token <str>   ORDER ORDINALITY OUT OUTER OVER OVERLAPS OVERLAY
 
-%token <str>   PARENT PARTIAL PARTITION PASSWORD PLACING POSITION
+%token <str>   PARENT PARTIAL PARTITION PASSWORD PAUSE PLACING POSITION
 %token <str>   PRECEDING PRECISION PREPARE PRIMARY PRIORITY
 
-%token <str>   QUERIES
+%token <str>   QUERIES QUERY
 
-%token <str>   RANGE READ REAL RECURSIVE REF REFERENCES
+%token <str>   RANGE READ REAL RECURRING RECURSIVE REF REFERENCES
 %token <str>   REGCLASS REGPROC REGPROCEDURE REGNAMESPACE REGTYPE
 %token <str>   RENAME REPEATABLE
-%token <str>   RELEASE RESET RESTORE RESTRICT RETURNING REVOKE RIGHT ROLLBACK ROLLUP
+%token <str>   RELEASE RESET RESTORE RESTRICT RESUME RETURNING REVOKE RIGHT ROLLBACK ROLLUP
 %token <str>   ROW ROWS RSHIFT
 
-%token <str>   SAVEPOINT SCATTER SEARCH SECOND SELECT
+%token <str>   SAVEPOINT SCATTER SCHEDULE SEARCH SECOND SELECT
 %token <str>   SERIAL SERIALIZABLE SESSION SESSIONS SESSION_USER SET SETTING SETTINGS
 %token <str>   SHOW SIMILAR SIMPLE SMALLINT SMALLSERIAL SNAPSHOT SOME SPLIT SQL
 %token <str>   START STATUS STDIN STRICT STRING STORING SUBSTRING
-%token <str>   SYMMETRIC SYSTEM
+%token <str>   SYMMETRIC SYNTAX SYSTEM
 
 %token <str>   TABLE TABLES TEMPLATE TESTING_RANGES TESTING_RELOCATE TEXT THEN
 %token <str>   TIME TIMESTAMP TIMESTAMPTZ TO TRAILING TRACE TRANSACTION TREAT TRIM TRUE
@@ -476,11 +480,14 @@ func (u *sqlSymUnion) transactionModes() TransactionModes {
 %type <Statement> stmt
 
 %type <Statement> alter_table_stmt
+%type <Statement> alter_zone_stmt
 %type <Statement> backup_stmt
+%type <Statement> cancel_stmt
 %type <Statement> copy_from_stmt
 %type <Statement> create_stmt
 %type <Statement> create_database_stmt
 %type <Statement> create_index_stmt
+%type <Statement> create_schedule_stmt
 %type <Statement> create_table_stmt
 %type <Statement> create_table_as_stmt
 %type <Statement> create_user_stmt
@@ -495,9 +502,11 @@ func (u *sqlSymUnion) transactionModes() TransactionModes {
 %type <Statement> deallocate_stmt
 %type <Statement> grant_stmt
 %type <Statement> insert_stmt
+%type <Statement> pause_stmt
 %type <Statement> release_stmt
 %type <Statement> rename_stmt
 %type <Statement> reset_stmt
+%type <Statement> resume_stmt
 %type <Statement> revoke_stmt
 %type <*Select> select_stmt
 %type <Statement> savepoint_stmt
@@ -552,6 +561,7 @@ func (u *sqlSymUnion) transactionModes() TransactionModes {
 %type <TableExpr> insert_target
 
 %type <*TableNameWithIndex> table_name_with_index
+%type <ZoneSpecifier> zone_specifier
 %type <TableNameWithIndexList> table_name_with_index_list
 
 %type <operator> math_op
@@ -658,7 +668,7 @@ func (u *sqlSymUnion) transactionModes() TransactionModes {
 %type <TableExpr> relation_expr_opt_alias
 %type <SelectExpr> target_elem
 %type <*UpdateExpr> single_set_clause
-%type <AsOfClause> opt_as_of_clause
+%type <AsOfClause> as_of_clause opt_as_of_clause
 
 %type <str> explain_option_name
 %type <[]string> explain_option_list
@@ -810,7 +820,9 @@ stmt_list:
 
 stmt:
   alter_table_stmt
+| alter_zone_stmt
 | backup_stmt
+| cancel_stmt
 | copy_from_stmt
 | create_stmt
 | delete_stmt
@@ -822,7 +834,9 @@ stmt:
 | deallocate_stmt
 | grant_stmt
 | insert_stmt
+| pause_stmt
 | rename_stmt
+| resume_stmt
 | revoke_stmt
 | savepoint_stmt
 | select_stmt
@@ -1072,6 +1086,24 @@ opt_with_options:
   }
 | /* EMPTY */ {}
 
+cancel_stmt:
+  CANCEL QUERY a_expr
+  {
+    $$.val = &CancelQuery{ID: $3.expr()}
+  }
+
+pause_stmt:
+  PAUSE SCHEDULE a_expr
+  {
+    $$.val = &PauseSchedule{ID: $3.expr()}
+  }
+
+resume_stmt:
+  RESUME SCHEDULE a_expr
+  {
+    $$.val = &ResumeSchedule{ID: $3.expr()}
+  }
+
 copy_from_stmt:
   COPY qualified_name FROM STDIN
   {
@@ -1090,6 +1122,7 @@ copy_from_stmt:
 create_stmt:
   create_database_stmt
 | create_index_stmt
+| create_schedule_stmt
 | create_table_stmt
 | create_table_as_stmt
 | create_user_stmt
@@ -1634,9 +1667,9 @@ show_stmt:
   {
     $$.val = &Show{Name: $2}
   }
-| SHOW BACKUP string_or_placeholder
+| SHOW BACKUP string_or_placeholder opt_with_options
   {
-    $$.val = &ShowBackup{Path: $3.expr()}
+    $$.val = &ShowBackup{Path: $3.expr(), Options: $4.kvOptions()}
   }
 | SHOW CLUSTER SETTING any_name
   {
@@ -1714,6 +1747,10 @@ show_stmt:
   {
     $$.val = &ShowSessions{Cluster: false}
   }
+| SHOW SYNTAX SCONST
+  {
+    $$.val = &ShowSyntax{Statement: $3}
+  }
 | SHOW TABLES FROM name
   {
     $$.val = &ShowTables{Database: Name($4)}
@@ -1769,6 +1806,14 @@ show_stmt:
     /* SKIP DOC */
     $$.val = &ShowFingerprints{Table: $5.newNormalizableTableName(), AsOf: $6.asOfClause()}
   }
+| SHOW ZONE CONFIGURATION FOR zone_specifier
+  {
+    $$.val = &ShowZoneConfig{ZoneSpecifier: $5.zoneSpecifier()}
+  }
+| SHOW ALL ZONE CONFIGURATIONS
+  {
+    $$.val = &ShowZoneConfig{}
+  }
 
 help_stmt:
   HELP unrestricted_name
@@ -1841,6 +1886,30 @@ scatter_stmt:
     $$.val = &Scatter{Index: $3.tableWithIdx(), From: $7.exprs(), To: $11.exprs()}
   }
 
+alter_zone_stmt:
+  ALTER zone_specifier CONFIGURE ZONE a_expr
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: $2.zoneSpecifier(), YAMLConfig: $5.expr()}
+  }
+
+zone_specifier:
+  RANGE unrestricted_name
+  {
+    $$.val = ZoneSpecifier{NamedZone: Name($2)}
+  }
+| DATABASE name
+  {
+    $$.val = ZoneSpecifier{Database: Name($2)}
+  }
+| TABLE qualified_name
+  {
+    $$.val = ZoneSpecifier{Table: $2.newNormalizableTableName()}
+  }
+| INDEX table_name_with_index
+  {
+    $$.val = ZoneSpecifier{Index: $2.tableWithIdx()}
+  }
+
 // CREATE TABLE relname
 create_table_stmt:
   CREATE TABLE any_name '(' opt_table_elem_list ')' opt_interleave
@@ -2187,6 +2256,12 @@ create_user_stmt:
     $$.val = &CreateUser{Name: Name($3), Password: $5.strPtr()}
   }
 
+create_schedule_stmt:
+  CREATE SCHEDULE name RECURRING SCONST opt_with_options FOR backup_stmt
+  {
+    $$.val = &CreateSchedule{Name: Name($3), Recurrence: $5, Options: $6.kvOptions(), Statement: $8.stmt()}
+  }
+
 opt_password:
   PASSWORD SCONST
   {
@@ -2448,6 +2523,10 @@ transaction_mode:
   {
     $$.val = TransactionModes{ReadWriteMode: $1.readWriteMode()}
   }
+| as_of_clause
+  {
+    $$.val = TransactionModes{AsOf: $1.asOfClause()}
+  }
 
 transaction_user_priority:
   PRIORITY user_priority
@@ -3321,11 +3400,14 @@ opt_alias_clause:
     $$.val = AliasClause{}
   }
 
-opt_as_of_clause:
+as_of_clause:
   AS_LA OF SYSTEM TIME a_expr_const
   {
     $$.val = AsOfClause{Expr: $5.expr()}
   }
+
+opt_as_of_clause:
+  as_of_clause
 | /* EMPTY */
   {
     $$.val = AsOfClause{}
@@ -5298,11 +5380,15 @@ unreserved_keyword:
 | BEGIN
 | BLOB
 | BY
+| CANCEL
 | CASCADE
 | CLUSTER
 | COLUMNS
 | COMMIT
 | COMMITTED
+| CONFIGURATION
+| CONFIGURATIONS
+| CONFIGURE
 | CONFLICT
 | CONSTRAINTS
 | COPY
@@ -5363,12 +5449,15 @@ unreserved_keyword:
 | PARTIAL
 | PARTITION
 | PASSWORD
+| PAUSE
 | PRECEDING
 | PREPARE
 | PRIORITY
 | QUERIES
+| QUERY
 | RANGE
 | READ
+| RECURRING
 | RECURSIVE
 | REF
 | REGCLASS
@@ -5382,6 +5471,7 @@ unreserved_keyword:
 | RESET
 | RESTORE
 | RESTRICT
+| RESUME
 | REVOKE
 | ROLLBACK
 | ROLLUP
@@ -5391,6 +5481,7 @@ unreserved_keyword:
 | STATUS
 | SAVEPOINT
 | SCATTER
+| SCHEDULE
 | SEARCH
 | SECOND
 | SERIALIZABLE
@@ -5406,6 +5497,7 @@ unreserved_keyword:
 | STORING
 | STRICT
 | SPLIT
+| SYNTAX
 | SYSTEM
 | TABLES
 | TEMPLATE
@@ -107,6 +107,12 @@ func (*BeginTransaction) StatementTag() string { return "BEGIN" }
 
 func (*BeginTransaction) hiddenFromStats() {}
 
+// StatementType implements the Statement interface.
+func (*CancelQuery) StatementType() StatementType { return Ack }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*CancelQuery) StatementTag() string { return "CANCEL QUERY" }
+
 // StatementType implements the Statement interface.
 func (*CommitTransaction) StatementType() StatementType { return Ack }
 
@@ -133,6 +139,12 @@ func (*CreateIndex) StatementType() StatementType { return DDL }
 // StatementTag returns a short string identifying the type of statement.
 func (*CreateIndex) StatementTag() string { return "CREATE INDEX" }
 
+// StatementType implements the Statement interface.
+func (*CreateSchedule) StatementType() StatementType { return Rows }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*CreateSchedule) StatementTag() string { return "CREATE SCHEDULE" }
+
 // StatementType implements the Statement interface.
 func (*CreateTable) StatementType() StatementType { return DDL }
 
@@ -240,6 +252,12 @@ func (*ParenSelect) StatementType() StatementType { return Rows }
 // StatementTag returns a short string identifying the type of statement.
 func (*ParenSelect) StatementTag() string { return "SELECT" }
 
+// StatementType implements the Statement interface.
+func (*PauseSchedule) StatementType() StatementType { return Ack }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*PauseSchedule) StatementTag() string { return "PAUSE SCHEDULE" }
+
 // StatementType implements the Statement interface.
 func (*Prepare) StatementType() StatementType { return Ack }
 
@@ -297,6 +315,12 @@ func (*Restore) StatementType() StatementType { return Rows }
 // StatementTag returns a short string identifying the type of statement.
 func (*Restore) StatementTag() string { return "RESTORE" }
 
+// StatementType implements the Statement interface.
+func (*ResumeSchedule) StatementType() StatementType { return Ack }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*ResumeSchedule) StatementTag() string { return "RESUME SCHEDULE" }
+
 // StatementType implements the Statement interface.
 func (*Revoke) StatementType() StatementType { return DDL }
 
@@ -353,6 +377,12 @@ func (*Set) StatementTag() string { return "SET" }
 
 func (*Set) hiddenFromStats() {}
 
+// StatementType implements the Statement interface.
+func (*SetZoneConfig) StatementType() StatementType { return DDL }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*SetZoneConfig) StatementTag() string { return "CONFIGURE ZONE" }
+
 // StatementType implements the Statement interface.
 func (*SetTransaction) StatementType() StatementType { return Ack }
 
@@ -467,6 +497,15 @@ func (*ShowSessions) StatementTag() string { return "SHOW SESSIONS" }
 func (*ShowSessions) hiddenFromStats()                   {}
 func (*ShowSessions) independentFromParallelizedPriors() {}
 
+// StatementType implements the Statement interface.
+func (*ShowSyntax) StatementType() StatementType { return Rows }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*ShowSyntax) StatementTag() string { return "SHOW SYNTAX" }
+
+func (*ShowSyntax) hiddenFromStats()                   {}
+func (*ShowSyntax) independentFromParallelizedPriors() {}
+
 // StatementType implements the Statement interface.
 func (*ShowTransactionStatus) StatementType() StatementType { return Rows }
 
@@ -501,6 +540,14 @@ func (*ShowFingerprints) StatementTag() string { return "SHOW EXPERIMENTAL_FINGE
 
 func (*ShowFingerprints) independentFromParallelizedPriors() {}
 
+// StatementType implements the Statement interface.
+func (*ShowZoneConfig) StatementType() StatementType { return Rows }
+
+// StatementTag returns a short string identifying the type of statement.
+func (*ShowZoneConfig) StatementTag() string { return "SHOW ZONE CONFIGURATION" }
+
+func (*ShowZoneConfig) independentFromParallelizedPriors() {}
+
 // StatementType implements the Statement interface.
 func (*Help) StatementType() StatementType { return Rows }
 
@@ -568,10 +615,12 @@ func (n *AlterTableDropNotNull) String() string    { return AsString(n) }
 func (n *AlterTableSetDefault) String() string     { return AsString(n) }
 func (n *Backup) String() string                   { return AsString(n) }
 func (n *BeginTransaction) String() string         { return AsString(n) }
+func (n *CancelQuery) String() string              { return AsString(n) }
 func (n *CommitTransaction) String() string        { return AsString(n) }
 func (n *CopyFrom) String() string                 { return AsString(n) }
 func (n *CreateDatabase) String() string           { return AsString(n) }
 func (n *CreateIndex) String() string              { return AsString(n) }
+func (n *CreateSchedule) String() string           { return AsString(n) }
 func (n *CreateTable) String() string              { return AsString(n) }
 func (n *CreateUser) String() string               { return AsString(n) }
 func (n *CreateView) String() string               { return AsString(n) }
@@ -588,6 +637,7 @@ func (n *Grant) String() string                    { return AsString(n) }
 func (n *Help) String() string                     { return AsString(n) }
 func (n *Insert) String() string                   { return AsString(n) }
 func (n *ParenSelect) String() string              { return AsString(n) }
+func (n *PauseSchedule) String() string            { return AsString(n) }
 func (n *Prepare) String() string                  { return AsString(n) }
 func (n *ReleaseSavepoint) String() string         { return AsString(n) }
 func (n *Relocate) String() string                 { return AsString(n) }
@@ -596,6 +646,7 @@ func (n *RenameDatabase) String() string           { return AsString(n) }
 func (n *RenameIndex) String() string              { return AsString(n) }
 func (n *RenameTable) String() string              { return AsString(n) }
 func (n *Restore) String() string                  { return AsString(n) }
+func (n *ResumeSchedule) String() string           { return AsString(n) }
 func (n *Revoke) String() string                   { return AsString(n) }
 func (n *RollbackToSavepoint) String() string      { return AsString(n) }
 func (n *RollbackTransaction) String() string      { return AsString(n) }
@@ -606,6 +657,7 @@ func (n *SelectClause) String() string             { return AsString(n) }
 func (n *Set) String() string                      { return AsString(n) }
 func (n *SetDefaultIsolation) String() string      { return AsString(n) }
 func (n *SetTransaction) String() string           { return AsString(n) }
+func (n *SetZoneConfig) String() string            { return AsString(n) }
 func (n *Show) String() string                     { return AsString(n) }
 func (n *ShowBackup) String() string               { return AsString(n) }
 func (n *ShowColumns) String() string              { return AsString(n) }
@@ -618,11 +670,13 @@ func (n *ShowConstraints) String() string          { return AsString(n) }
 func (n *ShowQueries) String() string              { return AsString(n) }
 func (n *ShowSessions) String() string             { return AsString(n) }
 func (n *ShowTables) String() string               { return AsString(n) }
+func (n *ShowSyntax) String() string               { return AsString(n) }
 func (n *ShowTrace) String() string                { return AsString(n) }
 func (n *ShowTransactionStatus) String() string    { return AsString(n) }
 func (n *ShowUsers) String() string                { return AsString(n) }
 func (n *ShowRanges) String() string               { return AsString(n) }
 func (n *ShowFingerprints) String() string         { return AsString(n) }
+func (n *ShowZoneConfig) String() string           { return AsString(n) }
 func (n *Split) String() string                    { return AsString(n) }
 func (l StatementList) String() string             { return AsString(l) }
 func (n *Truncate) String() string                 { return AsString(n) }
@@ -24,13 +24,20 @@ import (
 // Table names are used in statements like CREATE TABLE,
 // INSERT INTO, etc.
 // General syntax:
-//    [ <database-name> '.' ] <table-name>
+//    [ <database-name> '.' [ <schema-name> '.' ] ] <table-name>
+//
+// Databases double as schemas, so the three-part form is only accepted with
+// the "public" schema, which refers to the database itself.
 //
 // The other syntax nodes hold a mutable NormalizableTableName
 // attribute.  This is populated during parsing with an
 // UnresolvedName, and gets assigned an actual TableName upon the first
 // call to its Normalize() method.
 
+// PublicSchema is the name of the schema which, in three-part table names and
+// in the search path, stands for the database itself.
+const PublicSchema = "public"
+
 // NormalizableTableName implements an editable table name.
 type NormalizableTableName struct {
 	TableNameReference
@@ -144,10 +151,18 @@ func (t *TableName) Database() string {
 // valid if e.g. the name refers to a in-query table alias
 // (AS) or is qualified later using the QualifyWithDatabase method.
 func (n UnresolvedName) normalizeTableNameAsValue() (TableName, error) {
-	if len(n) == 0 || len(n) > 2 {
+	if len(n) == 0 || len(n) > 3 {
 		return TableName{}, fmt.Errorf("invalid table name: %q", n)
 	}
 
+	if len(n) == 3 {
+		// Reduce database.public.table to the two-part form.
+		if schema, ok := n[1].(Name); !ok || schema.Normalize() != PublicSchema {
+			return TableName{}, fmt.Errorf("invalid table name: %q", n)
+		}
+		n = UnresolvedName{n[0], n[2]}
+	}
+
 	name, ok := n[len(n)-1].(Name)
 	if !ok {
 		return TableName{}, fmt.Errorf("invalid table name: %q", n)
@@ -35,12 +35,15 @@ func TestNormalizeTableName(t *testing.T) {
 		{`foo`, `test.foo`, `test`, ``},
 		{`test.foo`, `test.foo`, ``, ``},
 		{`bar.foo`, `bar.foo`, `test`, ``},
+		{`bar.public.foo`, `bar.foo`, `test`, ``},
+		{`bar.PUBLIC.foo`, `bar.foo`, `test`, ``},
 
 		{`""`, ``, ``, `empty table name`},
 		{`foo`, ``, ``, `no database specified`},
 		{`foo@bar`, ``, ``, `syntax error`},
 		{`test.foo.bar`, ``, ``, `invalid table name: "test.foo.bar"`},
 		{`test.*`, ``, ``, `invalid table name: "test.*"`},
+		{`a.public.b.c`, ``, ``, `invalid table name: "a.public.b.c"`},
 	}
 
 	for _, tc := range testCases {
@@ -100,6 +100,9 @@ type TransactionModes struct {
 	Isolation     IsolationLevel
 	UserPriority  UserPriority
 	ReadWriteMode ReadWriteMode
+	// AsOf, if set, pins the transaction to a historical timestamp. Such
+	// transactions are read-only.
+	AsOf AsOfClause
 }
 
 // Format implements the NodeFormatter interface.
@@ -115,6 +118,11 @@ func (node *TransactionModes) Format(buf *bytes.Buffer, f FmtFlags) {
 	}
 	if node.ReadWriteMode != UnspecifiedReadWriteMode {
 		fmt.Fprintf(buf, "%s READ %s", sep, node.ReadWriteMode)
+		sep = ","
+	}
+	if node.AsOf.Expr != nil {
+		fmt.Fprintf(buf, "%s ", sep)
+		FormatNode(buf, f, node.AsOf)
 	}
 }
 
@@ -137,6 +145,12 @@ func (node *TransactionModes) merge(other TransactionModes) error {
 		}
 		node.ReadWriteMode = other.ReadWriteMode
 	}
+	if other.AsOf.Expr != nil {
+		if node.AsOf.Expr != nil {
+			return errors.New("AS OF SYSTEM TIME specified multiple times")
+		}
+		node.AsOf = other.AsOf
+	}
 	return nil
 }
 
@@ -0,0 +1,79 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "bytes"
+
+// ZoneSpecifier represents a reference to a configurable zone of the keyspace.
+type ZoneSpecifier struct {
+	// Only one of NamedZone, Database, Table and Index can be set.
+	NamedZone Name
+	Database  Name
+	Table     *NormalizableTableName
+	Index     *TableNameWithIndex
+}
+
+// Format implements the NodeFormatter interface.
+func (node ZoneSpecifier) Format(buf *bytes.Buffer, f FmtFlags) {
+	switch {
+	case node.NamedZone != "":
+		buf.WriteString("RANGE ")
+		FormatNode(buf, f, node.NamedZone)
+	case node.Database != "":
+		buf.WriteString("DATABASE ")
+		FormatNode(buf, f, node.Database)
+	case node.Index != nil:
+		buf.WriteString("INDEX ")
+		FormatNode(buf, f, node.Index)
+	default:
+		buf.WriteString("TABLE ")
+		FormatNode(buf, f, node.Table)
+	}
+}
+
+func (node ZoneSpecifier) String() string { return AsString(node) }
+
+// SetZoneConfig represents an ALTER DATABASE/TABLE/INDEX/RANGE ... CONFIGURE
+// ZONE statement.
+type SetZoneConfig struct {
+	ZoneSpecifier
+	// YAMLConfig evaluates to the YAML-encoded zone config changes, or to NULL
+	// to remove the zone config.
+	YAMLConfig Expr
+}
+
+// Format implements the NodeFormatter interface.
+func (node *SetZoneConfig) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("ALTER ")
+	FormatNode(buf, f, node.ZoneSpecifier)
+	buf.WriteString(" CONFIGURE ZONE ")
+	FormatNode(buf, f, node.YAMLConfig)
+}
+
+// ShowZoneConfig represents a SHOW ZONE CONFIGURATION statement. A zero
+// ZoneSpecifier shows all the zone configs.
+type ShowZoneConfig struct {
+	ZoneSpecifier
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowZoneConfig) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.ZoneSpecifier == (ZoneSpecifier{}) {
+		buf.WriteString("SHOW ALL ZONE CONFIGURATIONS")
+		return
+	}
+	buf.WriteString("SHOW ZONE CONFIGURATION FOR ")
+	FormatNode(buf, f, node.ZoneSpecifier)
+}
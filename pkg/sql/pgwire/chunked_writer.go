@@ -0,0 +1,132 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgwire
+
+import (
+	"io"
+	"net"
+	"sync"
+)
+
+// chunkSize is the size of the chunks in which a chunkedWriter buffers data.
+const chunkSize = 16 << 10
+
+// maxBufferedBytes is the amount of data that a chunkedWriter buffers before
+// writing out the full chunks, without waiting for an explicit Flush. This
+// bounds the memory used for large result sets.
+const maxBufferedBytes = 256 << 10
+
+type chunk struct {
+	buf [chunkSize]byte
+	n   int
+}
+
+var chunkPool = sync.Pool{
+	New: func() interface{} {
+		return &chunk{}
+	},
+}
+
+// chunkedWriter buffers the messages sent to a client in fixed-size chunks
+// taken from a pool, so that sending large result sets doesn't require large
+// contiguous allocations. The buffered data is written out when Flush is
+// called, which happens at the points where the protocol requires the client
+// to see the messages sent so far (e.g. when the server becomes ready for a
+// new query after a Sync message). In addition, the full chunks are written
+// out whenever more than maxBufferedBytes are buffered.
+//
+// Once writing to the underlying io.Writer fails, all subsequent operations
+// return the error.
+type chunkedWriter struct {
+	w io.Writer
+	// chunks holds the buffered data; all the chunks but the last one are full.
+	chunks   []*chunk
+	buffered int
+	// iov is reused across the calls to Flush.
+	iov net.Buffers
+	err error
+}
+
+func newChunkedWriter(w io.Writer) *chunkedWriter {
+	return &chunkedWriter{w: w}
+}
+
+// Write implements the io.Writer interface. It only fails if writing out
+// previously buffered data fails.
+func (w *chunkedWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	written := 0
+	for len(p) > 0 {
+		if len(w.chunks) == 0 || w.chunks[len(w.chunks)-1].n == chunkSize {
+			if w.buffered >= maxBufferedBytes {
+				// All the chunks are full; write them out.
+				if err := w.Flush(); err != nil {
+					return written, err
+				}
+			}
+			w.chunks = append(w.chunks, chunkPool.Get().(*chunk))
+		}
+		c := w.chunks[len(w.chunks)-1]
+		n := copy(c.buf[c.n:], p)
+		c.n += n
+		w.buffered += n
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Flush writes out all the buffered data and returns the chunks to the pool.
+func (w *chunkedWriter) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.chunks) == 0 {
+		return nil
+	}
+	w.iov = w.iov[:0]
+	for _, c := range w.chunks {
+		w.iov = append(w.iov, c.buf[:c.n])
+	}
+	// WriteTo consumes the slice it is called on, so use a copy of the header.
+	iov := w.iov
+	if _, err := iov.WriteTo(w.w); err != nil {
+		w.err = err
+		return err
+	}
+	for i := range w.iov {
+		w.iov[i] = nil
+	}
+	w.release()
+	return nil
+}
+
+// Buffered returns the number of bytes that haven't been written out yet.
+func (w *chunkedWriter) Buffered() int {
+	return w.buffered
+}
+
+// release discards the buffered data and returns the chunks to the pool.
+func (w *chunkedWriter) release() {
+	for i, c := range w.chunks {
+		c.n = 0
+		chunkPool.Put(c)
+		w.chunks[i] = nil
+	}
+	w.chunks = w.chunks[:0]
+	w.buffered = 0
+}
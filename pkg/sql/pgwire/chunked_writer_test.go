@@ -0,0 +1,118 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgwire
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// errWriter fails every write.
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("boom")
+}
+
+func TestChunkedWriter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var out bytes.Buffer
+	w := newChunkedWriter(&out)
+	var expected bytes.Buffer
+	write := func(size int) {
+		p := bytes.Repeat([]byte{byte(size)}, size)
+		if n, err := w.Write(p); err != nil {
+			t.Fatal(err)
+		} else if n != size {
+			t.Fatalf("expected %d bytes to be written, got %d", size, n)
+		}
+		expected.Write(p)
+	}
+
+	// Small writes are buffered until the writer is flushed, including writes
+	// that span several chunks.
+	write(10)
+	write(chunkSize)
+	write(3*chunkSize + 7)
+	if out.Len() != 0 {
+		t.Fatalf("expected no data to be written before Flush, got %d bytes", out.Len())
+	}
+	if e, a := expected.Len(), w.Buffered(); e != a {
+		t.Fatalf("expected %d bytes buffered, got %d", e, a)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), expected.Bytes()) {
+		t.Fatal("unexpected data written")
+	}
+	if w.Buffered() != 0 {
+		t.Fatalf("expected no data buffered after Flush, got %d bytes", w.Buffered())
+	}
+
+	// Once more than maxBufferedBytes are buffered, full chunks are written out
+	// without waiting for a Flush.
+	write(maxBufferedBytes + 1)
+	if out.Len() == expected.Len() {
+		t.Fatal("expected some data to remain buffered")
+	}
+	if w.Buffered() > maxBufferedBytes {
+		t.Fatalf("expected at most %d bytes buffered, got %d", maxBufferedBytes, w.Buffered())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), expected.Bytes()) {
+		t.Fatal("unexpected data written")
+	}
+
+	// Errors are sticky.
+	w = newChunkedWriter(errWriter{})
+	if _, err := w.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := w.Write([]byte("b")); err == nil {
+		t.Fatal("expected error")
+	}
+	w.release()
+}
+
+func BenchmarkChunkedWriter(b *testing.B) {
+	row := bytes.Repeat([]byte{'x'}, 100)
+	var out bytes.Buffer
+	w := newChunkedWriter(&out)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out.Reset()
+		// A result set of 10k rows, followed by a sync boundary.
+		for j := 0; j < 10000; j++ {
+			if _, err := w.Write(row); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -254,8 +254,17 @@ func (b *writeBuffer) putErrFieldMsg(field serverErrFieldType) {
 	}
 }
 
+// maxRetainedMsgSize is the size above which the buffer used to build a
+// message is not kept for the following messages, so that a single large
+// message doesn't pin memory for the lifetime of the connection.
+const maxRetainedMsgSize = 64 << 10
+
 func (b *writeBuffer) reset() {
-	b.wrapped.Reset()
+	if b.wrapped.Cap() > maxRetainedMsgSize {
+		b.wrapped = bytes.Buffer{}
+	} else {
+		b.wrapped.Reset()
+	}
 	b.err = nil
 }
 
@@ -33,6 +33,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/metric"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	"github.com/pkg/errors"
 )
 
@@ -155,6 +156,7 @@ func MakeServer(
 	parentMemoryMonitor *mon.MemoryMonitor,
 	histogramWindow time.Duration,
 ) *Server {
+	ambientCtx.SetSubsystem(tracing.SubsystemSQL)
 	server := &Server{
 		AmbientCtx: ambientCtx,
 		cfg:        cfg,
@@ -167,7 +167,7 @@ func (c *readTimeoutConn) Read(b []byte) (int, error) {
 type v3Conn struct {
 	conn        net.Conn
 	rd          *bufio.Reader
-	wr          *bufio.Writer
+	wr          *chunkedWriter
 	executor    *sql.Executor
 	readBuf     readBuffer
 	writeBuf    writeBuffer
@@ -197,7 +197,7 @@ func makeV3Conn(
 	return v3Conn{
 		conn:          conn,
 		rd:            bufio.NewReader(conn),
-		wr:            bufio.NewWriter(conn),
+		wr:            newChunkedWriter(conn),
 		writeBuf:      writeBuffer{bytecount: metrics.BytesOutCount},
 		metrics:       metrics,
 		executor:      executor,
@@ -210,6 +210,7 @@ func (c *v3Conn) finish(ctx context.Context) {
 	if err := c.wr.Flush(); err != nil {
 		log.Error(ctx, err)
 	}
+	c.wr.release()
 	_ = c.conn.Close()
 }
 
@@ -552,16 +553,9 @@ func (c *v3Conn) handleParse(buf *readBuffer) error {
 	// Prepare the mapping of SQL placeholder names to
 	// types. Pre-populate it with the type hints received from the
 	// client, if any.
-	sqlTypeHints := make(parser.PlaceholderTypes)
-	for i, t := range inTypeHints {
-		if t == 0 {
-			continue
-		}
-		v, ok := parser.OidToType[t]
-		if !ok {
-			return c.sendInternalError(fmt.Sprintf("unknown oid type: %v", t))
-		}
-		sqlTypeHints[strconv.Itoa(i+1)] = v
+	sqlTypeHints, err := placeholderTypeHints(inTypeHints)
+	if err != nil {
+		return c.sendInternalError(err.Error())
 	}
 	// Create the new PreparedStatement in the connection's Session.
 	stmt, err := c.session.PreparedStatements.NewFromString(c.executor, name, query, sqlTypeHints)
@@ -606,6 +600,29 @@ func (c *v3Conn) handleParse(buf *readBuffer) error {
 	return c.writeBuf.finishMsg(c.wr)
 }
 
+// placeholderTypeHints returns the types of the placeholders given by the
+// client in a Parse message, which take precedence over the types inferred
+// from the statement. As in Postgres, the placeholders whose type is 0 or
+// unknown are left to be inferred: their hints are reset to 0.
+func placeholderTypeHints(inTypeHints []oid.Oid) (parser.PlaceholderTypes, error) {
+	sqlTypeHints := make(parser.PlaceholderTypes)
+	for i, t := range inTypeHints {
+		if t == oid.T_unknown {
+			inTypeHints[i] = 0
+			continue
+		}
+		if t == 0 {
+			continue
+		}
+		v, ok := parser.OidToType[t]
+		if !ok {
+			return nil, errors.Errorf("unknown oid type: %v", t)
+		}
+		sqlTypeHints[strconv.Itoa(i+1)] = v
+	}
+	return sqlTypeHints, nil
+}
+
 // canSendNoData returns true if describing a result of the input statement
 // type should return NoData.
 func canSendNoData(stmt parser.Statement) bool {
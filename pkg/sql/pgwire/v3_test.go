@@ -21,12 +21,16 @@ import (
 	"io"
 	"io/ioutil"
 	"net"
+	"reflect"
 	"testing"
 
+	"github.com/lib/pq/oid"
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/pkg/sql"
 	"github.com/cockroachdb/cockroach/pkg/sql/mon"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
@@ -110,6 +114,49 @@ func testMaliciousInput(t *testing.T, data []byte) {
 	)
 }
 
+func TestPlaceholderTypeHints(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		hints         []oid.Oid
+		expected      parser.PlaceholderTypes
+		expectedHints []oid.Oid
+		err           string
+	}{
+		{nil, parser.PlaceholderTypes{}, nil, ""},
+		{
+			[]oid.Oid{oid.T_int8, 0, oid.T_text},
+			parser.PlaceholderTypes{"1": parser.TypeInt, "3": parser.TypeString},
+			[]oid.Oid{oid.T_int8, 0, oid.T_text},
+			"",
+		},
+		// Unknown placeholders are inferred, like those without a type.
+		{
+			[]oid.Oid{oid.T_unknown, oid.T_bool},
+			parser.PlaceholderTypes{"2": parser.TypeBool},
+			[]oid.Oid{0, oid.T_bool},
+			"",
+		},
+		{[]oid.Oid{oid.T_bool, oid.T_circle}, nil, nil, "unknown oid type: 718"},
+	}
+	for i, tc := range testCases {
+		types, err := placeholderTypeHints(tc.hints)
+		if !testutils.IsError(err, tc.err) {
+			t.Errorf("%d: expected error %q, got %v", i, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(types, tc.expected) {
+			t.Errorf("%d: expected %v, got %v", i, tc.expected, types)
+		}
+		if !reflect.DeepEqual(tc.hints, tc.expectedHints) {
+			t.Errorf("%d: expected the hints to become %v, got %v", i, tc.expectedHints, tc.hints)
+		}
+	}
+}
+
 // TestReadTimeoutConn asserts that a readTimeoutConn performs reads normally
 // and exits with an appropriate error when exit conditions are satisfied.
 func TestReadTimeoutConnExits(t *testing.T) {
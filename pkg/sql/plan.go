@@ -255,6 +255,10 @@ func (p *planner) newPlan(
 ) (planNode, error) {
 	tracing.AnnotateTrace()
 
+	if err := p.checkReadOnlyTxnStmt(stmt); err != nil {
+		return nil, err
+	}
+
 	// This will set the system DB trigger for transactions containing
 	// DDL statements that have no effect, such as
 	// `BEGIN; INSERT INTO ...; CREATE TABLE IF NOT EXISTS ...; COMMIT;`
@@ -276,6 +280,8 @@ func (p *planner) newPlan(
 		return p.AlterTable(ctx, n)
 	case *parser.BeginTransaction:
 		return p.BeginTransaction(n)
+	case *parser.CancelQuery:
+		return p.CancelQuery(ctx, n)
 	case CopyDataBlock:
 		return p.CopyData(ctx, n)
 	case *parser.CopyFrom:
@@ -284,6 +290,8 @@ func (p *planner) newPlan(
 		return p.CreateDatabase(n)
 	case *parser.CreateIndex:
 		return p.CreateIndex(ctx, n)
+	case *parser.CreateSchedule:
+		return p.CreateSchedule(n)
 	case *parser.CreateTable:
 		return p.CreateTable(ctx, n)
 	case *parser.CreateUser:
@@ -312,6 +320,8 @@ func (p *planner) newPlan(
 		return p.Insert(ctx, n, desiredTypes)
 	case *parser.ParenSelect:
 		return p.newPlan(ctx, n.Select, desiredTypes)
+	case *parser.PauseSchedule:
+		return p.PauseSchedule(ctx, n)
 	case *parser.Relocate:
 		return p.Relocate(ctx, n)
 	case *parser.RenameColumn:
@@ -322,6 +332,8 @@ func (p *planner) newPlan(
 		return p.RenameIndex(ctx, n)
 	case *parser.RenameTable:
 		return p.RenameTable(ctx, n)
+	case *parser.ResumeSchedule:
+		return p.ResumeSchedule(ctx, n)
 	case *parser.Revoke:
 		return p.Revoke(ctx, n)
 	case *parser.Scatter:
@@ -336,6 +348,8 @@ func (p *planner) newPlan(
 		return p.SetTransaction(n)
 	case *parser.SetDefaultIsolation:
 		return p.SetDefaultIsolation(n)
+	case *parser.SetZoneConfig:
+		return p.SetZoneConfig(ctx, n)
 	case *parser.Show:
 		return p.Show(n)
 	case *parser.ShowColumns:
@@ -360,6 +374,8 @@ func (p *planner) newPlan(
 		return p.ShowTables(ctx, n)
 	case *parser.ShowTrace:
 		return p.ShowTrace(ctx, n)
+	case *parser.ShowSyntax:
+		return p.ShowSyntax(ctx, n)
 	case *parser.ShowTransactionStatus:
 		return p.ShowTransactionStatus()
 	case *parser.ShowUsers:
@@ -368,6 +384,8 @@ func (p *planner) newPlan(
 		return p.ShowRanges(ctx, n)
 	case *parser.ShowFingerprints:
 		return p.ShowFingerprints(ctx, n)
+	case *parser.ShowZoneConfig:
+		return p.ShowZoneConfig(ctx, n)
 	case *parser.Split:
 		return p.Split(ctx, n)
 	case *parser.Truncate:
@@ -395,6 +413,8 @@ func (p *planner) prepare(ctx context.Context, stmt parser.Statement) (planNode,
 	switch n := stmt.(type) {
 	case *parser.Delete:
 		return p.Delete(ctx, n, nil)
+	case *parser.CreateSchedule:
+		return p.CreateSchedule(n)
 	case *parser.Explain:
 		return p.Explain(ctx, n)
 	case *parser.Help:
@@ -431,10 +451,14 @@ func (p *planner) prepare(ctx context.Context, stmt parser.Statement) (planNode,
 		return p.ShowTrace(ctx, n)
 	case *parser.ShowUsers:
 		return p.ShowUsers(ctx, n)
+	case *parser.ShowSyntax:
+		return p.ShowSyntax(ctx, n)
 	case *parser.ShowTransactionStatus:
 		return p.ShowTransactionStatus()
 	case *parser.ShowRanges:
 		return p.ShowRanges(ctx, n)
+	case *parser.ShowZoneConfig:
+		return p.ShowZoneConfig(ctx, n)
 	case *parser.Split:
 		return p.Split(ctx, n)
 	case *parser.Relocate:
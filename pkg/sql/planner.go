@@ -147,6 +147,15 @@ func (p *planner) LeaseMgr() *LeaseManager {
 	return p.session.leases.leaseMgr
 }
 
+// maybeRefreshLeaseDeadline refreshes the deadline of the transaction from
+// the leases of the session if the plan commits the transaction, which it's
+// about to do.
+func (p *planner) maybeRefreshLeaseDeadline(ctx context.Context) {
+	if p.autoCommit {
+		p.session.leases.refreshDeadline(ctx, p.txn)
+	}
+}
+
 func (p *planner) User() string {
 	return p.session.User
 }
@@ -18,15 +18,31 @@ package sql
 
 import (
 	"bytes"
+	"container/list"
 	"unsafe"
 
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/mon"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
 )
 
+// maxPreparedStatementsSize bounds the memory accounted for the prepared
+// statements and portals of a session. Preparing a statement evicts the least
+// recently used statements of the session as needed to stay under it, so that
+// clients preparing thousands of statements don't exhaust the memory of the
+// node.
+var maxPreparedStatementsSize = settings.RegisterByteSizeSetting(
+	"sql.session.prepared_statements.max_size",
+	"maximum memory used by the prepared statements and portals of a session, beyond which "+
+		"the least recently used statements are evicted (set to 0 to disable)",
+	16<<20)
+
 // PreparedStatement is a SQL statement that has been parsed and the types
 // of arguments and results have been determined.
 type PreparedStatement struct {
@@ -45,9 +61,14 @@ type PreparedStatement struct {
 	ProtocolMeta interface{} // a field for protocol implementations to hang metadata off of.
 
 	memAcc WrappableMemoryAccount
+	// memSize is the size accounted in memAcc.
+	memSize int64
 	// constantAcc handles the allocation of various constant-folded values which
 	// are generated while planning the statement.
 	constantAcc mon.BoundAccount
+	// lruElem is the element of the statement in the LRU list of the session's
+	// PreparedStatements.
+	lruElem *list.Element
 }
 
 func (p *PreparedStatement) close(ctx context.Context, s *Session) {
@@ -95,24 +116,40 @@ func (l StatementList) Format(buf *bytes.Buffer, f parser.FmtFlags) {
 type PreparedStatements struct {
 	session *Session
 	stmts   map[string]*PreparedStatement
+	// lru holds the names of the statements, from the most to the least
+	// recently used.
+	lru *list.List
+	// size is the memory accounted for the statements and the portals.
+	size int64
 }
 
 func makePreparedStatements(s *Session) PreparedStatements {
 	return PreparedStatements{
 		session: s,
 		stmts:   make(map[string]*PreparedStatement),
+		lru:     list.New(),
 	}
 }
 
-// Get returns the PreparedStatement with the provided name.
-func (ps PreparedStatements) Get(name string) (*PreparedStatement, bool) {
+// Get returns the PreparedStatement with the provided name, marking it as the
+// most recently used.
+func (ps *PreparedStatements) Get(name string) (*PreparedStatement, bool) {
 	stmt, ok := ps.stmts[name]
+	if ok {
+		ps.touch(stmt)
+	}
 	return stmt, ok
 }
 
+func (ps *PreparedStatements) touch(stmt *PreparedStatement) {
+	if stmt.lruElem != nil {
+		ps.lru.MoveToFront(stmt.lruElem)
+	}
+}
+
 // Exists returns whether a PreparedStatement with the provided name exists.
-func (ps PreparedStatements) Exists(name string) bool {
-	_, ok := ps.Get(name)
+func (ps *PreparedStatements) Exists(name string) bool {
+	_, ok := ps.stmts[name]
 	return ok
 }
 
@@ -121,7 +158,7 @@ func (ps PreparedStatements) Exists(name string) bool {
 // in inferring placeholder types.
 //
 // ps.session.Ctx() is used as the logging context for the prepare operation.
-func (ps PreparedStatements) NewFromString(
+func (ps *PreparedStatements) NewFromString(
 	e *Executor, name, query string, placeholderHints parser.PlaceholderTypes,
 ) (*PreparedStatement, error) {
 	sessionEventf(ps.session, "parsing: %s", query)
@@ -146,52 +183,99 @@ func (ps PreparedStatements) NewFromString(
 
 // New creates a new PreparedStatement with the provided name and corresponding
 // query statements, using the given PlaceholderTypes hints to assist in
-// inferring placeholder types.
+// inferring placeholder types. The least recently used statements are evicted
+// as needed to keep the session under sql.session.prepared_statements.max_size.
 //
 // ps.session.Ctx() is used as the logging context for the prepare operation.
-func (ps PreparedStatements) New(
+func (ps *PreparedStatements) New(
 	e *Executor,
 	name string,
 	stmt Statement,
 	stmtStr string,
 	placeholderHints parser.PlaceholderTypes,
 ) (*PreparedStatement, error) {
+	ctx := ps.session.Ctx()
+
 	// Prepare the query. This completes the typing of placeholders.
 	pStmt, err := e.Prepare(stmt, stmtStr, ps.session, placeholderHints)
 	if err != nil {
 		return nil, err
 	}
 
-	// For now we are just counting the size of the query string and
-	// statement name. When we start storing the prepared query plan
-	// during prepare, this should be tallied up to the monitor as well.
+	// For now we are just counting the size of the query string, the
+	// statement name and the result columns. When we start storing the
+	// prepared query plan during prepare, this should be tallied up to the
+	// monitor as well.
 	sz := int64(uintptr(len(name)+len(stmtStr)) + unsafe.Sizeof(*pStmt))
-	if err := pStmt.memAcc.Wsession(ps.session).OpenAndInit(ps.session.Ctx(), sz); err != nil {
+	for _, col := range pStmt.Columns {
+		sz += int64(uintptr(len(col.Name)) + unsafe.Sizeof(col))
+	}
+	if err := ps.evict(ctx, name, sz); err != nil {
+		pStmt.constantAcc.Close(ctx)
 		return nil, err
 	}
+	if err := pStmt.memAcc.Wsession(ps.session).OpenAndInit(ctx, sz); err != nil {
+		pStmt.constantAcc.Close(ctx)
+		return nil, err
+	}
+	pStmt.memSize = sz
 
-	if prevStmt, ok := ps.Get(name); ok {
-		prevStmt.close(ps.session.Ctx(), ps.session)
+	if prevStmt, ok := ps.stmts[name]; ok {
+		ps.close(ctx, prevStmt)
 	}
 
 	pStmt.Str = stmtStr
+	pStmt.lruElem = ps.lru.PushFront(name)
+	ps.size += sz
 	ps.stmts[name] = pStmt
 	return pStmt, nil
 }
 
+// evict deletes the least recently used statements until a new statement of
+// the given size fits under sql.session.prepared_statements.max_size. It
+// returns an error if the statement is larger than the limit on its own.
+func (ps *PreparedStatements) evict(ctx context.Context, name string, sz int64) error {
+	maxSize := maxPreparedStatementsSize.Get()
+	if maxSize <= 0 {
+		return nil
+	}
+	if sz > maxSize {
+		return pgerror.NewErrorf(pgerror.CodeProgramLimitExceededError,
+			"prepared statement %q requires %s, more than the %s allowed per session by "+
+				"sql.session.prepared_statements.max_size",
+			name, humanizeutil.IBytes(sz), humanizeutil.IBytes(maxSize))
+	}
+	for ps.size+sz > maxSize && ps.lru.Len() > 0 {
+		evicted := ps.lru.Back().Value.(string)
+		log.VEventf(ctx, 2, "evicting prepared statement %q", evicted)
+		ps.Delete(ctx, evicted)
+	}
+	return nil
+}
+
+// close releases the memory of a statement and removes it from the LRU list.
+// Its portals are left alone.
+func (ps *PreparedStatements) close(ctx context.Context, stmt *PreparedStatement) {
+	stmt.close(ctx, ps.session)
+	if stmt.lruElem != nil {
+		ps.lru.Remove(stmt.lruElem)
+		stmt.lruElem = nil
+	}
+	ps.size -= stmt.memSize
+}
+
 // Delete removes the PreparedStatement with the provided name from the PreparedStatements.
 // The method returns whether a statement with that name was found and removed.
-func (ps PreparedStatements) Delete(ctx context.Context, name string) bool {
-	if stmt, ok := ps.Get(name); ok {
+func (ps *PreparedStatements) Delete(ctx context.Context, name string) bool {
+	if stmt, ok := ps.stmts[name]; ok {
 		if ps.session.PreparedPortals.portals != nil {
 			for portalName := range stmt.portalNames {
-				if portal, ok := ps.session.PreparedPortals.Get(name); ok {
-					delete(ps.session.PreparedPortals.portals, portalName)
-					portal.memAcc.Wsession(ps.session).Close(ctx)
+				if portal, ok := ps.session.PreparedPortals.portals[portalName]; ok {
+					ps.session.PreparedPortals.remove(ctx, portalName, portal)
 				}
 			}
 		}
-		stmt.close(ctx, ps.session)
+		ps.close(ctx, stmt)
 		delete(ps.stmts, name)
 		return true
 	}
@@ -199,13 +283,16 @@ func (ps PreparedStatements) Delete(ctx context.Context, name string) bool {
 }
 
 // closeAll de-registers all statements and portals from the monitor.
-func (ps PreparedStatements) closeAll(ctx context.Context, s *Session) {
+func (ps *PreparedStatements) closeAll(ctx context.Context, s *Session) {
 	for _, stmt := range ps.stmts {
 		stmt.close(ctx, s)
+		stmt.lruElem = nil
 	}
 	for _, portal := range s.PreparedPortals.portals {
 		portal.memAcc.Wsession(s).Close(ctx)
 	}
+	ps.lru.Init()
+	ps.size = 0
 }
 
 // ClearStatementsAndPortals de-registers all statements and
@@ -234,6 +321,8 @@ type PreparedPortal struct {
 	ProtocolMeta interface{} // a field for protocol implementations to hang metadata off of.
 
 	memAcc WrappableMemoryAccount
+	// memSize is the size accounted in memAcc.
+	memSize int64
 }
 
 // PreparedPortals is a mapping of PreparedPortal names to their corresponding
@@ -250,15 +339,19 @@ func makePreparedPortals(s *Session) PreparedPortals {
 	}
 }
 
-// Get returns the PreparedPortal with the provided name.
+// Get returns the PreparedPortal with the provided name, marking its statement
+// as the most recently used.
 func (pp PreparedPortals) Get(name string) (*PreparedPortal, bool) {
 	portal, ok := pp.portals[name]
+	if ok {
+		pp.session.PreparedStatements.touch(portal.Stmt)
+	}
 	return portal, ok
 }
 
 // Exists returns whether a PreparedPortal with the provided name exists.
 func (pp PreparedPortals) Exists(name string) bool {
-	_, ok := pp.Get(name)
+	_, ok := pp.portals[name]
 	return ok
 }
 
@@ -272,16 +365,23 @@ func (pp PreparedPortals) New(
 		Qargs: qargs,
 	}
 	sz := int64(uintptr(len(name)) + unsafe.Sizeof(*portal))
+	for k, v := range qargs {
+		sz += int64(len(k))
+		if d, ok := v.(parser.Datum); ok {
+			sz += int64(d.Size())
+		}
+	}
 	if err := portal.memAcc.Wsession(pp.session).OpenAndInit(ctx, sz); err != nil {
 		return nil, err
 	}
+	portal.memSize = sz
 
-	stmt.portalNames[name] = struct{}{}
-
-	if prevPortal, ok := pp.Get(name); ok {
-		prevPortal.memAcc.Wsession(pp.session).Close(ctx)
+	if prevPortal, ok := pp.portals[name]; ok {
+		pp.remove(ctx, name, prevPortal)
 	}
 
+	stmt.portalNames[name] = struct{}{}
+	pp.session.PreparedStatements.size += sz
 	pp.portals[name] = portal
 	return portal, nil
 }
@@ -289,11 +389,16 @@ func (pp PreparedPortals) New(
 // Delete removes the PreparedPortal with the provided name from the PreparedPortals.
 // The method returns whether a portal with that name was found and removed.
 func (pp PreparedPortals) Delete(ctx context.Context, name string) bool {
-	if portal, ok := pp.Get(name); ok {
-		delete(portal.Stmt.portalNames, name)
-		portal.memAcc.Wsession(pp.session).Close(ctx)
-		delete(pp.portals, name)
+	if portal, ok := pp.portals[name]; ok {
+		pp.remove(ctx, name, portal)
 		return true
 	}
 	return false
 }
+
+func (pp PreparedPortals) remove(ctx context.Context, name string, portal *PreparedPortal) {
+	delete(portal.Stmt.portalNames, name)
+	portal.memAcc.Wsession(pp.session).Close(ctx)
+	pp.session.PreparedStatements.size -= portal.memSize
+	delete(pp.portals, name)
+}
@@ -0,0 +1,90 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/base"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+// TestPreparedStatementsEviction verifies that the least recently used
+// prepared statements of a session are evicted to keep it under
+// sql.session.prepared_statements.max_size.
+func TestPreparedStatementsEviction(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// The statements below account for a little more than 20KB each, so that
+	// three of them fit under the limit, but not four.
+	defer settings.TestingSetByteSize(&maxPreparedStatementsSize, 70000)()
+
+	s, db, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop(context.TODO())
+	// Prepared statements belong to a session, i.e. to a connection.
+	db.SetMaxOpenConns(1)
+
+	prepare := func(name string, size int) error {
+		_, err := db.Exec(fmt.Sprintf("PREPARE %s AS SELECT '%s'", name, strings.Repeat("x", size)))
+		return err
+	}
+	execute := func(name string) error {
+		_, err := db.Exec("EXECUTE " + name)
+		return err
+	}
+
+	for _, name := range []string{"a", "b", "c"} {
+		if err := prepare(name, 10000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Executing a makes b the least recently used statement, which is evicted
+	// to make room for d.
+	if err := execute("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := prepare("d", 10000); err != nil {
+		t.Fatal(err)
+	}
+	if err := execute("b"); !testutils.IsError(err, `prepared statement "b" does not exist`) {
+		t.Fatalf("expected b to have been evicted, got %v", err)
+	}
+	for _, name := range []string{"a", "c", "d"} {
+		if err := execute(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A statement larger than the limit on its own can't be prepared.
+	err := prepare("e", 100000)
+	if pqErr, ok := err.(*pq.Error); !ok || pqErr.Code != pgerror.CodeProgramLimitExceededError {
+		t.Fatalf("expected a program limit exceeded error, got %v", err)
+	}
+	if !testutils.IsError(err, `prepared statement "e" requires .*, more than the 68 KiB allowed`) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := execute("a"); err != nil {
+		t.Fatal(err)
+	}
+}
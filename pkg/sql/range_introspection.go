@@ -0,0 +1,125 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// This file implements the methods of the parser.EvalPlanner interface used
+// by the crdb_internal generators introspecting the ranges of the cluster,
+// e.g. crdb_internal.lease_holder(key).
+
+package sql
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/kv"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/server/serverpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+)
+
+// rangeStartKey returns the first key of the range that requests can address:
+// they can't cross the boundary between the local and the global keys.
+func rangeStartKey(desc roachpb.RangeDescriptor) roachpb.Key {
+	key := desc.StartKey.AsRawKey()
+	if bytes.Compare(key, keys.LocalMax) < 0 {
+		return keys.LocalMax
+	}
+	return key
+}
+
+// RangeDescriptors implements the parser.EvalPlanner interface. The
+// descriptors come from the range descriptor cache, so they may be stale.
+func (p *planner) RangeDescriptors(
+	ctx context.Context, span roachpb.Span,
+) ([]roachpb.RangeDescriptor, error) {
+	ds := p.ExecCfg().DistSender
+	if ds == nil {
+		return nil, errors.New("cannot look up ranges from this context")
+	}
+	var rspan roachpb.RSpan
+	var err error
+	if rspan.Key, err = keys.Addr(span.Key); err != nil {
+		return nil, err
+	}
+	if rspan.EndKey, err = keys.AddrUpperBound(span.EndKey); err != nil {
+		return nil, err
+	}
+
+	var descs []roachpb.RangeDescriptor
+	ri := kv.NewRangeIterator(ds)
+	for ri.Seek(ctx, rspan.Key, kv.Ascending); ; ri.Next(ctx) {
+		if !ri.Valid() {
+			return nil, ri.Error().GoError()
+		}
+		descs = append(descs, *ri.Desc())
+		if !ri.NeedAnother(rspan) {
+			return descs, nil
+		}
+	}
+}
+
+// RangeLeaseHolder implements the parser.EvalPlanner interface.
+func (p *planner) RangeLeaseHolder(
+	ctx context.Context, desc roachpb.RangeDescriptor,
+) (roachpb.ReplicaDescriptor, error) {
+	b := &client.Batch{}
+	b.AddRawRequest(&roachpb.LeaseInfoRequest{
+		Span: roachpb.Span{
+			Key: rangeStartKey(desc),
+		},
+	})
+	if err := p.ExecCfg().DB.Run(ctx, b); err != nil {
+		return roachpb.ReplicaDescriptor{}, errors.Wrapf(err, "error getting lease info of r%d", desc.RangeID)
+	}
+	resp := b.RawResponse().Responses[0].GetInner().(*roachpb.LeaseInfoResponse)
+	return resp.Lease.Replica, nil
+}
+
+// RangeStats implements the parser.EvalPlanner interface.
+func (p *planner) RangeStats(
+	ctx context.Context, desc roachpb.RangeDescriptor,
+) (enginepb.MVCCStats, error) {
+	if p.ExecCfg().StatusServer == nil {
+		return enginepb.MVCCStats{}, errors.New("cannot access range stats from this context")
+	}
+	leaseHolder, err := p.RangeLeaseHolder(ctx, desc)
+	if err != nil {
+		return enginepb.MVCCStats{}, err
+	}
+	resp, err := p.ExecCfg().StatusServer.Ranges(ctx, &serverpb.RangesRequest{
+		NodeId:   leaseHolder.NodeID.String(),
+		RangeIDs: []roachpb.RangeID{desc.RangeID},
+	})
+	if err != nil {
+		return enginepb.MVCCStats{}, err
+	}
+	for _, info := range resp.Ranges {
+		if info.SourceStoreID == leaseHolder.StoreID {
+			return info.State.Stats, nil
+		}
+	}
+	return enginepb.MVCCStats{}, errors.Errorf("r%d not found on its lease holder %s",
+		desc.RangeID, leaseHolder)
+}
+
+// CheckRangeConsistency implements the parser.EvalPlanner interface.
+func (p *planner) CheckRangeConsistency(ctx context.Context, desc roachpb.RangeDescriptor) error {
+	return p.ExecCfg().DB.CheckConsistency(
+		ctx, rangeStartKey(desc), desc.EndKey.AsRawKey(), false, /* withDiff */
+	)
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+)
+
+// scheduleOptionOverlapPolicy is the CREATE SCHEDULE option that sets the
+// schedule's overlap policy.
+const scheduleOptionOverlapPolicy = "overlap_policy"
+
+// CreateSchedule records a new schedule owned by the current user. The
+// statement runs with the privileges of its owner each time it fires.
+// Privileges: None.
+func (p *planner) CreateSchedule(n *parser.CreateSchedule) (planNode, error) {
+	sched := jobs.ScheduleRecord{
+		Name:          string(n.Name),
+		Owner:         p.session.User,
+		Cron:          n.Recurrence,
+		Statement:     parser.AsString(n.Statement),
+		OverlapPolicy: jobs.OverlapWait,
+	}
+	for _, opt := range n.Options {
+		switch opt.Key {
+		case scheduleOptionOverlapPolicy:
+			sched.OverlapPolicy = jobs.OverlapPolicy(opt.Value)
+		default:
+			return nil, errors.Errorf("unknown CREATE SCHEDULE option %q", opt.Key)
+		}
+	}
+
+	columns := sqlbase.ResultColumns{{Name: "schedule_id", Typ: parser.TypeInt}}
+	return &delayedNode{
+		name:    n.String(),
+		columns: columns,
+		constructor: func(ctx context.Context, p *planner) (planNode, error) {
+			ie := InternalExecutor{LeaseManager: p.LeaseMgr()}
+			id, err := jobs.CreateSchedule(ctx, ie, p.txn, sched)
+			if err != nil {
+				return nil, err
+			}
+			v := p.newContainerValuesNode(columns, 1)
+			if _, err := v.rows.AddRow(ctx, parser.Datums{parser.NewDInt(parser.DInt(id))}); err != nil {
+				v.rows.Close(ctx)
+				return nil, err
+			}
+			return v, nil
+		},
+	}, nil
+}
+
+// PauseSchedule stops a schedule from firing until it is resumed.
+// Privileges: None; users other than root can only pause their own schedules.
+func (p *planner) PauseSchedule(ctx context.Context, n *parser.PauseSchedule) (planNode, error) {
+	id, err := p.checkScheduleOwner(ctx, n.ID, "PAUSE SCHEDULE")
+	if err != nil {
+		return nil, err
+	}
+	if err := jobs.PauseSchedule(ctx, InternalExecutor{LeaseManager: p.LeaseMgr()}, p.txn, id); err != nil {
+		return nil, err
+	}
+	return &emptyNode{}, nil
+}
+
+// ResumeSchedule resumes a paused schedule.
+// Privileges: None; users other than root can only resume their own schedules.
+func (p *planner) ResumeSchedule(ctx context.Context, n *parser.ResumeSchedule) (planNode, error) {
+	id, err := p.checkScheduleOwner(ctx, n.ID, "RESUME SCHEDULE")
+	if err != nil {
+		return nil, err
+	}
+	if err := jobs.ResumeSchedule(ctx, InternalExecutor{LeaseManager: p.LeaseMgr()}, p.txn, id); err != nil {
+		return nil, err
+	}
+	return &emptyNode{}, nil
+}
+
+// checkScheduleOwner evaluates the ID of the schedule targeted by op and
+// verifies that the current user is allowed to modify it.
+func (p *planner) checkScheduleOwner(ctx context.Context, e parser.Expr, op string) (int64, error) {
+	typedE, err := parser.TypeCheckAndRequire(e, &p.semaCtx, parser.TypeInt, op)
+	if err != nil {
+		return 0, err
+	}
+	d, err := typedE.Eval(&p.evalCtx)
+	if err != nil {
+		return 0, err
+	}
+	if d == parser.DNull {
+		return 0, errors.Errorf("%s requires a schedule ID", op)
+	}
+	id := int64(*d.(*parser.DInt))
+
+	ie := InternalExecutor{LeaseManager: p.LeaseMgr()}
+	row, err := ie.QueryRowInTransaction(ctx, "schedule-owner", p.txn,
+		`SELECT owner FROM system.scheduled_jobs WHERE id = $1`, id)
+	if err != nil {
+		return 0, err
+	}
+	if row == nil {
+		return 0, errors.Errorf("schedule %d does not exist", id)
+	}
+	if owner := string(*row[0].(*parser.DString)); owner != p.session.User &&
+		p.session.User != security.RootUser {
+		return 0, errors.Errorf("user %s does not own schedule %d", p.session.User, id)
+	}
+	return id, nil
+}
@@ -60,21 +60,66 @@ type SchemaChanger struct {
 	leaseMgr   *LeaseManager
 	// The SchemaChangeManager can attempt to execute this schema
 	// changer after this time.
-	execAfter      time.Time
-	testingKnobs   *SchemaChangerTestingKnobs
-	distSQLPlanner *distSQLPlanner
-	jobLogger      *jobs.JobLogger
+	execAfter        time.Time
+	testingKnobs     *SchemaChangerTestingKnobs
+	jobsTestingKnobs *jobs.TestingKnobs
+	distSQLPlanner   *distSQLPlanner
+	jobLogger        *jobs.JobLogger
 }
 
+// truncateAndDropTable releases the name of the dropped table and, once its
+// GC deadline has passed, deletes its data and descriptor. The data of the
+// indexes dropped before the table whose own GC deadline passes first is
+// deleted beforehand. It returns false if the deadline of the table hasn't
+// passed yet, in which case sc.execAfter is set to the earliest deadline
+// pending so that the SchemaChangeManager comes back to the table.
 func (sc *SchemaChanger) truncateAndDropTable(
 	ctx context.Context,
 	lease *sqlbase.TableDescriptor_SchemaChangeLease,
 	tableDesc *sqlbase.TableDescriptor,
-) error {
+) (bool, error) {
 	if err := sc.ExtendLease(ctx, lease); err != nil {
+		return false, err
+	}
+	if err := releaseTableName(ctx, tableDesc, &sc.db, *sc.testingKnobs); err != nil {
+		return false, err
+	}
+	if err := sc.gcIndexes(ctx, lease); err != nil {
+		return false, err
+	}
+	deadline, err := gcDeadline(ctx, &sc.db, tableDesc, 0, tableDesc.DropTime)
+	if err != nil {
+		return false, err
+	}
+	if now := timeutil.Now(); now.Before(deadline) {
+		if !sc.execAfter.After(now) || deadline.Before(sc.execAfter) {
+			sc.execAfter = deadline
+		}
+		return false, nil
+	}
+
+	// The indexes still waiting for their GC deadline go away with the table.
+	if err := sc.db.Txn(ctx, func(ctx context.Context, txn *client.Txn) error {
+		var err error
+		tableDesc, err = sqlbase.GetTableDescFromID(ctx, txn, sc.tableID)
 		return err
+	}); err != nil {
+		return false, err
+	}
+	var indexJobs []gcJob
+	for _, m := range tableDesc.GCMutations {
+		indexJobs = append(indexJobs, sc.gcJob(ctx, m.JobID))
 	}
-	return truncateAndDropTable(ctx, tableDesc, &sc.db, *sc.testingKnobs)
+	job := sc.gcJob(ctx, tableDesc.DropJobID)
+	if err := truncateAndDropTable(ctx, tableDesc, &sc.db, job, indexJobs, func(row int64) error {
+		if err := sc.ExtendLease(ctx, lease); err != nil {
+			return err
+		}
+		return gcPause(ctx, row)
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // NewSchemaChangerForTesting only for tests.
@@ -211,7 +256,8 @@ func (sc *SchemaChanger) ExtendLease(
 }
 
 // maybe Add/Drop/Rename a table depending on the state of a table descriptor.
-// This method returns true if the table is deleted.
+// This method returns true if the table is deleted. A dropped table is only
+// deleted once its GC deadline has passed.
 func (sc *SchemaChanger) maybeAddDropRename(
 	ctx context.Context,
 	lease *sqlbase.TableDescriptor_SchemaChangeLease,
@@ -229,10 +275,7 @@ func (sc *SchemaChanger) maybeAddDropRename(
 		}
 
 		// Truncate the table and delete the descriptor.
-		if err := sc.truncateAndDropTable(ctx, lease, table); err != nil {
-			return false, err
-		}
-		return true, nil
+		return sc.truncateAndDropTable(ctx, lease, table)
 	}
 
 	if table.Adding() {
@@ -334,6 +377,9 @@ func (sc *SchemaChanger) exec(ctx context.Context, evalCtx parser.EvalContext) e
 	} else if drop {
 		needRelease = false
 		return nil
+	} else if tableDesc.Dropped() {
+		// The table is waiting for its GC deadline.
+		return nil
 	}
 
 	// Wait for the schema change to propagate to all nodes after this function
@@ -346,8 +392,8 @@ func (sc *SchemaChanger) exec(ctx context.Context, evalCtx parser.EvalContext) e
 	}()
 
 	if sc.mutationID == sqlbase.InvalidMutationID {
-		// Nothing more to do.
-		return nil
+		// Nothing more to do but to delete the data of the dropped indexes.
+		return sc.gcIndexes(ctx, &lease)
 	}
 
 	// Find our job.
@@ -358,7 +404,7 @@ func (sc *SchemaChanger) exec(ctx context.Context, evalCtx parser.EvalContext) e
 			if err != nil {
 				return err
 			}
-			sc.jobLogger = jl
+			sc.jobLogger = jl.WithTestingKnobs(sc.jobsTestingKnobs)
 			foundJobID = true
 			break
 		}
@@ -512,10 +558,13 @@ func (sc *SchemaChanger) waitToUpdateLeases(ctx context.Context, tableID sqlbase
 
 // done finalizes the mutations (adds new cols/indexes to the table).
 // It ensures that all nodes are on the current (pre-update) version of the
-// schema.
+// schema. The dropped indexes are queued for the deletion of their data.
 // Returns the updated of the descriptor.
 func (sc *SchemaChanger) done(ctx context.Context) (*sqlbase.Descriptor, error) {
-	return sc.leaseMgr.Publish(ctx, sc.tableID, func(desc *sqlbase.TableDescriptor) error {
+	return sc.leaseMgr.publishWithTxn(ctx, sc.tableID, func(
+		txn *client.Txn, desc *sqlbase.TableDescriptor,
+	) error {
+		dropTime := timeutil.Now().UnixNano()
 		i := 0
 		for _, mutation := range desc.Mutations {
 			if mutation.MutationID != sc.mutationID {
@@ -524,6 +573,18 @@ func (sc *SchemaChanger) done(ctx context.Context) (*sqlbase.Descriptor, error)
 				break
 			}
 			desc.MakeMutationComplete(mutation)
+			if idx := mutation.GetIndex(); idx != nil &&
+				mutation.Direction == sqlbase.DescriptorMutation_DROP {
+				jobID, err := sc.createIndexGCJob(ctx, txn)
+				if err != nil {
+					return err
+				}
+				desc.GCMutations = append(desc.GCMutations, sqlbase.TableDescriptor_GCDescriptorMutation{
+					Index:    *idx,
+					DropTime: dropTime,
+					JobID:    jobID,
+				})
+			}
 			i++
 		}
 		if i == 0 {
@@ -604,8 +665,12 @@ func (sc *SchemaChanger) runStateMachineAndBackfill(
 	}
 
 	// Mark the mutations as completed.
-	_, err := sc.done(ctx)
-	return err
+	if _, err := sc.done(ctx); err != nil {
+		return err
+	}
+
+	// Delete the data of the dropped indexes.
+	return sc.gcIndexes(ctx, lease)
 }
 
 // reverseMutations reverses the direction of all the mutations with the
@@ -646,6 +711,7 @@ func (sc *SchemaChanger) reverseMutations(ctx context.Context, causingError erro
 				job := sc.jobLogger.Job
 				job.Description = "ROLL BACK " + job.Description
 				jobLogger := jobs.NewJobLogger(&sc.db, InternalExecutor{LeaseManager: sc.leaseMgr}, job)
+				jobLogger.WithTestingKnobs(sc.jobsTestingKnobs)
 				if err := jobLogger.Created(ctx); err != nil {
 					return err
 				}
@@ -806,10 +872,11 @@ func (*SchemaChangerTestingKnobs) ModuleTestingKnobs() {}
 // processing the schema change this manager acts as a backup
 // execution mechanism.
 type SchemaChangeManager struct {
-	db           client.DB
-	gossip       *gossip.Gossip
-	leaseMgr     *LeaseManager
-	testingKnobs *SchemaChangerTestingKnobs
+	db               client.DB
+	gossip           *gossip.Gossip
+	leaseMgr         *LeaseManager
+	testingKnobs     *SchemaChangerTestingKnobs
+	jobsTestingKnobs *jobs.TestingKnobs
 	// Create a schema changer for every outstanding schema change seen.
 	schemaChangers map[sqlbase.ID]SchemaChanger
 	distSQLPlanner *distSQLPlanner
@@ -819,6 +886,7 @@ type SchemaChangeManager struct {
 // NewSchemaChangeManager returns a new SchemaChangeManager.
 func NewSchemaChangeManager(
 	testingKnobs *SchemaChangerTestingKnobs,
+	jobsTestingKnobs *jobs.TestingKnobs,
 	db client.DB,
 	nodeDesc roachpb.NodeDescriptor,
 	rpcContext *rpc.Context,
@@ -829,11 +897,12 @@ func NewSchemaChangeManager(
 	clock *hlc.Clock,
 ) *SchemaChangeManager {
 	return &SchemaChangeManager{
-		db:             db,
-		gossip:         gossip,
-		leaseMgr:       leaseMgr,
-		testingKnobs:   testingKnobs,
-		schemaChangers: make(map[sqlbase.ID]SchemaChanger),
+		db:               db,
+		gossip:           gossip,
+		leaseMgr:         leaseMgr,
+		testingKnobs:     testingKnobs,
+		jobsTestingKnobs: jobsTestingKnobs,
+		schemaChangers:   make(map[sqlbase.ID]SchemaChanger),
 		// TODO(radu): investigate using the same distSQLPlanner from the executor.
 		distSQLPlanner: newDistSQLPlanner(
 			nodeDesc,
@@ -887,11 +956,12 @@ func (s *SchemaChangeManager) Start(stopper *stop.Stopper) {
 					log.Info(ctx, "received a new config")
 				}
 				schemaChanger := SchemaChanger{
-					nodeID:         s.leaseMgr.nodeID.Get(),
-					db:             s.db,
-					leaseMgr:       s.leaseMgr,
-					testingKnobs:   s.testingKnobs,
-					distSQLPlanner: s.distSQLPlanner,
+					nodeID:           s.leaseMgr.nodeID.Get(),
+					db:               s.db,
+					leaseMgr:         s.leaseMgr,
+					testingKnobs:     s.testingKnobs,
+					jobsTestingKnobs: s.jobsTestingKnobs,
+					distSQLPlanner:   s.distSQLPlanner,
 				}
 				// Keep track of existing schema changers.
 				oldSchemaChangers := make(map[sqlbase.ID]struct{}, len(s.schemaChangers))
@@ -924,9 +994,10 @@ func (s *SchemaChangeManager) Start(stopper *stop.Stopper) {
 						// check for the presence of mutations?
 						// A schema change execution might fail soon after
 						// unsetting UpVersion, and we still want to process
-						// outstanding mutations. Similar with a table marked for deletion.
+						// outstanding mutations. Similar with a table marked for deletion,
+						// and with the dropped indexes whose data is yet to be deleted.
 						if table.UpVersion || table.Dropped() || table.Adding() ||
-							table.Renamed() || len(table.Mutations) > 0 {
+							table.Renamed() || len(table.Mutations) > 0 || len(table.GCMutations) > 0 {
 							if log.V(2) {
 								log.Infof(ctx, "%s: queue up pending schema change; table: %d, version: %d",
 									kv.Key, table.ID, table.Version)
@@ -984,6 +1055,10 @@ func (s *SchemaChangeManager) Start(stopper *stop.Stopper) {
 								// deletion which would remove this schemaChanger.
 								delete(s.schemaChangers, tableID)
 							}
+						} else if sc.execAfter.After(timeutil.Now()) {
+							// The schema changer is waiting for the GC deadline of
+							// dropped data. Come back to it then.
+							s.schemaChangers[tableID] = sc
 						} else {
 							// We successfully executed the schema change. Delete it.
 							delete(s.schemaChangers, tableID)
@@ -20,6 +20,7 @@ package sql
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -42,6 +43,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util"
 	"github.com/cockroachdb/cockroach/pkg/util/duration"
 	"github.com/cockroachdb/cockroach/pkg/util/envutil"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/retry"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
@@ -136,7 +138,7 @@ func DistSQLExecModeFromString(val string) DistSQLExecMode {
 // DistSQLClusterExecMode controls the cluster default for when DistSQL is used.
 var DistSQLClusterExecMode = settings.RegisterEnumSetting(
 	"sql.defaults.distsql",
-	"Default distributed SQL execution mode",
+	"default distributed SQL execution mode",
 	"Auto",
 	map[int64]string{
 		int64(DistSQLOff):  "Off",
@@ -159,6 +161,9 @@ const (
 // queryMeta stores metadata about a query. Stored as reference in
 // session.mu.ActiveQueries and planner.queryMeta.
 type queryMeta struct {
+	// The ID of the query, unique in the cluster (see makeQueryID).
+	id string
+
 	// The timestamp when this query began execution.
 	start time.Time
 
@@ -174,11 +179,42 @@ type queryMeta struct {
 
 	// Current phase of execution of query.
 	phase queryPhase
+
+	// Set when the query is canceled by CANCEL QUERY, so that the error it
+	// fails with can be reported as a cancelation.
+	canceled bool
+
+	// Cancels the context of the transaction the query runs in.
+	ctxCancel context.CancelFunc
 }
 
 // queryHandle is a type for uniquely identifying queries in a session.
 type queryHandle *queryMeta
 
+// errQueryCanceled is the error returned for a query canceled by CANCEL QUERY.
+var errQueryCanceled = errors.New("query execution canceled")
+
+// makeQueryID returns the ID of a query started at the given timestamp on the
+// given node. The timestamps of the HLC clock of a node are strictly
+// increasing, so the IDs are unique in the cluster; the node ID can be
+// extracted from them with nodeIDFromQueryID.
+func makeQueryID(ts hlc.Timestamp, nodeID roachpb.NodeID) string {
+	return fmt.Sprintf("%016x%08x%08x", ts.WallTime, uint32(ts.Logical), uint32(nodeID))
+}
+
+// nodeIDFromQueryID returns the ID of the node the query with the given ID was
+// started on.
+func nodeIDFromQueryID(queryID string) (roachpb.NodeID, error) {
+	if len(queryID) != 32 {
+		return 0, errors.Errorf("invalid query ID %q", queryID)
+	}
+	id, err := strconv.ParseUint(queryID[24:], 16, 32)
+	if err != nil {
+		return 0, errors.Errorf("invalid query ID %q", queryID)
+	}
+	return roachpb.NodeID(id), nil
+}
+
 // Session contains the state of a SQL client connection.
 // Create instances using NewSession().
 type Session struct {
@@ -187,19 +223,26 @@ type Session struct {
 	//
 
 	// Database indicates the "current" database for the purpose of
-	// resolving names. See searchAndQualifyDatabase() for details.
+	// resolving names. See tableResolver for details.
 	Database string
 	// DefaultIsolationLevel indicates the default isolation level of
 	// newly created transactions.
 	DefaultIsolationLevel enginepb.IsolationType
+	// DefaultReadOnly indicates whether newly created transactions are
+	// read-only.
+	DefaultReadOnly bool
+	// DiscardRows indicates whether the rows of queries are discarded, in
+	// which case only their number and the execution time are returned.
+	// Used to benchmark queries without the cost of sending their results.
+	DiscardRows bool
 	// DistSQLMode indicates whether to run queries using the distributed
 	// execution engine.
 	DistSQLMode DistSQLExecMode
 	// Location indicates the current time zone.
 	Location *time.Location
 	// SearchPath is a list of databases that will be searched for a table name
-	// before the database. Currently, this is used only for SELECTs.
-	// Names in the search path must have been normalized already.
+	// in addition to the current database. See tableResolver for the exact
+	// search order. Names in the search path must have been normalized already.
 	SearchPath parser.SearchPath
 	// User is the name of the user logged into the session.
 	User string
@@ -285,6 +328,17 @@ type Session struct {
 
 	Tracing SessionTracing
 
+	// traceContext is the value of the trace_context session variable: a span
+	// context encoded by tracing.EncodeSpanContext, usually of a span of the
+	// client application. The spans of the session's SQL transactions continue
+	// the trace of traceParent, its decoded form.
+	traceContext string
+	traceParent  opentracing.SpanContext
+	// batchTraceParent is the span context passed in a comment at the start of
+	// the current batch of statements (see tracing.SpanContextFromSQLComment).
+	// It takes precedence over traceParent.
+	batchTraceParent opentracing.SpanContext
+
 	leases LeaseCollection
 
 	// If set, contains the in progress COPY FROM columns.
@@ -382,6 +436,21 @@ func (r *SessionRegistry) deregister(s *Session) {
 	r.Unlock()
 }
 
+// CancelQuery cancels the query with the given ID, if it runs in a session of
+// the registry. Unless username is the root user, only the queries of that
+// user can be canceled. Returns false if the query wasn't found.
+func (r *SessionRegistry) CancelQuery(queryID string, username string) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	for s := range r.store {
+		if canceled, err := s.cancelQuery(queryID, username); canceled || err != nil {
+			return canceled, err
+		}
+	}
+	return false, nil
+}
+
 // SerializeAll returns a slice of all sessions in the registry, converted to serverpb.Sessions.
 func (r *SessionRegistry) SerializeAll() []serverpb.Session {
 	r.Lock()
@@ -441,7 +510,7 @@ func NewSession(
 	s.ClientAddr = remoteStr
 
 	if traceSessionEventLogEnabled.Get() {
-		s.eventLog = trace.NewEventLog(fmt.Sprintf("sql [%s]", args.User), remoteStr)
+		s.eventLog = trace.NewEventLog(tracing.SubsystemSQL, fmt.Sprintf("%s [%s]", remoteStr, args.User))
 	}
 	s.context, s.cancel = context.WithCancel(ctx)
 
@@ -623,6 +692,16 @@ func (s *Session) resetForBatch(e *Executor) {
 	// that we created in previous batches of the same transaction.
 	s.leases.databaseCache = e.getDatabaseCache()
 	s.TxnState.schemaChangers.curGroupNum++
+	s.batchTraceParent = nil
+}
+
+// traceParentCtx returns the span context of the client application that the
+// spans of new SQL transactions should continue, if any.
+func (s *Session) traceParentCtx() opentracing.SpanContext {
+	if s.batchTraceParent != nil {
+		return s.batchTraceParent
+	}
+	return s.traceParent
 }
 
 // releaseLeases releases all leases currently held by the Session.
@@ -655,9 +734,11 @@ func (s *Session) setTestingVerifyMetadata(fn func(config.SystemConfig) error) {
 func (s *Session) addActiveQuery(stmt Statement) queryHandle {
 	s.mu.Lock()
 	query := &queryMeta{
-		start: timeutil.Now(),
-		stmt:  stmt.AST,
-		phase: preparing,
+		id:        makeQueryID(s.execCfg.Clock.Now(), s.execCfg.NodeID.Get()),
+		start:     timeutil.Now(),
+		stmt:      stmt.AST,
+		phase:     preparing,
+		ctxCancel: s.TxnState.cancel,
 	}
 	s.mu.ActiveQueries[query] = struct{}{}
 	s.mu.Unlock()
@@ -682,6 +763,34 @@ func (s *Session) setQueryExecutionMode(query queryHandle, isDistributed bool) {
 	s.mu.Unlock()
 }
 
+// cancelQuery cancels the query with the given ID if it runs in this session;
+// see SessionRegistry.CancelQuery. Canceling a query cancels the context of its
+// transaction, which aborts the transaction.
+func (s *Session) cancelQuery(queryID string, username string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for query := range s.mu.ActiveQueries {
+		if query.id != queryID {
+			continue
+		}
+		if username != security.RootUser && username != s.User {
+			return false, errors.Errorf("user %s cannot cancel the queries of user %s", username, s.User)
+		}
+		query.canceled = true
+		query.ctxCancel()
+		return true, nil
+	}
+	return false, nil
+}
+
+// queryCanceled returns whether the given query was canceled by cancelQuery.
+func (s *Session) queryCanceled(query queryHandle) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return (*queryMeta)(query).canceled
+}
+
 // serialize serializes a Session into a serverpb.Session
 // that can be served over RPC.
 func (s *Session) serialize() serverpb.Session {
@@ -704,6 +813,7 @@ func (s *Session) serialize() serverpb.Session {
 			sql = sql[:997] + "..."
 		}
 		activeQueries = append(activeQueries, serverpb.ActiveQuery{
+			ID:            query.id,
 			Start:         query.start.UTC(),
 			Sql:           sql,
 			IsDistributed: query.isDistributed,
@@ -770,11 +880,23 @@ type txnState struct {
 
 	// Ctx is the context for everything running in this SQL txn.
 	Ctx context.Context
+	// cancel cancels Ctx; it is called when the txn is finished, or to cancel
+	// the query running in the txn.
+	cancel context.CancelFunc
 
 	// implicitTxn if set if the transaction was automatically created for a
 	// single statement.
 	implicitTxn bool
 
+	// historicalTimestamp is set if the transaction was pinned to a historical
+	// timestamp through BEGIN or SET TRANSACTION ... AS OF SYSTEM TIME. Such
+	// transactions are read-only and don't use cached descriptors.
+	historicalTimestamp *hlc.Timestamp
+
+	// readOnly is set if the transaction was declared read-only, through BEGIN
+	// or SET TRANSACTION ... READ ONLY, or default_transaction_read_only.
+	readOnly bool
+
 	// If set, the user declared the intention to retry the txn in case of retriable
 	// errors. The txn will enter a RestartWait state in case of such errors.
 	retryIntent bool
@@ -818,6 +940,8 @@ func (ts *txnState) resetForNewSQLTxn(e *Executor, s *Session, implicitTxn bool)
 	ts.retryIntent = false
 	ts.autoRetry = false
 	ts.commitSeen = false
+	ts.historicalTimestamp = nil
+	ts.readOnly = s.DefaultReadOnly
 
 	ts.implicitTxn = implicitTxn
 
@@ -841,6 +965,9 @@ func (ts *txnState) resetForNewSQLTxn(e *Executor, s *Session, implicitTxn bool)
 		// Create a child span for this SQL txn.
 		sp = parentSp.Tracer().StartSpan(
 			opName, opentracing.ChildOf(parentSp.Context()), tracing.Recordable)
+	} else if parentCtx := s.traceParentCtx(); parentCtx != nil {
+		// Continue the trace of the client application.
+		sp = tracer.StartSpan(opName, opentracing.ChildOf(parentCtx), tracing.Recordable)
 	} else {
 		// Create a root span for this SQL txn.
 		sp = tracer.StartSpan(opName, tracing.Recordable)
@@ -870,7 +997,7 @@ func (ts *txnState) resetForNewSQLTxn(e *Executor, s *Session, implicitTxn bool)
 	}
 
 	ts.sp = sp
-	ts.Ctx = ctx
+	ts.Ctx, ts.cancel = context.WithCancel(ctx)
 	ts.State = Open
 	s.Tracing.onNewSQLTxn(ts.sp)
 
@@ -924,6 +1051,7 @@ func (ts *txnState) finishSQLTxn(s *Session) {
 	if err := s.Tracing.onFinishSQLTxn(ts.sp); err != nil {
 		log.Errorf(s.context, "error finishing trace: %s", err)
 	}
+	ts.cancel()
 	// TODO(andrei): we should find a cheap way to get a trace's duration without
 	// calling the expensive GetRecording().
 	durThreshold := traceTxnThreshold.Get()
@@ -1023,6 +1151,7 @@ func (scc *schemaChangerCollection) execSchemaChanges(
 		sc := &scEntry.sc
 		sc.db = *e.cfg.DB
 		sc.testingKnobs = e.cfg.SchemaChangerTestingKnobs
+		sc.jobsTestingKnobs = e.cfg.JobsTestingKnobs
 		sc.distSQLPlanner = e.distSQLPlanner
 		for r := retry.Start(base.DefaultRetryOptions()); r.Next(); {
 			evalCtx := createSchemaChangeEvalCtx(e.cfg.Clock.Now())
@@ -278,7 +278,7 @@ func (p *planner) Show(n *parser.Show) (planNode, error) {
 //   Notes: postgres does not have a SHOW COLUMNS statement.
 //          mysql only returns columns you have privileges on.
 func (p *planner) ShowColumns(ctx context.Context, n *parser.ShowColumns) (planNode, error) {
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.QualifyWithDatabase(ctx, &n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -362,7 +362,7 @@ func (p *planner) showCreateInterleave(
 func (p *planner) ShowCreateTable(
 	ctx context.Context, n *parser.ShowCreateTable,
 ) (planNode, error) {
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.QualifyWithDatabase(ctx, &n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -496,7 +496,7 @@ func quoteNames(names ...string) string {
 // ShowCreateView returns a CREATE VIEW statement for the specified view.
 // Privileges: Any privilege on view.
 func (p *planner) ShowCreateView(ctx context.Context, n *parser.ShowCreateView) (planNode, error) {
-	tn, err := n.View.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.QualifyWithDatabase(ctx, &n.View)
 	if err != nil {
 		return nil, err
 	}
@@ -606,13 +606,12 @@ func (p *planner) ShowDatabases(ctx context.Context, n *parser.ShowDatabases) (p
 }
 
 // ShowGrants returns grant details for the specified objects and users.
-// TODO(marc): implement no targets (meaning full scan).
 // Privileges: None.
 //   Notes: postgres does not have a SHOW GRANTS statement.
 //          mysql only returns the user's privileges.
 func (p *planner) ShowGrants(ctx context.Context, n *parser.ShowGrants) (planNode, error) {
 	if n.Targets == nil {
-		return nil, errors.Errorf("TODO(marc): implement SHOW GRANT with no targets")
+		return p.showAllGrants(n)
 	}
 
 	objectType := "Database"
@@ -693,7 +692,7 @@ func (p *planner) ShowGrants(ctx context.Context, n *parser.ShowGrants) (planNod
 						v.rows.Close(ctx)
 						return nil, err
 					}
-					tables, err := expandTableGlob(ctx, p.txn, p.getVirtualTabler(), p.session.Database, tableGlob)
+					tables, err := p.expandTableGlob(ctx, tableGlob)
 					if err != nil {
 						v.rows.Close(ctx)
 						return nil, err
@@ -746,12 +745,102 @@ func (p *planner) ShowGrants(ctx context.Context, n *parser.ShowGrants) (planNod
 	}, nil
 }
 
+var (
+	grantTypeDatabase = parser.NewDString("database")
+	grantTypeTable    = parser.NewDString("table")
+	grantTypeView     = parser.NewDString("view")
+)
+
+// showAllGrants implements SHOW GRANTS without targets: it returns the grants
+// on all the databases and tables visible to the user, optionally restricted
+// to the given grantees. Virtual schemas are skipped, since their privileges
+// are fixed.
+func (p *planner) showAllGrants(n *parser.ShowGrants) (planNode, error) {
+	columns := sqlbase.ResultColumns{
+		{Name: "Database", Typ: parser.TypeString},
+		{Name: "Table", Typ: parser.TypeString},
+		{Name: "Type", Typ: parser.TypeString},
+		{Name: "User", Typ: parser.TypeString},
+		{Name: "Privileges", Typ: parser.TypeString},
+	}
+	grantees := n.Grantees.ToStrings()
+
+	return &delayedNode{
+		name:    "SHOW GRANTS",
+		columns: columns,
+		constructor: func(ctx context.Context, p *planner) (planNode, error) {
+			v := p.newContainerValuesNode(columns, 0)
+
+			addGrants := func(
+				db, table, objectType parser.Datum, privs *sqlbase.PrivilegeDescriptor,
+			) error {
+				for _, u := range privs.ShowUsers(grantees) {
+					for _, privilege := range u.Privileges {
+						if _, err := v.rows.AddRow(ctx, parser.Datums{
+							db,
+							table,
+							objectType,
+							parser.NewDString(u.User),
+							parser.NewDString(privilege),
+						}); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			}
+
+			if err := forEachDatabaseDesc(ctx, p, func(db *sqlbase.DatabaseDescriptor) error {
+				if isVirtualDescriptor(db) {
+					return nil
+				}
+				return addGrants(parser.NewDString(db.Name), parser.DNull, grantTypeDatabase, db.Privileges)
+			}); err != nil {
+				v.rows.Close(ctx)
+				return nil, err
+			}
+
+			if err := forEachTableDesc(ctx, p,
+				func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) error {
+					if table.IsVirtualTable() {
+						return nil
+					}
+					objectType := grantTypeTable
+					if table.IsView() {
+						objectType = grantTypeView
+					}
+					return addGrants(
+						parser.NewDString(db.Name), parser.NewDString(table.Name), objectType, table.Privileges,
+					)
+				},
+			); err != nil {
+				v.rows.Close(ctx)
+				return nil, err
+			}
+
+			// Sort the result by database, table, user and privileges. The grants
+			// on a database come before those on its tables.
+			return &sortNode{
+				p:    p,
+				plan: v,
+				ordering: sqlbase.ColumnOrdering{
+					{ColIdx: 0, Direction: encoding.Ascending},
+					{ColIdx: 1, Direction: encoding.Ascending},
+					{ColIdx: 3, Direction: encoding.Ascending},
+					{ColIdx: 4, Direction: encoding.Ascending},
+				},
+				columns: v.columns,
+			}, nil
+		},
+	}, nil
+}
+
 // ShowIndex returns all the indexes for a table.
 // Privileges: Any privilege on table.
 //   Notes: postgres does not have a SHOW INDEXES statement.
 //          mysql requires some privilege for any column.
 func (p *planner) ShowIndex(ctx context.Context, n *parser.ShowIndex) (planNode, error) {
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.QualifyWithDatabase(ctx, &n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -810,7 +899,7 @@ func (p *planner) ShowIndex(ctx context.Context, n *parser.ShowIndex) (planNode,
 func (p *planner) ShowConstraints(
 	ctx context.Context, n *parser.ShowConstraints,
 ) (planNode, error) {
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.QualifyWithDatabase(ctx, &n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -883,6 +972,7 @@ func (p *planner) ShowConstraints(
 
 func (p *planner) ShowQueries(ctx context.Context, n *parser.ShowQueries) (planNode, error) {
 	columns := sqlbase.ResultColumns{
+		{Name: "query_id", Typ: parser.TypeString},
 		{Name: "node_id", Typ: parser.TypeInt},
 		{Name: "username", Typ: parser.TypeString},
 		{Name: "start", Typ: parser.TypeTimestamp},
@@ -923,6 +1013,7 @@ func (p *planner) ShowQueries(ctx context.Context, n *parser.ShowQueries) (planN
 						}
 					}
 					row := parser.Datums{
+						parser.NewDString(query.ID),
 						parser.NewDInt(parser.DInt(session.NodeID)),
 						parser.NewDString(session.Username),
 						parser.MakeDTimestamp(query.Start, time.Microsecond),
@@ -943,6 +1034,7 @@ func (p *planner) ShowQueries(ctx context.Context, n *parser.ShowQueries) (planN
 				if rpcErr.NodeID != 0 {
 					// Add a row with this node ID, and nulls for all other columns
 					_, err := v.rows.AddRow(ctx, parser.Datums{
+						parser.DNull,
 						parser.NewDInt(parser.DInt(rpcErr.NodeID)),
 						parser.DNull,
 						parser.DNull,
@@ -1099,6 +1191,40 @@ func (p *planner) ShowTables(ctx context.Context, n *parser.ShowTables) (planNod
 	}, nil
 }
 
+// ShowSyntax implements the plan for SHOW SYNTAX. It parses the given
+// statement and returns its tag and normalized SQL, or the parse error,
+// without executing anything.
+// Privileges: None.
+func (p *planner) ShowSyntax(ctx context.Context, n *parser.ShowSyntax) (planNode, error) {
+	columns := sqlbase.ResultColumns{
+		{Name: "field", Typ: parser.TypeString},
+		{Name: "text", Typ: parser.TypeString},
+	}
+	return &delayedNode{
+		name:    "SHOW SYNTAX " + n.Statement,
+		columns: columns,
+		constructor: func(ctx context.Context, p *planner) (planNode, error) {
+			v := p.newContainerValuesNode(columns, 0)
+
+			var err error
+			parser.RunShowSyntax(n.Statement, func(field, msg string) {
+				if err != nil {
+					return
+				}
+				_, err = v.rows.AddRow(ctx, parser.Datums{
+					parser.NewDString(field),
+					parser.NewDString(msg),
+				})
+			})
+			if err != nil {
+				v.Close(ctx)
+				return nil, err
+			}
+			return v, nil
+		},
+	}, nil
+}
+
 // ShowTransactionStatus implements the plan for SHOW TRANSACTION STATUS.
 // This statement is usually handled as a special case in Executor,
 // but for FROM [SHOW TRANSACTION STATUS] we will arrive here too.
@@ -57,7 +57,7 @@ func (p *planner) ShowFingerprints(
 		}
 	}
 
-	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	tn, err := p.normalizeTableName(ctx, n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,148 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// slowQueryLogThreshold causes statements that take longer than the given
+// duration (from the start of parsing to the end of execution) to be logged,
+// together with their fingerprint (their key in the statement statistics),
+// their placeholder values, a summary of their plan and, if the transaction
+// is being traced (see sql.trace.txn.enable_threshold), the end of the trace.
+// The entries are tagged with "slow-query" so they can be found easily in the
+// node's logs.
+var slowQueryLogThreshold = settings.RegisterDurationSetting(
+	"sql.log.slow_query.latency_threshold",
+	"when set to non-zero, log statements whose service latency exceeds the threshold",
+	0,
+)
+
+// slowQueryLogTraceLines limits the size of the trace excerpt included in slow
+// query log entries.
+var slowQueryLogTraceLines = settings.RegisterIntSetting(
+	"sql.log.slow_query.trace_lines",
+	"maximum number of trace lines included in slow query log entries (0 to omit traces)",
+	20,
+)
+
+// maybeLogSlowQuery logs the statement if its service latency exceeds
+// sql.log.slow_query.latency_threshold. The plan may be nil if it's not
+// available.
+func maybeLogSlowQuery(planner *planner, stmt Statement, plan planNode, err error) {
+	threshold := slowQueryLogThreshold.Get()
+	if threshold <= 0 {
+		return
+	}
+	phaseTimes := &planner.phaseTimes
+	svcLat := phaseTimes[plannerEndExecStmt].Sub(phaseTimes[sessionStartParse])
+	if svcLat < threshold {
+		return
+	}
+
+	ctx := planner.session.Ctx()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s: %s", svcLat, stmt)
+	if stmt.AST != nil {
+		fmt.Fprintf(&buf, "\nfingerprint: %s", parser.Fingerprint(stmt.AST))
+	}
+	if err != nil {
+		fmt.Fprintf(&buf, "\nerror: %s", err)
+	}
+	if p := formatPlaceholders(planner.semaCtx.Placeholders.Values); p != "" {
+		fmt.Fprintf(&buf, "\nplaceholders: %s", p)
+	}
+	if plan != nil {
+		fmt.Fprintf(&buf, "\nplan:\n%s", planSummary(ctx, plan))
+	}
+	if maxLines := int(slowQueryLogTraceLines.Get()); maxLines > 0 {
+		if sp := opentracing.SpanFromContext(ctx); sp != nil {
+			if rec := tracing.GetRecording(sp); rec != nil {
+				fmt.Fprintf(&buf, "\ntrace excerpt:\n%s",
+					traceExcerpt(tracing.FormatRecordedSpans(rec), maxLines))
+			}
+		}
+	}
+	log.Info(log.WithLogTag(ctx, "slow-query", nil), buf.String())
+}
+
+// formatPlaceholders returns the placeholder values in the form
+// "$1 = 'a', $2 = 3", ordered by placeholder index.
+func formatPlaceholders(args parser.QueryArguments) string {
+	if len(args) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	// Placeholder names are numbers; order them numerically.
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) < len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	var buf bytes.Buffer
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "$%s = %s", name, parser.AsString(args[name]))
+	}
+	return buf.String()
+}
+
+// planSummary returns an indented representation of the plan's nodes and
+// their attributes, without expressions.
+func planSummary(ctx context.Context, plan planNode) string {
+	var buf bytes.Buffer
+	e := explainer{
+		fmtFlags: parser.FmtSimple,
+		makeRow: func(level int, name, field, description string, _ planNode) {
+			// Attributes are reported with an empty node name.
+			if name != "" {
+				fmt.Fprintf(&buf, "%*s%s\n", level*2, "", name)
+			} else {
+				fmt.Fprintf(&buf, "%*s%s: %s\n", (level+1)*2, "", field, description)
+			}
+		},
+	}
+	_ = walkPlan(ctx, plan, e.observer())
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// traceExcerpt returns the last maxLines lines of a formatted trace, which are
+// the ones closest to the end of the statement.
+func traceExcerpt(dump string, maxLines int) string {
+	lines := strings.Split(strings.TrimSuffix(dump, "\n"), "\n")
+	if len(lines) > maxLines {
+		skipped := len(lines) - maxLines
+		lines = append([]string{fmt.Sprintf("... (%d lines omitted)", skipped)}, lines[skipped:]...)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,58 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestFormatPlaceholders(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	if s := formatPlaceholders(nil); s != "" {
+		t.Errorf("expected no placeholders, got %q", s)
+	}
+	args := parser.QueryArguments{
+		"10": parser.NewDInt(10),
+		"2":  parser.NewDString("b"),
+		"1":  parser.DNull,
+	}
+	const expected = `$1 = NULL, $2 = 'b', $10 = 10`
+	if s := formatPlaceholders(args); s != expected {
+		t.Errorf("expected %q, got %q", expected, s)
+	}
+}
+
+func TestTraceExcerpt(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const dump = "a\nb\nc\nd\n"
+	testCases := []struct {
+		maxLines int
+		expected string
+	}{
+		{10, "a\nb\nc\nd"},
+		{4, "a\nb\nc\nd"},
+		{2, "... (2 lines omitted)\nc\nd"},
+	}
+	for _, tc := range testCases {
+		if s := traceExcerpt(dump, tc.maxLines); s != tc.expected {
+			t.Errorf("%d: expected %q, got %q", tc.maxLines, tc.expected, s)
+		}
+	}
+}
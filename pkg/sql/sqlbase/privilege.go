@@ -217,8 +217,17 @@ func (u UserPrivilegeString) PrivilegeString() string {
 // Show returns the list of {username, privileges} sorted by username.
 // 'privileges' is a string of comma-separated sorted privilege names.
 func (p PrivilegeDescriptor) Show() []UserPrivilegeString {
+	return p.ShowUsers(nil)
+}
+
+// ShowUsers is like Show, but only returns the privileges of the given users.
+// The privileges of all users are returned if users is empty.
+func (p PrivilegeDescriptor) ShowUsers(users []string) []UserPrivilegeString {
 	ret := make([]UserPrivilegeString, 0, len(p.Users))
 	for _, userPriv := range p.Users {
+		if len(users) > 0 && !containsUser(users, userPriv.User) {
+			continue
+		}
 		ret = append(ret, UserPrivilegeString{
 			User:       userPriv.User,
 			Privileges: privilege.ListFromBitField(userPriv.Privileges).SortedNames(),
@@ -227,6 +236,15 @@ func (p PrivilegeDescriptor) Show() []UserPrivilegeString {
 	return ret
 }
 
+func containsUser(users []string, user string) bool {
+	for _, u := range users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
 // CheckPrivilege returns true if 'user' has 'privilege' on this descriptor.
 func (p PrivilegeDescriptor) CheckPrivilege(user string, priv privilege.Kind) bool {
 	userPriv, ok := p.findUser(user)
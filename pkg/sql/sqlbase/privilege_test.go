@@ -17,6 +17,7 @@
 package sqlbase
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/cockroachdb/cockroach/pkg/keys"
@@ -88,6 +89,36 @@ func TestPrivilege(t *testing.T) {
 	}
 }
 
+func TestShowUsers(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	descriptor := NewDefaultPrivilegeDescriptor()
+	descriptor.Grant("foo", privilege.List{privilege.SELECT, privilege.INSERT})
+	descriptor.Grant("bar", privilege.List{privilege.DROP})
+
+	testCases := []struct {
+		users []string
+		show  []UserPrivilegeString
+	}{
+		{nil, []UserPrivilegeString{
+			{"bar", []string{"DROP"}},
+			{"foo", []string{"INSERT", "SELECT"}},
+			{security.RootUser, []string{"ALL"}},
+		}},
+		{[]string{"foo"}, []UserPrivilegeString{{"foo", []string{"INSERT", "SELECT"}}}},
+		{[]string{"foo", "bar"}, []UserPrivilegeString{
+			{"bar", []string{"DROP"}},
+			{"foo", []string{"INSERT", "SELECT"}},
+		}},
+		{[]string{"baz"}, []UserPrivilegeString{}},
+	}
+	for tcNum, tc := range testCases {
+		if show := descriptor.ShowUsers(tc.users); !reflect.DeepEqual(show, tc.show) {
+			t.Errorf("#%d: expected %+v, got %+v", tcNum, tc.show, show)
+		}
+	}
+}
+
 func TestCheckPrivilege(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
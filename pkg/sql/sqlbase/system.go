@@ -123,6 +123,37 @@ CREATE TABLE system.jobs (
 	INDEX (status, created),
 	FAMILY (id, status, created, payload)
 );`
+
+	ScheduledJobsTableSchema = `
+CREATE TABLE system.scheduled_jobs (
+	id                INT       DEFAULT unique_rowid() PRIMARY KEY,
+	name              STRING    NOT NULL,
+	owner             STRING    NOT NULL,
+	created           TIMESTAMP NOT NULL DEFAULT now(),
+	cron              STRING    NOT NULL,
+	statement         STRING    NOT NULL,
+	overlap_policy    STRING    NOT NULL,
+	paused            BOOL      NOT NULL DEFAULT false,
+	next_run          TIMESTAMP,
+	running_since     TIMESTAMP,
+	last_run          TIMESTAMP,
+	last_error        STRING,
+	INDEX (next_run),
+	FAMILY "primary" (id, name, owner, created, cron, statement, overlap_policy, paused,
+	                  next_run, running_since, last_run, last_error)
+);`
+
+	// livenesslog records the transitions between the liveness statuses of the
+	// nodes, as observed by the nodes of the cluster.
+	LivenessLogTableSchema = `
+CREATE TABLE system.livenesslog (
+	"nodeID"  INT       NOT NULL,
+	timestamp TIMESTAMP NOT NULL,
+	status    STRING    NOT NULL,
+	epoch     INT       NOT NULL,
+	PRIMARY KEY ("nodeID", timestamp),
+	FAMILY "primary" ("nodeID", timestamp, status, epoch)
+);`
 )
 
 func pk(name string) IndexDescriptor {
@@ -174,7 +205,9 @@ var SystemAllowedPrivileges = map[ID]privilege.Lists{
 	// users will be able to modify system tables' schemas at will. CREATE and
 	// DROP privileges are allowed on the above system tables for backwards
 	// compatibility reasons only!
-	keys.JobsTableID: {privilege.ReadWriteData},
+	keys.JobsTableID:          {privilege.ReadWriteData},
+	keys.ScheduledJobsTableID: {privilege.ReadWriteData},
+	keys.LivenessLogTableID:   {privilege.ReadWriteData},
 }
 
 // SystemDesiredPrivileges returns the desired privilege list (i.e., the
@@ -189,6 +222,7 @@ func SystemDesiredPrivileges(id ID) privilege.List {
 // Helpers used to make some of the TableDescriptor literals below more concise.
 var (
 	colTypeInt       = ColumnType{Kind: ColumnType_INT}
+	colTypeBool      = ColumnType{Kind: ColumnType_BOOL}
 	colTypeString    = ColumnType{Kind: ColumnType_STRING}
 	colTypeBytes     = ColumnType{Kind: ColumnType_BYTES}
 	colTypeTimestamp = ColumnType{Kind: ColumnType_TIMESTAMP}
@@ -522,6 +556,95 @@ var (
 		FormatVersion:  InterleavedFormatVersion,
 		NextMutationID: 1,
 	}
+
+	falseString = "false"
+
+	// ScheduledJobsTable is the descriptor for the scheduled_jobs table.
+	ScheduledJobsTable = TableDescriptor{
+		Name:     "scheduled_jobs",
+		ID:       keys.ScheduledJobsTableID,
+		ParentID: 1,
+		Version:  1,
+		Columns: []ColumnDescriptor{
+			{Name: "id", ID: 1, Type: colTypeInt, DefaultExpr: &uniqueRowIDString},
+			{Name: "name", ID: 2, Type: colTypeString},
+			{Name: "owner", ID: 3, Type: colTypeString},
+			{Name: "created", ID: 4, Type: colTypeTimestamp, DefaultExpr: &nowString},
+			{Name: "cron", ID: 5, Type: colTypeString},
+			{Name: "statement", ID: 6, Type: colTypeString},
+			{Name: "overlap_policy", ID: 7, Type: colTypeString},
+			{Name: "paused", ID: 8, Type: colTypeBool, DefaultExpr: &falseString},
+			{Name: "next_run", ID: 9, Type: colTypeTimestamp, Nullable: true},
+			{Name: "running_since", ID: 10, Type: colTypeTimestamp, Nullable: true},
+			{Name: "last_run", ID: 11, Type: colTypeTimestamp, Nullable: true},
+			{Name: "last_error", ID: 12, Type: colTypeString, Nullable: true},
+		},
+		NextColumnID: 13,
+		Families: []ColumnFamilyDescriptor{
+			{
+				Name: "primary",
+				ID:   0,
+				ColumnNames: []string{
+					"id", "name", "owner", "created", "cron", "statement", "overlap_policy", "paused",
+					"next_run", "running_since", "last_run", "last_error",
+				},
+				ColumnIDs: []ColumnID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+			},
+		},
+		NextFamilyID: 1,
+		PrimaryIndex: pk("id"),
+		Indexes: []IndexDescriptor{
+			{
+				Name:             "scheduled_jobs_next_run_idx",
+				ID:               2,
+				Unique:           false,
+				ColumnNames:      []string{"next_run"},
+				ColumnDirections: singleASC,
+				ColumnIDs:        []ColumnID{9},
+				ExtraColumnIDs:   []ColumnID{1},
+			},
+		},
+		NextIndexID:    3,
+		Privileges:     NewPrivilegeDescriptor(security.RootUser, SystemDesiredPrivileges(keys.ScheduledJobsTableID)),
+		FormatVersion:  InterleavedFormatVersion,
+		NextMutationID: 1,
+	}
+
+	// LivenessLogTable is the descriptor for the liveness log table.
+	LivenessLogTable = TableDescriptor{
+		Name:     "livenesslog",
+		ID:       keys.LivenessLogTableID,
+		ParentID: 1,
+		Version:  1,
+		Columns: []ColumnDescriptor{
+			{Name: "nodeID", ID: 1, Type: colTypeInt},
+			{Name: "timestamp", ID: 2, Type: colTypeTimestamp},
+			{Name: "status", ID: 3, Type: colTypeString},
+			{Name: "epoch", ID: 4, Type: colTypeInt},
+		},
+		NextColumnID: 5,
+		Families: []ColumnFamilyDescriptor{
+			{
+				Name:        "primary",
+				ID:          0,
+				ColumnNames: []string{"nodeID", "timestamp", "status", "epoch"},
+				ColumnIDs:   []ColumnID{1, 2, 3, 4},
+			},
+		},
+		NextFamilyID: 1,
+		PrimaryIndex: IndexDescriptor{
+			Name:             "primary",
+			ID:               1,
+			Unique:           true,
+			ColumnNames:      []string{"nodeID", "timestamp"},
+			ColumnDirections: []IndexDescriptor_Direction{IndexDescriptor_ASC, IndexDescriptor_ASC},
+			ColumnIDs:        []ColumnID{1, 2},
+		},
+		NextIndexID:    2,
+		Privileges:     NewPrivilegeDescriptor(security.RootUser, SystemDesiredPrivileges(keys.LivenessLogTableID)),
+		FormatVersion:  InterleavedFormatVersion,
+		NextMutationID: 1,
+	}
 )
 
 // Create the key/value pair for the default zone config entry.
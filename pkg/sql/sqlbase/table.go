@@ -21,6 +21,7 @@ import (
 	"sort"
 	"time"
 	"unicode/utf8"
+	"unsafe"
 
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
@@ -30,6 +31,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/util/bufalloc"
 	"github.com/cockroachdb/cockroach/pkg/util/duration"
 	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
@@ -902,6 +904,34 @@ type DatumAlloc struct {
 	duuidAlloc        []parser.DUuid
 	doidAlloc         []parser.DOid
 	env               parser.CollationEnvironment
+
+	// strAlloc backs the contents of the strings allocated through
+	// allocString. The strings share chunks of memory, so only small strings
+	// are allocated from it (see maxArenaStringSize).
+	strAlloc bufalloc.ByteAllocator
+	// scratch is reused as temporary storage when decoding.
+	scratch []byte
+}
+
+// maxArenaStringSize is the size above which the strings are not allocated
+// from DatumAlloc.strAlloc, to avoid large chunks of memory being pinned by a
+// few live strings.
+const maxArenaStringSize = 512
+
+// allocString returns a string with the contents of b. Small strings are
+// copied into a shared buffer, amortizing the cost of their allocation.
+func (a *DatumAlloc) allocString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	if len(b) > maxArenaStringSize {
+		return string(b)
+	}
+	var r []byte
+	a.strAlloc, r = a.strAlloc.Copy(b, 0)
+	// The memory backing r is never modified again, so it's safe to use it for
+	// an (immutable) string.
+	return *(*string)(unsafe.Pointer(&r))
 }
 
 // NewDInt allocates a DInt.
@@ -940,11 +970,23 @@ func (a *DatumAlloc) NewDString(v parser.DString) *parser.DString {
 	return r
 }
 
+// NewDStringFromBytes allocates a DString with the contents of b, which is
+// not retained.
+func (a *DatumAlloc) NewDStringFromBytes(b []byte) *parser.DString {
+	return a.NewDString(parser.DString(a.allocString(b)))
+}
+
 // NewDName allocates a DName.
 func (a *DatumAlloc) NewDName(v parser.DString) parser.Datum {
 	return parser.NewDNameFromDString(a.NewDString(v))
 }
 
+// NewDNameFromBytes allocates a DName with the contents of b, which is not
+// retained.
+func (a *DatumAlloc) NewDNameFromBytes(b []byte) parser.Datum {
+	return parser.NewDNameFromDString(a.NewDStringFromBytes(b))
+}
+
 // NewDBytes allocates a DBytes.
 func (a *DatumAlloc) NewDBytes(v parser.DBytes) *parser.DBytes {
 	buf := &a.dbytesAlloc
@@ -957,6 +999,12 @@ func (a *DatumAlloc) NewDBytes(v parser.DBytes) *parser.DBytes {
 	return r
 }
 
+// NewDBytesFromBytes allocates a DBytes with the contents of b, which is not
+// retained.
+func (a *DatumAlloc) NewDBytesFromBytes(b []byte) *parser.DBytes {
+	return a.NewDBytes(parser.DBytes(a.allocString(b)))
+}
+
 // NewDDecimal allocates a DDecimal.
 func (a *DatumAlloc) NewDDecimal(v parser.DDecimal) *parser.DDecimal {
 	buf := &a.ddecimalAlloc
@@ -1109,11 +1157,14 @@ func DecodeTableKey(
 	case parser.TypeBytes:
 		var r []byte
 		if dir == encoding.Ascending {
+			// Without escaped bytes, r is a sub-slice of key.
 			rkey, r, err = encoding.DecodeBytesAscending(key, nil)
 		} else {
-			rkey, r, err = encoding.DecodeBytesDescending(key, nil)
+			// The bytes are decoded in place in r, so it can't alias key.
+			rkey, r, err = encoding.DecodeBytesDescending(key, a.scratch[:0])
+			a.scratch = r[:0]
 		}
-		return a.NewDBytes(parser.DBytes(r)), rkey, err
+		return a.NewDBytesFromBytes(r), rkey, err
 	case parser.TypeDate:
 		var t int64
 		if dir == encoding.Ascending {
@@ -1211,15 +1262,15 @@ func DecodeTableValue(a *DatumAlloc, valType parser.Type, b []byte) (parser.Datu
 	case parser.TypeString:
 		var data []byte
 		b, data, err = encoding.DecodeBytesValue(b)
-		return a.NewDString(parser.DString(data)), b, err
+		return a.NewDStringFromBytes(data), b, err
 	case parser.TypeName:
 		var data []byte
 		b, data, err = encoding.DecodeBytesValue(b)
-		return a.NewDName(parser.DString(data)), b, err
+		return a.NewDNameFromBytes(data), b, err
 	case parser.TypeBytes:
 		var data []byte
 		b, data, err = encoding.DecodeBytesValue(b)
-		return a.NewDBytes(parser.DBytes(data)), b, err
+		return a.NewDBytesFromBytes(data), b, err
 	case parser.TypeDate:
 		var i int64
 		b, i, err = encoding.DecodeIntValue(b)
@@ -1532,13 +1583,13 @@ func UnmarshalColumnValue(
 		if err != nil {
 			return nil, err
 		}
-		return a.NewDString(parser.DString(v)), nil
+		return a.NewDStringFromBytes(v), nil
 	case ColumnType_BYTES:
 		v, err := value.GetBytes()
 		if err != nil {
 			return nil, err
 		}
-		return a.NewDBytes(parser.DBytes(v)), nil
+		return a.NewDBytesFromBytes(v), nil
 	case ColumnType_DATE:
 		v, err := value.GetInt()
 		if err != nil {
@@ -1584,7 +1635,7 @@ func UnmarshalColumnValue(
 		if err != nil {
 			return nil, err
 		}
-		return a.NewDName(parser.DString(v)), nil
+		return a.NewDNameFromBytes(v), nil
 	case ColumnType_OID:
 		v, err := value.GetInt()
 		if err != nil {
@@ -18,6 +18,7 @@ package sqlbase
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"golang.org/x/net/context"
@@ -244,3 +245,49 @@ func TestIndexKey(t *testing.T) {
 		checkEntry(&tableDesc.Indexes[0], secondaryIndexKV)
 	}
 }
+
+func TestDatumAllocStrings(t *testing.T) {
+	var a DatumAlloc
+	buf := []byte("abc")
+	s1 := a.NewDStringFromBytes(buf)
+	b1 := a.NewDBytesFromBytes(buf)
+	n1 := a.NewDNameFromBytes(buf)
+	large := bytes.Repeat([]byte("x"), maxArenaStringSize+1)
+	s2 := a.NewDStringFromBytes(large)
+	// The datums don't alias the input.
+	buf[0] = 'z'
+	large[0] = 'z'
+	if *s1 != "abc" || *b1 != "abc" || parser.MustBeDString(n1) != "abc" {
+		t.Errorf("unexpected values %s, %s, %s", s1, b1, n1)
+	}
+	if string(*s2) != strings.Repeat("x", maxArenaStringSize+1) {
+		t.Errorf("unexpected large value %s", s2)
+	}
+	if s := a.NewDStringFromBytes(nil); *s != "" {
+		t.Errorf("expected empty string, got %s", s)
+	}
+}
+
+func BenchmarkDecodeTableValueString(b *testing.B) {
+	values := make([][]byte, 100)
+	for i := range values {
+		var err error
+		values[i], err = EncodeTableValue(
+			nil, ColumnID(encoding.NoColumnID), parser.NewDString(strings.Repeat("x", i)),
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// DatumAllocs are typically used for a batch of rows.
+		var a DatumAlloc
+		for _, v := range values {
+			if _, _, err := DecodeTableValue(&a, parser.TypeString, v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
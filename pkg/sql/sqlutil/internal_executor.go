@@ -40,4 +40,11 @@ type InternalExecutor interface {
 	QueryRowInTransaction(
 		ctx context.Context, opName string, txn *client.Txn, statement string, qargs ...interface{},
 	) (parser.Datums, error)
+
+	// QueryRowsInTransaction executes the supplied SQL statement as part of
+	// the supplied transaction and returns all the resulting rows. Statements
+	// are currently executed as the root user.
+	QueryRowsInTransaction(
+		ctx context.Context, opName string, txn *client.Txn, statement string, qargs ...interface{},
+	) ([]parser.Datums, error)
 }
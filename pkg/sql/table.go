@@ -393,6 +393,53 @@ func (lc *LeaseCollection) removeLeaseIfExpiring(
 	return true
 }
 
+// refreshDeadline replaces the leases which the lease manager renewed, or
+// which are about to expire, with fresh leases on the same descriptor version
+// and recomputes the transaction deadline from the resulting leases. It's
+// called before committing, so that a long-running transaction or statement
+// can commit after the leases it acquired early on have expired, as long as
+// no schema change has published a new version of the descriptors it uses. A
+// lease on a descriptor that has since changed is kept, and its expiration
+// continues to bound the deadline.
+func (lc *LeaseCollection) refreshDeadline(ctx context.Context, txn *client.Txn) {
+	refreshed := false
+	for i, lease := range lc.leases {
+		newLease := lc.leaseMgr.acquireRenewed(lease)
+		if newLease == nil {
+			if lease.hasSomeLifeLeft(lc.leaseMgr.clock) {
+				continue
+			}
+			var err error
+			newLease, err = lc.leaseMgr.Acquire(ctx, txn, lease.ID, 0)
+			if err != nil {
+				log.VEventf(ctx, 2, "unable to refresh lease (%s): %s", lease, err)
+				continue
+			}
+			if newLease.Version != lease.Version || !newLease.expiration.After(lease.expiration.Time) {
+				if err := lc.leaseMgr.Release(newLease); err != nil {
+					log.Warning(ctx, err)
+				}
+				continue
+			}
+		}
+		if err := lc.leaseMgr.Release(lease); err != nil {
+			log.Warning(ctx, err)
+		}
+		lc.leases[i] = newLease
+		refreshed = true
+		if log.V(2) {
+			log.Infof(ctx, "refreshed lease (%s) as (%s)", lease, newLease)
+		}
+	}
+	if !refreshed {
+		return
+	}
+	txn.ResetDeadline()
+	for _, l := range lc.leases {
+		txn.UpdateDeadlineMaybe(hlc.Timestamp{WallTime: l.Expiration().UnixNano()})
+	}
+}
+
 // getTableNames retrieves the list of qualified names of tables
 // present in the given database.
 func getTableNames(
@@ -424,7 +471,9 @@ func getTableNames(
 	return tableNames, nil
 }
 
-func (p *planner) getAliasedTableName(n parser.TableExpr) (*parser.TableName, error) {
+func (p *planner) getAliasedTableName(
+	ctx context.Context, n parser.TableExpr,
+) (*parser.TableName, error) {
 	if ate, ok := n.(*parser.AliasedTableExpr); ok {
 		n = ate.Expr
 	}
@@ -432,7 +481,7 @@ func (p *planner) getAliasedTableName(n parser.TableExpr) (*parser.TableName, er
 	if !ok {
 		return nil, errors.Errorf("TODO(pmattis): unsupported FROM: %s", n)
 	}
-	return table.NormalizeWithDatabaseName(p.session.Database)
+	return p.normalizeTableName(ctx, table)
 }
 
 // createSchemaChangeJob finalizes the current mutations in the table
@@ -453,6 +502,7 @@ func (p *planner) createSchemaChangeJob(
 		Details:       jobs.SchemaChangeJobDetails{},
 	}
 	jobLogger := jobs.NewJobLogger(p.ExecCfg().DB, InternalExecutor{LeaseManager: p.session.leases.leaseMgr}, jobRecord)
+	jobLogger.WithTestingKnobs(p.ExecCfg().JobsTestingKnobs)
 	if err := jobLogger.WithTxn(p.txn).Created(ctx); err != nil {
 		return sqlbase.InvalidMutationID, nil
 	}
@@ -491,16 +541,14 @@ func (p *planner) writeTableDesc(ctx context.Context, tableDesc *sqlbase.TableDe
 }
 
 // expandTableGlob expands pattern into a list of tables represented
-// as a parser.TableNames.
-func expandTableGlob(
-	ctx context.Context,
-	txn *client.Txn,
-	vt VirtualTabler,
-	database string,
-	pattern parser.TablePattern,
+// as a parser.TableNames. An unqualified table name is resolved through
+// the session's search_path, while an unqualified glob expands to the
+// tables of the current database.
+func (p *planner) expandTableGlob(
+	ctx context.Context, pattern parser.TablePattern,
 ) (parser.TableNames, error) {
 	if t, ok := pattern.(*parser.TableName); ok {
-		if err := t.QualifyWithDatabase(database); err != nil {
+		if err := p.qualifyTableName(ctx, t); err != nil {
 			return nil, err
 		}
 		return parser.TableNames{*t}, nil
@@ -508,71 +556,23 @@ func expandTableGlob(
 
 	glob := pattern.(*parser.AllTablesSelector)
 
-	if err := glob.QualifyWithDatabase(database); err != nil {
+	if err := glob.QualifyWithDatabase(p.session.Database); err != nil {
 		return nil, err
 	}
 
-	dbDesc, err := MustGetDatabaseDesc(ctx, txn, vt, string(glob.Database))
+	vt := p.getVirtualTabler()
+	dbDesc, err := MustGetDatabaseDesc(ctx, p.txn, vt, string(glob.Database))
 	if err != nil {
 		return nil, err
 	}
 
-	tableNames, err := getTableNames(ctx, txn, vt, dbDesc)
+	tableNames, err := getTableNames(ctx, p.txn, vt, dbDesc)
 	if err != nil {
 		return nil, err
 	}
 	return tableNames, nil
 }
 
-// searchAndQualifyDatabase augments the table name with the database
-// where it was found. It searches first in the session current
-// database, if that's defined, otherwise the search path.  The
-// provided TableName is modified in-place in case of success, and
-// left unchanged otherwise.
-// The table name must not be qualified already.
-func (p *planner) searchAndQualifyDatabase(ctx context.Context, tn *parser.TableName) error {
-	t := *tn
-
-	descFunc := p.session.leases.getTableLease
-	if p.avoidCachedDescriptors {
-		// AS OF SYSTEM TIME queries need to fetch the table descriptor at the
-		// specified time, and never lease anything. The proto transaction already
-		// has its timestamps set correctly so getTableOrViewDesc will fetch with
-		// the correct timestamp.
-		descFunc = getTableOrViewDesc
-	}
-
-	if p.session.Database != "" {
-		t.DatabaseName = parser.Name(p.session.Database)
-		desc, err := descFunc(ctx, p.txn, p.getVirtualTabler(), &t)
-		if err != nil && !sqlbase.IsUndefinedTableError(err) && !sqlbase.IsUndefinedDatabaseError(err) {
-			return err
-		}
-		if desc != nil {
-			// Table was found, use this name.
-			*tn = t
-			return nil
-		}
-	}
-
-	// Not found using the current session's database, so try
-	// the search path instead.
-	for _, database := range p.session.SearchPath {
-		t.DatabaseName = parser.Name(database)
-		desc, err := descFunc(ctx, p.txn, p.getVirtualTabler(), &t)
-		if err != nil && !sqlbase.IsUndefinedTableError(err) && !sqlbase.IsUndefinedDatabaseError(err) {
-			return err
-		}
-		if desc != nil {
-			// The table or view exists in this database, so return it.
-			*tn = t
-			return nil
-		}
-	}
-
-	return sqlbase.NewUndefinedTableError(string(t.TableName))
-}
-
 // getQualifiedTableName returns the database-qualified name of the table
 // or view represented by the provided descriptor.
 func (p *planner) getQualifiedTableName(
@@ -590,9 +590,9 @@ func (p *planner) getQualifiedTableName(
 }
 
 // findTableContainingIndex returns the name of the table containing
-// an index of the given name. An error is returned if the index is
-// not found or if the index name is ambiguous (i.e. exists in
-// multiple tables).
+// an index of the given name, or nil if no table of the database
+// contains it. An error is returned if the index name is ambiguous
+// (i.e. exists in multiple tables).
 func (p *planner) findTableContainingIndex(
 	ctx context.Context, txn *client.Txn, vt VirtualTabler, dbName parser.Name, idxName parser.Name,
 ) (result *parser.TableName, err error) {
@@ -626,33 +626,47 @@ func (p *planner) findTableContainingIndex(
 		}
 		result = tn
 	}
-	if result == nil {
-		return nil, fmt.Errorf("index %q does not exist", normName)
-	}
 	return result, nil
 }
 
 // expandIndexName ensures that the index name is qualified with a
-// table name, and searches the table name if not yet specified. It returns
-// the TableName of the underlying table for convenience.
+// table name, and searches the table name if not yet specified. An
+// unqualified index name is searched for in the databases of the
+// session's search_path, in order. It returns the TableName of the
+// underlying table for convenience.
 func (p *planner) expandIndexName(
 	ctx context.Context, index *parser.TableNameWithIndex,
 ) (*parser.TableName, error) {
-	tn, err := index.Table.NormalizeWithDatabaseName(p.session.Database)
+	if !index.SearchTable {
+		return p.normalizeTableName(ctx, &index.Table)
+	}
+
+	tn, err := index.Table.Normalize()
 	if err != nil {
 		return nil, err
 	}
-
-	if index.SearchTable {
-		realTableName, err := p.findTableContainingIndex(ctx, p.txn, p.getVirtualTabler(), tn.DatabaseName, tn.TableName)
+	databases := []string{string(tn.DatabaseName)}
+	if tn.DatabaseName == "" {
+		databases = makeTableResolver(p.session).searchOrder()
+	}
+	for _, database := range databases {
+		realTableName, err := p.findTableContainingIndex(
+			ctx, p.txn, p.getVirtualTabler(), parser.Name(database), tn.TableName,
+		)
 		if err != nil {
+			if tn.DatabaseName == "" && sqlbase.IsUndefinedDatabaseError(err) {
+				// Databases of the search path need not exist.
+				continue
+			}
 			return nil, err
 		}
-		index.Index = tn.TableName
-		index.Table.TableNameReference = realTableName
-		tn = realTableName
+		if realTableName != nil {
+			index.Index = tn.TableName
+			index.Table.TableNameReference = realTableName
+			return realTableName, nil
+		}
 	}
-	return tn, nil
+	return nil, fmt.Errorf("index %q does not exist", tn.TableName.Normalize())
 }
 
 // getTableAndIndex returns the table and index descriptors for a table
@@ -670,7 +684,7 @@ func (p *planner) getTableAndIndex(
 	var err error
 	if tableWithIndex == nil {
 		// Variant: ALTER TABLE
-		tn, err = table.NormalizeWithDatabaseName(p.session.Database)
+		tn, err = p.normalizeTableName(ctx, table)
 	} else {
 		// Variant: ALTER INDEX
 		tn, err = p.expandIndexName(ctx, tableWithIndex)
@@ -48,7 +48,7 @@ func (p *planner) Truncate(ctx context.Context, n *parser.Truncate) (planNode, e
 		if err != nil {
 			return nil, err
 		}
-		if err := tn.QualifyWithDatabase(p.session.Database); err != nil {
+		if err := p.qualifyTableName(ctx, tn); err != nil {
 			return nil, err
 		}
 
@@ -120,12 +120,21 @@ func truncateTable(tableDesc *sqlbase.TableDescriptor, txn *client.Txn) error {
 
 // truncateTableInChunks truncates the data of a table in chunks. It deletes a
 // range of data for the table, which includes the PK and all indexes.
+// beforeChunk is called before the deletion of each chunk with the number of
+// rows deleted so far and the span of data left to delete, which is empty
+// before the first chunk.
 func truncateTableInChunks(
-	ctx context.Context, tableDesc *sqlbase.TableDescriptor, db *client.DB,
+	ctx context.Context,
+	tableDesc *sqlbase.TableDescriptor,
+	db *client.DB,
+	chunkSize int64,
+	beforeChunk func(row int64, resume roachpb.Span) error,
 ) error {
-	const chunkSize = TableTruncateChunkSize
 	var resume roachpb.Span
-	for row, done := 0, false; !done; row += chunkSize {
+	for row, done := int64(0), false; !done; row += chunkSize {
+		if err := beforeChunk(row, resume); err != nil {
+			return err
+		}
 		resumeAt := resume
 		if log.V(2) {
 			log.Infof(ctx, "table %s truncate at row: %d, span: %s", tableDesc.Name, row, resume)
@@ -19,6 +19,7 @@ package sql
 import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgerror"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/pkg/errors"
 )
@@ -46,6 +47,9 @@ func (p *planner) setTransactionModes(modes parser.TransactionModes) error {
 	if err := p.setReadWriteMode(modes.ReadWriteMode); err != nil {
 		return err
 	}
+	if err := p.setHistoricalTimestamp(modes.AsOf); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -82,10 +86,69 @@ func (p *planner) setReadWriteMode(readWriteMode parser.ReadWriteMode) error {
 	case parser.UnspecifiedReadWriteMode:
 		return nil
 	case parser.ReadOnly:
-		return errors.New("read only not supported")
+		p.session.TxnState.readOnly = true
+		return nil
 	case parser.ReadWrite:
+		p.session.TxnState.readOnly = false
 		return nil
 	default:
 		return errors.Errorf("unknown read mode: %s", readWriteMode)
 	}
 }
+
+// setHistoricalTimestamp pins the transaction to the timestamp of an AS OF
+// SYSTEM TIME clause, which makes it possible to run multiple consistent reads
+// in the past. The timestamp can only be set before the transaction has
+// performed any reads or writes. The transaction becomes read-only.
+func (p *planner) setHistoricalTimestamp(asOf parser.AsOfClause) error {
+	if asOf.Expr == nil {
+		return nil
+	}
+	// The command count is reset when the transaction is retried, which allows
+	// this statement to be executed again.
+	if p.txn.CommandCount() > 0 {
+		return errors.New("AS OF SYSTEM TIME must be specified before any statement in the transaction")
+	}
+	ts, err := EvalAsOfTimestamp(&p.evalCtx, asOf, p.session.execCfg.Clock.Now())
+	if err != nil {
+		return err
+	}
+	p.txn.SetFixedTimestamp(ts)
+	p.session.TxnState.historicalTimestamp = &ts
+	return nil
+}
+
+// checkReadOnlyTxnStmt returns an error if the statement can modify data or
+// schemas and the transaction is read-only, because it was declared so or
+// pinned to a historical timestamp.
+func (p *planner) checkReadOnlyTxnStmt(stmt parser.Statement) error {
+	ts := &p.session.TxnState
+	if ts.historicalTimestamp == nil && !ts.readOnly {
+		return nil
+	}
+	write := stmt.StatementType() == parser.DDL
+	tag := stmt.StatementTag()
+	switch t := stmt.(type) {
+	case *parser.Insert, *parser.Update, *parser.Delete, *parser.Truncate,
+		*parser.CopyFrom, *parser.CreateUser, *parser.DropUser,
+		*parser.Split, *parser.Relocate, *parser.Scatter,
+		*parser.Backup, *parser.Restore,
+		*parser.CreateSchedule, *parser.PauseSchedule, *parser.ResumeSchedule:
+		write = true
+	case *parser.Set:
+		// Cluster settings are stored in system.settings.
+		if t.SetMode == parser.SetModeClusterSetting {
+			write = true
+			tag = "SET CLUSTER SETTING"
+		}
+	}
+	if !write {
+		return nil
+	}
+	if ts.historicalTimestamp != nil {
+		return pgerror.NewErrorf(pgerror.CodeReadOnlySQLTransactionError,
+			"cannot execute %s in a transaction using AS OF SYSTEM TIME", tag)
+	}
+	return pgerror.NewErrorf(pgerror.CodeReadOnlySQLTransactionError,
+		"cannot execute %s in a read-only transaction", tag)
+}
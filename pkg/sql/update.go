@@ -225,7 +225,7 @@ func (p *planner) Update(
 ) (planNode, error) {
 	tracing.AnnotateTrace()
 
-	tn, err := p.getAliasedTableName(n.Table)
+	tn, err := p.getAliasedTableName(ctx, n.Table)
 	if err != nil {
 		return nil, err
 	}
@@ -408,6 +408,7 @@ func (u *updateNode) Next(ctx context.Context) (bool, error) {
 	if !next {
 		if err == nil {
 			// We're done. Finish the batch.
+			u.p.maybeRefreshLeaseDeadline(ctx)
 			err = u.tw.finalize(ctx)
 		}
 		return false, err
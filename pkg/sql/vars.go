@@ -113,6 +113,60 @@ var varGen = map[string]sessionVar{
 			return nil
 		},
 	},
+	`default_transaction_read_only`: {
+		Set: func(_ context.Context, p *planner, values []parser.TypedExpr) error {
+			s, err := p.getStringVal(`default_transaction_read_only`, values)
+			if err != nil {
+				return err
+			}
+			switch parser.Name(s).Normalize() {
+			case parser.ReNormalizeName("off"):
+				p.session.DefaultReadOnly = false
+			case parser.ReNormalizeName("on"):
+				p.session.DefaultReadOnly = true
+			default:
+				return fmt.Errorf("set default_transaction_read_only: \"%s\" not supported", s)
+			}
+			return nil
+		},
+		Get: func(p *planner) string {
+			if p.session.DefaultReadOnly {
+				return "on"
+			}
+			return "off"
+		},
+		Reset: func(p *planner) error {
+			p.session.DefaultReadOnly = false
+			return nil
+		},
+	},
+	`discard_rows`: {
+		Set: func(_ context.Context, p *planner, values []parser.TypedExpr) error {
+			s, err := p.getStringVal(`discard_rows`, values)
+			if err != nil {
+				return err
+			}
+			switch parser.Name(s).Normalize() {
+			case parser.ReNormalizeName("off"):
+				p.session.DiscardRows = false
+			case parser.ReNormalizeName("on"):
+				p.session.DiscardRows = true
+			default:
+				return fmt.Errorf("set discard_rows: \"%s\" not supported", s)
+			}
+			return nil
+		},
+		Get: func(p *planner) string {
+			if p.session.DiscardRows {
+				return "on"
+			}
+			return "off"
+		},
+		Reset: func(p *planner) error {
+			p.session.DiscardRows = false
+			return nil
+		},
+	},
 	`distsql`: {
 		Set: func(_ context.Context, p *planner, values []parser.TypedExpr) error {
 			s, err := p.getStringVal(`distsql`, values)
@@ -249,6 +303,29 @@ var varGen = map[string]sessionVar{
 	`transaction status`: {
 		Get: func(p *planner) string { return getTransactionState(&p.session.TxnState, p.autoCommit) },
 	},
+	`transaction_read_only`: {
+		// Equivalent to SET TRANSACTION READ ONLY or READ WRITE.
+		Set: func(_ context.Context, p *planner, values []parser.TypedExpr) error {
+			s, err := p.getStringVal(`transaction_read_only`, values)
+			if err != nil {
+				return err
+			}
+			switch parser.Name(s).Normalize() {
+			case parser.ReNormalizeName("off"):
+				return p.setReadWriteMode(parser.ReadWrite)
+			case parser.ReNormalizeName("on"):
+				return p.setReadWriteMode(parser.ReadOnly)
+			default:
+				return fmt.Errorf("set transaction_read_only: \"%s\" not supported", s)
+			}
+		},
+		Get: func(p *planner) string {
+			if p.session.TxnState.readOnly || p.session.TxnState.historicalTimestamp != nil {
+				return "on"
+			}
+			return "off"
+		},
+	},
 	`max_index_keys`: {
 		Get: func(*planner) string { return "32" },
 	},
@@ -285,6 +362,30 @@ var varGen = map[string]sessionVar{
 		Reset: func(*planner) error { return nil },
 	},
 
+	`trace_context`: {
+		Set: func(_ context.Context, p *planner, values []parser.TypedExpr) error {
+			s, err := p.getStringVal(`trace_context`, values)
+			if err != nil {
+				return err
+			}
+			sc, err := tracing.DecodeSpanContext(p.session.execCfg.AmbientCtx.Tracer, s)
+			if err != nil {
+				return err
+			}
+			p.session.traceContext = s
+			p.session.traceParent = sc
+			return nil
+		},
+		Get: func(p *planner) string {
+			return p.session.traceContext
+		},
+		Reset: func(p *planner) error {
+			p.session.traceContext = ""
+			p.session.traceParent = nil
+			return nil
+		},
+	},
+
 	`trace`: {
 		Get: func(p *planner) string {
 			if p.session.Tracing.Enabled() {
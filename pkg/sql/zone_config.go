@@ -0,0 +1,433 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/cockroachdb/cockroach/pkg/config"
+	"github.com/cockroachdb/cockroach/pkg/internal/client"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+)
+
+// namedZones maps the names accepted by `ALTER RANGE ... CONFIGURE ZONE` to
+// the IDs of the built-in zones they designate.
+var namedZones = map[string]sqlbase.ID{
+	"default":    keys.RootNamespaceID,
+	"meta":       keys.MetaRangesID,
+	"system":     keys.SystemRangesID,
+	"timeseries": keys.TimeseriesRangesID,
+}
+
+// zoneTarget is a resolved parser.ZoneSpecifier.
+type zoneTarget struct {
+	// ids holds the IDs of the zones the target inherits from, starting with
+	// the default zone and ending with the target's own zone. specifiers holds
+	// their names, in the format used by the `cockroach zone` command.
+	ids        []sqlbase.ID
+	specifiers []string
+	// index is set if the target is an index. The GC policies of indexes are
+	// stored in the zone config of their table.
+	index *sqlbase.IndexDescriptor
+	// indexSpecifier is the name of the index target.
+	indexSpecifier string
+}
+
+func (t zoneTarget) id() sqlbase.ID {
+	return t.ids[len(t.ids)-1]
+}
+
+// getZoneConfig returns the zone config with the given ID, if there is one.
+func getZoneConfig(
+	ctx context.Context, txn *client.Txn, id sqlbase.ID,
+) (config.ZoneConfig, bool, error) {
+	kv, err := txn.Get(ctx, sqlbase.MakeZoneKey(id))
+	if err != nil || kv.Value == nil {
+		return config.ZoneConfig{}, false, err
+	}
+	var zone config.ZoneConfig
+	if err := kv.ValueProto(&zone); err != nil {
+		return config.ZoneConfig{}, false, err
+	}
+	return zone, true, nil
+}
+
+// nearestZone returns the position in t.ids of the closest zone config that
+// applies to the target, along with that zone config.
+func (t zoneTarget) nearestZone(
+	ctx context.Context, txn *client.Txn,
+) (int, config.ZoneConfig, error) {
+	for i := len(t.ids) - 1; i >= 0; i-- {
+		zone, found, err := getZoneConfig(ctx, txn, t.ids[i])
+		if err != nil || found {
+			return i, zone, err
+		}
+	}
+	// The default zone config always exists.
+	return 0, config.ZoneConfig{}, errors.Errorf("default zone config is missing")
+}
+
+// resolveZone resolves a zone specifier. checkPrivilege is called on the
+// descriptor of the database or table the zone belongs to.
+func (p *planner) resolveZone(
+	ctx context.Context,
+	zs parser.ZoneSpecifier,
+	checkPrivilege func(sqlbase.DescriptorProto) error,
+) (zoneTarget, error) {
+	t := zoneTarget{
+		ids:        []sqlbase.ID{keys.RootNamespaceID},
+		specifiers: []string{".default"},
+	}
+	if zs.NamedZone != "" {
+		name := zs.NamedZone.Normalize()
+		id, ok := namedZones[name]
+		if !ok {
+			return zoneTarget{}, errors.Errorf("%q is not a built-in zone", string(zs.NamedZone))
+		}
+		if id != keys.RootNamespaceID {
+			t.ids = append(t.ids, id)
+			t.specifiers = append(t.specifiers, "."+name)
+		}
+		return t, nil
+	}
+
+	vt := p.getVirtualTabler()
+	if zs.Database != "" {
+		dbDesc, err := MustGetDatabaseDesc(ctx, p.txn, vt, string(zs.Database))
+		if err != nil {
+			return zoneTarget{}, err
+		}
+		if isVirtualDescriptor(dbDesc) {
+			return zoneTarget{}, errors.Errorf("%q is a virtual database and has no zone config", dbDesc.Name)
+		}
+		if err := checkPrivilege(dbDesc); err != nil {
+			return zoneTarget{}, err
+		}
+		t.ids = append(t.ids, dbDesc.ID)
+		t.specifiers = append(t.specifiers, dbDesc.Name)
+		return t, nil
+	}
+
+	var tn *parser.TableName
+	var err error
+	if zs.Index != nil {
+		tn, err = p.expandIndexName(ctx, zs.Index)
+	} else {
+		tn, err = p.normalizeTableName(ctx, zs.Table)
+	}
+	if err != nil {
+		return zoneTarget{}, err
+	}
+	dbDesc, err := MustGetDatabaseDesc(ctx, p.txn, vt, tn.Database())
+	if err != nil {
+		return zoneTarget{}, err
+	}
+	tableDesc, err := getTableDesc(ctx, p.txn, vt, tn)
+	if err != nil {
+		return zoneTarget{}, err
+	}
+	if tableDesc == nil {
+		return zoneTarget{}, sqlbase.NewUndefinedTableError(tn.String())
+	}
+	if tableDesc.IsVirtualTable() {
+		return zoneTarget{}, errors.Errorf("%q is a virtual table and has no zone config", tn)
+	}
+	if err := checkPrivilege(tableDesc); err != nil {
+		return zoneTarget{}, err
+	}
+	tableSpecifier := dbDesc.Name + "." + tableDesc.Name
+	t.ids = append(t.ids, dbDesc.ID, tableDesc.ID)
+	t.specifiers = append(t.specifiers, dbDesc.Name, tableSpecifier)
+
+	if zs.Index != nil {
+		index, dropped, err := tableDesc.FindIndexByName(zs.Index.Index)
+		if err != nil {
+			return zoneTarget{}, err
+		}
+		if dropped {
+			return zoneTarget{}, fmt.Errorf("index %q being dropped", zs.Index.Index)
+		}
+		t.index = &index
+		t.indexSpecifier = tableSpecifier + "@" + index.Name
+	}
+	return t, nil
+}
+
+// SetZoneConfig creates, updates or removes the zone config of a database,
+// table, index or built-in zone. The YAML config is merged into the zone config
+// the target currently inherits; NULL removes the target's zone config.
+// Privileges: CREATE on the database or table; root for built-in zones and the
+// system database.
+func (p *planner) SetZoneConfig(ctx context.Context, n *parser.SetZoneConfig) (planNode, error) {
+	typedConfig, err := parser.TypeCheckAndRequire(
+		n.YAMLConfig, &p.semaCtx, parser.TypeString, "CONFIGURE ZONE",
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n.NamedZone != "" {
+		if err := p.RequireSuperUser("configure built-in zones"); err != nil {
+			return nil, err
+		}
+	}
+	t, err := p.resolveZone(ctx, n.ZoneSpecifier, func(desc sqlbase.DescriptorProto) error {
+		if desc.GetID() == keys.SystemDatabaseID {
+			return p.RequireSuperUser("configure the system database")
+		}
+		if tableDesc, ok := desc.(*sqlbase.TableDescriptor); ok && tableDesc.ParentID == keys.SystemDatabaseID {
+			return errors.New("setting zone configs for individual system tables is not supported; " +
+				"try setting your config on the entire \"system\" database instead")
+		}
+		return p.CheckPrivilege(desc, privilege.CREATE)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := typedConfig.Eval(&p.evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	remove := d == parser.DNull
+	var yamlConfig []byte
+	if !remove {
+		yamlConfig = []byte(parser.AsStringWithFlags(d, parser.FmtBareStrings))
+	}
+
+	id := t.id()
+	pos, zone, err := t.nearestZone(ctx, p.txn)
+	if err != nil {
+		return nil, err
+	}
+	inherited := t.ids[pos] != id
+	if inherited {
+		// Index GC policies only make sense for the table they were set on.
+		zone.IndexGC = nil
+	}
+
+	if t.index != nil {
+		indexID := uint32(t.index.ID)
+		policy := zone.GCPolicyForIndex(indexID)
+		policies := zone.IndexGC[:0]
+		for _, indexPolicy := range zone.IndexGC {
+			if indexPolicy.IndexID != indexID {
+				policies = append(policies, indexPolicy)
+			}
+		}
+		if remove && (inherited || len(policies) == len(zone.IndexGC)) {
+			// There is no policy to remove.
+			return &emptyNode{}, nil
+		}
+		zone.IndexGC = policies
+		if !remove {
+			if err := unmarshalIndexZoneConfig(yamlConfig, &policy); err != nil {
+				return nil, err
+			}
+			zone.IndexGC = append(zone.IndexGC, config.IndexGCPolicy{IndexID: indexID, GC: policy})
+		}
+	} else if remove {
+		if id == keys.RootNamespaceID {
+			return nil, errors.New("unable to remove the default zone")
+		}
+		if err := p.deleteZoneConfig(ctx, id); err != nil {
+			return nil, err
+		}
+		return &emptyNode{}, nil
+	} else if err := yaml.Unmarshal(yamlConfig, &zone); err != nil {
+		return nil, errors.Wrap(err, "could not parse zone config")
+	}
+
+	if err := zone.Validate(); err != nil {
+		return nil, err
+	}
+	if err := p.writeZoneConfig(ctx, id, zone); err != nil {
+		return nil, err
+	}
+	return &emptyNode{}, nil
+}
+
+// unmarshalIndexZoneConfig merges the YAML config of an index into its GC
+// policy. Only the gc field can be set for an index.
+func unmarshalIndexZoneConfig(yamlConfig []byte, policy *config.GCPolicy) error {
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(yamlConfig, &fields); err != nil {
+		return errors.Wrap(err, "could not parse zone config")
+	}
+	for field := range fields {
+		if field != "gc" {
+			return errors.Errorf("only the gc field can be configured for an index, found %q", field)
+		}
+	}
+	indexZone := struct{ GC *config.GCPolicy }{GC: policy}
+	if err := yaml.Unmarshal(yamlConfig, &indexZone); err != nil {
+		return errors.Wrap(err, "could not parse zone config")
+	}
+	return nil
+}
+
+func (p *planner) writeZoneConfig(ctx context.Context, id sqlbase.ID, zone config.ZoneConfig) error {
+	buf, err := protoutil.Marshal(&zone)
+	if err != nil {
+		return err
+	}
+	ie := InternalExecutor{LeaseManager: p.LeaseMgr()}
+	_, err = ie.ExecuteStatementInTransaction(
+		ctx, "set-zone", p.txn, "UPSERT INTO system.zones (id, config) VALUES ($1, $2)", id, buf,
+	)
+	return err
+}
+
+func (p *planner) deleteZoneConfig(ctx context.Context, id sqlbase.ID) error {
+	ie := InternalExecutor{LeaseManager: p.LeaseMgr()}
+	_, err := ie.ExecuteStatementInTransaction(
+		ctx, "remove-zone", p.txn, "DELETE FROM system.zones WHERE id = $1", id,
+	)
+	return err
+}
+
+var showZoneConfigColumns = sqlbase.ResultColumns{
+	{Name: "id", Typ: parser.TypeInt},
+	{Name: "cli_specifier", Typ: parser.TypeString},
+	{Name: "config_yaml", Typ: parser.TypeString},
+}
+
+// ShowZoneConfig returns the zone config that applies to a database, table,
+// index or built-in zone, along with the zone it is inherited from, or all the
+// zone configs.
+// Privileges: Any privilege on the database or table.
+func (p *planner) ShowZoneConfig(ctx context.Context, n *parser.ShowZoneConfig) (planNode, error) {
+	return &delayedNode{
+		name:    n.String(),
+		columns: showZoneConfigColumns,
+		constructor: func(ctx context.Context, p *planner) (planNode, error) {
+			v := p.newContainerValuesNode(showZoneConfigColumns, 0)
+			var err error
+			if n.ZoneSpecifier == (parser.ZoneSpecifier{}) {
+				err = p.showAllZoneConfigs(ctx, v)
+			} else {
+				err = p.showZoneConfig(ctx, v, n.ZoneSpecifier)
+			}
+			if err != nil {
+				v.Close(ctx)
+				return nil, err
+			}
+			return v, nil
+		},
+	}, nil
+}
+
+func (p *planner) showZoneConfig(
+	ctx context.Context, v *valuesNode, zs parser.ZoneSpecifier,
+) error {
+	t, err := p.resolveZone(ctx, zs, p.anyPrivilege)
+	if err != nil {
+		return err
+	}
+	pos, zone, err := t.nearestZone(ctx, p.txn)
+	if err != nil {
+		return err
+	}
+	id, specifier := t.ids[pos], t.specifiers[pos]
+	if t.index != nil {
+		indexID := uint32(t.index.ID)
+		if id == t.id() {
+			for _, policy := range zone.IndexGC {
+				if policy.IndexID == indexID {
+					specifier = t.indexSpecifier
+				}
+			}
+		}
+		zone.GC = zone.GCPolicyForIndex(indexID)
+		zone.IndexGC = nil
+	}
+	return addZoneConfigRow(ctx, v, id, specifier, zone)
+}
+
+func (p *planner) showAllZoneConfigs(ctx context.Context, v *valuesNode) error {
+	ie := InternalExecutor{LeaseManager: p.LeaseMgr()}
+	rows, err := ie.QueryRowsInTransaction(
+		ctx, "show-zones", p.txn, "SELECT id, config FROM system.zones ORDER BY id",
+	)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		id := sqlbase.ID(parser.MustBeDInt(row[0]))
+		specifier, ok, err := p.zoneSpecifierForID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		var zone config.ZoneConfig
+		if err := zone.Unmarshal([]byte(*row[1].(*parser.DBytes))); err != nil {
+			return err
+		}
+		if err := addZoneConfigRow(ctx, v, id, specifier, zone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zoneSpecifierForID returns the name of the zone with the given ID, or false
+// if the zone belongs to a dropped or invisible database or table.
+func (p *planner) zoneSpecifierForID(ctx context.Context, id sqlbase.ID) (string, bool, error) {
+	for name, zoneID := range namedZones {
+		if zoneID == id {
+			return "." + name, true, nil
+		}
+	}
+	desc := &sqlbase.Descriptor{}
+	if err := p.txn.GetProto(ctx, sqlbase.MakeDescMetadataKey(id), desc); err != nil {
+		return "", false, err
+	}
+	if dbDesc := desc.GetDatabase(); dbDesc != nil {
+		return dbDesc.Name, p.anyPrivilege(dbDesc) == nil, nil
+	}
+	tableDesc := desc.GetTable()
+	if tableDesc == nil || tableDesc.Dropped() || p.anyPrivilege(tableDesc) != nil {
+		return "", false, nil
+	}
+	dbDesc, err := sqlbase.GetDatabaseDescFromID(ctx, p.txn, tableDesc.ParentID)
+	if err != nil {
+		return "", false, err
+	}
+	return dbDesc.Name + "." + tableDesc.Name, true, nil
+}
+
+func addZoneConfigRow(
+	ctx context.Context, v *valuesNode, id sqlbase.ID, specifier string, zone config.ZoneConfig,
+) error {
+	yamlConfig, err := yaml.Marshal(zone)
+	if err != nil {
+		return err
+	}
+	_, err = v.rows.AddRow(ctx, parser.Datums{
+		parser.NewDInt(parser.DInt(id)),
+		parser.NewDString(specifier),
+		parser.NewDString(string(yamlConfig)),
+	})
+	return err
+}
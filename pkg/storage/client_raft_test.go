@@ -1128,6 +1128,86 @@ func TestStoreRangeUpReplicate(t *testing.T) {
 	}
 }
 
+// delegatedSnapshotFailingHandler fails the requests to send delegated
+// snapshots, and passes the other messages to the store.
+type delegatedSnapshotFailingHandler struct {
+	storage.RaftMessageHandler
+}
+
+func (delegatedSnapshotFailingHandler) HandleDelegatedSnapshot(
+	_ context.Context, _ *storage.SnapshotRequest_Header,
+) error {
+	return errors.New("injected delegation failure")
+}
+
+// TestStoreRangeUpReplicateDelegated verifies that the snapshots sent to add
+// replicas are sent by the follower closest to the recipient, and applied,
+// and that the leader sends them itself when the delegation fails.
+func TestStoreRangeUpReplicateDelegated(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer storage.SetSnapshotDelegationEnabled(true)()
+
+	region := func(r string) roachpb.Locality {
+		return roachpb.Locality{Tiers: []roachpb.Tier{{Key: "region", Value: r}}}
+	}
+	sc := storage.TestStoreConfig(nil)
+	sc.TestingKnobs.DisableSplitQueue = true
+	mtc := &multiTestContext{
+		storeConfig: &sc,
+		localities:  []roachpb.Locality{region("us"), region("eu"), region("eu"), region("eu")},
+	}
+	defer mtc.Stop()
+	mtc.Start(t, 4)
+	mtc.initGossipNetwork()
+
+	key := roachpb.Key("a")
+	incArgs := incrementArgs(key, 5)
+	if _, err := client.SendWrapped(context.Background(), rg1(mtc.stores[0]), incArgs); err != nil {
+		t.Fatal(err)
+	}
+
+	delegated := func() []int64 {
+		var counts []int64
+		for _, s := range mtc.stores {
+			counts = append(counts, s.Metrics().RangeSnapshotsDelegated.Count())
+		}
+		return counts
+	}
+
+	// The first replica in eu is sent its snapshot by the leader, which has no
+	// follower closer to it.
+	const rangeID = roachpb.RangeID(1)
+	mtc.replicateRange(rangeID, 1)
+	mtc.waitForValues(key, []int64{5, 5, 0, 0})
+	if counts := delegated(); !reflect.DeepEqual(counts, []int64{0, 0, 0, 0}) {
+		t.Fatalf("expected no delegated snapshots, got %v", counts)
+	}
+
+	// The second one is sent its snapshot by the first one.
+	mtc.replicateRange(rangeID, 2)
+	mtc.waitForValues(key, []int64{5, 5, 5, 0})
+	if counts := delegated(); !reflect.DeepEqual(counts, []int64{0, 1, 0, 0}) {
+		t.Fatalf("expected a snapshot delegated to the second store, got %v", counts)
+	}
+
+	// The leader falls back to sending the snapshot itself when the delegate
+	// fails to send it.
+	for _, s := range mtc.stores[1:3] {
+		mtc.transport.Listen(s.Ident.StoreID, delegatedSnapshotFailingHandler{
+			RaftMessageHandler: s,
+		})
+	}
+	generated := mtc.stores[0].Metrics().RangeSnapshotsGenerated.Count()
+	mtc.replicateRange(rangeID, 3)
+	mtc.waitForValues(key, []int64{5, 5, 5, 5})
+	if counts := delegated(); !reflect.DeepEqual(counts, []int64{0, 1, 0, 0}) {
+		t.Fatalf("expected no other delegated snapshot, got %v", counts)
+	}
+	if g := mtc.stores[0].Metrics().RangeSnapshotsGenerated.Count(); g != generated+1 {
+		t.Fatalf("expected the leader to generate a snapshot, got %d snapshots (was %d)", g, generated)
+	}
+}
+
 // TestStoreRangeCorruptionChangeReplicas verifies that the replication queue
 // will notice corrupted replicas and replace them.
 func TestStoreRangeCorruptionChangeReplicas(t *testing.T) {
@@ -2608,6 +2688,12 @@ func (errorChannelTestHandler) HandleSnapshot(
 	panic("unimplemented")
 }
 
+func (errorChannelTestHandler) HandleDelegatedSnapshot(
+	_ context.Context, _ *storage.SnapshotRequest_Header,
+) error {
+	panic("unimplemented")
+}
+
 func TestReplicateRemovedNodeDisruptiveElection(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -1007,6 +1007,87 @@ func TestLeaseExtensionNotBlockedByRead(t *testing.T) {
 	}
 }
 
+// TestFollowerReadBelowClosedTimestamp verifies that a follower serves the
+// reads at or below its closed timestamp, and only those, and that the lease
+// holder doesn't write at or below the timestamps it closed.
+func TestFollowerReadBelowClosedTimestamp(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	const target = 100 * time.Millisecond
+	defer storage.SetClosedTimestampTargetDuration(target)()
+
+	mtc := &multiTestContext{}
+	mtc.manualClock = hlc.NewManualClock(time.Second.Nanoseconds())
+	defer mtc.Stop()
+	mtc.Start(t, 2)
+
+	key := roachpb.Key("a")
+	if _, pErr := client.SendWrapped(context.Background(), mtc.distSenders[0], putArgs(key, []byte("value"))); pErr != nil {
+		t.Fatal(pErr)
+	}
+	rangeID := mtc.stores[0].LookupReplica(roachpb.RKey(key), nil).RangeID
+	mtc.replicateRange(rangeID, 1)
+	replica1 := mtc.stores[1].LookupReplica(roachpb.RKey(key), nil)
+	replica1Desc, err := replica1.GetReplicaDescriptor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTS := mtc.clock.Now()
+
+	// Write again once the first write lags behind the present by more than
+	// the target duration: the proposal of the second write closes a
+	// timestamp above the first.
+	mtc.manualClock.Increment((2 * target).Nanoseconds())
+	if _, pErr := client.SendWrapped(context.Background(), mtc.distSenders[0], putArgs(roachpb.Key("b"), []byte("value"))); pErr != nil {
+		t.Fatal(pErr)
+	}
+	var closed hlc.Timestamp
+	testutils.SucceedsSoon(t, func() error {
+		if closed = replica1.ClosedTimestamp(); !writeTS.Less(closed) {
+			return errors.Errorf("closed timestamp %s not above %s", closed, writeTS)
+		}
+		return nil
+	})
+
+	sendRead := func(ts hlc.Timestamp) (roachpb.Response, *roachpb.Error) {
+		return client.SendWrappedWith(
+			context.Background(),
+			mtc.senders[1],
+			roachpb.Header{Replica: replica1Desc, Timestamp: ts},
+			getArgs(key),
+		)
+	}
+
+	// The follower serves a read at the closed timestamp, and sees the first
+	// write.
+	reply, pErr := sendRead(closed)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	if value := reply.(*roachpb.GetResponse).Value; value == nil {
+		t.Fatalf("expected a value for %s at %s", key, closed)
+	}
+
+	// It redirects a read above the closed timestamp to the lease holder.
+	if _, pErr := sendRead(closed.Next()); pErr == nil {
+		t.Fatalf("expected an error reading above %s", closed)
+	} else if _, ok := pErr.GetDetail().(*roachpb.NotLeaseHolderError); !ok {
+		t.Fatalf("expected %T, got %s", &roachpb.NotLeaseHolderError{}, pErr)
+	}
+
+	// The lease holder pushes a write at the closed timestamp above it.
+	var ba roachpb.BatchRequest
+	ba.RangeID = rangeID
+	ba.Timestamp = closed
+	ba.Add(putArgs(key, []byte("value2")))
+	br, pErr := mtc.stores[0].Send(context.Background(), ba)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	if !closed.Less(br.Timestamp) {
+		t.Fatalf("expected the write to be pushed above %s, got %s", closed, br.Timestamp)
+	}
+}
+
 // LeaseInfo runs a LeaseInfoRequest using the specified server.
 func LeaseInfo(
 	t *testing.T,
@@ -201,7 +201,10 @@ type multiTestContext struct {
 	// The per-store clocks slice normally contains aliases of
 	// multiTestContext.clock, but it may be populated before Start() to
 	// use distinct clocks per store.
-	clocks      []*hlc.Clock
+	clocks []*hlc.Clock
+	// localities may be populated before Start() to give the nodes of the
+	// stores a locality.
+	localities  []roachpb.Locality
 	engines     []engine.Engine
 	grpcServers []*grpc.Server
 	distSenders []*kv.DistSender
@@ -744,7 +747,10 @@ func (m *multiTestContext) addStore(idx int) {
 	cfg.NodeLiveness = m.nodeLivenesses[idx]
 	cfg.StorePool = m.storePools[idx]
 
-	store := storage.NewStore(cfg, eng, &roachpb.NodeDescriptor{NodeID: nodeID})
+	store := storage.NewStore(cfg, eng, &roachpb.NodeDescriptor{
+		NodeID:   nodeID,
+		Locality: m.locality(nodeID),
+	})
 	if needBootstrap {
 		if err := store.Bootstrap(roachpb.StoreIdent{
 			NodeID:  roachpb.NodeID(idx + 1),
@@ -833,9 +839,18 @@ func (m *multiTestContext) addStore(idx int) {
 func (m *multiTestContext) nodeDesc(nodeID roachpb.NodeID) *roachpb.NodeDescriptor {
 	addr := m.nodeIDtoAddrMu.nodeIDtoAddr[nodeID]
 	return &roachpb.NodeDescriptor{
-		NodeID:  nodeID,
-		Address: util.MakeUnresolvedAddr(addr.Network(), addr.String()),
+		NodeID:   nodeID,
+		Address:  util.MakeUnresolvedAddr(addr.Network(), addr.String()),
+		Locality: m.locality(nodeID),
+	}
+}
+
+// locality returns the locality of the node, if any.
+func (m *multiTestContext) locality(nodeID roachpb.NodeID) roachpb.Locality {
+	if idx := int(nodeID) - 1; idx < len(m.localities) {
+		return m.localities[idx]
 	}
+	return roachpb.Locality{}
 }
 
 // gossipNodeDesc adds the node descriptor to the gossip network.
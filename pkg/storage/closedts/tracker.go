@@ -0,0 +1,195 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package closedts tracks the closed timestamps of the ranges of a store. The
+// closed timestamp of a range is a timestamp at or below which no more writes
+// are accepted on the range: reads below it can be served by any replica
+// which has applied the log up to the point where it was closed, and the
+// changes below it are final for the consumers of the changes of the range.
+//
+// The lag of a closed timestamp behind the present bounds the staleness of
+// those reads and changes, so a range whose closed timestamp stops moving
+// forward is reported, along with the lag of the whole store.
+package closedts
+
+import (
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// TargetDuration is the lag behind the present at which the lease holders of
+// the ranges close timestamps.
+var TargetDuration = settings.RegisterNonNegativeDurationSetting(
+	"kv.closed_timestamp.target_duration",
+	"if nonzero, lease holders close timestamps lagging behind the present by this "+
+		"duration, which lets their followers serve reads at or below them",
+	0,
+)
+
+// lagThreshold is the lag beyond which a range is reported as lagging.
+var lagThreshold = settings.RegisterNonNegativeDurationSetting(
+	"kv.closed_timestamp.lag_threshold",
+	"lag of the closed timestamp of a range behind the present beyond which the range "+
+		"is reported as lagging (set to 0 to disable)",
+	time.Minute,
+)
+
+var (
+	metaClosedTimestampRanges = metric.Metadata{
+		Name: "kv.closed_timestamp.ranges",
+		Help: "Number of ranges with a closed timestamp"}
+	metaClosedTimestampMaxLag = metric.Metadata{
+		Name: "kv.closed_timestamp.max_lag_nanos",
+		Help: "Largest lag of the closed timestamp of a range behind the present"}
+	metaClosedTimestampLaggingRanges = metric.Metadata{
+		Name: "kv.closed_timestamp.lagging_ranges",
+		Help: "Number of ranges whose closed timestamp lags behind the present by more than " +
+			"kv.closed_timestamp.lag_threshold"}
+)
+
+// Metrics is the set of metrics for the closed timestamps of a store. They
+// are computed by Tracker.Update.
+type Metrics struct {
+	Ranges        *metric.Gauge
+	MaxLag        *metric.Gauge
+	LaggingRanges *metric.Gauge
+}
+
+func makeMetrics() Metrics {
+	return Metrics{
+		Ranges:        metric.NewGauge(metaClosedTimestampRanges),
+		MaxLag:        metric.NewGauge(metaClosedTimestampMaxLag),
+		LaggingRanges: metric.NewGauge(metaClosedTimestampLaggingRanges),
+	}
+}
+
+// A Tracker keeps track of the closed timestamps of the ranges of a store. It
+// is safe for concurrent use.
+type Tracker struct {
+	metrics Metrics
+
+	mu struct {
+		syncutil.Mutex
+		closed map[roachpb.RangeID]hlc.Timestamp
+		// lagging are the ranges found lagging by the last Update, which are
+		// only reported again once they caught up.
+		lagging map[roachpb.RangeID]struct{}
+	}
+}
+
+// NewTracker returns a Tracker which doesn't track any range yet.
+func NewTracker() *Tracker {
+	t := &Tracker{metrics: makeMetrics()}
+	t.mu.closed = make(map[roachpb.RangeID]hlc.Timestamp)
+	t.mu.lagging = make(map[roachpb.RangeID]struct{})
+	return t
+}
+
+// Metrics returns the metrics of the tracker.
+func (t *Tracker) Metrics() *Metrics {
+	return &t.metrics
+}
+
+// Forward records that the range closed the given timestamp. The closed
+// timestamp of a range never regresses: Forward is a no-op if it is already
+// at or above ts.
+func (t *Tracker) Forward(rangeID roachpb.RangeID, ts hlc.Timestamp) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	closed := t.mu.closed[rangeID]
+	if closed.Forward(ts) {
+		t.mu.closed[rangeID] = closed
+	}
+}
+
+// Get returns the closed timestamp of the range, if it has one.
+func (t *Tracker) Get(rangeID roachpb.RangeID) (hlc.Timestamp, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	closed, ok := t.mu.closed[rangeID]
+	return closed, ok
+}
+
+// Lag returns the lag of the closed timestamp of the range behind now, if
+// the range has a closed timestamp.
+func (t *Tracker) Lag(rangeID roachpb.RangeID, now hlc.Timestamp) (time.Duration, bool) {
+	closed, ok := t.Get(rangeID)
+	if !ok {
+		return 0, false
+	}
+	return lag(closed, now), true
+}
+
+// Remove stops tracking the range, e.g. when its replica is removed from the
+// store.
+func (t *Tracker) Remove(rangeID roachpb.RangeID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.mu.closed, rangeID)
+	delete(t.mu.lagging, rangeID)
+}
+
+// Update computes the lags of the closed timestamps behind now, updates the
+// metrics, and logs a warning for each range which started lagging beyond
+// kv.closed_timestamp.lag_threshold since the last call. It returns the
+// ranges lagging beyond the threshold, in order.
+func (t *Tracker) Update(ctx context.Context, now hlc.Timestamp) []roachpb.RangeID {
+	threshold := lagThreshold.Get()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var maxLag time.Duration
+	var lagging []roachpb.RangeID
+	for rangeID, closed := range t.mu.closed {
+		l := lag(closed, now)
+		if l > maxLag {
+			maxLag = l
+		}
+		if threshold == 0 || l <= threshold {
+			delete(t.mu.lagging, rangeID)
+			continue
+		}
+		lagging = append(lagging, rangeID)
+		if _, ok := t.mu.lagging[rangeID]; !ok {
+			t.mu.lagging[rangeID] = struct{}{}
+			log.Warningf(ctx, "r%d: closed timestamp %s lags behind the present by %s", rangeID, closed, l)
+		}
+	}
+	sort.Slice(lagging, func(i, j int) bool {
+		return lagging[i] < lagging[j]
+	})
+
+	t.metrics.Ranges.Update(int64(len(t.mu.closed)))
+	t.metrics.MaxLag.Update(maxLag.Nanoseconds())
+	t.metrics.LaggingRanges.Update(int64(len(lagging)))
+	return lagging
+}
+
+// lag returns the lag of the closed timestamp behind now, or 0 if it is
+// ahead of now.
+func lag(closed, now hlc.Timestamp) time.Duration {
+	if l := time.Duration(now.WallTime - closed.WallTime); l > 0 {
+		return l
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package closedts
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestTrackerForward(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tr := NewTracker()
+	if _, ok := tr.Get(1); ok {
+		t.Fatal("expected no closed timestamp for an untracked range")
+	}
+	tr.Forward(1, hlc.Timestamp{WallTime: 10})
+	// The closed timestamp never regresses.
+	tr.Forward(1, hlc.Timestamp{WallTime: 5})
+	if closed, _ := tr.Get(1); closed != (hlc.Timestamp{WallTime: 10}) {
+		t.Errorf("expected the closed timestamp to be 10, got %s", closed)
+	}
+	tr.Forward(1, hlc.Timestamp{WallTime: 10, Logical: 1})
+	if closed, _ := tr.Get(1); closed != (hlc.Timestamp{WallTime: 10, Logical: 1}) {
+		t.Errorf("expected the closed timestamp to be 10,1, got %s", closed)
+	}
+	if l, ok := tr.Lag(1, hlc.Timestamp{WallTime: 25}); !ok || l != 15 {
+		t.Errorf("expected a lag of 15ns, got %s (%t)", l, ok)
+	}
+	if l, _ := tr.Lag(1, hlc.Timestamp{WallTime: 5}); l != 0 {
+		t.Errorf("expected no lag behind an earlier timestamp, got %s", l)
+	}
+
+	tr.Remove(1)
+	if _, ok := tr.Get(1); ok {
+		t.Fatal("expected no closed timestamp for a removed range")
+	}
+}
+
+func TestTrackerUpdate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer settings.TestingSetDuration(&lagThreshold, 10*time.Second)()
+
+	ctx := context.Background()
+	tr := NewTracker()
+	now := hlc.Timestamp{WallTime: (time.Minute).Nanoseconds()}
+	behind := func(d time.Duration) hlc.Timestamp {
+		return hlc.Timestamp{WallTime: now.WallTime - d.Nanoseconds()}
+	}
+	tr.Forward(1, behind(time.Second))
+	tr.Forward(2, behind(20*time.Second))
+	tr.Forward(3, behind(30*time.Second))
+
+	check := func(expLagging []roachpb.RangeID, expMaxLag time.Duration) {
+		if lagging := tr.Update(ctx, now); !reflect.DeepEqual(lagging, expLagging) {
+			t.Errorf("expected lagging ranges %v, got %v", expLagging, lagging)
+		}
+		m := tr.Metrics()
+		if a, e := m.MaxLag.Value(), expMaxLag.Nanoseconds(); a != e {
+			t.Errorf("expected a max lag of %d, got %d", e, a)
+		}
+		if a, e := m.LaggingRanges.Value(), int64(len(expLagging)); a != e {
+			t.Errorf("expected %d lagging ranges, got %d", e, a)
+		}
+	}
+	check([]roachpb.RangeID{2, 3}, 30*time.Second)
+	if a, e := tr.Metrics().Ranges.Value(), int64(3); a != e {
+		t.Errorf("expected %d ranges, got %d", e, a)
+	}
+
+	// Range 2 catches up, range 3 goes away.
+	tr.Forward(2, now)
+	tr.Remove(3)
+	check(nil, time.Second)
+
+	// Without a threshold, no range is lagging.
+	tr.Forward(2, behind(time.Hour))
+	defer settings.TestingSetDuration(&lagThreshold, 0)()
+	check(nil, time.Second)
+}
@@ -35,16 +35,24 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/search"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/uuid"
 )
 
 const (
-	// gcQueueTimerDuration is the duration between GCs of queued replicas.
-	gcQueueTimerDuration = 1 * time.Second
+	// gcQueueTimerDuration is the initial duration between GCs of queued
+	// replicas. The duration is then tuned, within [gcQueueMinTimerDuration,
+	// gcQueueMaxTimerDuration], so that GC takes gcQueueTargetDutyCycle of the
+	// time while replicas are queued.
+	gcQueueTimerDuration    = 1 * time.Second
+	gcQueueMinTimerDuration = 100 * time.Millisecond
+	gcQueueMaxTimerDuration = 10 * time.Second
+	gcQueueTargetDutyCycle  = 0.05
 	// intentAgeNormalization is the average age of outstanding intents
 	// which amount to a score of "1" added to total replica priority.
 	intentAgeNormalization = 24 * time.Hour // 1 day
@@ -94,11 +102,26 @@ const (
 // single priority. If any task is overdue, shouldQueue returns true.
 type gcQueue struct {
 	*baseQueue
+	// pacer tunes the duration between GCs of queued replicas.
+	pacer *search.Tuner
 }
 
 // newGCQueue returns a new instance of gcQueue.
 func newGCQueue(store *Store, gossip *gossip.Gossip) *gcQueue {
-	gcq := &gcQueue{}
+	pacer, err := search.NewTuner(search.TunerConfig{
+		Min:     float64(gcQueueMinTimerDuration),
+		Max:     float64(gcQueueMaxTimerDuration),
+		Initial: float64(gcQueueTimerDuration),
+		Target:  gcQueueTargetDutyCycle,
+		// The longer the duration between GCs, the smaller the fraction of time
+		// spent processing.
+		Increasing: false,
+		Metric:     store.metrics.GCQueueTimerNanos,
+	})
+	if err != nil {
+		panic(err)
+	}
+	gcq := &gcQueue{pacer: pacer}
 	gcq.baseQueue = newBaseQueue(
 		"gc", gcq, store, gossip,
 		queueConfig{
@@ -179,12 +202,35 @@ func makeGCQueueScore(
 	// have slightly different priorities and even symmetrical workloads don't
 	// trigger GC at the same time.
 	r := makeGCQueueScoreImpl(
-		ctx, int64(desc.RangeID), now, ms, zone.GC.TTLSeconds,
+		ctx, int64(desc.RangeID), now, ms, zone.MinGCTTLSeconds(),
 	)
 	r.LikelyLastGC = time.Duration(now.WallTime - gcThreshold.Add(r.TTL.Nanoseconds(), 0).WallTime)
 	return r
 }
 
+// EstimateReclaimableBytes estimates how many of the non-live bytes of a
+// range a GC run with the given TTL would remove. The stats only record the
+// total age of the non-live bytes, so the estimate assumes that their ages are
+// spread evenly between zero and twice their average age.
+func EstimateReclaimableBytes(ms enginepb.MVCCStats, nowNanos int64, ttlSeconds int32) int64 {
+	if ttlSeconds <= 0 {
+		return 0
+	}
+	gcBytes := ms.GCBytes()
+	if gcBytes <= 0 {
+		return 0
+	}
+	avgAge := float64(ms.GCByteAge(nowNanos)) / float64(gcBytes)
+	if avgAge <= 0 {
+		return 0
+	}
+	fraction := 1 - float64(ttlSeconds)/(2*avgAge)
+	if fraction <= 0 {
+		return 0
+	}
+	return int64(fraction * float64(gcBytes))
+}
+
 // makeGCQueueScoreImpl is used to compute when to trigger the GC Queue. It's
 // important that we don't queue a replica before a relevant amount of data is
 // actually deletable, or the queue might run in a tight loop. To this end, we
@@ -581,7 +627,7 @@ func (gcq *gcQueue) processImpl(
 		return errors.Errorf("could not find zone config for range %s: %s", repl, err)
 	}
 
-	gcKeys, info, err := RunGC(ctx, desc, snap, now, zone.GC,
+	gcKeys, info, err := RunGC(ctx, desc, snap, now, zone,
 		func(now hlc.Timestamp, txn *roachpb.Transaction, typ roachpb.PushTxnType) {
 			pushTxn(ctx, gcq.store.DB(), now, txn, typ)
 		},
@@ -658,7 +704,9 @@ type GCInfo struct {
 	ResolveTotal int
 	// ResolveErrors is the number of successful intent resolutions.
 	ResolveSuccess int
-	// Threshold is the computed expiration timestamp. Equal to `Now - Policy`.
+	// Threshold is the computed expiration timestamp. Equal to `Now - Policy`,
+	// or to the newest such timestamp among the index GC policies of the zone
+	// which garbage collected a version.
 	Threshold hlc.Timestamp
 }
 
@@ -683,17 +731,31 @@ type lockableGCInfo struct {
 	GCInfo
 }
 
+// indexIDForKey returns the ID of the index that the given table key belongs
+// to, or false if the key isn't a table key.
+func indexIDForKey(key roachpb.Key) (uint32, bool) {
+	rest, _, err := keys.DecodeTablePrefix(key)
+	if err != nil {
+		return 0, false
+	}
+	_, indexID, err := encoding.DecodeUvarintAscending(rest)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(indexID), true
+}
+
 // RunGC runs garbage collection for the specified descriptor on the provided
-// Engine (which is not mutated). It uses the provided functions pushTxnFn and
-// resolveIntentsFn to clarify the true status of and clean up after encountered
-// transactions. It returns a slice of gc'able keys from the data, transaction,
-// and abort spans.
+// Engine (which is not mutated), using the GC policies of the given zone. It
+// uses the provided functions pushTxnFn and resolveIntentsFn to clarify the
+// true status of and clean up after encountered transactions. It returns a
+// slice of gc'able keys from the data, transaction, and abort spans.
 func RunGC(
 	ctx context.Context,
 	desc *roachpb.RangeDescriptor,
 	snap engine.Reader,
 	now hlc.Timestamp,
-	policy config.GCPolicy,
+	zone config.ZoneConfig,
 	pushTxnFn pushFunc,
 	resolveIntentsFn resolveFunc,
 ) ([]roachpb.GCRequest_GCKey, GCInfo, error) {
@@ -702,7 +764,7 @@ func RunGC(
 	defer iter.Close()
 
 	var infoMu = lockableGCInfo{}
-	infoMu.Policy = policy
+	infoMu.Policy = zone.GC
 	infoMu.Now = now
 
 	{
@@ -734,8 +796,29 @@ func RunGC(
 	txnExp.WallTime -= txnCleanupThreshold.Nanoseconds()
 	abortSpanGCThreshold := now.Add(-int64(abortCacheAgeThreshold), 0)
 
-	gc := engine.MakeGarbageCollector(now, policy)
+	gc := engine.MakeGarbageCollector(now, zone.GC)
 	infoMu.Threshold = gc.Threshold
+	// Indexes with their own GC policy get their own collector. The range's GC
+	// threshold, below which reads are rejected, is forwarded to the threshold
+	// of every index collector which garbage collects a version, so that no
+	// read is served from the versions it deleted.
+	indexGCs := make(map[uint32]engine.GarbageCollector, len(zone.IndexGC))
+	for _, p := range zone.IndexGC {
+		indexGCs[p.IndexID] = engine.MakeGarbageCollector(now, p.GC)
+	}
+	gcForKey := func(key roachpb.Key) engine.GarbageCollector {
+		if len(indexGCs) == 0 {
+			return gc
+		}
+		indexID, ok := indexIDForKey(key)
+		if !ok {
+			return gc
+		}
+		if indexGC, found := indexGCs[indexID]; found {
+			return indexGC
+		}
+		return gc
+	}
 	infoMu.TxnSpanGCThreshold = txnExp
 
 	var gcKeys []roachpb.GCRequest_GCKey
@@ -777,7 +860,9 @@ func RunGC(
 					startIdx = 2
 				}
 				// See if any values may be GC'd.
-				if gcTS := gc.Filter(keys[startIdx:], vals[startIdx:]); gcTS != (hlc.Timestamp{}) {
+				keyGC := gcForKey(expBaseKey)
+				if gcTS := keyGC.Filter(keys[startIdx:], vals[startIdx:]); gcTS != (hlc.Timestamp{}) {
+					infoMu.Threshold.Forward(keyGC.Threshold)
 					// TODO(spencer): need to split the requests up into
 					// multiple requests in the event that more than X keys
 					// are added to the request.
@@ -878,10 +963,15 @@ func RunGC(
 	return gcKeys, infoMu.GCInfo, nil
 }
 
-// timer returns a constant duration to space out GC processing
-// for successive queued replicas.
-func (*gcQueue) timer(_ time.Duration) time.Duration {
-	return gcQueueTimerDuration
+// timer returns the duration to space out GC processing for successive queued
+// replicas, tuned with the time spent processing the last one.
+func (gcq *gcQueue) timer(duration time.Duration) time.Duration {
+	timer := gcq.pacer.Value()
+	if duration > 0 {
+		dutyCycle := float64(duration) / (float64(duration) + timer)
+		timer = gcq.pacer.Update(dutyCycle)
+	}
+	return time.Duration(timer)
 }
 
 // purgatoryChan returns nil.
@@ -28,12 +28,14 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/pkg/config"
 	"github.com/cockroachdb/cockroach/pkg/keys"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
@@ -984,3 +986,79 @@ func TestChunkGCRequest(t *testing.T) {
 		t.Fatalf("expected GC Request's batch size smaller than %v, but got %v", gcChunkKeySize, size)
 	}
 }
+
+// TestRunGCIndexPolicies verifies that the GC policies of indexes override the
+// zone's policy and that the range's GC threshold is the newest threshold
+// below which versions were garbage collected.
+func TestRunGCIndexPolicies(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := context.Background()
+	eng := engine.NewInMem(roachpb.Attributes{}, 1<<20)
+	defer eng.Close()
+
+	tablePrefix := roachpb.Key(keys.MakeTablePrefix(keys.MaxReservedDescID + 1))
+	indexKey := func(indexID uint64) roachpb.Key {
+		key := encoding.EncodeUvarintAscending(append(roachpb.Key(nil), tablePrefix...), indexID)
+		return encoding.EncodeStringAscending(key, "a")
+	}
+	const second = int64(time.Second)
+	for _, indexID := range []uint64{1, 2} {
+		for _, wallTime := range []int64{10 * second, 20 * second, 95 * second} {
+			if err := engine.MVCCPut(
+				ctx, eng, nil, indexKey(indexID), hlc.Timestamp{WallTime: wallTime},
+				roachpb.MakeValueFromString("v"), nil,
+			); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	desc := roachpb.RangeDescriptor{
+		RangeID:  1,
+		StartKey: roachpb.RKey(tablePrefix),
+		EndKey:   roachpb.RKey(tablePrefix.PrefixEnd()),
+	}
+	zone := config.ZoneConfig{
+		GC:      config.GCPolicy{TTLSeconds: 50},
+		IndexGC: []config.IndexGCPolicy{{IndexID: 2, GC: config.GCPolicy{TTLSeconds: 1}}},
+	}
+	now := hlc.Timestamp{WallTime: 100 * second}
+	gcKeys, info, err := RunGC(ctx, &desc, eng, now, zone,
+		func(hlc.Timestamp, *roachpb.Transaction, roachpb.PushTxnType) {},
+		func([]roachpb.Intent, bool, bool) error { return nil },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With a 50s TTL, the version at 20s is still visible at the threshold; with
+	// a 1s TTL, only the version at 95s is.
+	expected := []roachpb.GCRequest_GCKey{
+		{Key: indexKey(1), Timestamp: hlc.Timestamp{WallTime: 10 * second}},
+		{Key: indexKey(2), Timestamp: hlc.Timestamp{WallTime: 20 * second}},
+	}
+	if !reflect.DeepEqual(gcKeys, expected) {
+		t.Errorf("expected GC keys %v, got %v", expected, gcKeys)
+	}
+	if exp := (hlc.Timestamp{WallTime: 99 * second}); info.Threshold != exp {
+		t.Errorf("expected threshold %s, got %s", exp, info.Threshold)
+	}
+
+	// Neither an index which is never collected nor an index without versions
+	// to collect hold the threshold back or move it forward.
+	zone.IndexGC = append(zone.IndexGC,
+		config.IndexGCPolicy{IndexID: 3},
+		config.IndexGCPolicy{IndexID: 4, GC: config.GCPolicy{TTLSeconds: 1}},
+	)
+	zone.IndexGC[0].GC.TTLSeconds = 0
+	if _, info, err = RunGC(ctx, &desc, eng, now, zone,
+		func(hlc.Timestamp, *roachpb.Transaction, roachpb.PushTxnType) {},
+		func([]roachpb.Intent, bool, bool) error { return nil },
+	); err != nil {
+		t.Fatal(err)
+	}
+	if exp := (hlc.Timestamp{WallTime: 50 * second}); info.Threshold != exp {
+		t.Errorf("expected threshold %s, got %s", exp, info.Threshold)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/internal/client"
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/storage/closedts"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
@@ -173,6 +174,25 @@ func (s *Store) SetReplicaScannerActive(active bool) {
 	s.setScannerActive(active)
 }
 
+// SetSnapshotDelegationEnabled sets kv.snapshot_delegation.enabled and
+// returns a function restoring its previous value.
+func SetSnapshotDelegationEnabled(v bool) func() {
+	return settings.TestingSetBool(&snapshotDelegationEnabled, v)
+}
+
+// SetClosedTimestampTargetDuration sets kv.closed_timestamp.target_duration
+// and returns a function restoring its previous value.
+func SetClosedTimestampTargetDuration(d time.Duration) func() {
+	return settings.TestingSetDuration(&closedts.TargetDuration, d)
+}
+
+// ClosedTimestamp returns the closed timestamp of the replica.
+func (r *Replica) ClosedTimestamp() hlc.Timestamp {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mu.closedTimestamp
+}
+
 func (s *Store) SetRebalancesDisabled(v bool) {
 	var i int32
 	if v {
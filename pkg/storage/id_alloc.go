@@ -101,6 +101,10 @@ func (ia *idAllocator) start() {
 			for newValue <= int64(ia.minID) {
 				var err error
 				var res client.KeyValue
+				// The increment isn't transactional, so an RPC that ends
+				// ambiguously returns an AmbiguousResultError rather than
+				// being retried internally. Retrying here is safe: if the
+				// first increment was applied, its block of IDs is skipped.
 				for r := retry.Start(base.DefaultRetryOptions()); r.Next(); {
 					idKey := ia.idKey.Load().(roachpb.Key)
 					if err := ia.stopper.RunTask(ctx, "storage.idAllocator: allocating block", func(ctx context.Context) {
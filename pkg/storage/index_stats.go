@@ -0,0 +1,150 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine"
+	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+)
+
+// IndexStats are the MVCC stats of the keys of a table index. The keys of
+// the interleaved tables are attributed to the index of their ancestor.
+type IndexStats struct {
+	TableID, IndexID uint32
+	Stats            enginepb.MVCCStats
+}
+
+// ComputeIndexStats computes the MVCC stats of the keys of each table index
+// in the ranges whose lease is held by the store, so that summing them over
+// all the stores counts each range once. The stats of a range lying within a
+// single index are those of the range, less its range-local keys: only the
+// ranges which span several indexes are scanned.
+func (s *Store) ComputeIndexStats() ([]IndexStats, error) {
+	now := s.Clock().Now()
+	iter := s.engine.NewIterator(false)
+	defer iter.Close()
+
+	type index struct {
+		tableID, indexID uint32
+	}
+	stats := make(map[index]*enginepb.MVCCStats)
+	seek := func(key roachpb.Key) (roachpb.Key, error) {
+		iter.Seek(engine.MakeMVCCMetadataKey(key))
+		if ok, err := iter.Valid(); !ok {
+			return nil, err
+		}
+		return iter.Key().Key, nil
+	}
+	var err error
+	newStoreReplicaVisitor(s).Visit(func(repl *Replica) bool {
+		if !repl.ownsValidLease(now) {
+			return true // continue
+		}
+		desc := repl.Desc()
+		err = forEachIndexSpan(desc.StartKey.AsRawKey(), desc.EndKey.AsRawKey(), seek,
+			func(tableID, indexID uint32, start, end roachpb.Key) error {
+				var ms enginepb.MVCCStats
+				if start.Equal(desc.StartKey.AsRawKey()) && end.Equal(desc.EndKey.AsRawKey()) {
+					ms = repl.GetMVCCStats()
+					ms.SysBytes, ms.SysCount = 0, 0
+				} else {
+					var err error
+					ms, err = iter.ComputeStats(
+						engine.MakeMVCCMetadataKey(start), engine.MakeMVCCMetadataKey(end), now.WallTime)
+					if err != nil {
+						return err
+					}
+				}
+				if total, ok := stats[index{tableID, indexID}]; ok {
+					total.Add(ms)
+				} else {
+					stats[index{tableID, indexID}] = &ms
+				}
+				return nil
+			})
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]IndexStats, 0, len(stats))
+	for i, ms := range stats {
+		result = append(result, IndexStats{TableID: i.tableID, IndexID: i.indexID, Stats: *ms})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TableID != result[j].TableID {
+			return result[i].TableID < result[j].TableID
+		}
+		return result[i].IndexID < result[j].IndexID
+	})
+	return result, nil
+}
+
+// forEachIndexSpan calls fn, in order, with the span of the keys of each
+// table index found in [start, end), clamped to it. seek returns the first
+// key at or after its argument, or nil if there is none. The keys which
+// aren't in a table index are skipped.
+func forEachIndexSpan(
+	start, end roachpb.Key,
+	seek func(roachpb.Key) (roachpb.Key, error),
+	fn func(tableID, indexID uint32, start, end roachpb.Key) error,
+) error {
+	key := start
+	if key.Compare(keys.TableDataMin) < 0 {
+		key = keys.TableDataMin
+	}
+	if end.Compare(keys.TableDataMax) > 0 {
+		end = keys.TableDataMax
+	}
+	for key.Compare(end) < 0 {
+		next, err := seek(key)
+		if err != nil {
+			return err
+		}
+		if next == nil || next.Compare(end) >= 0 {
+			return nil
+		}
+		rest, tableID, err := keys.DecodeTablePrefix(next)
+		if err != nil || len(rest) == 0 {
+			key = next.Next()
+			continue
+		}
+		_, indexID, err := encoding.DecodeUvarintAscending(rest)
+		if err != nil || indexID == 0 {
+			key = next.Next()
+			continue
+		}
+		indexStart := roachpb.Key(encoding.EncodeUvarintAscending(
+			keys.MakeTablePrefix(uint32(tableID)), indexID))
+		indexEnd := indexStart.PrefixEnd()
+		if indexStart.Compare(key) < 0 {
+			indexStart = key
+		}
+		if indexEnd.Compare(end) > 0 {
+			indexEnd = end
+		}
+		if err := fn(uint32(tableID), uint32(indexID), indexStart, indexEnd); err != nil {
+			return err
+		}
+		key = indexEnd
+	}
+	return nil
+}
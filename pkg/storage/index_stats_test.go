@@ -0,0 +1,96 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/encoding"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestForEachIndexSpan(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	indexKey := func(tableID, indexID uint32, suffix ...string) roachpb.Key {
+		key := encoding.EncodeUvarintAscending(keys.MakeTablePrefix(tableID), uint64(indexID))
+		for _, s := range suffix {
+			key = encoding.EncodeStringAscending(key, s)
+		}
+		return roachpb.Key(key)
+	}
+
+	// The keys of the store, in order.
+	storeKeys := []roachpb.Key{
+		keys.NodeLivenessKey(1),
+		roachpb.Key(keys.MakeTablePrefix(51)),
+		indexKey(51, 1, "a"),
+		indexKey(51, 1, "b"),
+		indexKey(51, 3, "a"),
+		indexKey(52, 0),
+		indexKey(52, 2, "a"),
+		indexKey(52, 2, "b"),
+	}
+	seek := func(key roachpb.Key) (roachpb.Key, error) {
+		i := sort.Search(len(storeKeys), func(i int) bool {
+			return storeKeys[i].Compare(key) >= 0
+		})
+		if i == len(storeKeys) {
+			return nil, nil
+		}
+		return storeKeys[i], nil
+	}
+
+	testCases := []struct {
+		start, end roachpb.Key
+		expected   []string
+	}{
+		// A span within an index.
+		{indexKey(51, 1, "a"), indexKey(51, 1, "c"), []string{
+			`51/1: /Table/51/1/"a"-/Table/51/1/"c"`,
+		}},
+		// The spans of the indexes without keys are skipped, and so are the
+		// keys which aren't in an index.
+		{roachpb.Key(keys.MakeTablePrefix(51)), indexKey(52, 2, "b"), []string{
+			`51/1: /Table/51/1-/Table/51/2`,
+			`51/3: /Table/51/3-/Table/51/4`,
+			`52/2: /Table/52/2-/Table/52/2/"b"`,
+		}},
+		// The keys outside of the table data are skipped.
+		{roachpb.KeyMin, roachpb.KeyMax, []string{
+			`51/1: /Table/51/1-/Table/51/2`,
+			`51/3: /Table/51/3-/Table/51/4`,
+			`52/2: /Table/52/2-/Table/52/3`,
+		}},
+		{keys.NodeLivenessPrefix, keys.NodeLivenessKeyMax, nil},
+	}
+	for i, tc := range testCases {
+		var spans []string
+		if err := forEachIndexSpan(tc.start, tc.end, seek, func(tableID, indexID uint32, start, end roachpb.Key) error {
+			spans = append(spans, fmt.Sprintf("%d/%d: %s-%s", tableID, indexID, start, end))
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(spans, tc.expected) {
+			t.Errorf("%d: expected %q, got %q", i, tc.expected, spans)
+		}
+	}
+}
@@ -204,6 +204,9 @@ var (
 	metaRangeSplits = metric.Metadata{
 		Name: "range.splits",
 		Help: "Number of range splits"}
+	metaRangeSplitsByLoad = metric.Metadata{
+		Name: "range.splits.load",
+		Help: "Number of range splits due to the load on the range"}
 	metaRangeAdds = metric.Metadata{
 		Name: "range.adds",
 		Help: "Number of range additions"}
@@ -213,6 +216,9 @@ var (
 	metaRangeSnapshotsGenerated = metric.Metadata{
 		Name: "range.snapshots.generated",
 		Help: "Number of generated snapshots"}
+	metaRangeSnapshotsDelegated = metric.Metadata{
+		Name: "range.snapshots.delegated",
+		Help: "Number of snapshots sent on behalf of another replica"}
 	metaRangeSnapshotsNormalApplied = metric.Metadata{
 		Name: "range.snapshots.normal-applied",
 		Help: "Number of applied snapshots"}
@@ -314,6 +320,9 @@ var (
 	metaGCQueueProcessingNanos = metric.Metadata{
 		Name: "queue.gc.processingnanos",
 		Help: "Nanoseconds spent processing replicas in the GC queue"}
+	metaGCQueueTimerNanos = metric.Metadata{
+		Name: "queue.gc.timernanos",
+		Help: "Nanoseconds between the processing of replicas in the GC queue, as tuned to the target duty cycle"}
 	metaRaftLogQueueSuccesses = metric.Metadata{
 		Name: "queue.raftlog.process.success",
 		Help: "Number of replicas successfully processed by the Raft log queue"}
@@ -533,9 +542,11 @@ type StoreMetrics struct {
 
 	// Range event metrics.
 	RangeSplits                     *metric.Counter
+	RangeSplitsByLoad               *metric.Counter
 	RangeAdds                       *metric.Counter
 	RangeRemoves                    *metric.Counter
 	RangeSnapshotsGenerated         *metric.Counter
+	RangeSnapshotsDelegated         *metric.Counter
 	RangeSnapshotsNormalApplied     *metric.Counter
 	RangeSnapshotsPreemptiveApplied *metric.Counter
 	RangeRaftLeaderTransfers        *metric.Counter
@@ -582,6 +593,7 @@ type StoreMetrics struct {
 	GCQueueFailures                           *metric.Counter
 	GCQueuePending                            *metric.Gauge
 	GCQueueProcessingNanos                    *metric.Counter
+	GCQueueTimerNanos                         *metric.GaugeFloat64
 	RaftLogQueueSuccesses                     *metric.Counter
 	RaftLogQueueFailures                      *metric.Counter
 	RaftLogQueuePending                       *metric.Gauge
@@ -711,9 +723,11 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 
 		// Range event metrics.
 		RangeSplits:                     metric.NewCounter(metaRangeSplits),
+		RangeSplitsByLoad:               metric.NewCounter(metaRangeSplitsByLoad),
 		RangeAdds:                       metric.NewCounter(metaRangeAdds),
 		RangeRemoves:                    metric.NewCounter(metaRangeRemoves),
 		RangeSnapshotsGenerated:         metric.NewCounter(metaRangeSnapshotsGenerated),
+		RangeSnapshotsDelegated:         metric.NewCounter(metaRangeSnapshotsDelegated),
 		RangeSnapshotsNormalApplied:     metric.NewCounter(metaRangeSnapshotsNormalApplied),
 		RangeSnapshotsPreemptiveApplied: metric.NewCounter(metaRangeSnapshotsPreemptiveApplied),
 		RangeRaftLeaderTransfers:        metric.NewCounter(metaRangeRaftLeaderTransfers),
@@ -758,6 +772,7 @@ func newStoreMetrics(histogramWindow time.Duration) *StoreMetrics {
 		GCQueueFailures:                           metric.NewCounter(metaGCQueueFailures),
 		GCQueuePending:                            metric.NewGauge(metaGCQueuePending),
 		GCQueueProcessingNanos:                    metric.NewCounter(metaGCQueueProcessingNanos),
+		GCQueueTimerNanos:                         metric.NewGaugeFloat64(metaGCQueueTimerNanos),
 		RaftLogQueueSuccesses:                     metric.NewCounter(metaRaftLogQueueSuccesses),
 		RaftLogQueueFailures:                      metric.NewCounter(metaRaftLogQueueFailures),
 		RaftLogQueuePending:                       metric.NewGauge(metaRaftLogQueuePending),
@@ -68,6 +68,32 @@ func (l *Liveness) isLive(now hlc.Timestamp, maxOffset time.Duration) bool {
 	return now.Less(expiration)
 }
 
+// The liveness statuses of a node, as recorded in the system.livenesslog
+// table.
+const (
+	// LivenessStatusLive means that the node's liveness record hasn't expired.
+	LivenessStatusLive = "live"
+	// LivenessStatusSuspect means that the node's liveness record has expired,
+	// but not for long enough for the node to be considered dead.
+	LivenessStatusSuspect = "suspect"
+	// LivenessStatusDead means that the node's liveness record has been
+	// expired for longer than the time until the node is considered dead.
+	LivenessStatusDead = "dead"
+)
+
+// Status returns the liveness status of the node at the given time, given
+// the time after which a node whose liveness record expired is considered
+// dead.
+func (l *Liveness) Status(now hlc.Timestamp, maxOffset, timeUntilDead time.Duration) string {
+	if l.isLive(now, maxOffset) {
+		return LivenessStatusLive
+	}
+	if deadAt := l.Expiration.Add(timeUntilDead.Nanoseconds(), 0); now.Less(deadAt) {
+		return LivenessStatusSuspect
+	}
+	return LivenessStatusDead
+}
+
 // LivenessMetrics holds metrics for use with node liveness activity.
 type LivenessMetrics struct {
 	LiveNodes          *metric.Gauge
@@ -101,6 +127,9 @@ type NodeLiveness struct {
 	pauseHeartbeat    atomic.Value // contains a bool
 	sem               chan struct{}
 	metrics           LivenessMetrics
+	// heartbeatCallbackC signals the worker running the heartbeat callback
+	// that the liveness record was updated.
+	heartbeatCallbackC chan struct{}
 
 	mu struct {
 		syncutil.Mutex
@@ -122,13 +151,14 @@ func NewNodeLiveness(
 	renewalDuration time.Duration,
 ) *NodeLiveness {
 	nl := &NodeLiveness{
-		ambientCtx:        ambient,
-		clock:             clock,
-		db:                db,
-		gossip:            g,
-		livenessThreshold: livenessThreshold,
-		heartbeatInterval: livenessThreshold - renewalDuration,
-		sem:               make(chan struct{}, 1),
+		ambientCtx:         ambient,
+		clock:              clock,
+		db:                 db,
+		gossip:             g,
+		livenessThreshold:  livenessThreshold,
+		heartbeatInterval:  livenessThreshold - renewalDuration,
+		sem:                make(chan struct{}, 1),
+		heartbeatCallbackC: make(chan struct{}, 1),
 	}
 	nl.metrics = LivenessMetrics{
 		LiveNodes:          metric.NewFunctionalGauge(metaLiveNodes, nl.numLiveNodes),
@@ -219,6 +249,10 @@ func (nl *NodeLiveness) IsLive(nodeID roachpb.NodeID) (bool, error) {
 // StartHeartbeat starts a periodic heartbeat to refresh this node's
 // last heartbeat in the node liveness table. The optionally provided
 // HeartbeatCallback will be invoked whenever this node updates its own liveness.
+// The callback runs asynchronously on its own worker, so that the writes it
+// performs to the stores can't delay the heartbeats: a stalled disk must not
+// cost this node its liveness, and with it all of its epoch-based leases.
+// Invocations requested while the callback is running are coalesced.
 func (nl *NodeLiveness) StartHeartbeat(
 	ctx context.Context, stopper *stop.Stopper, alive HeartbeatCallback,
 ) {
@@ -230,6 +264,20 @@ func (nl *NodeLiveness) StartHeartbeat(
 	nl.mu.heartbeatCallback = alive
 	nl.mu.Unlock()
 
+	stopper.RunWorker(ctx, func(context.Context) {
+		ambient := nl.ambientCtx
+		ambient.AddLogTag("hb-cb", nil)
+		ctx := ambient.AnnotateCtx(context.Background())
+		for {
+			select {
+			case <-nl.heartbeatCallbackC:
+				nl.runHeartbeatCallback(ctx)
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+
 	stopper.RunWorker(ctx, func(context.Context) {
 		ambient := nl.ambientCtx
 		ambient.AddLogTag("hb", nil)
@@ -245,11 +293,24 @@ func (nl *NodeLiveness) StartHeartbeat(
 					if err != nil && err != ErrNoLivenessRecord {
 						log.Errorf(ctx, "unexpected error getting liveness: %v", err)
 					}
-					if err := nl.heartbeatInternal(ctx, liveness, incrementEpoch); err != nil {
+					// Bound each attempt by the heartbeat interval. An attempt stuck
+					// behind a slow write is abandoned and retried, instead of holding
+					// up the heartbeats while the current liveness record runs out. If
+					// the abandoned attempt did commit, the retry finds the record
+					// already live.
+					hbCtx, cancel := context.WithTimeout(ctx, nl.heartbeatInterval)
+					err = nl.heartbeatInternal(hbCtx, liveness, incrementEpoch)
+					cancel()
+					if err != nil {
 						if err == errSkippedHeartbeat {
 							log.Infof(ctx, "%s; retrying", err)
 							continue
 						}
+						if errors.Cause(err) == context.DeadlineExceeded && ctx.Err() == nil {
+							log.Warningf(ctx, "node liveness heartbeat timed out after %s; retrying",
+								nl.heartbeatInterval)
+							continue
+						}
 						log.Warningf(ctx, "failed node liveness heartbeat: %v", err)
 					} else {
 						incrementEpoch = false // don't increment epoch after first heartbeat
@@ -267,6 +328,24 @@ func (nl *NodeLiveness) StartHeartbeat(
 	})
 }
 
+// runHeartbeatCallback invokes the heartbeat callback, if one is registered.
+func (nl *NodeLiveness) runHeartbeatCallback(ctx context.Context) {
+	nl.mu.Lock()
+	cb := nl.mu.heartbeatCallback
+	nl.mu.Unlock()
+	if cb == nil {
+		return
+	}
+	defer func(start time.Time) {
+		if dur := timeutil.Now().Sub(start); dur > time.Second {
+			log.Warningf(ctx, "slow heartbeat callback took %0.1fs", dur.Seconds())
+		}
+	}(timeutil.Now())
+	if err := cb(ctx); err != nil {
+		log.Warningf(ctx, "heartbeat callback failed: %v", err)
+	}
+}
+
 // PauseHeartbeat stops or restarts the periodic heartbeat depending
 // on the pause parameter.
 func (nl *NodeLiveness) PauseHeartbeat(pause bool) {
@@ -557,11 +636,10 @@ func (nl *NodeLiveness) updateLiveness(
 		return err
 	}
 
-	nl.mu.Lock()
-	cb := nl.mu.heartbeatCallback
-	nl.mu.Unlock()
-	if cb != nil {
-		return cb(ctx)
+	// Let the heartbeat callback run, without waiting for it.
+	select {
+	case nl.heartbeatCallbackC <- struct{}{}:
+	default:
 	}
 	return nil
 }
@@ -21,6 +21,7 @@ import (
 	"sort"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
@@ -30,6 +31,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/storage"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 )
@@ -214,9 +216,8 @@ func TestNodeHeartbeatCallback(t *testing.T) {
 		return nil
 	}
 
-	if err := verifyUptimes(); err != nil {
-		t.Fatal(err)
-	}
+	// The callback runs asynchronously.
+	testutils.SucceedsSoon(t, verifyUptimes)
 
 	// Advance clock past the liveness threshold and force a manual heartbeat on
 	// all node liveness objects, which should update the last up time for each
@@ -231,9 +232,7 @@ func TestNodeHeartbeatCallback(t *testing.T) {
 			t.Fatal(err)
 		}
 	}
-	if err := verifyUptimes(); err != nil {
-		t.Fatal(err)
-	}
+	testutils.SucceedsSoon(t, verifyUptimes)
 }
 
 // TestNodeLivenessEpochIncrement verifies that incrementing the epoch
@@ -702,3 +701,26 @@ func TestNodeLivenessRetryAmbiguousResultError(t *testing.T) {
 		t.Errorf("expected injected error count of 2; got %d", count)
 	}
 }
+
+func TestLivenessStatus(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const maxOffset = 100 * time.Millisecond
+	const timeUntilDead = time.Minute
+	l := storage.Liveness{Expiration: hlc.Timestamp{WallTime: time.Hour.Nanoseconds()}}
+	for _, tc := range []struct {
+		now      time.Duration
+		expected string
+	}{
+		{0, storage.LivenessStatusLive},
+		{time.Hour - maxOffset - 1, storage.LivenessStatusLive},
+		{time.Hour - maxOffset, storage.LivenessStatusSuspect},
+		{time.Hour + timeUntilDead - 1, storage.LivenessStatusSuspect},
+		{time.Hour + timeUntilDead, storage.LivenessStatusDead},
+	} {
+		now := hlc.Timestamp{WallTime: tc.now.Nanoseconds()}
+		if status := l.Status(now, maxOffset, timeUntilDead); status != tc.expected {
+			t.Errorf("at %s: expected %s, got %s", tc.now, tc.expected, status)
+		}
+	}
+}
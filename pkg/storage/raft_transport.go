@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/coreos/etcd/raft/raftpb"
+	"github.com/pkg/errors"
 	"github.com/rubyist/circuitbreaker"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -37,6 +38,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 )
 
 const (
@@ -106,6 +108,11 @@ type RaftMessageHandler interface {
 	// HandleSnapshot is called for each new incoming snapshot stream, after
 	// parsing the initial SnapshotRequest_Header on the stream.
 	HandleSnapshot(header *SnapshotRequest_Header, respStream SnapshotResponseStream) error
+
+	// HandleDelegatedSnapshot is called when the leader of a range asks one
+	// of the handler's replicas to send a snapshot on its behalf. See the
+	// DelegateRaftSnapshot RPC for the interpretation of the header.
+	HandleDelegatedSnapshot(ctx context.Context, header *SnapshotRequest_Header) error
 }
 
 // NodeAddressResolver is the function used by RaftTransport to map node IDs to
@@ -181,6 +188,7 @@ func NewRaftTransport(
 	grpcServer *grpc.Server,
 	rpcContext *rpc.Context,
 ) *RaftTransport {
+	ambient.SetSubsystem(tracing.SubsystemRaft)
 	t := &RaftTransport{
 		AmbientContext: ambient,
 		resolver:       resolver,
@@ -391,6 +399,24 @@ func (t *RaftTransport) RaftSnapshot(stream MultiRaft_RaftSnapshotServer) error
 	}
 }
 
+// DelegateRaftSnapshot handles requests from range leaders asking a local
+// replica to send a snapshot on their behalf.
+func (t *RaftTransport) DelegateRaftSnapshot(
+	ctx context.Context, header *SnapshotRequest_Header,
+) (*SnapshotResponse, error) {
+	delegate := header.RaftMessageRequest.FromReplica
+	t.recvMu.Lock()
+	handler, ok := t.recvMu.handlers[delegate.StoreID]
+	t.recvMu.Unlock()
+	if !ok {
+		return nil, roachpb.NewStoreNotFoundError(delegate.StoreID)
+	}
+	if err := handler.HandleDelegatedSnapshot(ctx, header); err != nil {
+		return &SnapshotResponse{Status: SnapshotResponse_ERROR, Message: err.Error()}, nil
+	}
+	return &SnapshotResponse{Status: SnapshotResponse_APPLIED}, nil
+}
+
 // Listen registers a raftMessageHandler to receive proxied messages.
 func (t *RaftTransport) Listen(storeID roachpb.StoreID, handler RaftMessageHandler) {
 	t.recvMu.Lock()
@@ -658,3 +684,26 @@ func (t *RaftTransport) SendSnapshot(
 	}()
 	return sendSnapshot(ctx, stream, storePool, header, snap, newBatch, sent)
 }
+
+// DelegateSnapshot asks the replica in header.RaftMessageRequest.FromReplica
+// to send a snapshot to header.RaftMessageRequest.ToReplica, and waits for
+// the recipient to apply it.
+func (t *RaftTransport) DelegateSnapshot(ctx context.Context, header SnapshotRequest_Header) error {
+	nodeID := header.RaftMessageRequest.FromReplica.NodeID
+	addr, err := t.resolver(nodeID)
+	if err != nil {
+		return err
+	}
+	conn, err := t.rpcContext.GRPCDial(addr.String(), grpc.WithBlock())
+	if err != nil {
+		return err
+	}
+	resp, err := NewMultiRaftClient(conn).DelegateRaftSnapshot(ctx, &header)
+	if err != nil {
+		return err
+	}
+	if resp.Status != SnapshotResponse_APPLIED {
+		return errors.Errorf("%s: delegated snapshot failed: %s", resp.Status, resp.Message)
+	}
+	return nil
+}
@@ -15,16 +15,53 @@
 //
 // Author: Spencer Kimball (spencer@cockroachlabs.com)
 
-package storage
+// Package raftentry provides a cache for the Raft log entries of the
+// replicas of a store.
+package raftentry
 
 import (
 	"github.com/biogo/store/llrb"
+	"github.com/coreos/etcd/raft/raftpb"
+
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/util/cache"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
-	"github.com/coreos/etcd/raft/raftpb"
 )
 
+var (
+	metaEntryCacheSize = metric.Metadata{
+		Name: "raft.entrycache.size",
+		Help: "Number of Raft entries in the Raft entry cache"}
+	metaEntryCacheBytes = metric.Metadata{
+		Name: "raft.entrycache.bytes",
+		Help: "Aggregate size of all Raft entries in the Raft entry cache"}
+	metaEntryCacheAccesses = metric.Metadata{
+		Name: "raft.entrycache.accesses",
+		Help: "Number of cache lookups in the Raft entry cache"}
+	metaEntryCacheHits = metric.Metadata{
+		Name: "raft.entrycache.hits",
+		Help: "Number of successful cache lookups in the Raft entry cache"}
+)
+
+// Metrics is the set of metrics for the Raft entry cache. The hit rate is
+// Hits / Accesses.
+type Metrics struct {
+	Size     *metric.Gauge
+	Bytes    *metric.Gauge
+	Accesses *metric.Counter
+	Hits     *metric.Counter
+}
+
+func makeMetrics() Metrics {
+	return Metrics{
+		Size:     metric.NewGauge(metaEntryCacheSize),
+		Bytes:    metric.NewGauge(metaEntryCacheBytes),
+		Accesses: metric.NewCounter(metaEntryCacheAccesses),
+		Hits:     metric.NewCounter(metaEntryCacheHits),
+	}
+}
+
 type entryCacheKey struct {
 	RangeID roachpb.RangeID
 	Index   uint64
@@ -48,21 +85,22 @@ func (a *entryCacheKey) Compare(b llrb.Comparable) int {
 	}
 }
 
-// A raftEntryCache maintains a global cache of Raft group log
-// entries. The cache mostly prevents unnecessary reads from disk of
-// recently-written log entries between log append and application
-// to the FSM.
-type raftEntryCache struct {
+// A Cache maintains a cache of the Raft log entries of all the replicas of a
+// store, within a byte budget shared by all of them. The cache mostly prevents
+// unnecessary reads from disk of recently-written log entries between log
+// append and application to the FSM. It is safe for concurrent use.
+type Cache struct {
 	syncutil.Mutex                     // protects Cache for concurrent access.
 	bytes          uint64              // total size of the cache in bytes
 	cache          *cache.OrderedCache // LRU cache of log entries, keyed by rangeID / log index
+	metrics        Metrics
 }
 
-// newRaftEntryCache returns a new RaftEntryCache with the given
-// maximum size in bytes.
-func newRaftEntryCache(maxBytes uint64) *raftEntryCache {
-	rec := &raftEntryCache{
-		cache: cache.NewOrderedCache(cache.Config{Policy: cache.CacheLRU}),
+// NewCache returns a new Cache with the given maximum size in bytes.
+func NewCache(maxBytes uint64) *Cache {
+	rec := &Cache{
+		cache:   cache.NewOrderedCache(cache.Config{Policy: cache.CacheLRU}),
+		metrics: makeMetrics(),
 	}
 	// The raft entry cache mutex will be held when the ShouldEvict
 	// and OnEvicted callbacks are invoked.
@@ -72,7 +110,7 @@ func newRaftEntryCache(maxBytes uint64) *raftEntryCache {
 	// in the cache to prevent the case where a very large entry isn't able
 	// to be cached at all.
 	rec.cache.Config.ShouldEvict = func(n int, k, v interface{}) bool {
-		return rec.bytes > maxBytes && n >= 1
+		return rec.bytes > maxBytes && n > 1
 	}
 	rec.cache.Config.OnEvicted = func(k, v interface{}) {
 		ent := v.(*raftpb.Entry)
@@ -82,7 +120,13 @@ func newRaftEntryCache(maxBytes uint64) *raftEntryCache {
 	return rec
 }
 
-func (rec *raftEntryCache) makeCacheEntry(key entryCacheKey, value raftpb.Entry) *cache.Entry {
+// Metrics returns the metrics of the cache, to be registered with the metric
+// registry of the store.
+func (rec *Cache) Metrics() *Metrics {
+	return &rec.metrics
+}
+
+func (rec *Cache) makeCacheEntry(key entryCacheKey, value raftpb.Entry) *cache.Entry {
 	alloc := struct {
 		key   entryCacheKey
 		value raftpb.Entry
@@ -96,9 +140,17 @@ func (rec *raftEntryCache) makeCacheEntry(key entryCacheKey, value raftpb.Entry)
 	return &alloc.entry
 }
 
-// addEntries adds the slice of raft entries, using the range ID and the
-// entry indexes as each cached entry's key.
-func (rec *raftEntryCache) addEntries(rangeID roachpb.RangeID, ents []raftpb.Entry) {
+// updateMetricsLocked updates the gauges with the current contents of the
+// cache.
+func (rec *Cache) updateMetricsLocked() {
+	rec.metrics.Size.Update(int64(rec.cache.Len()))
+	rec.metrics.Bytes.Update(int64(rec.bytes))
+}
+
+// Add adds the slice of raft entries, using the range ID and the entry
+// indexes as each cached entry's key. An entry already cached under the same
+// key is replaced.
+func (rec *Cache) Add(rangeID roachpb.RangeID, ents []raftpb.Entry) {
 	if len(ents) == 0 {
 		return
 	}
@@ -106,18 +158,23 @@ func (rec *raftEntryCache) addEntries(rangeID roachpb.RangeID, ents []raftpb.Ent
 	defer rec.Unlock()
 
 	for _, e := range ents {
+		key := entryCacheKey{RangeID: rangeID, Index: e.Index}
+		// The replaced entry isn't evicted, and must be accounted for here.
+		if v, ok := rec.cache.Get(&key); ok {
+			rec.bytes -= uint64(v.(*raftpb.Entry).Size())
+		}
 		rec.bytes += uint64(e.Size())
-		entry := rec.makeCacheEntry(entryCacheKey{RangeID: rangeID, Index: e.Index}, e)
-		rec.cache.AddEntry(entry)
+		rec.cache.AddEntry(rec.makeCacheEntry(key, e))
 	}
+	rec.updateMetricsLocked()
 }
 
-// getEntries returns entries between [lo, hi) for specified range.
+// Get returns entries between [lo, hi) for specified range.
 // If any entries are returned for the specified indexes, they will
 // start with index lo and proceed sequentially without gaps until
 // 1) all entries exclusive of hi are fetched, 2) > maxBytes of
 // entries data is fetched, or 3) a cache miss occurs.
-func (rec *raftEntryCache) getEntries(
+func (rec *Cache) Get(
 	ents []raftpb.Entry, rangeID roachpb.RangeID, lo, hi, maxBytes uint64,
 ) ([]raftpb.Entry, uint64, uint64) {
 	rec.Lock()
@@ -142,11 +199,16 @@ func (rec *raftEntryCache) getEntries(
 		return false
 	}, fromKey, toKey)
 
+	// The lookup is a hit if it spared the caller a read from disk.
+	rec.metrics.Accesses.Inc(1)
+	if nextIndex == hi || (maxBytes > 0 && bytes > maxBytes) {
+		rec.metrics.Hits.Inc(1)
+	}
 	return ents, bytes, nextIndex
 }
 
-// delEntries deletes entries between [lo, hi) for specified range.
-func (rec *raftEntryCache) delEntries(rangeID roachpb.RangeID, lo, hi uint64) {
+// Del deletes entries between [lo, hi) for specified range.
+func (rec *Cache) Del(rangeID roachpb.RangeID, lo, hi uint64) {
 	rec.Lock()
 	defer rec.Unlock()
 	if lo >= hi {
@@ -163,10 +225,11 @@ func (rec *raftEntryCache) delEntries(rangeID roachpb.RangeID, lo, hi uint64) {
 	for _, k := range keys {
 		rec.cache.Del(k)
 	}
+	rec.updateMetricsLocked()
 }
 
-// clearTo clears the entries in the cache for specified range up to,
+// ClearTo clears the entries in the cache for specified range up to,
 // but not including the specified index.
-func (rec *raftEntryCache) clearTo(rangeID roachpb.RangeID, index uint64) {
-	rec.delEntries(rangeID, 0, index)
+func (rec *Cache) ClearTo(rangeID roachpb.RangeID, index uint64) {
+	rec.Del(rangeID, 0, index)
 }
@@ -14,15 +14,16 @@
 //
 // Author: Spencer Kimball (spencer.kimball@gmail.com)
 
-package storage
+package raftentry
 
 import (
 	"reflect"
 	"testing"
 
+	"github.com/coreos/etcd/raft/raftpb"
+
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
-	"github.com/coreos/etcd/raft/raftpb"
 )
 
 func newEntry(index, size uint64) raftpb.Entry {
@@ -32,24 +33,24 @@ func newEntry(index, size uint64) raftpb.Entry {
 	}
 }
 
-func addEntries(rec *raftEntryCache, rangeID roachpb.RangeID, lo, hi uint64) []raftpb.Entry {
+func addEntries(rec *Cache, rangeID roachpb.RangeID, lo, hi uint64) []raftpb.Entry {
 	ents := []raftpb.Entry{}
 	for i := lo; i < hi; i++ {
 		ents = append(ents, newEntry(i, 1))
 	}
-	rec.addEntries(rangeID, ents)
+	rec.Add(rangeID, ents)
 	return ents
 }
 
 func verifyGet(
 	t *testing.T,
-	rec *raftEntryCache,
+	rec *Cache,
 	rangeID roachpb.RangeID,
 	lo, hi uint64,
 	expEnts []raftpb.Entry,
 	expNextIndex uint64,
 ) {
-	ents, _, nextIndex := rec.getEntries(nil, rangeID, lo, hi, 0)
+	ents, _, nextIndex := rec.Get(nil, rangeID, lo, hi, 0)
 	if !(len(expEnts) == 0 && len(ents) == 0) && !reflect.DeepEqual(expEnts, ents) {
 		t.Fatalf("expected entries %+v; got %+v", expEnts, ents)
 	}
@@ -60,7 +61,7 @@ func verifyGet(
 
 func TestEntryCache(t *testing.T) {
 	defer leaktest.AfterTest(t)()
-	rec := newRaftEntryCache(100)
+	rec := NewCache(100)
 	rangeID := roachpb.RangeID(2)
 	// Add entries for range 1, indexes (1-10).
 	ents := addEntries(rec, rangeID, 1, 11)
@@ -77,7 +78,7 @@ func TestEntryCache(t *testing.T) {
 	// Fetch data from later range.
 	verifyGet(t, rec, roachpb.RangeID(3), 1, 11, []raftpb.Entry{}, 1)
 	// Create a gap in the entries.
-	rec.delEntries(rangeID, 4, 8)
+	rec.Del(rangeID, 4, 8)
 	// Fetch all data; verify we get only first three.
 	verifyGet(t, rec, rangeID, 1, 11, ents[0:3], 4)
 	// Try to fetch from within the gap; expect no entries.
@@ -85,11 +86,11 @@ func TestEntryCache(t *testing.T) {
 	// Fetch after the gap.
 	verifyGet(t, rec, rangeID, 8, 11, ents[7:], 11)
 	// Delete the prefix of entries.
-	rec.delEntries(rangeID, 1, 3)
+	rec.Del(rangeID, 1, 3)
 	// Verify entries are gone.
 	verifyGet(t, rec, rangeID, 1, 5, []raftpb.Entry{}, 1)
 	// Delete the suffix of entries.
-	rec.delEntries(rangeID, 10, 11)
+	rec.Del(rangeID, 10, 11)
 	// Verify get of entries at end of range.
 	verifyGet(t, rec, rangeID, 8, 11, ents[7:9], 10)
 }
@@ -97,14 +98,14 @@ func TestEntryCache(t *testing.T) {
 func TestEntryCacheClearTo(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	rangeID := roachpb.RangeID(1)
-	rec := newRaftEntryCache(100)
-	rec.addEntries(rangeID, []raftpb.Entry{newEntry(2, 1)})
-	rec.addEntries(rangeID, []raftpb.Entry{newEntry(20, 1), newEntry(21, 1)})
-	rec.clearTo(rangeID, 21)
-	if ents, _, _ := rec.getEntries(nil, rangeID, 2, 21, 0); len(ents) != 0 {
+	rec := NewCache(100)
+	rec.Add(rangeID, []raftpb.Entry{newEntry(2, 1)})
+	rec.Add(rangeID, []raftpb.Entry{newEntry(20, 1), newEntry(21, 1)})
+	rec.ClearTo(rangeID, 21)
+	if ents, _, _ := rec.Get(nil, rangeID, 2, 21, 0); len(ents) != 0 {
 		t.Errorf("expected no entries after clearTo")
 	}
-	if ents, _, _ := rec.getEntries(nil, rangeID, 21, 22, 0); len(ents) != 1 {
+	if ents, _, _ := rec.Get(nil, rangeID, 21, 22, 0); len(ents) != 1 {
 		t.Errorf("expected entry 22 to remain in the cache clearTo")
 	}
 }
@@ -112,16 +113,74 @@ func TestEntryCacheClearTo(t *testing.T) {
 func TestEntryCacheEviction(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	rangeID := roachpb.RangeID(1)
-	rec := newRaftEntryCache(100)
-	rec.addEntries(rangeID, []raftpb.Entry{newEntry(1, 40), newEntry(2, 40)})
-	ents, _, hi := rec.getEntries(nil, rangeID, 1, 3, 0)
+	rec := NewCache(100)
+	rec.Add(rangeID, []raftpb.Entry{newEntry(1, 40), newEntry(2, 40)})
+	ents, _, hi := rec.Get(nil, rangeID, 1, 3, 0)
 	if len(ents) != 2 || hi != 3 {
 		t.Errorf("expected both entries; got %+v, %d", ents, hi)
 	}
 	// Add another entry to evict first.
-	rec.addEntries(rangeID, []raftpb.Entry{newEntry(3, 40)})
-	ents, _, hi = rec.getEntries(nil, rangeID, 2, 4, 0)
+	rec.Add(rangeID, []raftpb.Entry{newEntry(3, 40)})
+	ents, _, hi = rec.Get(nil, rangeID, 2, 4, 0)
 	if len(ents) != 2 || hi != 4 {
 		t.Errorf("expected only two entries; got %+v, %d", ents, hi)
 	}
 }
+
+func TestEntryCacheReplace(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	rangeID := roachpb.RangeID(1)
+	rec := NewCache(100)
+	ent := newEntry(1, 10)
+	rec.Add(rangeID, []raftpb.Entry{ent})
+	bytes := rec.bytes
+	// Adding the entry again, e.g. after reading it from disk, doesn't count it
+	// twice.
+	rec.Add(rangeID, []raftpb.Entry{ent})
+	if rec.bytes != bytes {
+		t.Errorf("expected %d bytes, got %d", bytes, rec.bytes)
+	}
+	// A larger entry with the same index replaces it.
+	big := newEntry(1, 20)
+	rec.Add(rangeID, []raftpb.Entry{big})
+	if expected := uint64(big.Size()); rec.bytes != expected {
+		t.Errorf("expected %d bytes, got %d", expected, rec.bytes)
+	}
+	if m := rec.Metrics(); m.Size.Value() != 1 || m.Bytes.Value() != int64(rec.bytes) {
+		t.Errorf("unexpected metrics: size %d, bytes %d", m.Size.Value(), m.Bytes.Value())
+	}
+}
+
+func TestEntryCacheLargeEntry(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	rangeID := roachpb.RangeID(1)
+	rec := NewCache(100)
+	// An entry larger than the cache is cached nonetheless, until it's evicted
+	// by the next one.
+	rec.Add(rangeID, []raftpb.Entry{newEntry(1, 200)})
+	verifyGet(t, rec, rangeID, 1, 2, []raftpb.Entry{newEntry(1, 200)}, 2)
+	rec.Add(rangeID, []raftpb.Entry{newEntry(2, 10)})
+	verifyGet(t, rec, rangeID, 1, 3, nil, 1)
+	verifyGet(t, rec, rangeID, 2, 3, []raftpb.Entry{newEntry(2, 10)}, 3)
+}
+
+func TestEntryCacheMetrics(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	rangeID := roachpb.RangeID(1)
+	rec := NewCache(100)
+	addEntries(rec, rangeID, 1, 5)
+	m := rec.Metrics()
+	if m.Size.Value() != 4 || m.Bytes.Value() != int64(rec.bytes) {
+		t.Errorf("unexpected metrics: size %d, bytes %d", m.Size.Value(), m.Bytes.Value())
+	}
+	rec.Get(nil, rangeID, 1, 5, 0) // hit
+	rec.Get(nil, rangeID, 3, 7, 0) // miss
+	rec.Get(nil, rangeID, 1, 5, 1) // hit, as the max bytes are reached
+	if a, h := m.Accesses.Count(), m.Hits.Count(); a != 3 || h != 2 {
+		t.Errorf("expected 3 accesses and 2 hits, got %d and %d", a, h)
+	}
+	rec.ClearTo(rangeID, 3)
+	if m.Size.Value() != 2 || m.Bytes.Value() != int64(rec.bytes) {
+		t.Errorf("unexpected metrics: size %d, bytes %d", m.Size.Value(), m.Bytes.Value())
+	}
+}
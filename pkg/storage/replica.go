@@ -222,6 +222,9 @@ type Replica struct {
 	pushTxnQueue *pushTxnQueue // Queues push txn attempts by txn ID
 
 	stats *replicaStats
+	// loadSplitter tracks the load on the replica to decide when and where to
+	// split it; see SplitByLoadEnabled.
+	loadSplitter *loadSplitDecider
 
 	// creatingReplica is set when a replica is created as uninitialized
 	// via a raft message.
@@ -265,6 +268,19 @@ type Replica struct {
 		global, local *CommandQueue
 	}
 
+	// closedTSMu tracks the writes evaluated by this replica, so that it only
+	// closes timestamps below them. See maybeCloseTimestampLocked.
+	closedTSMu struct {
+		// Locking notes: Replica.mu < Replica.closedTSMu < Store.tsCacheMu
+		syncutil.Mutex
+		// evaluating counts the write batches which passed the timestamp cache
+		// but weren't assigned a lease index yet, by timestamp.
+		evaluating map[hlc.Timestamp]int
+		// lastClosed is the last timestamp closed by a proposal of this
+		// replica.
+		lastClosed hlc.Timestamp
+	}
+
 	mu struct {
 		// Protects all fields in the mu struct.
 		syncutil.RWMutex
@@ -320,6 +336,10 @@ type Replica struct {
 		// lease extension that were in flight at the time of the transfer cannot be
 		// used, if they eventually apply.
 		minLeaseProposedTS hlc.Timestamp
+		// closedTimestamp is the largest timestamp closed by the commands
+		// applied by this replica: it can serve consistent reads at or below it
+		// without the lease. See canServeFollowerRead.
+		closedTimestamp hlc.Timestamp
 		// Max bytes before split.
 		maxBytes int64
 		// proposals stores the Raft in-flight commands which
@@ -557,12 +577,14 @@ func newReplica(rangeID roachpb.RangeID, store *Store) *Replica {
 		pushTxnQueue:   newPushTxnQueue(store),
 	}
 	r.mu.stateLoader = makeReplicaStateLoader(rangeID)
+	r.closedTSMu.evaluating = make(map[hlc.Timestamp]int)
 	if leaseHistoryMaxEntries > 0 {
 		r.leaseHistory = newLeaseHistory()
 	}
 	if store.cfg.StorePool != nil {
 		r.stats = newReplicaStats(store.Clock(), store.cfg.StorePool.getNodeLocalityString)
 	}
+	r.loadSplitter = newLoadSplitDecider(time.Unix(0, store.Clock().PhysicalNow()))
 
 	// Init rangeStr with the range ID.
 	r.rangeStr.store(0, &roachpb.RangeDescriptor{RangeID: rangeID})
@@ -1624,6 +1646,22 @@ func (r *Replica) maybeInitializeRaftGroup(ctx context.Context) {
 	}
 }
 
+// recordLoadForSplit records the batch in the replica's loadSplitter, and
+// adds the replica to the split queue once a split key was found.
+func (r *Replica) recordLoadForSplit(ba roachpb.BatchRequest) {
+	now := time.Unix(0, r.store.Clock().PhysicalNow())
+	shouldSplit := r.loadSplitter.record(now, len(ba.Requests), func() roachpb.Span {
+		rspan, err := keys.Range(ba)
+		if err != nil {
+			return roachpb.Span{}
+		}
+		return roachpb.Span{Key: rspan.Key.AsRawKey(), EndKey: rspan.EndKey.AsRawKey()}
+	})
+	if shouldSplit && r.store.splitQueue != nil {
+		r.store.splitQueue.MaybeAdd(r, r.store.Clock().Now())
+	}
+}
+
 // Send executes a command on this range, dispatching it to the
 // read-only, read-write, or admin execution path as appropriate.
 // ctx should contain the log tags from the store (and up).
@@ -1635,6 +1673,9 @@ func (r *Replica) Send(
 	if r.stats != nil && ba.Header.GatewayNodeID != 0 {
 		r.stats.record(ba.Header.GatewayNodeID)
 	}
+	if SplitByLoadEnabled.Get() {
+		r.recordLoadForSplit(ba)
+	}
 
 	if err := r.checkBatchRequest(ba); err != nil {
 		return nil, roachpb.NewError(err)
@@ -2235,12 +2276,21 @@ func (r *Replica) executeAdminBatch(
 // executeReadOnlyBatch updates the read timestamp cache and waits for any
 // overlapping writes currently processing through Raft ahead of us to
 // clear via the command queue.
+//
+// Read-only batches are evaluated directly against the local engine, without
+// being proposed to Raft. Consistent reads are served by the lease holder,
+// unless they are at or below the closed timestamp of the replica: the
+// writes below it are known to have been applied, so any replica can serve
+// them.
 func (r *Replica) executeReadOnlyBatch(
 	ctx context.Context, ba roachpb.BatchRequest,
 ) (br *roachpb.BatchResponse, pErr *roachpb.Error) {
-	// If the read is consistent, the read requires the range lease.
+	// If the read is consistent, the read requires the range lease, or to be
+	// below the closed timestamp.
 	if ba.ReadConsistency != roachpb.INCONSISTENT {
-		if _, pErr = r.redirectOnOrAcquireLease(ctx); pErr != nil {
+		if r.canServeFollowerRead(&ba) {
+			log.Event(ctx, "serving read below the closed timestamp")
+		} else if _, pErr = r.redirectOnOrAcquireLease(ctx); pErr != nil {
 			return nil, pErr
 		}
 	}
@@ -2442,9 +2492,11 @@ func (r *Replica) tryExecuteWriteBatch(
 	// commands which require this command to move its timestamp
 	// forward. Or, in the case of a transactional write, the txn
 	// timestamp and possible write-too-old bool.
-	if bumped, pErr := r.applyTimestampCache(&ba); pErr != nil {
+	bumped, untrack, pErr := r.applyTimestampCacheAndTrack(&ba)
+	if pErr != nil {
 		return nil, pErr, proposalNoRetry
-	} else if bumped {
+	}
+	if bumped {
 		// If we bump the transaction's timestamp, we must absolutely
 		// tell the client in a response transaction (for otherwise it
 		// doesn't know about the incremented timestamp). Response
@@ -2466,6 +2518,8 @@ func (r *Replica) tryExecuteWriteBatch(
 	log.Event(ctx, "applied timestamp cache")
 
 	ch, tryAbandon, undoQuotaAcquistion, err := r.propose(ctx, lease, ba, endCmds, spans)
+	// The command was assigned a lease index, if it was proposed at all.
+	untrack()
 	if err != nil {
 		return nil, roachpb.NewError(err), proposalNoRetry
 	}
@@ -2652,6 +2706,7 @@ func (r *Replica) insertProposalLocked(
 	}
 	if !proposal.Request.IsLeaseRequest() {
 		r.mu.lastAssignedLeaseIndex++
+		proposal.command.ClosedTimestamp = r.maybeCloseTimestampLocked()
 	}
 	proposal.command.MaxLeaseIndex = r.mu.lastAssignedLeaseIndex
 	proposal.command.ProposerReplica = proposerReplica
@@ -3135,7 +3190,7 @@ func (r *Replica) handleRaftReadyRaftMuLocked(
 	// ID) and set raft log entry cache. We clear any older, uncommitted
 	// log entries and cache the latest ones.
 	r.mu.Lock()
-	r.store.raftEntryCache.addEntries(r.RangeID, rd.Entries)
+	r.store.raftEntryCache.Add(r.RangeID, rd.Entries)
 	r.mu.lastIndex = lastIndex
 	r.mu.raftLogSize = raftLogSize
 	r.mu.leaderID = leaderID
@@ -4052,6 +4107,12 @@ func (r *Replica) processRaftCommand(
 		// Note that this must happen after committing (the engine.Batch), but
 		// before notifying a potentially waiting client.
 		r.handleEvalResultRaftMuLocked(ctx, lResult, raftCmd.ReplicatedEvalResult)
+
+		// A rejected command doesn't close its timestamp: it may have been
+		// proposed under a lease which is no longer in effect.
+		if closed := raftCmd.ClosedTimestamp; closed != nil && pErr == nil {
+			r.forwardClosedTimestamp(*closed)
+		}
 	}
 
 	if proposedLocally {
@@ -4986,6 +5047,16 @@ func (r *Replica) setPendingSnapshotIndex(index uint64) error {
 	return nil
 }
 
+// resetPendingSnapshotIndex reverts a pending snapshot index set by
+// setPendingSnapshotIndex to 1, so that the snapshot can be retried.
+func (r *Replica) resetPendingSnapshotIndex() {
+	r.mu.Lock()
+	if r.mu.pendingSnapshotIndex > 1 {
+		r.mu.pendingSnapshotIndex = 1
+	}
+	r.mu.Unlock()
+}
+
 func (r *Replica) clearPendingSnapshotIndex() {
 	r.mu.Lock()
 	r.mu.pendingSnapshotIndex = 0
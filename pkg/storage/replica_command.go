@@ -3672,6 +3672,12 @@ func (r *Replica) sendSnapshot(
 	snapType string,
 	priority SnapshotRequest_Priority,
 ) error {
+	if delegated, err := r.maybeDelegateSnapshot(ctx, repDesc, snapType, priority); err != nil {
+		log.Infof(ctx, "sending snapshot directly: %s", err)
+	} else if delegated {
+		return nil
+	}
+
 	snap, err := r.GetSnapshot(ctx, snapType)
 	if err != nil {
 		return errors.Wrapf(err, "%s: change replicas failed to generate snapshot", r)
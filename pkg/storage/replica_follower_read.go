@@ -0,0 +1,137 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/storage/closedts"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// closeIntervalFraction is the fraction of kv.closed_timestamp.target_duration
+// by which the closed timestamp of a range must be able to advance for a
+// proposal to close a new one. Closing a timestamp adds an entry spanning the
+// range to the timestamp cache, so it isn't done on every proposal.
+const closeIntervalFraction = 5
+
+// applyTimestampCacheAndTrack is applyTimestampCache for a write batch which
+// is about to be evaluated and proposed. The timestamp of the batch is tracked
+// until the returned function is called, which must happen once the batch was
+// assigned a lease index (or failed to be): the replica doesn't close
+// timestamps at or above those of the batches it tracks.
+func (r *Replica) applyTimestampCacheAndTrack(
+	ba *roachpb.BatchRequest,
+) (bool, func(), *roachpb.Error) {
+	r.closedTSMu.Lock()
+	defer r.closedTSMu.Unlock()
+	bumped, pErr := r.applyTimestampCache(ba)
+	if pErr != nil {
+		return bumped, nil, pErr
+	}
+	ts := ba.Timestamp
+	if ba.Txn != nil && ba.Txn.Timestamp.Less(ts) {
+		ts = ba.Txn.Timestamp
+	}
+	r.closedTSMu.evaluating[ts]++
+	return bumped, func() {
+		r.closedTSMu.Lock()
+		defer r.closedTSMu.Unlock()
+		if r.closedTSMu.evaluating[ts]--; r.closedTSMu.evaluating[ts] == 0 {
+			delete(r.closedTSMu.evaluating, ts)
+		}
+	}, nil
+}
+
+// maybeCloseTimestampLocked returns the timestamp closed by the proposal
+// being assigned the next lease index, or nil if it doesn't close one. The
+// closed timestamp trails the present by kv.closed_timestamp.target_duration
+// and is below the timestamps of the write batches still being evaluated,
+// which will be assigned larger lease indexes. The timestamp cache of the
+// range is forwarded to it so that the batches evaluated from now on are
+// pushed above it: no command with a lease index at least as large as that
+// of the proposal writes at or below the closed timestamp.
+//
+// Replica.mu must be held.
+func (r *Replica) maybeCloseTimestampLocked() *hlc.Timestamp {
+	target := closedts.TargetDuration.Get()
+	if target == 0 {
+		return nil
+	}
+	closed := r.store.Clock().Now().Add(-target.Nanoseconds(), 0)
+	if closed.WallTime <= 0 {
+		return nil
+	}
+
+	r.closedTSMu.Lock()
+	defer r.closedTSMu.Unlock()
+	if closed.WallTime-r.closedTSMu.lastClosed.WallTime < target.Nanoseconds()/closeIntervalFraction {
+		return nil
+	}
+	for ts := range r.closedTSMu.evaluating {
+		if !closed.Less(ts) {
+			closed = ts.Prev()
+		}
+	}
+	if !r.closedTSMu.lastClosed.Less(closed) {
+		return nil
+	}
+
+	r.store.tsCacheMu.Lock()
+	for _, keyRange := range makeReplicatedKeyRanges(r.mu.state.Desc) {
+		r.store.tsCacheMu.cache.add(
+			keyRange.start.Key, keyRange.end.Key, closed, lowWaterTxnIDMarker, true /* readTSCache */)
+	}
+	r.store.tsCacheMu.Unlock()
+
+	r.closedTSMu.lastClosed = closed
+	return &closed
+}
+
+// forwardClosedTimestamp records that the replica applied a command closing
+// the given timestamp.
+func (r *Replica) forwardClosedTimestamp(closed hlc.Timestamp) {
+	r.mu.Lock()
+	r.mu.closedTimestamp.Forward(closed)
+	r.mu.Unlock()
+	r.store.closedTimestamps.Forward(r.RangeID, closed)
+}
+
+// canServeFollowerRead returns true if the consistent read-only batch can be
+// served without the lease, which is the case if it only contains reads at
+// or below the closed timestamp of the replica: all the writes at or below it
+// were applied by the replica. The uncertainty interval of a transaction must
+// be below the closed timestamp as well. Its start is used rather than its
+// MaxTimestamp, which may have been lowered to the timestamp observed on this
+// node: that bound only applies to the writes of the lease holders of the
+// node.
+func (r *Replica) canServeFollowerRead(ba *roachpb.BatchRequest) bool {
+	for _, union := range ba.Requests {
+		switch union.GetInner().(type) {
+		case *roachpb.GetRequest, *roachpb.ScanRequest, *roachpb.ReverseScanRequest:
+		default:
+			return false
+		}
+	}
+	ts := ba.Timestamp
+	if ba.Txn != nil {
+		ts.Forward(ba.Txn.MaxTimestamp)
+		ts.Forward(ba.Txn.OrigTimestamp.Add(r.store.Clock().MaxOffset().Nanoseconds(), 0))
+	}
+
+	r.mu.RLock()
+	closed := r.mu.closedTimestamp
+	r.mu.RUnlock()
+	return closed != (hlc.Timestamp{}) && !closed.Less(ts)
+}
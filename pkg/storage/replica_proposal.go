@@ -679,7 +679,7 @@ func (r *Replica) handleReplicatedEvalResult(
 		r.mu.Unlock()
 		// Clear any entries in the Raft log entry cache for this range up
 		// to and including the most recently truncated index.
-		r.store.raftEntryCache.clearTo(r.RangeID, newTruncState.Index+1)
+		r.store.raftEntryCache.ClearTo(r.RangeID, newTruncState.Index+1)
 	}
 
 	if newThresh := rResult.State.GCThreshold; newThresh != (hlc.Timestamp{}) {
@@ -29,6 +29,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/roachpb"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/storage/raftentry"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
@@ -92,7 +93,7 @@ func entries(
 	ctx context.Context,
 	e engine.Reader,
 	rangeID roachpb.RangeID,
-	eCache *raftEntryCache,
+	eCache *raftentry.Cache,
 	lo, hi, maxBytes uint64,
 ) ([]raftpb.Entry, error) {
 	if lo > hi {
@@ -105,7 +106,7 @@ func entries(
 	}
 	ents := make([]raftpb.Entry, 0, n)
 
-	ents, size, hitIndex := eCache.getEntries(ents, rangeID, lo, hi, maxBytes)
+	ents, size, hitIndex := eCache.Get(ents, rangeID, lo, hi, maxBytes)
 	// Return results if the correct number of results came back or if
 	// we ran into the max bytes limit.
 	if uint64(len(ents)) == hi-lo || (maxBytes > 0 && size > maxBytes) {
@@ -137,7 +138,7 @@ func entries(
 		return nil, err
 	}
 	// Cache the fetched entries.
-	eCache.addEntries(rangeID, ents)
+	eCache.Add(rangeID, ents)
 
 	// Did the correct number of results come back? If so, we're all good.
 	if uint64(len(ents)) == hi-lo {
@@ -217,7 +218,7 @@ func (r *Replica) raftTermLocked(i uint64) (uint64, error) {
 }
 
 func term(
-	ctx context.Context, eng engine.Reader, rangeID roachpb.RangeID, eCache *raftEntryCache, i uint64,
+	ctx context.Context, eng engine.Reader, rangeID roachpb.RangeID, eCache *raftentry.Cache, i uint64,
 ) (uint64, error) {
 	ents, err := entries(ctx, eng, rangeID, eCache, i, i+1, 0)
 	if err == raft.ErrCompacted {
@@ -389,7 +390,7 @@ func snapshot(
 	snapType string,
 	snap engine.Reader,
 	rangeID roachpb.RangeID,
-	eCache *raftEntryCache,
+	eCache *raftentry.Cache,
 	startKey roachpb.RKey,
 ) (OutgoingSnapshot, error) {
 	var desc roachpb.RangeDescriptor
@@ -680,6 +680,76 @@ func TestApplyCmdLeaseError(t *testing.T) {
 	}
 }
 
+// TestReadOnlyBatchAtLeaseTransfer verifies that read-only batches are
+// evaluated on the lease holder without being proposed to Raft, and that the
+// previous lease holder doesn't serve reads after the lease was transferred,
+// even at timestamps below the start of the new lease: without a mechanism
+// guaranteeing that no writes can still happen below a timestamp, only the
+// lease holder knows all the writes that affect a read.
+func TestReadOnlyBatchAtLeaseTransfer(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	var proposedReads int32
+	manual := hlc.NewManualClock(123)
+	tc := testContext{manualClock: manual}
+	tsc := TestStoreConfig(hlc.NewClock(manual.UnixNano, time.Nanosecond))
+	tsc.TestingKnobs.TestingProposalFilter =
+		func(filterArgs storagebase.FilterArgs) *roachpb.Error {
+			if _, ok := filterArgs.Req.(*roachpb.GetRequest); ok {
+				atomic.AddInt32(&proposedReads, 1)
+			}
+			return nil
+		}
+	stopper := stop.NewStopper()
+	defer stopper.Stop(context.TODO())
+	tc.StartWithStoreConfig(t, stopper, tsc)
+	secondReplica, err := tc.addBogusReplicaToRangeDesc(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := roachpb.Key("a")
+	pArgs := putArgs(key, []byte("value"))
+	if _, pErr := tc.SendWrapped(&pArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	beforeTransfer := tc.Clock().Now()
+	gArgs := getArgs(key)
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: beforeTransfer}, &gArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	// Lose the lease to the second replica.
+	tc.manualClock.Set(leaseExpiry(tc.repl))
+	start := tc.Clock().Now()
+	if err := sendLeaseRequest(tc.repl, &roachpb.Lease{
+		Start:      start,
+		Expiration: start.Add(10, 0),
+		Replica:    secondReplica,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ts := range []hlc.Timestamp{beforeTransfer, tc.Clock().Now()} {
+		_, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: ts}, &gArgs)
+		if nlhe, ok := pErr.GetDetail().(*roachpb.NotLeaseHolderError); !ok ||
+			nlhe.LeaseHolder == nil || nlhe.LeaseHolder.StoreID != secondReplica.StoreID {
+			t.Errorf("read at %s: expected not lease holder error pointing to store %d, got %v",
+				ts, secondReplica.StoreID, pErr)
+		}
+	}
+
+	// Inconsistent reads don't need the lease.
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{
+		ReadConsistency: roachpb.INCONSISTENT,
+	}, &gArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	if n := atomic.LoadInt32(&proposedReads); n != 0 {
+		t.Errorf("expected reads not to be proposed to Raft, got %d proposals", n)
+	}
+}
+
 func TestLeaseReplicaNotInDesc(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	tc := testContext{}
@@ -6032,7 +6102,7 @@ func TestEntries(t *testing.T) {
 		// Case 19: lo and hi are available, but entry cache evicted.
 		{lo: indexes[5], hi: indexes[9], expResultCount: 4, expCacheCount: 0, setup: func() {
 			// Manually evict cache for the first 10 log entries.
-			repl.store.raftEntryCache.delEntries(rangeID, indexes[0], indexes[9]+1)
+			repl.store.raftEntryCache.Del(rangeID, indexes[0], indexes[9]+1)
 			indexes = append(indexes, populateLogs(10, 40)...)
 		}},
 		// Case 20: lo and hi are available, entry cache evicted and hi available in cache.
@@ -6045,7 +6115,7 @@ func TestEntries(t *testing.T) {
 		if tc.setup != nil {
 			tc.setup()
 		}
-		cacheEntries, _, _ := repl.store.raftEntryCache.getEntries(nil, rangeID, tc.lo, tc.hi, tc.maxBytes)
+		cacheEntries, _, _ := repl.store.raftEntryCache.Get(nil, rangeID, tc.lo, tc.hi, tc.maxBytes)
 		if len(cacheEntries) != tc.expCacheCount {
 			t.Errorf("%d: expected cache count %d, got %d", i, tc.expCacheCount, len(cacheEntries))
 		}
@@ -6075,7 +6145,7 @@ func TestEntries(t *testing.T) {
 	if err := engine.MVCCDelete(context.Background(), tc.store.Engine(), nil, keys.RaftLogKey(rangeID, indexes[6]), hlc.Timestamp{}, nil); err != nil {
 		t.Fatal(err)
 	}
-	repl.store.raftEntryCache.delEntries(rangeID, indexes[6], indexes[6]+1)
+	repl.store.raftEntryCache.Del(rangeID, indexes[6], indexes[6]+1)
 
 	repl.mu.Lock()
 	defer repl.mu.Unlock()
@@ -0,0 +1,104 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+var snapshotCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// snapshotHasher computes the checksum of each request of a snapshot stream,
+// and the hash of the data of the whole stream. The sender sets them on the
+// requests and the receiver verifies them, so that a snapshot corrupted in
+// transit (or in the memory of either end) is rejected instead of applied.
+// The checksums catch the corruption of a request as soon as it's received;
+// the hash catches the requests that went missing, or were reordered.
+type snapshotHasher struct {
+	sha hash.Hash
+	// n is the number of requests with data added so far.
+	n int
+}
+
+func newSnapshotHasher() *snapshotHasher {
+	return &snapshotHasher{sha: sha512.New()}
+}
+
+// add adds the data of the request to the hash of the stream, and returns its
+// checksum. The checksum is nil if the request carries no data.
+func (h *snapshotHasher) add(req *SnapshotRequest) ([]byte, error) {
+	if req.KVBatch == nil && len(req.LogEntries) == 0 {
+		return nil, nil
+	}
+	h.n++
+	crc := crc32.New(snapshotCRCTable)
+	w := io.MultiWriter(crc, h.sha)
+	if _, err := w.Write(req.KVBatch); err != nil {
+		return nil, err
+	}
+	for _, e := range req.LogEntries {
+		if _, err := w.Write(e); err != nil {
+			return nil, err
+		}
+	}
+	return crc.Sum(nil), nil
+}
+
+// sum returns the hash of the data added so far.
+func (h *snapshotHasher) sum() []byte {
+	sha := make([]byte, 0, sha512.Size)
+	return h.sha.Sum(sha)
+}
+
+// checksum sets the checksum of the request, and the hash of the stream on
+// the final request.
+func (h *snapshotHasher) checksum(req *SnapshotRequest) error {
+	checksum, err := h.add(req)
+	if err != nil {
+		return err
+	}
+	req.Checksum = checksum
+	if req.Final {
+		req.Sha512 = h.sum()
+	}
+	return nil
+}
+
+// verify checks the checksum of the request, and the hash of the stream on
+// the final request. The checks are skipped if the sender didn't set them,
+// i.e. if it predates them.
+func (h *snapshotHasher) verify(req *SnapshotRequest) error {
+	checksum, err := h.add(req)
+	if err != nil {
+		return err
+	}
+	if req.Checksum != nil && !bytes.Equal(req.Checksum, checksum) {
+		return errors.Errorf("checksum mismatch on request %d of the snapshot: expected %x, got %x",
+			h.n, req.Checksum, checksum)
+	}
+	if req.Final && req.Sha512 != nil {
+		if sha := h.sum(); !bytes.Equal(req.Sha512, sha) {
+			return errors.Errorf("hash mismatch on the %d requests of the snapshot: expected %x, got %x",
+				h.n, req.Sha512, sha)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestSnapshotHasher(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// makeStream returns the requests of a snapshot stream, checksummed by the
+	// sender.
+	makeStream := func() []*SnapshotRequest {
+		reqs := []*SnapshotRequest{
+			{KVBatch: []byte("batch 1")},
+			{KVBatch: []byte("batch 2")},
+			{LogEntries: [][]byte{[]byte("entry 1"), []byte("entry 2")}, Final: true},
+		}
+		sender := newSnapshotHasher()
+		for _, req := range reqs {
+			if err := sender.checksum(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return reqs
+	}
+	// verify returns the first error of the receiver on the requests.
+	verify := func(reqs []*SnapshotRequest) error {
+		receiver := newSnapshotHasher()
+		for _, req := range reqs {
+			if err := receiver.verify(req); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		name   string
+		modify func([]*SnapshotRequest) []*SnapshotRequest
+		expErr string
+	}{
+		{"intact", func(reqs []*SnapshotRequest) []*SnapshotRequest {
+			return reqs
+		}, ""},
+		{"corrupted batch", func(reqs []*SnapshotRequest) []*SnapshotRequest {
+			reqs[1].KVBatch[0] ^= 1
+			return reqs
+		}, "checksum mismatch on request 2 of the snapshot"},
+		{"corrupted log entry", func(reqs []*SnapshotRequest) []*SnapshotRequest {
+			reqs[2].LogEntries[1][0] ^= 1
+			return reqs
+		}, "checksum mismatch on request 3 of the snapshot"},
+		{"missing request", func(reqs []*SnapshotRequest) []*SnapshotRequest {
+			return append(reqs[:1], reqs[2:]...)
+		}, "hash mismatch on the 2 requests of the snapshot"},
+		{"reordered requests", func(reqs []*SnapshotRequest) []*SnapshotRequest {
+			reqs[0], reqs[1] = reqs[1], reqs[0]
+			return reqs
+		}, "hash mismatch on the 3 requests of the snapshot"},
+		{"unchecksummed", func(reqs []*SnapshotRequest) []*SnapshotRequest {
+			// A sender which predates the checksums.
+			for _, req := range reqs {
+				req.Checksum, req.Sha512 = nil, nil
+			}
+			return reqs
+		}, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verify(tc.modify(makeStream()))
+			if !testutils.IsError(err, tc.expErr) {
+				t.Fatalf("expected error %q, got %v", tc.expErr, err)
+			}
+		})
+	}
+}
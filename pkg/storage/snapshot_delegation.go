@@ -0,0 +1,260 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"github.com/coreos/etcd/raft"
+	"github.com/coreos/etcd/raft/raftpb"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+// Snapshot delegation lets the leader of a range ask a follower that is closer
+// to the recipient of a snapshot (by locality) to generate and send the
+// snapshot instead. When adding replicas in a new region this means the data
+// crosses the inter-region link once per region rather than once per
+// replica.
+//
+// The follower sends its own snapshot, so it has to be recent enough that the
+// recipient can catch up from the leader's log afterwards: followers are only
+// chosen if raft reports them caught up to at least the leader's truncated
+// index, and they re-check that their snapshot isn't older than that index
+// before sending it. If anything goes wrong the leader falls back to sending
+// the snapshot itself.
+var snapshotDelegationEnabled = settings.RegisterBoolSetting(
+	"kv.snapshot_delegation.enabled",
+	"if set, snapshots are sent by the follower closest to the recipient when it is "+
+		"closer than the leader",
+	false,
+)
+
+// snapshotDelegate returns the follower that should send a snapshot to
+// recipient on this replica's behalf, if there is one that is strictly closer
+// to the recipient than this replica. Only followers that have caught up to at
+// least minIndex are considered.
+func (r *Replica) snapshotDelegate(
+	recipient roachpb.ReplicaDescriptor, minIndex uint64,
+) (roachpb.ReplicaDescriptor, bool) {
+	storePool := r.store.allocator.storePool
+	if storePool == nil {
+		return roachpb.ReplicaDescriptor{}, false
+	}
+	return chooseSnapshotDelegate(
+		r.store.StoreID(), recipient, r.Desc().Replicas, r.RaftStatus(), minIndex,
+		storePool.getStoreDescriptor,
+	)
+}
+
+// chooseSnapshotDelegate returns the replica among replicas that is strictly
+// closer to recipient than the leader on localStoreID, and closest among
+// those. status is the raft status of the leader; replicas that haven't
+// caught up to at least minIndex aren't considered. getStoreDescriptor
+// provides the localities of the stores.
+func chooseSnapshotDelegate(
+	localStoreID roachpb.StoreID,
+	recipient roachpb.ReplicaDescriptor,
+	replicas []roachpb.ReplicaDescriptor,
+	status *raft.Status,
+	minIndex uint64,
+	getStoreDescriptor func(roachpb.StoreID) (roachpb.StoreDescriptor, bool),
+) (roachpb.ReplicaDescriptor, bool) {
+	if status == nil || status.RaftState != raft.StateLeader {
+		return roachpb.ReplicaDescriptor{}, false
+	}
+	recipientStore, ok := getStoreDescriptor(recipient.StoreID)
+	if !ok {
+		return roachpb.ReplicaDescriptor{}, false
+	}
+	localStore, ok := getStoreDescriptor(localStoreID)
+	if !ok {
+		return roachpb.ReplicaDescriptor{}, false
+	}
+
+	recipientLocality := recipientStore.Node.Locality
+	bestScore := localStore.Node.Locality.DiversityScore(recipientLocality)
+	var best roachpb.ReplicaDescriptor
+	found := false
+	for _, rep := range replicas {
+		if rep.StoreID == localStoreID || rep.StoreID == recipient.StoreID {
+			continue
+		}
+		progress, ok := status.Progress[uint64(rep.ReplicaID)]
+		if !ok || progress.State != raft.ProgressStateReplicate || progress.Match < minIndex {
+			continue
+		}
+		storeDesc, ok := getStoreDescriptor(rep.StoreID)
+		if !ok {
+			continue
+		}
+		if score := storeDesc.Node.Locality.DiversityScore(recipientLocality); score < bestScore {
+			best, bestScore, found = rep, score, true
+		}
+	}
+	return best, found
+}
+
+// maybeDelegateSnapshot tries to have a follower send the snapshot to
+// recipient. It returns true if the recipient applied a snapshot sent by a
+// delegate; otherwise the caller must send the snapshot itself.
+func (r *Replica) maybeDelegateSnapshot(
+	ctx context.Context,
+	recipient roachpb.ReplicaDescriptor,
+	snapType string,
+	priority SnapshotRequest_Priority,
+) (bool, error) {
+	if !snapshotDelegationEnabled.Get() {
+		return false, nil
+	}
+	r.mu.Lock()
+	truncState, err := r.raftTruncatedStateLocked(ctx)
+	r.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+	minIndex := truncState.Index
+	// A pending snapshot index of 1 or less has a special meaning (see
+	// setPendingSnapshotIndex); ranges this young are cheap to send anyway.
+	if minIndex <= 1 {
+		return false, nil
+	}
+	delegate, ok := r.snapshotDelegate(recipient, minIndex)
+	if !ok {
+		return false, nil
+	}
+	fromRepDesc, err := r.GetReplicaDescriptor()
+	if err != nil {
+		return false, err
+	}
+	status := r.RaftStatus()
+	if status == nil {
+		return false, errors.New("raft status not initialized")
+	}
+
+	if snapType == snapTypePreemptive {
+		// The delegate's snapshot is at least as recent as minIndex, so keeping
+		// the log from there on is enough for the recipient to catch up.
+		if err := r.setPendingSnapshotIndex(minIndex); err != nil {
+			return false, err
+		}
+	}
+
+	req := SnapshotRequest_Header{
+		State: storagebase.ReplicaState{
+			TruncatedState: &roachpb.RaftTruncatedState{Index: minIndex},
+		},
+		RaftMessageRequest: RaftMessageRequest{
+			RangeID:     r.RangeID,
+			FromReplica: delegate,
+			ToReplica:   recipient,
+			Message: raftpb.Message{
+				Type: raftpb.MsgSnap,
+				To:   uint64(recipient.ReplicaID),
+				From: uint64(fromRepDesc.ReplicaID),
+				Term: status.Term,
+			},
+		},
+		CanDecline: snapType == snapTypePreemptive,
+		Priority:   priority,
+	}
+	log.Eventf(ctx, "delegating %s snapshot to %s to %s", snapType, recipient, delegate)
+	if err := r.store.cfg.Transport.DelegateSnapshot(ctx, req); err != nil {
+		if snapType == snapTypePreemptive {
+			r.resetPendingSnapshotIndex()
+		}
+		return false, errors.Wrapf(err, "%s: delegating snapshot to %s failed", r, delegate)
+	}
+	return true, nil
+}
+
+// HandleDelegatedSnapshot implements the RaftMessageHandler interface.
+func (s *Store) HandleDelegatedSnapshot(
+	ctx context.Context, header *SnapshotRequest_Header,
+) error {
+	ctx = s.AnnotateCtx(ctx)
+	if s.IsDraining() {
+		return errors.New("store is draining")
+	}
+	r, err := s.GetReplica(header.RaftMessageRequest.RangeID)
+	if err != nil {
+		return err
+	}
+	return r.sendDelegatedSnapshot(ctx, header)
+}
+
+// sendDelegatedSnapshot sends a snapshot of this replica on behalf of the
+// leader named in the header.
+func (r *Replica) sendDelegatedSnapshot(ctx context.Context, header *SnapshotRequest_Header) error {
+	rmr := header.RaftMessageRequest
+	fromRepDesc, err := r.GetReplicaDescriptor()
+	if err != nil {
+		return err
+	}
+	if fromRepDesc.ReplicaID != rmr.FromReplica.ReplicaID {
+		return errors.Errorf("%s: asked to delegate for replica %s", r, rmr.FromReplica)
+	}
+	leader, ok := r.Desc().GetReplicaDescriptorByID(roachpb.ReplicaID(rmr.Message.From))
+	if !ok {
+		return errors.Errorf("%s: leader replica %d not found in range descriptor", r, rmr.Message.From)
+	}
+
+	snapType := snapTypeRaft
+	if rmr.ToReplica.ReplicaID == 0 {
+		snapType = snapTypePreemptive
+	}
+	snap, err := r.GetSnapshot(ctx, snapType)
+	if err != nil {
+		return errors.Wrapf(err, "%s: failed to generate delegated snapshot", r)
+	}
+	defer snap.Close()
+	log.Event(ctx, "generated delegated snapshot")
+
+	if ts := header.State.TruncatedState; ts != nil && snap.RaftSnap.Metadata.Index < ts.Index {
+		return errors.Errorf("%s: snapshot at index %d is older than the leader's truncated index %d",
+			r, snap.RaftSnap.Metadata.Index, ts.Index)
+	}
+
+	req := SnapshotRequest_Header{
+		State: snap.State,
+		RaftMessageRequest: RaftMessageRequest{
+			RangeID:     r.RangeID,
+			FromReplica: leader,
+			ToReplica:   rmr.ToReplica,
+			Message: raftpb.Message{
+				Type:     raftpb.MsgSnap,
+				To:       uint64(rmr.ToReplica.ReplicaID),
+				From:     uint64(leader.ReplicaID),
+				Term:     rmr.Message.Term,
+				Snapshot: snap.RaftSnap,
+			},
+		},
+		RangeSize:  r.GetMVCCStats().Total(),
+		CanDecline: header.CanDecline,
+		Priority:   header.Priority,
+	}
+	sent := func() {
+		r.store.metrics.RangeSnapshotsGenerated.Inc(1)
+		r.store.metrics.RangeSnapshotsDelegated.Inc(1)
+	}
+	if err := r.store.cfg.Transport.SendSnapshot(
+		ctx, r.store.allocator.storePool, req, snap, r.store.Engine().NewBatch, sent); err != nil {
+		return &snapshotError{err}
+	}
+	return nil
+}
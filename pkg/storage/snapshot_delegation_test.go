@@ -0,0 +1,102 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/coreos/etcd/raft"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestChooseSnapshotDelegate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	locality := func(region, zone string) roachpb.Locality {
+		return roachpb.Locality{Tiers: []roachpb.Tier{
+			{Key: "region", Value: region},
+			{Key: "zone", Value: zone},
+		}}
+	}
+	localities := map[roachpb.StoreID]roachpb.Locality{
+		1: locality("us", "a"),
+		2: locality("eu", "a"),
+		3: locality("eu", "b"),
+		4: locality("eu", "a"),
+		// Store 5 isn't known to the store pool.
+	}
+	getStoreDescriptor := func(storeID roachpb.StoreID) (roachpb.StoreDescriptor, bool) {
+		l, ok := localities[storeID]
+		return roachpb.StoreDescriptor{StoreID: storeID, Node: roachpb.NodeDescriptor{Locality: l}}, ok
+	}
+	replicas := []roachpb.ReplicaDescriptor{
+		{NodeID: 1, StoreID: 1, ReplicaID: 1},
+		{NodeID: 2, StoreID: 2, ReplicaID: 2},
+		{NodeID: 3, StoreID: 3, ReplicaID: 3},
+		{NodeID: 5, StoreID: 5, ReplicaID: 5},
+	}
+	recipient := roachpb.ReplicaDescriptor{NodeID: 4, StoreID: 4}
+	makeStatus := func(state raft.StateType, probing ...uint64) *raft.Status {
+		status := &raft.Status{Progress: make(map[uint64]raft.Progress)}
+		status.RaftState = state
+		for _, rep := range replicas {
+			status.Progress[uint64(rep.ReplicaID)] = raft.Progress{
+				State: raft.ProgressStateReplicate, Match: 10,
+			}
+		}
+		for _, id := range probing {
+			status.Progress[id] = raft.Progress{State: raft.ProgressStateProbe, Match: 10}
+		}
+		return status
+	}
+
+	testCases := []struct {
+		name      string
+		local     roachpb.StoreID
+		recipient roachpb.ReplicaDescriptor
+		status    *raft.Status
+		minIndex  uint64
+		expected  roachpb.StoreID // 0 if no delegate is expected
+	}{
+		{"closest follower", 1, recipient, makeStatus(raft.StateLeader), 10, 2},
+		{"closest follower probing", 1, recipient, makeStatus(raft.StateLeader, 2), 10, 3},
+		{"followers behind", 1, recipient, makeStatus(raft.StateLeader), 11, 0},
+		{"not leader", 1, recipient, makeStatus(raft.StateFollower), 10, 0},
+		{"no raft status", 1, recipient, nil, 10, 0},
+		{"leader as close", 2, recipient, makeStatus(raft.StateLeader), 10, 0},
+		{"leader in the same region", 3, recipient, makeStatus(raft.StateLeader), 10, 2},
+		{"unknown recipient", 1, roachpb.ReplicaDescriptor{NodeID: 6, StoreID: 6},
+			makeStatus(raft.StateLeader), 10, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			delegate, ok := chooseSnapshotDelegate(
+				tc.local, tc.recipient, replicas, tc.status, tc.minIndex, getStoreDescriptor,
+			)
+			if tc.expected == 0 {
+				if ok {
+					t.Fatalf("expected no delegate, got %s", delegate)
+				}
+				return
+			}
+			if !ok || delegate.StoreID != tc.expected {
+				t.Fatalf("expected the replica on store %d as delegate, got %s (%t)",
+					tc.expected, delegate, ok)
+			}
+		})
+	}
+}
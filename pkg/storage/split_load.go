@@ -0,0 +1,239 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+var (
+	// SplitByLoadEnabled controls whether ranges are split automatically when
+	// the load on them exceeds SplitByLoadQPSThreshold.
+	SplitByLoadEnabled = settings.RegisterBoolSetting(
+		"kv.range_split.by_load_enabled",
+		"set to enable automatic splitting of ranges based on their load",
+		false)
+
+	// SplitByLoadQPSThreshold is the number of queries per second above which a
+	// range is split when SplitByLoadEnabled is set.
+	SplitByLoadQPSThreshold = settings.RegisterIntSetting(
+		"kv.range_split.load_qps_threshold",
+		"the QPS over which a range is split by load",
+		250)
+)
+
+const (
+	// loadSplitQPSInterval is the interval at which the decaying QPS estimate
+	// of a range is updated.
+	loadSplitQPSInterval = time.Second
+	// loadSplitQPSDecay is the weight retained by the previous QPS estimate
+	// after each loadSplitQPSInterval.
+	loadSplitQPSDecay = 0.5
+	// loadSplitMinSampleDuration is the minimum amount of time during which
+	// request keys are sampled before a split key is chosen.
+	loadSplitMinSampleDuration = 10 * time.Second
+	// loadSplitSampleSize is the number of candidate split keys kept while
+	// sampling.
+	loadSplitSampleSize = 20
+	// loadSplitMinCounts is the minimum number of requests a candidate split
+	// key must have been compared against before it can be chosen.
+	loadSplitMinCounts = 100
+	// loadSplitMaxImbalance is the maximum acceptable score of a candidate
+	// split key; see loadSplitFinder.key.
+	loadSplitMaxImbalance = 0.5
+)
+
+// loadSplitDecider tracks the QPS of a replica with a decaying counter. While
+// the QPS exceeds SplitByLoadQPSThreshold, it samples the keys of the requests
+// to find a split key which divides the load evenly.
+type loadSplitDecider struct {
+	// intn is rand.Intn, overridable in tests.
+	intn func(n int) int
+
+	mu struct {
+		syncutil.Mutex
+		lastQPSRollover time.Time
+		count           int64
+		qps             float64
+		// finder is non-nil while the QPS is over the threshold.
+		finder *loadSplitFinder
+		// signaled is set once record returned true for the current finder.
+		signaled bool
+	}
+}
+
+func newLoadSplitDecider(now time.Time) *loadSplitDecider {
+	d := &loadSplitDecider{intn: rand.Intn}
+	d.mu.lastQPSRollover = now
+	return d
+}
+
+// record registers a request of n queries on the replica. span is only called
+// while keys are being sampled, and returns the span of the request. record
+// returns true the first time a split key becomes available, which is when
+// the replica should be added to the split queue.
+func (d *loadSplitDecider) record(now time.Time, n int, span func() roachpb.Span) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.mu.count += int64(n)
+	if elapsed := now.Sub(d.mu.lastQPSRollover); elapsed >= loadSplitQPSInterval {
+		d.rolloverLocked(now, elapsed)
+		if d.mu.qps <= float64(SplitByLoadQPSThreshold.Get()) {
+			d.mu.finder = nil
+			d.mu.signaled = false
+		} else if d.mu.finder == nil {
+			d.mu.finder = newLoadSplitFinder(now, d.intn)
+		}
+	}
+
+	if d.mu.finder == nil {
+		return false
+	}
+	d.mu.finder.record(span())
+	if d.mu.signaled || !d.mu.finder.ready(now) || d.mu.finder.key() == nil {
+		return false
+	}
+	d.mu.signaled = true
+	return true
+}
+
+// rolloverLocked folds the requests counted since the last rollover into the
+// QPS estimate. Intervals without any request decay the estimate as well.
+func (d *loadSplitDecider) rolloverLocked(now time.Time, elapsed time.Duration) {
+	intervals := float64(elapsed) / float64(loadSplitQPSInterval)
+	decay := math.Pow(loadSplitQPSDecay, intervals)
+	d.mu.qps = d.mu.qps*decay + (float64(d.mu.count)/elapsed.Seconds())*(1-decay)
+	d.mu.count = 0
+	d.mu.lastQPSRollover = now
+}
+
+// qps returns the decaying QPS estimate of the replica.
+func (d *loadSplitDecider) qps(now time.Time) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if elapsed := now.Sub(d.mu.lastQPSRollover); elapsed >= loadSplitQPSInterval {
+		d.rolloverLocked(now, elapsed)
+	}
+	return d.mu.qps
+}
+
+// maybeSplitKey returns the key at which the replica should be split to
+// divide its load, or nil if there is none.
+func (d *loadSplitDecider) maybeSplitKey(now time.Time) roachpb.Key {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.mu.finder == nil || !d.mu.finder.ready(now) {
+		return nil
+	}
+	return d.mu.finder.key()
+}
+
+// reset forgets the QPS estimate and the sampled keys. It is called after the
+// replica was split.
+func (d *loadSplitDecider) reset(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mu.lastQPSRollover = now
+	d.mu.count = 0
+	d.mu.qps = 0
+	d.mu.finder = nil
+	d.mu.signaled = false
+}
+
+// loadSplitSample is a candidate split key, along with the number of requests
+// which were entirely to its left, entirely to its right, or which contained
+// it since it was sampled.
+type loadSplitSample struct {
+	key                    roachpb.Key
+	left, right, contained int
+}
+
+// loadSplitFinder keeps a reservoir sample of the keys of the requests on a
+// replica, and chooses the one that divides the requests most evenly.
+type loadSplitFinder struct {
+	startTime time.Time
+	intn      func(n int) int
+	count     int
+	samples   [loadSplitSampleSize]loadSplitSample
+}
+
+func newLoadSplitFinder(now time.Time, intn func(n int) int) *loadSplitFinder {
+	return &loadSplitFinder{startTime: now, intn: intn}
+}
+
+func (f *loadSplitFinder) ready(now time.Time) bool {
+	return now.Sub(f.startTime) >= loadSplitMinSampleDuration
+}
+
+func (f *loadSplitFinder) record(span roachpb.Span) {
+	if span.Key == nil {
+		return
+	}
+	f.count++
+	idx := -1
+	if f.count <= len(f.samples) {
+		idx = f.count - 1
+	} else if j := f.intn(f.count); j < len(f.samples) {
+		idx = j
+	}
+
+	for i := range f.samples {
+		s := &f.samples[i]
+		if i == idx {
+			*s = loadSplitSample{key: append(roachpb.Key(nil), span.Key...)}
+			continue
+		}
+		if s.key == nil {
+			continue
+		}
+		if span.Key.Compare(s.key) >= 0 {
+			s.right++
+		} else if span.EndKey == nil || span.EndKey.Compare(s.key) <= 0 {
+			s.left++
+		} else {
+			s.contained++
+		}
+	}
+}
+
+// key returns the sampled key with the lowest score, or nil if no key has an
+// acceptable score. The score is the imbalance between the requests to the
+// left and to the right of the key, plus the fraction of requests that span
+// the key, since those would need to be split too.
+func (f *loadSplitFinder) key() roachpb.Key {
+	var best roachpb.Key
+	bestScore := loadSplitMaxImbalance
+	for i := range f.samples {
+		s := &f.samples[i]
+		total := s.left + s.right + s.contained
+		if s.key == nil || total < loadSplitMinCounts || s.left+s.right == 0 {
+			continue
+		}
+		balance := math.Abs(float64(s.left-s.right)) / float64(s.left+s.right)
+		score := balance + float64(s.contained)/float64(total)
+		if score < bestScore {
+			best = s.key
+			bestScore = score
+		}
+	}
+	return best
+}
@@ -0,0 +1,142 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/roachpb"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+)
+
+func TestLoadSplitDecider(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	start := time.Unix(0, 0)
+	d := newLoadSplitDecider(start)
+	d.intn = rand.New(rand.NewSource(1)).Intn
+
+	// Point requests spread uniformly over 100 keys.
+	keyAt := func(i int) roachpb.Key {
+		return roachpb.Key(fmt.Sprintf("k%02d", i%100))
+	}
+	pointSpan := func(i int) func() roachpb.Span {
+		return func() roachpb.Span {
+			key := keyAt(i)
+			return roachpb.Span{Key: key, EndKey: key.Next()}
+		}
+	}
+
+	// A load below the threshold doesn't cause keys to be sampled.
+	now := start
+	for i := 0; i < 1000; i++ {
+		now = now.Add(10 * time.Millisecond)
+		if d.record(now, 1, pointSpan(i)) {
+			t.Fatal("unexpected split at low QPS")
+		}
+	}
+	if qps := d.qps(now); qps > 100 || qps < 90 {
+		t.Errorf("expected a QPS of about 100, got %f", qps)
+	}
+	if key := d.maybeSplitKey(now); key != nil {
+		t.Fatalf("unexpected split key %s", key)
+	}
+
+	// At 1000 QPS, a split key is found once keys were sampled for long enough.
+	var signaled int
+	var signaledAt time.Time
+	for i := 0; i < 15000; i++ {
+		now = now.Add(time.Millisecond)
+		if d.record(now, 1, pointSpan(i)) {
+			signaled++
+			signaledAt = now
+		}
+	}
+	if signaled != 1 {
+		t.Fatalf("expected the split key to be signaled once, got %d", signaled)
+	}
+	if min := start.Add(10*time.Second + loadSplitMinSampleDuration); signaledAt.Before(min) {
+		t.Errorf("split key signaled at %s, before %s", signaledAt, min)
+	}
+	key := d.maybeSplitKey(now)
+	if key == nil {
+		t.Fatal("expected a split key")
+	}
+	// The chosen key divides the load with an imbalance of at most
+	// loadSplitMaxImbalance.
+	if key.Compare(keyAt(25)) <= 0 || key.Compare(keyAt(75)) >= 0 {
+		t.Errorf("expected a split key between %s and %s, got %s", keyAt(25), keyAt(75), key)
+	}
+
+	d.reset(now)
+	if key := d.maybeSplitKey(now); key != nil {
+		t.Errorf("unexpected split key %s after reset", key)
+	}
+	if qps := d.qps(now); qps != 0 {
+		t.Errorf("expected a QPS of 0 after reset, got %f", qps)
+	}
+}
+
+func TestLoadSplitFinder(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	start := time.Unix(0, 0)
+	testCases := []struct {
+		spans    []roachpb.Span
+		expected roachpb.Key
+	}{
+		// Requests which all contain the sampled keys can't be split.
+		{
+			spans: []roachpb.Span{
+				{Key: roachpb.Key("a"), EndKey: roachpb.Key("z")},
+				{Key: roachpb.Key("b"), EndKey: roachpb.Key("z")},
+			},
+			expected: nil,
+		},
+		// Requests on a single key can't be split.
+		{
+			spans: []roachpb.Span{
+				{Key: roachpb.Key("a")},
+			},
+			expected: nil,
+		},
+		// Requests on two keys are split between them.
+		{
+			spans: []roachpb.Span{
+				{Key: roachpb.Key("a")},
+				{Key: roachpb.Key("b")},
+			},
+			expected: roachpb.Key("b"),
+		},
+	}
+	for i, c := range testCases {
+		f := newLoadSplitFinder(start, rand.New(rand.NewSource(1)).Intn)
+		for j := 0; j < 10*loadSplitMinCounts; j++ {
+			f.record(c.spans[j%len(c.spans)])
+		}
+		if f.ready(start) {
+			t.Errorf("%d: finder unexpectedly ready", i)
+		}
+		if !f.ready(start.Add(loadSplitMinSampleDuration)) {
+			t.Errorf("%d: finder unexpectedly not ready", i)
+		}
+		if key := f.key(); !key.Equal(c.expected) {
+			t.Errorf("%d: expected split key %s, got %s", i, c.expected, key)
+		}
+	}
+}
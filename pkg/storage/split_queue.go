@@ -34,7 +34,7 @@ const (
 	splitQueueTimerDuration = 0 // zero duration to process splits greedily.
 )
 
-// splitQueue manages a queue of ranges slated to be split due to size
+// splitQueue manages a queue of ranges slated to be split due to size, load
 // or along intersecting zone config boundaries.
 type splitQueue struct {
 	*baseQueue
@@ -64,7 +64,8 @@ func newSplitQueue(store *Store, db *client.DB, gossip *gossip.Gossip) *splitQue
 
 // shouldQueue determines whether a range should be queued for
 // splitting. This is true if the range is intersected by a zone config
-// prefix or if the range's size in bytes exceeds the limit for the zone.
+// prefix, if the range's size in bytes exceeds the limit for the zone, or if
+// the load on the range calls for a split (see SplitByLoadEnabled).
 func (sq *splitQueue) shouldQueue(
 	ctx context.Context, now hlc.Timestamp, repl *Replica, sysCfg config.SystemConfig,
 ) (shouldQ bool, priority float64) {
@@ -81,6 +82,11 @@ func (sq *splitQueue) shouldQueue(
 		priority += ratio
 		shouldQ = true
 	}
+
+	if repl.loadSplitKey() != nil {
+		priority++
+		shouldQ = true
+	}
 	return
 }
 
@@ -104,6 +110,28 @@ func (sq *splitQueue) process(ctx context.Context, r *Replica, sysCfg config.Sys
 		return nil
 	}
 
+	// Next handle case of splitting due to load.
+	if splitKey := r.loadSplitKey(); splitKey != nil {
+		_, _, pErr := r.adminSplitWithDescriptor(
+			ctx,
+			roachpb.AdminSplitRequest{
+				Span: roachpb.Span{
+					Key: splitKey,
+				},
+				SplitKey: splitKey,
+			},
+			desc,
+		)
+		// Start over whether or not the split succeeded, so that a different key
+		// is chosen if the load persists.
+		r.loadSplitter.reset(time.Unix(0, r.store.Clock().PhysicalNow()))
+		if pErr != nil {
+			return errors.Wrapf(pErr.GoError(), "unable to split %s by load at key %q", r, splitKey)
+		}
+		r.store.metrics.RangeSplitsByLoad.Inc(1)
+		return nil
+	}
+
 	// Next handle case of splitting due to size. Note that we don't perform
 	// size-based splitting if maxBytes is 0 (happens in certain test
 	// situations).
@@ -142,3 +170,12 @@ func (*splitQueue) timer(_ time.Duration) time.Duration {
 func (*splitQueue) purgatoryChan() <-chan struct{} {
 	return nil
 }
+
+// loadSplitKey returns the key at which the replica should be split because
+// of its load, or nil if it shouldn't be split by load.
+func (r *Replica) loadSplitKey() roachpb.Key {
+	if !SplitByLoadEnabled.Get() {
+		return nil
+	}
+	return r.loadSplitter.maybeSplitKey(time.Unix(0, r.store.Clock().PhysicalNow()))
+}
@@ -43,8 +43,10 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/rpc"
 	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/sql/sqlutil"
+	"github.com/cockroachdb/cockroach/pkg/storage/closedts"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine"
 	"github.com/cockroachdb/cockroach/pkg/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/pkg/storage/raftentry"
 	"github.com/cockroachdb/cockroach/pkg/storage/storagebase"
 	"github.com/cockroachdb/cockroach/pkg/util/bufalloc"
 	"github.com/cockroachdb/cockroach/pkg/util/envutil"
@@ -402,7 +404,8 @@ type Store struct {
 	consistencyQueue   *consistencyQueue           // Replica consistency check queue
 	metrics            *StoreMetrics
 	intentResolver     *intentResolver
-	raftEntryCache     *raftEntryCache
+	raftEntryCache     *raftentry.Cache
+	closedTimestamps   *closedts.Tracker // Closed timestamps of the replicas
 
 	// gossipRangeCountdown and leaseRangeCountdown are countdowns of
 	// changes to range and leaseholder counts, after which the store
@@ -913,7 +916,10 @@ func NewStore(cfg StoreConfig, eng engine.Engine, nodeDesc *roachpb.NodeDescript
 		})
 	}
 	s.intentResolver = newIntentResolver(s)
-	s.raftEntryCache = newRaftEntryCache(cfg.RaftEntryCacheSize)
+	s.raftEntryCache = raftentry.NewCache(cfg.RaftEntryCacheSize)
+	s.metrics.registry.AddMetricStruct(s.raftEntryCache.Metrics())
+	s.closedTimestamps = closedts.NewTracker()
+	s.metrics.registry.AddMetricStruct(s.closedTimestamps.Metrics())
 	s.draining.Store(false)
 	s.scheduler = newRaftScheduler(s.cfg.AmbientCtx, s.metrics, s, storeSchedulerConcurrency)
 
@@ -1225,14 +1231,16 @@ func (s *Store) Start(ctx context.Context, stopper *stop.Stopper) error {
 	if s.cfg.Gossip != nil {
 		// Register update channel for any changes to the system config.
 		// This may trigger splits along structured boundaries,
-		// and update max range bytes.
-		gossipUpdateC := s.cfg.Gossip.RegisterSystemConfigChannel()
+		// and update max range bytes. Only the zone configs affected by the
+		// changes to the system config are looked up again.
+		watcher := s.cfg.Gossip.WatchSystemConfig(config.ZoneConfigCacheSpan)
+		zones := config.NewZoneConfigCache()
 		s.stopper.RunWorker(ctx, func(context.Context) {
 			for {
 				select {
-				case <-gossipUpdateC:
-					cfg, _ := s.cfg.Gossip.GetSystemConfig()
-					s.systemGossipUpdate(cfg)
+				case <-watcher.C:
+					zones.Update(watcher.Changes())
+					s.systemGossipUpdate(zones)
 				case <-s.stopper.ShouldStop():
 					return
 				}
@@ -1370,10 +1378,10 @@ func (s *Store) startGossip() {
 
 // systemGossipUpdate is a callback for gossip updates to
 // the system config which affect range split boundaries.
-func (s *Store) systemGossipUpdate(cfg config.SystemConfig) {
+func (s *Store) systemGossipUpdate(zones *config.ZoneConfigCache) {
 	// For every range, update its MaxBytes and check if it needs to be split.
 	newStoreReplicaVisitor(s).Visit(func(repl *Replica) bool {
-		if zone, err := cfg.GetZoneConfigForKey(repl.Desc().StartKey); err == nil {
+		if zone, err := zones.GetZoneConfigForKey(repl.Desc().StartKey); err == nil {
 			repl.SetMaxBytes(zone.RangeMaxBytes)
 		}
 		s.splitQueue.MaybeAdd(repl, s.cfg.Clock.Now())
@@ -2221,6 +2229,7 @@ func (s *Store) removeReplicaImpl(
 		log.Fatalf(ctx, "replica %+v unexpectedly overlapped by %+v", rep, placeholder)
 	}
 	delete(s.mu.replicaPlaceholders, rep.RangeID)
+	s.closedTimestamps.Remove(rep.RangeID)
 	// TODO(peter): Could release s.mu.Lock() here.
 	s.maybeGossipOnCapacityChange(ctx, rangeChangeEvent)
 	s.scanner.RemoveReplica(rep)
@@ -2763,6 +2772,7 @@ func (s *Store) HandleSnapshot(
 
 	var batches [][]byte
 	var logEntries [][]byte
+	hasher := newSnapshotHasher()
 	for {
 		req, err := stream.Recv()
 		if err != nil {
@@ -2771,6 +2781,9 @@ func (s *Store) HandleSnapshot(
 		if req.Header != nil {
 			return sendSnapError(errors.New("client error: provided a header mid-stream"))
 		}
+		if err := hasher.verify(req); err != nil {
+			return sendSnapError(errors.Wrap(err, "corrupted snapshot"))
+		}
 
 		if req.KVBatch != nil {
 			batches = append(batches, req.KVBatch)
@@ -3308,6 +3321,7 @@ func sendSnapshot(
 	// unreplicated keys from the snapshot.
 	unreplicatedPrefix := keys.MakeRangeIDUnreplicatedPrefix(header.State.Desc.RangeID)
 	var alloc bufalloc.ByteAllocator
+	hasher := newSnapshotHasher()
 	n := 0
 	var b engine.Batch
 	for ; ; snap.Iter.Next() {
@@ -3339,7 +3353,7 @@ func sendSnapshot(
 			if err := limiter.WaitN(ctx, 1); err != nil {
 				return err
 			}
-			if err := sendBatch(stream, b); err != nil {
+			if err := sendBatch(stream, hasher, b); err != nil {
 				return err
 			}
 			b = nil
@@ -3352,7 +3366,7 @@ func sendSnapshot(
 		if err := limiter.WaitN(ctx, 1); err != nil {
 			return err
 		}
-		if err := sendBatch(stream, b); err != nil {
+		if err := sendBatch(stream, hasher, b); err != nil {
 			return err
 		}
 	}
@@ -3377,6 +3391,9 @@ func sendSnapshot(
 		LogEntries: logEntries,
 		Final:      true,
 	}
+	if err := hasher.checksum(req); err != nil {
+		return err
+	}
 	// Notify the sent callback before the final snapshot request is sent so that
 	// the snapshots generated metric gets incremented before the snapshot is
 	// applied.
@@ -3409,10 +3426,13 @@ func sendSnapshot(
 	}
 }
 
-func sendBatch(stream OutgoingSnapshotStream, batch engine.Batch) error {
-	repr := batch.Repr()
+func sendBatch(stream OutgoingSnapshotStream, hasher *snapshotHasher, batch engine.Batch) error {
+	req := &SnapshotRequest{KVBatch: batch.Repr()}
 	batch.Close()
-	return stream.Send(&SnapshotRequest{KVBatch: repr})
+	if err := hasher.checksum(req); err != nil {
+		return err
+	}
+	return stream.Send(req)
 }
 
 // enqueueRaftUpdateCheck asynchronously registers the given range ID to be
@@ -4023,6 +4043,7 @@ func (s *Store) ComputeMetrics(ctx context.Context, tick int) error {
 	if err := s.updateCommandQueueGauges(); err != nil {
 		return err
 	}
+	s.closedTimestamps.Update(ctx, s.Clock().Now())
 
 	// Get the latest RocksDB stats.
 	stats, err := s.engine.GetStats()
@@ -0,0 +1,152 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package jobutils contains helpers for testing the jobs framework.
+package jobutils
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kr/pretty"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/jobs"
+	"github.com/cockroachdb/cockroach/pkg/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/pkg/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// VerifySystemJob checks that the offset-th job in crdb_internal.jobs, in
+// order of creation, has the expected type, status and record. The Details of
+// the expected record are ignored.
+func VerifySystemJob(
+	db *sqlutils.SQLRunner,
+	offset int,
+	expectedType string,
+	expectedStatus jobs.JobStatus,
+	expected jobs.JobRecord,
+) error {
+	var actual jobs.JobRecord
+	var rawDescriptorIDs pq.Int64Array
+	var actualType string
+	var statusString string
+	// We have to query for the nth job created rather than filtering by ID,
+	// because job-generating SQL queries (e.g. BACKUP) do not currently return
+	// the job ID.
+	db.QueryRow(`
+		SELECT type, description, username, descriptor_ids, status
+		FROM crdb_internal.jobs ORDER BY created LIMIT 1 OFFSET $1`,
+		offset,
+	).Scan(
+		&actualType, &actual.Description, &actual.Username, &rawDescriptorIDs,
+		&statusString,
+	)
+
+	for _, id := range rawDescriptorIDs {
+		actual.DescriptorIDs = append(actual.DescriptorIDs, sqlbase.ID(id))
+	}
+	sort.Sort(actual.DescriptorIDs)
+	sort.Sort(expected.DescriptorIDs)
+	expected.Details = nil
+	if e, a := expected, actual; !reflect.DeepEqual(e, a) {
+		return errors.Errorf("job %d did not match:\n%s",
+			offset, strings.Join(pretty.Diff(e, a), "\n"))
+	}
+
+	if e, a := expectedStatus, jobs.JobStatus(statusString); e != a {
+		return errors.Errorf("job %d: expected status %v, got %v", offset, e, a)
+	}
+	if e, a := expectedType, actualType; e != a {
+		return errors.Errorf("job %d: expected type %v, got type %v", offset, e, a)
+	}
+
+	return nil
+}
+
+// Checkpoint pauses jobs when they reach a given event, until the test
+// releases them. It is installed through the jobs.TestingKnobs returned by
+// Knob:
+//
+//   cp := jobutils.NewCheckpoint(jobs.JobEventProgressed)
+//   params.Knobs.SQLJobs = &jobs.TestingKnobs{BeforeJobEvent: cp.Knob}
+//   ...
+//   jobID := cp.Wait()
+//   // Inspect or disturb the paused job.
+//   cp.Release(nil)
+//
+// Once released, the checkpoint lets all jobs through.
+type Checkpoint struct {
+	event    jobs.JobEvent
+	reachedC chan int64
+	releaseC chan struct{}
+
+	mu struct {
+		syncutil.Mutex
+		released bool
+		err      error
+	}
+}
+
+// NewCheckpoint returns a Checkpoint pausing jobs at the given event.
+func NewCheckpoint(event jobs.JobEvent) *Checkpoint {
+	return &Checkpoint{
+		event:    event,
+		reachedC: make(chan int64, 1),
+		releaseC: make(chan struct{}),
+	}
+}
+
+// Knob is a jobs.TestingKnobs.BeforeJobEvent callback. A job reaching the
+// checkpoint's event blocks until the checkpoint is released, and then fails
+// with the error passed to Release, if any.
+func (c *Checkpoint) Knob(jobID int64, event jobs.JobEvent) error {
+	if event != c.event {
+		return nil
+	}
+	c.mu.Lock()
+	released := c.mu.released
+	c.mu.Unlock()
+	if !released {
+		select {
+		case c.reachedC <- jobID:
+		default:
+			// Another job is already waiting to be reported.
+		}
+		<-c.releaseC
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mu.err
+}
+
+// Wait blocks until a job reaches the checkpoint and returns its ID.
+func (c *Checkpoint) Wait() int64 {
+	return <-c.reachedC
+}
+
+// Release lets the jobs paused at the checkpoint, and all later ones,
+// proceed. If err is non-nil, it is injected as the result of their event.
+func (c *Checkpoint) Release(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mu.released {
+		return
+	}
+	c.mu.released = true
+	c.mu.err = err
+	close(c.releaseC)
+}
@@ -71,9 +71,34 @@ type Clock struct {
 		// lastPhysicalTime reports the last measured physical time. This
 		// is used to detect clock jumps.
 		lastPhysicalTime int64
+
+		// forwardJumpGuard is the largest advance of the physical clock between
+		// two readings which isn't considered a forward jump. Zero disables
+		// the detection of forward jumps. See SetForwardJumpGuard.
+		forwardJumpGuard time.Duration
+		// skew is the amount of time subtracted from the physical clock while
+		// a forward jump is being smoothed.
+		skew int64
+		// jumpHandler, if set, is notified of the detected clock jumps.
+		jumpHandler func(ClockJump)
 	}
 }
 
+// forwardJumpSlewRatio is the ratio between the time elapsing and the time of
+// a smoothed forward jump absorbed meanwhile; while a jump is being absorbed,
+// the clock runs 1/forwardJumpSlewRatio slower than the physical clock.
+const forwardJumpSlewRatio = 10
+
+// ClockJump describes a jump of the physical clock detected by a Clock.
+type ClockJump struct {
+	// Jump is the difference between two consecutive readings of the physical
+	// clock. It is negative for backward jumps.
+	Jump time.Duration
+	// Smoothed is set for the forward jumps which are absorbed gradually
+	// instead of being reflected in the clock at once.
+	Smoothed bool
+}
+
 // ManualClock is a convenience type to facilitate
 // creating a hybrid logical clock whose physical clock
 // is manually controlled. ManualClock is thread safe.
@@ -134,21 +159,66 @@ func (c *Clock) MaxOffset() time.Duration {
 	return c.maxOffset
 }
 
+// SetForwardJumpGuard enables the detection of forward jumps of the physical
+// clock larger than guard between two readings; zero disables it. This only
+// makes sense if the clock is read more often than guard, as done by the
+// server's clock monitor.
+//
+// A forward jump no larger than the maximum offset is smoothed: the clock only
+// advances by guard at once, and the rest of the jump is absorbed by running
+// the clock slower for a while, so that the timestamps handed out don't leap
+// ahead of the other nodes' clocks.
+func (c *Clock) SetForwardJumpGuard(guard time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mu.forwardJumpGuard = guard
+}
+
+// SetJumpHandler registers a function notified of the jumps of the physical
+// clock. It is called with the clock's lock held, so it must not block nor
+// call into the clock.
+func (c *Clock) SetJumpHandler(handler func(ClockJump)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mu.jumpHandler = handler
+}
+
 // getPhysicalClockLocked returns the current physical clock and checks for
 // time jumps.
 func (c *Clock) getPhysicalClockLocked() int64 {
 	newTime := c.physicalClock()
 
 	if c.mu.lastPhysicalTime != 0 {
-		interval := c.mu.lastPhysicalTime - newTime
-		if interval > int64(c.maxOffset/10) {
+		interval := newTime - c.mu.lastPhysicalTime
+		if -interval > int64(c.maxOffset/10) {
 			c.mu.monotonicityErrorsCount++
-			log.Warningf(context.TODO(), "backward time jump detected (%f seconds)", float64(newTime-c.mu.lastPhysicalTime)/1e9)
+			log.Warningf(context.TODO(), "backward time jump detected (%f seconds)", float64(interval)/1e9)
+			c.reportJumpLocked(ClockJump{Jump: time.Duration(interval)})
+		} else if guard := int64(c.mu.forwardJumpGuard); guard > 0 && interval > guard {
+			jump := ClockJump{Jump: time.Duration(interval)}
+			if c.maxOffset > 0 && interval <= int64(c.maxOffset) {
+				c.mu.skew += interval - guard
+				jump.Smoothed = true
+			}
+			log.Warningf(context.TODO(), "forward time jump detected (%f seconds)", float64(interval)/1e9)
+			c.reportJumpLocked(jump)
+		} else if c.mu.skew > 0 && interval > 0 {
+			absorbed := interval / forwardJumpSlewRatio
+			if absorbed > c.mu.skew {
+				absorbed = c.mu.skew
+			}
+			c.mu.skew -= absorbed
 		}
 	}
 
 	c.mu.lastPhysicalTime = newTime
-	return newTime
+	return newTime - c.mu.skew
+}
+
+func (c *Clock) reportJumpLocked(jump ClockJump) {
+	if c.mu.jumpHandler != nil {
+		c.mu.jumpHandler(jump)
+	}
 }
 
 // Now returns a timestamp associated with an event from
@@ -165,6 +165,50 @@ func TestExampleManualClock(t *testing.T) {
 	}
 }
 
+func TestHLCClockJumps(t *testing.T) {
+	m := NewManualClock(int64(time.Hour))
+	c := NewClock(m.UnixNano, 500*time.Millisecond)
+	var jumps []ClockJump
+	c.SetJumpHandler(func(j ClockJump) {
+		jumps = append(jumps, j)
+	})
+	c.SetForwardJumpGuard(100 * time.Millisecond)
+
+	start := m.UnixNano()
+	testCases := []struct {
+		incr     time.Duration
+		expected time.Duration // expected physical time, relative to start
+		jump     *ClockJump
+	}{
+		{0, 0, nil},
+		{50 * time.Millisecond, 50 * time.Millisecond, nil},
+		// A small forward jump is smoothed: the clock only advances by the guard.
+		{120 * time.Millisecond, 150 * time.Millisecond, &ClockJump{Jump: 120 * time.Millisecond, Smoothed: true}},
+		// The remaining 20ms are absorbed at a tenth of the elapsed time.
+		{90 * time.Millisecond, 249 * time.Millisecond, nil},
+		{90 * time.Millisecond, 348 * time.Millisecond, nil},
+		{90 * time.Millisecond, 440 * time.Millisecond, nil},
+		// A forward jump larger than the maximum offset is reflected at once.
+		{time.Second, 1440 * time.Millisecond, &ClockJump{Jump: time.Second}},
+		// Backward jumps are reported too.
+		{-100 * time.Millisecond, 1340 * time.Millisecond, &ClockJump{Jump: -100 * time.Millisecond}},
+	}
+	for i, tc := range testCases {
+		jumps = nil
+		m.Increment(tc.incr.Nanoseconds())
+		if now := time.Duration(c.PhysicalNow() - start); now != tc.expected {
+			t.Errorf("%d: expected physical time %s, got %s", i, tc.expected, now)
+		}
+		if tc.jump == nil {
+			if len(jumps) != 0 {
+				t.Errorf("%d: unexpected jumps %+v", i, jumps)
+			}
+		} else if len(jumps) != 1 || jumps[0] != *tc.jump {
+			t.Errorf("%d: expected jump %+v, got %+v", i, *tc.jump, jumps)
+		}
+	}
+}
+
 func TestHLCMonotonicityCheck(t *testing.T) {
 	m := NewManualClock(100000)
 	c := NewClock(m.UnixNano, 100*time.Nanosecond)
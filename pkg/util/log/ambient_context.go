@@ -18,6 +18,7 @@ package log
 
 import (
 	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 	otlog "github.com/opentracing/opentracing-go/log"
 	"golang.org/x/net/context"
 	"golang.org/x/net/trace"
@@ -63,6 +64,10 @@ type AmbientContext struct {
 	// log or an open span (if not nil).
 	eventLog *ctxEventLog
 
+	// subsystem is the subsystem (e.g. sql, kv or raft) of the component using
+	// the AmbientContext; see SetSubsystem.
+	subsystem string
+
 	tags *logTag
 
 	// Cached annotated version of context.{TODO,Background}, to avoid annotating
@@ -99,6 +104,15 @@ func (ac *AmbientContext) AddLogTagStr(name string, value string) {
 	ac.refreshCache()
 }
 
+// SetSubsystem sets the subsystem of the component using the AmbientContext;
+// see the tracing.Subsystem constants. The spans opened by
+// AnnotateCtxWithSpan are tagged with the subsystem as their component, which
+// groups them with the other spans of the subsystem in the /debug/requests
+// page, and the event log set up by StartEventLog is named after it.
+func (ac *AmbientContext) SetSubsystem(subsystem string) {
+	ac.subsystem = subsystem
+}
+
 // SetEventLog sets up an event log. Annotated contexts log into this event log
 // (unless there's an open Span).
 func (ac *AmbientContext) SetEventLog(family, title string) {
@@ -106,6 +120,21 @@ func (ac *AmbientContext) SetEventLog(family, title string) {
 	ac.refreshCache()
 }
 
+// StartEventLog sets up a long-lived event log in the family of the subsystem
+// of the AmbientContext (see SetEventLog and SetSubsystem). It returns the
+// function finishing the event log, which is meant to be tied to the lifecycle
+// of the component:
+//
+//   stopper.AddCloser(stop.CloserFn(ac.StartEventLog("gossip")))
+func (ac *AmbientContext) StartEventLog(title string) func() {
+	family := ac.subsystem
+	if family == "" {
+		family = title
+	}
+	ac.SetEventLog(family, title)
+	return ac.FinishEventLog
+}
+
 // FinishEventLog closes the event log. Concurrent and subsequent calls to
 // record events from contexts that use this event log embedded are allowed.
 func (ac *AmbientContext) FinishEventLog() {
@@ -196,15 +225,20 @@ func (ac *AmbientContext) AnnotateCtxWithSpan(
 		}
 	}
 
+	var opts []opentracing.StartSpanOption
+	if ac.subsystem != "" {
+		opts = append(opts, opentracing.Tag{Key: string(ext.Component), Value: ac.subsystem})
+	}
 	var span opentracing.Span
 	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
 		tracer := parentSpan.Tracer()
-		span = tracer.StartSpan(opName, opentracing.ChildOf(parentSpan.Context()))
+		opts = append(opts, opentracing.ChildOf(parentSpan.Context()))
+		span = tracer.StartSpan(opName, opts...)
 	} else {
 		if ac.Tracer == nil {
 			panic("no tracer in AmbientContext for root span")
 		}
-		span = ac.Tracer.StartSpan(opName)
+		span = ac.Tracer.StartSpan(opName, opts...)
 	}
 	return opentracing.ContextWithSpan(ctx, span), span
 }
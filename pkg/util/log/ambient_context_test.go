@@ -21,6 +21,8 @@ import (
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 )
 
 func TestAnnotateCtxTags(t *testing.T) {
@@ -85,6 +87,27 @@ func TestAnnotateCtxSpan(t *testing.T) {
 	}
 }
 
+func TestAnnotateCtxSubsystem(t *testing.T) {
+	tr := tracing.NewTracer().(*tracing.Tracer)
+	c := tracing.NewTestCollector(tr)
+	defer c.Close()
+
+	ac := AmbientContext{Tracer: tr}
+	ac.SetSubsystem(tracing.SubsystemKV)
+
+	ctx, sp := ac.AnnotateCtxWithSpan(context.Background(), "root")
+	_, childSp := ac.AnnotateCtxWithSpan(ctx, "child")
+	childSp.Finish()
+	sp.Finish()
+
+	if err := c.CheckChildOf("root", "child"); err != nil {
+		t.Fatal(err)
+	}
+	if spans := c.FindSpansWithTag("component", tracing.SubsystemKV); len(spans) != 2 {
+		t.Errorf("expected both spans to be tagged with the subsystem, got %v", c.Spans())
+	}
+}
+
 func TestAnnotateCtxNodeStoreReplica(t *testing.T) {
 	// Test the scenario of a context being continually re-annotated as it is
 	// passed down a call stack.
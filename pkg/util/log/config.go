@@ -0,0 +1,182 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/util/humanizeutil"
+	"github.com/cockroachdb/cockroach/pkg/util/log/logflags"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// configFile is the value of the --log-config-file flag: a file of logging
+// flags, one name=value per line, which is applied at startup and can be
+// reloaded while the process runs.
+var configFile string
+
+// configMu serializes the reloads of the config file.
+var configMu syncutil.Mutex
+
+// ConfigFile returns the path of the logging config file, or "" if
+// --log-config-file wasn't specified.
+func ConfigFile() string {
+	return configFile
+}
+
+// reloadableFlags returns the values of the flags which can be set in the
+// config file, by name. The other logging flags, e.g. --log-dir, can only be
+// set when the process starts.
+func reloadableFlags() map[string]flag.Value {
+	return map[string]flag.Value{
+		logflags.VerbosityName:                 &logging.verbosity,
+		logflags.VModuleName:                   &logging.vmodule,
+		logflags.LogBacktraceAtName:            &logging.traceLocation,
+		logflags.LogToStderrName:               &logging.stderrThreshold,
+		logflags.LogFileVerbosityThresholdName: &logging.fileThreshold,
+		logflags.LogFileMaxSizeName:            humanizeutil.NewBytesValue(&LogFileMaxSize),
+		logflags.LogFilesCombinedMaxSizeName:   humanizeutil.NewBytesValue(&LogFilesCombinedMaxSize),
+	}
+}
+
+// configSetting is a name=value line of the config file.
+type configSetting struct {
+	line        int
+	name, value string
+}
+
+// parseConfig parses the contents of a config file. Blank lines and lines
+// starting with # are ignored, and the names may be prefixed with "--" as on
+// the command line.
+func parseConfig(data []byte) ([]configSetting, error) {
+	flags := reloadableFlags()
+	var settings []configSetting
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		i := strings.IndexByte(s, '=')
+		if i < 0 {
+			return nil, errors.Errorf("line %d: expected name=value, found %q", line, s)
+		}
+		name := strings.TrimPrefix(strings.TrimSpace(s[:i]), "--")
+		if _, ok := flags[name]; !ok {
+			return nil, errors.Errorf("line %d: --%s can't be set in the logging config file", line, name)
+		}
+		settings = append(settings, configSetting{
+			line:  line,
+			name:  name,
+			value: strings.TrimSpace(s[i+1:]),
+		})
+	}
+	return settings, scanner.Err()
+}
+
+// configState is a snapshot of the settings of the reloadable flags.
+type configState struct {
+	verbosity        level
+	filter           []modulePat
+	traceFile        string
+	traceLine        int
+	stderrThreshold  Severity
+	fileThreshold    Severity
+	fileMaxSize      int64
+	filesCombinedMax int64
+}
+
+func saveConfigState() configState {
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	return configState{
+		verbosity:        logging.verbosity.get(),
+		filter:           append([]modulePat(nil), logging.vmodule.filter...),
+		traceFile:        logging.traceLocation.file,
+		traceLine:        logging.traceLocation.line,
+		stderrThreshold:  logging.stderrThreshold.get(),
+		fileThreshold:    logging.fileThreshold.get(),
+		fileMaxSize:      atomic.LoadInt64(&LogFileMaxSize),
+		filesCombinedMax: atomic.LoadInt64(&LogFilesCombinedMaxSize),
+	}
+}
+
+func (s configState) restore() {
+	logging.mu.Lock()
+	defer logging.mu.Unlock()
+	logging.setVState(s.verbosity, s.filter, true)
+	logging.traceLocation.file = s.traceFile
+	logging.traceLocation.line = s.traceLine
+	logging.stderrThreshold.set(s.stderrThreshold)
+	logging.fileThreshold.set(s.fileThreshold)
+	atomic.StoreInt64(&LogFileMaxSize, s.fileMaxSize)
+	atomic.StoreInt64(&LogFilesCombinedMaxSize, s.filesCombinedMax)
+}
+
+// applyConfig applies the settings of a config file. If one of them is
+// invalid, the previous settings are restored and an error is returned.
+func applyConfig(settings []configSetting) error {
+	prev := saveConfigState()
+	flags := reloadableFlags()
+	for _, s := range settings {
+		if err := flags[s.name].Set(s.value); err != nil {
+			prev.restore()
+			return errors.Wrapf(err, "line %d: invalid value %q for --%s", s.line, s.value, s.name)
+		}
+	}
+	if atomic.LoadInt64(&LogFileMaxSize) <= 0 {
+		prev.restore()
+		return errors.Errorf("--%s must be positive", logflags.LogFileMaxSizeName)
+	}
+	if atomic.LoadInt64(&LogFilesCombinedMaxSize) < atomic.LoadInt64(&LogFileMaxSize) {
+		prev.restore()
+		return errors.Errorf("--%s must be at least --%s",
+			logflags.LogFilesCombinedMaxSizeName, logflags.LogFileMaxSizeName)
+	}
+	return nil
+}
+
+// ReloadConfig reads the logging config file and applies its settings. The
+// settings are validated: if one of them is invalid, none is applied and an
+// error is returned. Settings which were applied by a previous load of the
+// file, but aren't in it anymore, are left as they are.
+func ReloadConfig(ctx context.Context) error {
+	if configFile == "" {
+		return errors.Errorf("--%s not specified", logflags.LogConfigFileName)
+	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	settings, err := parseConfig(data)
+	if err != nil {
+		return errors.Wrap(err, configFile)
+	}
+	if err := applyConfig(settings); err != nil {
+		return errors.Wrap(err, configFile)
+	}
+	Infof(ctx, "loaded logging config from %s", configFile)
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestReloadConfig(t *testing.T) {
+	defer saveConfigState().restore()
+	defer func(prev string) { configFile = prev }(configFile)
+
+	dir, err := ioutil.TempDir("", "logconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	configFile = filepath.Join(dir, "log.conf")
+	load := func(config string) error {
+		if err := ioutil.WriteFile(configFile, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return ReloadConfig(context.Background())
+	}
+
+	if err := load(`
+# Debug the config code.
+--verbosity=2
+vmodule = config_test=3
+logtostderr=ERROR
+log-file-max-size=1MiB
+log-dir-max-size=10MiB
+`); err != nil {
+		t.Fatal(err)
+	}
+	if v := logging.verbosity.get(); v != 2 {
+		t.Errorf("expected verbosity 2, got %d", v)
+	}
+	if !v(3) || v(4) {
+		t.Errorf("expected vmodule config_test=3, got %s", &logging.vmodule)
+	}
+	if s := logging.stderrThreshold.get(); s != Severity_ERROR {
+		t.Errorf("expected stderr threshold ERROR, got %s", s)
+	}
+	if LogFileMaxSize != 1<<20 || LogFilesCombinedMaxSize != 10<<20 {
+		t.Errorf("expected max sizes 1MiB and 10MiB, got %d and %d",
+			LogFileMaxSize, LogFilesCombinedMaxSize)
+	}
+
+	// None of the settings of an invalid config is applied.
+	loaded := saveConfigState()
+	testCases := []struct {
+		config string
+		err    string
+	}{
+		{"verbosity", `line 1: expected name=value, found "verbosity"`},
+		{"log-dir=/tmp", `line 1: --log-dir can't be set in the logging config file`},
+		{"vmodule=foo=1\nverbosity=x", `line 2: invalid value "x" for --verbosity`},
+		{"logtostderr=INFO\nlog-backtrace-at=foo", `line 2: invalid value "foo" for --log-backtrace-at`},
+		{"verbosity=3\nlog-file-max-size=0", `--log-file-max-size must be positive`},
+		{"log-dir-max-size=1KiB", `--log-dir-max-size must be at least --log-file-max-size`},
+	}
+	for _, tc := range testCases {
+		err := load(tc.config)
+		if err == nil {
+			t.Errorf("%q: expected error %q", tc.config, tc.err)
+		} else if !regexp.MustCompile(regexp.QuoteMeta(tc.err)).MatchString(err.Error()) {
+			t.Errorf("%q: expected error %q, got %v", tc.config, tc.err, err)
+		}
+		if state := saveConfigState(); !reflect.DeepEqual(state, loaded) {
+			t.Errorf("%q: expected the previous config %+v to be restored, got %+v", tc.config, loaded, state)
+		}
+	}
+
+	if err := os.Remove(configFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReloadConfig(context.Background()); !os.IsNotExist(err) {
+		t.Errorf("expected a missing file error, got %v", err)
+	}
+}
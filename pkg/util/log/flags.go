@@ -28,6 +28,7 @@ func init() {
 		&logDir, &showLogs, &logging.nocolor, &logging.verbosity,
 		&logging.vmodule, &logging.traceLocation,
 		&LogFileMaxSize, &LogFilesCombinedMaxSize,
+		&configFile,
 	)
 	// We define these flags here because they have the type Severity
 	// which we can't pass to logflags without creating an import cycle.
@@ -37,8 +37,20 @@ func handleVModule(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "ok: "+spec)
 }
 
+const httpLogConfigReloadPath = "/debug/logconfig/reload"
+
+func handleLogConfigReload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := ReloadConfig(context.Background()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, "ok: reloaded "+configFile)
+}
+
 func init() {
 	http.Handle(httpLogLevelPrefix, http.HandlerFunc(handleVModule))
+	http.Handle(httpLogConfigReloadPath, http.HandlerFunc(handleLogConfigReload))
 	copyStandardLogTo("INFO")
 }
 
@@ -72,6 +72,7 @@ const (
 	LogFileMaxSizeName            = "log-file-max-size"
 	LogFilesCombinedMaxSizeName   = "log-dir-max-size"
 	LogFileVerbosityThresholdName = "log-file-verbosity"
+	LogConfigFileName             = "log-config-file"
 )
 
 // InitFlags creates logging flags which update the given variables. The passed mutex is
@@ -83,6 +84,7 @@ func InitFlags(
 	nocolor *bool,
 	verbosity, vmodule, traceLocation flag.Value,
 	logFileMaxSize, logFilesCombinedMaxSize *int64,
+	logConfigFile *string,
 ) {
 	flag.BoolVar(nocolor, NoColorName, *nocolor, "disable standard error log colorization")
 	flag.BoolVar(noRedirectStderr, NoRedirectStderrName, *noRedirectStderr, "disable redirect of stderr to the log file")
@@ -93,4 +95,5 @@ func InitFlags(
 	flag.BoolVar(showLogs, ShowLogsName, *showLogs, "print logs instead of saving them in files")
 	flag.Var(humanizeutil.NewBytesValue(logFileMaxSize), LogFileMaxSizeName, "maximum size of each log file")
 	flag.Var(humanizeutil.NewBytesValue(logFilesCombinedMaxSize), LogFilesCombinedMaxSizeName, "maximum combined size of all log files")
+	flag.StringVar(logConfigFile, LogConfigFileName, *logConfigFile, "if non-empty, a file of name=value logging flags applied at startup and reloaded on SIGHUP")
 }
@@ -124,23 +124,64 @@ func formatTags(ctx context.Context, buf *msgBuf) bool {
 	return false
 }
 
+// formatArgs appends the formatted message to a bytes.Buffer. If format is
+// empty, the args are printed as with fmt.Print.
+func formatArgs(buf *msgBuf, format string, args []interface{}) {
+	if len(format) == 0 {
+		fmt.Fprint(buf, args...)
+	} else {
+		fmt.Fprintf(buf, format, args...)
+	}
+}
+
 // MakeMessage creates a structured log entry.
 func MakeMessage(ctx context.Context, format string, args []interface{}) string {
 	var buf msgBuf
 	formatTags(ctx, &buf)
-	if len(format) == 0 {
-		fmt.Fprint(&buf, args...)
+	formatArgs(&buf, format, args)
+	return buf.String()
+}
+
+// makeMessageWithTraceIDs is like MakeMessage, but additionally returns a
+// version of the message which, if the context has a span, includes the trace
+// and span IDs alongside the tags (e.g. "[n1,trace=2a,span=3f] msg"). The IDs
+// are in hex, as in the span contexts propagated between nodes, and allow log
+// entries to be correlated with traces collected elsewhere.
+func makeMessageWithTraceIDs(
+	ctx context.Context, format string, args []interface{},
+) (msg string, msgWithIDs string) {
+	var buf msgBuf
+	hasTags := formatTags(ctx, &buf)
+	tagsLen := buf.Len()
+	formatArgs(&buf, format, args)
+	msg = buf.String()
+
+	traceID, spanID, ok := spanIDsFromCtx(ctx)
+	if !ok {
+		return msg, msg
+	}
+	var idBuf bytes.Buffer
+	if hasTags {
+		// Insert the IDs before the "] " that closes the tags.
+		idBuf.WriteString(msg[:tagsLen-2])
+		idBuf.WriteByte(',')
 	} else {
-		fmt.Fprintf(&buf, format, args...)
+		idBuf.WriteByte('[')
 	}
-	return buf.String()
+	idBuf.WriteString("trace=")
+	idBuf.WriteString(strconv.FormatUint(traceID, 16))
+	idBuf.WriteString(",span=")
+	idBuf.WriteString(strconv.FormatUint(spanID, 16))
+	idBuf.WriteString("] ")
+	idBuf.WriteString(msg[tagsLen:])
+	return msg, idBuf.String()
 }
 
 // addStructured creates a structured log entry to be written to the
 // specified facility of the logger.
 func addStructured(ctx context.Context, s Severity, depth int, format string, args []interface{}) {
 	file, line, _ := caller.Lookup(depth + 1)
-	msg := MakeMessage(ctx, format, args)
+	msg, msgWithIDs := makeMessageWithTraceIDs(ctx, format, args)
 
 	if s == Severity_FATAL {
 		// we send the `format` str, not the formatted message, as args may be not
@@ -152,8 +193,9 @@ func addStructured(ctx context.Context, s Severity, depth int, format string, ar
 		reportable = fmt.Sprintf("%s:%d %s", filepath.Base(file), line, reportable)
 		sendCrashReport(ctx, reportable, depth+1)
 	}
-	// MakeMessage already added the tags when forming msg, we don't want
-	// eventInternal to prepend them again.
+	// makeMessageWithTraceIDs already added the tags when forming msg, we don't
+	// want eventInternal to prepend them again. The span's own IDs are only of
+	// interest in the log file.
 	eventInternal(ctx, (s >= Severity_ERROR), false /*withTags*/, "%s:%d %s", file, line, msg)
-	logging.outputLogEntry(s, file, line, msg)
+	logging.outputLogEntry(s, file, line, msgWithIDs)
 }
@@ -105,6 +105,16 @@ func getSpanOrEventLog(ctx context.Context) (opentracing.Span, *ctxEventLog, boo
 	return nil, nil, false
 }
 
+// spanIDsFromCtx returns the trace and span IDs of the span in the context,
+// if there is one and it isn't a noop span.
+func spanIDsFromCtx(ctx context.Context) (traceID uint64, spanID uint64, ok bool) {
+	sp := opentracing.SpanFromContext(ctx)
+	if sp == nil {
+		return 0, 0, false
+	}
+	return tracing.GetTraceAndSpanID(sp)
+}
+
 // eventInternal is the common code for logging an event. If no args are given,
 // the format is treated as a pre-formatted string.
 func eventInternal(ctx context.Context, isErr, withTags bool, format string, args ...interface{}) {
@@ -181,11 +191,32 @@ func ErrEventf(ctx context.Context, format string, args ...interface{}) {
 	eventInternal(ctx, true /*isErr*/, true /*withTags*/, format, args...)
 }
 
+// ExpensiveLogEnabled returns true if the given verbosity level is active,
+// either through the global verbosity and vmodule settings or because the
+// span in the context has a higher verbosity set (see tracing.SetVerbosity).
+// It is meant to guard logging calls that are too expensive or too noisy to
+// run unconditionally, so that they can be enabled for a single request.
+func ExpensiveLogEnabled(ctx context.Context, level level) bool {
+	return vDepthCtx(ctx, level, 1)
+}
+
+// vDepthCtx is like VDepth, but also takes into account the verbosity set on
+// the span in the context.
+func vDepthCtx(ctx context.Context, l level, depth int) bool {
+	if VDepth(l, depth+1) {
+		return true
+	}
+	if sp := opentracing.SpanFromContext(ctx); sp != nil {
+		return level(tracing.GetVerbosity(sp)) >= l
+	}
+	return false
+}
+
 // VEvent either logs a message to the log files (which also outputs to the
 // active trace or event log) or to the trace/event log alone, depending on
-// whether the specified verbosity level is active.
+// whether the specified verbosity level is active (see ExpensiveLogEnabled).
 func VEvent(ctx context.Context, level level, msg string) {
-	if VDepth(level, 1) {
+	if vDepthCtx(ctx, level, 1) {
 		// Log to INFO (which also logs an event).
 		logDepth(ctx, 1, Severity_INFO, "", []interface{}{msg})
 	} else {
@@ -195,9 +226,9 @@ func VEvent(ctx context.Context, level level, msg string) {
 
 // VEventf either logs a message to the log files (which also outputs to the
 // active trace or event log) or to the trace/event log alone, depending on
-// whether the specified verbosity level is active.
+// whether the specified verbosity level is active (see ExpensiveLogEnabled).
 func VEventf(ctx context.Context, level level, format string, args ...interface{}) {
-	if VDepth(level, 1) {
+	if vDepthCtx(ctx, level, 1) {
 		// Log to INFO (which also logs an event).
 		logDepth(ctx, 1, Severity_INFO, format, args)
 	} else {
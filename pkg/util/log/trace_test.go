@@ -19,11 +19,13 @@ package log
 import (
 	"fmt"
 	"regexp"
+	"strings"
 	"testing"
 
 	"golang.org/x/net/context"
 	"golang.org/x/net/trace"
 
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 	basictracer "github.com/opentracing/basictracer-go"
 	opentracing "github.com/opentracing/opentracing-go"
 )
@@ -230,3 +232,80 @@ func TestEventLogAndTrace(t *testing.T) {
 		t.Errorf("expected events '%s', got '%s'", elExpected, evStr)
 	}
 }
+
+func TestLogTraceIDs(t *testing.T) {
+	s := ScopeWithoutShowLogs(t)
+	defer s.Close(t)
+	setFlags()
+	defer logging.swap(logging.newBuffers())
+
+	tracer := tracing.NewTracer()
+	sp := tracer.StartSpan("s", tracing.Recordable)
+	defer sp.Finish()
+	tracing.StartRecording(sp, tracing.SingleNodeRecording)
+	traceID, spanID, ok := tracing.GetTraceAndSpanID(sp)
+	if !ok {
+		t.Fatal("expected a real span")
+	}
+
+	ctx := opentracing.ContextWithSpan(context.Background(), sp)
+	Info(ctx, "untagged")
+	Info(WithLogTagInt(ctx, "tag", 1), "tagged")
+
+	for _, exp := range []string{
+		fmt.Sprintf("[trace=%x,span=%x] untagged", traceID, spanID),
+		fmt.Sprintf("[tag=1,trace=%x,span=%x] tagged", traceID, spanID),
+	} {
+		if !contains(exp, t) {
+			t.Errorf("expected %q in log output:\n%s", exp, contents())
+		}
+	}
+
+	// The IDs are only added to the log file, not to the span's events.
+	rec := tracing.GetRecording(sp)
+	if len(rec) != 1 || len(rec[0].Logs) != 2 {
+		t.Fatalf("expected one span with two events, got %+v", rec)
+	}
+	for _, l := range rec[0].Logs {
+		if msg := l.Fields[0].Value; strings.Contains(msg, "trace=") {
+			t.Errorf("unexpected trace ID in span event %q", msg)
+		}
+	}
+}
+
+func TestVerbosityFromSpan(t *testing.T) {
+	s := ScopeWithoutShowLogs(t)
+	defer s.Close(t)
+	setFlags()
+	defer logging.swap(logging.newBuffers())
+
+	v := logging.verbosity.get() + 2
+	tracer := tracing.NewTracer()
+	sp := tracer.StartSpan("s", tracing.Recordable)
+	defer sp.Finish()
+	ctx := opentracing.ContextWithSpan(context.Background(), sp)
+
+	if ExpensiveLogEnabled(ctx, v) {
+		t.Fatalf("expected level %d to be disabled", v)
+	}
+	VEventf(ctx, v, "before")
+
+	tracing.SetVerbosity(sp, int32(v))
+	if !ExpensiveLogEnabled(ctx, v) {
+		t.Fatalf("expected level %d to be enabled", v)
+	}
+	if ExpensiveLogEnabled(ctx, v+1) {
+		t.Fatalf("expected level %d to be disabled", v+1)
+	}
+	if ExpensiveLogEnabled(context.Background(), v) {
+		t.Fatalf("expected level %d to be disabled without a span", v)
+	}
+	VEventf(ctx, v, "after")
+
+	if contains("before", t) {
+		t.Errorf("unexpected event in log output:\n%s", contents())
+	}
+	if !contains("after", t) {
+		t.Errorf("expected event in log output:\n%s", contents())
+	}
+}
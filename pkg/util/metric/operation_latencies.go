@@ -0,0 +1,112 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metric
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// maxOperationLatencies bounds the number of distinct histograms maintained by
+// an OperationLatencies. Operations seen after the limit is reached are
+// accounted for in a single overflow histogram.
+const maxOperationLatencies = 500
+
+// overflowOperation is the operation name used for the overflow histogram.
+const overflowOperation = "other"
+
+// operationNameReplaceRE matches the characters of an operation name which
+// can't be part of a metric name. Operation names commonly contain spaces and
+// slashes (e.g. gRPC method names).
+var operationNameReplaceRE = regexp.MustCompile("[^a-zA-Z0-9_.]")
+
+// OperationLatencies maintains a latency histogram per operation name. The
+// histograms are created lazily the first time an operation is seen and are
+// added to the Registry at that point.
+//
+// OperationLatencies implements tracing.SpanDurationRecorder, which allows the
+// tracer to feed the duration of finished spans into it.
+type OperationLatencies struct {
+	registry        *Registry
+	prefix          string
+	histogramWindow time.Duration
+
+	mu struct {
+		syncutil.Mutex
+		// histograms is keyed by operation name and byName by metric name.
+		// Operations whose names only differ in characters that are replaced in
+		// metric names share a histogram.
+		histograms map[string]*Histogram
+		byName     map[string]*Histogram
+	}
+}
+
+// NewOperationLatencies creates an OperationLatencies which registers its
+// histograms with the given registry. The histogram for an operation is named
+// "<prefix>.<operation>.latency", in which the characters of the operation
+// name other than [a-zA-Z0-9_.] are replaced with underscores.
+func NewOperationLatencies(
+	registry *Registry, prefix string, histogramWindow time.Duration,
+) *OperationLatencies {
+	o := &OperationLatencies{
+		registry:        registry,
+		prefix:          prefix,
+		histogramWindow: histogramWindow,
+	}
+	o.mu.histograms = make(map[string]*Histogram)
+	o.mu.byName = make(map[string]*Histogram)
+	return o
+}
+
+// RecordSpanDuration records the duration of an operation.
+func (o *OperationLatencies) RecordSpanDuration(operation string, duration time.Duration) {
+	o.histogram(operation).RecordValue(duration.Nanoseconds())
+}
+
+// histogram returns the histogram for the given operation, creating and
+// registering it if necessary.
+func (o *OperationLatencies) histogram(operation string) *Histogram {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if h, ok := o.mu.histograms[operation]; ok {
+		return h
+	}
+	name := operationNameReplaceRE.ReplaceAllString(operation, "_")
+	if h, ok := o.mu.byName[name]; ok {
+		o.mu.histograms[operation] = h
+		return h
+	}
+	overflow := len(o.mu.byName) >= maxOperationLatencies
+	if overflow {
+		// The overflow histogram isn't cached under the operation name, which
+		// keeps the map bounded.
+		name = overflowOperation
+		if h, ok := o.mu.byName[name]; ok {
+			return h
+		}
+	}
+	h := NewLatency(Metadata{
+		Name: o.prefix + "." + name + ".latency",
+		Help: "Latency of " + name + " operations",
+	}, o.histogramWindow)
+	o.mu.byName[name] = h
+	if !overflow {
+		o.mu.histograms[operation] = h
+	}
+	o.registry.AddMetric(h)
+	return h
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metric
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestOperationLatencies(t *testing.T) {
+	r := NewRegistry()
+	o := NewOperationLatencies(r, "trace.op", time.Minute)
+
+	o.RecordSpanDuration("a", time.Millisecond)
+	o.RecordSpanDuration("a", 2*time.Millisecond)
+	o.RecordSpanDuration("b", time.Millisecond)
+
+	if h, ok := r.findMetricByName("trace.op.a.latency").(*Histogram); !ok {
+		t.Fatal("histogram for operation a not registered")
+	} else if c := h.TotalCount(); c != 2 {
+		t.Errorf("expected 2 samples for operation a, got %d", c)
+	}
+	if r.findMetricByName("trace.op.b.latency") == nil {
+		t.Fatal("histogram for operation b not registered")
+	}
+
+	// Operations past the limit all go into the overflow histogram.
+	for i := 0; i < 2*maxOperationLatencies; i++ {
+		o.RecordSpanDuration(fmt.Sprintf("op%d", i), time.Millisecond)
+	}
+	var count int
+	r.Each(func(string, interface{}) { count++ })
+	if count != maxOperationLatencies+1 {
+		t.Errorf("expected %d histograms, got %d", maxOperationLatencies+1, count)
+	}
+	if r.findMetricByName("trace.op."+overflowOperation+".latency") == nil {
+		t.Fatal("overflow histogram not registered")
+	}
+}
+
+func TestOperationLatenciesMetricNames(t *testing.T) {
+	r := NewRegistry()
+	o := NewOperationLatencies(r, "trace.op", time.Minute)
+
+	o.RecordSpanDuration("/cockroach.roachpb.Internal/Batch", time.Millisecond)
+	o.RecordSpanDuration("exec stmt", time.Millisecond)
+	// Collides with the name above once sanitized.
+	o.RecordSpanDuration("exec/stmt", time.Millisecond)
+
+	var names []string
+	r.Each(func(name string, _ interface{}) { names = append(names, name) })
+	sort.Strings(names)
+	expected := []string{
+		"trace.op._cockroach.roachpb.Internal_Batch.latency",
+		"trace.op.exec_stmt.latency",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected metrics %v, got %v", expected, names)
+	}
+	if h := r.findMetricByName("trace.op.exec_stmt.latency").(*Histogram); h.TotalCount() != 2 {
+		t.Errorf("expected 2 samples for exec_stmt, got %d", h.TotalCount())
+	}
+}
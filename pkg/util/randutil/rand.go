@@ -24,6 +24,7 @@ import (
 	"math/rand"
 
 	"github.com/cockroachdb/cockroach/pkg/util/envutil"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 )
 
 // NewPseudoSeed generates a seed from crypto/rand.
@@ -44,6 +45,41 @@ func NewPseudoRand() (*rand.Rand, int64) {
 	return rand.New(rand.NewSource(seed)), seed
 }
 
+// lockedSource is a rand.Source which is safe for concurrent use.
+type lockedSource struct {
+	mu  syncutil.Mutex
+	src rand.Source64
+}
+
+var _ rand.Source64 = &lockedSource{}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// NewLockedPseudoRand is like NewPseudoRand, but the created object is safe
+// for concurrent access. Unlike the global functions of math/rand, its users
+// only contend with each other, and its seed can be logged to reproduce them.
+func NewLockedPseudoRand() (*rand.Rand, int64) {
+	seed := NewPseudoSeed()
+	src := &lockedSource{src: rand.NewSource(seed).(rand.Source64)}
+	return rand.New(src), seed
+}
+
 // RandIntInRange returns a value in [min, max)
 func RandIntInRange(r *rand.Rand, min, max int) int {
 	return min + r.Intn(max-min)
@@ -17,6 +17,7 @@
 package randutil_test
 
 import (
+	"sync"
 	"testing"
 
 	_ "github.com/cockroachdb/cockroach/pkg/util/log" // for flags
@@ -38,6 +39,23 @@ func TestPseudoRand(t *testing.T) {
 	}
 }
 
+func TestLockedPseudoRand(t *testing.T) {
+	rand, _ := randutil.NewLockedPseudoRand()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if x := rand.Intn(10); x < 0 || x >= 10 {
+					t.Errorf("got result out of range: %d", x)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func TestRandIntInRange(t *testing.T) {
 	rand, _ := randutil.NewPseudoRand()
 	for i := 0; i < 100; i++ {
@@ -0,0 +1,171 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package search provides the means for components to tune parameters
+// against measurements of their effect, e.g. to pace background work so that
+// it takes a target fraction of the time.
+//
+// A BinarySearcher finds a threshold in a fixed environment, one probe at a
+// time. A Tuner continuously adjusts a value towards a target, and is suited
+// to environments which change over time.
+package search
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// A BinarySearcher searches for the largest value in [min, max] which passes
+// a check, assuming that the values below it pass and the values above it
+// don't. Unlike sort.Search, the checks are performed by the caller, one at a
+// time:
+//
+//   for !s.Done() {
+//     s.Feedback(check(s.Value()))
+//   }
+//   threshold := s.Value()
+//
+// A BinarySearcher isn't safe for concurrent use.
+type BinarySearcher struct {
+	// lo passes (or is the minimum) and hi doesn't (or is the maximum).
+	lo, hi    float64
+	precision float64
+}
+
+// NewBinarySearcher returns a BinarySearcher for the given bounds, which
+// stops when the threshold is known within the given precision.
+func NewBinarySearcher(min, max, precision float64) (*BinarySearcher, error) {
+	if min > max {
+		return nil, errors.Errorf("min %f is greater than max %f", min, max)
+	}
+	if precision <= 0 {
+		return nil, errors.Errorf("precision %f is not positive", precision)
+	}
+	return &BinarySearcher{lo: min, hi: max, precision: precision}, nil
+}
+
+// Value returns the value to check next or, once the search is done, the
+// largest value known to pass the check. If no value passes the check, that
+// is min. max itself is never checked, but the search gets within the
+// precision of it if all the values pass.
+func (s *BinarySearcher) Value() float64 {
+	if s.Done() {
+		return s.lo
+	}
+	return s.lo + (s.hi-s.lo)/2
+}
+
+// Feedback narrows down the search with the result of the check of Value.
+func (s *BinarySearcher) Feedback(pass bool) {
+	if s.Done() {
+		return
+	}
+	v := s.Value()
+	if pass {
+		s.lo = v
+	} else {
+		s.hi = v
+	}
+}
+
+// Done returns whether the threshold is known within the precision.
+func (s *BinarySearcher) Done() bool {
+	return s.hi-s.lo <= s.precision
+}
+
+// TunerConfig configures a Tuner.
+type TunerConfig struct {
+	// Min and Max bound the tuned value. Initial is its initial value.
+	Min, Max, Initial float64
+	// Target is the target of the measurements.
+	Target float64
+	// Increasing is true if the measurement increases with the value, and
+	// false if it decreases with it.
+	Increasing bool
+	// MaxStep is the largest factor by which the value changes in a single
+	// update. It defaults to 2.
+	MaxStep float64
+	// Metric, if set, is updated with the tuned value.
+	Metric *metric.GaugeFloat64
+}
+
+// A Tuner adjusts a positive value so that the measurements of its effect
+// approach a target. Each update moves the value geometrically halfway to the
+// value which would meet the target if the measurement were proportional (or
+// inversely proportional) to it, which makes for a quick but damped
+// convergence. It is safe for concurrent use.
+type Tuner struct {
+	cfg TunerConfig
+	mu  struct {
+		syncutil.Mutex
+		value float64
+	}
+}
+
+// NewTuner returns a Tuner with the given configuration.
+func NewTuner(cfg TunerConfig) (*Tuner, error) {
+	if cfg.Min <= 0 || cfg.Min > cfg.Max {
+		return nil, errors.Errorf("invalid bounds [%f, %f]", cfg.Min, cfg.Max)
+	}
+	if cfg.Initial < cfg.Min || cfg.Initial > cfg.Max {
+		return nil, errors.Errorf("initial value %f is out of bounds [%f, %f]",
+			cfg.Initial, cfg.Min, cfg.Max)
+	}
+	if cfg.Target <= 0 {
+		return nil, errors.Errorf("target %f is not positive", cfg.Target)
+	}
+	if cfg.MaxStep == 0 {
+		cfg.MaxStep = 2
+	} else if cfg.MaxStep <= 1 {
+		return nil, errors.Errorf("max step %f is not greater than 1", cfg.MaxStep)
+	}
+	t := &Tuner{cfg: cfg}
+	t.setLocked(cfg.Initial)
+	return t, nil
+}
+
+// Value returns the tuned value.
+func (t *Tuner) Value() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.mu.value
+}
+
+// Update adjusts the value with a new measurement, and returns the new value.
+func (t *Tuner) Update(measurement float64) float64 {
+	step := t.cfg.MaxStep
+	if measurement > 0 {
+		step = math.Min(math.Sqrt(t.cfg.Target/measurement), t.cfg.MaxStep)
+		step = math.Max(step, 1/t.cfg.MaxStep)
+	}
+	if !t.cfg.Increasing {
+		step = 1 / step
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.setLocked(t.mu.value * step)
+	return t.mu.value
+}
+
+func (t *Tuner) setLocked(value float64) {
+	t.mu.value = math.Max(t.cfg.Min, math.Min(t.cfg.Max, value))
+	if t.cfg.Metric != nil {
+		t.cfg.Metric.Update(t.mu.value)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package search
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/testutils"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/metric"
+)
+
+func TestBinarySearcher(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const precision = 0.5
+	for _, threshold := range []float64{-1, 0, 12.3, 50, 99.9, 100, 150} {
+		s, err := NewBinarySearcher(0, 100, precision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checks := 0
+		for !s.Done() {
+			s.Feedback(s.Value() <= threshold)
+			checks++
+		}
+		expected := math.Max(0, math.Min(100, threshold))
+		if v := s.Value(); v > expected || v < expected-precision {
+			t.Errorf("threshold %f: expected a value within %f below %f, got %f",
+				threshold, precision, expected, v)
+		}
+		if checks > 8 {
+			t.Errorf("threshold %f: expected at most 8 checks, got %d", threshold, checks)
+		}
+	}
+
+	if _, err := NewBinarySearcher(1, 0, precision); !testutils.IsError(err, "greater than max") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := NewBinarySearcher(0, 1, 0); !testutils.IsError(err, "not positive") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestTuner(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		increasing bool
+		// measure returns the measurement for a value.
+		measure  func(v float64) float64
+		expected float64
+	}{
+		// A measurement proportional to the value, e.g. the time spent
+		// processing a batch of the given size.
+		{true, func(v float64) float64 { return 4 * v }, 25},
+		// A measurement inversely proportional to the value, e.g. the fraction
+		// of time spent processing with the given pause between batches.
+		{false, func(v float64) float64 { return 400 / v }, 4},
+		// A target out of bounds.
+		{true, func(v float64) float64 { return v / 100 }, 1000},
+		{false, func(v float64) float64 { return 1e-3 / v }, 1},
+	}
+	for i, tc := range testCases {
+		gauge := metric.NewGaugeFloat64(metric.Metadata{Name: "test"})
+		tuner, err := NewTuner(TunerConfig{
+			Min:        1,
+			Max:        1000,
+			Initial:    10,
+			Target:     100,
+			Increasing: tc.increasing,
+			Metric:     gauge,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 50; j++ {
+			tuner.Update(tc.measure(tuner.Value()))
+		}
+		if v := tuner.Value(); math.Abs(v-tc.expected) > tc.expected/100 {
+			t.Errorf("%d: expected %f, got %f", i, tc.expected, v)
+		}
+		if v := gauge.Value(); v != tuner.Value() {
+			t.Errorf("%d: expected the metric to be %f, got %f", i, tuner.Value(), v)
+		}
+	}
+
+	// The value changes by at most MaxStep at a time.
+	tuner, err := NewTuner(TunerConfig{
+		Min: 1, Max: 1000, Initial: 10, Target: 100, Increasing: true, MaxStep: 1.5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := tuner.Update(0); math.Abs(v-15) > 1e-9 {
+		t.Errorf("expected 15, got %f", v)
+	}
+	if v := tuner.Update(1e6); math.Abs(v-10) > 1e-9 {
+		t.Errorf("expected 10, got %f", v)
+	}
+
+	for _, cfg := range []TunerConfig{
+		{Min: 0, Max: 1, Initial: 1, Target: 1},
+		{Min: 2, Max: 1, Initial: 1, Target: 1},
+		{Min: 1, Max: 2, Initial: 3, Target: 1},
+		{Min: 1, Max: 2, Initial: 1, Target: 0},
+		{Min: 1, Max: 2, Initial: 1, Target: 1, MaxStep: 0.5},
+	} {
+		if _, err := NewTuner(cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
@@ -255,18 +255,8 @@ func (s *Stopper) RunAsyncTask(
 		return errUnavailable
 	}
 
-	ctx, span := tracing.ForkCtxSpan(ctx, taskName)
-
-	// Call f.
-	go func() {
-		defer s.Recover(ctx)
-		defer s.runPostlude(taskName)
-		defer tracing.FinishSpan(span)
-
-		f(ctx)
-	}()
+	s.runAsync(ctx, taskName, nil /* release */, f)
 	return nil
-
 }
 
 // RunLimitedAsyncTask runs function f in a goroutine, using the given
@@ -316,17 +306,30 @@ func (s *Stopper) RunLimitedAsyncTask(
 		return errUnavailable
 	}
 
+	s.runAsync(ctx, taskName, func() { <-sem }, f)
+	return nil
+}
+
+// runAsync calls f in a goroutine on behalf of a task registered through
+// runPrelude. f runs in a span named after the task which follows from the
+// caller's span, if any, so that async tasks show up in the traces of the
+// operations which started them. The span is finished as soon as f returns,
+// before release (if not nil) is called and the task is unregistered.
+func (s *Stopper) runAsync(
+	ctx context.Context, taskName string, release func(), f func(context.Context),
+) {
 	ctx, span := tracing.ForkCtxSpan(ctx, taskName)
 
 	go func() {
 		defer s.Recover(ctx)
 		defer s.runPostlude(taskName)
-		defer func() { <-sem }()
+		if release != nil {
+			defer release()
+		}
 		defer tracing.FinishSpan(span)
 
 		f(ctx)
 	}()
-	return nil
 }
 
 func (s *Stopper) runPrelude(taskName string) bool {
@@ -23,6 +23,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
@@ -32,6 +33,7 @@ import (
 	_ "github.com/cockroachdb/cockroach/pkg/util/log" // for flags
 	"github.com/cockroachdb/cockroach/pkg/util/stop"
 	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/cockroachdb/cockroach/pkg/util/tracing"
 )
 
 func TestStopper(t *testing.T) {
@@ -600,3 +602,51 @@ func BenchmarkStopperPar(b *testing.B) {
 		}
 	})
 }
+
+func TestStopperAsyncTaskSpans(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s := stop.NewStopper()
+	defer s.Stop(context.Background())
+
+	tr := tracing.NewTracer().(*tracing.Tracer)
+	c := tracing.NewTestCollector(tr)
+	defer c.Close()
+
+	sp := tr.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), sp)
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	f := func(ctx context.Context) {
+		defer wg.Done()
+		if opentracing.SpanFromContext(ctx) == sp {
+			t.Errorf("async task runs in its caller's span")
+		}
+		<-release
+	}
+	wg.Add(2)
+	if err := s.RunAsyncTask(ctx, "foo", f); err != nil {
+		t.Fatal(err)
+	}
+	sem := make(chan struct{}, 1)
+	if err := s.RunLimitedAsyncTask(ctx, "bar", sem, true /* wait */, f); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tasks outlive the operation which started them.
+	sp.Finish()
+	if spans := c.FindSpans("[async] foo"); len(spans) != 0 {
+		t.Fatalf("span of running task was finished: %+v", spans)
+	}
+
+	close(release)
+	wg.Wait()
+	testutils.SucceedsSoon(t, func() error {
+		for _, task := range []string{"[async] foo", "bar"} {
+			if err := c.CheckChildOf("parent", task); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// Baggage is shared with every child span and injected into every RPC, so its
+// size is limited. The Snowball and VerboseLogging items are exempt from the
+// limits.
+var maxBaggageItems = settings.RegisterIntSetting(
+	"trace.baggage.max_items",
+	"maximum number of baggage items in a span; items set beyond it are dropped",
+	32,
+)
+
+var maxBaggageBytes = settings.RegisterByteSizeSetting(
+	"trace.baggage.max_bytes",
+	"maximum total size of the keys and values of the baggage items in a span; items set beyond it are dropped",
+	4<<10,
+)
+
+// BaggageTruncatedEvent is logged in a span when a baggage item is dropped
+// because of the limits, or when the span's remote parent had some of its
+// baggage dropped.
+const BaggageTruncatedEvent = "baggage truncated"
+
+// checkBaggageLimits returns an error if setting the given item in baggage
+// would exceed the limits.
+func checkBaggageLimits(baggage map[string]string, key, value string) error {
+	if key == Snowball || key == VerboseLogging {
+		return nil
+	}
+	old, exists := baggage[key]
+	count := len(baggage)
+	if !exists {
+		count++
+	}
+	if max := maxBaggageItems.Get(); int64(count) > max {
+		return errors.Errorf("item %q exceeds the limit of %d baggage items", key, max)
+	}
+	size := int64(len(key) + len(value))
+	for k, v := range baggage {
+		size += int64(len(k) + len(v))
+	}
+	if exists {
+		size -= int64(len(key) + len(old))
+	}
+	if max := maxBaggageBytes.Get(); size > max {
+		return errors.Errorf("item %q exceeds the limit of %d bytes of baggage", key, max)
+	}
+	return nil
+}
+
+// copyBaggage returns a copy of the given baggage, with room for an additional
+// item. Baggage maps are shared between spans and contexts until one of them
+// is modified, so they must be copied first.
+func copyBaggage(baggage map[string]string) map[string]string {
+	c := make(map[string]string, len(baggage)+1)
+	for k, v := range baggage {
+		c[k] = v
+	}
+	return c
+}
+
+// SetVerbosity sets the VerboseLogging baggage item on the span, which raises
+// the log verbosity to the given level for all the work done under the span
+// and its descendants, including the ones on remote nodes (see
+// log.ExpensiveLogEnabled). A level of 0 clears the item.
+//
+// Baggage is not supported by noop spans; to ensure a real span is created,
+// use the Recordable option to StartSpan.
+func SetVerbosity(os opentracing.Span, level int32) {
+	if level <= 0 {
+		os.SetBaggageItem(VerboseLogging, "")
+		return
+	}
+	os.SetBaggageItem(VerboseLogging, strconv.Itoa(int(level)))
+}
+
+// GetVerbosity returns the log verbosity set on the span through the
+// VerboseLogging baggage item, or 0 if there is none.
+func GetVerbosity(os opentracing.Span) int32 {
+	s, ok := os.(*span)
+	if !ok {
+		return 0
+	}
+	return atomic.LoadInt32(&s.verbosity)
+}
+
+// parseVerbosity parses the value of a VerboseLogging baggage item. Invalid
+// values are treated as 0.
+func parseVerbosity(value string) int32 {
+	if value == "" {
+		return 0
+	}
+	v, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return int32(v)
+}
@@ -0,0 +1,252 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+const (
+	// exportQueueSize is the number of finished spans that can wait to be
+	// exported; spans finished while the queue is full are dropped.
+	exportQueueSize = 1024
+	// exportBatchSize is the maximum number of spans exported at once.
+	exportBatchSize = 128
+	// exportFlushInterval is the maximum amount of time a finished span waits
+	// for its batch to fill up.
+	exportFlushInterval = time.Second
+	// exportMaxAttempts is the number of times the export of a batch is
+	// attempted before the batch is dropped.
+	exportMaxAttempts = 4
+	// exportInitialBackoff and exportMaxBackoff bound the time waited between
+	// two attempts to export a batch.
+	exportInitialBackoff = 100 * time.Millisecond
+	exportMaxBackoff     = 2 * time.Second
+)
+
+// SpanExporter sends finished spans to an external collector. ExportSpans is
+// only called from the goroutine of an AsyncExporter; if it returns an error,
+// the same batch is retried with backoff. The spans slice is reused after the
+// call returns.
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, spans []RecordedSpan) error
+}
+
+// ExportStats counts the spans handled by an AsyncExporter.
+type ExportStats struct {
+	// Exported is the number of spans exported successfully.
+	Exported int64
+	// Dropped is the number of spans dropped because the queue was full.
+	Dropped int64
+	// Failed is the number of spans dropped because their export kept failing.
+	Failed int64
+}
+
+// exportItem is a finished span waiting in the queue of an AsyncExporter.
+// Depending on the exporter, either rec or shadow is set.
+type exportItem struct {
+	rec RecordedSpan
+	// shadow is a lightstep span which has to be finished at finishTime.
+	shadow     opentracing.Span
+	finishTime time.Time
+}
+
+// AsyncExporter exports the spans finished by a Tracer from a background
+// goroutine, so that a slow collector can't stall the operations that finish
+// spans. Finished spans wait in a bounded queue and are exported in batches;
+// when the queue is full, new spans are dropped. While an exporter is
+// registered, all spans are real spans and their tags and logs are retained
+// (like with a TestCollector).
+//
+//   e := tracing.NewAsyncExporter(tracer, myExporter)
+//   defer e.Close()
+type AsyncExporter struct {
+	tracer *Tracer
+	export func(context.Context, []exportItem) error
+
+	queue chan exportItem
+	stopC chan struct{}
+	done  chan struct{}
+
+	exported, dropped, failed int64
+}
+
+// NewAsyncExporter creates an AsyncExporter sending the spans finished by the
+// given Tracer to e, and registers it with the Tracer, replacing any
+// previously registered exporter. Only spans started after this call are
+// exported.
+func NewAsyncExporter(tracer *Tracer, e SpanExporter) *AsyncExporter {
+	var recs []RecordedSpan
+	ae := newAsyncExporter(tracer, func(ctx context.Context, items []exportItem) error {
+		recs = recs[:0]
+		for i := range items {
+			recs = append(recs, items[i].rec)
+		}
+		return e.ExportSpans(ctx, recs)
+	})
+	tracer.exporter.Store(ae)
+	return ae
+}
+
+func newAsyncExporter(
+	tracer *Tracer, export func(context.Context, []exportItem) error,
+) *AsyncExporter {
+	ae := &AsyncExporter{
+		tracer: tracer,
+		export: export,
+		queue:  make(chan exportItem, exportQueueSize),
+		stopC:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go ae.run()
+	return ae
+}
+
+// Close unregisters the exporter from its Tracer, exports the spans that are
+// still queued (with a single attempt) and stops the background goroutine.
+func (ae *AsyncExporter) Close() {
+	if ae.tracer.getExporter() == ae {
+		ae.tracer.exporter.Store((*AsyncExporter)(nil))
+	}
+	close(ae.stopC)
+	<-ae.done
+}
+
+// Stats returns the number of spans exported and dropped so far.
+func (ae *AsyncExporter) Stats() ExportStats {
+	return ExportStats{
+		Exported: atomic.LoadInt64(&ae.exported),
+		Dropped:  atomic.LoadInt64(&ae.dropped),
+		Failed:   atomic.LoadInt64(&ae.failed),
+	}
+}
+
+// enqueue adds a finished span to the queue, or drops it if the queue is full.
+// It never blocks.
+func (ae *AsyncExporter) enqueue(item exportItem) {
+	select {
+	case ae.queue <- item:
+	default:
+		atomic.AddInt64(&ae.dropped, 1)
+	}
+}
+
+func (ae *AsyncExporter) run() {
+	defer close(ae.done)
+	ctx := context.Background()
+	batch := make([]exportItem, 0, exportBatchSize)
+	timer := time.NewTimer(exportFlushInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case item := <-ae.queue:
+			batch = append(batch, item)
+			if len(batch) < exportBatchSize {
+				continue
+			}
+		case <-timer.C:
+			timer.Reset(exportFlushInterval)
+		case <-ae.stopC:
+			// Export whatever is left, without retrying.
+			for {
+				select {
+				case item := <-ae.queue:
+					batch = append(batch, item)
+					if len(batch) == exportBatchSize {
+						ae.flush(ctx, batch, false /* retry */)
+						batch = batch[:0]
+					}
+					continue
+				default:
+				}
+				break
+			}
+			ae.flush(ctx, batch, false /* retry */)
+			return
+		}
+		ae.flush(ctx, batch, true /* retry */)
+		batch = batch[:0]
+	}
+}
+
+// flush exports a batch. If retry is set, a failed export is attempted again
+// with exponential backoff, up to exportMaxAttempts times; the retries stop
+// early if the exporter is closed.
+func (ae *AsyncExporter) flush(ctx context.Context, batch []exportItem, retry bool) {
+	if len(batch) == 0 {
+		return
+	}
+	n := int64(len(batch))
+	backoff := exportInitialBackoff
+	for attempt := 1; ; attempt++ {
+		if err := ae.export(ctx, batch); err == nil {
+			atomic.AddInt64(&ae.exported, n)
+			break
+		}
+		if !retry || attempt == exportMaxAttempts {
+			atomic.AddInt64(&ae.failed, n)
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ae.stopC:
+			// Make one last attempt.
+			retry = false
+		}
+		if backoff *= 2; backoff > exportMaxBackoff {
+			backoff = exportMaxBackoff
+		}
+	}
+	// Don't retain the spans until the slots are reused.
+	for i := range batch {
+		batch[i] = exportItem{}
+	}
+}
+
+// getExporter returns the AsyncExporter registered with the tracer, if any.
+func (t *Tracer) getExporter() *AsyncExporter {
+	e, _ := t.exporter.Load().(*AsyncExporter)
+	return e
+}
+
+// getShadowExporter returns the AsyncExporter which finishes the lightstep
+// shadow spans, starting it the first time. It lives as long as the process.
+func (t *Tracer) getShadowExporter() *AsyncExporter {
+	t.shadowExporterOnce.Do(func() {
+		t.shadowExporter.Store(newAsyncExporter(t, func(_ context.Context, items []exportItem) error {
+			for i := range items {
+				items[i].shadow.FinishWithOptions(opentracing.FinishOptions{
+					FinishTime: items[i].finishTime,
+				})
+			}
+			return nil
+		}))
+	})
+	return t.shadowExporter.Load().(*AsyncExporter)
+}
+
+// ShadowExportStats returns the number of lightstep shadow spans exported and
+// dropped so far.
+func (t *Tracer) ShadowExportStats() ExportStats {
+	if e, ok := t.shadowExporter.Load().(*AsyncExporter); ok {
+		return e.Stats()
+	}
+	return ExportStats{}
+}
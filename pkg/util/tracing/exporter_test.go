@@ -0,0 +1,137 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// testSpanExporter fails the first failures calls to ExportSpans, and blocks
+// every call while block is non-nil.
+type testSpanExporter struct {
+	block chan struct{}
+
+	mu struct {
+		syncutil.Mutex
+		failures int
+		calls    int
+		ops      []string
+	}
+	exported chan struct{}
+}
+
+func (e *testSpanExporter) ExportSpans(_ context.Context, spans []RecordedSpan) error {
+	if e.block != nil {
+		<-e.block
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.mu.calls++
+	if e.mu.failures > 0 {
+		e.mu.failures--
+		return errors.New("injected failure")
+	}
+	for _, sp := range spans {
+		e.mu.ops = append(e.mu.ops, sp.Operation)
+	}
+	if e.exported != nil {
+		close(e.exported)
+		e.exported = nil
+	}
+	return nil
+}
+
+func TestAsyncExporter(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	te := &testSpanExporter{exported: make(chan struct{})}
+	te.mu.failures = 2
+	exported := te.exported
+	e := NewAsyncExporter(tr, te)
+
+	root := tr.StartSpan("root")
+	if IsNoopSpan(root) {
+		t.Fatal("expected real span while an exporter is registered")
+	}
+	child := tr.StartChildSpan("child", root.Context())
+	child.Finish()
+	root.Finish()
+
+	// The batch is retried until it succeeds.
+	select {
+	case <-exported:
+	case <-time.After(10 * time.Second):
+		t.Fatal("spans not exported")
+	}
+	e.Close()
+
+	te.mu.Lock()
+	if te.mu.calls != 3 {
+		t.Errorf("expected 3 calls, got %d", te.mu.calls)
+	}
+	if len(te.mu.ops) != 2 || te.mu.ops[0] != "child" || te.mu.ops[1] != "root" {
+		t.Errorf("expected spans [child root], got %v", te.mu.ops)
+	}
+	te.mu.Unlock()
+	if s := e.Stats(); s != (ExportStats{Exported: 2}) {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	// Once the exporter is closed, spans are no longer forced to be real.
+	if sp := tr.StartSpan("x"); !IsNoopSpan(sp) {
+		t.Error("expected noop span after the exporter was closed")
+	}
+}
+
+func TestAsyncExporterFailure(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	te := &testSpanExporter{}
+	te.mu.failures = 1
+	e := NewAsyncExporter(tr, te)
+
+	tr.StartSpan("a").Finish()
+	// The span is still queued; Close makes a single attempt to export it.
+	e.Close()
+	if s := e.Stats(); s != (ExportStats{Failed: 1}) {
+		t.Errorf("unexpected stats %+v", s)
+	}
+}
+
+func TestAsyncExporterDrop(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	te := &testSpanExporter{block: make(chan struct{})}
+	e := NewAsyncExporter(tr, te)
+
+	// With the exporter stuck, at most the queue and a batch can be retained.
+	const n = exportQueueSize + exportBatchSize + 10
+	for i := 0; i < n; i++ {
+		tr.StartSpan("a").Finish()
+	}
+	close(te.block)
+	e.Close()
+
+	s := e.Stats()
+	if s.Dropped < 10 {
+		t.Errorf("expected at least 10 dropped spans, got %+v", s)
+	}
+	if s.Exported+s.Dropped != n || s.Failed != 0 {
+		t.Errorf("expected %d spans to be exported or dropped, got %+v", n, s)
+	}
+}
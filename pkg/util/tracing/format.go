@@ -95,8 +95,8 @@ func FormatRecordedSpans(spans []RecordedSpan) string {
 				Timestamp: l.Time,
 				Fields:    make([]otlog.Field, len(l.Fields)),
 			}
-			for i, f := range l.Fields {
-				lr.Fields[i] = otlog.String(f.Key, f.Value)
+			for i := range l.Fields {
+				lr.Fields[i] = otlog.Object(l.Fields[i].Key, l.Fields[i].TypedValue())
 			}
 
 			logs = append(logs, traceLogData{LogRecord: lr, depth: d})
@@ -0,0 +1,87 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"net/http"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// HTTPMiddleware returns a function which wraps an http.Handler so that each
+// request it serves runs in a server span named after the route the handler
+// is registered for. The span is a child of the span context propagated in
+// the request headers, if any, and is available to the handler through the
+// context of the request.
+//
+//   traced := tracing.HTTPMiddleware(tracer)
+//   mux.Handle("/_status/", traced("/_status/", statusHandler))
+func HTTPMiddleware(tracer opentracing.Tracer) func(route string, h http.Handler) http.Handler {
+	return func(route string, h http.Handler) http.Handler {
+		opName := "http " + route
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			opts := []opentracing.StartSpanOption{
+				ext.SpanKindRPCServer,
+				opentracing.Tag{Key: string(ext.HTTPMethod), Value: r.Method},
+				opentracing.Tag{Key: string(ext.HTTPUrl), Value: r.URL.Path},
+			}
+			// A missing or corrupted span context results in a root span.
+			if wireCtx, err := tracer.Extract(
+				opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header),
+			); err == nil && wireCtx != nil {
+				opts = append(opts, opentracing.ChildOf(wireCtx))
+			}
+			sp := tracer.StartSpan(opName, opts...)
+			defer sp.Finish()
+
+			sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+			h.ServeHTTP(sw, r.WithContext(opentracing.ContextWithSpan(r.Context(), sp)))
+
+			ext.HTTPStatusCode.Set(sp, uint16(sw.status))
+			if sw.status >= http.StatusInternalServerError {
+				ext.Error.Set(sp, true)
+			}
+		})
+	}
+}
+
+// statusResponseWriter records the status code of a response.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, which is required by grpc-gateway for
+// streaming responses.
+func (w *statusResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	c := NewTestCollector(tr)
+	defer c.Close()
+
+	traced := HTTPMiddleware(tr)
+	h := traced("/foo/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sp := opentracing.SpanFromContext(r.Context())
+		if sp == nil {
+			t.Error("expected a span in the request context")
+		} else {
+			sp.LogKV("event", "handled")
+		}
+		if r.URL.Path == "/foo/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+
+	// A request carrying a span context is served in a child span.
+	client := tr.StartSpan("client")
+	req := httptest.NewRequest("GET", "/foo/missing", nil)
+	if err := tr.Inject(
+		client.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header),
+	); err != nil {
+		t.Fatal(err)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	client.Finish()
+
+	if err := c.CheckChildOf("client", "http /foo/"); err != nil {
+		t.Error(err)
+	}
+	sp, err := c.FindSpan("http /foo/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range map[string]string{
+		"span.kind":        "server",
+		"http.method":      "GET",
+		"http.url":         "/foo/missing",
+		"http.status_code": "404",
+	} {
+		if sp.Tags[k] != v {
+			t.Errorf("expected tag %s=%s, got %q", k, v, sp.Tags[k])
+		}
+	}
+
+	// A request without a span context is served in a root span.
+	c.Reset()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo/bar", nil))
+	sp, err = c.FindSpan("http /foo/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.ParentSpanID != 0 {
+		t.Errorf("expected a root span, got parent %d", sp.ParentSpanID)
+	}
+	if sp.Tags["http.status_code"] != "200" {
+		t.Errorf("expected status 200, got %q", sp.Tags["http.status_code"])
+	}
+}
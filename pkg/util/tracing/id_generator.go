@@ -0,0 +1,60 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import "sync/atomic"
+
+// IDGenerator is the source of the trace and span IDs assigned by a Tracer
+// (when lightstep is in use, lightstep assigns the IDs instead).
+// Implementations must be safe for concurrent use.
+type IDGenerator interface {
+	// NextID returns a new ID. IDs must be non-zero and fit in 63 bits.
+	NextID() uint64
+}
+
+// seededIDGenerator is an IDGenerator producing the splitmix64 sequence for a
+// given seed. Unlike the global math/rand source, it is lock-free: each ID
+// costs one atomic add.
+type seededIDGenerator struct {
+	state uint64
+}
+
+var _ IDGenerator = &seededIDGenerator{}
+
+// NewSeededIDGenerator returns an IDGenerator whose sequence of IDs is fully
+// determined by seed. Tests can use it (through Tracer.SetIDGenerator) to get
+// reproducible IDs.
+func NewSeededIDGenerator(seed int64) IDGenerator {
+	return &seededIDGenerator{state: uint64(seed)}
+}
+
+// NextID is part of the IDGenerator interface.
+func (g *seededIDGenerator) NextID() uint64 {
+	for {
+		z := atomic.AddUint64(&g.state, 0x9e3779b97f4a7c15)
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		z = (z ^ (z >> 31)) >> 1
+		if z != 0 {
+			return z
+		}
+	}
+}
+
+// idGeneratorBox allows storing an IDGenerator in an atomic.Value, which
+// requires a consistent concrete type.
+type idGeneratorBox struct {
+	IDGenerator
+}
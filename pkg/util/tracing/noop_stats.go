@@ -0,0 +1,123 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+var enableNoopSpanStats = settings.RegisterBoolSetting(
+	"trace.debug.noop_stats.enabled",
+	"if set, the number of noop spans started for each operation, and the reason they are noop, "+
+		"can be seen in the /debug/tracez/noop page",
+	false,
+)
+
+// NoopSpanReason describes why the Tracer returned a noop span instead of a
+// real one.
+type NoopSpanReason int
+
+const (
+	// NoopParent is used for the children of noop spans.
+	NoopParent NoopSpanReason = iota
+	// NoOptions is used for root spans which were not started with the
+	// Recordable or WithSnowball options while no tracing backend (x/net/trace,
+	// lightstep, span registry, etc.) was enabled.
+	NoOptions
+	// NoRecording is used for the children of real spans which aren't
+	// recording, while no tracing backend was enabled.
+	NoRecording
+	// ShadowDisabled is used for the children of real spans without a
+	// lightstep shadow span, when lightstep is the only enabled backend.
+	ShadowDisabled
+)
+
+var noopSpanReasonNames = [...]string{
+	NoopParent:     "noop parent",
+	NoOptions:      "no options",
+	NoRecording:    "no recording",
+	ShadowDisabled: "shadow disabled",
+}
+
+func (r NoopSpanReason) String() string {
+	return noopSpanReasonNames[r]
+}
+
+// NoopSpanStat is the number of noop spans started by a Tracer for an
+// operation for a given reason.
+type NoopSpanStat struct {
+	Operation string
+	Reason    NoopSpanReason
+	Count     int64
+}
+
+type noopSpanKey struct {
+	operation string
+	reason    NoopSpanReason
+}
+
+// noopSpanStats counts the noop spans returned by a Tracer while the
+// trace.debug.noop_stats.enabled setting is on.
+type noopSpanStats struct {
+	syncutil.Mutex
+	counts map[noopSpanKey]int64
+}
+
+// record counts a noop span, if the trace.debug.noop_stats.enabled setting is
+// on.
+func (s *noopSpanStats) record(operation string, reason NoopSpanReason) {
+	if !enableNoopSpanStats.Get() {
+		return
+	}
+	s.Lock()
+	if s.counts == nil {
+		s.counts = make(map[noopSpanKey]int64)
+	}
+	s.counts[noopSpanKey{operation: operation, reason: reason}]++
+	s.Unlock()
+}
+
+// NoopSpanStats returns the number of noop spans started by this tracer for
+// each operation and reason, ordered by operation and reason. It helps
+// understand why the traces of an operation are empty.
+//
+// Noop spans are only counted while the trace.debug.noop_stats.enabled setting
+// is on.
+func (t *Tracer) NoopSpanStats() []NoopSpanStat {
+	t.noopStats.Lock()
+	result := make([]NoopSpanStat, 0, len(t.noopStats.counts))
+	for k, c := range t.noopStats.counts {
+		result = append(result, NoopSpanStat{Operation: k.operation, Reason: k.reason, Count: c})
+	}
+	t.noopStats.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Operation != result[j].Operation {
+			return result[i].Operation < result[j].Operation
+		}
+		return result[i].Reason < result[j].Reason
+	})
+	return result
+}
+
+// ResetNoopSpanStats discards the noop span counts collected so far.
+func (t *Tracer) ResetNoopSpanStats() {
+	t.noopStats.Lock()
+	t.noopStats.counts = nil
+	t.noopStats.Unlock()
+}
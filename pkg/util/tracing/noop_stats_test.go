@@ -0,0 +1,62 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"reflect"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+)
+
+func TestNoopSpanStats(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+
+	// With the setting off, noop spans aren't counted.
+	tr.StartSpan("off").Finish()
+	if stats := tr.NoopSpanStats(); len(stats) != 0 {
+		t.Fatalf("expected no stats, got %+v", stats)
+	}
+
+	defer settings.TestingSetBool(&enableNoopSpanStats, true)()
+
+	root := tr.StartSpan("root")
+	tr.StartSpan("child", opentracing.ChildOf(root.Context())).Finish()
+	tr.StartChildSpan("child", root.Context()).Finish()
+	root.Finish()
+
+	sp := tr.StartSpan("real", Recordable)
+	if IsNoopSpan(sp) {
+		t.Fatal("expected real span")
+	}
+	tr.StartSpan("child", opentracing.ChildOf(sp.Context())).Finish()
+	sp.Finish()
+
+	expected := []NoopSpanStat{
+		{Operation: "child", Reason: NoopParent, Count: 2},
+		{Operation: "child", Reason: NoRecording, Count: 1},
+		{Operation: "root", Reason: NoOptions, Count: 1},
+	}
+	if stats := tr.NoopSpanStats(); !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+
+	tr.ResetNoopSpanStats()
+	if stats := tr.NoopSpanStats(); len(stats) != 0 {
+		t.Errorf("expected no stats after reset, got %+v", stats)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+// maxOnDemandRecordings is the number of recordings retained by on-demand
+// tracing; older recordings are discarded.
+const maxOnDemandRecordings = 100
+
+// onDemandTracing allows recording to be turned on, for a limited time, for all
+// new spans whose operation name matches a pattern. This makes it possible to
+// trace a subsystem on a running node without enabling tracing globally.
+//
+// A matching span that is not already part of a recording becomes the root of
+// a new recording, which also includes the span's local descendants. The
+// recording is retained when the root span finishes.
+type onDemandTracing struct {
+	// state holds an *onDemandState; it is nil if on-demand tracing is off.
+	state atomic.Value
+
+	mu struct {
+		syncutil.Mutex
+		recordings [][]RecordedSpan
+	}
+}
+
+type onDemandState struct {
+	pattern  *regexp.Regexp
+	deadline time.Time
+}
+
+// OnDemandTracingStatus describes the state of on-demand tracing.
+type OnDemandTracingStatus struct {
+	// Pattern is empty if on-demand tracing was never started or was stopped.
+	Pattern  string
+	Deadline time.Time
+}
+
+// Active returns true if new spans may be recorded.
+func (s OnDemandTracingStatus) Active() bool {
+	return s.Pattern != "" && time.Now().Before(s.Deadline)
+}
+
+func (o *onDemandTracing) getState() *onDemandState {
+	st, _ := o.state.Load().(*onDemandState)
+	return st
+}
+
+// matches returns true if a new span with the given operation name should
+// record.
+func (o *onDemandTracing) matches(operationName string) bool {
+	st := o.getState()
+	if st == nil {
+		return false
+	}
+	return time.Now().Before(st.deadline) && st.pattern.MatchString(operationName)
+}
+
+func (o *onDemandTracing) addRecording(rec []RecordedSpan) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.mu.recordings) >= maxOnDemandRecordings {
+		o.mu.recordings = o.mu.recordings[1:]
+	}
+	o.mu.recordings = append(o.mu.recordings, rec)
+}
+
+// StartOnDemandTracing causes new spans whose operation name matches pattern
+// (a regular expression) to record for the given duration. Recordings can be
+// retrieved through OnDemandRecordings; the ones collected by previous calls
+// are discarded.
+func (t *Tracer) StartOnDemandTracing(pattern string, duration time.Duration) error {
+	if pattern == "" {
+		return errors.New("empty pattern")
+	}
+	if duration <= 0 {
+		return errors.Errorf("invalid duration: %s", duration)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	t.onDemand.mu.Lock()
+	t.onDemand.mu.recordings = nil
+	t.onDemand.mu.Unlock()
+	t.onDemand.state.Store(&onDemandState{pattern: re, deadline: time.Now().Add(duration)})
+	return nil
+}
+
+// StopOnDemandTracing stops the recording of new spans started by
+// StartOnDemandTracing. Spans which are already recording continue to do so
+// until they finish. The collected recordings are retained.
+func (t *Tracer) StopOnDemandTracing() {
+	t.onDemand.state.Store((*onDemandState)(nil))
+}
+
+// GetOnDemandTracingStatus returns the current state of on-demand tracing.
+func (t *Tracer) GetOnDemandTracingStatus() OnDemandTracingStatus {
+	st := t.onDemand.getState()
+	if st == nil {
+		return OnDemandTracingStatus{}
+	}
+	return OnDemandTracingStatus{Pattern: st.pattern.String(), Deadline: st.deadline}
+}
+
+// OnDemandRecordings returns the recordings collected since on-demand tracing
+// was last started, oldest first.
+func (t *Tracer) OnDemandRecordings() [][]RecordedSpan {
+	t.onDemand.mu.Lock()
+	defer t.onDemand.mu.Unlock()
+	return append([][]RecordedSpan(nil), t.onDemand.mu.recordings...)
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+func TestOnDemandTracing(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+
+	if err := tr.StartOnDemandTracing("(", time.Minute); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+	if status := tr.GetOnDemandTracingStatus(); status.Active() {
+		t.Errorf("expected on-demand tracing to be inactive, got %+v", status)
+	}
+
+	if err := tr.StartOnDemandTracing("^txn", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if status := tr.GetOnDemandTracingStatus(); !status.Active() || status.Pattern != "^txn" {
+		t.Errorf("expected on-demand tracing to be active, got %+v", status)
+	}
+
+	// A non-matching span is not recorded.
+	s := tr.StartSpan("other")
+	if !IsNoopSpan(s) {
+		t.Error("expected noop span")
+	}
+	s.Finish()
+
+	// A matching span records, even under a noop parent, and so do its
+	// children.
+	parent := tr.StartSpan("parent")
+	s = tr.StartSpan("txn coordinator", opentracing.ChildOf(parent.Context()))
+	if IsNoopSpan(s) {
+		t.Fatal("expected real span")
+	}
+	s.LogKV("event", "hello")
+	child := tr.StartSpan("child", opentracing.ChildOf(s.Context()))
+	child.Finish()
+	s.Finish()
+	parent.Finish()
+
+	recs := tr.OnDemandRecordings()
+	if len(recs) != 1 {
+		t.Fatalf("expected 1 recording, got %d", len(recs))
+	}
+	checkRecordedSpans(t, recs[0], `
+	  span txn coordinator:
+      event: hello
+	  span child:
+	`)
+
+	tr.StopOnDemandTracing()
+	if s := tr.StartSpan("txn coordinator"); !IsNoopSpan(s) {
+		t.Error("expected noop span after stopping on-demand tracing")
+	}
+	if recs := tr.OnDemandRecordings(); len(recs) != 1 {
+		t.Errorf("expected recordings to be retained, got %d", len(recs))
+	}
+}
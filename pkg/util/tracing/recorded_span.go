@@ -0,0 +1,83 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"fmt"
+	"time"
+
+	otlog "github.com/opentracing/opentracing-go/log"
+)
+
+// makeRecordedField converts a logged field to its recorded form. Values of the
+// supported kinds retain their type, in addition to being converted to a
+// string.
+func makeRecordedField(f otlog.Field) RecordedSpan_LogRecord_Field {
+	rf := RecordedSpan_LogRecord_Field{
+		Key:   f.Key(),
+		Value: fmt.Sprint(f.Value()),
+	}
+	switch v := f.Value().(type) {
+	case bool:
+		rf.Kind, rf.BoolValue = FieldKind_BOOL, v
+	case int:
+		rf.Kind, rf.IntValue = FieldKind_INT, int64(v)
+	case int32:
+		rf.Kind, rf.IntValue = FieldKind_INT, int64(v)
+	case int64:
+		rf.Kind, rf.IntValue = FieldKind_INT, v
+	case uint32:
+		rf.Kind, rf.UintValue = FieldKind_UINT, uint64(v)
+	case uint64:
+		rf.Kind, rf.UintValue = FieldKind_UINT, v
+	case float32:
+		rf.Kind, rf.FloatValue = FieldKind_FLOAT, float64(v)
+	case float64:
+		rf.Kind, rf.FloatValue = FieldKind_FLOAT, v
+	case time.Duration:
+		rf.Kind, rf.IntValue = FieldKind_DURATION, int64(v)
+	}
+	return rf
+}
+
+// TypedValue returns the value of the field as a bool, int64, uint64, float64
+// or time.Duration, according to its kind. Values of kind STRING are returned
+// as strings.
+func (f *RecordedSpan_LogRecord_Field) TypedValue() interface{} {
+	switch f.Kind {
+	case FieldKind_BOOL:
+		return f.BoolValue
+	case FieldKind_INT:
+		return f.IntValue
+	case FieldKind_UINT:
+		return f.UintValue
+	case FieldKind_FLOAT:
+		return f.FloatValue
+	case FieldKind_DURATION:
+		return time.Duration(f.IntValue)
+	default:
+		return f.Value
+	}
+}
+
+// Int returns the value of the field if it is of kind INT.
+func (f *RecordedSpan_LogRecord_Field) Int() (int64, bool) {
+	return f.IntValue, f.Kind == FieldKind_INT
+}
+
+// Duration returns the value of the field if it is of kind DURATION.
+func (f *RecordedSpan_LogRecord_Field) Duration() (time.Duration, bool) {
+	return time.Duration(f.IntValue), f.Kind == FieldKind_DURATION
+}
@@ -0,0 +1,166 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// recordingJSONVersion is the version of the JSON format of recordings. It is
+// incremented when the format changes incompatibly; adding fields doesn't
+// require a new version.
+const recordingJSONVersion = 1
+
+// The types below define the JSON format of recordings. They are kept
+// separate from RecordedSpan so that the format remains stable as the protobuf
+// evolves. The 64-bit integers are encoded as strings, which JavaScript
+// consumers can't otherwise represent exactly.
+
+type jsonRecording struct {
+	Version int        `json:"version"`
+	Spans   []jsonSpan `json:"spans"`
+}
+
+type jsonSpan struct {
+	TraceID      uint64            `json:"trace_id,string"`
+	SpanID       uint64            `json:"span_id,string"`
+	ParentSpanID uint64            `json:"parent_span_id,string,omitempty"`
+	Operation    string            `json:"operation"`
+	StartTime    time.Time         `json:"start_time"`
+	Duration     int64             `json:"duration_nanos,string"`
+	Baggage      map[string]string `json:"baggage,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	Logs         []jsonLogRecord   `json:"logs,omitempty"`
+	Links        []jsonSpanLink    `json:"links,omitempty"`
+}
+
+type jsonLogRecord struct {
+	Time   time.Time   `json:"time"`
+	Fields []jsonField `json:"fields"`
+}
+
+type jsonField struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Kind is the lowercase name of the FieldKind; it is omitted for strings.
+	Kind       string  `json:"kind,omitempty"`
+	BoolValue  bool    `json:"bool_value,omitempty"`
+	IntValue   int64   `json:"int_value,string,omitempty"`
+	UintValue  uint64  `json:"uint_value,string,omitempty"`
+	FloatValue float64 `json:"float_value,omitempty"`
+}
+
+type jsonSpanLink struct {
+	TraceID     uint64 `json:"trace_id,string"`
+	SpanID      uint64 `json:"span_id,string"`
+	FollowsFrom bool   `json:"follows_from,omitempty"`
+}
+
+// RecordingToJSON encodes a recording in a stable, machine-readable JSON
+// format, which RecordingFromJSON decodes.
+func RecordingToJSON(rec []RecordedSpan) ([]byte, error) {
+	jr := jsonRecording{
+		Version: recordingJSONVersion,
+		Spans:   make([]jsonSpan, len(rec)),
+	}
+	for i := range rec {
+		sp := &rec[i]
+		js := &jr.Spans[i]
+		*js = jsonSpan{
+			TraceID:      sp.TraceID,
+			SpanID:       sp.SpanID,
+			ParentSpanID: sp.ParentSpanID,
+			Operation:    sp.Operation,
+			StartTime:    sp.StartTime,
+			Duration:     int64(sp.Duration),
+			Baggage:      sp.Baggage,
+			Tags:         sp.Tags,
+		}
+		for _, l := range sp.Logs {
+			jl := jsonLogRecord{Time: l.Time, Fields: make([]jsonField, len(l.Fields))}
+			for j, f := range l.Fields {
+				jl.Fields[j] = jsonField{
+					Key:        f.Key,
+					Value:      f.Value,
+					BoolValue:  f.BoolValue,
+					IntValue:   f.IntValue,
+					UintValue:  f.UintValue,
+					FloatValue: f.FloatValue,
+				}
+				if f.Kind != FieldKind_STRING {
+					jl.Fields[j].Kind = strings.ToLower(f.Kind.String())
+				}
+			}
+			js.Logs = append(js.Logs, jl)
+		}
+		for _, l := range sp.Links {
+			js.Links = append(js.Links, jsonSpanLink(l))
+		}
+	}
+	return json.Marshal(jr)
+}
+
+// RecordingFromJSON decodes a recording encoded by RecordingToJSON. Fields of
+// unknown kinds are decoded as strings.
+func RecordingFromJSON(data []byte) ([]RecordedSpan, error) {
+	var jr jsonRecording
+	if err := json.Unmarshal(data, &jr); err != nil {
+		return nil, errors.Wrap(err, "decoding recording")
+	}
+	if jr.Version < 1 || jr.Version > recordingJSONVersion {
+		return nil, errors.Errorf("unsupported recording version %d", jr.Version)
+	}
+	rec := make([]RecordedSpan, len(jr.Spans))
+	for i := range jr.Spans {
+		js := &jr.Spans[i]
+		sp := &rec[i]
+		*sp = RecordedSpan{
+			TraceID:      js.TraceID,
+			SpanID:       js.SpanID,
+			ParentSpanID: js.ParentSpanID,
+			Operation:    js.Operation,
+			StartTime:    js.StartTime,
+			Duration:     time.Duration(js.Duration),
+			Baggage:      js.Baggage,
+			Tags:         js.Tags,
+		}
+		for _, jl := range js.Logs {
+			l := RecordedSpan_LogRecord{
+				Time:   jl.Time,
+				Fields: make([]RecordedSpan_LogRecord_Field, len(jl.Fields)),
+			}
+			for j, jf := range jl.Fields {
+				f := RecordedSpan_LogRecord_Field{Key: jf.Key, Value: jf.Value}
+				if kind, ok := FieldKind_value[strings.ToUpper(jf.Kind)]; ok && jf.Kind != "" {
+					f.Kind = FieldKind(kind)
+					f.BoolValue = jf.BoolValue
+					f.IntValue = jf.IntValue
+					f.UintValue = jf.UintValue
+					f.FloatValue = jf.FloatValue
+				}
+				l.Fields[j] = f
+			}
+			sp.Logs = append(sp.Logs, l)
+		}
+		for _, jl := range js.Links {
+			sp.Links = append(sp.Links, RecordedSpan_SpanLink(jl))
+		}
+	}
+	return rec, nil
+}
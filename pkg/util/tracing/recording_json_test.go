@@ -0,0 +1,91 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordingJSON(t *testing.T) {
+	start := time.Date(2017, 6, 1, 12, 0, 0, 123456789, time.UTC)
+	rec := []RecordedSpan{
+		{
+			TraceID:   1 << 63,
+			SpanID:    2,
+			Operation: "root",
+			StartTime: start,
+			Duration:  3 * time.Millisecond,
+			Baggage:   map[string]string{"sb": "1"},
+			Tags:      map[string]string{"component": "sql"},
+			Logs: []RecordedSpan_LogRecord{{
+				Time: start.Add(time.Millisecond),
+				Fields: []RecordedSpan_LogRecord_Field{
+					{Key: "event", Value: "hello"},
+					{Key: "b", Value: "true", Kind: FieldKind_BOOL, BoolValue: true},
+					{Key: "i", Value: "-5", Kind: FieldKind_INT, IntValue: -5},
+					{Key: "u", Value: "18446744073709551615", Kind: FieldKind_UINT, UintValue: 1<<64 - 1},
+					{Key: "f", Value: "1.5", Kind: FieldKind_FLOAT, FloatValue: 1.5},
+					{Key: "d", Value: "2s", Kind: FieldKind_DURATION, IntValue: int64(2 * time.Second)},
+				},
+			}},
+		},
+		{
+			TraceID:      1 << 63,
+			SpanID:       3,
+			ParentSpanID: 2,
+			Operation:    "child",
+			StartTime:    start.Add(time.Millisecond),
+			Links:        []RecordedSpan_SpanLink{{TraceID: 7, SpanID: 8, FollowsFrom: true}},
+		},
+	}
+
+	data, err := RecordingToJSON(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The 64-bit integers are encoded as strings.
+	for _, exp := range []string{`"version":1`, `"trace_id":"9223372036854775808"`, `"kind":"uint"`} {
+		if !strings.Contains(string(data), exp) {
+			t.Errorf("expected %s in %s", exp, data)
+		}
+	}
+
+	decoded, err := RecordingFromJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, rec) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", rec, decoded)
+	}
+
+	// Fields of unknown kinds are decoded as strings.
+	decoded, err = RecordingFromJSON([]byte(`{"version":1,"spans":[{"trace_id":"1","span_id":"2",` +
+		`"start_time":"2017-06-01T12:00:00Z","duration_nanos":"0",` +
+		`"logs":[{"time":"2017-06-01T12:00:00Z","fields":[{"key":"k","value":"v","kind":"blob"}]}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := decoded[0].Logs[0].Fields[0]; f.Kind != FieldKind_STRING || f.Value != "v" {
+		t.Errorf("expected a string field, got %+v", f)
+	}
+
+	if _, err := RecordingFromJSON([]byte(`{"version":2,"spans":[]}`)); err == nil ||
+		!strings.Contains(err.Error(), "unsupported recording version 2") {
+		t.Errorf("expected a version error, got %v", err)
+	}
+}
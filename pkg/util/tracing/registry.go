@@ -0,0 +1,142 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+)
+
+var enableSpanRegistry = settings.RegisterBoolSetting(
+	"trace.registry.enabled",
+	"if set, open and recently finished slow spans can be seen in the /debug/tracez page",
+	false,
+)
+
+// SlowSpanThresholds are the lower bounds of the latency buckets in which
+// finished spans are kept by the span registry. A span is kept in every bucket
+// whose threshold it exceeds.
+var SlowSpanThresholds = [...]time.Duration{
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+}
+
+// slowSpansPerBucket is the number of finished spans kept per latency bucket.
+const slowSpansPerBucket = 10
+
+// spanRegistry tracks the open spans of a Tracer, as well as the most recent
+// slow spans it finished. Spans are only tracked while the
+// trace.registry.enabled setting is on.
+type spanRegistry struct {
+	syncutil.Mutex
+	active map[*span]struct{}
+	// slow contains a ring buffer of finished spans for each of the
+	// SlowSpanThresholds.
+	slow [len(SlowSpanThresholds)]struct {
+		spans [slowSpansPerBucket]RecordedSpan
+		next  int
+		count int
+	}
+}
+
+func (r *spanRegistry) addActive(s *span) {
+	r.Lock()
+	if r.active == nil {
+		r.active = make(map[*span]struct{})
+	}
+	r.active[s] = struct{}{}
+	r.Unlock()
+}
+
+// removeActive stops tracking s as open. If s was slow, info is retained in the
+// corresponding latency buckets.
+func (r *spanRegistry) removeActive(s *span, info *RecordedSpan) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.active, s)
+	if info == nil {
+		return
+	}
+	for i, threshold := range SlowSpanThresholds {
+		if info.Duration < threshold {
+			break
+		}
+		b := &r.slow[i]
+		b.spans[b.next] = *info
+		b.next = (b.next + 1) % slowSpansPerBucket
+		if b.count < slowSpansPerBucket {
+			b.count++
+		}
+	}
+}
+
+// SlowSpanBucket holds recently finished spans that took at least MinDuration.
+type SlowSpanBucket struct {
+	MinDuration time.Duration
+	// Spans are ordered from most to least recently finished.
+	Spans []RecordedSpan
+}
+
+// ActiveSpans returns the spans of this tracer which have been started but not
+// yet finished, ordered by start time. The Duration of each span is the time
+// elapsed since it was started. The returned spans don't include logs.
+//
+// Spans are only tracked while the trace.registry.enabled setting is on.
+func (t *Tracer) ActiveSpans() []RecordedSpan {
+	t.registry.Lock()
+	spans := make([]*span, 0, len(t.registry.active))
+	for s := range t.registry.active {
+		spans = append(spans, s)
+	}
+	t.registry.Unlock()
+
+	now := time.Now()
+	result := make([]RecordedSpan, len(spans))
+	for i, s := range spans {
+		s.mu.Lock()
+		result[i] = s.getInfoLocked()
+		s.mu.Unlock()
+		result[i].Duration = now.Sub(result[i].StartTime)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartTime.Before(result[j].StartTime)
+	})
+	return result
+}
+
+// SlowSpans returns the most recently finished spans of this tracer that took
+// at least as long as each of the SlowSpanThresholds. The returned spans don't
+// include logs.
+func (t *Tracer) SlowSpans() []SlowSpanBucket {
+	t.registry.Lock()
+	defer t.registry.Unlock()
+	result := make([]SlowSpanBucket, len(SlowSpanThresholds))
+	for i, threshold := range SlowSpanThresholds {
+		b := &t.registry.slow[i]
+		result[i].MinDuration = threshold
+		result[i].Spans = make([]RecordedSpan, b.count)
+		for j := 0; j < b.count; j++ {
+			idx := (b.next - 1 - j + slowSpansPerBucket) % slowSpansPerBucket
+			result[i].Spans[j] = b.spans[idx]
+		}
+	}
+	return result
+}
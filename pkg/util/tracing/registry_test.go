@@ -0,0 +1,111 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
+)
+
+func activeOps(tr *Tracer) []string {
+	var ops []string
+	for _, s := range tr.ActiveSpans() {
+		ops = append(ops, s.Operation)
+	}
+	return ops
+}
+
+func TestSpanRegistry(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+
+	// With the setting off, spans are noop and aren't tracked.
+	s := tr.StartSpan("off")
+	if !IsNoopSpan(s) {
+		t.Error("expected noop span")
+	}
+	if ops := activeOps(tr); len(ops) != 0 {
+		t.Errorf("expected no active spans, got %v", ops)
+	}
+	s.Finish()
+
+	defer settings.TestingSetBool(&enableSpanRegistry, true)()
+
+	start := time.Now()
+	s1 := tr.StartSpan("a", opentracing.StartTime(start))
+	s1.SetTag("tag", "val")
+	s2 := tr.StartSpan("b", opentracing.ChildOf(s1.Context()),
+		opentracing.StartTime(start.Add(time.Millisecond)))
+
+	if ops := activeOps(tr); len(ops) != 2 || ops[0] != "a" || ops[1] != "b" {
+		t.Fatalf("expected active spans [a b], got %v", ops)
+	}
+	if tag := tr.ActiveSpans()[0].Tags["tag"]; tag != "val" {
+		t.Errorf("expected tag to be retained, got %q", tag)
+	}
+
+	// A fast span doesn't show up in any latency bucket.
+	s2.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(2 * time.Millisecond)})
+	if ops := activeOps(tr); len(ops) != 1 || ops[0] != "a" {
+		t.Fatalf("expected active spans [a], got %v", ops)
+	}
+
+	s1.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(150 * time.Millisecond)})
+	if ops := activeOps(tr); len(ops) != 0 {
+		t.Errorf("expected no active spans, got %v", ops)
+	}
+	buckets := tr.SlowSpans()
+	if len(buckets) != len(SlowSpanThresholds) {
+		t.Fatalf("expected %d buckets, got %d", len(SlowSpanThresholds), len(buckets))
+	}
+	for i, b := range buckets {
+		expected := 0
+		if b.MinDuration <= 150*time.Millisecond {
+			expected = 1
+		}
+		if len(b.Spans) != expected {
+			t.Errorf("bucket %d (>= %s): expected %d spans, got %d", i, b.MinDuration, expected, len(b.Spans))
+			continue
+		}
+		if expected == 1 && (b.Spans[0].Operation != "a" || b.Spans[0].Tags["tag"] != "val") {
+			t.Errorf("bucket %d: unexpected span %+v", i, b.Spans[0])
+		}
+	}
+}
+
+func TestSpanRegistrySlowSpansWrapAround(t *testing.T) {
+	defer settings.TestingSetBool(&enableSpanRegistry, true)()
+	tr := NewTracer().(*Tracer)
+
+	start := time.Now()
+	for i := 0; i < slowSpansPerBucket+3; i++ {
+		s := tr.StartSpan("op", opentracing.StartTime(start), opentracing.Tag{Key: "i", Value: i})
+		s.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(time.Second)})
+	}
+	spans := tr.SlowSpans()[0].Spans
+	if len(spans) != slowSpansPerBucket {
+		t.Fatalf("expected %d spans, got %d", slowSpansPerBucket, len(spans))
+	}
+	// The most recently finished span comes first.
+	if i := spans[0].Tags["i"]; i != "12" {
+		t.Errorf("expected most recent span first, got i=%s", i)
+	}
+	if i := spans[len(spans)-1].Tags["i"]; i != "3" {
+		t.Errorf("expected oldest retained span last, got i=%s", i)
+	}
+}
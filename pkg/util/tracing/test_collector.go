@@ -0,0 +1,142 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
+	"github.com/pkg/errors"
+)
+
+// TestCollector records, in memory, every span finished by a Tracer. It acts
+// as a shadow of the Tracer (much like a lightstep tracer would): while a
+// collector is registered, all spans are real spans (no matter whether tracing
+// is otherwise enabled) and their tags and logs are retained.
+//
+// TestCollector is meant for tests that want to assert on the tracing behavior
+// of the code under test:
+//
+//   c := tracing.NewTestCollector(tracer)
+//   defer c.Close()
+//   ... run the code ...
+//   for _, sp := range c.FindSpans("my op") { ... }
+type TestCollector struct {
+	tracer *Tracer
+
+	mu struct {
+		syncutil.Mutex
+		spans []RecordedSpan
+	}
+}
+
+// NewTestCollector creates a TestCollector and registers it with the given
+// Tracer, replacing any previously registered collector. Only spans started
+// after this call are collected.
+func NewTestCollector(tracer *Tracer) *TestCollector {
+	c := &TestCollector{tracer: tracer}
+	tracer.collector.Store(c)
+	return c
+}
+
+// Close unregisters the collector from its Tracer. The spans collected so far
+// remain available.
+func (c *TestCollector) Close() {
+	if c.tracer.getCollector() == c {
+		c.tracer.collector.Store((*TestCollector)(nil))
+	}
+}
+
+func (c *TestCollector) addSpan(rs RecordedSpan) {
+	c.mu.Lock()
+	c.mu.spans = append(c.mu.spans, rs)
+	c.mu.Unlock()
+}
+
+// Spans returns all the spans collected so far, in the order in which they
+// finished.
+func (c *TestCollector) Spans() []RecordedSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]RecordedSpan(nil), c.mu.spans...)
+}
+
+// Reset discards all the spans collected so far.
+func (c *TestCollector) Reset() {
+	c.mu.Lock()
+	c.mu.spans = nil
+	c.mu.Unlock()
+}
+
+// findSpans returns the collected spans for which the predicate is true.
+func (c *TestCollector) findSpans(pred func(*RecordedSpan) bool) []RecordedSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var res []RecordedSpan
+	for i := range c.mu.spans {
+		if pred(&c.mu.spans[i]) {
+			res = append(res, c.mu.spans[i])
+		}
+	}
+	return res
+}
+
+// FindSpans returns the collected spans with the given operation name.
+func (c *TestCollector) FindSpans(operation string) []RecordedSpan {
+	return c.findSpans(func(rs *RecordedSpan) bool {
+		return rs.Operation == operation
+	})
+}
+
+// FindSpansWithTag returns the collected spans that have the given tag set to
+// the given value. Tag values are compared in their string form.
+func (c *TestCollector) FindSpansWithTag(key, value string) []RecordedSpan {
+	return c.findSpans(func(rs *RecordedSpan) bool {
+		v, ok := rs.Tags[key]
+		return ok && v == value
+	})
+}
+
+// FindSpan returns the single collected span with the given operation name. An
+// error is returned if there isn't exactly one such span.
+func (c *TestCollector) FindSpan(operation string) (RecordedSpan, error) {
+	spans := c.FindSpans(operation)
+	if len(spans) != 1 {
+		return RecordedSpan{}, errors.Errorf(
+			"expected exactly one span with operation %q, found %d", operation, len(spans))
+	}
+	return spans[0], nil
+}
+
+// CheckChildOf verifies that the span with operation child is a direct child
+// of the span with operation parent, and that both are part of the same trace.
+// Both operations must identify exactly one collected span.
+func (c *TestCollector) CheckChildOf(parent, child string) error {
+	p, err := c.FindSpan(parent)
+	if err != nil {
+		return err
+	}
+	ch, err := c.FindSpan(child)
+	if err != nil {
+		return err
+	}
+	if ch.TraceID != p.TraceID {
+		return errors.Errorf("span %q is in trace %d, but its expected parent %q is in trace %d",
+			child, ch.TraceID, parent, p.TraceID)
+	}
+	if ch.ParentSpanID != p.SpanID {
+		return errors.Errorf("span %q has parent span %d, expected %q (%d)",
+			child, ch.ParentSpanID, parent, p.SpanID)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+func TestTestCollector(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	c := NewTestCollector(tr)
+
+	root := tr.StartSpan("root")
+	if IsNoopSpan(root) {
+		t.Fatal("expected real span while a collector is registered")
+	}
+	ctx := opentracing.ContextWithSpan(context.Background(), root)
+	_, child := ChildSpan(ctx, "child")
+	child.SetTag("tag", "val")
+	child.LogKV("x", 1)
+	child.Finish()
+	_, forked := ForkCtxSpan(ctx, "forked")
+	forked.Finish()
+	root.Finish()
+
+	if l := len(c.Spans()); l != 3 {
+		t.Fatalf("expected 3 spans, got %d", l)
+	}
+	if err := c.CheckChildOf("root", "child"); err != nil {
+		t.Error(err)
+	}
+	if err := c.CheckChildOf("root", "forked"); err != nil {
+		t.Error(err)
+	}
+	if err := c.CheckChildOf("child", "root"); err == nil {
+		t.Error("expected root not to be a child of child")
+	}
+	if spans := c.FindSpansWithTag("tag", "val"); len(spans) != 1 || spans[0].Operation != "child" {
+		t.Errorf("unexpected spans with tag: %+v", spans)
+	}
+	sp, err := c.FindSpan("child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sp.Logs) != 1 || sp.Logs[0].Fields[0].Key != "x" {
+		t.Errorf("unexpected logs: %+v", sp.Logs)
+	}
+
+	c.Reset()
+	c.Close()
+	s := tr.StartSpan("after close")
+	if !IsNoopSpan(s) {
+		t.Error("expected noop span after the collector was closed")
+	}
+	s.Finish()
+	if l := len(c.Spans()); l != 0 {
+		t.Errorf("expected no spans after Reset and Close, got %d", l)
+	}
+}
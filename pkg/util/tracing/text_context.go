@@ -0,0 +1,105 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// EncodeSpanContext serializes a span context into a single-line string, so
+// that an application can pass the context of its trace to the database (see
+// DecodeSpanContext). The string contains the fields of the TextMap carrier of
+// the span context, encoded as a URL query; it contains no quotes or comment
+// delimiters, so it can be used in a SQL string literal or comment.
+//
+// The context of a noop span is encoded as an empty string.
+func EncodeSpanContext(tr opentracing.Tracer, sc opentracing.SpanContext) (string, error) {
+	carrier := opentracing.TextMapCarrier{}
+	if err := tr.Inject(sc, opentracing.TextMap, carrier); err != nil {
+		return "", err
+	}
+	values := make(url.Values, len(carrier))
+	for k, v := range carrier {
+		values.Set(k, v)
+	}
+	return values.Encode(), nil
+}
+
+// DecodeSpanContext parses a span context encoded by EncodeSpanContext. The
+// spans started as children of the returned context continue the trace of the
+// encoded span.
+func DecodeSpanContext(tr opentracing.Tracer, s string) (opentracing.SpanContext, error) {
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid span context")
+	}
+	carrier := opentracing.TextMapCarrier{}
+	for k, v := range values {
+		if len(v) != 1 {
+			return nil, errors.Errorf("invalid span context: multiple values for %q", k)
+		}
+		carrier[k] = v[0]
+	}
+	sc, err := tr.Extract(opentracing.TextMap, carrier)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid span context")
+	}
+	return sc, nil
+}
+
+// sqlCommentPrefix starts the comment in which a span context can be passed
+// along with SQL statements (see SpanContextFromSQLComment).
+const sqlCommentPrefix = "/* trace:"
+
+// SQLComment returns a SQL comment passing the given span context; when it
+// prefixes SQL statements, the spans of the transactions they run continue the
+// trace of the span context. For example:
+//
+//   comment, err := tracing.SQLComment(tracer, sp.Context())
+//   ...
+//   db.Exec(comment + " INSERT INTO t VALUES (1)")
+func SQLComment(tr opentracing.Tracer, sc opentracing.SpanContext) (string, error) {
+	s, err := EncodeSpanContext(tr, sc)
+	if err != nil {
+		return "", err
+	}
+	return sqlCommentPrefix + " " + s + " */", nil
+}
+
+// SpanContextFromSQLComment extracts the span context passed in a comment
+// created by SQLComment at the start of the given SQL statements. The returned
+// bool is false if the statements don't start with such a comment.
+func SpanContextFromSQLComment(
+	tr opentracing.Tracer, stmts string,
+) (opentracing.SpanContext, bool, error) {
+	stmts = strings.TrimLeft(stmts, " \t\r\n")
+	if !strings.HasPrefix(stmts, sqlCommentPrefix) {
+		return nil, false, nil
+	}
+	end := strings.Index(stmts, "*/")
+	if end == -1 {
+		return nil, false, errors.New("unterminated trace comment")
+	}
+	sc, err := DecodeSpanContext(tr, strings.TrimSpace(stmts[len(sqlCommentPrefix):end]))
+	if err != nil {
+		return nil, false, err
+	}
+	return sc, true, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"strings"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+func TestEncodeSpanContext(t *testing.T) {
+	tr := NewTracer()
+	sp := tr.StartSpan("app", Recordable)
+	sp.SetBaggageItem("user", "a b,c=d")
+	defer sp.Finish()
+	// The recording is propagated with the span context, so that the child
+	// started from the decoded context below is a real span.
+	StartRecording(sp, SnowballRecording)
+
+	s, err := EncodeSpanContext(tr, sp.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.ContainsAny(s, "'\" \n") || strings.Contains(s, "*/") {
+		t.Fatalf("encoded span context can't be used in SQL: %q", s)
+	}
+
+	sc, err := DecodeSpanContext(tr, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := tr.StartSpan("db", opentracing.ChildOf(sc))
+	defer child.Finish()
+	c, ok := child.Context().(*spanContext)
+	if !ok {
+		t.Fatalf("expected the child to be a real span, got context %T", child.Context())
+	}
+	if orig := sp.Context().(*spanContext); c.TraceID != orig.TraceID {
+		t.Errorf("expected the child to continue trace %d, got %d", orig.TraceID, c.TraceID)
+	}
+	if v := child.BaggageItem("user"); v != "a b,c=d" {
+		t.Errorf("expected the baggage to be propagated, got %q", v)
+	}
+
+	// A noop span context is encoded as an empty string.
+	if s, err := EncodeSpanContext(tr, tr.StartSpan("noop").Context()); err != nil || s != "" {
+		t.Errorf("expected empty encoding for noop span, got %q (%v)", s, err)
+	}
+	if sc, err := DecodeSpanContext(tr, ""); err != nil || !isNoopContext(sc) {
+		t.Errorf("expected noop span context, got %v (%v)", sc, err)
+	}
+
+	if _, err := DecodeSpanContext(tr, "ot-tracer-traceid=zz"); err == nil {
+		t.Error("expected error decoding corrupted span context")
+	}
+}
+
+func isNoopContext(sc opentracing.SpanContext) bool {
+	_, ok := sc.(noopSpanContext)
+	return ok
+}
+
+func TestSpanContextFromSQLComment(t *testing.T) {
+	tr := NewTracer()
+	sp := tr.StartSpan("app", Recordable)
+	defer sp.Finish()
+
+	comment, err := SQLComment(tr, sp.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc, ok, err := SpanContextFromSQLComment(tr, "\n  "+comment+" SELECT 1")
+	if err != nil || !ok {
+		t.Fatalf("expected span context, got ok=%t err=%v", ok, err)
+	}
+	if sc.(*spanContext).SpanID != sp.Context().(*spanContext).SpanID {
+		t.Errorf("expected span context of %v, got %v", sp.Context(), sc)
+	}
+
+	for _, stmts := range []string{"SELECT 1", "/* other */ SELECT 1", "SELECT 1 /* trace: x */"} {
+		if _, ok, err := SpanContextFromSQLComment(tr, stmts); ok || err != nil {
+			t.Errorf("%q: expected no span context, got ok=%t err=%v", stmts, ok, err)
+		}
+	}
+	if _, _, err := SpanContextFromSQLComment(tr, "/* trace: ot-tracer-traceid=1"); err == nil {
+		t.Error("expected error for unterminated comment")
+	}
+}
@@ -20,24 +20,36 @@ package tracing
 
 import (
 	"fmt"
-	"math/rand"
+	"math"
+	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
 	"unsafe"
 
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 	"golang.org/x/net/trace"
 
 	"github.com/cockroachdb/cockroach/pkg/settings"
+	"github.com/cockroachdb/cockroach/pkg/util/caller"
+	"github.com/cockroachdb/cockroach/pkg/util/randutil"
 	lightstep "github.com/lightstep/lightstep-tracer-go"
 	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
 )
 
 // Snowball is set as Baggage on traces which are used for snowball tracing.
 const Snowball = "sb"
 
+// VerboseLogging is set as Baggage on traces for which verbose logging is
+// enabled; see SetVerbosity.
+const VerboseLogging = "vl"
+
 // maxLogsPerSpan limits the number of logs in a Span; use a comfortable limit.
 const maxLogsPerSpan = 1000
 
@@ -61,15 +73,93 @@ var enableNetTrace = settings.RegisterBoolSetting(
 	false,
 )
 
+// The granularities of the x/net/trace families spans are grouped in.
+const (
+	netTraceFamilyNone = iota
+	netTraceFamilyComponent
+	netTraceFamilyOperation
+)
+
+var netTraceFamilyGranularity = settings.RegisterEnumSetting(
+	"trace.debug.family_granularity",
+	"how the traces in the /debug/requests page are grouped: in a single family, by component or by operation",
+	"component",
+	map[int64]string{
+		netTraceFamilyNone:      "none",
+		netTraceFamilyComponent: "component",
+		netTraceFamilyOperation: "operation",
+	},
+)
+
+var enableCallerComponent = settings.RegisterBoolSetting(
+	"trace.caller_component.enabled",
+	"if set, the spans started by ChildSpan and ForkCtxSpan are tagged with the package of their caller as component",
+	true,
+)
+
 var lightstepToken = settings.RegisterStringSetting(
 	"trace.lightstep.token",
 	"if set, traces go to Lightstep using this token",
 	"",
 )
 
+var lightstepCollectorHost = settings.RegisterStringSetting(
+	"trace.lightstep.collector_host",
+	"if set, the host of the Lightstep collector traces are sent to (instead of Lightstep's)",
+	"",
+)
+
+var lightstepCollectorPort = settings.RegisterValidatedIntSetting(
+	"trace.lightstep.collector_port",
+	"if set, the port of the Lightstep collector traces are sent to",
+	0,
+	func(v int64) error {
+		if v < 0 || v > math.MaxUint16 {
+			return errors.Errorf("port %d out of range", v)
+		}
+		return nil
+	},
+)
+
+var lightstepPlaintext = settings.RegisterBoolSetting(
+	"trace.lightstep.plaintext",
+	"if set, the connection to the Lightstep collector doesn't use TLS",
+	false,
+)
+
+var lightstepMaxBufferedSpans = settings.RegisterValidatedIntSetting(
+	"trace.lightstep.max_buffered_spans",
+	"if set, the maximum number of spans buffered between two reports to Lightstep",
+	0,
+	func(v int64) error {
+		if v < 0 {
+			return errors.Errorf("cannot be set to a negative value: %d", v)
+		}
+		return nil
+	},
+)
+
+var lightstepReportingPeriod = settings.RegisterNonNegativeDurationSetting(
+	"trace.lightstep.reporting_period",
+	"if set, the interval between two reports to Lightstep",
+	0,
+)
+
+var enableOpHistograms = settings.RegisterBoolSetting(
+	"trace.histograms.enabled",
+	"if set, the duration of every finished span is recorded in a per-operation latency histogram",
+	false,
+)
+
 // We don't call OnChange inline above because it causes an "initialization
-// loop" compile error.
+// loop" compile error. The Lightstep tracer is recreated when any of its
+// settings change.
 var _ = lightstepToken.OnChange(updateLightstep)
+var _ = lightstepCollectorHost.OnChange(updateLightstep)
+var _ = lightstepCollectorPort.OnChange(updateLightstep)
+var _ = lightstepPlaintext.OnChange(updateLightstep)
+var _ = lightstepMaxBufferedSpans.OnChange(updateLightstep)
+var _ = lightstepReportingPeriod.OnChange(updateLightstep)
 
 // Atomic pointer of type *opentracing.Tracer which itself points to a lightstep
 // tracer. We don't use sync.Value because we can't set it to nil.
@@ -82,15 +172,32 @@ func updateLightstep() {
 		// Filed https://github.com/lightstep/lightstep-tracer-go/issues/82.
 		atomic.StorePointer(&lightstepPtr, nil)
 	} else {
-		lsTr := lightstep.NewTracer(lightstep.Options{
-			AccessToken:    token,
-			MaxLogsPerSpan: maxLogsPerSpan,
-			UseGRPC:        true,
-		})
+		lsTr := lightstep.NewTracer(lightstepOptions(token))
 		atomic.StorePointer(&lightstepPtr, unsafe.Pointer(&lsTr))
 	}
 }
 
+// lightstepOptions returns the options of a Lightstep tracer using the given
+// token, according to the trace.lightstep settings. Unset settings leave the
+// Lightstep defaults in place.
+func lightstepOptions(token string) lightstep.Options {
+	opts := lightstep.Options{
+		AccessToken:      token,
+		MaxLogsPerSpan:   maxLogsPerSpan,
+		UseGRPC:          true,
+		MaxBufferedSpans: int(lightstepMaxBufferedSpans.Get()),
+		ReportingPeriod:  lightstepReportingPeriod.Get(),
+	}
+	if host := lightstepCollectorHost.Get(); host != "" {
+		opts.Collector = lightstep.Endpoint{
+			Host:      host,
+			Port:      int(lightstepCollectorPort.Get()),
+			Plaintext: lightstepPlaintext.Get(),
+		}
+	}
+	return opts
+}
+
 func getLightstep() opentracing.Tracer {
 	if ptr := atomic.LoadPointer(&lightstepPtr); ptr != nil {
 		return *(*opentracing.Tracer)(ptr)
@@ -98,6 +205,53 @@ func getLightstep() opentracing.Tracer {
 	return nil
 }
 
+// defaultNetTraceFamily is the x/net/trace family of the spans which can't be
+// grouped more finely.
+const defaultNetTraceFamily = "tracing"
+
+// The subsystems which name the x/net/trace families of spans and event logs
+// (see log.AmbientContext.SetSubsystem).
+const (
+	SubsystemSQL    = "sql"
+	SubsystemKV     = "kv"
+	SubsystemRaft   = "raft"
+	SubsystemGossip = "gossip"
+)
+
+// netTraceFamily returns the x/net/trace family of a span with the given
+// operation name and tags, at the given granularity. When grouping by
+// component, the family is the top-level package of the component tag if set
+// (the spans of "sql/distsqlrun" belong to sql), and otherwise the first word
+// of the operation name ("sql txn" belongs to sql, "storage.Replica: ..." to
+// storage); gRPC methods are grouped together.
+func netTraceFamily(granularity int64, operationName string, tags opentracing.Tags) string {
+	switch granularity {
+	case netTraceFamilyComponent:
+		if c, ok := tags[string(ext.Component)].(string); ok && c != "" {
+			if i := strings.IndexByte(c, '/'); i > 0 {
+				return c[:i]
+			}
+			return c
+		}
+		if strings.HasPrefix(operationName, "/") {
+			return "grpc"
+		}
+		if i := strings.IndexFunc(operationName, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		}); i != 0 && operationName != "" {
+			if i > 0 {
+				return operationName[:i]
+			}
+			return operationName
+		}
+	case netTraceFamilyOperation:
+		if operationName != "" {
+			return operationName
+		}
+	}
+	return defaultNetTraceFamily
+}
+
 // Tracer is our own custom implementation of opentracing.Tracer. It supports:
 //
 //  - forwarding events to x/net/trace instances
@@ -109,17 +263,97 @@ func getLightstep() opentracing.Tracer {
 //  - lightstep traces. This is implemented by maintaining a "shadow" lightstep
 //    span inside each of our spans.
 //
+//  - per-operation duration histograms. When the trace.histograms.enabled
+//    setting is on and a SpanDurationRecorder was installed, the duration of
+//    every finished span is reported to the recorder.
+//
+//  - a registry of open spans and recently finished slow spans (see
+//    ActiveSpans and SlowSpans), when the trace.registry.enabled setting is on.
+//
+//  - on-demand recording of the spans whose operation name matches a pattern
+//    (see StartOnDemandTracing).
+//
 // Even when tracing is disabled, we still use this Tracer (with x/net/trace and
 // lightstep disabled) because of its recording capability (snowball
 // tracing needs to work in all cases).
-//
-// Tracer is currently stateless so we could have a single instance; however,
-// this won't be the case if the cluster settings move away from using global
-// state.
 type Tracer struct {
 	// Preallocated noopSpan, used to avoid creating spans when we are not using
 	// x/net/trace or lightstep and we are not recording.
 	noopSpan noopSpan
+
+	// durationRecorder holds a spanDurationRecorderBox; it is set through
+	// SetSpanDurationRecorder.
+	durationRecorder atomic.Value
+
+	// collector holds a *TestCollector which receives all finished spans; it
+	// is set through NewTestCollector.
+	collector atomic.Value
+
+	// exporter holds an *AsyncExporter which receives all finished spans; it
+	// is set through NewAsyncExporter.
+	exporter atomic.Value
+
+	// shadowExporter holds the *AsyncExporter which finishes the lightstep
+	// shadow spans; it is started by the first span that needs it.
+	shadowExporter     atomic.Value
+	shadowExporterOnce sync.Once
+
+	// idGen holds an idGeneratorBox with the source of trace and span IDs; it
+	// is set through SetIDGenerator.
+	idGen atomic.Value
+
+	registry spanRegistry
+
+	onDemand onDemandTracing
+
+	noopStats noopSpanStats
+}
+
+// SpanDurationRecorder is notified of the duration of every span finished by
+// a Tracer, keyed by operation name. Implementations must be thread-safe.
+type SpanDurationRecorder interface {
+	RecordSpanDuration(operation string, duration time.Duration)
+}
+
+// spanDurationRecorderBox allows storing a (possibly nil) SpanDurationRecorder
+// in an atomic.Value, which requires a consistent concrete type.
+type spanDurationRecorderBox struct {
+	SpanDurationRecorder
+}
+
+// SetSpanDurationRecorder installs the recorder that receives span durations
+// when the trace.histograms.enabled setting is on. Passing nil removes the
+// recorder.
+func (t *Tracer) SetSpanDurationRecorder(r SpanDurationRecorder) {
+	t.durationRecorder.Store(spanDurationRecorderBox{r})
+}
+
+// SetIDGenerator replaces the source of the trace and span IDs assigned to
+// new spans. By default each Tracer uses its own randomly-seeded generator.
+func (t *Tracer) SetIDGenerator(g IDGenerator) {
+	t.idGen.Store(idGeneratorBox{g})
+}
+
+func (t *Tracer) nextID() uint64 {
+	return t.idGen.Load().(idGeneratorBox).NextID()
+}
+
+// getCollector returns the TestCollector registered with the tracer, if any.
+func (t *Tracer) getCollector() *TestCollector {
+	c, _ := t.collector.Load().(*TestCollector)
+	return c
+}
+
+// getDurationRecorder returns the SpanDurationRecorder that finished spans
+// should report to, or nil if duration histograms are disabled.
+func (t *Tracer) getDurationRecorder() SpanDurationRecorder {
+	if !enableOpHistograms.Get() {
+		return nil
+	}
+	if box, ok := t.durationRecorder.Load().(spanDurationRecorderBox); ok {
+		return box.SpanDurationRecorder
+	}
+	return nil
 }
 
 var _ opentracing.Tracer = &Tracer{}
@@ -129,6 +363,7 @@ var _ opentracing.Tracer = &Tracer{}
 func NewTracer() opentracing.Tracer {
 	t := &Tracer{}
 	t.noopSpan.tracer = t
+	t.SetIDGenerator(NewSeededIDGenerator(randutil.NewPseudoSeed()))
 	return t
 }
 
@@ -182,84 +417,225 @@ var Recordable opentracing.StartSpanOption = recordableOption{}
 
 func (recordableOption) Apply(*opentracing.StartSpanOptions) {}
 
+type snowballOption struct{}
+
+// WithSnowball returns a StartSpanOption that creates a real span with
+// snowball recording enabled: the span records, and so do all the spans
+// derived from it, including the remote ones (see SnowballRecording). Unless
+// the parent is already part of a snowball recording, the span starts a new
+// recording; its events can be retrieved through GetRecording.
+//
+// Using this option is equivalent to passing Recordable and calling
+// StartRecording(sp, SnowballRecording) on the new span, except that the span
+// is never observable without the recording or the Snowball baggage item.
+func WithSnowball() opentracing.StartSpanOption {
+	return snowballOption{}
+}
+
+func (snowballOption) Apply(*opentracing.StartSpanOptions) {}
+
 // StartSpan is part of the opentracing.Tracer interface.
 func (t *Tracer) StartSpan(
 	operationName string, opts ...opentracing.StartSpanOption,
 ) opentracing.Span {
-	// Fast paths to avoid the allocation of StartSpanOptions below when tracing
-	// is disabled: if we have no options or a single SpanReference (the common
-	// case) with a noop context, return a noop span now.
-	if len(opts) == 1 {
+	// Fast paths to avoid the allocation of StartSpanOptions below: if we have
+	// no options or a single SpanReference (the common case), we can start the
+	// span directly.
+	switch len(opts) {
+	case 0:
+		return t.startSpanGeneric(
+			operationName, nil /* parentCtx */, opentracing.ChildOfRef,
+			false /* recordable */, false /* snowball */, time.Time{},
+			nil /* tags */, nil, /* links */
+		)
+	case 1:
 		if o, ok := opts[0].(opentracing.SpanReference); ok {
-			if _, noopCtx := o.ReferencedContext.(noopSpanContext); noopCtx {
-				return &t.noopSpan
+			if o.Type != opentracing.ChildOfRef && o.Type != opentracing.FollowsFromRef {
+				o.ReferencedContext = nil
 			}
+			return t.startSpanGeneric(
+				operationName, o.ReferencedContext, o.Type,
+				false /* recordable */, false /* snowball */, time.Time{},
+				nil /* tags */, nil, /* links */
+			)
 		}
 	}
 
-	netTrace := enableNetTrace.Get()
-	lsTr := getLightstep()
-
-	if len(opts) == 0 && !netTrace && lsTr == nil {
-		return &t.noopSpan
-	}
-
 	var sso opentracing.StartSpanOptions
-	var recordable bool
+	var recordable, snowball bool
 	for _, o := range opts {
 		o.Apply(&sso)
-		if _, ok := o.(recordableOption); ok {
+		switch o.(type) {
+		case recordableOption:
+			recordable = true
+		case snowballOption:
 			recordable = true
+			snowball = true
 		}
 	}
 
-	var hasParent bool
-	var parentType opentracing.SpanReferenceType
-	var parentCtx *spanContext
-	var recordingGroup *spanGroup
-	var recordingType RecordingType
-
-	for _, r := range sso.References {
-		if r.Type != opentracing.ChildOfRef && r.Type != opentracing.FollowsFromRef {
+	// The span inherits the trace of its first ChildOf reference or, if there is
+	// none, of its first FollowsFrom reference. The other references are
+	// recorded as links.
+	parent := -1
+	for i, r := range sso.References {
+		if !isSpanReference(r) {
 			continue
 		}
-		if r.ReferencedContext == nil {
-			continue
+		if parent == -1 ||
+			(r.Type == opentracing.ChildOfRef && sso.References[parent].Type != opentracing.ChildOfRef) {
+			parent = i
 		}
-		if _, noopCtx := r.ReferencedContext.(noopSpanContext); noopCtx {
-			continue
+	}
+	var parentCtx opentracing.SpanContext
+	parentType := opentracing.ChildOfRef
+	var links []opentracing.SpanReference
+	if parent != -1 {
+		parentCtx = sso.References[parent].ReferencedContext
+		parentType = sso.References[parent].Type
+		for i, r := range sso.References {
+			if i != parent && isSpanReference(r) {
+				links = append(links, r)
+			}
+		}
+	}
+	return t.startSpanGeneric(
+		operationName, parentCtx, parentType, recordable, snowball, sso.StartTime, sso.Tags, links,
+	)
+}
+
+// isSpanReference returns true if r is a ChildOf or FollowsFrom reference to a
+// span which isn't a noop span.
+func isSpanReference(r opentracing.SpanReference) bool {
+	if r.Type != opentracing.ChildOfRef && r.Type != opentracing.FollowsFromRef {
+		return false
+	}
+	if r.ReferencedContext == nil {
+		return false
+	}
+	_, noopCtx := r.ReferencedContext.(noopSpanContext)
+	return !noopCtx
+}
+
+// StartChildSpan creates a span which is a child of the span with the given
+// context. It is equivalent to
+//   StartSpan(operationName, opentracing.ChildOf(parentCtx))
+// but avoids the overhead of processing StartSpanOptions; it is meant for hot
+// paths.
+func (t *Tracer) StartChildSpan(
+	operationName string, parentCtx opentracing.SpanContext,
+) opentracing.Span {
+	return t.startSpanGeneric(
+		operationName, parentCtx, opentracing.ChildOfRef,
+		false /* recordable */, false /* snowball */, time.Time{},
+		nil /* tags */, nil, /* links */
+	)
+}
+
+// startSpanGeneric is the implementation of StartSpan and StartChildSpan.
+// parentCtx can be nil or a noop context, in which case the span has no parent.
+// A zero startTime means that the span starts now. links are the references to
+// spans other than the parent; they don't influence the trace of the span.
+func (t *Tracer) startSpanGeneric(
+	operationName string,
+	parentCtx opentracing.SpanContext,
+	parentType opentracing.SpanReferenceType,
+	recordable bool,
+	snowball bool,
+	startTime time.Time,
+	tags map[string]interface{},
+	links []opentracing.SpanReference,
+) opentracing.Span {
+	// Spans selected by on-demand tracing need to be real so that they can
+	// record, even if their parent is a noop span.
+	onDemand := t.onDemand.matches(operationName)
+
+	var hasParent bool
+	var parent *spanContext
+	if parentCtx != nil {
+		if _, noopCtx := parentCtx.(noopSpanContext); !noopCtx {
+			hasParent = true
+			parent = parentCtx.(*spanContext)
 		}
-		hasParent = true
-		parentType = r.Type
-		parentCtx = r.ReferencedContext.(*spanContext)
-		if parentCtx.recordingGroup != nil {
-			recordingGroup = parentCtx.recordingGroup
-			recordingType = parentCtx.recordingType
-		} else if parentCtx.Baggage[Snowball] != "" {
+	}
+
+	// Fast path to avoid looking up the settings below when tracing is disabled:
+	// the child of a noop span is a noop span.
+	if parentCtx != nil && !hasParent && !recordable && !onDemand {
+		t.noopStats.record(operationName, NoopParent)
+		return &t.noopSpan
+	}
+
+	netTrace := enableNetTrace.Get()
+	lsTr := getLightstep()
+	// If we are feeding duration histograms, a TestCollector or an
+	// AsyncExporter, every span needs to be real so that it can be timed and
+	// collected.
+	histograms := t.getDurationRecorder() != nil
+	collect := t.getCollector() != nil || t.getExporter() != nil
+	// Likewise, the span registry needs real spans to track.
+	register := enableSpanRegistry.Get()
+
+	var recordingGroup *spanGroup
+	var recordingType RecordingType
+	// The verbosity set through the VerboseLogging baggage item is inherited by
+	// the children, which need to be real spans to carry it.
+	var verbosity int32
+	if hasParent {
+		verbosity = parseVerbosity(parent.Baggage[VerboseLogging])
+		if parent.recordingGroup != nil {
+			recordingGroup = parent.recordingGroup
+			recordingType = parent.recordingType
+		} else if parent.Baggage[Snowball] != "" {
 			// Automatically enable recording if we have the Snowball baggage item.
 			recordingGroup = new(spanGroup)
 			recordingType = SnowballRecording
 		}
-		// TODO(radu): can we do something for multiple references?
-		break
 	}
-	if hasParent && parentCtx.lightstep == nil {
+	var shadowDisabled bool
+	if hasParent && parent.lightstep == nil {
 		// If a lightstep tracer was configured, don't use it if the parent span
 		// isn't using it.
+		shadowDisabled = lsTr != nil
 		lsTr = nil
 	}
 
 	// If tracing is disabled, the Recordable option wasn't passed, and we're not
-	// part of a recording or snowball trace, avoid overhead and return a noop
-	// span.
-	if !recordable && recordingGroup == nil && lsTr == nil && !netTrace {
+	// part of a recording, snowball or verbose trace, avoid overhead and return a
+	// noop span.
+	if !recordable && recordingGroup == nil && verbosity == 0 && lsTr == nil && !netTrace &&
+		!histograms && !collect && !register && !onDemand {
+		reason := NoOptions
+		if shadowDisabled {
+			reason = ShadowDisabled
+		} else if hasParent {
+			reason = NoRecording
+		}
+		t.noopStats.record(operationName, reason)
 		return &t.noopSpan
 	}
 
+	if snowball && (recordingGroup == nil || recordingType != SnowballRecording) {
+		recordingGroup = new(spanGroup)
+		recordingType = SnowballRecording
+	}
+
+	// A span selected by on-demand tracing starts a new recording, unless it is
+	// already part of one.
+	onDemandRoot := onDemand && recordingGroup == nil
+	if onDemandRoot {
+		recordingGroup = new(spanGroup)
+		recordingType = SingleNodeRecording
+	}
+
 	s := &span{
-		tracer:    t,
-		operation: operationName,
-		startTime: sso.StartTime,
+		tracer:       t,
+		operation:    operationName,
+		startTime:    startTime,
+		collect:      collect,
+		registered:   register,
+		onDemandRoot: onDemandRoot,
+		verbosity:    verbosity,
 	}
 	if s.startTime.IsZero() {
 		s.startTime = time.Now()
@@ -273,70 +649,109 @@ func (t *Tracer) StartSpan(
 		// Create the shadow lightstep span.
 		var lsOpts []opentracing.StartSpanOption
 		// Replicate the options, using the lightstep context in the reference.
-		if !sso.StartTime.IsZero() {
-			lsOpts = append(lsOpts, opentracing.StartTime(sso.StartTime))
+		if !startTime.IsZero() {
+			lsOpts = append(lsOpts, opentracing.StartTime(startTime))
 		}
-		if sso.Tags != nil {
-			lsOpts = append(lsOpts, opentracing.Tags(sso.Tags))
+		if tags != nil {
+			lsOpts = append(lsOpts, opentracing.Tags(tags))
 		}
 		if hasParent {
-			if parentCtx.lightstep == nil {
+			if parent.lightstep == nil {
 				panic("lightstep span derived from non-lightstep span")
 			}
 			lsOpts = append(lsOpts, opentracing.SpanReference{
 				Type:              parentType,
-				ReferencedContext: parentCtx.lightstep,
+				ReferencedContext: parent.lightstep,
 			})
 		}
+		// Links to spans which have a shadow lightstep span are passed on as
+		// additional references.
+		for _, l := range links {
+			if sc, ok := l.ReferencedContext.(*spanContext); ok && sc.lightstep != nil {
+				lsOpts = append(lsOpts, opentracing.SpanReference{
+					Type:              l.Type,
+					ReferencedContext: sc.lightstep,
+				})
+			}
+		}
 		s.lightstep = lsTr.StartSpan(operationName, lsOpts...)
 		s.TraceID, s.SpanID = getLightstepSpanIDs(lsTr, s.lightstep.Context())
-		if hasParent && s.TraceID != parentCtx.TraceID {
+		if hasParent && s.TraceID != parent.TraceID {
 			panic(fmt.Sprintf(
 				"TraceID doesn't match between parent (%d) and child (%d) spans",
-				parentCtx.TraceID, s.TraceID,
+				parent.TraceID, s.TraceID,
 			))
 		}
 	} else {
-		s.SpanID = uint64(rand.Int63())
+		s.SpanID = t.nextID()
 
 		if !hasParent {
 			// No parent Span; allocate new trace id.
-			s.TraceID = uint64(rand.Int63())
+			s.TraceID = t.nextID()
 		} else {
-			s.TraceID = parentCtx.TraceID
+			s.TraceID = parent.TraceID
 		}
 	}
 
-	// Start recording if necessary.
-	if recordingGroup != nil {
-		s.enableRecording(recordingGroup, recordingType)
+	for _, l := range links {
+		if sc, ok := l.ReferencedContext.(*spanContext); ok {
+			s.links = append(s.links, RecordedSpan_SpanLink{
+				TraceID:     sc.TraceID,
+				SpanID:      sc.SpanID,
+				FollowsFrom: l.Type == opentracing.FollowsFromRef,
+			})
+		}
+	}
+
+	if register {
+		t.registry.addActive(s)
 	}
 
 	if netTrace {
-		s.netTr = trace.New("tracing", operationName)
+		s.netTr = trace.New(
+			netTraceFamily(netTraceFamilyGranularity.Get(), operationName, tags), operationName,
+		)
 		s.netTr.SetMaxEvents(maxLogsPerSpan)
 	}
 
 	if hasParent {
-		s.parentSpanID = parentCtx.SpanID
-		// Copy baggage from parent.
-		if l := len(parentCtx.Baggage); l > 0 {
-			s.mu.Baggage = make(map[string]string, l)
-			for k, v := range parentCtx.Baggage {
-				s.mu.Baggage[k] = v
-			}
+		s.parentSpanID = parent.SpanID
+		// Share the baggage of the parent; it is only copied if the span sets an
+		// item (see setBaggageItemLocked), which most spans never do.
+		if len(parent.Baggage) > 0 {
+			s.mu.Baggage = parent.Baggage
+			s.mu.baggageShared = true
 		}
 	}
 
-	for k, v := range sso.Tags {
-		s.SetTag(k, v)
+	// Start recording if necessary. This needs to happen after the parent's
+	// baggage is copied, so that the Snowball item set for snowball recordings
+	// is not overwritten.
+	if recordingGroup != nil {
+		s.enableRecording(recordingGroup, recordingType)
 	}
 
-	if netTrace || lsTr != nil {
-		// Copy baggage items to tags so they show up in the Lightstep UI or x/net/trace.
-		for k, v := range s.mu.Baggage {
-			s.SetTag(k, v)
+	// Apply the tags, and copy the baggage items to tags so they show up in the
+	// Lightstep UI or x/net/trace, under a single lock acquisition.
+	baggageTags := (netTrace || lsTr != nil) && len(s.mu.Baggage) > 0
+	if len(tags) > 0 || baggageTags {
+		s.mu.Lock()
+		for k, v := range tags {
+			s.setTagInner(k, v, true /* locked */)
 		}
+		if baggageTags {
+			for k, v := range s.mu.Baggage {
+				s.setTagInner(k, v, true /* locked */)
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	if hasParent && parent.baggageTruncated {
+		s.LogFields(
+			otlog.String("event", BaggageTruncatedEvent),
+			otlog.String("reason", "the baggage received from the remote parent exceeded the limits"),
+		)
 	}
 
 	return s
@@ -410,10 +825,15 @@ func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.S
 			}
 		default:
 			if strings.HasPrefix(k, prefixBaggage) {
+				k = strings.TrimPrefix(k, prefixBaggage)
+				if checkBaggageLimits(sc.Baggage, k, v) != nil {
+					sc.baggageTruncated = true
+					return nil
+				}
 				if sc.Baggage == nil {
 					sc.Baggage = make(map[string]string)
 				}
-				sc.Baggage[strings.TrimPrefix(k, prefixBaggage)] = v
+				sc.Baggage[k] = v
 			}
 		}
 		return nil
@@ -456,8 +876,17 @@ func ForkCtxSpan(ctx context.Context, opName string) (context.Context, opentraci
 			// Optimization: avoid ContextWithSpan call if tracing is disabled.
 			return ctx, span
 		}
-		tr := span.Tracer()
-		newSpan := tr.StartSpan(opName, opentracing.FollowsFrom(span.Context()))
+		var newSpan opentracing.Span
+		tags := callerComponentTags(1 /* depth */)
+		if tr, ok := span.Tracer().(*Tracer); ok {
+			newSpan = tr.startSpanGeneric(
+				opName, span.Context(), opentracing.FollowsFromRef,
+				false /* recordable */, false /* snowball */, time.Time{},
+				tags, nil, /* links */
+			)
+		} else {
+			newSpan = span.Tracer().StartSpan(opName, opentracing.FollowsFrom(span.Context()), tags)
+		}
 		return opentracing.ContextWithSpan(ctx, newSpan), newSpan
 	}
 	return ctx, nil
@@ -477,10 +906,52 @@ func ChildSpan(ctx context.Context, opName string) (context.Context, opentracing
 		// Optimization: avoid ContextWithSpan call if tracing is disabled.
 		return ctx, span
 	}
-	newSpan := span.Tracer().StartSpan(opName, opentracing.ChildOf(span.Context()))
+	var newSpan opentracing.Span
+	tags := callerComponentTags(1 /* depth */)
+	if tr, ok := span.Tracer().(*Tracer); ok {
+		newSpan = tr.startSpanGeneric(
+			opName, span.Context(), opentracing.ChildOfRef,
+			false /* recordable */, false /* snowball */, time.Time{},
+			tags, nil, /* links */
+		)
+	} else {
+		newSpan = span.Tracer().StartSpan(opName, opentracing.ChildOf(span.Context()), tags)
+	}
 	return opentracing.ContextWithSpan(ctx, newSpan), newSpan
 }
 
+// ComponentFromCaller returns a StartSpanOption which sets the component tag
+// of the span to the package of the caller, e.g. "sql" or "storage/engine".
+// ChildSpan and ForkCtxSpan do this automatically (see the
+// trace.caller_component.enabled setting), so that recordings and exported
+// traces can be filtered by subsystem.
+func ComponentFromCaller() opentracing.StartSpanOption {
+	return opentracing.Tag{Key: string(ext.Component), Value: callerComponent(1 /* depth */)}
+}
+
+// callerComponent returns the package of the caller at the given depth,
+// relative to the repository.
+func callerComponent(depth int) string {
+	file, _, _ := caller.Lookup(depth + 1)
+	if dir := path.Dir(file); dir != "." && dir != "/" {
+		return dir
+	}
+	return ""
+}
+
+// callerComponentTags returns the tags setting the component of a span started
+// by the caller at the given depth, or nil if the component isn't derived from
+// the caller.
+func callerComponentTags(depth int) opentracing.Tags {
+	if !enableCallerComponent.Get() {
+		return nil
+	}
+	if c := callerComponent(depth + 1); c != "" {
+		return opentracing.Tags{string(ext.Component): c}
+	}
+	return nil
+}
+
 // EnsureContext checks whether the given context.Context contains a Span. If
 // not, it creates one using the provided Tracer and wraps it in the returned
 // Span. The returned closure must be called after the request has been fully
@@ -507,11 +978,10 @@ func StartSnowballTrace(
 	var span opentracing.Span
 	if parentSpan := opentracing.SpanFromContext(ctx); parentSpan != nil {
 		span = parentSpan.Tracer().StartSpan(
-			opName, opentracing.ChildOf(parentSpan.Context()), Recordable,
+			opName, opentracing.ChildOf(parentSpan.Context()), WithSnowball(),
 		)
 	} else {
-		span = tracer.StartSpan(opName, Recordable)
+		span = tracer.StartSpan(opName, WithSnowball())
 	}
-	StartRecording(span, SnowballRecording)
 	return opentracing.ContextWithSpan(ctx, span), span, nil
 }
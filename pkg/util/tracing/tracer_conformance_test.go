@@ -0,0 +1,337 @@
+// Copyright 2017 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+)
+
+// The tests in this file check that the Tracer honors the contract of the
+// opentracing API, independently of the features we build on top of it.
+
+// conformanceCarriers are the format and carrier combinations the Tracer
+// supports for Inject and Extract.
+var conformanceCarriers = []struct {
+	name       string
+	format     interface{}
+	newCarrier func() interface{}
+}{
+	{"HTTPHeaders", opentracing.HTTPHeaders, func() interface{} {
+		return opentracing.HTTPHeadersCarrier(make(map[string][]string))
+	}},
+	{"TextMap", opentracing.TextMap, func() interface{} {
+		return opentracing.TextMapCarrier(make(map[string]string))
+	}},
+}
+
+func TestConformanceInjectExtract(t *testing.T) {
+	tr := NewTracer()
+	tr2 := NewTracer()
+
+	for _, c := range conformanceCarriers {
+		t.Run(c.name, func(t *testing.T) {
+			sp := tr.StartSpan("local", Recordable)
+			defer sp.Finish()
+			sp.SetBaggageItem("user", "alice")
+
+			carrier := c.newCarrier()
+			if err := tr.Inject(sp.Context(), c.format, carrier); err != nil {
+				t.Fatal(err)
+			}
+			wireCtx, err := tr2.Extract(c.format, carrier)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The extracted context carries the identity and baggage of the
+			// injected one.
+			sc := sp.Context().(*spanContext)
+			wsc, ok := wireCtx.(*spanContext)
+			if !ok {
+				t.Fatalf("expected a span context, got %T", wireCtx)
+			}
+			if wsc.TraceID != sc.TraceID || wsc.SpanID != sc.SpanID {
+				t.Errorf("expected trace %d span %d, got trace %d span %d",
+					sc.TraceID, sc.SpanID, wsc.TraceID, wsc.SpanID)
+			}
+			baggage := make(map[string]string)
+			wireCtx.ForeachBaggageItem(func(k, v string) bool {
+				baggage[k] = v
+				return true
+			})
+			if baggage["user"] != "alice" {
+				t.Errorf("expected the user baggage item to be propagated, got %v", baggage)
+			}
+
+			// A span started from the extracted context belongs to the same trace
+			// and is a child of the injected span.
+			remote := tr2.StartSpan("remote", opentracing.ChildOf(wireCtx), Recordable)
+			defer remote.Finish()
+			if rsc := remote.Context().(*spanContext); rsc.TraceID != sc.TraceID {
+				t.Errorf("expected trace %d, got %d", sc.TraceID, rsc.TraceID)
+			}
+			if p := remote.(*span).parentSpanID; p != sc.SpanID {
+				t.Errorf("expected parent span %d, got %d", sc.SpanID, p)
+			}
+			if v := remote.BaggageItem("user"); v != "alice" {
+				t.Errorf("expected the remote span to inherit the baggage, got %q", v)
+			}
+		})
+	}
+}
+
+func TestConformanceInjectExtractErrors(t *testing.T) {
+	tr := NewTracer()
+	sp := tr.StartSpan("a", Recordable)
+	defer sp.Finish()
+
+	// Extract always returns a usable context, even when it fails.
+	checkExtract := func(t *testing.T, format, carrier interface{}, expErr error) {
+		wireCtx, err := tr.Extract(format, carrier)
+		if err != expErr {
+			t.Errorf("expected error %v, got %v", expErr, err)
+		}
+		if wireCtx == nil {
+			t.Fatal("expected a non-nil context")
+		}
+		child := tr.StartSpan("child", opentracing.ChildOf(wireCtx))
+		child.Finish()
+	}
+
+	t.Run("unsupported format", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := tr.Inject(sp.Context(), opentracing.Binary, &buf); err != opentracing.ErrUnsupportedFormat {
+			t.Errorf("expected %v, got %v", opentracing.ErrUnsupportedFormat, err)
+		}
+		checkExtract(t, opentracing.Binary, &buf, opentracing.ErrUnsupportedFormat)
+		checkExtract(t, "unknown", opentracing.TextMapCarrier{}, opentracing.ErrUnsupportedFormat)
+	})
+
+	t.Run("invalid carrier", func(t *testing.T) {
+		for _, c := range conformanceCarriers {
+			if err := tr.Inject(sp.Context(), c.format, 42); err != opentracing.ErrInvalidCarrier {
+				t.Errorf("%s: expected %v, got %v", c.name, opentracing.ErrInvalidCarrier, err)
+			}
+			checkExtract(t, c.format, 42, opentracing.ErrInvalidCarrier)
+		}
+	})
+
+	t.Run("invalid span context", func(t *testing.T) {
+		type foreignSpanContext struct{ noopSpanContext }
+		err := tr.Inject(foreignSpanContext{}, opentracing.TextMap, opentracing.TextMapCarrier{})
+		if err != opentracing.ErrInvalidSpanContext {
+			t.Errorf("expected %v, got %v", opentracing.ErrInvalidSpanContext, err)
+		}
+	})
+
+	t.Run("corrupted span context", func(t *testing.T) {
+		for _, key := range []string{fieldNameTraceID, fieldNameSpanID} {
+			carrier := opentracing.TextMapCarrier{}
+			if err := tr.Inject(sp.Context(), opentracing.TextMap, carrier); err != nil {
+				t.Fatal(err)
+			}
+			carrier[key] = "not hex"
+			checkExtract(t, opentracing.TextMap, carrier, opentracing.ErrSpanContextCorrupted)
+		}
+	})
+
+	t.Run("empty carrier", func(t *testing.T) {
+		for _, c := range conformanceCarriers {
+			checkExtract(t, c.format, c.newCarrier(), nil)
+		}
+	})
+}
+
+func TestConformanceBaggage(t *testing.T) {
+	tr := NewTracer()
+	parent := tr.StartSpan("parent", Recordable)
+	defer parent.Finish()
+
+	// SetBaggageItem returns the span, for chaining.
+	if sp := parent.SetBaggageItem("k1", "v1"); sp != parent {
+		t.Errorf("expected SetBaggageItem to return the span")
+	}
+	if v := parent.BaggageItem("k1"); v != "v1" {
+		t.Errorf("expected v1, got %q", v)
+	}
+	if v := parent.BaggageItem("missing"); v != "" {
+		t.Errorf("expected no value for a missing item, got %q", v)
+	}
+
+	// Children inherit the baggage present when they are started, through
+	// both reference types, and their own items don't flow back up. The spans
+	// are recordable so that they aren't noop spans.
+	for _, ref := range []opentracing.SpanReferenceType{
+		opentracing.ChildOfRef, opentracing.FollowsFromRef,
+	} {
+		child := tr.StartSpan("child", opentracing.SpanReference{
+			Type: ref, ReferencedContext: parent.Context(),
+		}, Recordable)
+		if v := child.BaggageItem("k1"); v != "v1" {
+			t.Errorf("%v: expected the child to inherit k1, got %q", ref, v)
+		}
+		child.SetBaggageItem("k2", "v2")
+		grandchild := tr.StartSpan("grandchild", opentracing.ChildOf(child.Context()), Recordable)
+		if v1, v2 := grandchild.BaggageItem("k1"), grandchild.BaggageItem("k2"); v1 != "v1" || v2 != "v2" {
+			t.Errorf("%v: expected the grandchild to inherit k1 and k2, got %q and %q", ref, v1, v2)
+		}
+		grandchild.Finish()
+		child.Finish()
+	}
+	if v := parent.BaggageItem("k2"); v != "" {
+		t.Errorf("expected the child's baggage not to reach the parent, got %q", v)
+	}
+
+	// Items set after a child is started don't reach it.
+	child := tr.StartSpan("child", opentracing.ChildOf(parent.Context()), Recordable)
+	defer child.Finish()
+	parent.SetBaggageItem("k3", "v3")
+	if v := child.BaggageItem("k3"); v != "" {
+		t.Errorf("expected a later baggage item not to reach the child, got %q", v)
+	}
+
+	// The span context exposes the baggage, and iteration stops when the
+	// handler returns false.
+	seen := make(map[string]string)
+	parent.Context().ForeachBaggageItem(func(k, v string) bool {
+		seen[k] = v
+		return true
+	})
+	if seen["k1"] != "v1" || seen["k3"] != "v3" {
+		t.Errorf("expected k1 and k3 in the span context, got %v", seen)
+	}
+	n := 0
+	parent.Context().ForeachBaggageItem(func(k, v string) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("expected the iteration to stop after one item, got %d", n)
+	}
+}
+
+func TestConformanceReferences(t *testing.T) {
+	tr := NewTracer()
+	root := tr.StartSpan("root", Recordable)
+	defer root.Finish()
+	rootCtx := root.Context().(*spanContext)
+
+	testCases := []struct {
+		name      string
+		opts      []opentracing.StartSpanOption
+		hasParent bool
+	}{
+		{"no references", nil, false},
+		{"ChildOf", []opentracing.StartSpanOption{opentracing.ChildOf(root.Context())}, true},
+		{"FollowsFrom", []opentracing.StartSpanOption{opentracing.FollowsFrom(root.Context())}, true},
+		// A nil context is allowed by the API and ignored.
+		{"nil ChildOf", []opentracing.StartSpanOption{opentracing.ChildOf(nil)}, false},
+		{"noop ChildOf", []opentracing.StartSpanOption{opentracing.ChildOf(noopSpanContext{})}, false},
+		{"ChildOf with options", []opentracing.StartSpanOption{
+			opentracing.ChildOf(root.Context()),
+			opentracing.Tag{Key: "k", Value: "v"},
+			opentracing.StartTime(time.Unix(1, 0)),
+		}, true},
+		{"unknown reference type", []opentracing.StartSpanOption{opentracing.SpanReference{
+			Type: opentracing.SpanReferenceType(42), ReferencedContext: root.Context(),
+		}}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sp := tr.StartSpan("sp", append(tc.opts, Recordable)...)
+			defer sp.Finish()
+			sc := sp.Context().(*spanContext)
+			s := sp.(*span)
+			if tc.hasParent {
+				if sc.TraceID != rootCtx.TraceID || s.parentSpanID != rootCtx.SpanID {
+					t.Errorf("expected a child of trace %d span %d, got trace %d parent %d",
+						rootCtx.TraceID, rootCtx.SpanID, sc.TraceID, s.parentSpanID)
+				}
+			} else {
+				if sc.TraceID == rootCtx.TraceID || s.parentSpanID != 0 {
+					t.Errorf("expected a new trace, got trace %d parent %d", sc.TraceID, s.parentSpanID)
+				}
+			}
+			if sc.SpanID == 0 || sc.SpanID == rootCtx.SpanID {
+				t.Errorf("expected a new span ID, got %d", sc.SpanID)
+			}
+		})
+	}
+}
+
+func TestConformanceNoop(t *testing.T) {
+	tr := NewTracer()
+	sp := tr.StartSpan("noop")
+	if !IsNoopSpan(sp) {
+		t.Fatalf("expected a noop span, got %T", sp)
+	}
+
+	// All the span methods can be called on a noop span and return it where
+	// the API returns a span.
+	for i, sp2 := range []opentracing.Span{
+		sp.SetTag("k", "v"),
+		sp.SetOperationName("op"),
+		sp.SetBaggageItem("k", "v"),
+	} {
+		if sp2 != sp {
+			t.Errorf("%d: expected the noop span to be returned, got %v", i, sp2)
+		}
+	}
+	sp.LogFields(otlog.String("k", "v"))
+	sp.LogKV("k", "v")
+	sp.LogEvent("event")
+	sp.LogEventWithPayload("event", 1)
+	sp.Log(opentracing.LogData{Event: "event"})
+	if tr2 := sp.Tracer(); tr2 != tr {
+		t.Errorf("expected the span's tracer to be %v, got %v", tr, tr2)
+	}
+	sp.Context().ForeachBaggageItem(func(k, v string) bool {
+		t.Errorf("expected no baggage, got %s=%s", k, v)
+		return true
+	})
+
+	// The children of a noop span are noop spans, whatever the reference type.
+	for _, opt := range []opentracing.StartSpanOption{
+		opentracing.ChildOf(sp.Context()), opentracing.FollowsFrom(sp.Context()),
+	} {
+		if child := tr.StartSpan("child", opt); !IsNoopSpan(child) {
+			t.Errorf("expected a noop child, got %T", child)
+		}
+	}
+
+	// Noop contexts round-trip through all carriers as noop contexts.
+	for _, c := range conformanceCarriers {
+		carrier := c.newCarrier()
+		if err := tr.Inject(sp.Context(), c.format, carrier); err != nil {
+			t.Fatal(err)
+		}
+		wireCtx, err := tr.Extract(c.format, carrier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := wireCtx.(noopSpanContext); !ok {
+			t.Errorf("%s: expected a noop context, got %T", c.name, wireCtx)
+		}
+	}
+
+	// Finishing is idempotent for noop spans.
+	sp.Finish()
+	sp.FinishWithOptions(opentracing.FinishOptions{})
+}
@@ -51,6 +51,10 @@ type spanContext struct {
 
 	// The span's associated baggage.
 	Baggage map[string]string
+
+	// baggageTruncated is set if some baggage items were dropped by Extract
+	// because of the baggage limits.
+	baggageTruncated bool
 }
 
 var _ opentracing.SpanContext = &spanContext{}
@@ -65,20 +69,45 @@ func (sc *spanContext) ForeachBaggageItem(handler func(k, v string) bool) {
 }
 
 // RecordingType is the type of recording that a span might be performing.
-type RecordingType bool
+type RecordingType int
 
 const (
+	// NotRecording is the type reported for spans that are not recording. It
+	// can't be passed to StartRecording.
+	NotRecording RecordingType = iota
+	// SingleNodeRecording means that only spans on the current node are
+	// recorded. The span's descendants started on the same node join the
+	// recording; spans started on other nodes through RPCs do not, because the
+	// recording doesn't travel with the span's context.
+	SingleNodeRecording
 	// SnowballRecording means that remote child spans (generally opened through
-	// RPCs) are also recorded.
-	SnowballRecording RecordingType = true
-	// SingleNodeRecording means that only spans on the current node are recorded.
-	SingleNodeRecording RecordingType = false
+	// RPCs) are also recorded. The span carries the Snowball baggage item, which
+	// makes remote children start their own recording; their spans are then
+	// returned to the caller with the RPC response and imported through
+	// ImportRemoteSpans.
+	SnowballRecording
 )
 
+func (t RecordingType) String() string {
+	switch t {
+	case NotRecording:
+		return "none"
+	case SingleNodeRecording:
+		return "single-node"
+	case SnowballRecording:
+		return "snowball"
+	default:
+		return fmt.Sprintf("RecordingType(%d)", int(t))
+	}
+}
+
 type span struct {
 	spanMeta
 
 	parentSpanID uint64
+	// links are the references to spans other than the parent which the span
+	// was started with.
+	links []RecordedSpan_SpanLink
 
 	tracer *Tracer
 
@@ -93,6 +122,23 @@ type span struct {
 	// Atomic flag used to avoid taking the mutex in the hot path.
 	recording int32
 
+	// verbosity mirrors the VerboseLogging baggage item; it is accessed
+	// atomically so that logging calls don't need to take the mutex.
+	verbosity int32
+
+	// collect is set if the span will be handed to the tracer's TestCollector
+	// or AsyncExporter when it finishes; tags and logs are retained as if
+	// recording.
+	collect bool
+
+	// registered is set if the span is tracked by the tracer's span registry;
+	// tags are retained so they can be displayed.
+	registered bool
+
+	// onDemandRoot is set if the span started a recording because of on-demand
+	// tracing; the recording is handed to the tracer when the span finishes.
+	onDemandRoot bool
+
 	mu struct {
 		syncutil.Mutex
 		// duration is initialized to -1 and set on Finish().
@@ -108,6 +154,10 @@ type span struct {
 
 		// The span's associated baggage.
 		Baggage map[string]string
+		// baggageShared is set when Baggage is shared with the span's parent,
+		// its children or the contexts returned by Context(), which all treat
+		// the map as immutable; it is then copied before being modified.
+		baggageShared bool
 	}
 }
 
@@ -117,6 +167,12 @@ func (s *span) isRecording() bool {
 	return atomic.LoadInt32(&s.recording) != 0
 }
 
+// retainsEvents returns true if tags and logs need to be stored in the span,
+// either because it is recording or because it will be collected.
+func (s *span) retainsEvents() bool {
+	return s.collect || s.isRecording()
+}
+
 func (s *span) enableRecording(group *spanGroup, recType RecordingType) {
 	if group == nil {
 		panic("no spanGroup")
@@ -125,8 +181,13 @@ func (s *span) enableRecording(group *spanGroup, recType RecordingType) {
 	atomic.StoreInt32(&s.recording, 1)
 	s.mu.recordingGroup = group
 	s.mu.recordingType = recType
+	// The Snowball baggage item is set only for snowball recordings, so that
+	// remote children don't start recording when the caller only asked for the
+	// local spans.
 	if recType == SnowballRecording {
 		s.setBaggageItemLocked(Snowball, "1")
+	} else if s.mu.Baggage[Snowball] != "" {
+		s.setBaggageItemLocked(Snowball, "")
 	}
 	// Clear any previously recorded logs.
 	s.mu.recordedLogs = nil
@@ -159,9 +220,29 @@ func StartRecording(os opentracing.Span, recType RecordingType) {
 	if IsNoopSpan(os) {
 		panic("StartRecording called on NoopSpan; use the Force option for StartSpan")
 	}
+	if recType != SingleNodeRecording && recType != SnowballRecording {
+		panic(fmt.Sprintf("invalid recording type %s", recType))
+	}
 	os.(*span).enableRecording(new(spanGroup), recType)
 }
 
+// GetRecordingType returns the type of recording that the span is performing,
+// or NotRecording if it isn't part of a recording (including if it's a noop
+// span). A span that joined the recording of its parent reports the parent's
+// recording type.
+func GetRecordingType(os opentracing.Span) RecordingType {
+	s, ok := os.(*span)
+	if !ok || !s.isRecording() {
+		return NotRecording
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mu.recordingGroup == nil {
+		return NotRecording
+	}
+	return s.mu.recordingType
+}
+
 // StopRecording disables recording on this span. Child spans that were created
 // since recording was started will continue to record until they finish.
 //
@@ -176,10 +257,10 @@ func (s *span) disableRecording() {
 	atomic.StoreInt32(&s.recording, 0)
 	s.mu.recordingGroup = nil
 	if s.mu.recordingType == SnowballRecording {
-		// Clear the Snowball baggage item, assuming that it was set by
-		// enableRecording().
+		// Clear the Snowball baggage item, which was set by enableRecording().
 		s.setBaggageItemLocked(Snowball, "")
 	}
+	s.mu.recordingType = NotRecording
 	s.mu.Unlock()
 }
 
@@ -193,6 +274,16 @@ func IsRecordable(os opentracing.Span) bool {
 	return isCockroachSpan
 }
 
+// GetTraceAndSpanID returns the trace and span IDs of the given span. It
+// returns false if the span isn't our custom type (e.g. it is a noopSpan).
+func GetTraceAndSpanID(os opentracing.Span) (traceID uint64, spanID uint64, ok bool) {
+	s, ok := os.(*span)
+	if !ok {
+		return 0, 0, false
+	}
+	return s.TraceID, s.SpanID, true
+}
+
 // GetRecording retrieves the current recording, if the span has
 // recording enabled. This can be called while spans that are part of the
 // record are still open; it can run concurrently with operations on those
@@ -214,6 +305,36 @@ func GetRecording(os opentracing.Span) []RecordedSpan {
 	return group.getSpans()
 }
 
+// UnfinishedTag is set on the spans returned by GetRecordingSnapshot which
+// haven't finished yet.
+const UnfinishedTag = "unfinished"
+
+// GetRecordingSnapshot is like GetRecording, but is meant for reporting the
+// progress of long-running operations, which can call it periodically while
+// the recording continues. The spans that haven't finished yet have their
+// duration set to the time elapsed since they started (instead of 0) and are
+// tagged with UnfinishedTag.
+func GetRecordingSnapshot(os opentracing.Span) []RecordedSpan {
+	rec := GetRecording(os)
+	now := time.Now()
+	for i := range rec {
+		sp := &rec[i]
+		if sp.Duration != 0 {
+			continue
+		}
+		sp.Duration = now.Sub(sp.StartTime)
+		// The tags of remote spans are shared with the recording; don't modify
+		// them in place.
+		tags := make(map[string]string, len(sp.Tags)+1)
+		for k, v := range sp.Tags {
+			tags[k] = v
+		}
+		tags[UnfinishedTag] = "true"
+		sp.Tags = tags
+	}
+	return rec
+}
+
 // ImportRemoteSpans adds RecordedSpan data to the recording of the given span;
 // these spans will be part of the result of GetRecording. Used to import
 // recorded traces from other nodes.
@@ -249,11 +370,45 @@ func (s *span) FinishWithOptions(opts opentracing.FinishOptions) {
 	if finishTime.IsZero() {
 		finishTime = time.Now()
 	}
+	duration := finishTime.Sub(s.startTime)
 	s.mu.Lock()
-	s.mu.duration = finishTime.Sub(s.startTime)
+	s.mu.duration = duration
+	var rs RecordedSpan
+	if s.collect {
+		rs = s.getRecordingLocked()
+	}
+	var slowInfo *RecordedSpan
+	if s.registered && duration >= SlowSpanThresholds[0] {
+		info := s.getInfoLocked()
+		slowInfo = &info
+	}
 	s.mu.Unlock()
+	if s.registered {
+		s.tracer.registry.removeActive(s, slowInfo)
+	}
+	if s.onDemandRoot {
+		if rec := GetRecording(s); rec != nil {
+			s.tracer.onDemand.addRecording(rec)
+		}
+	}
+	if s.collect {
+		if c := s.tracer.getCollector(); c != nil {
+			c.addSpan(rs)
+		}
+		if e := s.tracer.getExporter(); e != nil {
+			e.enqueue(exportItem{rec: rs})
+		}
+	}
+	if r := s.tracer.getDurationRecorder(); r != nil {
+		r.RecordSpanDuration(s.operation, duration)
+	}
 	if s.lightstep != nil {
-		s.lightstep.Finish()
+		// Finishing the shadow span hands it to the lightstep client, which can
+		// block; do it asynchronously, with the original finish time.
+		s.tracer.getShadowExporter().enqueue(exportItem{
+			shadow:     s.lightstep,
+			finishTime: finishTime,
+		})
 	}
 	if s.netTr != nil {
 		s.netTr.Finish()
@@ -264,13 +419,14 @@ func (s *span) FinishWithOptions(opts opentracing.FinishOptions) {
 func (s *span) Context() opentracing.SpanContext {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	baggageCopy := make(map[string]string, len(s.mu.Baggage))
-	for k, v := range s.mu.Baggage {
-		baggageCopy[k] = v
+	// The baggage is shared with the context instead of being copied; the span
+	// copies it if it sets an item later.
+	if len(s.mu.Baggage) > 0 {
+		s.mu.baggageShared = true
 	}
 	sc := &spanContext{
 		spanMeta: s.spanMeta,
-		Baggage:  baggageCopy,
+		Baggage:  s.mu.Baggage,
 	}
 	if s.lightstep != nil {
 		sc.lightstep = s.lightstep.Context()
@@ -304,7 +460,7 @@ func (s *span) setTagInner(key string, value interface{}, locked bool) opentraci
 	if s.netTr != nil {
 		s.netTr.LazyPrintf("%s:%v", key, value)
 	}
-	if s.isRecording() {
+	if s.retainsEvents() || s.registered {
 		if !locked {
 			s.mu.Lock()
 		}
@@ -342,7 +498,7 @@ func (s *span) LogFields(fields ...otlog.Field) {
 			s.netTr.LazyPrintf("%s", buf.String())
 		}
 	}
-	if s.isRecording() {
+	if s.retainsEvents() {
 		s.mu.Lock()
 		if len(s.mu.recordedLogs) < maxLogsPerSpan {
 			s.mu.recordedLogs = append(s.mu.recordedLogs, opentracing.LogRecord{
@@ -364,18 +520,40 @@ func (s *span) LogKV(alternatingKeyValues ...interface{}) {
 	s.LogFields(fields...)
 }
 
-// SetBaggageItem is part of the opentracing.Span interface.
+// SetBaggageItem is part of the opentracing.Span interface. Items exceeding
+// the baggage limits are dropped, and a BaggageTruncatedEvent is logged.
+//
+// The Snowball item can't be set directly: it is managed by StartRecording and
+// StopRecording according to the recording type, and attempts to set it are
+// ignored.
 func (s *span) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	if restrictedKey == Snowball {
+		s.LogFields(otlog.String("event", "ignoring Snowball baggage item; use StartRecording"))
+		return s
+	}
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	return s.setBaggageItemLocked(restrictedKey, value)
+	err := checkBaggageLimits(s.mu.Baggage, restrictedKey, value)
+	if err == nil {
+		s.setBaggageItemLocked(restrictedKey, value)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		s.LogFields(otlog.String("event", BaggageTruncatedEvent), otlog.Error(err))
+	}
+	return s
 }
 
 func (s *span) setBaggageItemLocked(restrictedKey, value string) opentracing.Span {
 	if s.mu.Baggage == nil {
 		s.mu.Baggage = make(map[string]string)
+	} else if s.mu.baggageShared {
+		s.mu.Baggage = copyBaggage(s.mu.Baggage)
+		s.mu.baggageShared = false
 	}
 	s.mu.Baggage[restrictedKey] = value
+	if restrictedKey == VerboseLogging {
+		atomic.StoreInt32(&s.verbosity, parseVerbosity(value))
+	}
 
 	if s.lightstep != nil {
 		s.lightstep.SetBaggageItem(restrictedKey, value)
@@ -444,53 +622,66 @@ func (ss *spanGroup) getSpans() []RecordedSpan {
 	result := make([]RecordedSpan, 0, len(spans)+len(remoteSpans))
 	for _, s := range spans {
 		s.mu.Lock()
-		rs := RecordedSpan{
-			TraceID:      s.TraceID,
-			SpanID:       s.SpanID,
-			ParentSpanID: s.parentSpanID,
-			Operation:    s.operation,
-			StartTime:    s.startTime,
-			Duration:     s.mu.duration,
-		}
-		switch rs.Duration {
-		case -1:
-			// -1 indicates an unfinished span.
-			// TODO(radu): depending how recording of in-progress spans is used, we
-			// may want to set this to (Now - StartTime).
-			rs.Duration = 0
-		case 0:
-			// 0 is a special value for unfinished spans. Change to 1ns.
-			rs.Duration = time.Nanosecond
-		}
+		result = append(result, s.getRecordingLocked())
+		s.mu.Unlock()
+	}
+	return append(result, remoteSpans...)
+}
 
-		if len(s.mu.Baggage) > 0 {
-			rs.Baggage = make(map[string]string)
-			for k, v := range s.mu.Baggage {
-				rs.Baggage[k] = v
-			}
+// getRecordingLocked returns the RecordedSpan representation of the span's
+// current state. The span's lock must be held.
+func (s *span) getRecordingLocked() RecordedSpan {
+	rs := s.getInfoLocked()
+	rs.Logs = make([]RecordedSpan_LogRecord, len(s.mu.recordedLogs))
+	for i, r := range s.mu.recordedLogs {
+		rs.Logs[i].Time = r.Timestamp
+		rs.Logs[i].Fields = make([]RecordedSpan_LogRecord_Field, len(r.Fields))
+		for j, f := range r.Fields {
+			rs.Logs[i].Fields[j] = makeRecordedField(f)
 		}
-		if len(s.mu.tags) > 0 {
-			rs.Tags = make(map[string]string)
-			for k, v := range s.mu.tags {
-				// We encode the tag values as strings.
-				rs.Tags[k] = fmt.Sprint(v)
-			}
+	}
+	return rs
+}
+
+// getInfoLocked returns the RecordedSpan representation of the span's current
+// state, without the logs. The span's lock must be held.
+func (s *span) getInfoLocked() RecordedSpan {
+	rs := RecordedSpan{
+		TraceID:      s.TraceID,
+		SpanID:       s.SpanID,
+		ParentSpanID: s.parentSpanID,
+		Operation:    s.operation,
+		StartTime:    s.startTime,
+		Duration:     s.mu.duration,
+	}
+	if len(s.links) > 0 {
+		rs.Links = append([]RecordedSpan_SpanLink(nil), s.links...)
+	}
+	switch rs.Duration {
+	case -1:
+		// -1 indicates an unfinished span.
+		// TODO(radu): depending how recording of in-progress spans is used, we
+		// may want to set this to (Now - StartTime).
+		rs.Duration = 0
+	case 0:
+		// 0 is a special value for unfinished spans. Change to 1ns.
+		rs.Duration = time.Nanosecond
+	}
+
+	if len(s.mu.Baggage) > 0 {
+		rs.Baggage = make(map[string]string)
+		for k, v := range s.mu.Baggage {
+			rs.Baggage[k] = v
 		}
-		rs.Logs = make([]RecordedSpan_LogRecord, len(s.mu.recordedLogs))
-		for i, r := range s.mu.recordedLogs {
-			rs.Logs[i].Time = r.Timestamp
-			rs.Logs[i].Fields = make([]RecordedSpan_LogRecord_Field, len(r.Fields))
-			for j, f := range r.Fields {
-				rs.Logs[i].Fields[j] = RecordedSpan_LogRecord_Field{
-					Key:   f.Key(),
-					Value: fmt.Sprint(f.Value()),
-				}
-			}
+	}
+	if len(s.mu.tags) > 0 {
+		rs.Tags = make(map[string]string)
+		for k, v := range s.mu.tags {
+			// We encode the tag values as strings.
+			rs.Tags[k] = fmt.Sprint(v)
 		}
-		s.mu.Unlock()
-		result = append(result, rs)
 	}
-	return append(result, remoteSpans...)
+	return rs
 }
 
 type noopSpanContext struct{}
@@ -18,13 +18,19 @@ package tracing
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 	"unsafe"
 
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/pkg/settings"
 	"github.com/cockroachdb/cockroach/pkg/util/caller"
+	"github.com/cockroachdb/cockroach/pkg/util/syncutil"
 	lightstep "github.com/lightstep/lightstep-tracer-go"
 	opentracing "github.com/opentracing/opentracing-go"
 )
@@ -253,6 +259,112 @@ func TestTracerInjectExtract(t *testing.T) {
 	`)
 }
 
+func TestTracerWithSnowball(t *testing.T) {
+	tr := NewTracer()
+	tr2 := NewTracer()
+
+	// A root span starts a snowball recording.
+	s1 := tr.StartSpan("a", WithSnowball())
+	if IsNoopSpan(s1) {
+		t.Fatal("expected real span")
+	}
+	if v := s1.BaggageItem(Snowball); v != "1" {
+		t.Errorf("expected Snowball baggage item, got %q", v)
+	}
+	s1.LogKV("x", 1)
+
+	// The snowball baggage propagates to remote spans, which record.
+	carrier := make(opentracing.HTTPHeadersCarrier)
+	if err := tr.Inject(s1.Context(), opentracing.HTTPHeaders, carrier); err != nil {
+		t.Fatal(err)
+	}
+	wireContext, err := tr2.Extract(opentracing.HTTPHeaders, carrier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2 := tr2.StartSpan("remote op", opentracing.FollowsFrom(wireContext))
+	s2.LogKV("x", 2)
+	if err := ImportRemoteSpans(s1, GetRecording(s2)); err != nil {
+		t.Fatal(err)
+	}
+	checkRecordedSpans(t, GetRecording(s1), `
+	  span a:
+		  tags: sb=1
+		  x: 1
+		span remote op:
+		  tags: sb=1
+			x: 2
+	`)
+
+	// The baggage of a non-recording parent is retained, and the parent doesn't
+	// become part of the recording.
+	parent := tr.StartSpan("parent", Recordable)
+	parent.SetBaggageItem("k", "v")
+	s3 := tr.StartSpan("b", opentracing.ChildOf(parent.Context()), WithSnowball())
+	if v := s3.BaggageItem("k"); v != "v" {
+		t.Errorf("expected parent baggage item, got %q", v)
+	}
+	if v := s3.BaggageItem(Snowball); v != "1" {
+		t.Errorf("expected Snowball baggage item, got %q", v)
+	}
+	if rec := GetRecording(parent); rec != nil {
+		t.Errorf("expected parent not to record, got %v", rec)
+	}
+	checkRecordedSpans(t, GetRecording(s3), `
+	  span b:
+		  tags: sb=1
+	`)
+}
+
+func TestRecordingType(t *testing.T) {
+	tr := NewTracer()
+
+	if typ := GetRecordingType(tr.StartSpan("noop")); typ != NotRecording {
+		t.Errorf("expected noop span not to record, got %s", typ)
+	}
+
+	s1 := tr.StartSpan("a", Recordable)
+	if typ := GetRecordingType(s1); typ != NotRecording {
+		t.Errorf("expected %s, got %s", NotRecording, typ)
+	}
+
+	// A single-node recording doesn't set the Snowball baggage item, and it
+	// can't be set directly.
+	StartRecording(s1, SingleNodeRecording)
+	s1.SetBaggageItem(Snowball, "1")
+	if v := s1.BaggageItem(Snowball); v != "" {
+		t.Errorf("expected no Snowball baggage item, got %q", v)
+	}
+	s2 := tr.StartSpan("b", opentracing.ChildOf(s1.Context()))
+	if typ := GetRecordingType(s2); typ != SingleNodeRecording {
+		t.Errorf("expected child to inherit %s, got %s", SingleNodeRecording, typ)
+	}
+	s2.Finish()
+
+	// Switching to a snowball recording sets the item, and stopping the
+	// recording clears it.
+	StartRecording(s1, SnowballRecording)
+	if typ := GetRecordingType(s1); typ != SnowballRecording {
+		t.Errorf("expected %s, got %s", SnowballRecording, typ)
+	}
+	if v := s1.BaggageItem(Snowball); v != "1" {
+		t.Errorf("expected Snowball baggage item, got %q", v)
+	}
+	s3 := tr.StartSpan("c", opentracing.ChildOf(s1.Context()))
+	if typ := GetRecordingType(s3); typ != SnowballRecording {
+		t.Errorf("expected child to inherit %s, got %s", SnowballRecording, typ)
+	}
+	s3.Finish()
+	StopRecording(s1)
+	if typ := GetRecordingType(s1); typ != NotRecording {
+		t.Errorf("expected %s, got %s", NotRecording, typ)
+	}
+	if v := s1.BaggageItem(Snowball); v != "" {
+		t.Errorf("expected no Snowball baggage item, got %q", v)
+	}
+	s1.Finish()
+}
+
 func TestLightstepContext(t *testing.T) {
 	lsTr := lightstep.NewTracer(lightstep.Options{
 		AccessToken: "invalid",
@@ -310,3 +422,550 @@ func TestLightstepContext(t *testing.T) {
 		}
 	}
 }
+
+func TestLightstepOptions(t *testing.T) {
+	opts := lightstepOptions("token")
+	if opts.AccessToken != "token" || opts.Collector != (lightstep.Endpoint{}) ||
+		opts.MaxBufferedSpans != 0 || opts.ReportingPeriod != 0 {
+		t.Errorf("unexpected default options %+v", opts)
+	}
+
+	defer settings.TestingSetString(&lightstepCollectorHost, "collector")()
+	defer settings.TestingSetInt(&lightstepCollectorPort, 1234)()
+	defer settings.TestingSetBool(&lightstepPlaintext, true)()
+	defer settings.TestingSetInt(&lightstepMaxBufferedSpans, 100)()
+	defer settings.TestingSetDuration(&lightstepReportingPeriod, 5*time.Second)()
+	opts = lightstepOptions("token")
+	expected := lightstep.Endpoint{Host: "collector", Port: 1234, Plaintext: true}
+	if opts.Collector != expected {
+		t.Errorf("expected collector %+v, got %+v", expected, opts.Collector)
+	}
+	if opts.MaxBufferedSpans != 100 || opts.ReportingPeriod != 5*time.Second {
+		t.Errorf("unexpected options %+v", opts)
+	}
+}
+
+type testDurationRecorder struct {
+	syncutil.Mutex
+	ops []string
+}
+
+func (r *testDurationRecorder) RecordSpanDuration(operation string, _ time.Duration) {
+	r.Lock()
+	r.ops = append(r.ops, operation)
+	r.Unlock()
+}
+
+func TestTracerDurationRecorder(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	var r testDurationRecorder
+	tr.SetSpanDurationRecorder(&r)
+
+	// With the setting off, spans are noop and nothing gets recorded.
+	s := tr.StartSpan("off")
+	if !IsNoopSpan(s) {
+		t.Error("expected noop span")
+	}
+	s.Finish()
+
+	defer settings.TestingSetBool(&enableOpHistograms, true)()
+	s1 := tr.StartSpan("a")
+	if IsNoopSpan(s1) {
+		t.Error("expected real span when histograms are enabled")
+	}
+	s2 := tr.StartSpan("b", opentracing.ChildOf(s1.Context()))
+	s2.Finish()
+	s1.Finish()
+
+	if exp := []string{"b", "a"}; !reflect.DeepEqual(r.ops, exp) {
+		t.Errorf("expected %v, got %v", exp, r.ops)
+	}
+}
+
+func TestTracerIDGenerator(t *testing.T) {
+	const seed = 42
+	ids := func() []uint64 {
+		tr := NewTracer().(*Tracer)
+		tr.SetIDGenerator(NewSeededIDGenerator(seed))
+		s1 := tr.StartSpan("a", Recordable)
+		s2 := tr.StartSpan("b", Recordable, opentracing.ChildOf(s1.Context()))
+		defer s1.Finish()
+		defer s2.Finish()
+		c1, c2 := s1.Context().(*spanContext), s2.Context().(*spanContext)
+		if c1.TraceID != c2.TraceID {
+			t.Errorf("child has trace ID %d, parent has %d", c2.TraceID, c1.TraceID)
+		}
+		return []uint64{c1.SpanID, c1.TraceID, c2.SpanID}
+	}
+
+	first, second := ids(), ids()
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("tracers with the same seed assigned different IDs: %v vs %v", first, second)
+	}
+	for _, id := range first {
+		if id == 0 || id >= 1<<63 {
+			t.Errorf("invalid ID %d", id)
+		}
+	}
+	if first[0] == first[2] {
+		t.Errorf("duplicate span ID %d", first[0])
+	}
+}
+
+func TestTracerRecordingTypedFields(t *testing.T) {
+	tr := NewTracer()
+	sp := tr.StartSpan("a", Recordable)
+	StartRecording(sp, SingleNodeRecording)
+	sp.LogKV("s", "x", "b", true, "i", 5, "u", uint64(6), "f", 1.5, "d", 2*time.Second)
+	sp.Finish()
+
+	// The typed values survive the conversion to the wire format.
+	data, err := GetRecording(sp)[0].Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rs RecordedSpan
+	if err := rs.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		kind  FieldKind
+		value interface{}
+		str   string
+	}{
+		{FieldKind_STRING, "x", "x"},
+		{FieldKind_BOOL, true, "true"},
+		{FieldKind_INT, int64(5), "5"},
+		{FieldKind_UINT, uint64(6), "6"},
+		{FieldKind_FLOAT, 1.5, "1.5"},
+		{FieldKind_DURATION, 2 * time.Second, "2s"},
+	}
+	fields := rs.Logs[0].Fields
+	if len(fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %+v", len(expected), fields)
+	}
+	for i, e := range expected {
+		f := &fields[i]
+		if f.Kind != e.kind || f.TypedValue() != e.value || f.Value != e.str {
+			t.Errorf("%s: expected %s %v (%q), got %s %v (%q)",
+				f.Key, e.kind, e.value, e.str, f.Kind, f.TypedValue(), f.Value)
+		}
+	}
+	if d, ok := fields[5].Duration(); !ok || d != 2*time.Second {
+		t.Errorf("expected duration, got %s %t", d, ok)
+	}
+	if _, ok := fields[4].Int(); ok {
+		t.Errorf("float field reported as INT")
+	}
+}
+
+func TestTracerMultipleReferences(t *testing.T) {
+	tr := NewTracer()
+	a := tr.StartSpan("a", Recordable)
+	b := tr.StartSpan("b", Recordable)
+	c := tr.StartSpan("c", Recordable)
+	defer a.Finish()
+	defer b.Finish()
+	defer c.Finish()
+	meta := func(sp opentracing.Span) spanMeta { return sp.(*span).spanMeta }
+
+	// The ChildOf reference determines the parent even if it isn't the first
+	// reference; the others are recorded as links.
+	d := tr.StartSpan("d", Recordable,
+		opentracing.FollowsFrom(a.Context()),
+		opentracing.ChildOf(b.Context()),
+		opentracing.ChildOf(c.Context()),
+	)
+	StartRecording(d, SingleNodeRecording)
+	d.Finish()
+
+	// The links survive the conversion to the wire format.
+	data, err := GetRecording(d)[0].Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rs RecordedSpan
+	if err := rs.Unmarshal(data); err != nil {
+		t.Fatal(err)
+	}
+	if rs.TraceID != meta(b).TraceID || rs.ParentSpanID != meta(b).SpanID {
+		t.Errorf("expected d to be a child of b, got trace %d, parent %d", rs.TraceID, rs.ParentSpanID)
+	}
+	expected := []RecordedSpan_SpanLink{
+		{TraceID: meta(a).TraceID, SpanID: meta(a).SpanID, FollowsFrom: true},
+		{TraceID: meta(c).TraceID, SpanID: meta(c).SpanID},
+	}
+	if !reflect.DeepEqual(rs.Links, expected) {
+		t.Errorf("expected links %+v, got %+v", expected, rs.Links)
+	}
+
+	// Without a ChildOf reference, the first FollowsFrom reference determines
+	// the parent. References to noop spans are ignored.
+	e := tr.StartSpan("e", Recordable,
+		opentracing.ChildOf(noopSpanContext{}),
+		opentracing.FollowsFrom(a.Context()),
+		opentracing.FollowsFrom(c.Context()),
+	)
+	StartRecording(e, SingleNodeRecording)
+	e.Finish()
+	rs = GetRecording(e)[0]
+	if rs.ParentSpanID != meta(a).SpanID {
+		t.Errorf("expected e to be a child of a, got parent %d", rs.ParentSpanID)
+	}
+	expected = []RecordedSpan_SpanLink{
+		{TraceID: meta(c).TraceID, SpanID: meta(c).SpanID, FollowsFrom: true},
+	}
+	if !reflect.DeepEqual(rs.Links, expected) {
+		t.Errorf("expected links %+v, got %+v", expected, rs.Links)
+	}
+}
+
+func TestGetRecordingSnapshot(t *testing.T) {
+	tr := NewTracer()
+
+	s1 := tr.StartSpan("a", Recordable)
+	StartRecording(s1, SingleNodeRecording)
+	s1.LogKV("x", 1)
+	s2 := tr.StartSpan("b", opentracing.ChildOf(s1.Context()))
+	s2.LogKV("x", 2)
+	s2.Finish()
+
+	checkRecordedSpans(t, GetRecordingSnapshot(s1), `
+	  span a:
+		  tags: unfinished=true
+      x: 1
+	  span b:
+      x: 2
+	`)
+
+	// Taking a snapshot doesn't stop the recording.
+	s1.LogKV("x", 3)
+	s1.Finish()
+	rec := GetRecordingSnapshot(s1)
+	checkRecordedSpans(t, rec, `
+	  span a:
+      x: 1
+      x: 3
+	  span b:
+      x: 2
+	`)
+	for _, sp := range rec {
+		if sp.Duration <= 0 {
+			t.Errorf("%s: expected positive duration, got %s", sp.Operation, sp.Duration)
+		}
+	}
+}
+
+func TestTracerStartChildSpan(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+
+	// The child of a noop span is a noop span.
+	if sp := tr.StartChildSpan("a", tr.StartSpan("noop").Context()); !IsNoopSpan(sp) {
+		t.Error("expected noop span")
+	}
+
+	s1 := tr.StartSpan("a", Recordable)
+	s1.SetBaggageItem("x", "1")
+	StartRecording(s1, SingleNodeRecording)
+	s2 := tr.StartChildSpan("b", s1.Context())
+	c1, c2 := s1.Context().(*spanContext), s2.Context().(*spanContext)
+	if c1.TraceID != c2.TraceID {
+		t.Errorf("child has trace ID %d, parent has %d", c2.TraceID, c1.TraceID)
+	}
+	if v := s2.BaggageItem("x"); v != "1" {
+		t.Errorf("expected baggage to be inherited, got %q", v)
+	}
+	s2.LogKV("x", 2)
+	s2.Finish()
+	s1.Finish()
+	checkRecordedSpans(t, GetRecording(s1), `
+	  span a:
+	  span b:
+	    x: 2
+	`)
+	if rec := GetRecording(s1); rec[1].ParentSpanID != c1.SpanID {
+		t.Errorf("child has parent span ID %d, expected %d", rec[1].ParentSpanID, c1.SpanID)
+	}
+}
+
+type discardDurationRecorder struct{}
+
+func (discardDurationRecorder) RecordSpanDuration(string, time.Duration) {}
+
+func BenchmarkTracerStartSpan(b *testing.B) {
+	tr := NewTracer().(*Tracer)
+	// Enable duration histograms so that the spans are real.
+	defer settings.TestingSetBool(&enableOpHistograms, true)()
+	tr.SetSpanDurationRecorder(discardDurationRecorder{})
+
+	parent := tr.StartSpan("parent")
+	parent.SetBaggageItem("x", "1")
+	defer parent.Finish()
+	parentCtx := parent.Context()
+
+	b.Run("root", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tr.StartSpan("a").Finish()
+		}
+	})
+	b.Run("child-with-baggage", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tr.StartSpan("a", opentracing.ChildOf(parentCtx)).Finish()
+		}
+	})
+	b.Run("child-with-baggage-fast-path", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tr.StartChildSpan("a", parentCtx).Finish()
+		}
+	})
+}
+
+// BenchmarkTracerStartSpanDeep starts the spans of a deep tree, as done by
+// DistSQL flows, in which every span inherits the baggage of the root.
+func BenchmarkTracerStartSpanDeep(b *testing.B) {
+	tr := NewTracer().(*Tracer)
+	defer settings.TestingSetBool(&enableOpHistograms, true)()
+	tr.SetSpanDurationRecorder(discardDurationRecorder{})
+
+	root := tr.StartSpan("root")
+	for i := 0; i < 8; i++ {
+		root.SetBaggageItem(fmt.Sprintf("item%d", i), "value")
+	}
+	defer root.Finish()
+
+	for _, depth := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			b.ReportAllocs()
+			spans := make([]opentracing.Span, depth)
+			for i := 0; i < b.N; i++ {
+				parentCtx := root.Context()
+				for j := range spans {
+					spans[j] = tr.StartChildSpan("a", parentCtx)
+					parentCtx = spans[j].Context()
+				}
+				for j := len(spans) - 1; j >= 0; j-- {
+					spans[j].Finish()
+				}
+			}
+		})
+	}
+}
+
+func TestTracerBaggageCopyOnWrite(t *testing.T) {
+	tr := NewTracer()
+
+	parent := tr.StartSpan("parent", Recordable)
+	parent.SetBaggageItem("x", "1")
+	parentCtx := parent.Context()
+	child := tr.StartSpan("child", opentracing.ChildOf(parentCtx))
+	sibling := tr.StartSpan("sibling", opentracing.ChildOf(parentCtx))
+
+	// Setting items after the baggage is shared doesn't affect the other spans
+	// and contexts.
+	parent.SetBaggageItem("y", "2")
+	child.SetBaggageItem("x", "3")
+	sibling.SetBaggageItem("z", "4")
+
+	check := func(name string, sc opentracing.SpanContext, expected map[string]string) {
+		actual := make(map[string]string)
+		sc.ForeachBaggageItem(func(k, v string) bool {
+			actual[k] = v
+			return true
+		})
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: expected baggage %v, got %v", name, expected, actual)
+		}
+	}
+	check("parent context", parentCtx, map[string]string{"x": "1"})
+	check("parent", parent.Context(), map[string]string{"x": "1", "y": "2"})
+	check("child", child.Context(), map[string]string{"x": "3"})
+	check("sibling", sibling.Context(), map[string]string{"x": "1", "z": "4"})
+
+	child.Finish()
+	sibling.Finish()
+	parent.Finish()
+}
+
+func TestTracerBaggageLimits(t *testing.T) {
+	defer settings.TestingSetInt(&maxBaggageItems, 2)()
+	defer settings.TestingSetByteSize(&maxBaggageBytes, 10)()
+	tr := NewTracer()
+
+	s := tr.StartSpan("a", Recordable)
+	StartRecording(s, SingleNodeRecording)
+	s.SetBaggageItem("a", "1")
+	s.SetBaggageItem("b", "22")
+	// Too many items.
+	s.SetBaggageItem("c", "3")
+	// Replacing an item within the size limit.
+	s.SetBaggageItem("b", "2222222")
+	// Too many bytes.
+	s.SetBaggageItem("a", "123")
+	if v := s.BaggageItem("a"); v != "1" {
+		t.Errorf("expected a=1, got %q", v)
+	}
+	if v := s.BaggageItem("c"); v != "" {
+		t.Errorf("expected c to be dropped, got %q", v)
+	}
+	s.Finish()
+	checkRecordedSpans(t, GetRecording(s), `
+	  span a:
+	    tags: a=1 b=2222222
+	    event: baggage truncated  error: item "c" exceeds the limit of 2 baggage items
+	    event: baggage truncated  error: item "a" exceeds the limit of 10 bytes of baggage
+	`)
+
+	// The Snowball item is exempt from the limits.
+	if err := checkBaggageLimits(map[string]string{"a": "1", "b": "2222222"}, Snowball, "1"); err != nil {
+		t.Error(err)
+	}
+
+	// Items beyond the limits are dropped when extracting a remote context, and
+	// the child of that context records it.
+	carrier := opentracing.TextMapCarrier{
+		fieldNameTraceID:    "1",
+		fieldNameSpanID:     "2",
+		prefixBaggage + "x": "1",
+		prefixBaggage + "y": "2",
+		prefixBaggage + "z": "3",
+	}
+	sc, err := tr.Extract(opentracing.TextMap, carrier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := sc.(*spanContext); len(c.Baggage) != 2 || !c.baggageTruncated {
+		t.Fatalf("expected the baggage to be truncated to 2 items, got %v", c.Baggage)
+	}
+	child := tr.StartSpan("b", opentracing.ChildOf(sc), WithSnowball())
+	child.Finish()
+	rec := GetRecording(child)
+	if len(rec) != 1 || len(rec[0].Logs) != 1 || rec[0].Logs[0].Fields[0].Value != BaggageTruncatedEvent {
+		t.Errorf("expected a %q event, got %+v", BaggageTruncatedEvent, rec)
+	}
+}
+
+func TestTracerVerbosity(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	tr2 := NewTracer()
+
+	if v := GetVerbosity(tr.StartSpan("noop")); v != 0 {
+		t.Errorf("expected verbosity 0 for a noop span, got %d", v)
+	}
+
+	s1 := tr.StartSpan("a", Recordable)
+	SetVerbosity(s1, 2)
+	if v := GetVerbosity(s1); v != 2 {
+		t.Errorf("expected verbosity 2, got %d", v)
+	}
+
+	// The verbosity is inherited by local and remote children.
+	s2 := tr.StartChildSpan("b", s1.Context())
+	if v := GetVerbosity(s2); v != 2 {
+		t.Errorf("expected verbosity 2 for a local child, got %d", v)
+	}
+	carrier := make(opentracing.HTTPHeadersCarrier)
+	if err := tr.Inject(s1.Context(), opentracing.HTTPHeaders, carrier); err != nil {
+		t.Fatal(err)
+	}
+	wireContext, err := tr2.Extract(opentracing.HTTPHeaders, carrier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3 := tr2.StartSpan("c", opentracing.FollowsFrom(wireContext))
+	if v := GetVerbosity(s3); v != 2 {
+		t.Errorf("expected verbosity 2 for a remote child, got %d", v)
+	}
+
+	// Clearing the verbosity doesn't affect the existing children.
+	SetVerbosity(s1, 0)
+	if v := GetVerbosity(s1); v != 0 {
+		t.Errorf("expected verbosity 0, got %d", v)
+	}
+	if v := GetVerbosity(s2); v != 2 {
+		t.Errorf("expected verbosity 2 for a local child, got %d", v)
+	}
+	s4 := tr.StartChildSpan("d", s1.Context())
+	if v := GetVerbosity(s4); v != 0 {
+		t.Errorf("expected verbosity 0 for a new child, got %d", v)
+	}
+
+	// Invalid values are ignored.
+	s1.SetBaggageItem(VerboseLogging, "x")
+	if v := GetVerbosity(s1); v != 0 {
+		t.Errorf("expected verbosity 0, got %d", v)
+	}
+
+	s4.Finish()
+	s3.Finish()
+	s2.Finish()
+	s1.Finish()
+}
+
+func TestNetTraceFamily(t *testing.T) {
+	component := opentracing.Tags{"component": "distsql"}
+	testCases := []struct {
+		granularity int64
+		op          string
+		tags        opentracing.Tags
+		expected    string
+	}{
+		{netTraceFamilyNone, "sql txn", nil, "tracing"},
+		{netTraceFamilyNone, "sql txn", component, "tracing"},
+		{netTraceFamilyComponent, "sql txn", nil, "sql"},
+		{netTraceFamilyComponent, "storage.Replica: read", nil, "storage"},
+		{netTraceFamilyComponent, "kv.DistSender: sending batch", nil, "kv"},
+		{netTraceFamilyComponent, "heartbeat", nil, "heartbeat"},
+		{netTraceFamilyComponent, "/cockroach.roachpb.Internal/Batch", nil, "grpc"},
+		{netTraceFamilyComponent, "[n1] foo", nil, "tracing"},
+		{netTraceFamilyComponent, "", nil, "tracing"},
+		{netTraceFamilyComponent, "sql txn", component, "distsql"},
+		{netTraceFamilyComponent, "flow", opentracing.Tags{"component": "sql/distsqlrun"}, "sql"},
+		{netTraceFamilyOperation, "sql txn", component, "sql txn"},
+		{netTraceFamilyOperation, "", nil, "tracing"},
+	}
+	for _, c := range testCases {
+		if f := netTraceFamily(c.granularity, c.op, c.tags); f != c.expected {
+			t.Errorf("%d %q %v: expected family %q, got %q", c.granularity, c.op, c.tags, c.expected, f)
+		}
+	}
+}
+
+func TestCallerComponent(t *testing.T) {
+	tr := NewTracer().(*Tracer)
+	c := NewTestCollector(tr)
+	defer c.Close()
+
+	const component = "util/tracing"
+	root := tr.StartSpan("root", ComponentFromCaller())
+	ctx := opentracing.ContextWithSpan(context.Background(), root)
+	_, child := ChildSpan(ctx, "child")
+	child.Finish()
+	_, forked := ForkCtxSpan(ctx, "forked")
+	forked.Finish()
+
+	defer settings.TestingSetBool(&enableCallerComponent, false)()
+	_, untagged := ChildSpan(ctx, "untagged")
+	untagged.Finish()
+	root.Finish()
+
+	for _, op := range []string{"root", "child", "forked"} {
+		sp, err := c.FindSpan(op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := sp.Tags["component"]; v != component {
+			t.Errorf("%s: expected component %q, got %q", op, component, v)
+		}
+	}
+	sp, err := c.FindSpan("untagged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := sp.Tags["component"]; ok {
+		t.Errorf("expected no component, got %q", v)
+	}
+}